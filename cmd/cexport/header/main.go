@@ -0,0 +1,275 @@
+// Command header generates the C header of the cexport package (see
+// `go generate ./cmd/cexport`).
+//
+// It declares every function that's marked with an //export directive and
+// defines every package-level constant as a CTK_ prefixed macro (e.g. keySize
+// becomes CTK_KEY_SIZE and statusOK becomes CTK_STATUS_OK). The functions' doc
+// comments are copied over.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// prefix is prepended to the names of the constants.
+const prefix = "CTK_"
+
+// types maps the cgo types that are used in the exported functions to C types.
+var types = map[string]string{
+	"C.int":      "int",
+	"C.size_t":   "size_t",
+	"C.uint8_t":  "uint8_t",
+	"*C.size_t":  "size_t *",
+	"*C.uint8_t": "uint8_t *",
+}
+
+var errUnsupported = errors.New("ctk: unsupported declaration")
+
+func main() {
+	dir := flag.String("dir", ".", "directory of the cexport package")
+	out := flag.String("o", "", "output file (default stdout)")
+	flag.Parse()
+
+	header, err := generate(*dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *out == "" {
+		os.Stdout.Write(header)
+		return
+	}
+
+	err = os.WriteFile(*out, header, 0o644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// generate returns the header of the package in dir.
+func generate(dir string) ([]byte, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return []byte{}, err
+	}
+
+	slices.Sort(paths)
+
+	var constants, functions bytes.Buffer
+	fset := token.NewFileSet()
+
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return []byte{}, err
+		}
+
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				if decl.Tok != token.CONST {
+					continue
+				}
+
+				for _, spec := range decl.Specs {
+					err := writeConstant(&constants, spec.(*ast.ValueSpec))
+					if err != nil {
+						return []byte{}, err
+					}
+				}
+			case *ast.FuncDecl:
+				err := writeFunction(&functions, decl)
+				if err != nil {
+					return []byte{}, err
+				}
+			}
+		}
+	}
+
+	var b bytes.Buffer
+
+	b.WriteString("// Code generated by cmd/cexport/header. DO NOT EDIT.\n\n")
+	b.WriteString("#ifndef CTK_H\n#define CTK_H\n\n")
+	b.WriteString("#include <stddef.h>\n#include <stdint.h>\n\n")
+	b.Write(constants.Bytes())
+	b.WriteString("\n#ifdef __cplusplus\nextern \"C\" {\n#endif\n")
+	b.Write(functions.Bytes())
+	b.WriteString("\n#ifdef __cplusplus\n}\n#endif\n\n#endif\n")
+
+	return b.Bytes(), nil
+}
+
+// writeConstant writes the constants of the spec as macros.
+func writeConstant(b *bytes.Buffer, spec *ast.ValueSpec) error {
+	if len(spec.Names) != len(spec.Values) {
+		return fmt.Errorf("%w: constant %s needs an explicit value", errUnsupported, spec.Names[0].Name)
+	}
+
+	for i, name := range spec.Names {
+		value, err := expression(spec.Values[i])
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(b, "#define %s %s\n", macro(name.Name), value)
+	}
+
+	return nil
+}
+
+// writeFunction writes the declaration of the function if it's exported.
+func writeFunction(b *bytes.Buffer, decl *ast.FuncDecl) error {
+	if decl.Doc == nil || decl.Recv != nil {
+		return nil
+	}
+
+	var exported bool
+	var doc []string
+
+	for _, comment := range decl.Doc.List {
+		if strings.HasPrefix(comment.Text, "//export ") {
+			exported = strings.TrimPrefix(comment.Text, "//export ") == decl.Name.Name
+			continue
+		}
+
+		doc = append(doc, strings.TrimRight(comment.Text, " "))
+	}
+
+	if !exported {
+		return nil
+	}
+
+	result := "void"
+	if decl.Type.Results != nil {
+		if len(decl.Type.Results.List) != 1 {
+			return fmt.Errorf("%w: function %s has multiple results", errUnsupported, decl.Name.Name)
+		}
+
+		var err error
+		result, err = cType(decl.Type.Results.List[0].Type)
+		if err != nil {
+			return err
+		}
+	}
+
+	var params []string
+	for _, field := range decl.Type.Params.List {
+		typ, err := cType(field.Type)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range field.Names {
+			if strings.HasSuffix(typ, "*") {
+				params = append(params, typ+name.Name)
+			} else {
+				params = append(params, typ+" "+name.Name)
+			}
+		}
+	}
+
+	if len(params) == 0 {
+		params = []string{"void"}
+	}
+
+	// Drop the blank line that separates the doc from the directive.
+	for len(doc) > 0 && doc[len(doc)-1] == "//" {
+		doc = doc[:len(doc)-1]
+	}
+
+	b.WriteString("\n")
+	for _, line := range doc {
+		b.WriteString(line + "\n")
+	}
+	fmt.Fprintf(b, "%s %s(%s);\n", result, decl.Name.Name, strings.Join(params, ", "))
+
+	return nil
+}
+
+// cType returns the C type of the cgo type.
+func cType(expr ast.Expr) (string, error) {
+	var name string
+
+	switch expr := expr.(type) {
+	case *ast.SelectorExpr:
+		name = types[fmt.Sprintf("%s.%s", expr.X, expr.Sel.Name)]
+	case *ast.StarExpr:
+		if sel, ok := expr.X.(*ast.SelectorExpr); ok {
+			name = types[fmt.Sprintf("*%s.%s", sel.X, sel.Sel.Name)]
+		}
+	}
+
+	if name == "" {
+		return "", fmt.Errorf("%w: type %T", errUnsupported, expr)
+	}
+
+	return name, nil
+}
+
+// expression returns the constant expression in C.
+func expression(expr ast.Expr) (string, error) {
+	switch expr := expr.(type) {
+	case *ast.BasicLit:
+		if expr.Kind != token.INT {
+			return "", fmt.Errorf("%w: literal %s", errUnsupported, expr.Value)
+		}
+
+		return expr.Value, nil
+	case *ast.Ident:
+		return macro(expr.Name), nil
+	case *ast.ParenExpr:
+		x, err := expression(expr.X)
+		return "(" + x + ")", err
+	case *ast.UnaryExpr:
+		x, err := expression(expr.X)
+		return "(" + expr.Op.String() + x + ")", err
+	case *ast.BinaryExpr:
+		x, err := expression(expr.X)
+		if err != nil {
+			return "", err
+		}
+
+		y, err := expression(expr.Y)
+		return "(" + x + " " + expr.Op.String() + " " + y + ")", err
+	}
+
+	return "", fmt.Errorf("%w: expression %T", errUnsupported, expr)
+}
+
+// macro returns the macro name of the Go identifier (e.g. CTK_KEY_SIZE for
+// keySize).
+func macro(name string) string {
+	var b strings.Builder
+
+	b.WriteString(prefix)
+
+	runes := []rune(name)
+	for i, r := range runes {
+		// Start a new word at an upper case letter unless it continues an
+		// initialism (e.g. "OK").
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			b.WriteRune('_')
+		}
+
+		b.WriteRune(unicode.ToUpper(r))
+	}
+
+	return b.String()
+}
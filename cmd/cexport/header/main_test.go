@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	want, err := os.ReadFile("../ctk.h")
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	got, err := generate("..")
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	if string(got) != string(want) {
+		t.Errorf("ctk.h is out of date, run go generate ./cmd/cexport")
+	}
+}
+
+func TestMacro(t *testing.T) {
+	tt := map[string]struct {
+		name string
+		want string
+	}{
+		"Lower Case":  {name: "overhead", want: "CTK_OVERHEAD"},
+		"Camel Case":  {name: "keySize", want: "CTK_KEY_SIZE"},
+		"Initialism":  {name: "statusOK", want: "CTK_STATUS_OK"},
+		"Mixed Words": {name: "statusInvalidArguments", want: "CTK_STATUS_INVALID_ARGUMENTS"},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := macro(tc.name)
+
+			if got != tc.want {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+// Command cexport exposes XChaCha20-Poly1305 via a C ABI so that the toolkit
+// can be used from C, Python, Rust, etc. when it's built as a shared library:
+//
+//	go build -buildmode=c-shared -o libctk.so ./cmd/cexport
+//
+// The functions are declared in ctk.h which is generated from the exported
+// functions and status codes via `go generate ./cmd/cexport`. Sealed messages
+// have the same layout as the ones of the WebAssembly example (see
+// cmd/ctk-wasm):
+//
+//	nonce (24) | ciphertext | tag (16)
+//
+// The nonce is generated randomly. All functions return CTK_STATUS_OK on
+// success or one of the other (negative) status codes on failure. Outputs are
+// written into buffers which are owned by the caller so that no memory has to
+// be freed across the language boundary.
+//
+// Example (C):
+//
+//	uint8_t key[CTK_KEY_SIZE];
+//	uint8_t sealed[sizeof(message) + CTK_OVERHEAD];
+//	size_t sealed_len;
+//
+//	ctk_keygen(key);
+//	ctk_seal(key, message, sizeof(message), NULL, 0, sealed, sizeof(sealed), &sealed_len);
+//
+// Example (Python):
+//
+//	ctk = ctypes.CDLL("./libctk.so")
+//	key = ctypes.create_string_buffer(32)
+//	ctk.ctk_keygen(key)
+package main
+
+//go:generate go run ./header -o ctk.h
+
+// #include <stddef.h>
+// #include <stdint.h>
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/pmuens/ctk-go/ctk/random"
+	"github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
+)
+
+const (
+	// keySize is the size (in bytes) of a key.
+	keySize = 32
+
+	// nonceSize is the size (in bytes) of the nonce that's prepended to a
+	// sealed message.
+	nonceSize = 24
+
+	// tagSize is the size (in bytes) of the tag that's appended to a sealed
+	// message.
+	tagSize = 16
+
+	// overhead is the size (in bytes) a sealed message is longer than its
+	// plaintext.
+	overhead = nonceSize + tagSize
+)
+
+// status is a status code that's returned to C.
+type status C.int
+
+const (
+	// statusOK is returned on success.
+	statusOK status = 0
+
+	// statusInvalidArguments is returned if a required pointer is NULL.
+	statusInvalidArguments status = -1
+
+	// statusBufferTooSmall is returned if the output buffer is too small.
+	statusBufferTooSmall status = -2
+
+	// statusInvalidMessage is returned if the sealed message is malformed or
+	// its tag is invalid.
+	statusInvalidMessage status = -3
+
+	// statusRandomFailure is returned if no randomness could be read.
+	statusRandomFailure status = -4
+)
+
+func main() {}
+
+// ctk_key_size returns the size (in bytes) of a key.
+//
+//export ctk_key_size
+func ctk_key_size() C.size_t {
+	return keySize
+}
+
+// ctk_overhead returns the size (in bytes) a sealed message is longer than its
+// plaintext.
+//
+//export ctk_overhead
+func ctk_overhead() C.size_t {
+	return overhead
+}
+
+// ctk_keygen writes a random key (CTK_KEY_SIZE bytes) into key.
+//
+//export ctk_keygen
+func ctk_keygen(key *C.uint8_t) C.int {
+	if key == nil {
+		return C.int(statusInvalidArguments)
+	}
+
+	k, err := random.Key()
+	if err != nil {
+		return C.int(statusRandomFailure)
+	}
+
+	copy(bytes(key, keySize), k[:])
+
+	return C.int(statusOK)
+}
+
+// ctk_seal encrypts the plaintext with the key (CTK_KEY_SIZE bytes) and a
+// random nonce and writes the sealed message (plaintext_len + CTK_OVERHEAD
+// bytes) into out. The AAD may be NULL if aad_len is 0.
+//
+//export ctk_seal
+func ctk_seal(key *C.uint8_t, plaintext *C.uint8_t, plaintext_len C.size_t, aad *C.uint8_t, aad_len C.size_t, out *C.uint8_t, out_cap C.size_t, out_len *C.size_t) C.int {
+	if key == nil || out == nil || out_len == nil || (plaintext == nil && plaintext_len > 0) || (aad == nil && aad_len > 0) {
+		return C.int(statusInvalidArguments)
+	}
+
+	if uint64(out_cap) < uint64(plaintext_len)+overhead {
+		return C.int(statusBufferTooSmall)
+	}
+
+	nonce, err := random.XNonce()
+	if err != nil {
+		return C.int(statusRandomFailure)
+	}
+
+	pool := xchacha20poly1305.NewPool([keySize]byte(bytes(key, keySize)))
+	sealed := pool.Seal(nonce[:], nonce[:], bytes(plaintext, plaintext_len), bytes(aad, aad_len))
+
+	copy(bytes(out, out_cap), sealed)
+	*out_len = C.size_t(len(sealed))
+
+	return C.int(statusOK)
+}
+
+// ctk_open decrypts the sealed message with the key (CTK_KEY_SIZE bytes) and
+// writes the plaintext (sealed_len - CTK_OVERHEAD bytes) into out. The AAD may
+// be NULL if aad_len is 0.
+//
+//export ctk_open
+func ctk_open(key *C.uint8_t, sealed *C.uint8_t, sealed_len C.size_t, aad *C.uint8_t, aad_len C.size_t, out *C.uint8_t, out_cap C.size_t, out_len *C.size_t) C.int {
+	if key == nil || sealed == nil || out_len == nil || (aad == nil && aad_len > 0) {
+		return C.int(statusInvalidArguments)
+	}
+
+	if sealed_len < overhead {
+		return C.int(statusInvalidMessage)
+	}
+
+	if out == nil && sealed_len > overhead {
+		return C.int(statusInvalidArguments)
+	}
+
+	if out_cap < sealed_len-overhead {
+		return C.int(statusBufferTooSmall)
+	}
+
+	message := bytes(sealed, sealed_len)
+	pool := xchacha20poly1305.NewPool([keySize]byte(bytes(key, keySize)))
+
+	plaintext, err := pool.Open(nil, message[:nonceSize], message[nonceSize:], bytes(aad, aad_len))
+	if err != nil {
+		return C.int(statusInvalidMessage)
+	}
+
+	copy(bytes(out, out_cap), plaintext)
+	*out_len = C.size_t(len(plaintext))
+
+	return C.int(statusOK)
+}
+
+// bytes returns the C buffer as a byte slice without copying it (a NULL buffer
+// is empty).
+func bytes(p *C.uint8_t, n C.size_t) []byte {
+	if p == nil {
+		return []byte{}
+	}
+
+	return unsafe.Slice((*byte)(unsafe.Pointer(p)), int(n))
+}
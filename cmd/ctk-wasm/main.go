@@ -0,0 +1,118 @@
+//go:build js && wasm
+
+// Command ctk-wasm exposes XChaCha20-Poly1305 to JavaScript when compiled to
+// WebAssembly (GOOS=js GOARCH=wasm, see `make wasm`).
+//
+// It registers two global functions which take and return Uint8Arrays:
+//
+//	ctkSeal(key, plaintext, aad) // nonce (24) | ciphertext | tag (16)
+//	ctkOpen(key, sealed, aad)    // plaintext
+//
+// The nonce is generated randomly. On failure (e.g. if the tag is invalid) the
+// functions return an Error object rather than throwing it so that callers
+// need to check the result via `instanceof Error`.
+package main
+
+import (
+	"errors"
+	"syscall/js"
+
+	"github.com/pmuens/ctk-go/ctk/random"
+	"github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
+)
+
+// nonceSize is the size (in bytes) of the nonce that's prepended to a sealed
+// message.
+const nonceSize = 24
+
+var (
+	// errInvalidArguments is returned if a function is called with wrong
+	// arguments.
+	errInvalidArguments = errors.New("ctk: invalid arguments")
+
+	// errInvalidKeySize is returned if the key isn't 32 bytes long.
+	errInvalidKeySize = errors.New("ctk: invalid key size")
+
+	// errInvalidMessage is returned if the sealed message is shorter than the
+	// nonce.
+	errInvalidMessage = errors.New("ctk: invalid sealed message")
+)
+
+func main() {
+	js.Global().Set("ctkSeal", function(seal))
+	js.Global().Set("ctkOpen", function(open))
+
+	// Keep the functions available until the page is closed.
+	select {}
+}
+
+// seal encrypts the plaintext with the key and a random nonce.
+func seal(key [32]byte, plaintext []byte, aad []byte) ([]byte, error) {
+	nonce, err := random.XNonce()
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return xchacha20poly1305.NewPool(key).Seal(nonce[:], nonce[:], plaintext, aad), nil
+}
+
+// open decrypts the sealed message with the key.
+func open(key [32]byte, sealed []byte, aad []byte) ([]byte, error) {
+	if len(sealed) < nonceSize {
+		return []byte{}, errInvalidMessage
+	}
+
+	nonce := sealed[:nonceSize]
+
+	return xchacha20poly1305.NewPool(key).Open(nil, nonce, sealed[nonceSize:], aad)
+}
+
+// function wraps seal or open as a JavaScript function which takes the key and
+// two Uint8Arrays and returns a Uint8Array (or an Error).
+func function(f func(key [32]byte, data []byte, aad []byte) ([]byte, error)) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) (result any) {
+		// Arguments which aren't Uint8Arrays make the copy panic.
+		defer func() {
+			if r := recover(); r != nil {
+				result = jsError(errInvalidArguments)
+			}
+		}()
+
+		if len(args) != 3 {
+			return jsError(errInvalidArguments)
+		}
+
+		key := bytes(args[0])
+		if len(key) != 32 {
+			return jsError(errInvalidKeySize)
+		}
+
+		out, err := f([32]byte(key), bytes(args[1]), bytes(args[2]))
+		if err != nil {
+			return jsError(err)
+		}
+
+		array := js.Global().Get("Uint8Array").New(len(out))
+		js.CopyBytesToJS(array, out)
+
+		return array
+	})
+}
+
+// bytes copies the Uint8Array into a byte slice (undefined and null are empty).
+// Panics if the value isn't a Uint8Array.
+func bytes(v js.Value) []byte {
+	if v.IsUndefined() || v.IsNull() {
+		return []byte{}
+	}
+
+	b := make([]byte, v.Get("length").Int())
+	js.CopyBytesToGo(b, v)
+
+	return b
+}
+
+// jsError returns the error as a JavaScript Error object.
+func jsError(err error) js.Value {
+	return js.Global().Get("Error").New(err.Error())
+}
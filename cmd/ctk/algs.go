@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/pmuens/ctk-go/ctk"
+)
+
+// algorithmInfo is the JSON representation of an algorithm's metadata.
+type algorithmInfo struct {
+	// Name is the name of the algorithm.
+	Name string `json:"name"`
+
+	// KeySize is the size (in bytes) of the key.
+	KeySize int `json:"key_size"`
+
+	// NonceSize is the size (in bytes) of the nonce.
+	NonceSize int `json:"nonce_size"`
+
+	// TagSize is the size (in bytes) of the tag.
+	TagSize int `json:"tag_size"`
+
+	// MaxPlaintext is the maximum size (in bytes) of a plaintext.
+	MaxPlaintext uint64 `json:"max_plaintext"`
+
+	// Specification is the document that specifies the algorithm.
+	Specification string `json:"specification"`
+
+	// Approved indicates whether the algorithm is approved by FIPS 140-3.
+	Approved bool `json:"approved"`
+
+	// Notes are security notes.
+	Notes string `json:"notes"`
+}
+
+// runAlgs prints the metadata of the registered algorithms.
+func runAlgs(args []string) error {
+	flags := flag.NewFlagSet("algs", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the metadata as JSON")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	infos := []algorithmInfo{}
+	for _, algorithm := range ctk.Describe() {
+		infos = append(infos, algorithmInfo{
+			Name:          algorithm.Name,
+			KeySize:       algorithm.Metadata.KeySize,
+			NonceSize:     algorithm.Metadata.NonceSize,
+			TagSize:       algorithm.Metadata.TagSize,
+			MaxPlaintext:  algorithm.Metadata.MaxPlaintext,
+			Specification: algorithm.Compliance.Specification,
+			Approved:      algorithm.Compliance.Approved,
+			Notes:         algorithm.Metadata.Notes,
+		})
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(infos)
+	}
+
+	for _, info := range infos {
+		approved := ""
+		if info.Approved {
+			approved = " (approved)"
+		}
+
+		fmt.Printf("%v\n", info.Name)
+		fmt.Printf("  specification: %v%v\n", info.Specification, approved)
+		fmt.Printf("  key: %v bytes, nonce: %v bytes, tag: %v bytes\n", info.KeySize, info.NonceSize, info.TagSize)
+		fmt.Printf("  max plaintext: %v bytes\n", info.MaxPlaintext)
+		fmt.Printf("  notes: %v\n", info.Notes)
+	}
+
+	return nil
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/flate"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/pmuens/ctk-go/ctk/stream"
+)
+
+// runArchive runs the archive subcommands.
+func runArchive(args []string) error {
+	if len(args) < 1 {
+		return errors.New("usage: ctk archive <create|extract> [arguments]")
+	}
+
+	switch args[0] {
+	case "create":
+		return runArchiveCreate(args[1:])
+	case "extract":
+		return runArchiveExtract(args[1:])
+	default:
+		return fmt.Errorf("unknown subcommand %q", args[0])
+	}
+}
+
+// runArchiveCreate tars a directory and encrypts the (optionally compressed)
+// tarball into a stream container which is written to a file (or stdout).
+// See stream.WithCompression for why compression is disabled by default.
+func runArchiveCreate(args []string) error {
+	flags := flag.NewFlagSet("archive create", flag.ContinueOnError)
+	keyFlags := registerKeyFlags(flags)
+	output := flags.String("o", "", "path of the encrypted archive (stdout if empty)")
+	compress := flags.Bool("compress", false, "compress the archive before encryption (leaks information about the content via its size)")
+	chunkSize := flags.Int("chunk-size", stream.DefaultChunkSize, "number of plaintext bytes per encrypted chunk")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: ctk archive create [flags] <directory>")
+	}
+
+	key, err := keyFlags.key()
+	if err != nil {
+		return err
+	}
+
+	out, name := os.Stdout, "stdout"
+	if *output != "" {
+		out, err = os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		name = *output
+	}
+
+	opts := []stream.Option{stream.WithChunkSize(*chunkSize)}
+	if *compress {
+		opts = append(opts, stream.WithCompression(flate.DefaultCompression))
+	}
+
+	sw, err := stream.NewWriter(out, key, opts...)
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(sw)
+
+	err = addDir(tw, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return err
+	}
+
+	err = sw.Close()
+	if err != nil {
+		return fmt.Errorf("%v: %w", name, err)
+	}
+
+	if out != os.Stdout {
+		return out.Close()
+	}
+
+	return nil
+}
+
+// maxStrictArchiveSize is the maximum size (in bytes) of an archive that's
+// buffered in memory to be authenticated before it's extracted (see the -strict
+// flag of archive extract).
+const maxStrictArchiveSize = 1 << 30
+
+// runArchiveExtract decrypts an encrypted archive (read from a file or stdin)
+// and restores its content in a directory.
+// Note that the files of a chunk are written as soon as the chunk is
+// authenticated which is why a truncated archive is only reported once its end
+// is reached (unless -strict is used).
+func runArchiveExtract(args []string) error {
+	flags := flag.NewFlagSet("archive extract", flag.ContinueOnError)
+	keyFlags := registerKeyFlags(flags)
+	output := flags.String("o", ".", "directory the archive is extracted to")
+	strict := flags.Bool("strict", false, "authenticate the whole archive before extracting anything (archives read from pipes are buffered in memory)")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() > 1 {
+		return errors.New("usage: ctk archive extract [flags] [archive]")
+	}
+
+	key, err := keyFlags.key()
+	if err != nil {
+		return err
+	}
+
+	in, name := os.Stdin, "stdin"
+	if flags.NArg() == 1 {
+		in, err = os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		name = flags.Arg(0)
+	}
+
+	var opts []stream.ReaderOption
+	if *strict {
+		opts = append(opts, stream.WithStrictVerification(maxStrictArchiveSize))
+	}
+
+	sr, err := stream.NewReader(in, key, opts...)
+	if err != nil {
+		return fmt.Errorf("%v: %w", name, err)
+	}
+
+	// Errors of the stream name the chunk that was rejected (see
+	// stream.ChunkError) and errors of an entry name the entry.
+	err = extract(tar.NewReader(sr), *output)
+	if err != nil {
+		return fmt.Errorf("%v: %w", name, err)
+	}
+
+	// Read the rest of the stream (i.e. the tar padding) so that a truncated
+	// archive is detected.
+	_, err = io.Copy(io.Discard, sr)
+	if err != nil {
+		return fmt.Errorf("%v: %w", name, err)
+	}
+
+	return nil
+}
+
+// addDir adds the regular files and directories in dir to the tarball. Their
+// names are relative to dir. Other files (e.g. symlinks) are skipped.
+func addDir(tw *tar.Writer, dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(dir, path)
+		if err != nil || name == "." {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			fmt.Fprintf(os.Stderr, "skipping %v (not a regular file)\n", path)
+			return nil
+		}
+
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(name)
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+
+		// Don't leak the owner of the files.
+		hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "", ""
+
+		err = tw.WriteHeader(hdr)
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(tw, f)
+
+		return err
+	})
+}
+
+// extract restores the regular files and directories of the tarball in dir.
+// Entries whose names would escape dir (e.g. absolute paths or paths that
+// contain ".."), links and existing files are rejected.
+func extract(tr *tar.Reader, dir string) error {
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := filepath.FromSlash(hdr.Name)
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("invalid path %q in archive", hdr.Name)
+		}
+		path := filepath.Join(dir, name)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(path, 0o700)
+		case tar.TypeReg:
+			err = extractFile(tr, path, hdr.FileInfo().Mode().Perm())
+		default:
+			err = errors.New("unsupported entry")
+		}
+		if err != nil {
+			return fmt.Errorf("%q: %w", hdr.Name, err)
+		}
+	}
+}
+
+// extractFile writes the current tarball entry to a new file at path.
+func extractFile(r io.Reader, path string, perm fs.FileMode) error {
+	err := os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
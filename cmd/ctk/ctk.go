@@ -2,11 +2,84 @@ package main
 
 import (
 	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
 
-	"github.com/pmuens/ctk-go/ctk"
+	"github.com/pmuens/ctk-go/ctk/logging"
 )
 
+// logEnv is the environment variable which enables logging to stderr at the
+// level (debug, info, warn or error). Key material is always redacted.
+const logEnv = "CTK_LOG"
+
+// logger receives the events of the commands and the subsystems they use
+// (e.g. the keystore).
+var logger logging.Logger = logging.Discard
+
+// command is a subcommand of the CLI.
+type command struct {
+	// description is a short summary of what the command does.
+	description string
+
+	// run runs the command with the remaining command line arguments.
+	run func(args []string) error
+}
+
+// commands are the available subcommands.
+var commands = map[string]command{
+	"algs":      {description: "list the available algorithms and their metadata", run: runAlgs},
+	"archive":   {description: "create or extract an encrypted archive of a directory", run: runArchive},
+	"doctor":    {description: "check the environment and summarize its health", run: runDoctor},
+	"explain":   {description: "explain the computations of a primitive step by step", run: runExplain},
+	"filter":    {description: "encrypt and decrypt files in git via clean and smudge filters", run: runFilter},
+	"key":       {description: "manage keys in a passphrase protected keystore", run: runKey},
+	"recipient": {description: "encrypt for recipients and decrypt with identities", run: runRecipient},
+	"serve":     {description: "serve encryption and key generation on a local REST API", run: runServe},
+	"shamir":    {description: "split a key into shares or combine shares", run: runShamir},
+	"tunnel":    {description: "forward TCP connections through an encrypted tunnel", run: runTunnel},
+}
+
 func main() {
-	result := ctk.Mul(42, 2)
-	fmt.Printf("42 * 2 = %v\n", result)
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "ctk: unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if level, ok := os.LookupEnv(logEnv); ok {
+		var l slog.Level
+
+		err := l.UnmarshalText([]byte(level))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ctk: invalid %v: %v\n", logEnv, err)
+			os.Exit(2)
+		}
+
+		logger = slog.New(logging.Redact(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: l})))
+	}
+
+	err := cmd.run(os.Args[2:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ctk %v: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+// usage prints the available commands.
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: ctk <command> [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+
+	for _, name := range slices.Sorted(maps.Keys(commands)) {
+		fmt.Fprintf(os.Stderr, "  %-10v %v\n", name, commands[name].description)
+	}
 }
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/pmuens/ctk-go/ctk"
+	"github.com/pmuens/ctk-go/ctk/poly1305"
+	"github.com/pmuens/ctk-go/ctk/random"
+)
+
+// doctorBlockSize is the size of the messages that are sealed to measure the
+// throughput.
+const doctorBlockSize = 1 << 20
+
+// check is the result of an environment check.
+type check struct {
+	// name is the name of the check.
+	name string
+
+	// ok indicates whether the check passed.
+	ok bool
+
+	// detail describes the result.
+	detail string
+}
+
+// runDoctor checks the environment (random source, self tests, backends,
+// throughput and keystore permissions) and prints a summary that can be
+// attached to support requests.
+func runDoctor(args []string) error {
+	flags := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	path := keystoreFlag(flags)
+	duration := flags.Duration("duration", 200*time.Millisecond, "duration of the throughput measurement per algorithm")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	checks := []check{
+		{name: "version", ok: true, detail: fmt.Sprintf("ctk %v, %v %v/%v", ctk.Version, runtime.Version(), runtime.GOOS, runtime.GOARCH)},
+		checkRandom(),
+		checkSelfTest(),
+		{name: "backend", ok: true, detail: "poly1305 " + poly1305.Backend},
+	}
+	for _, algorithm := range ctk.Describe() {
+		checks = append(checks, checkThroughput(algorithm, *duration))
+	}
+	checks = append(checks, checkKeystore(*path))
+
+	failed := 0
+	for _, c := range checks {
+		status := "ok"
+		if !c.ok {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Printf("%-28v %-4v  %v\n", c.name, status, c.detail)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%v of %v checks failed", failed, len(checks))
+	}
+
+	return nil
+}
+
+// checkRandom checks that crypto/rand is available and passes the health
+// checks.
+func checkRandom() check {
+	_, err := random.Bytes(64)
+	if err != nil {
+		return check{name: "random", detail: err.Error()}
+	}
+
+	return check{name: "random", ok: true, detail: "crypto/rand passed the health checks"}
+}
+
+// checkSelfTest runs the known-answer tests of the primitives.
+func checkSelfTest() check {
+	err := ctk.SelfTest()
+	if err != nil {
+		return check{name: "self test", detail: err.Error()}
+	}
+
+	return check{name: "self test", ok: true, detail: "all known-answer tests passed"}
+}
+
+// checkThroughput measures how many bytes per second the algorithm seals.
+func checkThroughput(algorithm ctk.Algorithm, duration time.Duration) check {
+	name := "throughput " + algorithm.Name
+
+	key, err := ctk.GenerateKey()
+	if err != nil {
+		return check{name: name, detail: err.Error()}
+	}
+
+	aead := algorithm.New(key)
+	nonce := make([]byte, aead.NonceSize())
+	plaintext := make([]byte, doctorBlockSize)
+	buf := make([]byte, 0, doctorBlockSize+aead.Overhead())
+
+	sealed := 0
+	start := time.Now()
+	for sealed == 0 || time.Since(start) < duration {
+		// The nonce is reused as the ciphertexts are discarded.
+		_, err = aead.Seal(buf[:0], nonce, plaintext, nil)
+		if err != nil {
+			return check{name: name, detail: err.Error()}
+		}
+		sealed += doctorBlockSize
+	}
+
+	rate := float64(sealed) / time.Since(start).Seconds() / (1 << 20)
+
+	return check{name: name, ok: true, detail: fmt.Sprintf("%.1f MiB/s", rate)}
+}
+
+// checkKeystore checks that the keystore file and its directory are only
+// accessible by the owner.
+func checkKeystore(path string) check {
+	name := "keystore"
+
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return check{name: name, ok: true, detail: fmt.Sprintf("%v doesn't exist", path)}
+	}
+	if err != nil {
+		return check{name: name, detail: err.Error()}
+	}
+
+	// Windows doesn't support Unix permissions.
+	if runtime.GOOS == "windows" {
+		return check{name: name, ok: true, detail: fmt.Sprintf("%v exists (permissions not checked)", path)}
+	}
+
+	if info.Mode().Perm()&0o077 != 0 {
+		return check{name: name, detail: fmt.Sprintf("%v has mode %#o (want 0600)", path, info.Mode().Perm())}
+	}
+
+	dir, err := os.Stat(filepath.Dir(path))
+	if err != nil {
+		return check{name: name, detail: err.Error()}
+	}
+
+	if dir.Mode().Perm()&0o077 != 0 {
+		return check{name: name, detail: fmt.Sprintf("%v has mode %#o (want 0700)", filepath.Dir(path), dir.Mode().Perm())}
+	}
+
+	return check{name: name, ok: true, detail: fmt.Sprintf("%v is only accessible by the owner", path)}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/pmuens/ctk-go/ctk/chacha20"
+	"github.com/pmuens/ctk-go/ctk/encoding"
+)
+
+// The key and nonce of the RFC 8439 (section 2.3.2) block function example
+// which are used if no key or nonce is given.
+const (
+	explainKey   = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	explainNonce = "000000090000004a00000000"
+)
+
+// runExplain runs the explain subcommands which print the intermediate values of
+// a computation step by step.
+func runExplain(args []string) error {
+	if len(args) < 1 {
+		return errors.New("usage: ctk explain <chacha20-block> [arguments]")
+	}
+
+	switch args[0] {
+	case "chacha20-block":
+		return runExplainChaCha20Block(args[1:])
+	default:
+		return fmt.Errorf("unknown subcommand %q", args[0])
+	}
+}
+
+// runExplainChaCha20Block prints the initial state, the state after every double
+// round and the serialized block of the ChaCha20 block function.
+func runExplainChaCha20Block(args []string) error {
+	flags := flag.NewFlagSet("explain chacha20-block", flag.ContinueOnError)
+	key := flags.String("key", explainKey, "hex (or base64) encoded 32 byte key")
+	nonce := flags.String("nonce", explainNonce, "hex (or base64) encoded 12 byte nonce")
+	counter := flags.Uint("counter", 1, "block counter")
+	rounds := flags.Int("rounds", chacha20.DefaultRounds, "number of rounds (8, 12 or 20)")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	parsedKey, err := encoding.ParseKey(*key)
+	if err != nil {
+		return fmt.Errorf("invalid -key: %w", err)
+	}
+
+	parsedNonce, err := encoding.ParseNonce(*nonce)
+	if err != nil {
+		return fmt.Errorf("invalid -nonce: %w", err)
+	}
+
+	if *counter > 0xffffffff {
+		return errors.New("invalid -counter: needs to fit into 32 bit")
+	}
+
+	// WithRounds panics on invalid rounds which is why they're checked upfront.
+	if *rounds != 8 && *rounds != 12 && *rounds != 20 {
+		return errors.New("invalid -rounds: needs to be 8, 12 or 20")
+	}
+
+	cha := chacha20.NewChaCha20WithCounter(parsedKey, parsedNonce, uint32(*counter), chacha20.WithRounds(*rounds), chacha20.WithTrace(os.Stdout))
+	cha.CreateBlock()
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pmuens/ctk-go/ctk/keystore"
+	"github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
+)
+
+// filterMagic is the prefix of files that were encrypted by the clean filter
+// so that the smudge filter can pass through files that were committed before
+// the filter was configured.
+var filterMagic = []byte("\x00CTKFILTER\x00")
+
+// filterHeaderSize is the size (in bytes) of the header of encrypted files (the
+// magic followed by the 32 bit big endian key version).
+var filterHeaderSize = len(filterMagic) + 4
+
+// runFilter runs the git filter subcommands which transparently encrypt files
+// in a git repository (like git-crypt). The clean filter encrypts a file (read
+// from stdin) when it's staged and the smudge filter decrypts it when it's
+// checked out.
+//
+// Files are encrypted with the deterministic XChaCha20-Poly1305 mode (see
+// xchacha20poly1305.DeterministicSeal) so that an unchanged file always
+// results in the same blob and isn't reported as modified. Note that this
+// reveals which versions of a file are equal.
+//
+// Encrypted files are the header (the magic and the version of the keystore
+// key, 0 for -key) followed by the sealed content which authenticates the
+// header. The smudge filter passes through files without the header and the
+// clean filter passes through files which are already encrypted.
+//
+// Given that git writes the file to stdin the keystore passphrase has to be
+// set via CTK_PASSPHRASE.
+//
+// Example:
+//
+//	$ git config filter.ctk.clean "ctk filter clean -name repo"
+//	$ git config filter.ctk.smudge "ctk filter smudge -name repo"
+//	$ git config filter.ctk.required true
+//	$ echo "secrets/** filter=ctk" >> .gitattributes
+func runFilter(args []string) error {
+	if len(args) < 1 {
+		return errors.New("usage: ctk filter <clean|smudge> [arguments]")
+	}
+
+	switch args[0] {
+	case "clean":
+		return runFilterClean(args[1:])
+	case "smudge":
+		return runFilterSmudge(args[1:])
+	default:
+		return fmt.Errorf("unknown subcommand %q", args[0])
+	}
+}
+
+// runFilterClean encrypts stdin with the (latest version of the) key and
+// writes it to stdout.
+func runFilterClean(args []string) error {
+	flags := flag.NewFlagSet("filter clean", flag.ContinueOnError)
+	keyFlags := registerKeyFlags(flags)
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	content, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	if bytes.HasPrefix(content, filterMagic) {
+		_, err = os.Stdout.Write(content)
+		return err
+	}
+
+	key, version, err := filterKey(keyFlags, 0)
+	if err != nil {
+		return err
+	}
+	defer clear(key[:])
+
+	header := binary.BigEndian.AppendUint32(bytes.Clone(filterMagic), uint32(version))
+	sealed := xchacha20poly1305.DeterministicSeal(key, content, header)
+
+	_, err = os.Stdout.Write(append(header, sealed...))
+
+	return err
+}
+
+// runFilterSmudge decrypts stdin with the version of the key it was encrypted
+// with and writes it to stdout.
+func runFilterSmudge(args []string) error {
+	flags := flag.NewFlagSet("filter smudge", flag.ContinueOnError)
+	keyFlags := registerKeyFlags(flags)
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	content, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.HasPrefix(content, filterMagic) {
+		_, err = os.Stdout.Write(content)
+		return err
+	}
+
+	if len(content) < filterHeaderSize {
+		return xchacha20poly1305.ErrInvalidSealedMessage
+	}
+
+	version := int(binary.BigEndian.Uint32(content[len(filterMagic):filterHeaderSize]))
+	// Files encrypted with -key have the version 0 (which would select the
+	// latest keystore key).
+	if version == 0 && *keyFlags.name != "" {
+		return errors.New("file was encrypted with -key (use -key instead of -name)")
+	}
+
+	key, _, err := filterKey(keyFlags, version)
+	if err != nil {
+		return err
+	}
+	defer clear(key[:])
+
+	plaintext, err := xchacha20poly1305.DeterministicOpen(key, content[filterHeaderSize:], content[:filterHeaderSize])
+	if err != nil {
+		return err
+	}
+	defer clear(plaintext)
+
+	_, err = os.Stdout.Write(plaintext)
+
+	return err
+}
+
+// filterKey returns the -key (and the version 0) or the version of the
+// keystore key (the latest one if the version is 0) and its version.
+func filterKey(k keyFlags, version int) ([32]byte, int, error) {
+	if *k.name == "" {
+		if version != 0 {
+			return [32]byte{}, 0, fmt.Errorf("file was encrypted with version %v of a keystore key (use -name)", version)
+		}
+
+		key, err := k.key()
+
+		return key, 0, err
+	}
+
+	if *k.hex != "" {
+		return [32]byte{}, 0, errors.New("-key and -name are mutually exclusive")
+	}
+
+	if _, ok := os.LookupEnv(passphraseEnv); !ok {
+		return [32]byte{}, 0, fmt.Errorf("%v needs to be set (stdin is used by git)", passphraseEnv)
+	}
+
+	ks, err := openKeystore(*k.keystore)
+	if err != nil {
+		return [32]byte{}, 0, err
+	}
+	defer ks.Close()
+
+	var key keystore.Key
+	if version == 0 {
+		key, err = ks.GetKey(*k.name)
+	} else {
+		key, err = ks.GetKeyVersion(*k.name, version)
+	}
+	if err != nil {
+		return [32]byte{}, 0, err
+	}
+
+	if len(key.Material) != 32 {
+		return [32]byte{}, 0, fmt.Errorf("key needs to be 32 bytes, got %v", len(key.Material))
+	}
+
+	return [32]byte(key.Material), key.Version, nil
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pmuens/ctk-go/ctk/argon2"
+	"github.com/pmuens/ctk-go/ctk/encoding"
+	"github.com/pmuens/ctk-go/ctk/keystore"
+)
+
+// passphraseEnv is the environment variable the keystore passphrase is read
+// from (the user is prompted if it isn't set).
+const passphraseEnv = "CTK_PASSPHRASE"
+
+// runKey runs the key subcommands.
+func runKey(args []string) error {
+	if len(args) < 1 {
+		return errors.New("usage: ctk key <add|list|export> [arguments]")
+	}
+
+	switch args[0] {
+	case "add":
+		return runKeyAdd(args[1:])
+	case "list":
+		return runKeyList(args[1:])
+	case "export":
+		return runKeyExport(args[1:])
+	default:
+		return fmt.Errorf("unknown subcommand %q", args[0])
+	}
+}
+
+// runKeyAdd adds a (generated or hex encoded) key to the keystore which is
+// created if it doesn't exist yet.
+func runKeyAdd(args []string) error {
+	flags := flag.NewFlagSet("key add", flag.ContinueOnError)
+	path := keystoreFlag(flags)
+	name := flags.String("name", "", "name of the key")
+	key := flags.String("key", "", "hex encoded key (generated if empty)")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if *name == "" {
+		return errors.New("missing -name")
+	}
+
+	material, err := encoding.Decode(*key, encoding.Hex)
+	if err != nil {
+		return err
+	}
+
+	// The passphrase of a new keystore is confirmed.
+	_, err = os.Stat(*path)
+	create := errors.Is(err, os.ErrNotExist)
+
+	var passphrase []byte
+	if create {
+		passphrase, err = readNewPassphrase()
+	} else {
+		passphrase, err = readPassphrase()
+	}
+	if err != nil {
+		return err
+	}
+
+	var ks *keystore.Keystore
+	if create {
+		err = os.MkdirAll(filepath.Dir(*path), 0o700)
+		if err != nil {
+			return err
+		}
+		ks, err = keystore.Create(*path, passphrase, argon2.DefaultParams, keystore.WithLogger(logger))
+	} else {
+		ks, err = keystore.Open(*path, passphrase, keystore.WithLogger(logger))
+	}
+	if err != nil {
+		return err
+	}
+	defer ks.Close()
+
+	return ks.AddKey(*name, material)
+}
+
+// runKeyList prints the names and latest versions of all keys in the keystore.
+func runKeyList(args []string) error {
+	flags := flag.NewFlagSet("key list", flag.ContinueOnError)
+	path := keystoreFlag(flags)
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	ks, err := openKeystore(*path)
+	if err != nil {
+		return err
+	}
+	defer ks.Close()
+
+	names, err := ks.Names()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		key, err := ks.GetKey(name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%v\tv%v\t%v\n", name, key.Version, key.Created.Format("2006-01-02T15:04:05Z"))
+	}
+
+	return nil
+}
+
+// runKeyExport prints the hex encoded (latest version of the) key.
+func runKeyExport(args []string) error {
+	flags := flag.NewFlagSet("key export", flag.ContinueOnError)
+	path := keystoreFlag(flags)
+	name := flags.String("name", "", "name of the key")
+	version := flags.Int("version", 0, "version of the key (latest if 0)")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	ks, err := openKeystore(*path)
+	if err != nil {
+		return err
+	}
+	defer ks.Close()
+
+	var key keystore.Key
+	if *version == 0 {
+		key, err = ks.GetKey(*name)
+	} else {
+		key, err = ks.GetKeyVersion(*name, *version)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(encoding.Encode(key.Material, encoding.Hex))
+
+	return nil
+}
+
+// keyFlags are the flags that select the key of an encryption command.
+type keyFlags struct {
+	// hex is the hex encoded key.
+	hex *string
+
+	// name is the name of the key in the keystore.
+	name *string
+
+	// keystore is the path of the keystore.
+	keystore *string
+}
+
+// registerKeyFlags registers the -key, -name and -keystore flags.
+func registerKeyFlags(flags *flag.FlagSet) keyFlags {
+	return keyFlags{
+		hex:      flags.String("key", "", "hex encoded 32 byte key"),
+		name:     flags.String("name", "", "name of the key in the keystore (instead of -key)"),
+		keystore: keystoreFlag(flags),
+	}
+}
+
+// key returns the hex encoded key or the (latest version of the) key from the
+// keystore.
+func (k keyFlags) key() ([32]byte, error) {
+	var material []byte
+
+	switch {
+	case *k.hex != "" && *k.name != "":
+		return [32]byte{}, errors.New("-key and -name are mutually exclusive")
+	case *k.hex != "":
+		decoded, err := encoding.Decode(*k.hex, encoding.Hex)
+		if err != nil {
+			return [32]byte{}, err
+		}
+		material = decoded
+	case *k.name != "":
+		ks, err := openKeystore(*k.keystore)
+		if err != nil {
+			return [32]byte{}, err
+		}
+		defer ks.Close()
+
+		key, err := ks.GetKey(*k.name)
+		if err != nil {
+			return [32]byte{}, err
+		}
+		material = key.Material
+	default:
+		return [32]byte{}, errors.New("missing -key or -name")
+	}
+
+	if len(material) != 32 {
+		return [32]byte{}, fmt.Errorf("key needs to be 32 bytes, got %v", len(material))
+	}
+
+	return [32]byte(material), nil
+}
+
+// keystoreFlag registers the -keystore flag which defaults to a file in the
+// user's config directory.
+func keystoreFlag(flags *flag.FlagSet) *string {
+	path := "ctk-keystore.json"
+
+	dir, err := os.UserConfigDir()
+	if err == nil {
+		path = filepath.Join(dir, "ctk", "keystore.json")
+	}
+
+	return flags.String("keystore", path, "path of the keystore file")
+}
+
+// openKeystore opens the keystore at path with the user's passphrase.
+func openKeystore(path string) (*keystore.Keystore, error) {
+	passphrase, err := readPassphrase()
+	if err != nil {
+		return nil, err
+	}
+
+	return keystore.Open(path, passphrase, keystore.WithLogger(logger))
+}
+
+// readPassphrase reads the passphrase from the environment or prompts for it
+// (without echoing it).
+func readPassphrase() ([]byte, error) {
+	if passphrase, ok := os.LookupEnv(passphraseEnv); ok {
+		return []byte(passphrase), nil
+	}
+
+	return readSecret("Passphrase: ")
+}
+
+// readNewPassphrase reads a passphrase that protects new data (e.g. a new
+// keystore) from the environment or prompts for it twice so that a typo doesn't
+// lock the user out.
+// Returns an error if the passphrases don't match.
+func readNewPassphrase() ([]byte, error) {
+	if passphrase, ok := os.LookupEnv(passphraseEnv); ok {
+		return []byte(passphrase), nil
+	}
+
+	passphrase, err := readSecret("New passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+
+	confirmation, err := readSecret("Confirm passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(passphrase, confirmation) {
+		return nil, errors.New("passphrases don't match")
+	}
+
+	return passphrase, nil
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pmuens/ctk-go/ctk/argon2"
+	"github.com/pmuens/ctk-go/ctk/multirecipient"
+	"github.com/pmuens/ctk-go/ctk/pkcs8"
+	"github.com/pmuens/ctk-go/ctk/x25519"
+)
+
+// stringsFlag is a flag that can be set multiple times.
+type stringsFlag []string
+
+// String returns the values of the flag.
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+// Set appends a value to the flag.
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// runRecipient runs the recipient subcommands.
+func runRecipient(args []string) error {
+	if len(args) < 1 {
+		return errors.New("usage: ctk recipient <keygen|encrypt|decrypt> [arguments]")
+	}
+
+	switch args[0] {
+	case "keygen":
+		return runRecipientKeygen(args[1:])
+	case "encrypt":
+		return runRecipientEncrypt(args[1:])
+	case "decrypt":
+		return runRecipientDecrypt(args[1:])
+	default:
+		return fmt.Errorf("unknown subcommand %q", args[0])
+	}
+}
+
+// runRecipientKeygen generates a new X25519 identity and writes it (with its
+// recipient as a comment) to a file (or stdout). With -pem the identity is
+// written as a PEM encoded PKCS#8 private key (without the comment) which can
+// be used with OpenSSL.
+func runRecipientKeygen(args []string) error {
+	flags := flag.NewFlagSet("recipient keygen", flag.ContinueOnError)
+	output := flags.String("o", "", "path of the identity file (stdout if empty)")
+	usePEM := flags.Bool("pem", false, "write the identity as a PEM encoded PKCS#8 private key")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	private, _, err := x25519.GenerateKey()
+	if err != nil {
+		return err
+	}
+	defer clear(private[:])
+
+	identity := multirecipient.NewX25519Identity(private)
+
+	out := os.Stdout
+	if *output != "" {
+		out, err = os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+
+	if *output != "" || *usePEM {
+		fmt.Fprintf(os.Stderr, "Public key: %v\n", identity.Recipient())
+	}
+
+	if *usePEM {
+		_, err = out.Write(pkcs8.MarshalX25519PrivateKey(private))
+	} else {
+		_, err = fmt.Fprintf(out, "# public key: %v\n%v\n", identity.Recipient(), identity)
+	}
+	if err != nil {
+		return err
+	}
+
+	if out != os.Stdout {
+		return out.Close()
+	}
+
+	return nil
+}
+
+// runRecipientEncrypt encrypts stdin for the recipients (and / or a passphrase)
+// and writes the multi-recipient container to a file (or stdout).
+func runRecipientEncrypt(args []string) error {
+	flags := flag.NewFlagSet("recipient encrypt", flag.ContinueOnError)
+	output := flags.String("o", "", "path of the encrypted file (stdout if empty)")
+	passphrase := flags.Bool("p", false, "encrypt with a passphrase (read from "+passphraseEnv+" or prompted)")
+
+	var recipientFlags, recipientFiles stringsFlag
+	flags.Var(&recipientFlags, "r", "recipient (ctk1... or an SSH public key), can be repeated")
+	flags.Var(&recipientFiles, "R", "path of a recipients file (e.g. ~/.ssh/id_ed25519.pub), can be repeated")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	var recipients []multirecipient.Recipient
+
+	for _, r := range recipientFlags {
+		recipient, err := multirecipient.ParseRecipient(r)
+		if err != nil {
+			return fmt.Errorf("%q: %w", r, err)
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	for _, path := range recipientFiles {
+		parsed, err := parseFile(path, multirecipient.ParseRecipients)
+		if err != nil {
+			return err
+		}
+		recipients = append(recipients, parsed...)
+	}
+
+	if *passphrase {
+		p, err := readNewPassphrase()
+		if err != nil {
+			return err
+		}
+
+		recipient, err := multirecipient.NewPassphraseRecipient(p, argon2.DefaultParams)
+		if err != nil {
+			return err
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	if len(recipients) == 0 {
+		return errors.New("usage: ctk recipient encrypt [-r recipient]... [-R file]... [-p] [-o output]")
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		out, err = os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+
+	w, err := multirecipient.Encrypt(out, recipients...)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	if out != os.Stdout {
+		return out.Close()
+	}
+
+	return nil
+}
+
+// runRecipientDecrypt decrypts a multi-recipient container (read from a file or
+// stdin) with the identities (and / or a passphrase) and writes the plaintext
+// to stdout.
+func runRecipientDecrypt(args []string) error {
+	flags := flag.NewFlagSet("recipient decrypt", flag.ContinueOnError)
+	passphrase := flags.Bool("p", false, "decrypt with a passphrase (read from "+passphraseEnv+" or prompted)")
+
+	var identityFiles stringsFlag
+	flags.Var(&identityFiles, "i", "path of an identity file (e.g. ~/.ssh/id_ed25519), can be repeated")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() > 1 {
+		return errors.New("usage: ctk recipient decrypt [-i file]... [-p] [file]")
+	}
+
+	var identities []multirecipient.Identity
+
+	for _, path := range identityFiles {
+		parsed, err := parseFile(path, multirecipient.ParseIdentities)
+		if err != nil {
+			return err
+		}
+		identities = append(identities, parsed...)
+	}
+
+	if *passphrase {
+		p, err := readPassphrase()
+		if err != nil {
+			return err
+		}
+		identities = append(identities, multirecipient.NewPassphraseIdentity(p))
+	}
+
+	if len(identities) == 0 {
+		return errors.New("no identities (use -i or -p)")
+	}
+
+	in, name := os.Stdin, "stdin"
+	if flags.NArg() == 1 {
+		in, err = os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		name = flags.Arg(0)
+	}
+
+	r, err := multirecipient.Decrypt(in, identities...)
+	if err != nil {
+		return fmt.Errorf("%v: %w", name, err)
+	}
+
+	// Errors of the payload name the chunk that was rejected (see
+	// stream.ChunkError).
+	_, err = io.Copy(os.Stdout, r)
+	if err != nil {
+		return fmt.Errorf("%v: %w", name, err)
+	}
+
+	return nil
+}
+
+// parseFile opens the file at path and parses it with parse.
+func parseFile[T any](path string, parse func(io.Reader) ([]T, error)) ([]T, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	parsed, err := parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+
+	return parsed, nil
+}
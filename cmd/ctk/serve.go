@@ -0,0 +1,377 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/pmuens/ctk-go/ctk/encoding"
+	"github.com/pmuens/ctk-go/ctk/metrics"
+	"github.com/pmuens/ctk-go/ctk/random"
+	"github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
+)
+
+// tokenEnv is the environment variable the auth token of the service is read
+// from (a random token is generated and printed if it isn't set).
+const tokenEnv = "CTK_TOKEN"
+
+// serviceLayer is the layer name of the service's metrics.
+const serviceLayer = "service"
+
+// maxRequestSize is the maximum size (in bytes) of a request body.
+const maxRequestSize = 16 << 20
+
+// serviceNonceSize is the size (in bytes) of the nonce that's prepended to the
+// ciphertexts of the service.
+const serviceNonceSize = 24
+
+// runServe runs a local REST service which encrypts and decrypts with
+// XChaCha20-Poly1305 and generates keys so that services that aren't written
+// in Go can use the toolkit during development. It listens on a unix socket
+// (or on a TCP address via -listen) and requires the auth token as bearer
+// token in every request.
+//
+// The endpoints take and return JSON objects with base64 encoded fields. The
+// key is optional if the service was started with -key or -name:
+//
+//	POST /v1/keygen  {}                                  -> {"key"}
+//	POST /v1/encrypt {"key", "plaintext", "aad"}         -> {"ciphertext"}
+//	POST /v1/decrypt {"key", "ciphertext", "aad"}        -> {"plaintext"}
+//	GET  /metrics                                        -> Prometheus metrics
+//
+// Ciphertexts are the random nonce (24 bytes) followed by the encrypted
+// plaintext and the tag.
+//
+// Example:
+//
+//	$ CTK_TOKEN=secret ctk serve -socket "$XDG_RUNTIME_DIR/ctk.sock"
+//	$ curl --unix-socket "$XDG_RUNTIME_DIR/ctk.sock" -H "Authorization: Bearer secret" -X POST localhost/v1/keygen
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	keyFlags := registerKeyFlags(flags)
+	socket := flags.String("socket", defaultSocket(), "path of the unix socket to listen on")
+	listen := flags.String("listen", "", "TCP address to listen on instead of the unix socket (e.g. 127.0.0.1:8080)")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	var key *[32]byte
+	if *keyFlags.hex != "" || *keyFlags.name != "" {
+		k, err := keyFlags.key()
+		if err != nil {
+			return err
+		}
+		key = &k
+	}
+
+	token, ok := os.LookupEnv(tokenEnv)
+	if !ok || token == "" {
+		generated, err := random.Bytes(32)
+		if err != nil {
+			return err
+		}
+		token = encoding.Encode(generated, encoding.Hex)
+		fmt.Fprintf(os.Stderr, "generated auth token (set %v to choose one): %v\n", tokenEnv, token)
+	}
+
+	ln, err := listenService(*socket, *listen)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{Handler: newService(key, token)}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	fmt.Fprintf(os.Stderr, "serving on %v\n", ln.Addr())
+
+	err = server.Serve(ln)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// defaultSocket returns the default path of the unix socket which is in the
+// per-user runtime directory ($XDG_RUNTIME_DIR) or in the user's cache
+// directory if it isn't set (rather than in the shared temporary directory).
+func defaultSocket() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "ctk.sock")
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "ctk.sock"
+	}
+
+	return filepath.Join(dir, "ctk", "ctk.sock")
+}
+
+// listenService listens on the TCP address if it's set and on the unix socket
+// otherwise. The socket is only accessible by the current user and a stale
+// socket (e.g. of a crashed service) is replaced.
+//
+// The socket is created in a new directory which only the current user can
+// access and is moved into place once its permissions are restricted so that
+// nobody else can connect in between (independent of the umask).
+func listenService(socket string, address string) (net.Listener, error) {
+	if address != "" {
+		return net.Listen("tcp", address)
+	}
+
+	info, err := os.Lstat(socket)
+	if err == nil && info.Mode()&os.ModeSocket == 0 {
+		return nil, fmt.Errorf("%v exists and isn't a socket", socket)
+	}
+
+	dir := filepath.Dir(socket)
+	err = os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, err
+	}
+
+	// MkdirTemp creates the directory with the permissions 0700.
+	private, err := os.MkdirTemp(dir, ".ctk-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(private)
+
+	path := filepath.Join(private, "ctk.sock")
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	// The socket is removed from its final path when the listener is closed.
+	ln.SetUnlinkOnClose(false)
+
+	err = os.Chmod(path, 0o600)
+	if err == nil {
+		// Replaces a stale socket atomically.
+		err = os.Rename(path, socket)
+	}
+	if err != nil {
+		ln.Close()
+		return nil, err
+	}
+
+	return &socketListener{UnixListener: ln, path: socket}, nil
+}
+
+// socketListener is a listener on a unix socket that was moved to path after
+// it was created.
+type socketListener struct {
+	*net.UnixListener
+
+	// path is the path of the socket.
+	path string
+}
+
+// Addr returns the listener's address (the path of the socket).
+func (l *socketListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: l.path, Net: "unix"}
+}
+
+// Close closes the listener and removes the socket.
+func (l *socketListener) Close() error {
+	err := l.UnixListener.Close()
+	os.Remove(l.path)
+
+	return err
+}
+
+// serviceRequest is the body of a request to the service.
+type serviceRequest struct {
+	// Key is the key (the service's key is used if it's empty).
+	Key []byte `json:"key"`
+
+	// Plaintext is the plaintext to encrypt.
+	Plaintext []byte `json:"plaintext"`
+
+	// Ciphertext is the ciphertext to decrypt.
+	Ciphertext []byte `json:"ciphertext"`
+
+	// AAD is the additional authenticated data.
+	AAD []byte `json:"aad"`
+}
+
+// serviceResponse is the body of a response of the service.
+type serviceResponse struct {
+	// Key is the generated key.
+	Key []byte `json:"key,omitempty"`
+
+	// Plaintext is the decrypted plaintext.
+	Plaintext []byte `json:"plaintext,omitempty"`
+
+	// Ciphertext is the encrypted plaintext.
+	Ciphertext []byte `json:"ciphertext,omitempty"`
+
+	// Error describes why the request failed.
+	Error string `json:"error,omitempty"`
+}
+
+// service handles the requests of the service.
+type service struct {
+	// key is the key that's used if a request doesn't contain a key (nil if
+	// there's none).
+	key *[32]byte
+
+	// token is the auth token the requests need to contain.
+	token string
+
+	// metrics collects the metrics of the service.
+	metrics *metrics.Prometheus
+}
+
+// newService returns the handler of the service.
+func newService(key *[32]byte, token string) http.Handler {
+	s := &service{key: key, token: token, metrics: metrics.NewPrometheus()}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", s.serveMetrics)
+	mux.HandleFunc("POST /v1/keygen", s.handle(s.keygen))
+	mux.HandleFunc("POST /v1/encrypt", s.handle(s.encrypt))
+	mux.HandleFunc("POST /v1/decrypt", s.handle(s.decrypt))
+
+	return mux
+}
+
+// handle wraps the endpoint with the authentication and the decoding of the
+// request and the encoding of the response.
+func (s *service) handle(endpoint func(req serviceRequest) (serviceResponse, int)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if !s.authorized(r) {
+			logger.LogAttrs(r.Context(), slog.LevelWarn, "request rejected", slog.String("path", r.URL.Path), slog.String("error", "invalid auth token"))
+			respond(w, serviceResponse{Error: "invalid auth token"}, http.StatusUnauthorized)
+			return
+		}
+
+		var req serviceRequest
+
+		// An empty body is an empty request (e.g. for keygen).
+		err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&req)
+		if err != nil && !errors.Is(err, io.EOF) {
+			respond(w, serviceResponse{Error: "invalid request: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+
+		res, status := endpoint(req)
+		logger.LogAttrs(r.Context(), slog.LevelInfo, "request", slog.String("path", r.URL.Path), slog.Int("status", status))
+		respond(w, res, status)
+	}
+}
+
+// serveMetrics writes the metrics of the service in the Prometheus text format.
+func (s *service) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		logger.LogAttrs(r.Context(), slog.LevelWarn, "request rejected", slog.String("path", r.URL.Path), slog.String("error", "invalid auth token"))
+		w.Header().Set("Content-Type", "application/json")
+		respond(w, serviceResponse{Error: "invalid auth token"}, http.StatusUnauthorized)
+		return
+	}
+
+	s.metrics.ServeHTTP(w, r)
+}
+
+// authorized returns whether the request contains the auth token.
+func (s *service) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// keygen generates a random key.
+func (s *service) keygen(req serviceRequest) (serviceResponse, int) {
+	key, err := random.Key()
+	if err != nil {
+		return serviceResponse{Error: err.Error()}, http.StatusInternalServerError
+	}
+
+	return serviceResponse{Key: key[:]}, http.StatusOK
+}
+
+// encrypt encrypts the plaintext with a random nonce.
+func (s *service) encrypt(req serviceRequest) (serviceResponse, int) {
+	key, err := s.requestKey(req)
+	if err != nil {
+		return serviceResponse{Error: err.Error()}, http.StatusBadRequest
+	}
+
+	nonce, err := random.XNonce()
+	if err != nil {
+		return serviceResponse{Error: err.Error()}, http.StatusInternalServerError
+	}
+
+	ciphertext := xchacha20poly1305.NewPool(key).Seal(nonce[:], nonce[:], req.Plaintext, req.AAD)
+	s.metrics.Sealed(serviceLayer, len(req.Plaintext))
+
+	return serviceResponse{Ciphertext: ciphertext}, http.StatusOK
+}
+
+// decrypt decrypts the ciphertext.
+func (s *service) decrypt(req serviceRequest) (serviceResponse, int) {
+	key, err := s.requestKey(req)
+	if err != nil {
+		return serviceResponse{Error: err.Error()}, http.StatusBadRequest
+	}
+
+	// Malformed and forged ciphertexts get the same response so that clients
+	// can't tell which check rejected a ciphertext.
+	var plaintext []byte
+	err = xchacha20poly1305.ErrInvalidTag
+	if len(req.Ciphertext) >= serviceNonceSize {
+		nonce := req.Ciphertext[:serviceNonceSize]
+		plaintext, err = xchacha20poly1305.NewPool(key).Open(nil, nonce, req.Ciphertext[serviceNonceSize:], req.AAD)
+	}
+	if err != nil {
+		s.metrics.AuthFailed(serviceLayer)
+		return serviceResponse{Error: "decryption failed"}, http.StatusBadRequest
+	}
+
+	s.metrics.Opened(serviceLayer, len(plaintext))
+
+	return serviceResponse{Plaintext: plaintext}, http.StatusOK
+}
+
+// requestKey returns the key of the request or the service's key.
+func (s *service) requestKey(req serviceRequest) ([32]byte, error) {
+	switch {
+	case len(req.Key) == 32:
+		return [32]byte(req.Key), nil
+	case len(req.Key) != 0:
+		return [32]byte{}, fmt.Errorf("key needs to be 32 bytes, got %v", len(req.Key))
+	case s.key != nil:
+		return *s.key, nil
+	default:
+		return [32]byte{}, errors.New("missing key (the service was started without -key or -name)")
+	}
+}
+
+// respond writes the response as JSON.
+func respond(w http.ResponseWriter, res serviceResponse, status int) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pmuens/ctk-go/ctk/encoding"
+	"github.com/pmuens/ctk-go/ctk/shamir"
+)
+
+// runShamir runs the shamir subcommands.
+func runShamir(args []string) error {
+	if len(args) < 1 {
+		return errors.New("usage: ctk shamir <split|combine> [arguments]")
+	}
+
+	switch args[0] {
+	case "split":
+		return runShamirSplit(args[1:])
+	case "combine":
+		return runShamirCombine(args[1:])
+	default:
+		return fmt.Errorf("unknown subcommand %q", args[0])
+	}
+}
+
+// runShamirSplit splits a hex encoded key (read from stdin if not provided via
+// a flag) into hex encoded shares, one per line.
+func runShamirSplit(args []string) error {
+	flags := flag.NewFlagSet("shamir split", flag.ContinueOnError)
+	n := flags.Int("n", 5, "number of shares")
+	threshold := flags.Int("threshold", 3, "number of shares required to combine")
+	key := flags.String("key", "", "hex encoded key (read from stdin if empty)")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if *key == "" {
+		lines, err := readLines()
+		if err != nil {
+			return err
+		}
+		if len(lines) != 1 {
+			return errors.New("expected a single hex encoded key on stdin")
+		}
+		*key = lines[0]
+	}
+
+	secret, err := encoding.Decode(*key, encoding.Hex)
+	if err != nil {
+		return err
+	}
+
+	shares, err := shamir.Split(secret, *n, *threshold)
+	if err != nil {
+		return err
+	}
+
+	for _, share := range shares {
+		fmt.Println(encoding.Encode(share, encoding.Hex))
+	}
+
+	return nil
+}
+
+// runShamirCombine combines hex encoded shares (read from stdin, one per line,
+// if not provided as arguments) and prints the hex encoded key.
+func runShamirCombine(args []string) error {
+	flags := flag.NewFlagSet("shamir combine", flag.ContinueOnError)
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	encoded := flags.Args()
+	if len(encoded) == 0 {
+		encoded, err = readLines()
+		if err != nil {
+			return err
+		}
+	}
+
+	shares := make([][]byte, 0, len(encoded))
+	for _, e := range encoded {
+		share, err := encoding.Decode(e, encoding.Hex)
+		if err != nil {
+			return err
+		}
+		shares = append(shares, share)
+	}
+
+	secret, err := shamir.Combine(shares)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(encoding.Encode(secret, encoding.Hex))
+
+	return nil
+}
+
+// readLines reads all non-empty, trimmed lines from stdin.
+func readLines() ([]string, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines, scanner.Err()
+}
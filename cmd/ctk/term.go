@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// errNoTerminal is returned if stdin isn't a terminal whose echo can be
+// disabled.
+var errNoTerminal = errors.New("stdin isn't a terminal")
+
+// readSecret prompts for a secret on stderr and reads it from stdin. The input
+// isn't echoed if stdin is a terminal.
+func readSecret(prompt string) ([]byte, error) {
+	fmt.Fprint(os.Stderr, prompt)
+
+	restore, err := disableEcho(os.Stdin)
+	if err == nil {
+		defer func() {
+			restore()
+			// The newline the user typed wasn't echoed.
+			fmt.Fprintln(os.Stderr)
+		}()
+	}
+
+	return readLine(os.Stdin)
+}
+
+// readLine reads a line (without the line ending). The line is read byte by
+// byte so that nothing after it is consumed (e.g. the next line of a piped
+// input that's read by the next prompt).
+func readLine(f *os.File) ([]byte, error) {
+	var line []byte
+	b := make([]byte, 1)
+
+	for {
+		n, err := f.Read(b)
+		if n == 1 && b[0] == '\n' {
+			break
+		}
+		if n == 1 {
+			line = append(line, b[0])
+		}
+		if err != nil {
+			if len(line) > 0 {
+				break
+			}
+			return nil, err
+		}
+	}
+
+	return bytes.TrimRight(line, "\r"), nil
+}
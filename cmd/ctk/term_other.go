@@ -0,0 +1,11 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+
+package main
+
+import "os"
+
+// disableEcho returns errNoTerminal as the echo can't be disabled on the
+// platform (the input is read with echo).
+func disableEcho(f *os.File) (func(), error) {
+	return nil, errNoTerminal
+}
@@ -0,0 +1,41 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// disableEcho turns off the echo of the terminal (like stty -echo) and returns
+// a function which restores its previous state.
+// Returns errNoTerminal if the file isn't a terminal.
+func disableEcho(f *os.File) (func(), error) {
+	fd := f.Fd()
+
+	var state syscall.Termios
+	if ioctl(fd, ioctlGetTermios, &state) != nil {
+		return nil, errNoTerminal
+	}
+
+	noEcho := state
+	noEcho.Lflag &^= syscall.ECHO
+	if ioctl(fd, ioctlSetTermios, &noEcho) != nil {
+		return nil, errNoTerminal
+	}
+
+	return func() {
+		ioctl(fd, ioctlSetTermios, &state)
+	}, nil
+}
+
+// ioctl runs the terminal ioctl request on the file descriptor.
+func ioctl(fd uintptr, request uintptr, state *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(unsafe.Pointer(state)))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// enableEchoInput is the console mode flag which echoes the input.
+const enableEchoInput = 0x0004
+
+// setConsoleMode is the SetConsoleMode function of kernel32.dll (which the
+// syscall package doesn't provide).
+var setConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// disableEcho turns off the echo of the console and returns a function which
+// restores its previous mode.
+// Returns errNoTerminal if the file isn't a console.
+func disableEcho(f *os.File) (func(), error) {
+	handle := syscall.Handle(f.Fd())
+
+	var mode uint32
+	if syscall.GetConsoleMode(handle, &mode) != nil {
+		return nil, errNoTerminal
+	}
+
+	ok, _, _ := setConsoleMode.Call(uintptr(handle), uintptr(mode&^enableEchoInput))
+	if ok == 0 {
+		return nil, errNoTerminal
+	}
+
+	return func() {
+		setConsoleMode.Call(uintptr(handle), uintptr(mode))
+	}, nil
+}
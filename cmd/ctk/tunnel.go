@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+
+	"github.com/pmuens/ctk-go/ctk/secretstream"
+)
+
+// runTunnel runs an encrypted TCP proxy. In client mode it accepts plaintext
+// connections and forwards them encrypted to the target (which is usually a
+// tunnel in server mode). In server mode it accepts encrypted connections and
+// forwards them decrypted to the target.
+//
+// Example (encrypting the traffic between two hosts):
+//
+//	host-a$ ctk tunnel -mode client -listen :9000 -target host-b:9001 -key ...
+//	host-b$ ctk tunnel -mode server -listen :9001 -target localhost:443 -key ...
+func runTunnel(args []string) error {
+	flags := flag.NewFlagSet("tunnel", flag.ContinueOnError)
+	keyFlags := registerKeyFlags(flags)
+	listen := flags.String("listen", "", "address to accept connections on (e.g. :9000)")
+	target := flags.String("target", "", "address to forward connections to (e.g. host:443)")
+	mode := flags.String("mode", "client", "client (encrypt towards the target) or server (decrypt towards the target)")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if *listen == "" || *target == "" {
+		return errors.New("missing -listen or -target")
+	}
+
+	if *mode != "client" && *mode != "server" {
+		return fmt.Errorf("unknown mode %q", *mode)
+	}
+
+	key, err := keyFlags.key()
+	if err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+
+	// An interrupt stops accepting connections and tears down the forwarded
+	// ones.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	context.AfterFunc(ctx, func() {
+		ln.Close()
+	})
+
+	fmt.Fprintf(os.Stderr, "forwarding %v to %v (%v mode)\n", ln.Addr(), *target, *mode)
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		go func() {
+			err := forward(ctx, conn, *target, key, *mode == "client")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v: %v\n", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// forward connects to the target and copies the data between the accepted
+// connection and the target in both directions. The encrypted side is the
+// target if client is set and the accepted connection otherwise. Both
+// connections are closed once the context is done.
+func forward(ctx context.Context, conn net.Conn, target string, key [32]byte, client bool) error {
+	defer conn.Close()
+
+	var d net.Dialer
+	targetConn, err := d.DialContext(ctx, "tcp", target)
+	if err != nil {
+		return err
+	}
+	defer targetConn.Close()
+
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+		targetConn.Close()
+	})
+	defer stop()
+
+	var plain net.Conn
+	var secure *secretstream.Conn
+
+	if client {
+		plain = conn
+		secure, err = secretstream.Client(targetConn, key)
+	} else {
+		plain = targetConn
+		secure, err = secretstream.Server(conn, key)
+	}
+	if err != nil {
+		return err
+	}
+
+	errs := make(chan error, 2)
+
+	go func() {
+		errs <- pipe(secure, plain)
+	}()
+
+	go func() {
+		errs <- pipe(plain, secure)
+	}()
+
+	// Both directions need to finish as a direction can still carry data after
+	// the other one was closed (e.g. a response after the request). An error
+	// (e.g. a frame that can't be authenticated) tears down both directions.
+	first := <-errs
+	if first != nil {
+		conn.Close()
+		targetConn.Close()
+	}
+
+	return errors.Join(first, <-errs)
+}
+
+// pipe copies the data from src to dst and closes dst for writing once src
+// reached its end so that the peer sees the end of the direction.
+func pipe(dst io.Writer, src io.Reader) error {
+	_, err := io.Copy(dst, src)
+	if err != nil {
+		return err
+	}
+
+	if cw, ok := dst.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+// Package aad serializes structured context (e.g. tenant IDs or record IDs) into
+// canonical bytes that can be used as additional authenticated data (AAD).
+//
+// Binding a ciphertext to its context via AAD ensures that it can't be moved to
+// another context (e.g. copied to a different tenant's record). Ad-hoc encodings
+// such as joining values with a separator are prone to collisions
+// ("a:b" + "c" vs. "a" + "b:c") which is why every key and value is length
+// prefixed.
+//
+// The encoding is:
+//
+//	count (4 bytes, big endian)
+//	for every entry (sorted by key):
+//	  len(key) (4 bytes, big endian) | key | len(value) (4 bytes, big endian) | value
+package aad
+
+import (
+	"encoding/binary"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+const (
+	// ErrUnsupportedType is returned if a value or struct field can't be encoded.
+	ErrUnsupportedType = Error("unsupported type")
+
+	// ErrDuplicateKey is returned if multiple struct fields have the same name.
+	ErrDuplicateKey = Error("duplicate key")
+)
+
+// TagName is the name of the struct tag that's used to rename or skip ("-")
+// struct fields.
+const TagName = "aad"
+
+// Encode serializes the context into canonical bytes.
+// The result is independent of the map's iteration order.
+func Encode(context map[string]string) []byte {
+	keys := slices.Sorted(maps.Keys(context))
+
+	size := 4
+	for _, k := range keys {
+		size += 4 + len(k) + 4 + len(context[k])
+	}
+
+	result := make([]byte, 0, size)
+	result = binary.BigEndian.AppendUint32(result, uint32(len(keys)))
+
+	for _, k := range keys {
+		result = appendField(result, k)
+		result = appendField(result, context[k])
+	}
+
+	return result
+}
+
+// EncodeStruct serializes the exported fields of the struct (or pointer to a
+// struct) into canonical bytes by turning it into a context in which the field
+// names are the keys.
+// Fields can be renamed or skipped ("-") via the "aad" struct tag.
+// Supported field types are strings, byte slices, booleans and integers.
+func EncodeStruct(v any) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return []byte{}, ErrUnsupportedType
+	}
+
+	context := make(map[string]string)
+
+	for i := range rv.NumField() {
+		field := rv.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		if tag, ok := field.Tag.Lookup(TagName); ok {
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+
+		if _, ok := context[name]; ok {
+			return []byte{}, ErrDuplicateKey
+		}
+
+		value, err := format(rv.Field(i))
+		if err != nil {
+			return []byte{}, err
+		}
+
+		context[name] = value
+	}
+
+	return Encode(context), nil
+}
+
+// format turns the value into its string representation.
+func format(v reflect.Value) (string, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes()), nil
+		}
+	}
+
+	return "", ErrUnsupportedType
+}
+
+// appendField appends the length prefixed field to the buffer.
+func appendField(buf []byte, field string) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(field)))
+	return append(buf, field...)
+}
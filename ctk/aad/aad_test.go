@@ -0,0 +1,135 @@
+package aad_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/aad"
+)
+
+func TestEncode(t *testing.T) {
+	t.Run("Canonical Encoding", func(t *testing.T) {
+		t.Parallel()
+
+		context := map[string]string{"tenant": "acme", "id": "42"}
+
+		got := aad.Encode(context)
+		want := []byte{
+			0x00, 0x00, 0x00, 0x02,
+			0x00, 0x00, 0x00, 0x02, 'i', 'd',
+			0x00, 0x00, 0x00, 0x02, '4', '2',
+			0x00, 0x00, 0x00, 0x06, 't', 'e', 'n', 'a', 'n', 't',
+			0x00, 0x00, 0x00, 0x04, 'a', 'c', 'm', 'e',
+		}
+
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Empty Context", func(t *testing.T) {
+		t.Parallel()
+
+		got := aad.Encode(nil)
+		want := []byte{0x00, 0x00, 0x00, 0x00}
+
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("No Collisions", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]struct {
+			a map[string]string
+			b map[string]string
+		}{
+			"Shifted Separator": {
+				a: map[string]string{"a:b": "c"},
+				b: map[string]string{"a": "b:c"},
+			},
+			"Merged Entries": {
+				a: map[string]string{"a": "b", "c": "d"},
+				b: map[string]string{"a": "b\x00\x00\x00\x01c\x00\x00\x00\x01d"},
+			},
+		}
+
+		for name, tc := range tt {
+			if slices.Equal(aad.Encode(tc.a), aad.Encode(tc.b)) {
+				t.Errorf("%v: want different encodings, got equal ones", name)
+			}
+		}
+	})
+}
+
+func TestEncodeStruct(t *testing.T) {
+	t.Run("Struct", func(t *testing.T) {
+		t.Parallel()
+
+		type record struct {
+			Tenant   string `aad:"tenant"`
+			ID       uint64 `aad:"id"`
+			Deleted  bool
+			Checksum []byte
+			Comment  string `aad:"-"`
+			internal string
+		}
+
+		r := record{
+			Tenant:   "acme",
+			ID:       42,
+			Deleted:  false,
+			Checksum: []byte{0x01, 0x02},
+			Comment:  "ignored",
+			internal: "ignored",
+		}
+
+		got, err := aad.EncodeStruct(&r)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		want := aad.Encode(map[string]string{
+			"tenant":   "acme",
+			"id":       "42",
+			"Deleted":  "false",
+			"Checksum": "\x01\x02",
+		})
+
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		type unsupported struct {
+			Values []string
+		}
+
+		type duplicate struct {
+			A string `aad:"id"`
+			B string `aad:"id"`
+		}
+
+		tt := map[string]struct {
+			v    any
+			want error
+		}{
+			"Not A Struct":      {v: "acme", want: aad.ErrUnsupportedType},
+			"Unsupported Field": {v: unsupported{}, want: aad.ErrUnsupportedType},
+			"Duplicate Key":     {v: duplicate{}, want: aad.ErrDuplicateKey},
+		}
+
+		for name, tc := range tt {
+			_, err := aad.EncodeStruct(tc.v)
+
+			if !errors.Is(err, tc.want) {
+				t.Errorf("%v: want error %v, got %v", name, tc.want, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,363 @@
+package ctk
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/pmuens/ctk-go/ctk/chacha20poly1305"
+	"github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
+)
+
+const (
+	// ErrUnknownAlgorithm is returned if no algorithm is registered under a name.
+	ErrUnknownAlgorithm = Error("unknown algorithm")
+
+	// ErrAlgorithmExists is returned if an algorithm is registered under a name
+	// that's already taken.
+	ErrAlgorithmExists = Error("algorithm already registered")
+
+	// ErrInvalidAlgorithm is returned if an algorithm without a name or
+	// constructor is registered.
+	ErrInvalidAlgorithm = Error("invalid algorithm")
+
+	// ErrInvalidNonceSize is returned if a nonce doesn't have the algorithm's
+	// nonce size.
+	ErrInvalidNonceSize = Error("invalid nonce size")
+
+	// ErrDecryption is returned if a ciphertext can't be authenticated.
+	ErrDecryption = Error("decryption failed")
+
+	// ErrNotApproved is returned if an algorithm is looked up that's not
+	// allowed by the policy.
+	ErrNotApproved = Error("algorithm not approved")
+)
+
+// Names of the built-in algorithms.
+const (
+	// ChaCha20Poly1305 is ChaCha20-Poly1305 as specified in RFC 8439.
+	ChaCha20Poly1305 = "chacha20poly1305"
+
+	// XChaCha20Poly1305 is XChaCha20-Poly1305 as specified in
+	// draft-irtf-cfrg-xchacha.
+	XChaCha20Poly1305 = "xchacha20poly1305"
+
+	// AES256GCM is AES-256 in Galois/Counter Mode as specified in NIST SP
+	// 800-38D (backed by crypto/aes and crypto/cipher).
+	AES256GCM = "aes256gcm"
+)
+
+// Policy determines which of the registered algorithms can be used.
+type Policy int
+
+const (
+	// PolicyAll allows all registered algorithms (the default).
+	PolicyAll Policy = iota
+
+	// PolicyApprovedOnly only allows algorithms whose compliance metadata marks
+	// them as approved (e.g. for deployments that must only use FIPS 140
+	// approved algorithms).
+	PolicyApprovedOnly
+)
+
+// AEAD is an authenticated encryption with associated data algorithm that's
+// keyed with a fixed key. Implementations need to be safe for concurrent use.
+type AEAD interface {
+	// NonceSize returns the size (in bytes) of the nonce.
+	NonceSize() int
+
+	// Overhead returns the number of bytes a ciphertext is longer than its
+	// plaintext.
+	Overhead() int
+
+	// Seal encrypts and authenticates the plaintext and authenticates the aad.
+	// The ciphertext followed by the tag is appended to dst (like the Seal
+	// method of crypto/cipher.AEAD) so that buffers can be reused.
+	// Returns ErrInvalidNonceSize if the nonce doesn't have NonceSize bytes.
+	Seal(dst []byte, nonce []byte, plaintext []byte, aad []byte) ([]byte, error)
+
+	// Open authenticates and decrypts a ciphertext that was created via Seal
+	// and appends the plaintext to dst.
+	// Returns ErrInvalidNonceSize if the nonce doesn't have NonceSize bytes and
+	// ErrDecryption if the ciphertext can't be authenticated.
+	Open(dst []byte, nonce []byte, ciphertext []byte, aad []byte) ([]byte, error)
+}
+
+// Algorithm describes an AEAD algorithm of the registry.
+type Algorithm struct {
+	// Name is the unique name the algorithm is registered under.
+	Name string
+
+	// New creates an instance of the algorithm that's keyed with the key.
+	New func(key Key) AEAD
+
+	// Metadata describes the algorithm's parameters and security properties.
+	Metadata Metadata
+
+	// Compliance describes the standards the algorithm complies with.
+	Compliance Compliance
+}
+
+// Metadata describes the parameters and security properties of an algorithm
+// (e.g. to validate configuration or to generate user interfaces). Zero values
+// mean that the property is unknown. The metadata of registered algorithms is
+// provided by the caller of Register and isn't verified.
+type Metadata struct {
+	// KeySize is the size (in bytes) of the key.
+	KeySize int
+
+	// NonceSize is the size (in bytes) of the nonce.
+	NonceSize int
+
+	// TagSize is the size (in bytes) of the tag.
+	TagSize int
+
+	// MaxPlaintext is the maximum size (in bytes) of a plaintext that can be
+	// sealed with a single nonce.
+	MaxPlaintext uint64
+
+	// Notes are security notes (e.g. how many messages can be sealed with
+	// random nonces under the same key).
+	Notes string
+}
+
+// Compliance is the compliance metadata of an algorithm. The metadata of
+// registered algorithms is provided by the caller of Register and isn't
+// verified.
+type Compliance struct {
+	// Specification is the document that specifies the algorithm (e.g.
+	// "RFC 8439").
+	Specification string
+
+	// Approved indicates whether the algorithm is approved by FIPS 140-3 (i.e.
+	// listed in NIST SP 800-140C). Only approved algorithms can be used if the
+	// policy is PolicyApprovedOnly.
+	Approved bool
+}
+
+// registry holds the registered algorithms by name and the policy that
+// determines which of them can be used.
+var registry = struct {
+	sync.RWMutex
+	algorithms map[string]Algorithm
+	policy     Policy
+}{
+	algorithms: map[string]Algorithm{
+		ChaCha20Poly1305: {
+			Name: ChaCha20Poly1305,
+			New:  newChaCha20Poly1305,
+			Metadata: Metadata{
+				KeySize:      32,
+				NonceSize:    12,
+				TagSize:      16,
+				MaxPlaintext: (1<<32 - 1) * 64,
+				Notes:        "Random nonces should only be used for up to 2^32 messages per key, use counter nonces or XChaCha20-Poly1305 otherwise.",
+			},
+			Compliance: Compliance{Specification: "RFC 8439"},
+		},
+		XChaCha20Poly1305: {
+			Name: XChaCha20Poly1305,
+			New:  newXChaCha20Poly1305,
+			Metadata: Metadata{
+				KeySize:      32,
+				NonceSize:    24,
+				TagSize:      16,
+				MaxPlaintext: (1<<32 - 1) * 64,
+				Notes:        "Nonces are large enough to be generated randomly for a practically unlimited number of messages per key.",
+			},
+			Compliance: Compliance{Specification: "draft-irtf-cfrg-xchacha"},
+		},
+		AES256GCM: {
+			Name: AES256GCM,
+			New:  newAES256GCM,
+			Metadata: Metadata{
+				KeySize:      32,
+				NonceSize:    12,
+				TagSize:      16,
+				MaxPlaintext: 1<<36 - 32,
+				Notes:        "Random nonces should only be used for up to 2^32 messages per key. Only constant-time on platforms with AES instructions.",
+			},
+			Compliance: Compliance{Specification: "NIST SP 800-38D", Approved: true},
+		},
+	},
+}
+
+// SetPolicy sets the policy that determines which of the registered
+// algorithms can be used. It applies to all subsequent lookups, instances that
+// were already created aren't affected.
+func SetPolicy(policy Policy) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	registry.policy = policy
+}
+
+// CurrentPolicy returns the policy that's currently in effect.
+func CurrentPolicy() Policy {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	return registry.policy
+}
+
+// allowed reports whether the policy allows the algorithm to be used.
+func (p Policy) allowed(algorithm Algorithm) bool {
+	return p != PolicyApprovedOnly || algorithm.Compliance.Approved
+}
+
+// Register adds an algorithm to the registry.
+// Returns an error if the algorithm has no name or constructor or if its name
+// is already taken.
+func Register(algorithm Algorithm) error {
+	if algorithm.Name == "" || algorithm.New == nil {
+		return ErrInvalidAlgorithm
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	if _, ok := registry.algorithms[algorithm.Name]; ok {
+		return ErrAlgorithmExists
+	}
+
+	registry.algorithms[algorithm.Name] = algorithm
+
+	return nil
+}
+
+// Lookup returns the algorithm that's registered under the name.
+// Returns ErrUnknownAlgorithm if there's no such algorithm and ErrNotApproved
+// if the policy doesn't allow it.
+func Lookup(name string) (Algorithm, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	algorithm, ok := registry.algorithms[name]
+	if !ok {
+		return Algorithm{}, ErrUnknownAlgorithm
+	}
+
+	if !registry.policy.allowed(algorithm) {
+		return Algorithm{}, ErrNotApproved
+	}
+
+	return algorithm, nil
+}
+
+// Algorithms returns the sorted names of the registered algorithms that the
+// policy allows.
+func Algorithms() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	names := make([]string, 0, len(registry.algorithms))
+	for name, algorithm := range registry.algorithms {
+		if registry.policy.allowed(algorithm) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// Describe returns the registered algorithms that the policy allows sorted by
+// name (e.g. to list their metadata).
+func Describe() []Algorithm {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	algorithms := make([]Algorithm, 0, len(registry.algorithms))
+	for _, algorithm := range registry.algorithms {
+		if registry.policy.allowed(algorithm) {
+			algorithms = append(algorithms, algorithm)
+		}
+	}
+	slices.SortFunc(algorithms, func(a, b Algorithm) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return algorithms
+}
+
+// NewAEAD creates an instance of the algorithm with the name that's keyed with
+// the key.
+// Returns ErrUnknownAlgorithm if there's no such algorithm and ErrNotApproved
+// if the policy doesn't allow it.
+func NewAEAD(name string, key Key) (AEAD, error) {
+	algorithm, err := Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return algorithm.New(key), nil
+}
+
+// cipherAEAD adapts an implementation of the crypto/cipher.AEAD interface
+// (which panics if a nonce has the wrong size) to the AEAD interface.
+type cipherAEAD struct {
+	// aead is the adapted implementation.
+	aead cipher.AEAD
+}
+
+// newChaCha20Poly1305 creates a ChaCha20-Poly1305 AEAD.
+func newChaCha20Poly1305(key Key) AEAD {
+	return &cipherAEAD{aead: chacha20poly1305.NewPool(key)}
+}
+
+// newXChaCha20Poly1305 creates a XChaCha20-Poly1305 AEAD.
+func newXChaCha20Poly1305(key Key) AEAD {
+	return &cipherAEAD{aead: xchacha20poly1305.NewPool(key)}
+}
+
+// newAES256GCM creates an AES-256-GCM AEAD.
+func newAES256GCM(key Key) AEAD {
+	// NewCipher only fails for invalid key sizes and NewGCM only for block
+	// sizes other than 16 bytes.
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		panic("ctk: " + err.Error())
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic("ctk: " + err.Error())
+	}
+
+	return &cipherAEAD{aead: aead}
+}
+
+// NonceSize implements the AEAD interface.
+func (c *cipherAEAD) NonceSize() int {
+	return c.aead.NonceSize()
+}
+
+// Overhead implements the AEAD interface.
+func (c *cipherAEAD) Overhead() int {
+	return c.aead.Overhead()
+}
+
+// Seal implements the AEAD interface.
+func (c *cipherAEAD) Seal(dst []byte, nonce []byte, plaintext []byte, aad []byte) ([]byte, error) {
+	if len(nonce) != c.NonceSize() {
+		return []byte{}, ErrInvalidNonceSize
+	}
+
+	return c.aead.Seal(dst, nonce, plaintext, aad), nil
+}
+
+// Open implements the AEAD interface.
+func (c *cipherAEAD) Open(dst []byte, nonce []byte, ciphertext []byte, aad []byte) ([]byte, error) {
+	if len(nonce) != c.NonceSize() {
+		return []byte{}, ErrInvalidNonceSize
+	}
+
+	plaintext, err := c.aead.Open(dst, nonce, ciphertext, aad)
+	if err != nil {
+		return []byte{}, ErrDecryption
+	}
+
+	return plaintext, nil
+}
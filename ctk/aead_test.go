@@ -0,0 +1,246 @@
+package ctk_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk"
+)
+
+func TestAEAD(t *testing.T) {
+	// The key and AAD are the ones of RFC 8439 (section 2.8.2). The tags were
+	// generated with golang.org/x/crypto and crypto/cipher (AES-256-GCM) by
+	// sealing an empty plaintext.
+	key := ctk.Key{
+		0x80, 0x81, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87, 0x88, 0x89, 0x8a, 0x8b, 0x8c, 0x8d, 0x8e, 0x8f,
+		0x90, 0x91, 0x92, 0x93, 0x94, 0x95, 0x96, 0x97, 0x98, 0x99, 0x9a, 0x9b, 0x9c, 0x9d, 0x9e, 0x9f,
+	}
+
+	aad := []byte{
+		0x50, 0x51, 0x52, 0x53, 0xc0, 0xc1, 0xc2, 0xc3, 0xc4, 0xc5, 0xc6, 0xc7,
+	}
+
+	tt := map[string]struct {
+		nonce []byte
+		want  []byte
+	}{
+		ctk.ChaCha20Poly1305: {
+			nonce: []byte{
+				0x07, 0x00, 0x00, 0x00, 0x40, 0x41, 0x42, 0x43, 0x44, 0x45, 0x46, 0x47,
+			},
+			want: []byte{
+				0xe6, 0x22, 0xe5, 0x64, 0x7a, 0x38, 0xd9, 0x67, 0xa7, 0xec, 0xbc, 0xb4, 0x6c, 0x7f, 0x67, 0x5c,
+			},
+		},
+		ctk.XChaCha20Poly1305: {
+			nonce: []byte{
+				0x40, 0x41, 0x42, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48, 0x49, 0x4a, 0x4b, 0x4c, 0x4d, 0x4e, 0x4f,
+				0x50, 0x51, 0x52, 0x53, 0x54, 0x55, 0x56, 0x57,
+			},
+			want: []byte{
+				0xe4, 0xc5, 0x19, 0x1f, 0x68, 0xfd, 0x06, 0xd9, 0x59, 0x2f, 0x83, 0x75, 0x44, 0x80, 0xd1, 0x9d,
+			},
+		},
+		ctk.AES256GCM: {
+			nonce: []byte{
+				0x07, 0x00, 0x00, 0x00, 0x40, 0x41, 0x42, 0x43, 0x44, 0x45, 0x46, 0x47,
+			},
+			want: []byte{
+				0xfc, 0x1b, 0x3c, 0xa4, 0x79, 0xa9, 0x50, 0x91, 0x9b, 0x26, 0xcd, 0xeb, 0xc2, 0x49, 0xd0, 0xca,
+			},
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			aead, err := ctk.NewAEAD(name, key)
+			if err != nil {
+				t.Fatalf("want error %v, got %v", nil, err)
+			}
+
+			if aead.NonceSize() != len(tc.nonce) || aead.Overhead() != len(ctk.Tag{}) {
+				t.Errorf("want sizes %v and %v, got %v and %v", len(tc.nonce), len(ctk.Tag{}), aead.NonceSize(), aead.Overhead())
+			}
+
+			got, err := aead.Seal(nil, tc.nonce, []byte{}, aad)
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("want %v, got %v (error %v)", tc.want, got, err)
+			}
+
+			plaintext := []byte("Ladies and Gentlemen of the class of '99")
+
+			sealed, _ := aead.Seal(nil, tc.nonce, plaintext, aad)
+			opened, err := aead.Open(nil, tc.nonce, sealed, aad)
+			if !slices.Equal(opened, plaintext) {
+				t.Errorf("want %v, got %v (error %v)", plaintext, opened, err)
+			}
+
+			sealed[0] ^= 0x01
+			_, err = aead.Open(nil, tc.nonce, sealed, aad)
+			if !errors.Is(err, ctk.ErrDecryption) {
+				t.Errorf("want error %v, got %v", ctk.ErrDecryption, err)
+			}
+
+			_, err = aead.Open(nil, tc.nonce, sealed[:aead.Overhead()-1], aad)
+			if !errors.Is(err, ctk.ErrDecryption) {
+				t.Errorf("want error %v, got %v", ctk.ErrDecryption, err)
+			}
+
+			_, err = aead.Seal(nil, tc.nonce[1:], plaintext, aad)
+			if !errors.Is(err, ctk.ErrInvalidNonceSize) {
+				t.Errorf("want error %v, got %v", ctk.ErrInvalidNonceSize, err)
+			}
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	t.Run("Built-in Algorithms", func(t *testing.T) {
+		t.Parallel()
+
+		for _, name := range []string{ctk.AES256GCM, ctk.ChaCha20Poly1305, ctk.XChaCha20Poly1305} {
+			if !slices.Contains(ctk.Algorithms(), name) {
+				t.Errorf("want %v in %v", name, ctk.Algorithms())
+			}
+		}
+	})
+
+	t.Run("Register", func(t *testing.T) {
+		t.Parallel()
+
+		// Register a second name for ChaCha20-Poly1305.
+		chaPoly, _ := ctk.Lookup(ctk.ChaCha20Poly1305)
+		alias := ctk.Algorithm{Name: "test-alias", New: chaPoly.New}
+
+		err := ctk.Register(alias)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		aead, err := ctk.NewAEAD("test-alias", ctk.Key{})
+		if err != nil || aead.NonceSize() != len(ctk.Nonce{}) {
+			t.Errorf("want nonce size %v, got %v (error %v)", len(ctk.Nonce{}), aead, err)
+		}
+
+		err = ctk.Register(alias)
+		if !errors.Is(err, ctk.ErrAlgorithmExists) {
+			t.Errorf("want error %v, got %v", ctk.ErrAlgorithmExists, err)
+		}
+
+		err = ctk.Register(ctk.Algorithm{Name: "test-invalid"})
+		if !errors.Is(err, ctk.ErrInvalidAlgorithm) {
+			t.Errorf("want error %v, got %v", ctk.ErrInvalidAlgorithm, err)
+		}
+	})
+
+	t.Run("Unknown Algorithm", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ctk.NewAEAD("aes-256-gcm", ctk.Key{})
+		if !errors.Is(err, ctk.ErrUnknownAlgorithm) {
+			t.Errorf("want error %v, got %v", ctk.ErrUnknownAlgorithm, err)
+		}
+	})
+}
+
+func TestDescribe(t *testing.T) {
+	for _, algorithm := range ctk.Describe() {
+		if !strings.HasPrefix(algorithm.Name, "test-") {
+			t.Run(algorithm.Name, func(t *testing.T) {
+				t.Parallel()
+
+				aead := algorithm.New(ctk.Key{})
+				metadata := algorithm.Metadata
+
+				if metadata.KeySize != len(ctk.Key{}) || metadata.NonceSize != aead.NonceSize() || metadata.TagSize != aead.Overhead() {
+					t.Errorf("want sizes %v, %v and %v, got %v, %v and %v", len(ctk.Key{}), aead.NonceSize(), aead.Overhead(), metadata.KeySize, metadata.NonceSize, metadata.TagSize)
+				}
+
+				if metadata.MaxPlaintext == 0 || metadata.Notes == "" || algorithm.Compliance.Specification == "" {
+					t.Errorf("want complete metadata, got %+v and %+v", metadata, algorithm.Compliance)
+				}
+			})
+		}
+	}
+
+	names := []string{}
+	for _, algorithm := range ctk.Describe() {
+		names = append(names, algorithm.Name)
+	}
+
+	if !slices.Equal(names, ctk.Algorithms()) {
+		t.Errorf("want %v, got %v", ctk.Algorithms(), names)
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	// The policy is global so the subtests can't run in parallel (the other
+	// tests' subtests have finished when this test starts).
+	t.Run("Compliance Metadata", func(t *testing.T) {
+		tt := map[string]ctk.Compliance{
+			ctk.AES256GCM:         {Specification: "NIST SP 800-38D", Approved: true},
+			ctk.ChaCha20Poly1305:  {Specification: "RFC 8439"},
+			ctk.XChaCha20Poly1305: {Specification: "draft-irtf-cfrg-xchacha"},
+		}
+
+		for name, want := range tt {
+			algorithm, err := ctk.Lookup(name)
+			if algorithm.Compliance != want {
+				t.Errorf("%v: want %v, got %v (error %v)", name, want, algorithm.Compliance, err)
+			}
+		}
+	})
+
+	t.Run("Approved Only", func(t *testing.T) {
+		ctk.SetPolicy(ctk.PolicyApprovedOnly)
+		defer ctk.SetPolicy(ctk.PolicyAll)
+
+		if got := ctk.CurrentPolicy(); got != ctk.PolicyApprovedOnly {
+			t.Errorf("want %v, got %v", ctk.PolicyApprovedOnly, got)
+		}
+
+		if slices.Contains(ctk.Algorithms(), ctk.ChaCha20Poly1305) || !slices.Contains(ctk.Algorithms(), ctk.AES256GCM) {
+			t.Errorf("want only approved algorithms, got %v", ctk.Algorithms())
+		}
+
+		for _, name := range []string{ctk.ChaCha20Poly1305, ctk.XChaCha20Poly1305} {
+			_, err := ctk.NewAEAD(name, ctk.Key{})
+			if !errors.Is(err, ctk.ErrNotApproved) {
+				t.Errorf("%v: want error %v, got %v", name, ctk.ErrNotApproved, err)
+			}
+		}
+
+		_, err := ctk.NewAEAD(ctk.AES256GCM, ctk.Key{})
+		if !errors.Is(err, nil) {
+			t.Errorf("want error %v, got %v", nil, err)
+		}
+
+		err = ctk.Register(ctk.Algorithm{
+			Name: "test-approved",
+			New: func(key ctk.Key) ctk.AEAD {
+				aead, _ := ctk.NewAEAD(ctk.AES256GCM, key)
+				return aead
+			},
+			Compliance: ctk.Compliance{Approved: true},
+		})
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		_, err = ctk.NewAEAD("test-approved", ctk.Key{})
+		if !errors.Is(err, nil) {
+			t.Errorf("want error %v, got %v", nil, err)
+		}
+	})
+
+	t.Run("All", func(t *testing.T) {
+		_, err := ctk.NewAEAD(ctk.ChaCha20Poly1305, ctk.Key{})
+		if !errors.Is(err, nil) {
+			t.Errorf("want error %v, got %v", nil, err)
+		}
+	})
+}
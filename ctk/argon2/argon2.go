@@ -0,0 +1,447 @@
+// Package argon2 implements the Argon2 memory-hard password hashing function as
+// specified in https://datatracker.ietf.org/doc/html/rfc9106.
+package argon2
+
+import (
+	"context"
+	"encoding/binary"
+	"math/bits"
+
+	"github.com/pmuens/ctk-go/ctk/blake2b"
+)
+
+const (
+	// ErrInvalidParams is returned if the parameters violate the limits of the
+	// specification.
+	ErrInvalidParams = Error("invalid parameters")
+
+	// ErrInvalidSalt is returned if the salt is shorter than 8 bytes.
+	ErrInvalidSalt = Error("invalid salt")
+)
+
+// Variant is the Argon2 variant (Argon2d, Argon2i or Argon2id).
+type Variant uint32
+
+const (
+	// Argon2d uses data-dependent memory access.
+	Argon2d Variant = 0
+
+	// Argon2i uses data-independent memory access.
+	Argon2i Variant = 1
+
+	// Argon2id uses data-independent memory access in the first half of the
+	// first pass and data-dependent memory access afterwards.
+	Argon2id Variant = 2
+)
+
+// String returns the name of the variant.
+func (v Variant) String() string {
+	switch v {
+	case Argon2d:
+		return "argon2d"
+	case Argon2i:
+		return "argon2i"
+	case Argon2id:
+		return "argon2id"
+	default:
+		return "unknown"
+	}
+}
+
+// Version is the implemented version of Argon2 (1.3).
+const Version = 0x13
+
+// BlockSize is the size (in bytes) of a memory block.
+const BlockSize = 1024
+
+// MinSaltSize is the minimum size (in bytes) of the salt.
+const MinSaltSize = 8
+
+// syncPoints is the number of slices every lane is divided into.
+const syncPoints = 4
+
+// blockWords is the number of 64 bit words in a memory block.
+const blockWords = BlockSize / 8
+
+// block is a memory block.
+type block [blockWords]uint64
+
+// Params are the parameters of an Argon2 computation.
+type Params struct {
+	// Variant is the Argon2 variant.
+	Variant Variant
+
+	// Time is the number of passes over the memory.
+	Time uint32
+
+	// Memory is the memory size in KiB.
+	Memory uint32
+
+	// Parallelism is the number of lanes.
+	Parallelism uint32
+
+	// KeyLength is the size (in bytes) of the derived key (tag).
+	KeyLength uint32
+
+	// Secret is an optional secret value (pepper).
+	Secret []byte
+
+	// AssociatedData is optional associated data.
+	AssociatedData []byte
+}
+
+// DefaultParams are the parameters of the second recommended option in
+// RFC 9106 (Argon2id with t=3, p=4 and 64 MiB of memory).
+var DefaultParams = Params{
+	Variant:     Argon2id,
+	Time:        3,
+	Memory:      64 * 1024,
+	Parallelism: 4,
+	KeyLength:   32,
+}
+
+// Key derives a key from the password and salt using the parameters.
+// Returns an error if the salt is too short or the parameters are invalid.
+func Key(password []byte, salt []byte, params Params) ([]byte, error) {
+	return KeyContext(context.Background(), password, salt, params)
+}
+
+// KeyContext is Key which stops (and wipes the memory) once the context is
+// done. The context is checked at every synchronization point (4 times per
+// pass) so that a computation with large parameters can be cancelled or bound
+// by a deadline.
+// Returns the context's error if it's done before the key was derived.
+func KeyContext(ctx context.Context, password []byte, salt []byte, params Params) ([]byte, error) {
+	if len(salt) < MinSaltSize {
+		return []byte{}, ErrInvalidSalt
+	}
+
+	if params.Variant > Argon2id || params.Time < 1 || params.KeyLength < 4 ||
+		params.Parallelism < 1 || params.Parallelism > 1<<24-1 ||
+		uint64(params.Memory) < 8*uint64(params.Parallelism) {
+		return []byte{}, ErrInvalidParams
+	}
+
+	h0 := initialHash(password, salt, params)
+
+	lanes := params.Parallelism
+	// The memory is rounded down to a multiple of 4 * lanes blocks.
+	memory := params.Memory / (syncPoints * lanes) * (syncPoints * lanes)
+	laneLength := memory / lanes
+	segmentLength := laneLength / syncPoints
+
+	memoryBlocks := make([]block, memory)
+
+	// Compute the first two blocks of every lane.
+	var input [64 + 8]byte
+	copy(input[:], h0[:])
+	for lane := range lanes {
+		binary.LittleEndian.PutUint32(input[68:72], lane)
+
+		binary.LittleEndian.PutUint32(input[64:68], 0)
+		memoryBlocks[lane*laneLength] = bytesToBlock(hPrime(input[:], BlockSize))
+
+		binary.LittleEndian.PutUint32(input[64:68], 1)
+		memoryBlocks[lane*laneLength+1] = bytesToBlock(hPrime(input[:], BlockSize))
+	}
+
+	s := &state{
+		blocks:        memoryBlocks,
+		variant:       params.Variant,
+		time:          params.Time,
+		memory:        memory,
+		lanes:         lanes,
+		laneLength:    laneLength,
+		segmentLength: segmentLength,
+	}
+
+	for pass := range params.Time {
+		for slice := range uint32(syncPoints) {
+			err := ctx.Err()
+			if err != nil {
+				clear(memoryBlocks)
+				return []byte{}, err
+			}
+
+			for lane := range lanes {
+				s.fillSegment(pass, slice, lane)
+			}
+		}
+	}
+
+	// XOR the last blocks of every lane to compute the final block.
+	final := memoryBlocks[laneLength-1]
+	for lane := uint32(1); lane < lanes; lane++ {
+		last := &memoryBlocks[lane*laneLength+laneLength-1]
+		for i := range final {
+			final[i] ^= last[i]
+		}
+	}
+
+	// Wipe the memory as it was derived from the password.
+	clear(memoryBlocks)
+
+	return hPrime(blockToBytes(&final), params.KeyLength), nil
+}
+
+// state is the state of an Argon2 computation.
+type state struct {
+	// blocks is the memory.
+	blocks []block
+
+	// variant is the Argon2 variant.
+	variant Variant
+
+	// time is the number of passes.
+	time uint32
+
+	// memory is the number of memory blocks.
+	memory uint32
+
+	// lanes is the number of lanes.
+	lanes uint32
+
+	// laneLength is the number of blocks per lane.
+	laneLength uint32
+
+	// segmentLength is the number of blocks per segment.
+	segmentLength uint32
+}
+
+// fillSegment computes the blocks of the segment identified by the pass, slice
+// and lane.
+func (s *state) fillSegment(pass, slice, lane uint32) {
+	dataIndependent := s.variant == Argon2i ||
+		(s.variant == Argon2id && pass == 0 && slice < syncPoints/2)
+
+	var address, input, zero block
+	if dataIndependent {
+		input[0] = uint64(pass)
+		input[1] = uint64(lane)
+		input[2] = uint64(slice)
+		input[3] = uint64(s.memory)
+		input[4] = uint64(s.time)
+		input[5] = uint64(s.variant)
+	}
+
+	index := uint32(0)
+	// The first two blocks of every lane were already computed.
+	if pass == 0 && slice == 0 {
+		index = 2
+		if dataIndependent {
+			nextAddresses(&address, &input, &zero)
+		}
+	}
+
+	offset := lane*s.laneLength + slice*s.segmentLength + index
+
+	for ; index < s.segmentLength; index, offset = index+1, offset+1 {
+		prev := offset - 1
+		// The first block of a lane references the last block of the lane.
+		if index == 0 && slice == 0 {
+			prev += s.laneLength
+		}
+
+		var pseudoRand uint64
+		if dataIndependent {
+			if index%blockWords == 0 {
+				nextAddresses(&address, &input, &zero)
+			}
+			pseudoRand = address[index%blockWords]
+		} else {
+			pseudoRand = s.blocks[prev][0]
+		}
+
+		ref := s.referenceIndex(pseudoRand, pass, slice, lane, index)
+
+		// In version 1.3 the new block is XORed into the old block (which is
+		// all zeros in the first pass).
+		compress(&s.blocks[offset], &s.blocks[prev], &s.blocks[ref], true)
+	}
+}
+
+// referenceIndex maps the pseudo-random value to the index of the reference block.
+func (s *state) referenceIndex(pseudoRand uint64, pass, slice, lane, index uint32) uint32 {
+	refLane := uint32(pseudoRand>>32) % s.lanes
+	// Blocks in the first slice of the first pass can only reference their lane.
+	if pass == 0 && slice == 0 {
+		refLane = lane
+	}
+	sameLane := refLane == lane
+
+	// Compute the size of the reference area and where it starts.
+	var area, start uint32
+	if pass == 0 {
+		area = slice * s.segmentLength
+		if sameLane {
+			area += index - 1
+		} else if index == 0 {
+			area -= 1
+		}
+	} else {
+		area = s.laneLength - s.segmentLength
+		if sameLane {
+			area += index - 1
+		} else if index == 0 {
+			area -= 1
+		}
+		start = ((slice + 1) % syncPoints) * s.segmentLength
+	}
+
+	// Map the pseudo-random value to a position in the reference area with a
+	// non-uniform distribution that favors recent blocks.
+	x := (pseudoRand & 0xffffffff) * (pseudoRand & 0xffffffff) >> 32
+	y := (uint64(area) * x) >> 32
+	relative := uint64(area) - 1 - y
+
+	return refLane*s.laneLength + uint32((uint64(start)+relative)%uint64(s.laneLength))
+}
+
+// nextAddresses increments the counter of the input block and computes the next
+// block of pseudo-random addresses for data-independent addressing.
+func nextAddresses(address, input, zero *block) {
+	input[6]++
+	compress(address, zero, input, false)
+	compress(address, zero, address, false)
+}
+
+// compress is an implementation of the compression function G.
+// If xor is set the result is XORed into out instead of overwriting it.
+func compress(out, x, y *block, xor bool) {
+	var r block
+	for i := range r {
+		r[i] = x[i] ^ y[i]
+	}
+
+	z := r
+
+	// Apply the permutation P to the rows (8 registers of 16 bytes each).
+	for i := 0; i < blockWords; i += 16 {
+		permute(&z, i, i+1, i+2, i+3, i+4, i+5, i+6, i+7, i+8, i+9, i+10, i+11, i+12, i+13, i+14, i+15)
+	}
+
+	// Apply the permutation P to the columns.
+	for i := 0; i < 16; i += 2 {
+		permute(&z, i, i+1, i+16, i+17, i+32, i+33, i+48, i+49, i+64, i+65, i+80, i+81, i+96, i+97, i+112, i+113)
+	}
+
+	if xor {
+		for i := range z {
+			out[i] ^= z[i] ^ r[i]
+		}
+	} else {
+		for i := range z {
+			out[i] = z[i] ^ r[i]
+		}
+	}
+}
+
+// permute is an implementation of the permutation P which is based on the
+// BLAKE2b round function. The arguments are used to index into the block.
+func permute(b *block, v0, v1, v2, v3, v4, v5, v6, v7, v8, v9, v10, v11, v12, v13, v14, v15 int) {
+	mix(b, v0, v4, v8, v12)
+	mix(b, v1, v5, v9, v13)
+	mix(b, v2, v6, v10, v14)
+	mix(b, v3, v7, v11, v15)
+
+	mix(b, v0, v5, v10, v15)
+	mix(b, v1, v6, v11, v12)
+	mix(b, v2, v7, v8, v13)
+	mix(b, v3, v4, v9, v14)
+}
+
+// mix is an implementation of the GB function which is the BLAKE2b mixing
+// function with additional multiplications.
+func mix(v *block, a, b, c, d int) {
+	v[a] = v[a] + v[b] + 2*uint64(uint32(v[a]))*uint64(uint32(v[b]))
+	v[d] = bits.RotateLeft64(v[d]^v[a], -32)
+	v[c] = v[c] + v[d] + 2*uint64(uint32(v[c]))*uint64(uint32(v[d]))
+	v[b] = bits.RotateLeft64(v[b]^v[c], -24)
+	v[a] = v[a] + v[b] + 2*uint64(uint32(v[a]))*uint64(uint32(v[b]))
+	v[d] = bits.RotateLeft64(v[d]^v[a], -16)
+	v[c] = v[c] + v[d] + 2*uint64(uint32(v[c]))*uint64(uint32(v[d]))
+	v[b] = bits.RotateLeft64(v[b]^v[c], -63)
+}
+
+// initialHash computes H_0 from the inputs and parameters.
+func initialHash(password []byte, salt []byte, params Params) [64]byte {
+	h := blake2b.New512()
+
+	var word [4]byte
+	writeUint32 := func(v uint32) {
+		binary.LittleEndian.PutUint32(word[:], v)
+		h.Write(word[:])
+	}
+
+	writeUint32(params.Parallelism)
+	writeUint32(params.KeyLength)
+	writeUint32(params.Memory)
+	writeUint32(params.Time)
+	writeUint32(Version)
+	writeUint32(uint32(params.Variant))
+
+	for _, data := range [][]byte{password, salt, params.Secret, params.AssociatedData} {
+		writeUint32(uint32(len(data)))
+		h.Write(data)
+	}
+
+	return [64]byte(h.Sum(nil))
+}
+
+// hPrime is an implementation of the variable-length hash function H'.
+func hPrime(input []byte, length uint32) []byte {
+	var prefix [4]byte
+	binary.LittleEndian.PutUint32(prefix[:], length)
+
+	if length <= blake2b.Size {
+		h, _ := blake2b.NewBlake2b(int(length), nil)
+		h.Write(prefix[:])
+		h.Write(input)
+
+		return h.Sum(nil)
+	}
+
+	// r is the number of intermediate digests of which only the first 32 bytes
+	// are used.
+	r := (length+31)/32 - 2
+	result := make([]byte, 0, length)
+
+	h := blake2b.New512()
+	h.Write(prefix[:])
+	h.Write(input)
+	v := h.Sum(nil)
+	result = append(result, v[:32]...)
+
+	for range r - 1 {
+		h.Reset()
+		h.Write(v)
+		v = h.Sum(nil)
+		result = append(result, v[:32]...)
+	}
+
+	// The last digest has the size of the remaining bytes.
+	last, _ := blake2b.NewBlake2b(int(length-32*r), nil)
+	last.Write(v)
+
+	return last.Sum(result)
+}
+
+// bytesToBlock turns the little endian bytes into a block.
+func bytesToBlock(data []byte) block {
+	var b block
+	for i := range b {
+		b[i] = binary.LittleEndian.Uint64(data[i*8:])
+	}
+
+	return b
+}
+
+// blockToBytes turns the block into little endian bytes.
+func blockToBytes(b *block) []byte {
+	result := make([]byte, BlockSize)
+	for i, word := range b {
+		binary.LittleEndian.PutUint64(result[i*8:], word)
+	}
+
+	return result
+}
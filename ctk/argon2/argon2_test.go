@@ -0,0 +1,165 @@
+package argon2_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pmuens/ctk-go/ctk/argon2"
+)
+
+func TestArgon2Key(t *testing.T) {
+	password := []byte{
+		0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01,
+		0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01,
+	}
+
+	salt := []byte{
+		0x02, 0x02, 0x02, 0x02, 0x02, 0x02, 0x02, 0x02, 0x02, 0x02, 0x02, 0x02, 0x02, 0x02, 0x02, 0x02,
+	}
+
+	secret := []byte{
+		0x03, 0x03, 0x03, 0x03, 0x03, 0x03, 0x03, 0x03,
+	}
+
+	aad := []byte{
+		0x04, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04,
+	}
+
+	tt := map[string]struct {
+		variant argon2.Variant
+		want    []byte
+	}{
+		"RFC 9106 - Test Vectors - 5.1": {
+			variant: argon2.Argon2d,
+			want: []byte{
+				0x51, 0x2b, 0x39, 0x1b, 0x6f, 0x11, 0x62, 0x97, 0x53, 0x71, 0xd3, 0x09, 0x19, 0x73, 0x42, 0x94,
+				0xf8, 0x68, 0xe3, 0xbe, 0x39, 0x84, 0xf3, 0xc1, 0xa1, 0x3a, 0x4d, 0xb9, 0xfa, 0xbe, 0x4a, 0xcb,
+			},
+		},
+		"RFC 9106 - Test Vectors - 5.2": {
+			variant: argon2.Argon2i,
+			want: []byte{
+				0xc8, 0x14, 0xd9, 0xd1, 0xdc, 0x7f, 0x37, 0xaa, 0x13, 0xf0, 0xd7, 0x7f, 0x24, 0x94, 0xbd, 0xa1,
+				0xc8, 0xde, 0x6b, 0x01, 0x6d, 0xd3, 0x88, 0xd2, 0x99, 0x52, 0xa4, 0xc4, 0x67, 0x2b, 0x6c, 0xe8,
+			},
+		},
+		"RFC 9106 - Test Vectors - 5.3": {
+			variant: argon2.Argon2id,
+			want: []byte{
+				0x0d, 0x64, 0x0d, 0xf5, 0x8d, 0x78, 0x76, 0x6c, 0x08, 0xc0, 0x37, 0xa3, 0x4a, 0x8b, 0x53, 0xc9,
+				0xd0, 0x1e, 0xf0, 0x45, 0x2d, 0x75, 0xb6, 0x5e, 0xb5, 0x25, 0x20, 0xe9, 0x6b, 0x01, 0xe6, 0x59,
+			},
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			params := argon2.Params{
+				Variant:        tc.variant,
+				Time:           3,
+				Memory:         32,
+				Parallelism:    4,
+				KeyLength:      32,
+				Secret:         secret,
+				AssociatedData: aad,
+			}
+
+			got, err := argon2.Key(password, salt, params)
+
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+
+			if !errors.Is(err, nil) {
+				t.Errorf("want error %v, got %v", nil, err)
+			}
+		})
+	}
+}
+
+func TestArgon2(t *testing.T) {
+	t.Run("Long Key", func(t *testing.T) {
+		t.Parallel()
+
+		params := argon2.Params{Variant: argon2.Argon2id, Time: 1, Memory: 64, Parallelism: 1, KeyLength: 100}
+
+		got, _ := argon2.Key([]byte("password"), []byte("somesalt"), params)
+
+		if len(got) != 100 {
+			t.Errorf("want length %v, got %v", 100, len(got))
+		}
+
+		params.KeyLength = 64
+		prefix, _ := argon2.Key([]byte("password"), []byte("somesalt"), params)
+
+		// Different lengths are bound into the initial hash.
+		if slices.Equal(got[:64], prefix) {
+			t.Errorf("want different keys, got %v twice", prefix)
+		}
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		t.Parallel()
+
+		params := argon2.Params{Variant: argon2.Argon2id, Time: 1, Memory: 64, Parallelism: 1, KeyLength: 32}
+
+		want, _ := argon2.Key([]byte("password"), []byte("somesalt"), params)
+
+		got, err := argon2.KeyContext(context.Background(), []byte("password"), []byte("somesalt"), params)
+		if err != nil || !slices.Equal(got, want) {
+			t.Errorf("want %v and error %v, got %v and %v", want, nil, got, err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err = argon2.KeyContext(ctx, []byte("password"), []byte("somesalt"), params)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("want error %v, got %v", context.Canceled, err)
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		// The parameters take much longer than the deadline.
+		_, err = argon2.KeyContext(ctx, []byte("password"), []byte("somesalt"), argon2.Params{Variant: argon2.Argon2id, Time: 100, Memory: 64 * 1024, Parallelism: 4, KeyLength: 32})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("want error %v, got %v", context.DeadlineExceeded, err)
+		}
+	})
+
+	t.Run("Invalid Parameters", func(t *testing.T) {
+		t.Parallel()
+
+		valid := argon2.Params{Variant: argon2.Argon2id, Time: 1, Memory: 64, Parallelism: 1, KeyLength: 32}
+
+		tt := map[string]struct {
+			salt   []byte
+			modify func(p *argon2.Params)
+			err    error
+		}{
+			"Short Salt":      {salt: []byte("salt"), modify: func(p *argon2.Params) {}, err: argon2.ErrInvalidSalt},
+			"Zero Time":       {salt: []byte("somesalt"), modify: func(p *argon2.Params) { p.Time = 0 }, err: argon2.ErrInvalidParams},
+			"Low Memory":      {salt: []byte("somesalt"), modify: func(p *argon2.Params) { p.Parallelism = 9 }, err: argon2.ErrInvalidParams},
+			"Zero Lanes":      {salt: []byte("somesalt"), modify: func(p *argon2.Params) { p.Parallelism = 0 }, err: argon2.ErrInvalidParams},
+			"Short Key":       {salt: []byte("somesalt"), modify: func(p *argon2.Params) { p.KeyLength = 3 }, err: argon2.ErrInvalidParams},
+			"Unknown Variant": {salt: []byte("somesalt"), modify: func(p *argon2.Params) { p.Variant = 3 }, err: argon2.ErrInvalidParams},
+		}
+
+		for name, tc := range tt {
+			params := valid
+			tc.modify(&params)
+
+			_, err := argon2.Key([]byte("password"), tc.salt, params)
+
+			if !errors.Is(err, tc.err) {
+				t.Errorf("%v: want error %v, got %v", name, tc.err, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,170 @@
+// Package balloon implements the Balloon memory-hard password hashing function
+// as specified in https://eprint.iacr.org/2016/027 (Algorithm 1).
+//
+// Balloon only relies on a standard hash function (SHA-256 or BLAKE2b-256)
+// and its memory-hardness has a simpler analysis than the one of Argon2. It
+// can be used in the same places as Argon2 (see the argon2 package) and hash
+// strings can be created with the passhash package.
+//
+// The integers of the algorithm are encoded as 8 byte little endian values:
+// the counter that's prepended to every hash input and the indices (t, m, i)
+// of the pseudorandom block selection. As in the reference implementation, the
+// index block ("ints_to_block") is the hash of the encoded indices (without a
+// counter). A selected block is the hash interpreted as a little endian integer
+// reduced modulo the space cost ("to_int"). The output is the last block of the
+// buffer.
+package balloon
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+
+	"github.com/pmuens/ctk-go/ctk/blake2b"
+)
+
+const (
+	// ErrInvalidParams is returned if a cost parameter is zero or the hash
+	// function is unknown.
+	ErrInvalidParams = Error("invalid parameters")
+)
+
+// Hash is the hash function the Balloon construction is instantiated with.
+type Hash uint32
+
+const (
+	// SHA256 is SHA-256.
+	SHA256 Hash = 0
+
+	// BLAKE2b256 is BLAKE2b with a 32 byte digest.
+	BLAKE2b256 Hash = 1
+)
+
+// String returns the name of the hash function.
+func (h Hash) String() string {
+	switch h {
+	case SHA256:
+		return "sha256"
+	case BLAKE2b256:
+		return "blake2b256"
+	default:
+		return "unknown"
+	}
+}
+
+// new returns a new instance of the hash function.
+func (h Hash) new() hash.Hash {
+	if h == BLAKE2b256 {
+		return blake2b.New256()
+	}
+
+	return sha256.New()
+}
+
+// Size is the size (in bytes) of a block and the derived key.
+const Size = 32
+
+// Params are the parameters of a Balloon computation.
+type Params struct {
+	// Hash is the hash function.
+	Hash Hash
+
+	// Space is the space cost, i.e. the number of blocks in the buffer (each
+	// block is Size bytes).
+	Space uint32
+
+	// Time is the number of mixing rounds.
+	Time uint32
+
+	// Delta is the number of pseudorandomly selected blocks that are mixed into
+	// every block per round.
+	Delta uint32
+}
+
+// DefaultParams are parameters which use 16 MiB of memory (2^19 blocks), three
+// rounds and the recommended number of dependencies (delta = 3).
+var DefaultParams = Params{
+	Hash:  SHA256,
+	Space: 1 << 19,
+	Time:  3,
+	Delta: 3,
+}
+
+// Key derives a Size byte key from the password and salt using the parameters.
+// Returns an error if the parameters are invalid.
+func Key(password []byte, salt []byte, params Params) ([]byte, error) {
+	if params.Hash > BLAKE2b256 || params.Space < 1 || params.Time < 1 || params.Delta < 1 {
+		return []byte{}, ErrInvalidParams
+	}
+
+	h := params.Hash.new()
+	space := uint64(params.Space)
+	buf := make([][Size]byte, space)
+	defer clear(buf)
+
+	var counter uint64
+
+	// sum hashes the counter followed by the inputs into out and increments the
+	// counter.
+	sum := func(out *[Size]byte, inputs ...[]byte) {
+		h.Reset()
+		h.Write(binary.LittleEndian.AppendUint64(nil, counter))
+		for _, input := range inputs {
+			h.Write(input)
+		}
+		h.Sum(out[:0])
+		counter++
+	}
+
+	// Step 1: Expand the input into the buffer.
+	sum(&buf[0], password, salt)
+	for m := uint64(1); m < space; m++ {
+		sum(&buf[m], buf[m-1][:])
+	}
+
+	// Step 2: Mix the buffer contents.
+	var indices [24]byte
+	var indexBlock [Size]byte
+	var other [Size]byte
+
+	for t := range uint64(params.Time) {
+		for m := range space {
+			// Step 2a: Hash the last and the current block.
+			prev := buf[(m+space-1)%space]
+			sum(&buf[m], prev[:], buf[m][:])
+
+			// Step 2b: Hash in pseudorandomly chosen blocks.
+			for i := range uint64(params.Delta) {
+				binary.LittleEndian.PutUint64(indices[0:], t)
+				binary.LittleEndian.PutUint64(indices[8:], m)
+				binary.LittleEndian.PutUint64(indices[16:], i)
+
+				// The index block is hashed without a counter.
+				h.Reset()
+				h.Write(indices[:])
+				h.Sum(indexBlock[:0])
+
+				sum(&other, salt, indexBlock[:])
+				j := reduce(other, space)
+
+				sum(&buf[m], buf[m][:], buf[j][:])
+			}
+		}
+	}
+
+	// Step 3: Extract the output from the buffer.
+	key := buf[space-1]
+
+	return key[:], nil
+}
+
+// reduce interprets the block as a little endian integer and reduces it modulo
+// n (which is less than 2^32).
+func reduce(block [Size]byte, n uint64) uint64 {
+	var r uint64
+	for i := Size - 1; i >= 0; i-- {
+		r = (r<<8 | uint64(block[i])) % n
+	}
+
+	return r
+}
@@ -0,0 +1,89 @@
+package balloon_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/balloon"
+)
+
+func TestBalloonKey(t *testing.T) {
+	// The "1024 Blocks" vector is the published vector of the reference
+	// implementation (https://github.com/nachonavarro/balloon-hashing). The
+	// other vectors were computed with the reference implementation.
+	tt := map[string]struct {
+		password string
+		salt     string
+		params   balloon.Params
+		want     []byte
+	}{
+		"SHA-256": {
+			password: "password",
+			salt:     "somesalt",
+			params:   balloon.Params{Hash: balloon.SHA256, Space: 16, Time: 3, Delta: 3},
+			want: []byte{
+				0x61, 0x32, 0x72, 0xe7, 0xa9, 0x56, 0x2b, 0x29, 0x6a, 0x80, 0x38, 0x4a, 0x69, 0x6e, 0xb6, 0x45,
+				0x87, 0x6d, 0xf8, 0xb2, 0x83, 0x03, 0xf6, 0x72, 0x3d, 0x36, 0xd6, 0x7e, 0xa6, 0x2a, 0x36, 0xa8,
+			},
+		},
+		"BLAKE2b-256": {
+			password: "password",
+			salt:     "somesalt",
+			params:   balloon.Params{Hash: balloon.BLAKE2b256, Space: 16, Time: 3, Delta: 3},
+			want: []byte{
+				0x6a, 0x8b, 0xa2, 0x4b, 0xc2, 0x7a, 0x8a, 0x66, 0xe6, 0xdb, 0x0e, 0xd1, 0x07, 0x97, 0x78, 0x7d,
+				0xd9, 0x99, 0x08, 0xfc, 0x1f, 0x0b, 0xa8, 0x9c, 0x41, 0xd0, 0x35, 0x90, 0x8f, 0xf8, 0xe0, 0x8a,
+			},
+		},
+		"Minimal Costs": {
+			password: "",
+			salt:     "salt",
+			params:   balloon.Params{Hash: balloon.SHA256, Space: 1, Time: 1, Delta: 1},
+			want: []byte{
+				0x6e, 0x1f, 0x1a, 0x74, 0xb9, 0x1f, 0xef, 0x5f, 0x7a, 0x0a, 0x5d, 0x97, 0xf2, 0x2b, 0x8b, 0x5a,
+				0x79, 0x8b, 0x41, 0xc3, 0xdd, 0x2f, 0x2a, 0x04, 0xb6, 0x12, 0x0b, 0x02, 0x98, 0x37, 0xdd, 0xf1,
+			},
+		},
+		"1024 Blocks": {
+			password: "hunter42",
+			salt:     "examplesalt",
+			params:   balloon.Params{Hash: balloon.SHA256, Space: 1024, Time: 3, Delta: 3},
+			want: []byte{
+				0x71, 0x60, 0x43, 0xdf, 0xf7, 0x77, 0xb4, 0x4a, 0xa7, 0xb8, 0x8d, 0xcb, 0xab, 0x12, 0xc0, 0x78,
+				0xab, 0xec, 0xfa, 0xc9, 0xd2, 0x89, 0xc5, 0xb5, 0x19, 0x59, 0x67, 0xaa, 0x63, 0x44, 0x0d, 0xfb,
+			},
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := balloon.Key([]byte(tc.password), []byte(tc.salt), tc.params)
+
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("want %v, got %v (error %v)", tc.want, got, err)
+			}
+		})
+	}
+
+	t.Run("Invalid Params", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]balloon.Params{
+			"Zero Space":   {Hash: balloon.SHA256, Space: 0, Time: 1, Delta: 3},
+			"Zero Time":    {Hash: balloon.SHA256, Space: 16, Time: 0, Delta: 3},
+			"Zero Delta":   {Hash: balloon.SHA256, Space: 16, Time: 1, Delta: 0},
+			"Unknown Hash": {Hash: 2, Space: 16, Time: 1, Delta: 3},
+		}
+
+		for name, params := range tt {
+			_, err := balloon.Key([]byte("password"), []byte("salt"), params)
+
+			if !errors.Is(err, balloon.ErrInvalidParams) {
+				t.Errorf("%v: want error %v, got %v", name, balloon.ErrInvalidParams, err)
+			}
+		}
+	})
+}
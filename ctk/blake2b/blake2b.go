@@ -0,0 +1,256 @@
+// Package blake2b implements the BLAKE2b hash function as specified in
+// https://datatracker.ietf.org/doc/html/rfc7693.
+package blake2b
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+const (
+	// ErrInvalidSize is returned if the digest size isn't in the range [1, 64].
+	ErrInvalidSize = Error("invalid digest size")
+
+	// ErrInvalidKeySize is returned if the key is longer than 64 bytes.
+	ErrInvalidKeySize = Error("invalid key size")
+)
+
+// BlockSize is the size (in bytes) of the input to be processed at a time.
+const BlockSize = 128
+
+// Size is the maximum size (in bytes) of a BLAKE2b digest.
+const Size = 64
+
+// Size256 is the size (in bytes) of a BLAKE2b-256 digest.
+const Size256 = 32
+
+// KeySize is the maximum size (in bytes) of a BLAKE2b key.
+const KeySize = 64
+
+// iv is the initialization vector (same as SHA-512).
+var iv = [8]uint64{
+	0x6a09e667f3bcc908, 0xbb67ae8584caa73b, 0x3c6ef372fe94f82b, 0xa54ff53a5f1d36f1,
+	0x510e527fade682d1, 0x9b05688c2b3e6c1f, 0x1f83d9abfb41bd6b, 0x5be0cd19137e2179,
+}
+
+// sigma are the message word permutations used in the rounds.
+var sigma = [12][16]byte{
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{14, 10, 4, 8, 9, 15, 13, 6, 1, 12, 0, 2, 11, 7, 5, 3},
+	{11, 8, 12, 0, 5, 2, 15, 13, 10, 14, 3, 6, 7, 1, 9, 4},
+	{7, 9, 3, 1, 13, 12, 11, 14, 2, 6, 5, 10, 4, 0, 15, 8},
+	{9, 0, 5, 7, 2, 4, 10, 15, 14, 1, 11, 12, 6, 8, 3, 13},
+	{2, 12, 6, 10, 0, 11, 8, 3, 4, 13, 7, 5, 15, 14, 1, 9},
+	{12, 5, 1, 15, 14, 13, 4, 10, 0, 7, 6, 3, 9, 2, 8, 11},
+	{13, 11, 7, 14, 12, 1, 3, 9, 5, 0, 15, 4, 8, 6, 2, 10},
+	{6, 15, 14, 9, 11, 3, 0, 8, 12, 2, 13, 7, 1, 4, 10, 5},
+	{10, 2, 8, 4, 7, 6, 1, 5, 15, 11, 9, 14, 3, 12, 13, 0},
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{14, 10, 4, 8, 9, 15, 13, 6, 1, 12, 0, 2, 11, 7, 5, 3},
+}
+
+// Blake2b is a stateful instance of the BLAKE2b hash function.
+// It implements the hash.Hash interface.
+// An instance isn't safe for concurrent use.
+type Blake2b struct {
+	// h is the chained state.
+	h [8]uint64
+
+	// t is the 128 bit counter of processed bytes (low word first).
+	t [2]uint64
+
+	// buf buffers the input that hasn't been processed yet.
+	buf [BlockSize]byte
+
+	// bufLen is the number of bytes in buf.
+	bufLen int
+
+	// size is the digest size (in bytes).
+	size int
+
+	// key is the (optional) key used for keyed hashing (MAC mode).
+	key []byte
+}
+
+// NewBlake2b creates a new instance of BLAKE2b that computes size byte digests.
+// An optional key of up to 64 bytes turns BLAKE2b into a MAC.
+// Returns an error if the size or key size is invalid.
+func NewBlake2b(size int, key []byte) (*Blake2b, error) {
+	if size < 1 || size > Size {
+		return nil, ErrInvalidSize
+	}
+
+	if len(key) > KeySize {
+		return nil, ErrInvalidKeySize
+	}
+
+	b := &Blake2b{
+		size: size,
+		key:  append([]byte{}, key...),
+	}
+	b.Reset()
+
+	return b, nil
+}
+
+// New512 returns a hash.Hash computing unkeyed BLAKE2b-512 digests.
+func New512() hash.Hash {
+	b, _ := NewBlake2b(Size, nil)
+	return b
+}
+
+// New256 returns a hash.Hash computing unkeyed BLAKE2b-256 digests.
+func New256() hash.Hash {
+	b, _ := NewBlake2b(Size256, nil)
+	return b
+}
+
+// Sum512 returns the BLAKE2b-512 digest of the data.
+func Sum512(data []byte) [64]byte {
+	b, _ := NewBlake2b(Size, nil)
+	b.Write(data)
+
+	return [64]byte(b.Sum(nil))
+}
+
+// Sum256 returns the BLAKE2b-256 digest of the data.
+func Sum256(data []byte) [32]byte {
+	b, _ := NewBlake2b(Size256, nil)
+	b.Write(data)
+
+	return [32]byte(b.Sum(nil))
+}
+
+// Sum returns the size byte BLAKE2b digest of the data.
+// Returns an error if the size is invalid.
+func Sum(data []byte, size int) ([]byte, error) {
+	b, err := NewBlake2b(size, nil)
+	if err != nil {
+		return []byte{}, err
+	}
+	b.Write(data)
+
+	return b.Sum(nil), nil
+}
+
+// Size returns the digest size (in bytes).
+func (b *Blake2b) Size() int {
+	return b.size
+}
+
+// BlockSize returns the block size (in bytes).
+func (b *Blake2b) BlockSize() int {
+	return BlockSize
+}
+
+// Reset resets the instance to its initial state.
+func (b *Blake2b) Reset() {
+	b.h = iv
+	// Parameter block: digest length, key length, fanout = 1 and depth = 1.
+	b.h[0] ^= 0x01010000 ^ uint64(len(b.key))<<8 ^ uint64(b.size)
+
+	b.t = [2]uint64{}
+	b.buf = [BlockSize]byte{}
+	b.bufLen = 0
+
+	// The key is padded to a full block and processed as the first block.
+	if len(b.key) > 0 {
+		copy(b.buf[:], b.key)
+		b.bufLen = BlockSize
+	}
+}
+
+// Write adds the data to the hash. It never returns an error.
+func (b *Blake2b) Write(data []byte) (int, error) {
+	n := len(data)
+
+	for len(data) > 0 {
+		// The buffered block can only be compressed once more data follows given
+		// that the last block needs to be compressed with the final flag set.
+		if b.bufLen == BlockSize {
+			b.compress(false)
+			b.bufLen = 0
+		}
+
+		copied := copy(b.buf[b.bufLen:], data)
+		b.bufLen += copied
+		data = data[copied:]
+	}
+
+	return n, nil
+}
+
+// Sum appends the digest to in and returns the result.
+// The state of the instance isn't modified.
+func (b *Blake2b) Sum(in []byte) []byte {
+	// Work on a copy so that more data can be written afterwards.
+	c := *b
+
+	clear(c.buf[c.bufLen:])
+	c.compress(true)
+
+	var digest [Size]byte
+	for i, word := range c.h {
+		binary.LittleEndian.PutUint64(digest[i*8:], word)
+	}
+
+	return append(in, digest[:c.size]...)
+}
+
+// compress compresses the buffered block into the state.
+func (b *Blake2b) compress(final bool) {
+	b.t[0] += uint64(b.bufLen)
+	if b.t[0] < uint64(b.bufLen) {
+		b.t[1]++
+	}
+
+	var m [16]uint64
+	for i := range m {
+		m[i] = binary.LittleEndian.Uint64(b.buf[i*8:])
+	}
+
+	var v [16]uint64
+	copy(v[0:8], b.h[:])
+	copy(v[8:16], iv[:])
+
+	v[12] ^= b.t[0]
+	v[13] ^= b.t[1]
+
+	if final {
+		v[14] = ^v[14]
+	}
+
+	for i := range 12 {
+		s := &sigma[i]
+
+		// Column step.
+		mix(&v, 0, 4, 8, 12, m[s[0]], m[s[1]])
+		mix(&v, 1, 5, 9, 13, m[s[2]], m[s[3]])
+		mix(&v, 2, 6, 10, 14, m[s[4]], m[s[5]])
+		mix(&v, 3, 7, 11, 15, m[s[6]], m[s[7]])
+
+		// Diagonal step.
+		mix(&v, 0, 5, 10, 15, m[s[8]], m[s[9]])
+		mix(&v, 1, 6, 11, 12, m[s[10]], m[s[11]])
+		mix(&v, 2, 7, 8, 13, m[s[12]], m[s[13]])
+		mix(&v, 3, 4, 9, 14, m[s[14]], m[s[15]])
+	}
+
+	for i := range 8 {
+		b.h[i] ^= v[i] ^ v[i+8]
+	}
+}
+
+// mix is an implementation of the BLAKE2b mixing function G.
+// a, b, c and d are used to index into the working vector.
+func mix(v *[16]uint64, a, b, c, d int, x, y uint64) {
+	v[a] = v[a] + v[b] + x
+	v[d] = bits.RotateLeft64(v[d]^v[a], -32)
+	v[c] = v[c] + v[d]
+	v[b] = bits.RotateLeft64(v[b]^v[c], -24)
+	v[a] = v[a] + v[b] + y
+	v[d] = bits.RotateLeft64(v[d]^v[a], -16)
+	v[c] = v[c] + v[d]
+	v[b] = bits.RotateLeft64(v[b]^v[c], -63)
+}
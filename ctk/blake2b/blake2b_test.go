@@ -0,0 +1,151 @@
+package blake2b_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/blake2b"
+)
+
+func TestBlake2bSum(t *testing.T) {
+	t.Run("RFC 7693 - Test Vectors - Appendix A", func(t *testing.T) {
+		t.Parallel()
+
+		data := []byte{0x61, 0x62, 0x63}
+
+		got := blake2b.Sum512(data)
+		want := [64]byte{
+			0xba, 0x80, 0xa5, 0x3f, 0x98, 0x1c, 0x4d, 0x0d, 0x6a, 0x27, 0x97, 0xb6, 0x9f, 0x12, 0xf6, 0xe9,
+			0x4c, 0x21, 0x2f, 0x14, 0x68, 0x5a, 0xc4, 0xb7, 0x4b, 0x12, 0xbb, 0x6f, 0xdb, 0xff, 0xa2, 0xd1,
+			0x7d, 0x87, 0xc5, 0x39, 0x2a, 0xab, 0x79, 0x2d, 0xc2, 0x52, 0xd5, 0xde, 0x45, 0x33, 0xcc, 0x95,
+			0x18, 0xd3, 0x8a, 0xa8, 0xdb, 0xf1, 0x92, 0x5a, 0xb9, 0x23, 0x86, 0xed, 0xd4, 0x00, 0x99, 0x23,
+		}
+
+		if got != want {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("BLAKE2b-256 - Empty Input", func(t *testing.T) {
+		t.Parallel()
+
+		got := blake2b.Sum256([]byte{})
+		want := [32]byte{
+			0x0e, 0x57, 0x51, 0xc0, 0x26, 0xe5, 0x43, 0xb2, 0xe8, 0xab, 0x2e, 0xb0, 0x60, 0x99, 0xda, 0xa1,
+			0xd1, 0xe5, 0xdf, 0x47, 0x77, 0x8f, 0x77, 0x87, 0xfa, 0xab, 0x45, 0xcd, 0xf1, 0x2f, 0xe3, 0xa8,
+		}
+
+		if got != want {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+}
+
+func TestBlake2bKeyed(t *testing.T) {
+	key := []byte{
+		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+		0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f,
+		0x20, 0x21, 0x22, 0x23, 0x24, 0x25, 0x26, 0x27, 0x28, 0x29, 0x2a, 0x2b, 0x2c, 0x2d, 0x2e, 0x2f,
+		0x30, 0x31, 0x32, 0x33, 0x34, 0x35, 0x36, 0x37, 0x38, 0x39, 0x3a, 0x3b, 0x3c, 0x3d, 0x3e, 0x3f,
+	}
+
+	t.Run("BLAKE2 Reference - Keyed KAT - #0", func(t *testing.T) {
+		t.Parallel()
+
+		b, _ := blake2b.NewBlake2b(blake2b.Size, key)
+
+		got := b.Sum(nil)
+		want := []byte{
+			0x10, 0xeb, 0xb6, 0x77, 0x00, 0xb1, 0x86, 0x8e, 0xfb, 0x44, 0x17, 0x98, 0x7a, 0xcf, 0x46, 0x90,
+			0xae, 0x9d, 0x97, 0x2f, 0xb7, 0xa5, 0x90, 0xc2, 0xf0, 0x28, 0x71, 0x79, 0x9a, 0xaa, 0x47, 0x86,
+			0xb5, 0xe9, 0x96, 0xe8, 0xf0, 0xf4, 0xeb, 0x98, 0x1f, 0xc2, 0x14, 0xb0, 0x05, 0xf4, 0x2d, 0x2f,
+			0xf4, 0x23, 0x34, 0x99, 0x39, 0x16, 0x53, 0xdf, 0x7a, 0xef, 0xcb, 0xc1, 0x3f, 0xc5, 0x15, 0x68,
+		}
+
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("BLAKE2 Reference - Keyed KAT - #3", func(t *testing.T) {
+		t.Parallel()
+
+		b, _ := blake2b.NewBlake2b(blake2b.Size, key)
+		b.Write([]byte{0x00})
+		b.Write([]byte{0x01, 0x02})
+
+		got := b.Sum(nil)
+		want := []byte{
+			0x33, 0xd0, 0x82, 0x5d, 0xdd, 0xf7, 0xad, 0xa9, 0x9b, 0x0e, 0x7e, 0x30, 0x71, 0x04, 0xad, 0x07,
+			0xca, 0x9c, 0xfd, 0x96, 0x92, 0x21, 0x4f, 0x15, 0x61, 0x35, 0x63, 0x15, 0xe7, 0x84, 0xf3, 0xe5,
+			0xa1, 0x7e, 0x36, 0x4a, 0xe9, 0xdb, 0xb1, 0x4c, 0xb2, 0x03, 0x6d, 0xf9, 0x32, 0xb7, 0x7f, 0x4b,
+			0x29, 0x27, 0x61, 0x36, 0x5f, 0xb3, 0x28, 0xde, 0x7a, 0xfd, 0xc6, 0xd8, 0x99, 0x8f, 0x5f, 0xc1,
+		}
+
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+}
+
+func TestBlake2b(t *testing.T) {
+	t.Run("Incremental Writes", func(t *testing.T) {
+		t.Parallel()
+
+		data := make([]byte, 3*blake2b.BlockSize+5)
+		for i := range data {
+			data[i] = byte(i)
+		}
+
+		want := blake2b.Sum512(data)
+
+		b := blake2b.New512()
+		for i := range data {
+			b.Write(data[i : i+1])
+		}
+
+		got := [64]byte(b.Sum(nil))
+
+		if got != want {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		t.Parallel()
+
+		b := blake2b.New256()
+		b.Write([]byte{0x01, 0x02, 0x03})
+		b.Reset()
+
+		got := [32]byte(b.Sum(nil))
+		want := blake2b.Sum256([]byte{})
+
+		if got != want {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Invalid Parameters", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]struct {
+			size int
+			key  []byte
+			err  error
+		}{
+			"Size 0":   {size: 0, key: nil, err: blake2b.ErrInvalidSize},
+			"Size 65":  {size: 65, key: nil, err: blake2b.ErrInvalidSize},
+			"Key Size": {size: 32, key: make([]byte, 65), err: blake2b.ErrInvalidKeySize},
+		}
+
+		for name, tc := range tt {
+			_, err := blake2b.NewBlake2b(tc.size, tc.key)
+
+			if !errors.Is(err, tc.err) {
+				t.Errorf("%v: want error %v, got %v", name, tc.err, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,316 @@
+// Package blobstore implements a content-addressed store of encrypted blobs on
+// the filesystem.
+//
+// Every blob is stored in its own file as a stream container (see the stream
+// package) which is encrypted with a key from a keystore. The ID of a blob is
+// the keyed BLAKE2b-256 hash of its plaintext so that identical blobs are only
+// stored once while the IDs don't reveal anything about the contents to anyone
+// without the key.
+//
+// Blob file format:
+//
+//	key version (4, big endian) | stream container
+//
+// New blobs are encrypted with the latest version of the key. Older versions
+// stay usable for decryption so that the key can be rotated via the keystore.
+package blobstore
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/pmuens/ctk-go/ctk/blake2b"
+	"github.com/pmuens/ctk-go/ctk/keystore"
+	"github.com/pmuens/ctk-go/ctk/stream"
+	"github.com/pmuens/ctk-go/ctk/subtle"
+)
+
+const (
+	// ErrInvalidKey is returned if the keystore key isn't a 32 byte key.
+	ErrInvalidKey = Error("invalid blob store key")
+
+	// ErrInvalidID is returned if a blob ID is malformed.
+	ErrInvalidID = Error("invalid blob ID")
+
+	// ErrNotFound is returned if there's no blob with the ID.
+	ErrNotFound = Error("blob not found")
+
+	// ErrCorrupted is returned if a blob file is malformed or its content
+	// doesn't match its ID.
+	ErrCorrupted = Error("blob corrupted")
+)
+
+// IDSize is the size (in bytes) of a (decoded) blob ID.
+const IDSize = 32
+
+// idLabel is used for domain separation when deriving the ID key.
+var idLabel = []byte("ctk-go blobstore id")
+
+// ID identifies a blob. It's the hex encoded keyed hash of the blob's plaintext.
+type ID string
+
+// ParseID parses a hex encoded blob ID.
+// Returns an error if the ID is malformed.
+func ParseID(s string) (ID, error) {
+	decoded, err := hex.DecodeString(s)
+	if err != nil || len(decoded) != IDSize {
+		return "", ErrInvalidID
+	}
+
+	// Normalize the ID so that it always maps to the same file.
+	return ID(hex.EncodeToString(decoded)), nil
+}
+
+// Store is a blob store in a directory.
+// A Store can be used concurrently as long as the keystore isn't modified at
+// the same time.
+type Store struct {
+	// dir is the directory the blobs are stored in.
+	dir string
+
+	// keystore holds the encryption keys.
+	keystore *keystore.Keystore
+
+	// name is the name of the keystore key.
+	name string
+
+	// idKey is the key used to compute the blob IDs.
+	idKey [32]byte
+}
+
+// New creates a blob store in dir (which is created if it doesn't exist) that
+// encrypts the blobs with the keystore key with the name.
+// Returns an error if there's no such key or if it isn't a 32 byte key.
+func New(dir string, ks *keystore.Keystore, name string) (*Store, error) {
+	// The ID key is derived from the first version of the key so that the IDs
+	// don't change when the key is rotated.
+	first, err := ks.GetKeyVersion(name, 1)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(first.Material)
+
+	if len(first.Material) != 32 {
+		return nil, ErrInvalidKey
+	}
+
+	// The key size is valid so that no error can occur.
+	h, _ := blake2b.NewBlake2b(32, first.Material)
+	h.Write(idLabel)
+	idKey := [32]byte(h.Sum(nil))
+
+	err = os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		dir:      dir,
+		keystore: ks,
+		name:     name,
+		idKey:    idKey,
+	}, nil
+}
+
+// Put encrypts the data read from r, stores it and returns its ID.
+// The data is only stored once if it's put multiple times.
+func (s *Store) Put(r io.Reader) (ID, error) {
+	key, err := s.keystore.GetKey(s.name)
+	if err != nil {
+		return "", err
+	}
+	defer clear(key.Material)
+
+	if len(key.Material) != 32 {
+		return "", ErrInvalidKey
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".blob.tmp*")
+	if err != nil {
+		return "", err
+	}
+	// The temporary file is renamed on success which makes the removal a no-op.
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	err = binary.Write(tmp, binary.BigEndian, uint32(key.Version))
+	if err != nil {
+		return "", err
+	}
+
+	w, err := stream.NewWriter(tmp, [32]byte(key.Material))
+	if err != nil {
+		return "", err
+	}
+
+	h := s.newHash()
+
+	_, err = io.Copy(io.MultiWriter(w, h), r)
+	if err != nil {
+		return "", err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return "", err
+	}
+
+	err = tmp.Sync()
+	if err != nil {
+		return "", err
+	}
+
+	id := ID(hex.EncodeToString(h.Sum(nil)))
+	path := s.path(id)
+
+	// The blob already exists which is why the new copy is discarded.
+	_, err = os.Stat(path)
+	if err == nil {
+		return id, nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return "", err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return "", err
+	}
+
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// Get returns a reader of the decrypted blob with the ID which needs to be
+// closed after use.
+// The blob is verified while it's read. Read returns an error (rather than
+// io.EOF) at the end of the blob if it was tampered with.
+// Returns an error if the ID is malformed or there's no such blob.
+func (s *Store) Get(id ID) (io.ReadCloser, error) {
+	id, err := ParseID(string(id))
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(s.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var version uint32
+
+	err = binary.Read(f, binary.BigEndian, &version)
+	if err != nil {
+		f.Close()
+		return nil, ErrCorrupted
+	}
+
+	key, err := s.keystore.GetKeyVersion(s.name, int(version))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	defer clear(key.Material)
+
+	if len(key.Material) != 32 {
+		f.Close()
+		return nil, ErrInvalidKey
+	}
+
+	r, err := stream.NewReader(f, [32]byte(key.Material))
+	if err != nil {
+		f.Close()
+		return nil, ErrCorrupted
+	}
+
+	return &blobReader{
+		r:    r,
+		f:    f,
+		hash: s.newHash(),
+		id:   id,
+	}, nil
+}
+
+// Delete deletes the blob with the ID.
+// Returns an error if the ID is malformed or there's no such blob.
+func (s *Store) Delete(id ID) error {
+	id, err := ParseID(string(id))
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(s.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+
+	return err
+}
+
+// path returns the path of the blob's file. The blobs are spread across
+// subdirectories (named after the ID's first byte) to keep directories small.
+func (s *Store) path(id ID) string {
+	return filepath.Join(s.dir, string(id[0:2]), string(id[2:]))
+}
+
+// newHash returns the keyed hash that computes blob IDs.
+func (s *Store) newHash() hash.Hash {
+	// The key and digest sizes are valid so that no error can occur.
+	h, _ := blake2b.NewBlake2b(IDSize, s.idKey[:])
+
+	return h
+}
+
+// blobReader decrypts a blob and verifies that its content matches its ID.
+type blobReader struct {
+	// r decrypts the blob.
+	r *stream.Reader
+
+	// f is the blob's file.
+	f *os.File
+
+	// hash computes the ID of the read content.
+	hash hash.Hash
+
+	// id is the ID of the blob.
+	id ID
+}
+
+// Read reads the decrypted blob.
+func (b *blobReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	b.hash.Write(p[:n])
+
+	if errors.Is(err, stream.ErrDecryption) {
+		return n, ErrCorrupted
+	}
+
+	if err == io.EOF {
+		got := hex.EncodeToString(b.hash.Sum(nil))
+		if subtle.ConstantTimeCompare([]byte(got), []byte(b.id)) != 1 {
+			return n, ErrCorrupted
+		}
+	}
+
+	return n, err
+}
+
+// Close closes the blob's file.
+func (b *blobReader) Close() error {
+	return b.f.Close()
+}
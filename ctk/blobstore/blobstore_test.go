@@ -0,0 +1,219 @@
+package blobstore_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/argon2"
+	"github.com/pmuens/ctk-go/ctk/blobstore"
+	"github.com/pmuens/ctk-go/ctk/keystore"
+)
+
+// params are cheap Argon2id parameters to keep the tests fast.
+var params = argon2.Params{Time: 1, Memory: 64, Parallelism: 1}
+
+// newStore creates a blob store backed by a new keystore with a "blobs" key.
+func newStore(t *testing.T) (*blobstore.Store, *keystore.Keystore, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	ks, err := keystore.Create(filepath.Join(dir, "keystore.json"), []byte("passphrase"), params)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+	t.Cleanup(func() { ks.Close() })
+
+	ks.AddKey("blobs", nil)
+
+	blobDir := filepath.Join(dir, "blobs")
+
+	store, err := blobstore.New(blobDir, ks, "blobs")
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	return store, ks, blobDir
+}
+
+// get reads the whole blob with the ID.
+func get(store *blobstore.Store, id blobstore.ID) ([]byte, error) {
+	r, err := store.Get(id)
+	if err != nil {
+		return []byte{}, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+func TestBlobStore(t *testing.T) {
+	t.Run("Put + Get + Delete", func(t *testing.T) {
+		t.Parallel()
+
+		store, _, _ := newStore(t)
+		data := bytes.Repeat([]byte("attack at dawn "), 10000)
+
+		id, err := store.Put(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		got, err := get(store, id)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if !slices.Equal(got, data) {
+			t.Errorf("want blob to match")
+		}
+
+		err = store.Delete(id)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		_, err = store.Get(id)
+
+		if !errors.Is(err, blobstore.ErrNotFound) {
+			t.Errorf("want error %v, got %v", blobstore.ErrNotFound, err)
+		}
+	})
+
+	t.Run("Content Addressed", func(t *testing.T) {
+		t.Parallel()
+
+		store, _, dir := newStore(t)
+
+		first, _ := store.Put(strings.NewReader("attack at dawn"))
+		second, _ := store.Put(strings.NewReader("attack at dawn"))
+		other, _ := store.Put(strings.NewReader("attack at dusk"))
+
+		if first != second {
+			t.Errorf("want %v, got %v", first, second)
+		}
+
+		if first == other {
+			t.Errorf("want different IDs, got %v twice", first)
+		}
+
+		// Every blob is stored once and no temporary files are left behind.
+		var files []string
+		filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if !d.IsDir() {
+				files = append(files, path)
+			}
+			return err
+		})
+
+		if len(files) != 2 {
+			t.Errorf("want %v files, got %v", 2, files)
+		}
+	})
+
+	t.Run("Rotation", func(t *testing.T) {
+		t.Parallel()
+
+		store, ks, _ := newStore(t)
+
+		before, _ := store.Put(strings.NewReader("before rotation"))
+		ks.Rotate("blobs")
+		after, _ := store.Put(strings.NewReader("after rotation"))
+
+		for id, want := range map[blobstore.ID]string{before: "before rotation", after: "after rotation"} {
+			got, err := get(store, id)
+			if err != nil {
+				t.Fatalf("want error %v, got %v", nil, err)
+			}
+
+			if string(got) != want {
+				t.Errorf("want %v, got %v", want, string(got))
+			}
+		}
+
+		again, _ := store.Put(strings.NewReader("before rotation"))
+
+		if again != before {
+			t.Errorf("want %v, got %v", before, again)
+		}
+	})
+
+	t.Run("Corrupted", func(t *testing.T) {
+		t.Parallel()
+
+		store, _, dir := newStore(t)
+
+		id, _ := store.Put(strings.NewReader("attack at dawn"))
+		path := filepath.Join(dir, string(id[0:2]), string(id[2:]))
+
+		data, _ := os.ReadFile(path)
+		data[len(data)-1] ^= 0x01
+		os.WriteFile(path, data, 0o600)
+
+		_, err := get(store, id)
+
+		if !errors.Is(err, blobstore.ErrCorrupted) {
+			t.Errorf("want error %v, got %v", blobstore.ErrCorrupted, err)
+		}
+	})
+
+	t.Run("Swapped", func(t *testing.T) {
+		t.Parallel()
+
+		store, _, dir := newStore(t)
+
+		first, _ := store.Put(strings.NewReader("attack at dawn"))
+		second, _ := store.Put(strings.NewReader("attack at dusk"))
+
+		// Moving a blob to another ID is detected once it's read completely.
+		firstPath := filepath.Join(dir, string(first[0:2]), string(first[2:]))
+		secondPath := filepath.Join(dir, string(second[0:2]), string(second[2:]))
+		os.Rename(secondPath, firstPath)
+
+		_, err := get(store, first)
+
+		if !errors.Is(err, blobstore.ErrCorrupted) {
+			t.Errorf("want error %v, got %v", blobstore.ErrCorrupted, err)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		store, ks, dir := newStore(t)
+
+		ks.AddKey("short", []byte{0x01, 0x02, 0x03, 0x04})
+		_, shortKeyErr := blobstore.New(dir, ks, "short")
+		_, missingKeyErr := blobstore.New(dir, ks, "missing")
+
+		tt := map[string]struct {
+			err  error
+			want error
+		}{
+			"Invalid Key":    {err: shortKeyErr, want: blobstore.ErrInvalidKey},
+			"Missing Key":    {err: missingKeyErr, want: keystore.ErrKeyNotFound},
+			"Path Traversal": {err: second(store.Get("../../keystore.json")), want: blobstore.ErrInvalidID},
+			"Short ID":       {err: second(store.Get("abcd")), want: blobstore.ErrInvalidID},
+			"Missing Blob":   {err: second(store.Get(blobstore.ID(strings.Repeat("ab", 32)))), want: blobstore.ErrNotFound},
+			"Delete Missing": {err: store.Delete(blobstore.ID(strings.Repeat("ab", 32))), want: blobstore.ErrNotFound},
+			"Delete Invalid": {err: store.Delete("../keystore.json"), want: blobstore.ErrInvalidID},
+		}
+
+		for name, tc := range tt {
+			if !errors.Is(tc.err, tc.want) {
+				t.Errorf("%v: want error %v, got %v", name, tc.want, tc.err)
+			}
+		}
+	})
+}
+
+// second returns the second of two return values.
+func second[T any](_ T, err error) error {
+	return err
+}
@@ -1,22 +1,79 @@
 // Package chacha20 implements the ChaCha20 stream cipher as specified in
 // https://datatracker.ietf.org/doc/html/rfc8439.
+//
+// The block counter is 32 bits so that the keystream of a key and nonce is
+// limited to 2^32 blocks (256 GiB). XORWithKeyStream panics if data would need
+// a block after the one with the counter 2^32-1 instead of silently wrapping
+// the counter around to 0 which would repeat the keystream. CreateBlock is the
+// raw block function whose counter does wrap around.
 package chacha20
 
 import (
 	"encoding/binary"
-	"math"
+	"fmt"
+	"io"
 	"math/bits"
 	"slices"
+
+	"github.com/pmuens/ctk-go/ctk/internal/chunk"
+	"github.com/pmuens/ctk-go/ctk/internal/leutil"
+	"github.com/pmuens/ctk-go/ctk/internal/trace"
 )
 
 // BlockSize is the size (in bytes) of the input to be processed at a time.
 const BlockSize = 64
 
+// DefaultRounds is the number of rounds specified in RFC 8439.
+const DefaultRounds = 20
+
+// Option configures a ChaCha20 instance.
+type Option func(*ChaCha20)
+
+// WithRounds sets the number of rounds the block function runs.
+// The reduced-round variants ChaCha8 and ChaCha12 are faster, but have a
+// smaller security margin and aren't covered by RFC 8439.
+// Panics if the rounds aren't 8, 12 or 20.
+func WithRounds(rounds int) Option {
+	if rounds != 8 && rounds != 12 && rounds != 20 {
+		panic("chacha20: rounds need to be 8, 12 or 20")
+	}
+
+	return func(c *ChaCha20) {
+		c.rounds = rounds
+	}
+}
+
+// WithTrace writes the intermediate states of the block function (the initial
+// state, the state after every double round, the state after adding the initial
+// state and the serialized block) to w. The states are formatted like the
+// worked examples of RFC 8439 so that learners can follow the computations.
+// Tracing slows the cipher down considerably and is meant for education only.
+func WithTrace(w io.Writer) Option {
+	return func(c *ChaCha20) {
+		c.trace = w
+	}
+}
+
+// WithInitialCounter sets the block counter of the first keystream block (it
+// overrides the counter passed to the constructor which can then be left zero).
+func WithInitialCounter(counter uint32) Option {
+	return func(c *ChaCha20) {
+		c.counter = counter
+		c.state = initState(c.key, c.nonce, c.counter)
+	}
+}
+
 // ChaCha20 is a stateful instance of the ChaCha stream cipher.
+// An instance isn't safe for concurrent use (use Clone to hand a copy to
+// another goroutine).
 type ChaCha20 struct {
 	// counter is the block counter.
 	counter uint32
 
+	// exhausted indicates whether the block with the counter 2^32-1 was created
+	// so that the next block would repeat the keystream.
+	exhausted bool
+
 	// key is the key used for encryption / decryption.
 	key [8]uint32
 
@@ -25,125 +82,178 @@ type ChaCha20 struct {
 
 	// state is the internal state on which operations are performed.
 	state [16]uint32
+
+	// rounds is the number of rounds the block function runs.
+	rounds int
+
+	// trace receives the intermediate states (if set).
+	trace io.Writer
 }
 
-// NewChaCha20 creates a new instance of the ChaCha20 stream cipher.
-func NewChaCha20(key [32]byte, nonce [12]byte, counter [4]byte) *ChaCha20 {
-	// Key bits.
-	k := [8]uint32{
-		binary.LittleEndian.Uint32(key[0:4]),
-		binary.LittleEndian.Uint32(key[4:8]),
-		binary.LittleEndian.Uint32(key[8:12]),
-		binary.LittleEndian.Uint32(key[12:16]),
-		binary.LittleEndian.Uint32(key[16:20]),
-		binary.LittleEndian.Uint32(key[20:24]),
-		binary.LittleEndian.Uint32(key[24:28]),
-		binary.LittleEndian.Uint32(key[28:32]),
+// NewChaCha20 creates a new instance of the ChaCha20 stream cipher with the
+// counter encoded as 4 little endian bytes.
+//
+// Deprecated: Use NewChaCha20WithCounter which takes the counter as a uint32.
+func NewChaCha20(key [32]byte, nonce [12]byte, counter [4]byte, opts ...Option) *ChaCha20 {
+	return NewChaCha20WithCounter(key, nonce, binary.LittleEndian.Uint32(counter[:]), opts...)
+}
+
+// NewChaCha20WithCounter creates a new instance of the ChaCha20 stream cipher
+// whose first keystream block has the block counter counter.
+func NewChaCha20WithCounter(key [32]byte, nonce [12]byte, counter uint32, opts ...Option) *ChaCha20 {
+	c := &ChaCha20{
+		rounds: DefaultRounds,
+	}
+
+	c.ResetWithCounter(key, nonce, counter)
+
+	for _, opt := range opts {
+		opt(c)
 	}
 
+	return c
+}
+
+// Reset reinitializes the instance with the key, nonce and counter (encoded as
+// 4 little endian bytes) so that it can be reused without allocating a new one.
+// The options (e.g. the number of rounds) are kept.
+//
+// Deprecated: Use ResetWithCounter which takes the counter as a uint32.
+func (c *ChaCha20) Reset(key [32]byte, nonce [12]byte, counter [4]byte) {
+	c.ResetWithCounter(key, nonce, binary.LittleEndian.Uint32(counter[:]))
+}
+
+// ResetWithCounter reinitializes the instance with the key, nonce and counter
+// so that it can be reused without allocating a new one.
+// The options (e.g. the number of rounds) are kept.
+func (c *ChaCha20) ResetWithCounter(key [32]byte, nonce [12]byte, counter uint32) {
+	// Key bits.
+	leutil.ReadWords(c.key[:], key[:])
+
 	// Counter.
-	b := binary.LittleEndian.Uint32(counter[:])
+	c.counter = counter
+	c.exhausted = false
 
 	// Nonce bits.
-	n := [3]uint32{
-		binary.LittleEndian.Uint32(nonce[0:4]),
-		binary.LittleEndian.Uint32(nonce[4:8]),
-		binary.LittleEndian.Uint32(nonce[8:12]),
-	}
+	leutil.ReadWords(c.nonce[:], nonce[:])
 
 	// State.
-	var s = initState(k, n, b)
+	c.state = initState(c.key, c.nonce, c.counter)
+}
 
-	return &ChaCha20{
-		counter: b,
-		key:     k,
-		nonce:   n,
-		state:   s,
-	}
+// Clone returns a copy of the instance (including its current counter) which
+// can be used independently of the original.
+func (c *ChaCha20) Clone() *ChaCha20 {
+	clone := *c
+	return &clone
 }
 
 // XORWithKeyStream creates a key stream using the ChaCha20 block function
 // and XOR's the data with such key stream to create the return value.
 // This function is used for both, encryption and decryption.
+// Panics if the data needs more keystream blocks than are left before the
+// counter would wrap around (the data isn't processed in this case).
 func (c *ChaCha20) XORWithKeyStream(data []byte) []byte {
+	if blocks := (uint64(len(data)) + BlockSize - 1) / BlockSize; blocks > c.RemainingBlocks() {
+		panic("chacha20: counter overflow")
+	}
+
 	// Create a copy of the data to be processed so we can manipulate it directly.
 	result := slices.Clone(data)
 
-	numBlocks := int(math.Ceil(float64(len(data)) / BlockSize))
-
-	for i := range numBlocks {
-		keyStream := c.CreateBlock()
-
-		// A block is a BlockSize bytes (or less) block from the input data.
-		// Default to slice from the last sliced block to the end (to handle blocks
-		// that have fewer than BlockSize bytes).
-		block := result[(i * BlockSize):]
-		// Check if an exact BlockSize byte block can be sliced and slice it, if so.
-		if (i+1)*BlockSize < len(data) {
-			block = result[(i * BlockSize):((i + 1) * BlockSize)]
+	// The blocks are sub-slices of the result so that XORing them with the key
+	// stream modifies the result in place.
+	for block := range chunk.Blocks(result, BlockSize) {
+		// Turn the key stream block into bytes (little endian order) so that it
+		// can be XORed with the (possibly partial) block byte-by-byte.
+		words := c.CreateBlock()
+		var keyStream [BlockSize]byte
+		leutil.PutWords(keyStream[:], words[:])
+
+		for i := range block {
+			block[i] ^= keyStream[i]
 		}
+	}
 
-		// Process the block, 4 bytes a time (8 bit * 4 = 32 bit) as we're XORing it
-		// with one word (32 bit).
-		for i := 0; i+4 <= len(block); i += 4 {
-			// Extract a 32 bit value (uint32) from the key stream.
-			keyStreamIndex := i >> 2
-			word := keyStream[keyStreamIndex]
-			// XOR the block with the word byte-by-byte.
-			block[i] ^= byte(word)
-			block[i+1] ^= byte(word >> 8)
-			block[i+2] ^= byte(word >> 16)
-			block[i+3] ^= byte(word >> 24)
-		}
+	return result
+}
 
-		// The bitmask is used to calculate the maximum number of bytes that are a
-		// multiple of 4 that still fit into the current block.
-		// This works because the values 1, 2 and 3 in binary can't occur (2^0 and
-		// 2^1 are set to 0).
-		numProcessedBytes := len(block) & 0b1111100
-		// Check if there are still some bytes left to process.
-		if numProcessedBytes < len(block) {
-			// Extract a 32 bit value (uint32) from the key stream.
-			keyStreamIndex := numProcessedBytes >> 2
-			word := keyStream[keyStreamIndex]
-			// XOR the rest of the block with the word byte-by-byte.
-			rest := block[numProcessedBytes:]
-			for i := 0; i < len(rest); i++ {
-				rest[i] ^= byte(word)
-				word >>= 8
-			}
-		}
+// XORKeyStream XORs src with the key stream and writes the result to dst
+// without allocating (like crypto/cipher.Stream). dst needs to be at least as
+// long as src and may only overlap src entirely (e.g. for in-place
+// encryption).
+// Panics if dst is shorter than src or if src needs more keystream blocks than
+// are left before the counter would wrap around (src isn't processed in this
+// case).
+func (c *ChaCha20) XORKeyStream(dst []byte, src []byte) {
+	if len(dst) < len(src) {
+		panic("chacha20: output smaller than input")
 	}
 
-	return result
+	if blocks := (uint64(len(src)) + BlockSize - 1) / BlockSize; blocks > c.RemainingBlocks() {
+		panic("chacha20: counter overflow")
+	}
+
+	c.xor(dst[:len(src)], src)
 }
 
 // CreateBlock produces a 512 bit ChaCha20 block by permuting the state via 10
-// double rounds (10 * 2 = 20 rounds in total).
+// double rounds (10 * 2 = 20 rounds in total) or the configured number of rounds.
 func (s *ChaCha20) CreateBlock() [16]uint32 {
 	s.state = initState(s.key, s.nonce, s.counter)
 	old_state := s.state
 
-	s.TwentyRounds()
+	if s.trace != nil {
+		trace.State(s.trace, fmt.Sprintf("Initial state (block counter %d)", s.counter), s.state)
+	}
+
+	s.Permute()
 
 	for i, val := range old_state {
 		s.state[i] += val
 	}
 
-	// Increment the counter.
+	if s.trace != nil {
+		trace.State(s.trace, "State after adding the initial state", s.state)
+		trace.Bytes(s.trace, "Serialized block", leutil.WordsToBytes(s.state[:]))
+	}
+
+	// Increment the counter (which wraps around after 2^32-1).
 	s.counter += 1
+	if s.counter == 0 {
+		s.exhausted = true
+	}
 
 	return s.state
 }
 
 // TwentyRounds permutes the state by running the doubleRound function 10 times.
 func (s *ChaCha20) TwentyRounds() [16]uint32 {
-	for range 10 {
+	for i := range 10 {
+		s.doubleRound()
+		s.traceDoubleRound(i)
+	}
+	return s.state
+}
+
+// Permute permutes the state by running the doubleRound function rounds / 2
+// times (which is the same as TwentyRounds if the default rounds are used).
+func (s *ChaCha20) Permute() [16]uint32 {
+	for i := range s.rounds / 2 {
 		s.doubleRound()
+		s.traceDoubleRound(i)
 	}
 	return s.state
 }
 
+// traceDoubleRound writes the state after the i-th (zero based) double round to
+// the trace (if set).
+func (s *ChaCha20) traceDoubleRound(i int) {
+	if s.trace != nil {
+		trace.State(s.trace, fmt.Sprintf("State after double round %d", i+1), s.state)
+	}
+}
+
 // doubleRound permutes the state by running two rounds in sequence
 // (one column round and one diagonal round).
 func (s *ChaCha20) doubleRound() [16]uint32 {
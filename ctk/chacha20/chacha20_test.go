@@ -2,6 +2,7 @@ package chacha20_test
 
 import (
 	"slices"
+	"strings"
 	"testing"
 
 	"github.com/pmuens/ctk-go/ctk/chacha20"
@@ -277,6 +278,62 @@ func TestChaCha20XORWithKeyStream(t *testing.T) {
 	})
 }
 
+func TestChaCha20XORKeyStream(t *testing.T) {
+	key := [32]byte{0x01}
+	nonce := [12]byte{0x02}
+
+	data := make([]byte, 3*chacha20.BlockSize+5)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	want := chacha20.NewChaCha20WithCounter(key, nonce, 1).XORWithKeyStream(data)
+
+	t.Run("Separate Buffers", func(t *testing.T) {
+		t.Parallel()
+
+		got := make([]byte, len(data))
+		chacha20.NewChaCha20WithCounter(key, nonce, 1).XORKeyStream(got, data)
+
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("In Place", func(t *testing.T) {
+		t.Parallel()
+
+		got := slices.Clone(data)
+		chacha20.NewChaCha20WithCounter(key, nonce, 1).XORKeyStream(got, got)
+
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Allocations", func(t *testing.T) {
+		c := chacha20.NewChaCha20WithCounter(key, nonce, 1)
+		dst := make([]byte, len(data))
+
+		allocs := testing.AllocsPerRun(100, func() {
+			c.SetCounter(1)
+			c.XORKeyStream(dst, data)
+		})
+		if allocs != 0 {
+			t.Errorf("want %v, got %v", 0, allocs)
+		}
+	})
+
+	t.Run("Short Output", func(t *testing.T) {
+		t.Parallel()
+
+		c := chacha20.NewChaCha20WithCounter(key, nonce, 1)
+		if !panics(func() { c.XORKeyStream(make([]byte, len(data)-1), data) }) {
+			t.Errorf("want panic, got none")
+		}
+	})
+}
+
 func TestChaCha20BlockFunction(t *testing.T) {
 	t.Run("RFC 8439 - Test Vectors - 2.3.2", func(t *testing.T) {
 		t.Parallel()
@@ -470,3 +527,200 @@ func TestChaCha20BlockFunction(t *testing.T) {
 		}
 	})
 }
+
+func TestChaCha20Rounds(t *testing.T) {
+	// See: https://datatracker.ietf.org/doc/html/draft-strombergson-chacha-test-vectors-01 (TC1)
+	tt := map[int][]byte{
+		8: {
+			0x3e, 0x00, 0xef, 0x2f, 0x89, 0x5f, 0x40, 0xd6,
+			0x7f, 0x5b, 0xb8, 0xe8, 0x1f, 0x09, 0xa5, 0xa1,
+			0x2c, 0x84, 0x0e, 0xc3, 0xce, 0x9a, 0x7f, 0x3b,
+			0x18, 0x1b, 0xe1, 0x88, 0xef, 0x71, 0x1a, 0x1e,
+			0x98, 0x4c, 0xe1, 0x72, 0xb9, 0x21, 0x6f, 0x41,
+			0x9f, 0x44, 0x53, 0x67, 0x45, 0x6d, 0x56, 0x19,
+			0x31, 0x4a, 0x42, 0xa3, 0xda, 0x86, 0xb0, 0x01,
+			0x38, 0x7b, 0xfd, 0xb8, 0x0e, 0x0c, 0xfe, 0x42,
+		},
+		12: {
+			0x9b, 0xf4, 0x9a, 0x6a, 0x07, 0x55, 0xf9, 0x53,
+			0x81, 0x1f, 0xce, 0x12, 0x5f, 0x26, 0x83, 0xd5,
+			0x04, 0x29, 0xc3, 0xbb, 0x49, 0xe0, 0x74, 0x14,
+			0x7e, 0x00, 0x89, 0xa5, 0x2e, 0xae, 0x15, 0x5f,
+			0x05, 0x64, 0xf8, 0x79, 0xd2, 0x7a, 0xe3, 0xc0,
+			0x2c, 0xe8, 0x28, 0x34, 0xac, 0xfa, 0x8c, 0x79,
+			0x3a, 0x62, 0x9f, 0x2c, 0xa0, 0xde, 0x69, 0x19,
+			0x61, 0x0b, 0xe8, 0x2f, 0x41, 0x13, 0x26, 0xbe,
+		},
+		20: {
+			0x76, 0xb8, 0xe0, 0xad, 0xa0, 0xf1, 0x3d, 0x90,
+			0x40, 0x5d, 0x6a, 0xe5, 0x53, 0x86, 0xbd, 0x28,
+			0xbd, 0xd2, 0x19, 0xb8, 0xa0, 0x8d, 0xed, 0x1a,
+			0xa8, 0x36, 0xef, 0xcc, 0x8b, 0x77, 0x0d, 0xc7,
+			0xda, 0x41, 0x59, 0x7c, 0x51, 0x57, 0x48, 0x8d,
+			0x77, 0x24, 0xe0, 0x3f, 0xb8, 0xd8, 0x4a, 0x37,
+			0x6a, 0x43, 0xb8, 0xf4, 0x15, 0x18, 0xa1, 0x1c,
+			0xc3, 0x87, 0xb6, 0x69, 0xb2, 0xee, 0x65, 0x86,
+		},
+	}
+
+	for rounds, want := range tt {
+		cha := chacha20.NewChaCha20([32]byte{}, [12]byte{}, [4]byte{}, chacha20.WithRounds(rounds))
+
+		got := cha.XORWithKeyStream(make([]byte, 64))
+
+		if !slices.Equal(got, want) {
+			t.Errorf("%v rounds: want %v, got %v", rounds, want, got)
+		}
+	}
+}
+
+func TestChaCha20ResetClone(t *testing.T) {
+	t.Run("Reset", func(t *testing.T) {
+		t.Parallel()
+
+		key := [32]byte{0x01}
+		nonce := [12]byte{0x02}
+		counter := [4]byte{0x03}
+
+		data := []byte("attack at dawn")
+
+		cha := chacha20.NewChaCha20([32]byte{0x04}, [12]byte{0x05}, [4]byte{0x06})
+		cha.XORWithKeyStream(data)
+		cha.Reset(key, nonce, counter)
+
+		got := cha.XORWithKeyStream(data)
+		want := chacha20.NewChaCha20(key, nonce, counter).XORWithKeyStream(data)
+
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Clone", func(t *testing.T) {
+		t.Parallel()
+
+		data := make([]byte, 100)
+
+		cha := chacha20.NewChaCha20([32]byte{0x01}, [12]byte{0x02}, [4]byte{0x03})
+		cha.XORWithKeyStream(data)
+
+		clone := cha.Clone()
+
+		got := clone.XORWithKeyStream(data)
+		want := cha.XORWithKeyStream(data)
+
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+}
+
+func TestChaCha20BlockBoundaries(t *testing.T) {
+	t.Parallel()
+
+	key := [32]byte{0x01}
+	nonce := [12]byte{0x02}
+	counter := [4]byte{0x01}
+
+	// The keystream of the longest input is the reference for all shorter ones.
+	keyStream := chacha20.NewChaCha20(key, nonce, counter).XORWithKeyStream(make([]byte, 129))
+
+	for _, length := range []int{0, 1, 15, 16, 17, 63, 64, 65, 127, 128, 129} {
+		data := make([]byte, length)
+		for i := range data {
+			data[i] = byte(i)
+		}
+
+		got := chacha20.NewChaCha20(key, nonce, counter).XORWithKeyStream(data)
+
+		want := make([]byte, length)
+		for i := range want {
+			want[i] = data[i] ^ keyStream[i]
+		}
+
+		if !slices.Equal(got, want) {
+			t.Errorf("%v bytes: want %v, got %v", length, want, got)
+		}
+	}
+}
+
+func BenchmarkChaCha20XORWithKeyStream(b *testing.B) {
+	data := make([]byte, 1024)
+
+	b.SetBytes(int64(len(data)))
+
+	for range b.N {
+		chacha20.NewChaCha20([32]byte{0x01}, [12]byte{0x02}, [4]byte{}).XORWithKeyStream(data)
+	}
+}
+
+func TestChaCha20Trace(t *testing.T) {
+	t.Run("RFC 8439 - Test Vectors - 2.3.2", func(t *testing.T) {
+		t.Parallel()
+
+		key := [32]byte{
+			0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07,
+			0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+			0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17,
+			0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f,
+		}
+
+		nonce := [12]byte{
+			0x00, 0x00, 0x00, 0x09, 0x00, 0x00,
+			0x00, 0x4a, 0x00, 0x00, 0x00, 0x00,
+		}
+
+		counter := [4]byte{0x01, 0x00, 0x00, 0x00}
+
+		var trace strings.Builder
+		cha := chacha20.NewChaCha20(key, nonce, counter, chacha20.WithTrace(&trace))
+		cha.CreateBlock()
+
+		got := trace.String()
+
+		// The excerpts are the ones shown in RFC 8439 (section 2.3.2).
+		for _, want := range []string{
+			"Initial state (block counter 1):\n" +
+				"    61707865  3320646e  79622d32  6b206574\n" +
+				"    03020100  07060504  0b0a0908  0f0e0d0c\n" +
+				"    13121110  17161514  1b1a1918  1f1e1d1c\n" +
+				"    00000001  09000000  4a000000  00000000\n",
+			"State after double round 10:\n" +
+				"    837778ab  e238d763  a67ae21e  5950bb2f\n" +
+				"    c4f2d0c7  fc62bb2f  8fa018fc  3f5ec7b7\n" +
+				"    335271c2  f29489f3  eabda8fc  82e46ebd\n" +
+				"    d19c12b4  b04e16de  9e83d0cb  4e3c50a2\n",
+			"State after adding the initial state:\n" +
+				"    e4e7f110  15593bd1  1fdd0f50  c47120a3\n",
+			"Serialized block:\n" +
+				"    000  10 f1 e7 e4 d1 3b 59 15 50 0f dd 1f a3 20 71 c4  .....;Y.P.... q.\n",
+		} {
+			if !strings.Contains(got, want) {
+				t.Errorf("want trace to contain %q, got %q", want, got)
+			}
+		}
+
+		if strings.Count(got, "State after double round") != 10 {
+			t.Errorf("want %v double rounds, got %v", 10, strings.Count(got, "State after double round"))
+		}
+	})
+
+	t.Run("Output Unchanged", func(t *testing.T) {
+		t.Parallel()
+
+		var trace strings.Builder
+		withTrace := chacha20.NewChaCha20([32]byte{}, [12]byte{}, [4]byte{}, chacha20.WithTrace(&trace))
+		withoutTrace := chacha20.NewChaCha20([32]byte{}, [12]byte{}, [4]byte{})
+
+		got := withTrace.XORWithKeyStream(make([]byte, 100))
+		want := withoutTrace.XORWithKeyStream(make([]byte, 100))
+
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+
+		if strings.Count(trace.String(), "Serialized block") != 2 {
+			t.Errorf("want %v blocks, got %v", 2, strings.Count(trace.String(), "Serialized block"))
+		}
+	})
+}
@@ -0,0 +1,204 @@
+package chacha20_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/chacha20"
+)
+
+// panics reports whether f panics.
+func panics(f func()) (panicked bool) {
+	defer func() {
+		panicked = recover() != nil
+	}()
+
+	f()
+
+	return false
+}
+
+func TestChaCha20Counter(t *testing.T) {
+	// RFC 8439 - Test Vectors - 2.3.2 (key and nonce).
+	key := [32]byte{
+		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+		0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f,
+	}
+
+	nonce := [12]byte{
+		0x00, 0x00, 0x00, 0x09, 0x00, 0x00, 0x00, 0x4a, 0x00, 0x00, 0x00, 0x00,
+	}
+
+	// The keystream block with the counter 2^32-1 (computed with
+	// golang.org/x/crypto/chacha20).
+	lastBlock := []byte{
+		0xff, 0x29, 0x41, 0xb8, 0xd7, 0x40, 0xf6, 0xcb, 0xb5, 0x09, 0x36, 0xbf, 0x99, 0x7e, 0xbd, 0x52,
+		0x18, 0xcb, 0x10, 0x8d, 0xc5, 0x3f, 0x41, 0xc6, 0x48, 0x41, 0xd0, 0x21, 0x81, 0x67, 0x43, 0x0c,
+		0xa0, 0x3b, 0x77, 0x0c, 0xa7, 0x4c, 0xcb, 0x64, 0x2a, 0x28, 0x19, 0x4d, 0x1d, 0xed, 0xd2, 0xed,
+		0x13, 0x15, 0x1e, 0x25, 0xec, 0x5d, 0x7f, 0xae, 0xb6, 0xd0, 0x60, 0xbf, 0xb7, 0xe6, 0xb1, 0x46,
+	}
+
+	t.Run("Last Block", func(t *testing.T) {
+		t.Parallel()
+
+		cha := chacha20.NewChaCha20(key, nonce, [4]byte{}, chacha20.WithInitialCounter(0xffffffff))
+		got := cha.XORWithKeyStream(make([]byte, chacha20.BlockSize))
+
+		if !slices.Equal(got, lastBlock) {
+			t.Errorf("want %v, got %v", lastBlock, got)
+		}
+	})
+
+	t.Run("Counter Encodings", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]struct {
+			counter [4]byte
+			initial uint32
+		}{
+			"Zero":      {[4]byte{0x00, 0x00, 0x00, 0x00}, 0},
+			"One":       {[4]byte{0x01, 0x00, 0x00, 0x00}, 1},
+			"Multibyte": {[4]byte{0x04, 0x03, 0x02, 0x01}, 0x01020304},
+			"Maximum":   {[4]byte{0xff, 0xff, 0xff, 0xff}, 0xffffffff},
+		}
+
+		for name, tc := range tt {
+			data := make([]byte, chacha20.BlockSize)
+			want := chacha20.NewChaCha20(key, nonce, tc.counter).XORWithKeyStream(data)
+
+			got := chacha20.NewChaCha20(key, nonce, [4]byte{}, chacha20.WithInitialCounter(tc.initial)).XORWithKeyStream(data)
+			if !slices.Equal(got, want) {
+				t.Errorf("%v: want %v, got %v", name, want, got)
+			}
+
+			got = chacha20.NewChaCha20WithCounter(key, nonce, tc.initial).XORWithKeyStream(data)
+			if !slices.Equal(got, want) {
+				t.Errorf("%v: want %v, got %v", name, want, got)
+			}
+
+			cha := chacha20.NewChaCha20WithCounter([32]byte{}, [12]byte{}, 0)
+			cha.ResetWithCounter(key, nonce, tc.initial)
+
+			got = cha.XORWithKeyStream(data)
+			if !slices.Equal(got, want) {
+				t.Errorf("%v: want %v, got %v", name, want, got)
+			}
+		}
+	})
+
+	t.Run("Up To The Last Block", func(t *testing.T) {
+		t.Parallel()
+
+		// The blocks with the counters 2^32-2 and 2^32-1 can be used (the second
+		// one partially).
+		cha := chacha20.NewChaCha20(key, nonce, [4]byte{}, chacha20.WithInitialCounter(0xfffffffe))
+		got := cha.XORWithKeyStream(make([]byte, 2*chacha20.BlockSize-1))
+
+		if !slices.Equal(got[chacha20.BlockSize:], lastBlock[:chacha20.BlockSize-1]) {
+			t.Errorf("want %v, got %v", lastBlock[:chacha20.BlockSize-1], got[chacha20.BlockSize:])
+		}
+	})
+
+	t.Run("Wrap Around", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]struct {
+			initial uint32
+			lengths []int
+		}{
+			"Single Call":    {0xfffffffe, []int{2*chacha20.BlockSize + 1}},
+			"After Last":     {0xffffffff, []int{chacha20.BlockSize, 1}},
+			"After Partial":  {0xffffffff, []int{1, 1}},
+			"Last Block + 1": {0xffffffff, []int{chacha20.BlockSize + 1}},
+		}
+
+		for name, tc := range tt {
+			cha := chacha20.NewChaCha20(key, nonce, [4]byte{}, chacha20.WithInitialCounter(tc.initial))
+
+			last := len(tc.lengths) - 1
+			for _, length := range tc.lengths[:last] {
+				cha.XORWithKeyStream(make([]byte, length))
+			}
+
+			if !panics(func() { cha.XORWithKeyStream(make([]byte, tc.lengths[last])) }) {
+				t.Errorf("%v: want panic, got none", name)
+			}
+		}
+	})
+
+	t.Run("Empty Data", func(t *testing.T) {
+		t.Parallel()
+
+		cha := chacha20.NewChaCha20(key, nonce, [4]byte{}, chacha20.WithInitialCounter(0xffffffff))
+		cha.XORWithKeyStream(make([]byte, chacha20.BlockSize))
+
+		got := cha.XORWithKeyStream([]byte{})
+		if len(got) != 0 {
+			t.Errorf("want %v, got %v", 0, len(got))
+		}
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		t.Parallel()
+
+		cha := chacha20.NewChaCha20(key, nonce, [4]byte{}, chacha20.WithInitialCounter(0xffffffff))
+		cha.XORWithKeyStream(make([]byte, chacha20.BlockSize))
+
+		_, err := cha.MarshalCheckpoint([32]byte{0x01})
+		if !errors.Is(err, chacha20.ErrCounterOverflow) {
+			t.Errorf("want error %v, got %v", chacha20.ErrCounterOverflow, err)
+		}
+
+		cha.Reset(key, nonce, [4]byte{0xff, 0xff, 0xff, 0xff})
+
+		got := cha.XORWithKeyStream(make([]byte, chacha20.BlockSize))
+		if !slices.Equal(got, lastBlock) {
+			t.Errorf("want %v, got %v", lastBlock, got)
+		}
+	})
+
+	t.Run("Seek", func(t *testing.T) {
+		t.Parallel()
+
+		cha := chacha20.NewChaCha20WithCounter(key, nonce, 0)
+		cha.XORWithKeyStream(make([]byte, chacha20.BlockSize))
+
+		err := cha.Seek(chacha20.MaxKeystreamSize - chacha20.BlockSize)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if cha.Counter() != 0xffffffff || cha.RemainingBlocks() != 1 {
+			t.Errorf("want %v and %v, got %v and %v", 0xffffffff, 1, cha.Counter(), cha.RemainingBlocks())
+		}
+
+		got := cha.XORWithKeyStream(make([]byte, chacha20.BlockSize))
+		if !slices.Equal(got, lastBlock) {
+			t.Errorf("want %v, got %v", lastBlock, got)
+		}
+
+		// Moving the counter back recovers the exhausted instance.
+		cha.SetCounter(0xffffffff)
+
+		got = cha.XORWithKeyStream(make([]byte, chacha20.BlockSize))
+		if !slices.Equal(got, lastBlock) {
+			t.Errorf("want %v, got %v", lastBlock, got)
+		}
+	})
+
+	t.Run("CreateBlock Wraps", func(t *testing.T) {
+		t.Parallel()
+
+		// The raw block function wraps the counter around to 0.
+		cha := chacha20.NewChaCha20(key, nonce, [4]byte{}, chacha20.WithInitialCounter(0xffffffff))
+		cha.CreateBlock()
+
+		got := cha.CreateBlock()
+		want := chacha20.NewChaCha20(key, nonce, [4]byte{}).CreateBlock()
+
+		if got != want {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+}
@@ -0,0 +1,47 @@
+package chacha20_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/blake2b"
+	"github.com/pmuens/ctk-go/ctk/chacha20"
+	"github.com/pmuens/ctk-go/ctk/internal/edgecase"
+)
+
+func TestChaCha20EdgeCases(t *testing.T) {
+	t.Parallel()
+
+	key := edgecase.Key()
+	nonce := [12]byte(edgecase.Nonce(12))
+	counter := [4]byte{0x01}
+
+	// The digest of all ciphertexts was cross-checked with
+	// golang.org/x/crypto/chacha20.
+	digest := blake2b.New256()
+
+	for _, length := range edgecase.Lengths {
+		data := edgecase.Data(length)
+
+		ciphertext := chacha20.NewChaCha20(key, nonce, counter).XORWithKeyStream(data)
+		plaintext := chacha20.NewChaCha20(key, nonce, counter).XORWithKeyStream(ciphertext)
+
+		if !slices.Equal(plaintext, data) {
+			t.Errorf("%v bytes: want %v, got %v", length, data, plaintext)
+		}
+
+		digest.Write(ciphertext)
+	}
+
+	got := [32]byte(digest.Sum(nil))
+	want := [32]byte{
+		0x03, 0x7d, 0xf3, 0x9d, 0xc1, 0xaf, 0xcd, 0xd7,
+		0x12, 0x03, 0x69, 0x0a, 0x05, 0x73, 0x34, 0x68,
+		0x4f, 0x79, 0x53, 0x40, 0x18, 0xc0, 0x66, 0x76,
+		0x33, 0xc2, 0x86, 0x46, 0x8f, 0x85, 0xe3, 0x14,
+	}
+
+	if got != want {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
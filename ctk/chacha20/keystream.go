@@ -0,0 +1,70 @@
+package chacha20
+
+import "github.com/pmuens/ctk-go/ctk/internal/leutil"
+
+const (
+	// ErrCounterOverflow is returned if the keystream is exhausted because the
+	// 32 bit block counter would wrap around and repeat the keystream.
+	ErrCounterOverflow = Error("chacha20 counter overflow")
+)
+
+// KeystreamReader reads the raw ChaCha20 keystream (e.g. to use it as a
+// deterministic random number generator in simulations or to generate test
+// vectors).
+// It implements the io.Reader interface.
+type KeystreamReader struct {
+	// chacha20 is the instance that generates the keystream blocks.
+	chacha20 *ChaCha20
+
+	// block is the current keystream block.
+	block [BlockSize]byte
+
+	// offset is the offset of the next unread byte in the block.
+	offset int
+
+	// exhausted indicates whether the last block before the counter wraps around
+	// was generated.
+	exhausted bool
+}
+
+// KeystreamReader returns a reader of the keystream which starts at the
+// instance's current counter.
+// The reader advances the instance's counter so that the instance shouldn't be
+// used for anything else while the reader is in use.
+func (c *ChaCha20) KeystreamReader() *KeystreamReader {
+	return &KeystreamReader{
+		chacha20: c,
+		offset:   BlockSize,
+	}
+}
+
+// Read fills p with keystream bytes.
+// Returns ErrCounterOverflow once the counter would wrap around (after which no
+// further bytes are returned).
+func (r *KeystreamReader) Read(p []byte) (int, error) {
+	n := 0
+
+	for n < len(p) {
+		if r.offset == BlockSize {
+			if r.exhausted {
+				return n, ErrCounterOverflow
+			}
+
+			block := r.chacha20.CreateBlock()
+			leutil.PutWords(r.block[:], block[:])
+			r.offset = 0
+
+			// The counter wrapped around which means that the next block would
+			// repeat the keystream.
+			if r.chacha20.counter == 0 {
+				r.exhausted = true
+			}
+		}
+
+		copied := copy(p[n:], r.block[r.offset:])
+		r.offset += copied
+		n += copied
+	}
+
+	return n, nil
+}
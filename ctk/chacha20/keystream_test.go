@@ -0,0 +1,64 @@
+package chacha20_test
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/chacha20"
+)
+
+func TestKeystreamReader(t *testing.T) {
+	t.Run("Matches Keystream", func(t *testing.T) {
+		t.Parallel()
+
+		key := [32]byte{0x01}
+		nonce := [12]byte{0x02}
+		counter := [4]byte{0x01}
+
+		want := chacha20.NewChaCha20(key, nonce, counter).XORWithKeyStream(make([]byte, 200))
+
+		r := chacha20.NewChaCha20(key, nonce, counter).KeystreamReader()
+
+		// Read in chunks that don't align with the block size.
+		var got []byte
+		for _, size := range []int{1, 63, 65, 71} {
+			buf := make([]byte, size)
+
+			_, err := io.ReadFull(r, buf)
+			if err != nil {
+				t.Fatalf("want error %v, got %v", nil, err)
+			}
+
+			got = append(got, buf...)
+		}
+
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Counter Overflow", func(t *testing.T) {
+		t.Parallel()
+
+		counter := [4]byte{0xfe, 0xff, 0xff, 0xff}
+
+		r := chacha20.NewChaCha20([32]byte{}, [12]byte{}, counter).KeystreamReader()
+
+		// The blocks with the counters 0xfffffffe and 0xffffffff can be read.
+		n, err := io.ReadFull(r, make([]byte, 2*chacha20.BlockSize))
+		if err != nil || n != 2*chacha20.BlockSize {
+			t.Fatalf("want %v bytes and error %v, got %v and %v", 2*chacha20.BlockSize, nil, n, err)
+		}
+
+		_, err = r.Read(make([]byte, 1))
+
+		gotError := err
+		wantError := chacha20.ErrCounterOverflow
+
+		if !errors.Is(gotError, wantError) {
+			t.Errorf("want error %v, got %v", wantError, gotError)
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package chacha20
+
+import (
+	"encoding/binary"
+
+	"github.com/pmuens/ctk-go/ctk/internal/checkpoint"
+	"github.com/pmuens/ctk-go/ctk/internal/leutil"
+)
+
+const (
+	// ErrInvalidState is returned if an encoded state is malformed or was
+	// modified.
+	ErrInvalidState = Error("invalid chacha20 state")
+)
+
+// stateLabel identifies encoded ChaCha20 states.
+const stateLabel = "ctk-go chacha20"
+
+// stateSize is the size (in bytes) of an encoded state without the checkpoint
+// overhead.
+const stateSize = 1 + 32 + 12 + 4
+
+// MarshalCheckpoint encodes the instance's rounds, key, nonce and counter so
+// that a long-running encryption can be checkpointed and resumed via
+// UnmarshalCheckpoint:
+//
+//	rounds (1) | key (32) | nonce (12) | counter (4, big endian)
+//
+// The state is versioned and authenticated with a MAC whose key is derived
+// from the checkpoint key so that modifications (e.g. a rolled back counter)
+// are rejected. The checkpoint key needs to be kept apart from the encoding
+// (and differ from the ChaCha20 key) as anyone who knows it can forge states.
+// As the counter is a block counter, the state always points at the start of a
+// block (XORWithKeyStream discards the rest of a partial block).
+//
+// Warning: The encoding contains the key and needs to be stored as
+// confidentially as the key itself. Restoring the same state more than once and
+// encrypting different data reuses the keystream which breaks the encryption.
+// Returns ErrCounterOverflow if the keystream is exhausted.
+func (c *ChaCha20) MarshalCheckpoint(checkpointKey [32]byte) ([]byte, error) {
+	if c.exhausted {
+		return []byte{}, ErrCounterOverflow
+	}
+
+	state := make([]byte, stateSize)
+
+	state[0] = byte(c.rounds)
+	leutil.PutWords(state[1:33], c.key[:])
+	leutil.PutWords(state[33:45], c.nonce[:])
+	binary.BigEndian.PutUint32(state[45:], c.counter)
+
+	return checkpoint.Encode(stateLabel, state, checkpoint.Key(stateLabel, checkpointKey)), nil
+}
+
+// UnmarshalCheckpoint restores a state that was encoded via MarshalCheckpoint
+// with the checkpoint key.
+// The options that aren't part of the state (e.g. the trace) are kept.
+// Returns an error if the encoding is malformed, was modified or belongs to
+// another checkpoint key.
+func (c *ChaCha20) UnmarshalCheckpoint(checkpointKey [32]byte, data []byte) error {
+	state, ok := checkpoint.Decode(stateLabel, data, stateSize)
+	if !ok || len(state) != stateSize || !checkpoint.Verify(data, checkpoint.Key(stateLabel, checkpointKey)) {
+		return ErrInvalidState
+	}
+
+	rounds := int(state[0])
+	if rounds != 8 && rounds != 12 && rounds != 20 {
+		return ErrInvalidState
+	}
+
+	c.rounds = rounds
+	leutil.ReadWords(c.key[:], state[1:33])
+	leutil.ReadWords(c.nonce[:], state[33:45])
+	c.counter = binary.BigEndian.Uint32(state[45:])
+	c.exhausted = false
+	c.state = initState(c.key, c.nonce, c.counter)
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package chacha20_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/chacha20"
+	"github.com/pmuens/ctk-go/ctk/internal/checkpoint"
+)
+
+func TestChaCha20MarshalCheckpoint(t *testing.T) {
+	key := [32]byte{0x01}
+	nonce := [12]byte{0x02}
+	counter := [4]byte{0x03}
+	checkpointKey := [32]byte{0x04}
+
+	t.Run("Resume", func(t *testing.T) {
+		t.Parallel()
+
+		data := make([]byte, 3*chacha20.BlockSize)
+
+		cha := chacha20.NewChaCha20(key, nonce, counter, chacha20.WithRounds(12))
+		want := cha.XORWithKeyStream(data)
+
+		cha = chacha20.NewChaCha20(key, nonce, counter, chacha20.WithRounds(12))
+		first := cha.XORWithKeyStream(data[:chacha20.BlockSize])
+
+		state, err := cha.MarshalCheckpoint(checkpointKey)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		// The rounds are restored from the state.
+		resumed := chacha20.NewChaCha20([32]byte{}, [12]byte{}, [4]byte{})
+
+		err = resumed.UnmarshalCheckpoint(checkpointKey, state)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		got := append(first, resumed.XORWithKeyStream(data[chacha20.BlockSize:])...)
+
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Invalid State", func(t *testing.T) {
+		t.Parallel()
+
+		cha := chacha20.NewChaCha20(key, nonce, counter)
+		state, _ := cha.MarshalCheckpoint(checkpointKey)
+
+		tt := map[string]func(state []byte) []byte{
+			"Label":     func(state []byte) []byte { state[0] ^= 0x01; return state },
+			"Version":   func(state []byte) []byte { state[len("ctk-go chacha20")]++; return state },
+			"Key":       func(state []byte) []byte { state[len("ctk-go chacha20")+2] ^= 0x01; return state },
+			"Counter":   func(state []byte) []byte { state[len(state)-33]++; return state },
+			"Tag":       func(state []byte) []byte { state[len(state)-1] ^= 0x01; return state },
+			"Truncated": func(state []byte) []byte { return state[:len(state)-1] },
+			"Empty":     func(state []byte) []byte { return []byte{} },
+		}
+
+		for name, tamper := range tt {
+			err := chacha20.NewChaCha20(key, nonce, counter).UnmarshalCheckpoint(checkpointKey, tamper(slices.Clone(state)))
+			if !errors.Is(err, chacha20.ErrInvalidState) {
+				t.Errorf("%v: want error %v, got %v", name, chacha20.ErrInvalidState, err)
+			}
+		}
+	})
+
+	t.Run("Forged State", func(t *testing.T) {
+		t.Parallel()
+
+		cha := chacha20.NewChaCha20(key, nonce, counter)
+		cha.XORWithKeyStream(make([]byte, 2*chacha20.BlockSize))
+		encoded, _ := cha.MarshalCheckpoint(checkpointKey)
+
+		// The counter is rolled back and the state is tagged with everything
+		// the encoding reveals (including the ChaCha20 key).
+		state, _ := checkpoint.Decode("ctk-go chacha20", encoded, 0)
+		state = slices.Clone(state)
+		binary.BigEndian.PutUint32(state[45:], 3)
+
+		for name, macKey := range map[string][]byte{
+			"Key From State":   state[1:33],
+			"ChaCha20 Key":     checkpoint.Key("ctk-go chacha20", key),
+			"Other Checkpoint": checkpoint.Key("ctk-go chacha20", [32]byte{0x05}),
+		} {
+			forged := checkpoint.Encode("ctk-go chacha20", state, macKey)
+
+			err := chacha20.NewChaCha20(key, nonce, counter).UnmarshalCheckpoint(checkpointKey, forged)
+			if !errors.Is(err, chacha20.ErrInvalidState) {
+				t.Errorf("%v: want error %v, got %v", name, chacha20.ErrInvalidState, err)
+			}
+		}
+
+		err := chacha20.NewChaCha20(key, nonce, counter).UnmarshalCheckpoint([32]byte{0x05}, encoded)
+		if !errors.Is(err, chacha20.ErrInvalidState) {
+			t.Errorf("want error %v, got %v", chacha20.ErrInvalidState, err)
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package chacha20
+
+import (
+	"context"
+
+	"github.com/pmuens/ctk-go/ctk/internal/leutil"
+	"github.com/pmuens/ctk-go/ctk/internal/parallel"
+)
+
+// ParallelChunkSize is the number of bytes XORWithKeyStreamParallel processes
+// at a time (per worker) before it checks whether the context is done.
+const ParallelChunkSize = 64 * 1024
+
+// XORWithKeyStreamParallel is XORWithKeyStream which splits the data into
+// chunks of ParallelChunkSize bytes that are processed concurrently by up to
+// workers goroutines (one per CPU if workers is below 1). Every chunk uses a
+// clone of the instance which is moved to the chunk's position in the
+// keystream, so the result is the same as the one of XORWithKeyStream.
+// Returns the context's error if it's done before all chunks were processed in
+// which case the instance's counter isn't advanced.
+// Panics if the data needs more keystream blocks than are left before the
+// counter would wrap around (the data isn't processed in this case).
+func (c *ChaCha20) XORWithKeyStreamParallel(ctx context.Context, data []byte, workers int) ([]byte, error) {
+	blocks := (uint64(len(data)) + BlockSize - 1) / BlockSize
+	if blocks > c.RemainingBlocks() {
+		panic("chacha20: counter overflow")
+	}
+
+	result := make([]byte, len(data))
+	chunks := (len(data) + ParallelChunkSize - 1) / ParallelChunkSize
+
+	parallel.Range(chunks, parallel.Workers(workers), func(start int, end int) {
+		clone := c.Clone()
+		clone.SetCounter(c.counter + uint32(start*ParallelChunkSize/BlockSize))
+
+		for i := start; i < end; i++ {
+			if ctx.Err() != nil {
+				return
+			}
+
+			from := i * ParallelChunkSize
+			to := min(from+ParallelChunkSize, len(data))
+			clone.xor(result[from:to], data[from:to])
+		}
+	})
+
+	err := ctx.Err()
+	if err != nil {
+		return []byte{}, err
+	}
+
+	// Advance the counter as if the blocks were processed by the instance.
+	if blocks > 0 {
+		c.SetCounter(c.counter + uint32(blocks))
+		c.exhausted = c.counter == 0
+	}
+
+	return result, nil
+}
+
+// xor XORs src with the keystream and writes the result to dst (which needs to
+// be as long as src).
+func (c *ChaCha20) xor(dst []byte, src []byte) {
+	var keyStream [BlockSize]byte
+
+	for len(src) > 0 {
+		words := c.CreateBlock()
+		leutil.PutWords(keyStream[:], words[:])
+
+		n := min(BlockSize, len(src))
+		for i := range n {
+			dst[i] = src[i] ^ keyStream[i]
+		}
+
+		dst, src = dst[n:], src[n:]
+	}
+}
@@ -0,0 +1,80 @@
+package chacha20_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/chacha20"
+)
+
+func TestChaCha20XORWithKeyStreamParallel(t *testing.T) {
+	key := [32]byte{0x01, 0x02, 0x03}
+	nonce := [12]byte{0x04, 0x05, 0x06}
+
+	for _, size := range []int{0, 1, 64, 100, chacha20.ParallelChunkSize, 3*chacha20.ParallelChunkSize + 17} {
+		for _, workers := range []int{0, 1, 4} {
+			t.Run(fmt.Sprintf("%d bytes, %d workers", size, workers), func(t *testing.T) {
+				t.Parallel()
+
+				data := make([]byte, size)
+				for i := range data {
+					data[i] = byte(i)
+				}
+
+				serial := chacha20.NewChaCha20WithCounter(key, nonce, 7)
+				want := serial.XORWithKeyStream(data)
+
+				c := chacha20.NewChaCha20WithCounter(key, nonce, 7)
+
+				got, err := c.XORWithKeyStreamParallel(context.Background(), data, workers)
+				if err != nil {
+					t.Fatalf("want error %v, got %v", nil, err)
+				}
+
+				if !slices.Equal(got, want) {
+					t.Errorf("want result to match XORWithKeyStream")
+				}
+
+				if c.Counter() != serial.Counter() {
+					t.Errorf("want %v, got %v", serial.Counter(), c.Counter())
+				}
+			})
+		}
+	}
+
+	t.Run("Cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		c := chacha20.NewChaCha20WithCounter(key, nonce, 7)
+
+		_, err := c.XORWithKeyStreamParallel(ctx, make([]byte, 2*chacha20.ParallelChunkSize), 2)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("want error %v, got %v", context.Canceled, err)
+		}
+
+		if c.Counter() != 7 {
+			t.Errorf("want %v, got %v", 7, c.Counter())
+		}
+	})
+
+	t.Run("Last Block", func(t *testing.T) {
+		t.Parallel()
+
+		c := chacha20.NewChaCha20WithCounter(key, nonce, ^uint32(0))
+
+		_, err := c.XORWithKeyStreamParallel(context.Background(), make([]byte, chacha20.BlockSize), 1)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if c.RemainingBlocks() != 0 {
+			t.Errorf("want %v, got %v", 0, c.RemainingBlocks())
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package chacha20
+
+const (
+	// MaxBlocks is the number of keystream blocks of a key and nonce.
+	MaxBlocks = 1 << 32
+
+	// MaxKeystreamSize is the size (in bytes) of the keystream of a key and nonce.
+	MaxKeystreamSize = MaxBlocks * BlockSize
+)
+
+const (
+	// ErrUnalignedOffset is returned if a keystream offset isn't a multiple of
+	// the block size.
+	ErrUnalignedOffset = Error("chacha20 offset not a multiple of the block size")
+
+	// ErrOffsetOutOfRange is returned if a keystream offset is at or beyond the
+	// end of the keystream.
+	ErrOffsetOutOfRange = Error("chacha20 offset out of range")
+)
+
+// Counter returns the block counter of the next keystream block.
+func (c *ChaCha20) Counter() uint32 {
+	return c.counter
+}
+
+// SetCounter sets the block counter of the next keystream block so that the
+// keystream can be accessed at random positions. It also recovers an instance
+// whose keystream is exhausted.
+func (c *ChaCha20) SetCounter(counter uint32) {
+	c.counter = counter
+	c.exhausted = false
+	c.state = initState(c.key, c.nonce, c.counter)
+}
+
+// Seek moves the instance to the keystream byte offset (counted from the block
+// with the counter 0) so that the next call to XORWithKeyStream processes the
+// data that starts at the offset.
+// XORWithKeyStream always starts at a block boundary which is why the offset
+// needs to be a multiple of BlockSize (ErrUnalignedOffset is returned
+// otherwise). ErrOffsetOutOfRange is returned if the offset is at or beyond
+// MaxKeystreamSize.
+func (c *ChaCha20) Seek(offset uint64) error {
+	if offset%BlockSize != 0 {
+		return ErrUnalignedOffset
+	}
+
+	if offset >= MaxKeystreamSize {
+		return ErrOffsetOutOfRange
+	}
+
+	c.SetCounter(uint32(offset / BlockSize))
+
+	return nil
+}
+
+// RemainingBlocks returns the number of keystream blocks that are left before
+// the counter would wrap around and repeat the keystream.
+func (c *ChaCha20) RemainingBlocks() uint64 {
+	if c.exhausted {
+		return 0
+	}
+
+	return MaxBlocks - uint64(c.counter)
+}
@@ -0,0 +1,15 @@
+package chacha20poly1305
+
+// AddAAD adds segments to the additional authenticated data (AAD) of the
+// instance's next call (e.g. Encrypt, Decrypt, Seal or Open) for protocols whose
+// headers are scattered across buffers. The segments followed by the call's aad
+// argument are authenticated exactly like their concatenation, but they aren't
+// copied which is why they must not be modified until the call returns.
+func (c *ChaCha20Poly1305) AddAAD(segments ...[]byte) {
+	c.aad = append(c.aad, segments...)
+}
+
+// segments returns the AAD segments that were added via AddAAD followed by aad.
+func (c *ChaCha20Poly1305) segments(aad []byte) [][]byte {
+	return append(c.aad, aad)
+}
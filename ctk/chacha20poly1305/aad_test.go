@@ -0,0 +1,93 @@
+package chacha20poly1305_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/chacha20poly1305"
+)
+
+func TestChaCha20Poly1305AddAAD(t *testing.T) {
+	key := [32]byte{0x01}
+	nonce := [12]byte{0x02}
+	plaintext := []byte("Hello World")
+	aad := []byte("version 1 | header with 23 bytes | record 42")
+
+	want := chacha20poly1305.NewChaCha20Poly1305(key, nonce).Seal(nil, plaintext, aad)
+
+	tt := map[string]struct {
+		segments [][]byte
+		aad      []byte
+	}{
+		"Segments Only":        {[][]byte{aad[:9], aad[9:34], aad[34:]}, []byte{}},
+		"Segments And AAD":     {[][]byte{aad[:9], aad[9:34]}, aad[34:]},
+		"Empty Segments":       {[][]byte{{}, aad[:16], {}, aad[16:]}, nil},
+		"Single Byte Segments": {chunks(aad, 1), []byte{}},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			sealer := chacha20poly1305.NewChaCha20Poly1305(key, nonce)
+			sealer.AddAAD(tc.segments...)
+
+			got := sealer.Seal(nil, plaintext, tc.aad)
+			if !slices.Equal(got, want) {
+				t.Errorf("want %v, got %v", want, got)
+			}
+
+			opener := chacha20poly1305.NewChaCha20Poly1305(key, nonce)
+			opener.AddAAD(tc.segments[0])
+			opener.AddAAD(tc.segments[1:]...)
+
+			opened, err := opener.Open(nil, want, tc.aad)
+			if err != nil || !slices.Equal(opened, plaintext) {
+				t.Errorf("want %v, got %v (error %v)", plaintext, opened, err)
+			}
+		})
+	}
+
+	t.Run("Reordered Segments", func(t *testing.T) {
+		t.Parallel()
+
+		opener := chacha20poly1305.NewChaCha20Poly1305(key, nonce)
+		opener.AddAAD(aad[9:], aad[:9])
+
+		_, err := opener.Open(nil, want, []byte{})
+		if !errors.Is(err, chacha20poly1305.ErrInvalidTag) {
+			t.Errorf("want error %v, got %v", chacha20poly1305.ErrInvalidTag, err)
+		}
+	})
+}
+
+// chunks splits data into chunks of the size.
+func chunks(data []byte, size int) [][]byte {
+	var result [][]byte
+	for chunk := range slices.Chunk(data, size) {
+		result = append(result, chunk)
+	}
+
+	return result
+}
+
+func TestGeneratePoly1305InputSegments(t *testing.T) {
+	aad := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	ciphertext := []byte("ciphertext")
+
+	want := chacha20poly1305.GeneratePoly1305Input(aad, ciphertext)
+
+	for size := 1; size <= len(aad); size++ {
+		got := chacha20poly1305.GeneratePoly1305InputSegments(chunks(aad, size), ciphertext)
+		if !slices.Equal(got, want) {
+			t.Errorf("segment size %v: want %v, got %v", size, want, got)
+		}
+	}
+
+	got := chacha20poly1305.GeneratePoly1305InputSegments(nil, ciphertext)
+	want = chacha20poly1305.GeneratePoly1305Input([]byte{}, ciphertext)
+	if !slices.Equal(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
@@ -1,14 +1,29 @@
 // Package chacha20poly1305 implements the ChaCha20-Poly1305 authenticated
 // encryption with associated data (AEAD) algorithm as specified in
 // https://datatracker.ietf.org/doc/html/rfc8439.
+//
+// Concurrency: The stateful AEAD instances aren't safe for concurrent use and
+// are meant to be used for a single message. A Pool (which hands out an instance
+// per call) and a SyncAEAD (which serializes calls via a mutex) are safe for
+// concurrent use and can be shared between goroutines.
+//
+// Key usage: A KeyUsage counts the messages and bytes that are sealed under a
+// key (and optionally tracks its age via an injectable clock) and reports (via
+// ErrKeyExpired) once the key should be rotated.
+//
+// Tags: SealDetached and OpenDetached (as well as Encrypt and Decrypt) handle
+// the tag separately from the ciphertext (e.g. for libsodium's detached mode)
+// whereas Seal and Open append the tag to the ciphertext (e.g. for TLS records).
 package chacha20poly1305
 
 import (
 	"encoding/binary"
-	"slices"
 
 	"github.com/pmuens/ctk-go/ctk/chacha20"
+	"github.com/pmuens/ctk-go/ctk/internal/debug"
+	"github.com/pmuens/ctk-go/ctk/padding"
 	"github.com/pmuens/ctk-go/ctk/poly1305"
+	"github.com/pmuens/ctk-go/ctk/subtle"
 )
 
 const (
@@ -16,25 +31,137 @@ const (
 	ErrInvalidTag = Error("invalid Poly1305 tag")
 )
 
+// TagSize is the size (in bytes) of a (full) Poly1305 tag.
+const TagSize = 16
+
+// MinTagSize is the smallest size (in bytes) a tag can be truncated to.
+const MinTagSize = 8
+
+// Options are the settings of an AEAD instance which are set via Option
+// functions rather than via dedicated constructors.
+// Note that the nonce size is fixed by the algorithm (and the array type of the
+// API) which is why there's no option to change it.
+type Options struct {
+	// Rounds is the number of ChaCha20 rounds (0 uses the default of 20).
+	Rounds int
+
+	// TagSize is the number of tag bytes that are emitted and verified (0 uses
+	// the default of TagSize).
+	TagSize int
+
+	// Padding is the mode used to pad the plaintext before encryption (nil
+	// disables padding).
+	Padding padding.Mode
+}
+
+// Option configures an AEAD instance.
+type Option func(*Options)
+
+// WithRounds sets the number of rounds of the underlying stream cipher (see
+// chacha20.WithRounds).
+// Note that the reduced-round variants aren't interoperable with RFC 8439.
+// The constructor panics if the rounds aren't 8, 12 or 20.
+func WithRounds(rounds int) Option {
+	return func(o *Options) {
+		o.Rounds = rounds
+	}
+}
+
+// WithTruncatedTag truncates the tag to the size (in bytes).
+//
+// WARNING: A truncated tag significantly lowers the security level as forging a
+// message only requires guessing size (rather than 16) bytes. Only use this
+// option if a protocol requires it (e.g. for interoperability with protocols
+// that use 8 byte tags).
+//
+// Encrypt returns a tag in which all bytes after the size are zero and Decrypt
+// only compares the first size bytes.
+// Note that tags can't be extended beyond TagSize bytes.
+// Panics if the size isn't between MinTagSize and TagSize.
+func WithTruncatedTag(size int) Option {
+	if size < MinTagSize || size > TagSize {
+		panic("chacha20poly1305: tag size needs to be between 8 and 16 bytes")
+	}
+
+	return func(o *Options) {
+		o.TagSize = size
+	}
+}
+
+// WithPadding pads the plaintext according to the mode before it's encrypted
+// and removes the padding after decryption so that the ciphertext only reveals
+// the padded length.
+func WithPadding(mode padding.Mode) Option {
+	return func(o *Options) {
+		o.Padding = mode
+	}
+}
+
+// NewOptions applies the Option functions to the default options.
+func NewOptions(opts ...Option) Options {
+	o := Options{TagSize: TagSize}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// TruncateTag zeroes all bytes of the tag after the configured tag size.
+func (o Options) TruncateTag(tag [16]byte) [16]byte {
+	clear(tag[o.TagSize:])
+	return tag
+}
+
+// VerifyTag compares the configured number of bytes of both tags in constant
+// time.
+func (o Options) VerifyTag(tag [16]byte, computedTag [16]byte) bool {
+	return subtle.ConstantTimeCompare(tag[:o.TagSize], computedTag[:o.TagSize]) == 1
+}
+
+// CipherOptions turns the options into the options of the underlying stream
+// cipher.
+func (o Options) CipherOptions() []chacha20.Option {
+	var result []chacha20.Option
+
+	if o.Rounds != 0 {
+		result = append(result, chacha20.WithRounds(o.Rounds))
+	}
+
+	return result
+}
+
 // ChaCha20Poly1305 is a stateful instance of the ChaCha20-Poly1305 AEAD
 // algorithm.
+// An instance isn't safe for concurrent use. Use a Pool or a SyncAEAD to share
+// a key across goroutines.
 type ChaCha20Poly1305 struct {
 	// chacha20 is an instance of the ChaCha20 stream cipher.
 	chacha20 *chacha20.ChaCha20
 
 	// poly1305 is an instance of the Poly1305 one-time authenticator.
 	poly1305 *poly1305.Poly1305
+
+	// aad are the AAD segments that were added via AddAAD.
+	aad [][]byte
+
+	// debug tracks the use of the instance (see the ctkdebug build tag).
+	debug debug.Instance
+
+	// options are the options the instance was created with.
+	options Options
 }
 
 // NewChaCha20Poly1305 creates a new instance of the ChaCha20-Poly1305 AEAD
 // algorithm.
-func NewChaCha20Poly1305(key [32]byte, nonce [12]byte) *ChaCha20Poly1305 {
-	// The counter needs to be set to 0 as the first block of ChaCha20 will
-	// be used to generate the Poly1305 key.
-	counter := [4]byte{0x00, 0x00, 0x00, 0x00}
+func NewChaCha20Poly1305(key [32]byte, nonce [12]byte, opts ...Option) *ChaCha20Poly1305 {
+	options := NewOptions(opts...)
 
 	// Create a new instance of ChaCha20 that will be used for the AEAD construction.
-	chacha20 := chacha20.NewChaCha20(key, nonce, counter)
+	// The counter needs to be set to 0 as the first block of ChaCha20 will
+	// be used to generate the Poly1305 key.
+	chacha20 := chacha20.NewChaCha20WithCounter(key, nonce, 0, options.CipherOptions()...)
 
 	// Use ChaCha20's first block to generated the Poly1305 key and create a new
 	// instance of Poly1305 with it.
@@ -42,39 +169,115 @@ func NewChaCha20Poly1305(key [32]byte, nonce [12]byte) *ChaCha20Poly1305 {
 	polyKey := Poly1305KeyGen(firstBlock)
 	poly1305 := poly1305.NewPoly1305(polyKey)
 
-	return &ChaCha20Poly1305{
+	c := &ChaCha20Poly1305{
 		chacha20: chacha20,
 		poly1305: poly1305,
+		options:  options,
 	}
+	c.debug.Reset(key[:], nonce[:])
+
+	return c
+}
+
+// reset reinitializes the instance with the key and nonce so that it can be
+// reused for another message.
+func (c *ChaCha20Poly1305) reset(key [32]byte, nonce [12]byte) {
+	c.chacha20.ResetWithCounter(key, nonce, 0)
+
+	// Derive the new Poly1305 key from the first block (see the constructor).
+	firstBlock := c.chacha20.CreateBlock()
+	c.poly1305.Reset(Poly1305KeyGen(firstBlock))
+
+	clear(c.aad)
+	c.aad = c.aad[:0]
+
+	c.debug.Reset(key[:], nonce[:])
 }
 
 // Encrypt encrypts the plaintext via ChaCha20 and creates a message
 // authentication tag for the additional authenticated data (AAD) and the generated
 // ciphertext using Poly1305.
+// As the Poly1305 key is derived from the key and nonce, an instance can only
+// be used for a single Encrypt or Decrypt call.
+// Panics if the instance was already used (like the counter overflow of
+// ChaCha20, reusing it is a programming error).
 func (c *ChaCha20Poly1305) Encrypt(plaintext []byte, aad []byte) ([]byte, [16]byte) {
+	if debug.Enabled {
+		c.debug.Encrypt(plaintext, c.segments(aad)...)
+	}
+
+	if c.options.Padding != nil {
+		plaintext = padding.Pad(plaintext, c.options.Padding)
+	}
+
 	// Use ChaCha20 to encrypt the plaintext (note that at this point the counter
 	// is 1, given that we initialized ChaCha20 with a counter of 0 to generate
 	// the Poly1305 key).
 	ciphertext := c.chacha20.XORWithKeyStream(plaintext)
 
-	// Get the padded input for Poly1305 and create a tag based on such data.
-	poly1305Input := GeneratePoly1305Input(aad, ciphertext)
-	tag := c.poly1305.GenerateTag(poly1305Input)
+	// Create a tag based on the padded AAD and ciphertext.
+	tag, err := GeneratePoly1305Tag(c.poly1305, c.aad, aad, ciphertext)
+	if err != nil {
+		panic("chacha20poly1305: instance reused")
+	}
+
+	return ciphertext, c.options.TruncateTag(tag)
+}
+
+// Authenticate creates a tag for the additional authenticated data (AAD)
+// without encrypting anything (the same tag that Encrypt returns for an empty
+// plaintext). It's meant for callers who need authentication but no
+// encryption (e.g. for public headers). The padding option is ignored.
+// As for Encrypt, an instance can only be used for a single call.
+// Panics if the instance was already used.
+func (c *ChaCha20Poly1305) Authenticate(aad []byte) [16]byte {
+	if debug.Enabled {
+		c.debug.Encrypt([]byte{}, c.segments(aad)...)
+	}
+
+	tag, err := GeneratePoly1305Tag(c.poly1305, c.aad, aad, []byte{})
+	if err != nil {
+		panic("chacha20poly1305: instance reused")
+	}
 
-	return ciphertext, tag
+	return c.options.TruncateTag(tag)
+}
+
+// Verify checks the tag that was created via Authenticate for the additional
+// authenticated data (AAD).
+// Returns an error if the tag is invalid or if the instance was already used.
+func (c *ChaCha20Poly1305) Verify(aad []byte, tag [16]byte) error {
+	c.debug.Decrypt()
+
+	computedTag, err := GeneratePoly1305Tag(c.poly1305, c.aad, aad, []byte{})
+	if err != nil {
+		return err
+	}
+
+	if !c.options.VerifyTag(tag, computedTag) {
+		return ErrInvalidTag
+	}
+
+	return nil
 }
 
 // Decrypt checks if the tag generated via Poly1305 is valid using the additional
 // authenticated data (AAD) and the ciphertext. If valid it decrypts the ciphertext
 // using ChaCha20.
-// Returns an error if the tag is invalid.
+// Returns an error if the tag or the padding is invalid or if the instance was
+// already used.
 func (c *ChaCha20Poly1305) Decrypt(ciphertext []byte, aad []byte, tag [16]byte) ([]byte, error) {
-	// Get the padded input for Poly1305 and create a tag based on such data.
-	poly1305Input := GeneratePoly1305Input(aad, ciphertext)
-	computedTag := c.poly1305.GenerateTag(poly1305Input)
+	c.debug.Decrypt()
 
-	// Return an error and exit early if the tags don't match.
-	if tag != computedTag {
+	// Create a tag based on the padded AAD and ciphertext.
+	computedTag, err := GeneratePoly1305Tag(c.poly1305, c.aad, aad, ciphertext)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	// Return an error and exit early if the tags don't match (the comparison is
+	// done in constant time).
+	if !c.options.VerifyTag(tag, computedTag) {
 		return []byte{}, ErrInvalidTag
 	}
 
@@ -83,87 +286,113 @@ func (c *ChaCha20Poly1305) Decrypt(ciphertext []byte, aad []byte, tag [16]byte)
 	// the Poly1305 key).
 	plaintext := c.chacha20.XORWithKeyStream(ciphertext)
 
+	if c.options.Padding != nil {
+		return unpad(plaintext)
+	}
+
 	return plaintext, nil
 }
 
-// Poly1305KeyGen generates the Poly1305 key based on the first ChaCha20 block.
-func Poly1305KeyGen(block [16]uint32) [32]byte {
-	// The Poly1305 key will be 256 bit long (128 bit for the r and 128 bit for
-	// the s value).
-	var result [32]byte
+// unpad removes the padding from the decrypted plaintext. The plaintext is
+// wiped if the padding is invalid so that no decrypted data outlives the error.
+func unpad(plaintext []byte) ([]byte, error) {
+	unpadded, err := padding.Unpad(plaintext)
+	if err != nil {
+		clear(plaintext)
+		return []byte{}, err
+	}
 
-	// Only the first 256 bit of the 512 bit ChaCha20 state will be used.
-	// Iterate over every word (32 bit) of those 256 bit.
-	for i, word := range block[0:8] {
-		index := (i * 4)
+	return unpadded, nil
+}
 
-		// Extract the individual bytes from the word.
-		result[index] = byte(word)
-		result[index+1] = byte(word >> 8)
-		result[index+2] = byte(word >> 16)
-		result[index+3] = byte(word >> 24)
-	}
+// TagSize returns the number of meaningful bytes of the tags that are emitted
+// and verified.
+func (c *ChaCha20Poly1305) TagSize() int {
+	return c.options.TagSize
+}
 
-	return result
+// Poly1305KeyGen generates the (opaque) Poly1305 key based on the first ChaCha20
+// block.
+func Poly1305KeyGen(block [16]uint32) poly1305.OneTimeKey {
+	// The Poly1305 key will be 256 bit long (128 bit for the r and 128 bit for
+	// the s value).
+	// Only the first 256 bit (8 words) of the 512 bit ChaCha20 state will be
+	// used and turned into bytes with little endian order.
+	return poly1305.KeyFromBlock(block)
 }
 
 // GeneratePoly1305Input creates the (padded) input to be processed by Poly1305
 // to create a tag.
 func GeneratePoly1305Input(aad []byte, ciphertext []byte) []byte {
-	// Add padding to AAD so that its total length is a multiple of 16.
-	paddedAad := padTo16Bytes(aad)
-
-	// Add padding to ciphertext so that its total length is a multiple of 16.
-	paddedCiphertext := padTo16Bytes(ciphertext)
-
-	// Calculate length of AAD and turn it into bytes in little endian order.
-	// See: https://stackoverflow.com/a/29062148
-	aadLength := make([]byte, 8)
-	binary.LittleEndian.PutUint32(aadLength, uint32(len(aad)))
+	return GeneratePoly1305InputSegments([][]byte{aad}, ciphertext)
+}
 
-	// Calculate length of ciphertext and turn it into bytes in little endian order.
-	// See: https://stackoverflow.com/a/29062148
-	cipertextLength := make([]byte, 8)
-	binary.LittleEndian.PutUint32(cipertextLength, uint32(len(ciphertext)))
+// GeneratePoly1305InputSegments creates the same input as GeneratePoly1305Input
+// for the concatenation of the AAD segments without concatenating them first.
+func GeneratePoly1305InputSegments(aad [][]byte, ciphertext []byte) []byte {
+	aadLength := 0
+	for _, segment := range aad {
+		aadLength += len(segment)
+	}
 
 	// Create an empty result byte slice that has a capacity of the data that
 	// Poly1305 will compute a tag for.
-	result := make([]byte, 0, len(paddedAad)+len(paddedCiphertext)+len(aadLength)+len(cipertextLength))
+	result := make([]byte, 0, paddedLength(aadLength)+paddedLength(len(ciphertext))+8+8)
 
 	// 1. Additional authenticated data (AAD).
 	// 2. Padding #1 (>= 15 zero bytes. Total length = multiple of 16).
-	result = append(result, paddedAad...)
+	for _, segment := range aad {
+		result = append(result, segment...)
+	}
+	result = append(result, make([]byte, paddedLength(aadLength)-aadLength)...)
 
 	// 3. Ciphertext
 	// 4. Padding #2 (>= 15 zero bytes. Total length = multiple of 16).
-	result = append(result, paddedCiphertext...)
+	result = append(result, ciphertext...)
+	result = append(result, make([]byte, paddedLength(len(ciphertext))-len(ciphertext))...)
 
 	// 5. Length of AAD in octets as 64 bit little endian integer.
-	result = append(result, aadLength...)
+	result = binary.LittleEndian.AppendUint64(result, uint64(aadLength))
 
 	// 6. Length of ciphertext in octets as 64 bit little endian integer.
-	result = append(result, cipertextLength...)
+	result = binary.LittleEndian.AppendUint64(result, uint64(len(ciphertext)))
 
 	return result
 }
 
-// padTo16Bytes appends up to 15 zero bytes so that the data byte slice is always
-// a multiple of 16 bytes.
-// Note that the data that's passed-in won't be mutated, but a modified copy will
-// be returned.
-func padTo16Bytes(data []byte) []byte {
-	// Don't do anything if length of data is already divisible by 16.
-	if len(data)%16 == 0 {
-		return data
+// GeneratePoly1305Tag creates the tag for the same input as
+// GeneratePoly1305InputSegments (for the AAD segments followed by aad) by adding
+// the parts to the Poly1305 instance one after another rather than
+// concatenating them first (which avoids allocating the input).
+// Returns an error if the instance was already used to generate a tag.
+func GeneratePoly1305Tag(p *poly1305.Poly1305, segments [][]byte, aad []byte, ciphertext []byte) ([16]byte, error) {
+	var zeros [15]byte
+
+	// 1. Additional authenticated data (AAD).
+	// 2. Padding #1 (>= 15 zero bytes. Total length = multiple of 16).
+	aadLength := len(aad)
+	for _, segment := range segments {
+		p.Update(segment)
+		aadLength += len(segment)
 	}
+	p.Update(aad)
+	p.Update(zeros[:paddedLength(aadLength)-aadLength])
 
-	result := slices.Clone(data)
+	// 3. Ciphertext
+	// 4. Padding #2 (>= 15 zero bytes. Total length = multiple of 16).
+	p.Update(ciphertext)
+	p.Update(zeros[:paddedLength(len(ciphertext))-len(ciphertext)])
 
-	toPad := 16 - (len(data) % 16)
-	for range toPad {
-		// Append 0x00 as the padding.
-		result = append(result, 0x00)
-	}
+	// 5. Length of AAD in octets as 64 bit little endian integer.
+	// 6. Length of ciphertext in octets as 64 bit little endian integer.
+	var lengths [16]byte
+	binary.LittleEndian.PutUint64(lengths[0:8], uint64(aadLength))
+	binary.LittleEndian.PutUint64(lengths[8:16], uint64(len(ciphertext)))
 
-	return result
+	return p.GenerateTag(lengths[:])
+}
+
+// paddedLength returns the length rounded up to a multiple of 16 bytes.
+func paddedLength(length int) int {
+	return (length + 15) / 16 * 16
 }
@@ -7,6 +7,8 @@ import (
 
 	"github.com/pmuens/ctk-go/ctk/chacha20"
 	"github.com/pmuens/ctk-go/ctk/chacha20poly1305"
+	"github.com/pmuens/ctk-go/ctk/padding"
+	"github.com/pmuens/ctk-go/ctk/poly1305"
 )
 
 func TestChaCha20Poly1305Poly1305KeyGen(t *testing.T) {
@@ -31,10 +33,10 @@ func TestChaCha20Poly1305Poly1305KeyGen(t *testing.T) {
 		firstBlock := cha.CreateBlock()
 
 		got := chacha20poly1305.Poly1305KeyGen(firstBlock)
-		want := [32]byte{
+		want := poly1305.UnsafeOneTimeKeyFromBytes([32]byte{
 			0x8a, 0xd5, 0xa0, 0x8b, 0x90, 0x5f, 0x81, 0xcc, 0x81, 0x50, 0x40, 0x27, 0x4a, 0xb2, 0x94, 0x71,
 			0xa8, 0x33, 0xb6, 0x37, 0xe3, 0xfd, 0x0d, 0xa5, 0x08, 0xdb, 0xb8, 0xe2, 0xfd, 0xd1, 0xa6, 0x46,
-		}
+		})
 
 		if got != want {
 			t.Errorf("want %v, got %v", want, got)
@@ -62,10 +64,10 @@ func TestChaCha20Poly1305Poly1305KeyGen(t *testing.T) {
 		firstBlock := cha.CreateBlock()
 
 		got := chacha20poly1305.Poly1305KeyGen(firstBlock)
-		want := [32]byte{
+		want := poly1305.UnsafeOneTimeKeyFromBytes([32]byte{
 			0x76, 0xb8, 0xe0, 0xad, 0xa0, 0xf1, 0x3d, 0x90, 0x40, 0x5d, 0x6a, 0xe5, 0x53, 0x86, 0xbd, 0x28,
 			0xbd, 0xd2, 0x19, 0xb8, 0xa0, 0x8d, 0xed, 0x1a, 0xa8, 0x36, 0xef, 0xcc, 0x8b, 0x77, 0x0d, 0xc7,
-		}
+		})
 
 		if got != want {
 			t.Errorf("want %v, got %v", want, got)
@@ -93,10 +95,10 @@ func TestChaCha20Poly1305Poly1305KeyGen(t *testing.T) {
 		firstBlock := cha.CreateBlock()
 
 		got := chacha20poly1305.Poly1305KeyGen(firstBlock)
-		want := [32]byte{
+		want := poly1305.UnsafeOneTimeKeyFromBytes([32]byte{
 			0xec, 0xfa, 0x25, 0x4f, 0x84, 0x5f, 0x64, 0x74, 0x73, 0xd3, 0xcb, 0x14, 0x0d, 0xa9, 0xe8, 0x76,
 			0x06, 0xcb, 0x33, 0x06, 0x6c, 0x44, 0x7b, 0x87, 0xbc, 0x26, 0x66, 0xdd, 0xe3, 0xfb, 0xb7, 0x39,
-		}
+		})
 
 		if got != want {
 			t.Errorf("want %v, got %v", want, got)
@@ -124,10 +126,10 @@ func TestChaCha20Poly1305Poly1305KeyGen(t *testing.T) {
 		firstBlock := cha.CreateBlock()
 
 		got := chacha20poly1305.Poly1305KeyGen(firstBlock)
-		want := [32]byte{
+		want := poly1305.UnsafeOneTimeKeyFromBytes([32]byte{
 			0x96, 0x5e, 0x3b, 0xc6, 0xf9, 0xec, 0x7e, 0xd9, 0x56, 0x08, 0x08, 0xf4, 0xd2, 0x29, 0xf9, 0x4b,
 			0x13, 0x7f, 0xf2, 0x75, 0xca, 0x9b, 0x3f, 0xcb, 0xdd, 0x59, 0xde, 0xaa, 0xd2, 0x33, 0x10, 0xae,
-		}
+		})
 
 		if got != want {
 			t.Errorf("want %v, got %v", want, got)
@@ -380,4 +382,212 @@ func TestChaCha20Poly1305(t *testing.T) {
 			t.Errorf("want %v, got %v", want, got)
 		}
 	})
+
+	t.Run("Padding", func(t *testing.T) {
+		t.Parallel()
+
+		key := [32]byte{0x01}
+		nonce := [12]byte{0x07}
+		aad := []byte{0x02}
+
+		data := []byte("attack at dawn")
+
+		chaPoly1 := chacha20poly1305.NewChaCha20Poly1305(key, nonce, chacha20poly1305.WithPadding(padding.PadToBlock(32)))
+		ciphertext, tag := chaPoly1.Encrypt(data, aad)
+
+		if len(ciphertext) != 32 {
+			t.Errorf("want length %v, got %v", 32, len(ciphertext))
+		}
+
+		chaPoly2 := chacha20poly1305.NewChaCha20Poly1305(key, nonce, chacha20poly1305.WithPadding(padding.PadToBlock(32)))
+		plaintext, err := chaPoly2.Decrypt(ciphertext, aad, tag)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		got := plaintext
+		want := data
+
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Rounds", func(t *testing.T) {
+		t.Parallel()
+
+		key := [32]byte{0x01}
+		nonce := [12]byte{0x07}
+		aad := []byte{0x02}
+
+		data := []byte("attack at dawn")
+
+		chaPoly1 := chacha20poly1305.NewChaCha20Poly1305(key, nonce, chacha20poly1305.WithRounds(12))
+		ciphertext, tag := chaPoly1.Encrypt(data, aad)
+
+		chaPoly2 := chacha20poly1305.NewChaCha20Poly1305(key, nonce)
+		defaultCiphertext, _ := chaPoly2.Encrypt(data, aad)
+
+		if slices.Equal(ciphertext, defaultCiphertext) {
+			t.Errorf("want ciphertexts to differ, got %v", ciphertext)
+		}
+
+		chaPoly3 := chacha20poly1305.NewChaCha20Poly1305(key, nonce, chacha20poly1305.WithRounds(12))
+		plaintext, err := chaPoly3.Decrypt(ciphertext, aad, tag)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		got := plaintext
+		want := data
+
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Truncated Tag", func(t *testing.T) {
+		t.Parallel()
+
+		key := [32]byte{0x01}
+		nonce := [12]byte{0x07}
+		aad := []byte{0x02}
+
+		data := []byte("attack at dawn")
+
+		chaPoly1 := chacha20poly1305.NewChaCha20Poly1305(key, nonce)
+		_, fullTag := chaPoly1.Encrypt(data, aad)
+
+		chaPoly2 := chacha20poly1305.NewChaCha20Poly1305(key, nonce, chacha20poly1305.WithTruncatedTag(8))
+		ciphertext, tag := chaPoly2.Encrypt(data, aad)
+
+		want := [16]byte(append(fullTag[:8:8], make([]byte, 8)...))
+
+		if tag != want {
+			t.Errorf("want %v, got %v", want, tag)
+		}
+
+		chaPoly3 := chacha20poly1305.NewChaCha20Poly1305(key, nonce, chacha20poly1305.WithTruncatedTag(8))
+		plaintext, err := chaPoly3.Decrypt(ciphertext, aad, tag)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if !slices.Equal(plaintext, data) {
+			t.Errorf("want %v, got %v", data, plaintext)
+		}
+
+		tag[7] ^= 0x01
+
+		chaPoly4 := chacha20poly1305.NewChaCha20Poly1305(key, nonce, chacha20poly1305.WithTruncatedTag(8))
+		_, err = chaPoly4.Decrypt(ciphertext, aad, tag)
+
+		gotError := err
+		wantError := chacha20poly1305.ErrInvalidTag
+
+		if !errors.Is(gotError, wantError) {
+			t.Errorf("want error %v, got %v", wantError, gotError)
+		}
+	})
+
+	t.Run("Reuse", func(t *testing.T) {
+		t.Parallel()
+
+		chaPoly := chacha20poly1305.NewChaCha20Poly1305([32]byte{0x01}, [12]byte{0x07})
+		ciphertext, tag := chaPoly.Encrypt([]byte("attack at dawn"), nil)
+
+		_, err := chaPoly.Decrypt(ciphertext, nil, tag)
+
+		gotError := err
+		wantError := poly1305.ErrKeyReused
+
+		if !errors.Is(gotError, wantError) {
+			t.Errorf("want error %v, got %v", wantError, gotError)
+		}
+
+		// Encrypting again would reuse the Poly1305 key.
+		if !panics(func() { chaPoly.Encrypt([]byte("attack at dusk"), nil) }) {
+			t.Errorf("want panic, got none")
+		}
+	})
+}
+
+func TestChaCha20Poly1305Authenticate(t *testing.T) {
+	// The key, nonce and AAD are the ones of RFC 8439 (section 2.8.2). The tag
+	// was generated with golang.org/x/crypto/chacha20poly1305 (sealing an empty
+	// plaintext).
+	key := [32]byte{
+		0x80, 0x81, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87,
+		0x88, 0x89, 0x8a, 0x8b, 0x8c, 0x8d, 0x8e, 0x8f,
+		0x90, 0x91, 0x92, 0x93, 0x94, 0x95, 0x96, 0x97,
+		0x98, 0x99, 0x9a, 0x9b, 0x9c, 0x9d, 0x9e, 0x9f,
+	}
+
+	nonce := [12]byte{
+		0x07, 0x00, 0x00, 0x00, 0x40, 0x41,
+		0x42, 0x43, 0x44, 0x45, 0x46, 0x47,
+	}
+
+	aad := []byte{
+		0x50, 0x51, 0x52, 0x53, 0xc0, 0xc1, 0xc2, 0xc3, 0xc4, 0xc5, 0xc6, 0xc7,
+	}
+
+	wantTag := [16]byte{
+		0xe6, 0x22, 0xe5, 0x64, 0x7a, 0x38, 0xd9, 0x67, 0xa7, 0xec, 0xbc, 0xb4, 0x6c, 0x7f, 0x67, 0x5c,
+	}
+
+	t.Run("Authenticate", func(t *testing.T) {
+		t.Parallel()
+
+		got := chacha20poly1305.NewChaCha20Poly1305(key, nonce).Authenticate(aad)
+		if got != wantTag {
+			t.Errorf("want %v, got %v", wantTag, got)
+		}
+
+		// Encrypting an empty plaintext creates the same tag.
+		ciphertext, tag := chacha20poly1305.NewChaCha20Poly1305(key, nonce).Encrypt([]byte{}, aad)
+		if len(ciphertext) != 0 || tag != wantTag {
+			t.Errorf("want %v, got %v (ciphertext %v)", wantTag, tag, ciphertext)
+		}
+	})
+
+	t.Run("Verify", func(t *testing.T) {
+		t.Parallel()
+
+		tamperedTag := wantTag
+		tamperedTag[0] ^= 0x01
+
+		tt := map[string]struct {
+			aad  []byte
+			tag  [16]byte
+			want error
+		}{
+			"Valid":        {aad: aad, tag: wantTag, want: nil},
+			"Tampered AAD": {aad: aad[1:], tag: wantTag, want: chacha20poly1305.ErrInvalidTag},
+			"Tampered Tag": {aad: aad, tag: tamperedTag, want: chacha20poly1305.ErrInvalidTag},
+		}
+
+		for name, tc := range tt {
+			err := chacha20poly1305.NewChaCha20Poly1305(key, nonce).Verify(tc.aad, tc.tag)
+			if !errors.Is(err, tc.want) {
+				t.Errorf("%v: want error %v, got %v", name, tc.want, err)
+			}
+		}
+	})
+
+	t.Run("Used Instance", func(t *testing.T) {
+		t.Parallel()
+
+		chaPoly := chacha20poly1305.NewChaCha20Poly1305(key, nonce)
+		chaPoly.Authenticate(aad)
+
+		if !panics(func() { chaPoly.Authenticate(aad) }) {
+			t.Errorf("want panic, got none")
+		}
+
+		err := chaPoly.Verify(aad, wantTag)
+		if err == nil {
+			t.Errorf("want error, got %v", err)
+		}
+	})
 }
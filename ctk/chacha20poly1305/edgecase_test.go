@@ -0,0 +1,62 @@
+package chacha20poly1305_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/blake2b"
+	"github.com/pmuens/ctk-go/ctk/chacha20poly1305"
+	"github.com/pmuens/ctk-go/ctk/internal/edgecase"
+)
+
+func TestChaCha20Poly1305EdgeCases(t *testing.T) {
+	t.Parallel()
+
+	key := edgecase.Key()
+	nonce := [12]byte(edgecase.Nonce(12))
+
+	// The digest of all ciphertexts and tags was cross-checked with
+	// golang.org/x/crypto/chacha20poly1305.
+	digest := blake2b.New256()
+
+	for _, tc := range edgecase.AEADCases() {
+		ciphertext, tag := chacha20poly1305.NewChaCha20Poly1305(key, nonce).Encrypt(tc.Plaintext, tc.AAD)
+
+		if len(ciphertext) != len(tc.Plaintext) {
+			t.Errorf("%v: want length %v, got %v", tc.Name, len(tc.Plaintext), len(ciphertext))
+		}
+
+		plaintext, err := chacha20poly1305.NewChaCha20Poly1305(key, nonce).Decrypt(ciphertext, tc.AAD, tag)
+		if err != nil {
+			t.Fatalf("%v: want error %v, got %v", tc.Name, nil, err)
+		}
+
+		if !slices.Equal(plaintext, tc.Plaintext) {
+			t.Errorf("%v: want %v, got %v", tc.Name, tc.Plaintext, plaintext)
+		}
+
+		tamperedTag := tag
+		tamperedTag[len(tamperedTag)-1] ^= 0x01
+
+		_, err = chacha20poly1305.NewChaCha20Poly1305(key, nonce).Decrypt(ciphertext, tc.AAD, tamperedTag)
+		if !errors.Is(err, chacha20poly1305.ErrInvalidTag) {
+			t.Errorf("%v: want error %v, got %v", tc.Name, chacha20poly1305.ErrInvalidTag, err)
+		}
+
+		digest.Write(ciphertext)
+		digest.Write(tag[:])
+	}
+
+	got := [32]byte(digest.Sum(nil))
+	want := [32]byte{
+		0xf5, 0x26, 0xb1, 0xca, 0xbf, 0xa5, 0xe8, 0x7d,
+		0x67, 0x99, 0xfc, 0x50, 0x77, 0x0a, 0x16, 0xa3,
+		0xba, 0x67, 0x7a, 0xfc, 0xc1, 0x8d, 0x4a, 0xcc,
+		0xdb, 0xa7, 0x5e, 0x90, 0xb4, 0x25, 0x92, 0xe3,
+	}
+
+	if got != want {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,97 @@
+package chacha20poly1305
+
+import "sync"
+
+// Pool encrypts and decrypts many messages under the same key by reusing
+// ChaCha20-Poly1305 instances (and their ChaCha20 and Poly1305 state) rather
+// than allocating new ones for every message.
+// A Pool is safe for concurrent use.
+type Pool struct {
+	// key is the key used for all messages.
+	key [32]byte
+
+	// tagSize is the (possibly truncated) tag size of the instances.
+	tagSize int
+
+	// pool holds the reusable *ChaCha20Poly1305 instances.
+	pool sync.Pool
+}
+
+// NewPool creates a new pool of ChaCha20-Poly1305 instances for the key.
+// The options are applied to all instances.
+func NewPool(key [32]byte, opts ...Option) *Pool {
+	p := &Pool{
+		key:     key,
+		tagSize: NewOptions(opts...).TagSize,
+	}
+
+	p.pool.New = func() any {
+		return NewChaCha20Poly1305(key, [12]byte{}, opts...)
+	}
+
+	return p
+}
+
+// Encrypt encrypts the plaintext with the nonce (see ChaCha20Poly1305.Encrypt).
+// The nonce must never be reused for the pool's key.
+func (p *Pool) Encrypt(nonce [12]byte, plaintext []byte, aad []byte) ([]byte, [16]byte) {
+	c := p.get(nonce)
+	defer p.pool.Put(c)
+
+	return c.Encrypt(plaintext, aad)
+}
+
+// Decrypt decrypts the ciphertext with the nonce (see ChaCha20Poly1305.Decrypt).
+// Returns an error if the tag or the padding is invalid.
+func (p *Pool) Decrypt(nonce [12]byte, ciphertext []byte, aad []byte, tag [16]byte) ([]byte, error) {
+	c := p.get(nonce)
+	defer p.pool.Put(c)
+
+	return c.Decrypt(ciphertext, aad, tag)
+}
+
+// get takes an instance from the pool and resets it for the nonce.
+func (p *Pool) get(nonce [12]byte) *ChaCha20Poly1305 {
+	c := p.pool.Get().(*ChaCha20Poly1305)
+	c.reset(p.key, nonce)
+
+	return c
+}
+
+// NonceSize returns the size (in bytes) of the nonces. Together with Overhead,
+// Seal and Open it implements the crypto/cipher.AEAD interface.
+func (p *Pool) NonceSize() int {
+	return 12
+}
+
+// Overhead returns the size (in bytes) of the tag that Seal appends (not
+// counting the padding if the padding option is used).
+func (p *Pool) Overhead() int {
+	return p.tagSize
+}
+
+// Seal encrypts the plaintext with the nonce and appends the ciphertext and the
+// tag to dst (see ChaCha20Poly1305.Seal). The nonce must never be reused for the
+// pool's key.
+// Panics if the nonce isn't NonceSize bytes long.
+func (p *Pool) Seal(dst []byte, nonce []byte, plaintext []byte, aad []byte) []byte {
+	checkNonce(nonce, 12)
+
+	c := p.get([12]byte(nonce))
+	defer p.pool.Put(c)
+
+	return c.Seal(dst, plaintext, aad)
+}
+
+// Open decrypts the ciphertext (with the appended tag) with the nonce and
+// appends the plaintext to dst (see ChaCha20Poly1305.Open).
+// Returns an error if the tag or the padding is invalid.
+// Panics if the nonce isn't NonceSize bytes long.
+func (p *Pool) Open(dst []byte, nonce []byte, ciphertext []byte, aad []byte) ([]byte, error) {
+	checkNonce(nonce, 12)
+
+	c := p.get([12]byte(nonce))
+	defer p.pool.Put(c)
+
+	return c.Open(dst, ciphertext, aad)
+}
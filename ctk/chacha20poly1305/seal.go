@@ -0,0 +1,127 @@
+package chacha20poly1305
+
+import "github.com/pmuens/ctk-go/ctk/internal/debug"
+
+// SealDetached encrypts and authenticates the plaintext and returns the
+// ciphertext and the tag separately. It's the same as Encrypt.
+func (c *ChaCha20Poly1305) SealDetached(plaintext []byte, aad []byte) ([]byte, [16]byte) {
+	return c.Encrypt(plaintext, aad)
+}
+
+// OpenDetached checks the tag and decrypts the ciphertext. It's the same as
+// Decrypt.
+func (c *ChaCha20Poly1305) OpenDetached(ciphertext []byte, aad []byte, tag [16]byte) ([]byte, error) {
+	return c.Decrypt(ciphertext, aad, tag)
+}
+
+// Seal encrypts and authenticates the plaintext and appends the ciphertext
+// followed by the tag to dst. The appended tag is as long as the instance's
+// TagSize (less than 16 bytes if the tag is truncated).
+// The plaintext is encrypted directly into dst which is why Seal doesn't
+// allocate if dst has enough capacity (unless the padding option is used).
+// To reuse the plaintext's storage for the output, pass plaintext[:0] as dst
+// (other overlaps of dst and the plaintext aren't allowed).
+// Panics if the instance was already used.
+func (c *ChaCha20Poly1305) Seal(dst []byte, plaintext []byte, aad []byte) []byte {
+	if c.options.Padding != nil {
+		ciphertext, tag := c.Encrypt(plaintext, aad)
+
+		dst = append(dst, ciphertext...)
+
+		return append(dst, tag[:c.TagSize()]...)
+	}
+
+	if debug.Enabled {
+		c.debug.Encrypt(plaintext, c.segments(aad)...)
+	}
+
+	ret, out := sliceForAppend(dst, len(plaintext)+c.TagSize())
+	ciphertext := out[:len(plaintext)]
+
+	// The counter is 1 at this point (see Encrypt).
+	c.chacha20.XORKeyStream(ciphertext, plaintext)
+
+	tag, err := GeneratePoly1305Tag(c.poly1305, c.aad, aad, ciphertext)
+	if err != nil {
+		panic("chacha20poly1305: instance reused")
+	}
+	copy(out[len(plaintext):], tag[:c.TagSize()])
+
+	return ret
+}
+
+// Open checks the tag that's appended to the ciphertext, decrypts the
+// ciphertext and appends the plaintext to dst.
+// The ciphertext is decrypted directly into dst which is why Open doesn't
+// allocate if dst has enough capacity (unless the padding option is used).
+// To reuse the sealed message's storage for the output, pass sealed[:0] as dst
+// (other overlaps of dst and the sealed message aren't allowed).
+// Neither dst nor the sealed message are modified if an error is returned.
+// Returns ErrInvalidTag if the sealed message is shorter than the tag or if the
+// tag is invalid.
+func (c *ChaCha20Poly1305) Open(dst []byte, sealed []byte, aad []byte) ([]byte, error) {
+	if len(sealed) < c.TagSize() {
+		return []byte{}, ErrInvalidTag
+	}
+
+	split := len(sealed) - c.TagSize()
+
+	var tag [16]byte
+	copy(tag[:], sealed[split:])
+
+	if c.options.Padding != nil {
+		plaintext, err := c.Decrypt(sealed[:split], aad, tag)
+		if err != nil {
+			return []byte{}, err
+		}
+
+		// The plaintext is copied to dst which is why the intermediate buffer is
+		// wiped.
+		dst = append(dst, plaintext...)
+		clear(plaintext)
+
+		return dst, nil
+	}
+
+	c.debug.Decrypt()
+
+	// The tag is checked before anything is written to dst.
+	computedTag, err := GeneratePoly1305Tag(c.poly1305, c.aad, aad, sealed[:split])
+	if err != nil {
+		return []byte{}, err
+	}
+
+	if !c.options.VerifyTag(tag, computedTag) {
+		return []byte{}, ErrInvalidTag
+	}
+
+	ret, out := sliceForAppend(dst, split)
+
+	// The counter is 1 at this point (see Decrypt).
+	c.chacha20.XORKeyStream(out, sealed[:split])
+
+	return ret, nil
+}
+
+// sliceForAppend extends the slice by n bytes and returns the extended slice
+// and the n bytes that were appended.
+func sliceForAppend(in []byte, n int) ([]byte, []byte) {
+	total := len(in) + n
+	if cap(in) >= total {
+		head := in[:total]
+		return head, head[len(in):]
+	}
+
+	head := make([]byte, total)
+	copy(head, in)
+
+	return head, head[len(in):]
+}
+
+// checkNonce panics if the nonce doesn't have the size (like the
+// crypto/cipher.AEAD implementations of the standard library).
+func checkNonce(nonce []byte, size int) {
+	if len(nonce) != size {
+		panic("chacha20poly1305: invalid nonce size")
+	}
+}
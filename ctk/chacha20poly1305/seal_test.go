@@ -0,0 +1,285 @@
+package chacha20poly1305_test
+
+import (
+	"crypto/cipher"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/chacha20poly1305"
+	"github.com/pmuens/ctk-go/ctk/internal/debug"
+	"github.com/pmuens/ctk-go/ctk/internal/race"
+	"github.com/pmuens/ctk-go/ctk/padding"
+)
+
+func TestChaCha20Poly1305Seal(t *testing.T) {
+	key := [32]byte{0x01}
+	nonce := [12]byte{0x02}
+	plaintext := []byte("Hello World")
+	aad := []byte("aad")
+
+	t.Run("Attached Tag", func(t *testing.T) {
+		t.Parallel()
+
+		ciphertext, tag := chacha20poly1305.NewChaCha20Poly1305(key, nonce).SealDetached(plaintext, aad)
+		want := append(ciphertext, tag[:]...)
+
+		got := chacha20poly1305.NewChaCha20Poly1305(key, nonce).Seal(nil, plaintext, aad)
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+
+		opened, err := chacha20poly1305.NewChaCha20Poly1305(key, nonce).Open(nil, got, aad)
+		if err != nil || !slices.Equal(opened, plaintext) {
+			t.Errorf("want %v, got %v (error %v)", plaintext, opened, err)
+		}
+
+		opened, err = chacha20poly1305.NewChaCha20Poly1305(key, nonce).OpenDetached(ciphertext, aad, tag)
+		if err != nil || !slices.Equal(opened, plaintext) {
+			t.Errorf("want %v, got %v (error %v)", plaintext, opened, err)
+		}
+	})
+
+	t.Run("Buffer Reuse", func(t *testing.T) {
+		t.Parallel()
+
+		want := chacha20poly1305.NewChaCha20Poly1305(key, nonce).Seal(nil, plaintext, aad)
+
+		prefix := []byte("prefix")
+		got := chacha20poly1305.NewChaCha20Poly1305(key, nonce).Seal(slices.Clone(prefix), plaintext, aad)
+		if !slices.Equal(got, append(prefix, want...)) {
+			t.Errorf("want %v, got %v", append(prefix, want...), got)
+		}
+
+		// Seal in place (the buffer has room for the tag).
+		buf := make([]byte, len(plaintext), len(plaintext)+chacha20poly1305.TagSize)
+		copy(buf, plaintext)
+
+		got = chacha20poly1305.NewChaCha20Poly1305(key, nonce).Seal(buf[:0], buf, aad)
+		if !slices.Equal(got, want) || &got[0] != &buf[0] {
+			t.Errorf("want %v in place, got %v", want, got)
+		}
+
+		// Open in place.
+		opened, err := chacha20poly1305.NewChaCha20Poly1305(key, nonce).Open(got[:0], got, aad)
+		if err != nil || !slices.Equal(opened, plaintext) || &opened[0] != &buf[0] {
+			t.Errorf("want %v in place, got %v (error %v)", plaintext, opened, err)
+		}
+	})
+
+	t.Run("Truncated Tag", func(t *testing.T) {
+		t.Parallel()
+
+		opt := chacha20poly1305.WithTruncatedTag(8)
+
+		sealed := chacha20poly1305.NewChaCha20Poly1305(key, nonce, opt).Seal(nil, plaintext, aad)
+		if len(sealed) != len(plaintext)+8 {
+			t.Errorf("want %v, got %v", len(plaintext)+8, len(sealed))
+		}
+
+		opened, err := chacha20poly1305.NewChaCha20Poly1305(key, nonce, opt).Open(nil, sealed, aad)
+		if err != nil || !slices.Equal(opened, plaintext) {
+			t.Errorf("want %v, got %v (error %v)", plaintext, opened, err)
+		}
+	})
+
+	t.Run("Invalid Sealed Messages", func(t *testing.T) {
+		t.Parallel()
+
+		sealed := chacha20poly1305.NewChaCha20Poly1305(key, nonce).Seal(nil, plaintext, aad)
+
+		tt := map[string]struct {
+			sealed []byte
+			aad    []byte
+		}{
+			"Tampered Tag":     {append(slices.Clone(sealed[:len(sealed)-1]), sealed[len(sealed)-1]^0x01), aad},
+			"Wrong AAD":        {sealed, []byte("other aad")},
+			"Shorter Than Tag": {sealed[:chacha20poly1305.TagSize-1], aad},
+			"Empty":            {[]byte{}, aad},
+		}
+
+		for name, tc := range tt {
+			_, err := chacha20poly1305.NewChaCha20Poly1305(key, nonce).Open(nil, tc.sealed, tc.aad)
+			if !errors.Is(err, chacha20poly1305.ErrInvalidTag) {
+				t.Errorf("%v: want error %v, got %v", name, chacha20poly1305.ErrInvalidTag, err)
+			}
+		}
+	})
+
+	t.Run("Failed Open", func(t *testing.T) {
+		t.Parallel()
+
+		sealed := chacha20poly1305.NewChaCha20Poly1305(key, nonce).Seal(nil, plaintext, aad)
+
+		// Nothing is written to the spare capacity of dst.
+		dst := make([]byte, 0, 64)
+
+		opened, err := chacha20poly1305.NewChaCha20Poly1305(key, nonce).Open(dst, sealed, []byte("other aad"))
+		if err == nil || len(opened) != 0 || slices.ContainsFunc(dst[:cap(dst)], func(b byte) bool { return b != 0 }) {
+			t.Errorf("want empty result and untouched dst, got %v and %v (error %v)", opened, dst[:cap(dst)], err)
+		}
+
+		// The sealed message isn't overwritten when opening in place.
+		buf := slices.Clone(sealed)
+
+		opened, err = chacha20poly1305.NewChaCha20Poly1305(key, nonce).Open(buf[:0], buf, []byte("other aad"))
+		if err == nil || len(opened) != 0 || !slices.Equal(buf, sealed) {
+			t.Errorf("want empty result and untouched message, got %v and %v (error %v)", opened, buf, err)
+		}
+
+		// The tag of a message that wasn't padded is valid but its padding isn't.
+		opened, err = chacha20poly1305.NewChaCha20Poly1305(key, nonce, chacha20poly1305.WithPadding(padding.PadToBlock(16))).Open(nil, sealed, aad)
+		if !errors.Is(err, padding.ErrInvalidPadding) || len(opened) != 0 {
+			t.Errorf("want error %v and empty result, got %v (error %v)", padding.ErrInvalidPadding, opened, err)
+		}
+	})
+}
+
+func TestChaCha20Poly1305CipherAEAD(t *testing.T) {
+	key := [32]byte{0x01}
+	nonce := [12]byte{0x02}
+	plaintext := []byte("Hello World")
+	aad := []byte("aad")
+
+	want := chacha20poly1305.NewChaCha20Poly1305(key, nonce).Seal(nil, plaintext, aad)
+
+	tt := map[string]struct {
+		aead cipher.AEAD
+	}{
+		"Pool":     {chacha20poly1305.NewPool(key)},
+		"SyncAEAD": {chacha20poly1305.NewSyncAEAD(key)},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if tc.aead.NonceSize() != 12 || tc.aead.Overhead() != chacha20poly1305.TagSize {
+				t.Errorf("want %v and %v, got %v and %v", 12, chacha20poly1305.TagSize, tc.aead.NonceSize(), tc.aead.Overhead())
+			}
+
+			got := tc.aead.Seal(nil, nonce[:], plaintext, aad)
+			if !slices.Equal(got, want) {
+				t.Errorf("want %v, got %v", want, got)
+			}
+
+			opened, err := tc.aead.Open([]byte("prefix"), nonce[:], got, aad)
+			if err != nil || !slices.Equal(opened, append([]byte("prefix"), plaintext...)) {
+				t.Errorf("want %v, got %v (error %v)", plaintext, opened, err)
+			}
+
+			_, err = tc.aead.Open(nil, nonce[:], got[1:], aad)
+			if !errors.Is(err, chacha20poly1305.ErrInvalidTag) {
+				t.Errorf("want error %v, got %v", chacha20poly1305.ErrInvalidTag, err)
+			}
+
+			if !panics(func() { tc.aead.Seal(nil, nonce[1:], plaintext, aad) }) {
+				t.Errorf("want panic, got none")
+			}
+		})
+	}
+}
+
+func TestChaCha20Poly1305Allocations(t *testing.T) {
+	if debug.Enabled {
+		t.Skip("the ctkdebug build tag tracks the use of the instances (which allocates)")
+	}
+
+	key := [32]byte{0x01}
+	nonce := [12]byte{0x02}
+	plaintext := make([]byte, 100)
+	aad := []byte("aad")
+
+	sealed := chacha20poly1305.NewChaCha20Poly1305(key, nonce).Seal(nil, plaintext, aad)
+
+	// AllocsPerRun calls the functions once more to warm up and an instance can
+	// only be used for a single message.
+	const runs = 100
+
+	var sealers, openers []*chacha20poly1305.ChaCha20Poly1305
+	for range runs + 1 {
+		sealers = append(sealers, chacha20poly1305.NewChaCha20Poly1305(key, nonce))
+		openers = append(openers, chacha20poly1305.NewChaCha20Poly1305(key, nonce))
+	}
+
+	pool := chacha20poly1305.NewPool(key)
+	syncAEAD := chacha20poly1305.NewSyncAEAD(key)
+
+	tt := map[string]struct {
+		seal   func(dst []byte) []byte
+		open   func(dst []byte) ([]byte, error)
+		pooled bool
+	}{
+		"ChaCha20Poly1305": {
+			seal: func(dst []byte) []byte {
+				c := sealers[0]
+				sealers = sealers[1:]
+
+				return c.Seal(dst, plaintext, aad)
+			},
+			open: func(dst []byte) ([]byte, error) {
+				c := openers[0]
+				openers = openers[1:]
+
+				return c.Open(dst, sealed, aad)
+			},
+		},
+		"Pool": {
+			seal:   func(dst []byte) []byte { return pool.Seal(dst, nonce[:], plaintext, aad) },
+			open:   func(dst []byte) ([]byte, error) { return pool.Open(dst, nonce[:], sealed, aad) },
+			pooled: true,
+		},
+		"SyncAEAD": {
+			seal: func(dst []byte) []byte { return syncAEAD.Seal(dst, nonce[:], plaintext, aad) },
+			open: func(dst []byte) ([]byte, error) { return syncAEAD.Open(dst, nonce[:], sealed, aad) },
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			// The allocations are counted for the whole process which is why the
+			// subtests don't run in parallel.
+			if tc.pooled && race.Enabled {
+				t.Skip("sync.Pool drops items randomly with the race detector")
+			}
+
+			got := make([]byte, 0, len(sealed))
+
+			allocs := testing.AllocsPerRun(runs, func() {
+				got = tc.seal(got[:0])
+			})
+			if allocs != 0 {
+				t.Errorf("Seal: want %v allocations, got %v", 0, allocs)
+			}
+
+			if !slices.Equal(got, sealed) {
+				t.Errorf("want %v, got %v", sealed, got)
+			}
+
+			opened := make([]byte, 0, len(plaintext))
+
+			var err error
+			allocs = testing.AllocsPerRun(runs, func() {
+				opened, err = tc.open(opened[:0])
+			})
+			if allocs != 0 {
+				t.Errorf("Open: want %v allocations, got %v", 0, allocs)
+			}
+
+			if err != nil || !slices.Equal(opened, plaintext) {
+				t.Errorf("want %v, got %v (error %v)", plaintext, opened, err)
+			}
+		})
+	}
+}
+
+// panics reports whether f panics.
+func panics(f func()) (panicked bool) {
+	defer func() {
+		panicked = recover() != nil
+	}()
+
+	f()
+
+	return false
+}
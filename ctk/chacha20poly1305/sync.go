@@ -0,0 +1,90 @@
+package chacha20poly1305
+
+import "sync"
+
+// SyncAEAD is a keyed ChaCha20-Poly1305 AEAD that's safe for concurrent use.
+// It reuses a single instance and serializes all calls via a mutex which keeps
+// the memory usage constant. Use a Pool if the calls should run in parallel.
+type SyncAEAD struct {
+	// mu guards aead.
+	mu sync.Mutex
+
+	// key is the key used for all messages.
+	key [32]byte
+
+	// aead is the instance that's reset for every message.
+	aead *ChaCha20Poly1305
+}
+
+// NewSyncAEAD creates a new ChaCha20-Poly1305 AEAD for the key that's safe for
+// concurrent use.
+// The options are applied to the underlying instance.
+func NewSyncAEAD(key [32]byte, opts ...Option) *SyncAEAD {
+	return &SyncAEAD{
+		key:  key,
+		aead: NewChaCha20Poly1305(key, [12]byte{}, opts...),
+	}
+}
+
+// Encrypt encrypts the plaintext with the nonce (see ChaCha20Poly1305.Encrypt).
+// The nonce must never be reused for the key.
+func (s *SyncAEAD) Encrypt(nonce [12]byte, plaintext []byte, aad []byte) ([]byte, [16]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.aead.reset(s.key, nonce)
+
+	return s.aead.Encrypt(plaintext, aad)
+}
+
+// Decrypt decrypts the ciphertext with the nonce (see ChaCha20Poly1305.Decrypt).
+// Returns an error if the tag or the padding is invalid.
+func (s *SyncAEAD) Decrypt(nonce [12]byte, ciphertext []byte, aad []byte, tag [16]byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.aead.reset(s.key, nonce)
+
+	return s.aead.Decrypt(ciphertext, aad, tag)
+}
+
+// NonceSize returns the size (in bytes) of the nonces. Together with Overhead,
+// Seal and Open it implements the crypto/cipher.AEAD interface.
+func (s *SyncAEAD) NonceSize() int {
+	return 12
+}
+
+// Overhead returns the size (in bytes) of the tag that Seal appends (not
+// counting the padding if the padding option is used).
+func (s *SyncAEAD) Overhead() int {
+	return s.aead.TagSize()
+}
+
+// Seal encrypts the plaintext with the nonce and appends the ciphertext and the
+// tag to dst (see ChaCha20Poly1305.Seal). The nonce must never be reused for the key.
+// Panics if the nonce isn't NonceSize bytes long.
+func (s *SyncAEAD) Seal(dst []byte, nonce []byte, plaintext []byte, aad []byte) []byte {
+	checkNonce(nonce, 12)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.aead.reset(s.key, [12]byte(nonce))
+
+	return s.aead.Seal(dst, plaintext, aad)
+}
+
+// Open decrypts the ciphertext (with the appended tag) with the nonce and
+// appends the plaintext to dst (see ChaCha20Poly1305.Open).
+// Returns an error if the tag or the padding is invalid.
+// Panics if the nonce isn't NonceSize bytes long.
+func (s *SyncAEAD) Open(dst []byte, nonce []byte, ciphertext []byte, aad []byte) ([]byte, error) {
+	checkNonce(nonce, 12)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.aead.reset(s.key, [12]byte(nonce))
+
+	return s.aead.Open(dst, ciphertext, aad)
+}
@@ -0,0 +1,44 @@
+package chacha20poly1305_test
+
+import (
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/chacha20poly1305"
+)
+
+func TestSyncAEAD(t *testing.T) {
+	t.Parallel()
+
+	key := [32]byte{0x01}
+
+	aead := chacha20poly1305.NewSyncAEAD(key)
+
+	var wg sync.WaitGroup
+
+	for i := range 8 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			nonce := [12]byte{byte(i)}
+			data := []byte{byte(i)}
+
+			ciphertext, tag := aead.Encrypt(nonce, data, nil)
+
+			wantCiphertext, wantTag := chacha20poly1305.NewChaCha20Poly1305(key, nonce).Encrypt(data, nil)
+			if !slices.Equal(ciphertext, wantCiphertext) || tag != wantTag {
+				t.Errorf("want %v %v, got %v %v", wantCiphertext, wantTag, ciphertext, tag)
+			}
+
+			plaintext, err := aead.Decrypt(nonce, ciphertext, nil, tag)
+			if err != nil || !slices.Equal(plaintext, data) {
+				t.Errorf("want %v, got %v (error %v)", data, plaintext, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
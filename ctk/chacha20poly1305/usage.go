@@ -0,0 +1,151 @@
+package chacha20poly1305
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pmuens/ctk-go/ctk/clock"
+)
+
+const (
+	// ErrKeyExpired is returned if sealing a message would exceed a usage limit
+	// of the key.
+	ErrKeyExpired = Error("key usage limit reached")
+)
+
+// DefaultMaxMessages is the default number of messages that can be sealed under
+// a key. With random 96 bit nonces the probability of a nonce collision (which
+// breaks confidentiality and integrity) reaches 2^-32 after 2^32 messages
+// (the bound NIST SP 800-38D recommends for random nonces).
+const DefaultMaxMessages = 1 << 32
+
+// DefaultMaxBytes is the default number of plaintext bytes that can be sealed
+// under a key. It's a conservative bound (1 PiB) which is far beyond what's
+// usually encrypted under a single key but stops runaway use of a key.
+const DefaultMaxBytes = 1 << 50
+
+// UsageOption configures a KeyUsage.
+type UsageOption func(*KeyUsage)
+
+// WithMaxMessages sets the number of messages that can be sealed under the key.
+func WithMaxMessages(n uint64) UsageOption {
+	return func(u *KeyUsage) {
+		u.maxMessages = n
+	}
+}
+
+// WithMaxBytes sets the number of plaintext bytes that can be sealed under the
+// key.
+func WithMaxBytes(n uint64) UsageOption {
+	return func(u *KeyUsage) {
+		u.maxBytes = n
+	}
+}
+
+// WithMaxAge sets the time after which no more messages can be sealed under the
+// key (counting from the creation of the KeyUsage). There's no age limit by
+// default.
+func WithMaxAge(d time.Duration) UsageOption {
+	return func(u *KeyUsage) {
+		u.maxAge = d
+	}
+}
+
+// WithClock sets the clock the age of the key is measured with (clock.System by
+// default), e.g. to test age limits without sleeping.
+func WithClock(c clock.Clock) UsageOption {
+	return func(u *KeyUsage) {
+		u.clock = clock.OrSystem(c)
+	}
+}
+
+// KeyUsage counts the messages and the plaintext bytes that are sealed under a
+// key (and optionally its age) and reports once a limit is reached so that the
+// key can be rotated (e.g. via the rotation package) before it's used beyond
+// its safe bounds.
+// A KeyUsage is safe for concurrent use.
+type KeyUsage struct {
+	// mu guards messages and bytes.
+	mu sync.Mutex
+
+	// messages is the number of messages that were sealed.
+	messages uint64
+
+	// bytes is the number of plaintext bytes that were sealed.
+	bytes uint64
+
+	// maxMessages is the number of messages that can be sealed.
+	maxMessages uint64
+
+	// maxBytes is the number of plaintext bytes that can be sealed.
+	maxBytes uint64
+
+	// maxAge is the age up to which messages can be sealed (0 if unlimited).
+	maxAge time.Duration
+
+	// clock tells the current time.
+	clock clock.Clock
+
+	// created is the time the KeyUsage was created at.
+	created time.Time
+}
+
+// NewKeyUsage creates a new KeyUsage with the default limits (see
+// DefaultMaxMessages and DefaultMaxBytes) or the limits set via the options.
+func NewKeyUsage(opts ...UsageOption) *KeyUsage {
+	u := &KeyUsage{
+		maxMessages: DefaultMaxMessages,
+		maxBytes:    DefaultMaxBytes,
+		clock:       clock.System,
+	}
+
+	for _, opt := range opts {
+		opt(u)
+	}
+	u.created = u.clock.Now()
+
+	return u
+}
+
+// Record records a message with size plaintext bytes that's about to be
+// sealed. It should be called before the message is sealed.
+// Returns ErrKeyExpired (without recording the message) if the message would
+// exceed a limit.
+func (u *KeyUsage) Record(size int) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if size < 0 || u.messages >= u.maxMessages || uint64(size) > u.maxBytes-u.bytes {
+		return ErrKeyExpired
+	}
+
+	if u.maxAge > 0 && u.clock.Now().Sub(u.created) >= u.maxAge {
+		return ErrKeyExpired
+	}
+
+	u.messages++
+	u.bytes += uint64(size)
+
+	return nil
+}
+
+// Messages returns the number of messages that were recorded.
+func (u *KeyUsage) Messages() uint64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return u.messages
+}
+
+// Bytes returns the number of plaintext bytes that were recorded.
+func (u *KeyUsage) Bytes() uint64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return u.bytes
+}
+
+// Age returns the time that passed since the KeyUsage was created.
+func (u *KeyUsage) Age() time.Duration {
+	return u.clock.Now().Sub(u.created)
+}
@@ -0,0 +1,101 @@
+package chacha20poly1305_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pmuens/ctk-go/ctk/chacha20poly1305"
+	"github.com/pmuens/ctk-go/ctk/clock"
+)
+
+func TestKeyUsage(t *testing.T) {
+	t.Run("Limits", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]struct {
+			opts  []chacha20poly1305.UsageOption
+			sizes []int
+			want  []error
+		}{
+			"Max Messages": {
+				opts:  []chacha20poly1305.UsageOption{chacha20poly1305.WithMaxMessages(2)},
+				sizes: []int{1, 1, 1},
+				want:  []error{nil, nil, chacha20poly1305.ErrKeyExpired},
+			},
+			"Max Bytes": {
+				opts:  []chacha20poly1305.UsageOption{chacha20poly1305.WithMaxBytes(10)},
+				sizes: []int{4, 7, 6, 1},
+				want:  []error{nil, chacha20poly1305.ErrKeyExpired, nil, chacha20poly1305.ErrKeyExpired},
+			},
+			"Negative Size": {
+				sizes: []int{-1},
+				want:  []error{chacha20poly1305.ErrKeyExpired},
+			},
+		}
+
+		for name, tc := range tt {
+			u := chacha20poly1305.NewKeyUsage(tc.opts...)
+
+			for i, size := range tc.sizes {
+				err := u.Record(size)
+				if !errors.Is(err, tc.want[i]) {
+					t.Errorf("%v: message %v: want error %v, got %v", name, i, tc.want[i], err)
+				}
+			}
+		}
+	})
+
+	t.Run("Max Age", func(t *testing.T) {
+		t.Parallel()
+
+		c := clock.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+		u := chacha20poly1305.NewKeyUsage(chacha20poly1305.WithMaxAge(time.Hour), chacha20poly1305.WithClock(c))
+
+		err := u.Record(1)
+		if !errors.Is(err, nil) {
+			t.Errorf("want error %v, got %v", nil, err)
+		}
+
+		c.Advance(time.Hour - time.Second)
+		if got, want := u.Age(), time.Hour-time.Second; got != want {
+			t.Errorf("want %v, got %v", want, got)
+		}
+
+		err = u.Record(1)
+		if !errors.Is(err, nil) {
+			t.Errorf("want error %v, got %v", nil, err)
+		}
+
+		c.Advance(time.Second)
+		err = u.Record(1)
+		if !errors.Is(err, chacha20poly1305.ErrKeyExpired) {
+			t.Errorf("want error %v, got %v", chacha20poly1305.ErrKeyExpired, err)
+		}
+
+		if u.Messages() != 2 {
+			t.Errorf("want %v messages, got %v", 2, u.Messages())
+		}
+	})
+
+	t.Run("Counters", func(t *testing.T) {
+		t.Parallel()
+
+		u := chacha20poly1305.NewKeyUsage(chacha20poly1305.WithMaxMessages(100))
+
+		var wg sync.WaitGroup
+		for range 200 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				u.Record(3)
+			}()
+		}
+		wg.Wait()
+
+		if u.Messages() != 100 || u.Bytes() != 300 {
+			t.Errorf("want %v messages and %v bytes, got %v and %v", 100, 300, u.Messages(), u.Bytes())
+		}
+	})
+}
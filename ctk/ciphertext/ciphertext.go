@@ -0,0 +1,256 @@
+// Package ciphertext implements a structured ciphertext type which bundles the
+// payload with everything (except the key) that's needed to decrypt it.
+//
+// Its binary encoding is:
+//
+//	version (1 byte) | algorithm ID (1 byte) | nonce | AAD hash (32 bytes) | tag (16 bytes) | payload
+//
+// The nonce size depends on the algorithm. The AAD hash is the BLAKE2b-256 hash
+// of the additional authenticated data (AAD) which allows to detect a wrong AAD
+// before decrypting without having to store the AAD itself.
+//
+// Decrypt reports every failure (a malformed ciphertext, a wrong AAD or a
+// payload that can't be authenticated) as ErrDecryption so that an attacker
+// who submits forged ciphertexts can't use the errors to test guesses of the
+// AAD. Builds with the ctkdebug build tag add the actual cause to the error.
+package ciphertext
+
+import (
+	"github.com/pmuens/ctk-go/ctk/blake2b"
+	"github.com/pmuens/ctk-go/ctk/chacha20poly1305"
+	"github.com/pmuens/ctk-go/ctk/internal/debug"
+	"github.com/pmuens/ctk-go/ctk/random"
+	"github.com/pmuens/ctk-go/ctk/subtle"
+	"github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
+)
+
+const (
+	// ErrInvalidCiphertext is returned if the ciphertext is malformed or uses an
+	// unsupported version or algorithm.
+	ErrInvalidCiphertext = Error("invalid ciphertext")
+
+	// ErrAADMismatch is the cause of ErrDecryption if the AAD doesn't match the
+	// AAD hash.
+	ErrAADMismatch = Error("aad mismatch")
+
+	// ErrDecryption is returned if a ciphertext can't be decrypted.
+	ErrDecryption = Error("decryption failed")
+)
+
+// Version is the version of the binary encoding.
+const Version = 1
+
+// AADHashSize is the size (in bytes) of the AAD hash.
+const AADHashSize = 32
+
+// TagSize is the size (in bytes) of the tag.
+const TagSize = 16
+
+// AlgorithmID identifies the AEAD algorithm the payload was encrypted with.
+type AlgorithmID uint8
+
+const (
+	// ChaCha20Poly1305 identifies ChaCha20-Poly1305 (RFC 8439).
+	ChaCha20Poly1305 AlgorithmID = 1
+
+	// XChaCha20Poly1305 identifies XChaCha20-Poly1305 (draft-irtf-cfrg-xchacha).
+	XChaCha20Poly1305 AlgorithmID = 2
+)
+
+// algorithmNames maps the algorithm IDs to their names.
+var algorithmNames = map[AlgorithmID]string{
+	ChaCha20Poly1305:  "chacha20poly1305",
+	XChaCha20Poly1305: "xchacha20poly1305",
+}
+
+// String returns the name of the algorithm.
+func (a AlgorithmID) String() string {
+	name, ok := algorithmNames[a]
+	if !ok {
+		return "unknown"
+	}
+
+	return name
+}
+
+// NonceSize returns the size (in bytes) of the algorithm's nonce or 0 if the
+// algorithm is unknown.
+func (a AlgorithmID) NonceSize() int {
+	switch a {
+	case ChaCha20Poly1305:
+		return 12
+	case XChaCha20Poly1305:
+		return 24
+	default:
+		return 0
+	}
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (a AlgorithmID) MarshalText() ([]byte, error) {
+	name, ok := algorithmNames[a]
+	if !ok {
+		return []byte{}, ErrInvalidCiphertext
+	}
+
+	return []byte(name), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (a *AlgorithmID) UnmarshalText(text []byte) error {
+	for id, name := range algorithmNames {
+		if name == string(text) {
+			*a = id
+			return nil
+		}
+	}
+
+	return ErrInvalidCiphertext
+}
+
+// Ciphertext is an encrypted payload together with its metadata.
+type Ciphertext struct {
+	// AlgorithmID identifies the AEAD algorithm.
+	AlgorithmID AlgorithmID `json:"alg"`
+
+	// Nonce is the nonce used to encrypt the payload.
+	Nonce []byte `json:"nonce"`
+
+	// AADHash is the BLAKE2b-256 hash of the additional authenticated data.
+	AADHash []byte `json:"aad_hash"`
+
+	// Payload is the encrypted plaintext.
+	Payload []byte `json:"payload"`
+
+	// Tag is the authentication tag.
+	Tag []byte `json:"tag"`
+}
+
+// Encrypt encrypts the plaintext with the algorithm and a random nonce.
+// The additional authenticated data (AAD) is bound to the payload.
+func Encrypt(alg AlgorithmID, key [32]byte, plaintext []byte, aad []byte) (*Ciphertext, error) {
+	nonce := make([]byte, alg.NonceSize())
+	if len(nonce) == 0 {
+		return nil, ErrInvalidCiphertext
+	}
+
+	err := random.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload []byte
+	var tag [16]byte
+
+	switch alg {
+	case ChaCha20Poly1305:
+		chaPoly := chacha20poly1305.NewChaCha20Poly1305(key, [12]byte(nonce))
+		payload, tag = chaPoly.Encrypt(plaintext, aad)
+	case XChaCha20Poly1305:
+		xchaPoly := xchacha20poly1305.NewXChaCha20Poly1305(key, [24]byte(nonce))
+		payload, tag = xchaPoly.Encrypt(plaintext, aad)
+	}
+
+	aadHash := blake2b.Sum256(aad)
+
+	return &Ciphertext{
+		AlgorithmID: alg,
+		Nonce:       nonce,
+		AADHash:     aadHash[:],
+		Payload:     payload,
+		Tag:         tag[:],
+	}, nil
+}
+
+// Decrypt decrypts the payload with the key.
+// Returns an error if the ciphertext is malformed, the AAD doesn't match or the
+// payload can't be authenticated.
+func (c *Ciphertext) Decrypt(key [32]byte, aad []byte) ([]byte, error) {
+	err := c.validate()
+	if err != nil {
+		return []byte{}, debug.Detail(ErrDecryption, err)
+	}
+
+	aadHash := blake2b.Sum256(aad)
+	if subtle.ConstantTimeCompare(aadHash[:], c.AADHash) != 1 {
+		return []byte{}, debug.Detail(ErrDecryption, ErrAADMismatch)
+	}
+
+	var plaintext []byte
+
+	switch c.AlgorithmID {
+	case ChaCha20Poly1305:
+		chaPoly := chacha20poly1305.NewChaCha20Poly1305(key, [12]byte(c.Nonce))
+		plaintext, err = chaPoly.Decrypt(c.Payload, aad, [16]byte(c.Tag))
+	case XChaCha20Poly1305:
+		xchaPoly := xchacha20poly1305.NewXChaCha20Poly1305(key, [24]byte(c.Nonce))
+		plaintext, err = xchaPoly.Decrypt(c.Payload, aad, [16]byte(c.Tag))
+	}
+
+	if err != nil {
+		return []byte{}, ErrDecryption
+	}
+
+	return plaintext, nil
+}
+
+// Bytes returns the binary encoding of the ciphertext.
+// Returns an error if the ciphertext is malformed.
+func (c *Ciphertext) Bytes() ([]byte, error) {
+	err := c.validate()
+	if err != nil {
+		return []byte{}, err
+	}
+
+	result := make([]byte, 0, 2+len(c.Nonce)+AADHashSize+TagSize+len(c.Payload))
+	result = append(result, Version, byte(c.AlgorithmID))
+	result = append(result, c.Nonce...)
+	result = append(result, c.AADHash...)
+	result = append(result, c.Tag...)
+	result = append(result, c.Payload...)
+
+	return result, nil
+}
+
+// Parse parses the binary encoding of a ciphertext.
+// Returns an error if the data is malformed or uses an unsupported version or
+// algorithm.
+func Parse(data []byte) (*Ciphertext, error) {
+	if len(data) < 2 || data[0] != Version {
+		return nil, ErrInvalidCiphertext
+	}
+
+	alg := AlgorithmID(data[1])
+	nonceSize := alg.NonceSize()
+
+	if nonceSize == 0 || len(data) < 2+nonceSize+AADHashSize+TagSize {
+		return nil, ErrInvalidCiphertext
+	}
+
+	rest := data[2:]
+
+	c := &Ciphertext{AlgorithmID: alg}
+	c.Nonce, rest = clone(rest, nonceSize)
+	c.AADHash, rest = clone(rest, AADHashSize)
+	c.Tag, rest = clone(rest, TagSize)
+	c.Payload, _ = clone(rest, len(rest))
+
+	return c, nil
+}
+
+// validate checks that the algorithm is supported and that the fields have the
+// expected sizes.
+func (c *Ciphertext) validate() error {
+	nonceSize := c.AlgorithmID.NonceSize()
+
+	if nonceSize == 0 || len(c.Nonce) != nonceSize || len(c.AADHash) != AADHashSize || len(c.Tag) != TagSize {
+		return ErrInvalidCiphertext
+	}
+
+	return nil
+}
+
+// clone returns a copy of the first n bytes of data and the rest of data.
+func clone(data []byte, n int) ([]byte, []byte) {
+	return append([]byte{}, data[:n]...), data[n:]
+}
@@ -0,0 +1,129 @@
+package ciphertext_test
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/ciphertext"
+)
+
+func TestCiphertext(t *testing.T) {
+	key := [32]byte{0x01}
+	aad := []byte("record-42")
+	data := []byte("attack at dawn")
+
+	t.Run("Encrypt + Decrypt", func(t *testing.T) {
+		t.Parallel()
+
+		for _, alg := range []ciphertext.AlgorithmID{ciphertext.ChaCha20Poly1305, ciphertext.XChaCha20Poly1305} {
+			c, err := ciphertext.Encrypt(alg, key, data, aad)
+			if err != nil {
+				t.Fatalf("%v: want error %v, got %v", alg, nil, err)
+			}
+
+			if len(c.Nonce) != alg.NonceSize() {
+				t.Errorf("%v: want nonce length %v, got %v", alg, alg.NonceSize(), len(c.Nonce))
+			}
+
+			got, err := c.Decrypt(key, aad)
+			if err != nil {
+				t.Fatalf("%v: want error %v, got %v", alg, nil, err)
+			}
+
+			if !slices.Equal(got, data) {
+				t.Errorf("%v: want %v, got %v", alg, data, got)
+			}
+		}
+	})
+
+	t.Run("Bytes + Parse", func(t *testing.T) {
+		t.Parallel()
+
+		c, _ := ciphertext.Encrypt(ciphertext.XChaCha20Poly1305, key, data, aad)
+
+		encoded, err := c.Bytes()
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		wantLength := 2 + 24 + ciphertext.AADHashSize + ciphertext.TagSize + len(data)
+		if len(encoded) != wantLength {
+			t.Errorf("want length %v, got %v", wantLength, len(encoded))
+		}
+
+		parsed, err := ciphertext.Parse(encoded)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		got, _ := parsed.Decrypt(key, aad)
+
+		if !slices.Equal(got, data) {
+			t.Errorf("want %v, got %v", data, got)
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		t.Parallel()
+
+		c, _ := ciphertext.Encrypt(ciphertext.ChaCha20Poly1305, key, data, aad)
+
+		encoded, err := json.Marshal(c)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if !strings.Contains(string(encoded), `"alg":"chacha20poly1305"`) {
+			t.Errorf("want algorithm name in %s", encoded)
+		}
+
+		var parsed ciphertext.Ciphertext
+
+		err = json.Unmarshal(encoded, &parsed)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		got, _ := parsed.Decrypt(key, aad)
+
+		if !slices.Equal(got, data) {
+			t.Errorf("want %v, got %v", data, got)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		c, _ := ciphertext.Encrypt(ciphertext.XChaCha20Poly1305, key, data, aad)
+
+		tampered := *c
+		tampered.Payload = slices.Clone(c.Payload)
+		tampered.Payload[0] ^= 0x01
+
+		_, parseErr := ciphertext.Parse([]byte{ciphertext.Version, 0x07})
+		_, wrongAAD := c.Decrypt(key, []byte("record-43"))
+		_, wrongKey := c.Decrypt([32]byte{0x02}, aad)
+		_, tamperedErr := tampered.Decrypt(key, aad)
+		_, unknownAlg := ciphertext.Encrypt(0, key, data, aad)
+
+		tt := map[string]struct {
+			err  error
+			want error
+		}{
+			"Unknown Algorithm": {err: parseErr, want: ciphertext.ErrInvalidCiphertext},
+			"Wrong AAD":         {err: wrongAAD, want: ciphertext.ErrDecryption},
+			"Wrong Key":         {err: wrongKey, want: ciphertext.ErrDecryption},
+			"Tampered Payload":  {err: tamperedErr, want: ciphertext.ErrDecryption},
+			"Encrypt Unknown":   {err: unknownAlg, want: ciphertext.ErrInvalidCiphertext},
+		}
+
+		for name, tc := range tt {
+			if !errors.Is(tc.err, tc.want) {
+				t.Errorf("%v: want error %v, got %v", name, tc.want, tc.err)
+			}
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pmuens/ctk-go/ctk/clock"
+)
+
+func TestClock(t *testing.T) {
+	t.Run("System", func(t *testing.T) {
+		t.Parallel()
+
+		before := time.Now()
+		got := clock.OrSystem(nil).Now()
+
+		if got.Before(before) || got.After(time.Now()) {
+			t.Errorf("want time between %v and now, got %v", before, got)
+		}
+	})
+
+	t.Run("Manual", func(t *testing.T) {
+		t.Parallel()
+
+		start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		c := clock.NewManual(start)
+
+		if got := clock.OrSystem(c).Now(); !got.Equal(start) {
+			t.Errorf("want %v, got %v", start, got)
+		}
+
+		c.Advance(time.Hour)
+		if got, want := c.Now(), start.Add(time.Hour); !got.Equal(want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+
+		c.Set(start)
+		if got := c.Now(); !got.Equal(start) {
+			t.Errorf("want %v, got %v", start, got)
+		}
+	})
+}
@@ -0,0 +1,234 @@
+// Package dgram implements the protection of individual datagrams (e.g. UDP
+// packets) which can be lost, duplicated or reordered in transit.
+//
+// Every datagram is encrypted via ChaCha20-Poly1305 and carries an explicit
+// 64 bit sequence number:
+//
+//	sequence number (8, big endian) | ciphertext | tag (16)
+//
+// The nonce is the sequence number (zero padded to 12 bytes) and the sequence
+// number is bound to the datagram as additional authenticated data (AAD). The
+// receiver keeps track of the sequence numbers it accepted in a sliding window
+// (see RFC 6347, section 4.1.2.6 and WireGuard) so that replayed datagrams are
+// rejected while datagrams that arrive out of order are still accepted.
+//
+// Note that a key must only be used by a single Sender. Use different keys per
+// direction.
+package dgram
+
+import (
+	"encoding/binary"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pmuens/ctk-go/ctk/chacha20poly1305"
+	"github.com/pmuens/ctk-go/ctk/internal/debug"
+)
+
+const (
+	// ErrInvalidDatagram is the cause of ErrDecryption if a datagram is too
+	// short (only added in builds with the ctkdebug build tag).
+	ErrInvalidDatagram = Error("invalid datagram")
+
+	// ErrDecryption is returned if a datagram is malformed or can't be
+	// authenticated.
+	ErrDecryption = Error("datagram authentication failed")
+
+	// ErrReplayed is returned if a datagram with the sequence number was
+	// already accepted.
+	ErrReplayed = Error("replayed datagram")
+
+	// ErrTooOld is returned if the sequence number of a datagram is too far
+	// behind the highest accepted sequence number to be checked for replays.
+	ErrTooOld = Error("datagram outside of replay window")
+
+	// ErrExhausted is returned if all sequence numbers of a key were used.
+	ErrExhausted = Error("sequence numbers exhausted")
+)
+
+// SequenceSize is the size (in bytes) of the sequence number.
+const SequenceSize = 8
+
+// TagSize is the size (in bytes) of the tag.
+const TagSize = 16
+
+// Overhead is the number of bytes a datagram is larger than its payload.
+const Overhead = SequenceSize + TagSize
+
+// WindowSize is the number of sequence numbers (up to and including the
+// highest accepted one) the receiver keeps track of.
+const WindowSize = 64
+
+// Sender protects datagrams with increasing sequence numbers.
+// A Sender is safe for concurrent use.
+type Sender struct {
+	// key is the encryption key.
+	key [32]byte
+
+	// next is the sequence number of the next datagram.
+	next atomic.Uint64
+}
+
+// NewSender creates a new Sender for the key which starts with the sequence
+// number 0.
+func NewSender(key [32]byte) *Sender {
+	return &Sender{key: key}
+}
+
+// Seal encrypts the payload and binds the additional authenticated data (AAD)
+// to it. The AAD isn't part of the returned datagram.
+// Returns an error if all sequence numbers were used (the key needs to be
+// replaced in that case).
+func (s *Sender) Seal(payload []byte, aad []byte) ([]byte, error) {
+	seq, err := s.reserve()
+	if err != nil {
+		return []byte{}, err
+	}
+
+	header := binary.BigEndian.AppendUint64(nil, seq)
+
+	ciphertext, tag := chacha20poly1305.NewChaCha20Poly1305(s.key, nonce(seq)).Encrypt(payload, slices.Concat(header, aad))
+
+	return slices.Concat(header, ciphertext, tag[:]), nil
+}
+
+// reserve returns the next sequence number. The last sequence number is never
+// used so that the counter can't wrap around to already used ones.
+func (s *Sender) reserve() (uint64, error) {
+	for {
+		seq := s.next.Load()
+		if seq == ^uint64(0) {
+			return 0, ErrExhausted
+		}
+
+		if s.next.CompareAndSwap(seq, seq+1) {
+			return seq, nil
+		}
+	}
+}
+
+// Receiver opens datagrams and rejects replays.
+// A Receiver is safe for concurrent use.
+type Receiver struct {
+	// key is the decryption key.
+	key [32]byte
+
+	// mu guards highest, bitmap and started.
+	mu sync.Mutex
+
+	// highest is the highest accepted sequence number.
+	highest uint64
+
+	// bitmap marks the accepted sequence numbers in the window. Bit i is set if
+	// highest - i was accepted.
+	bitmap uint64
+
+	// started indicates whether a datagram was accepted yet.
+	started bool
+}
+
+// NewReceiver creates a new Receiver for the key.
+func NewReceiver(key [32]byte) *Receiver {
+	return &Receiver{key: key}
+}
+
+// Open authenticates and decrypts the datagram with the additional
+// authenticated data (AAD) and returns the payload and its sequence number.
+// Only datagrams that are authenticated update the replay window.
+// Returns an error if the datagram is malformed, can't be authenticated or was
+// replayed.
+func (r *Receiver) Open(datagram []byte, aad []byte) ([]byte, uint64, error) {
+	if len(datagram) < Overhead {
+		return []byte{}, 0, debug.Detail(ErrDecryption, ErrInvalidDatagram)
+	}
+
+	header := datagram[:SequenceSize]
+	ciphertext := datagram[SequenceSize : len(datagram)-TagSize]
+	tag := [16]byte(datagram[len(datagram)-TagSize:])
+	seq := binary.BigEndian.Uint64(header)
+
+	// Check the window before decrypting to reject replays cheaply. The check
+	// needs to be repeated after decrypting as the lock isn't held while
+	// decrypting.
+	err := r.check(seq)
+	if err != nil {
+		return []byte{}, 0, err
+	}
+
+	payload, err := chacha20poly1305.NewChaCha20Poly1305(r.key, nonce(seq)).Decrypt(ciphertext, slices.Concat(header, aad), tag)
+	if err != nil {
+		return []byte{}, 0, ErrDecryption
+	}
+
+	err = r.accept(seq)
+	if err != nil {
+		return []byte{}, 0, err
+	}
+
+	return payload, seq, nil
+}
+
+// check returns an error if the sequence number is outside of the window or
+// was already accepted.
+func (r *Receiver) check(seq uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.checkLocked(seq)
+}
+
+// checkLocked is check with r.mu held.
+func (r *Receiver) checkLocked(seq uint64) error {
+	if !r.started || seq > r.highest {
+		return nil
+	}
+
+	diff := r.highest - seq
+	if diff >= WindowSize {
+		return ErrTooOld
+	}
+
+	if r.bitmap&(1<<diff) != 0 {
+		return ErrReplayed
+	}
+
+	return nil
+}
+
+// accept marks the sequence number as accepted and slides the window forward
+// if it's the highest sequence number so far.
+func (r *Receiver) accept(seq uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.checkLocked(seq)
+	if err != nil {
+		return err
+	}
+
+	if !r.started || seq > r.highest {
+		shift := seq - r.highest
+		if !r.started || shift >= WindowSize {
+			r.bitmap = 0
+		} else {
+			r.bitmap <<= shift
+		}
+
+		r.highest = seq
+		r.started = true
+	}
+
+	r.bitmap |= 1 << (r.highest - seq)
+
+	return nil
+}
+
+// nonce returns the nonce for the sequence number (8 bytes, big endian) which
+// is zero padded to 12 bytes.
+func nonce(seq uint64) [12]byte {
+	var result [12]byte
+	binary.BigEndian.PutUint64(result[4:], seq)
+
+	return result
+}
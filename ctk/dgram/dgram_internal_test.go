@@ -0,0 +1,25 @@
+package dgram
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSenderExhausted(t *testing.T) {
+	t.Parallel()
+
+	s := NewSender([32]byte{0x01})
+	s.next.Store(^uint64(0) - 1)
+
+	_, err := s.Seal([]byte("last"), nil)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	for range 2 {
+		_, err = s.Seal([]byte("too many"), nil)
+		if !errors.Is(err, ErrExhausted) {
+			t.Errorf("want error %v, got %v", ErrExhausted, err)
+		}
+	}
+}
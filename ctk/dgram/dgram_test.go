@@ -0,0 +1,188 @@
+package dgram_test
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/dgram"
+)
+
+func TestDgram(t *testing.T) {
+	key := [32]byte{0x01, 0x02, 0x03}
+
+	// seal seals count datagrams with the payloads 0, 1, 2, ...
+	seal := func(t *testing.T, count int) [][]byte {
+		t.Helper()
+
+		s := dgram.NewSender(key)
+		datagrams := make([][]byte, count)
+
+		for i := range datagrams {
+			datagram, err := s.Seal([]byte{byte(i)}, []byte("aad"))
+			if err != nil {
+				t.Fatalf("want error %v, got %v", nil, err)
+			}
+			datagrams[i] = datagram
+		}
+
+		return datagrams
+	}
+
+	t.Run("Seal + Open", func(t *testing.T) {
+		t.Parallel()
+
+		datagrams := seal(t, 3)
+		r := dgram.NewReceiver(key)
+
+		for i, datagram := range datagrams {
+			if len(datagram) != 1+dgram.Overhead {
+				t.Errorf("want length %v, got %v", 1+dgram.Overhead, len(datagram))
+			}
+
+			payload, seq, err := r.Open(datagram, []byte("aad"))
+			if err != nil {
+				t.Fatalf("want error %v, got %v", nil, err)
+			}
+
+			if seq != uint64(i) || !slices.Equal(payload, []byte{byte(i)}) {
+				t.Errorf("want sequence number %v and payload %v, got %v and %v", i, []byte{byte(i)}, seq, payload)
+			}
+		}
+	})
+
+	t.Run("Replay Window", func(t *testing.T) {
+		t.Parallel()
+
+		datagrams := seal(t, 2*dgram.WindowSize)
+
+		tt := []struct {
+			seq  int
+			want error
+		}{
+			{seq: 5, want: nil},
+			{seq: 5, want: dgram.ErrReplayed},
+			{seq: 2, want: nil},
+			{seq: 2, want: dgram.ErrReplayed},
+			{seq: 0, want: nil},
+			{seq: 5 + dgram.WindowSize - 1, want: nil},
+			{seq: 5, want: dgram.ErrReplayed},
+			{seq: 4, want: dgram.ErrTooOld},
+			{seq: 6, want: nil},
+			{seq: 6, want: dgram.ErrReplayed},
+			{seq: 2*dgram.WindowSize - 1, want: nil},
+			{seq: 5 + dgram.WindowSize - 1, want: dgram.ErrReplayed},
+			{seq: dgram.WindowSize, want: nil},
+			{seq: dgram.WindowSize - 1, want: dgram.ErrTooOld},
+		}
+
+		r := dgram.NewReceiver(key)
+
+		for i, tc := range tt {
+			_, _, err := r.Open(datagrams[tc.seq], []byte("aad"))
+
+			if !errors.Is(err, tc.want) {
+				t.Errorf("step %v (sequence number %v): want error %v, got %v", i, tc.seq, tc.want, err)
+			}
+		}
+	})
+
+	t.Run("Forgeries Don't Move The Window", func(t *testing.T) {
+		t.Parallel()
+
+		datagrams := seal(t, 2)
+		r := dgram.NewReceiver(key)
+
+		// A forged datagram with a high sequence number would make all other
+		// datagrams too old if it moved the window.
+		forged := slices.Clone(datagrams[1])
+		forged[0] = 0xff
+
+		_, _, err := r.Open(forged, []byte("aad"))
+		if !errors.Is(err, dgram.ErrDecryption) {
+			t.Errorf("want error %v, got %v", dgram.ErrDecryption, err)
+		}
+
+		_, _, err = r.Open(datagrams[0], []byte("aad"))
+		if err != nil {
+			t.Errorf("want error %v, got %v", nil, err)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		datagram := seal(t, 1)[0]
+
+		tampered := slices.Clone(datagram)
+		tampered[dgram.SequenceSize] ^= 0x01
+
+		tt := map[string]struct {
+			key      [32]byte
+			datagram []byte
+			aad      []byte
+			want     error
+		}{
+			"Wrong Key":      {key: [32]byte{0x04}, datagram: datagram, aad: []byte("aad"), want: dgram.ErrDecryption},
+			"Wrong AAD":      {key: key, datagram: datagram, aad: []byte("add"), want: dgram.ErrDecryption},
+			"Tampered":       {key: key, datagram: tampered, aad: []byte("aad"), want: dgram.ErrDecryption},
+			"Truncated":      {key: key, datagram: datagram[:len(datagram)-1], aad: []byte("aad"), want: dgram.ErrDecryption},
+			"Too Short":      {key: key, datagram: datagram[:dgram.Overhead-1], aad: []byte("aad"), want: dgram.ErrDecryption},
+			"Empty Datagram": {key: key, datagram: []byte{}, aad: []byte("aad"), want: dgram.ErrDecryption},
+		}
+
+		for name, tc := range tt {
+			_, _, err := dgram.NewReceiver(tc.key).Open(tc.datagram, tc.aad)
+
+			if !errors.Is(err, tc.want) {
+				t.Errorf("%v: want error %v, got %v", name, tc.want, err)
+			}
+		}
+	})
+
+	t.Run("Concurrency", func(t *testing.T) {
+		t.Parallel()
+
+		s := dgram.NewSender(key)
+		r := dgram.NewReceiver(key)
+
+		var wg sync.WaitGroup
+		seqs := make(chan uint64, 100)
+
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				for range 10 {
+					datagram, err := s.Seal([]byte("data"), nil)
+					if err != nil {
+						t.Errorf("want error %v, got %v", nil, err)
+						return
+					}
+
+					_, seq, err := r.Open(datagram, nil)
+					if err != nil && !errors.Is(err, dgram.ErrTooOld) {
+						t.Errorf("want error %v, got %v", nil, err)
+						return
+					}
+					if err == nil {
+						seqs <- seq
+					}
+				}
+			}()
+		}
+
+		wg.Wait()
+		close(seqs)
+
+		seen := make(map[uint64]bool)
+		for seq := range seqs {
+			if seen[seq] {
+				t.Errorf("want unique sequence numbers, got %v twice", seq)
+			}
+			seen[seq] = true
+		}
+	})
+}
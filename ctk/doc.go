@@ -1,7 +1,20 @@
 /*
-Package ctk exposes an API for mathematical operations.
+Package ctk is the entry point of the crypto toolkit.
 
-Use the ctk.Mul function to perform a multiplication of two numbers.
-The ctk.Div function can be used to divide a number a by a second number b.
+It provides a small facade over the toolkit's primitives: the shared key, nonce
+and tag types, a registry of the available AEAD algorithms which can be looked
+up by name (e.g. to select an algorithm via configuration) and the version of
+the toolkit.
+
+Each algorithm is annotated with metadata (key, nonce and tag sizes, the
+maximum plaintext size and security notes, see Describe) and compliance
+metadata (the specification and whether it's approved by FIPS 140-3).
+Deployments which must only use approved algorithms can call
+SetPolicy(PolicyApprovedOnly) so that the registry rejects all other
+algorithms (e.g. only AES-256-GCM of the built-in algorithms).
+
+The primitives and protocols themselves live in the sub packages (e.g.
+ctk/chacha20poly1305 or ctk/stream) which can be used directly when more control
+is needed.
 */
 package ctk
@@ -0,0 +1,542 @@
+// Package encfs implements an fs.FS over a directory of encrypted files so
+// that applications can serve (e.g. via http.FileServerFS) or embed (e.g. via
+// embed.FS) encrypted assets which are decrypted transparently while they're
+// read.
+//
+// Directory layout:
+//
+//	manifest     | stream container of the JSON manifest
+//	<object ID>  | stream container of a file (one per file)
+//
+// The manifest maps the file names to random object IDs and holds the sizes,
+// modes and modification times of the files so that the directory doesn't
+// reveal the names or the structure of the files. Every file is encrypted
+// with its own key which is derived from the key and its object ID via
+// HKDF-SHA256 so that objects can't be swapped without the decryption failing.
+//
+// Files are opened via stream.OpenSeeker which is why they implement io.Seeker
+// (e.g. for range requests) and are authenticated chunk by chunk while they're
+// read. Read returns stream.ErrDecryption if a file was tampered with.
+//
+// Directories are derived from the file names. Empty directories and files
+// which aren't regular files (e.g. symlinks) aren't stored.
+package encfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/pmuens/ctk-go/ctk/hkdf"
+	"github.com/pmuens/ctk-go/ctk/random"
+	"github.com/pmuens/ctk-go/ctk/stream"
+)
+
+const (
+	// ErrInvalidManifest is returned if the manifest can't be decrypted or is
+	// malformed.
+	ErrInvalidManifest = Error("invalid encrypted file system manifest")
+
+	// ErrSizeMismatch is returned if the size of a decrypted file doesn't
+	// match the size in the manifest.
+	ErrSizeMismatch = Error("encrypted file size mismatch")
+
+	// errNotDir is returned if a file is read as a directory.
+	errNotDir = Error("not a directory")
+
+	// errIsDir is returned if a directory is read as a file.
+	errIsDir = Error("is a directory")
+)
+
+// ManifestName is the name of the manifest in the directory.
+const ManifestName = "manifest"
+
+// manifestVersion is the version of the manifest format.
+const manifestVersion = 1
+
+// objectIDSize is the size (in bytes) of a (decoded) object ID.
+const objectIDSize = 16
+
+var (
+	// manifestLabel is used for domain separation when deriving the manifest
+	// key.
+	manifestLabel = []byte("ctk-go encfs manifest")
+
+	// fileLabel is used for domain separation when deriving the file keys.
+	fileLabel = []byte("ctk-go encfs file ")
+)
+
+// manifest is the (decrypted) manifest of a directory.
+type manifest struct {
+	// Version is the version of the manifest format.
+	Version int `json:"version"`
+
+	// Files are the files of the directory.
+	Files []manifestFile `json:"files"`
+}
+
+// manifestFile is a file in the manifest.
+type manifestFile struct {
+	// Name is the (slash separated) path of the file.
+	Name string `json:"name"`
+
+	// Object is the hex encoded ID of the object the file is stored in.
+	Object string `json:"object"`
+
+	// Size is the size (in bytes) of the file's plaintext.
+	Size int64 `json:"size"`
+
+	// Mode holds the permission bits of the file.
+	Mode fs.FileMode `json:"mode"`
+
+	// ModTime is the modification time of the file.
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Create encrypts the regular files of src into dir (which is created if it
+// doesn't exist) with the key. An existing manifest in dir is replaced.
+func Create(dir string, src fs.FS, key [32]byte) error {
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return err
+	}
+
+	m := manifest{Version: manifestVersion, Files: []manifestFile{}}
+
+	err = fs.WalkDir(src, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		id, err := random.Bytes(objectIDSize)
+		if err != nil {
+			return err
+		}
+		object := hex.EncodeToString(id)
+
+		err = encryptFile(filepath.Join(dir, object), src, name, fileKey(key, object))
+		if err != nil {
+			return err
+		}
+
+		m.Files = append(m.Files, manifestFile{
+			Name:    name,
+			Object:  object,
+			Size:    info.Size(),
+			Mode:    info.Mode().Perm(),
+			ModTime: info.ModTime(),
+		})
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	container, err := stream.Encrypt(manifestKey(key), encoded)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".manifest.tmp*")
+	if err != nil {
+		return err
+	}
+	// The temporary file is renamed on success which makes the removal a no-op.
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	_, err = tmp.Write(container)
+	if err != nil {
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(dir, ManifestName))
+}
+
+// encryptFile encrypts the file with the name in src into a new file at dst.
+func encryptFile(dst string, src fs.FS, name string, key [32]byte) error {
+	in, err := src.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	w, err := stream.NewWriter(out, key)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, in)
+	if err != nil {
+		return err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+// EncryptedFS is a read-only fs.FS which decrypts the files of a directory
+// created via Create while they're read.
+// An EncryptedFS can be used concurrently.
+type EncryptedFS struct {
+	// fsys holds the encrypted files.
+	fsys fs.FS
+
+	// key is the key the file keys are derived from.
+	key [32]byte
+
+	// nodes maps the names of all files and directories to their nodes.
+	nodes map[string]*node
+}
+
+// node is a file or directory of an EncryptedFS.
+type node struct {
+	// info describes the file or directory.
+	info fileInfo
+
+	// object is the object ID of a file (empty for directories).
+	object string
+
+	// children are the sorted names of the entries of a directory.
+	children []string
+}
+
+// New creates an EncryptedFS over fsys (e.g. os.DirFS or an embed.FS via
+// fs.Sub) which contains a directory created via Create.
+// Returns ErrInvalidManifest if the manifest can't be decrypted with the key or
+// is malformed.
+func New(fsys fs.FS, key [32]byte) (*EncryptedFS, error) {
+	container, err := fs.ReadFile(fsys, ManifestName)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted, err := stream.Decrypt(manifestKey(key), container)
+	if err != nil {
+		return nil, ErrInvalidManifest
+	}
+
+	var m manifest
+
+	err = json.Unmarshal(decrypted, &m)
+	if err != nil || m.Version != manifestVersion {
+		return nil, ErrInvalidManifest
+	}
+
+	root := &node{info: fileInfo{name: ".", mode: fs.ModeDir | 0o555}}
+	nodes := map[string]*node{".": root}
+
+	for _, f := range m.Files {
+		id, err := hex.DecodeString(f.Object)
+		if err != nil || len(id) != objectIDSize || f.Size < 0 || f.Mode&^fs.ModePerm != 0 {
+			return nil, ErrInvalidManifest
+		}
+
+		// Files can't replace the root or other files and directories.
+		if !fs.ValidPath(f.Name) || nodes[f.Name] != nil {
+			return nil, ErrInvalidManifest
+		}
+
+		nodes[f.Name] = &node{
+			info: fileInfo{
+				name:    path.Base(f.Name),
+				size:    f.Size,
+				mode:    f.Mode,
+				modTime: f.ModTime,
+			},
+			object: f.Object,
+		}
+
+		// Add the file to its parent directories (which are created unless
+		// they already exist).
+		for name := f.Name; name != "."; {
+			parent := path.Dir(name)
+
+			dir := nodes[parent]
+			if dir == nil {
+				dir = &node{info: fileInfo{name: path.Base(parent), mode: fs.ModeDir | 0o555}}
+				nodes[parent] = dir
+			} else if !dir.info.IsDir() {
+				return nil, ErrInvalidManifest
+			}
+
+			dir.children = append(dir.children, path.Base(name))
+
+			// The remaining parents already exist.
+			if len(dir.children) > 1 {
+				break
+			}
+
+			name = parent
+		}
+	}
+
+	for _, n := range nodes {
+		slices.Sort(n.children)
+	}
+
+	return &EncryptedFS{
+		fsys:  fsys,
+		key:   key,
+		nodes: nodes,
+	}, nil
+}
+
+// Open opens the file or directory with the name. Files are decrypted while
+// they're read.
+// Returns an error if there's no such file or if the file's object is missing
+// or can't be authenticated.
+func (e *EncryptedFS) Open(name string) (fs.File, error) {
+	n, err := e.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.info.IsDir() {
+		return &dir{fsys: e, node: n, name: name}, nil
+	}
+
+	f, err := e.fsys.Open(n.object)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	// Seekers need random access which is why files that don't support it
+	// are read into memory.
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+
+		f = nil
+		rs = bytes.NewReader(data)
+	}
+
+	s, err := stream.OpenSeeker(rs, fileKey(e.key, n.object))
+	if err == nil && s.Size() != n.info.size {
+		err = ErrSizeMismatch
+	}
+	if err != nil {
+		if f != nil {
+			f.Close()
+		}
+
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	return &file{Seeker: s, f: f, info: n.info}, nil
+}
+
+// Stat returns the FileInfo of the file or directory with the name without
+// decrypting the file.
+// Returns an error if there's no such file or directory.
+func (e *EncryptedFS) Stat(name string) (fs.FileInfo, error) {
+	n, err := e.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	return n.info, nil
+}
+
+// ReadDir returns the sorted entries of the directory with the name.
+// Returns an error if there's no such directory.
+func (e *EncryptedFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	n, err := e.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !n.info.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errNotDir}
+	}
+
+	return e.entries(name, n.children), nil
+}
+
+// lookup returns the node with the name.
+// Returns a PathError for the operation if the name is invalid or there's no
+// such node.
+func (e *EncryptedFS) lookup(op string, name string) (*node, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	n, ok := e.nodes[name]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+
+	return n, nil
+}
+
+// entries returns the DirEntries of the children of the directory with the
+// name.
+func (e *EncryptedFS) entries(name string, children []string) []fs.DirEntry {
+	entries := make([]fs.DirEntry, len(children))
+	for i, child := range children {
+		entries[i] = fs.FileInfoToDirEntry(e.nodes[path.Join(name, child)].info)
+	}
+
+	return entries
+}
+
+// manifestKey derives the manifest key from the key.
+func manifestKey(key [32]byte) [32]byte {
+	// The output length is valid so that no error can occur.
+	derived, _ := hkdf.Key(sha256.New, key[:], nil, manifestLabel, 32)
+
+	return [32]byte(derived)
+}
+
+// fileKey derives the key of the object with the ID from the key.
+func fileKey(key [32]byte, object string) [32]byte {
+	info := append(slices.Clone(fileLabel), object...)
+
+	// The output length is valid so that no error can occur.
+	derived, _ := hkdf.Key(sha256.New, key[:], nil, info, 32)
+
+	return [32]byte(derived)
+}
+
+// file is an open file of an EncryptedFS.
+type file struct {
+	*stream.Seeker
+
+	// f is the underlying encrypted file (nil if it was read into memory).
+	f fs.File
+
+	// info describes the file.
+	info fileInfo
+}
+
+// Stat returns the FileInfo of the file.
+func (f *file) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+// Close closes the underlying encrypted file.
+func (f *file) Close() error {
+	if f.f == nil {
+		return nil
+	}
+
+	return f.f.Close()
+}
+
+// dir is an open directory of an EncryptedFS.
+type dir struct {
+	// fsys is the file system of the directory.
+	fsys *EncryptedFS
+
+	// node is the directory's node.
+	node *node
+
+	// name is the name the directory was opened with.
+	name string
+
+	// offset is the number of entries returned by ReadDir so far.
+	offset int
+}
+
+// Stat returns the FileInfo of the directory.
+func (d *dir) Stat() (fs.FileInfo, error) {
+	return d.node.info, nil
+}
+
+// Read returns an error as directories can't be read.
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errIsDir}
+}
+
+// ReadDir returns the next n entries of the directory (or all remaining ones
+// if n <= 0) as described by fs.ReadDirFile.
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.node.children[d.offset:]
+	if n > 0 && len(remaining) == 0 {
+		return nil, io.EOF
+	}
+
+	if n > 0 && n < len(remaining) {
+		remaining = remaining[:n]
+	}
+	d.offset += len(remaining)
+
+	return d.fsys.entries(d.name, remaining), nil
+}
+
+// Close closes the directory.
+func (d *dir) Close() error {
+	return nil
+}
+
+// fileInfo implements fs.FileInfo for the files and directories of an
+// EncryptedFS.
+type fileInfo struct {
+	// name is the base name.
+	name string
+
+	// size is the size (in bytes) of the plaintext.
+	size int64
+
+	// mode is the file mode.
+	mode fs.FileMode
+
+	// modTime is the modification time.
+	modTime time.Time
+}
+
+// Name returns the base name.
+func (i fileInfo) Name() string { return i.name }
+
+// Size returns the size (in bytes) of the plaintext.
+func (i fileInfo) Size() int64 { return i.size }
+
+// Mode returns the file mode.
+func (i fileInfo) Mode() fs.FileMode { return i.mode }
+
+// ModTime returns the modification time.
+func (i fileInfo) ModTime() time.Time { return i.modTime }
+
+// IsDir reports whether the info describes a directory.
+func (i fileInfo) IsDir() bool { return i.mode.IsDir() }
+
+// Sys returns nil.
+func (i fileInfo) Sys() any { return nil }
@@ -0,0 +1,221 @@
+package encfs_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/pmuens/ctk-go/ctk/encfs"
+	"github.com/pmuens/ctk-go/ctk/stream"
+)
+
+// create encrypts the files into a new directory and returns its path.
+func create(t *testing.T, files fstest.MapFS, key [32]byte) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	err := encfs.Create(dir, files, key)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	return dir
+}
+
+// objects returns the paths of the encrypted files in the directory.
+func objects(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	var paths []string
+	for _, e := range entries {
+		if e.Name() != encfs.ManifestName {
+			paths = append(paths, filepath.Join(dir, e.Name()))
+		}
+	}
+
+	return paths
+}
+
+func TestEncryptedFS(t *testing.T) {
+	key := [32]byte{0x01}
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	files := fstest.MapFS{
+		"index.html":         {Data: []byte("<h1>index</h1>"), Mode: 0o644, ModTime: modTime},
+		"css/style.css":      {Data: []byte("body {}"), Mode: 0o600, ModTime: modTime},
+		"img/logo.png":       {Data: bytes.Repeat([]byte("png"), 100000), Mode: 0o644, ModTime: modTime},
+		"img/icons/fav.ico":  {Data: []byte{}, Mode: 0o644, ModTime: modTime},
+		"docs/a/b/c/deep.md": {Data: []byte("# deep"), Mode: 0o444, ModTime: modTime},
+	}
+
+	t.Run("File System", func(t *testing.T) {
+		t.Parallel()
+
+		dir := create(t, files, key)
+
+		fsys, err := encfs.New(os.DirFS(dir), key)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		err = fstest.TestFS(fsys, "index.html", "css/style.css", "img/logo.png", "img/icons/fav.ico", "docs/a/b/c/deep.md")
+		if err != nil {
+			t.Error(err)
+		}
+
+		for name, want := range files {
+			got, err := fs.ReadFile(fsys, name)
+			if !bytes.Equal(got, want.Data) || err != nil {
+				t.Errorf("%v: want %v bytes, got %v (error %v)", name, len(want.Data), len(got), err)
+			}
+
+			info, err := fs.Stat(fsys, name)
+			if err != nil || info.Mode() != want.Mode || !info.ModTime().Equal(modTime) {
+				t.Errorf("%v: want mode %v, got %v (error %v)", name, want.Mode, info.Mode(), err)
+			}
+		}
+	})
+
+	t.Run("Directory Hides Names", func(t *testing.T) {
+		t.Parallel()
+
+		dir := create(t, files, key)
+
+		for _, path := range append(objects(t, dir), filepath.Join(dir, encfs.ManifestName)) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("want error %v, got %v", nil, err)
+			}
+
+			for _, plaintext := range [][]byte{[]byte("index"), []byte("style.css"), []byte("pngpng")} {
+				if bytes.Contains(data, plaintext) {
+					t.Errorf("%v contains %q", path, plaintext)
+				}
+			}
+		}
+	})
+
+	t.Run("Seek", func(t *testing.T) {
+		t.Parallel()
+
+		fsys, err := encfs.New(os.DirFS(create(t, files, key)), key)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		f, err := fsys.Open("img/logo.png")
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+		defer f.Close()
+
+		_, err = f.(io.Seeker).Seek(-4, io.SeekEnd)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		got, err := io.ReadAll(f)
+		if string(got) != "gpng" || err != nil {
+			t.Errorf("want %q, got %q (error %v)", "gpng", got, err)
+		}
+	})
+
+	t.Run("Wrong Key", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := encfs.New(os.DirFS(create(t, files, key)), [32]byte{0x02})
+		if !errors.Is(err, encfs.ErrInvalidManifest) {
+			t.Errorf("want error %v, got %v", encfs.ErrInvalidManifest, err)
+		}
+	})
+
+	t.Run("Modified File", func(t *testing.T) {
+		t.Parallel()
+
+		dir := create(t, fstest.MapFS{"a": files["img/logo.png"]}, key)
+		path := objects(t, dir)[0]
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+		data[len(data)/2] ^= 1
+
+		err = os.WriteFile(path, data, 0o600)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		fsys, err := encfs.New(os.DirFS(dir), key)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		_, err = fs.ReadFile(fsys, "a")
+		if !errors.Is(err, stream.ErrDecryption) {
+			t.Errorf("want error %v, got %v", stream.ErrDecryption, err)
+		}
+	})
+
+	t.Run("Swapped Files", func(t *testing.T) {
+		t.Parallel()
+
+		dir := create(t, fstest.MapFS{"a": files["index.html"], "b": files["css/style.css"]}, key)
+		paths := objects(t, dir)
+
+		tmp := filepath.Join(dir, "tmp")
+		for _, rename := range [][2]string{{paths[0], tmp}, {paths[1], paths[0]}, {tmp, paths[1]}} {
+			err := os.Rename(rename[0], rename[1])
+			if err != nil {
+				t.Fatalf("want error %v, got %v", nil, err)
+			}
+		}
+
+		fsys, err := encfs.New(os.DirFS(dir), key)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		for _, name := range []string{"a", "b"} {
+			_, err = fsys.Open(name)
+			if !errors.Is(err, stream.ErrDecryption) {
+				t.Errorf("%v: want error %v, got %v", name, stream.ErrDecryption, err)
+			}
+		}
+	})
+
+	t.Run("Missing File", func(t *testing.T) {
+		t.Parallel()
+
+		fsys, err := encfs.New(os.DirFS(create(t, files, key)), key)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		tests := map[string]error{
+			"missing.html":   fs.ErrNotExist,
+			"css/other.css":  fs.ErrNotExist,
+			"/index.html":    fs.ErrInvalid,
+			"css/../secrets": fs.ErrInvalid,
+		}
+
+		for name, want := range tests {
+			_, err := fsys.Open(name)
+			if !errors.Is(err, want) {
+				t.Errorf("%v: want error %v, got %v", name, want, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,350 @@
+// Package encoding implements strict hex and base64 encodings for keys, nonces
+// and tags.
+//
+// Encoding and decoding run in constant time with respect to the data (there
+// are no data-dependent branches or table lookups) so that secrets such as keys
+// don't leak through timing side-channels while being formatted or parsed.
+package encoding
+
+import "strings"
+
+const (
+	// ErrInvalidEncoding is returned if the input contains invalid characters,
+	// has an invalid padding or isn't canonical.
+	ErrInvalidEncoding = Error("invalid encoding")
+
+	// ErrInvalidLength is returned if the decoded data doesn't have the expected
+	// length.
+	ErrInvalidLength = Error("invalid length")
+)
+
+// Encoding is a text encoding for binary data.
+type Encoding int
+
+const (
+	// Hex is the (lowercase) hexadecimal encoding. Decoding accepts lowercase and
+	// uppercase characters.
+	Hex Encoding = iota
+
+	// Base64 is the standard, padded base64 encoding as specified in
+	// https://datatracker.ietf.org/doc/html/rfc4648#section-4.
+	Base64
+
+	// Base64URL is the unpadded, URL and filename safe base64 encoding as
+	// specified in https://datatracker.ietf.org/doc/html/rfc4648#section-5.
+	Base64URL
+)
+
+// String returns the name of the encoding.
+func (e Encoding) String() string {
+	switch e {
+	case Hex:
+		return "hex"
+	case Base64:
+		return "base64"
+	case Base64URL:
+		return "base64url"
+	default:
+		return "unknown"
+	}
+}
+
+// Encode encodes the data with the encoding.
+func Encode(data []byte, enc Encoding) string {
+	var sb strings.Builder
+
+	switch enc {
+	case Hex:
+		sb.Grow(len(data) * 2)
+		for _, b := range data {
+			sb.WriteByte(hexChar(int(b >> 4)))
+			sb.WriteByte(hexChar(int(b & 0x0f)))
+		}
+	case Base64, Base64URL:
+		sb.Grow((len(data) + 2) / 3 * 4)
+
+		// Process 3 bytes (24 bits) at a time which are encoded as 4 characters.
+		for i := 0; i < len(data); i += 3 {
+			n := min(len(data)-i, 3)
+
+			var group uint32
+			for j := range n {
+				group |= uint32(data[i+j]) << (16 - 8*j)
+			}
+
+			// n bytes are encoded as n + 1 characters.
+			for j := range n + 1 {
+				sb.WriteByte(base64Char(int(group>>(18-6*j))&0x3f, enc == Base64URL))
+			}
+
+			if enc == Base64 {
+				sb.WriteString("=="[:3-n])
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// Decode decodes the string with the encoding in constant time.
+// Returns an error if the string isn't a valid, canonical encoding.
+func Decode(s string, enc Encoding) ([]byte, error) {
+	switch enc {
+	case Hex:
+		return decodeHex(s)
+	case Base64:
+		return decodeBase64(s, false, true)
+	case Base64URL:
+		return decodeBase64(s, true, false)
+	default:
+		return []byte{}, ErrInvalidEncoding
+	}
+}
+
+// ParseKey parses a 32 byte key that's hex, base64 or base64url encoded.
+func ParseKey(s string) ([32]byte, error) {
+	key, err := parse(s, 32)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	defer clear(key)
+
+	return [32]byte(key), nil
+}
+
+// ParseNonce parses a 12 byte (ChaCha20) nonce that's hex, base64 or base64url
+// encoded.
+func ParseNonce(s string) ([12]byte, error) {
+	nonce, err := parse(s, 12)
+	if err != nil {
+		return [12]byte{}, err
+	}
+
+	return [12]byte(nonce), nil
+}
+
+// ParseXNonce parses a 24 byte (XChaCha20) nonce that's hex, base64 or base64url
+// encoded.
+func ParseXNonce(s string) ([24]byte, error) {
+	nonce, err := parse(s, 24)
+	if err != nil {
+		return [24]byte{}, err
+	}
+
+	return [24]byte(nonce), nil
+}
+
+// ParseTag parses a 16 byte (Poly1305) tag that's hex, base64 or base64url
+// encoded.
+func ParseTag(s string) ([16]byte, error) {
+	tag, err := parse(s, 16)
+	if err != nil {
+		return [16]byte{}, err
+	}
+
+	return [16]byte(tag), nil
+}
+
+// FormatKey hex encodes the key.
+func FormatKey(key [32]byte) string {
+	return Encode(key[:], Hex)
+}
+
+// FormatNonce hex encodes the nonce.
+func FormatNonce(nonce []byte) string {
+	return Encode(nonce, Hex)
+}
+
+// FormatTag hex encodes the tag.
+func FormatTag(tag [16]byte) string {
+	return Encode(tag[:], Hex)
+}
+
+// parse decodes the string with the first encoding (hex, base64 or base64url)
+// that results in size bytes.
+// Note that the encoding is detected via the (public) length of the string and
+// the characters that are used so that only the encoding leaks, not the data.
+func parse(s string, size int) ([]byte, error) {
+	for _, enc := range []Encoding{Hex, Base64, Base64URL} {
+		if len(s) != encodedLen(size, enc) {
+			continue
+		}
+
+		// The padded base64 encoding has the same length for different sizes.
+		data, err := Decode(s, enc)
+		if err == nil && len(data) == size {
+			return data, nil
+		}
+		clear(data)
+	}
+
+	return []byte{}, ErrInvalidLength
+}
+
+// encodedLen returns the length of the encoding of size bytes.
+func encodedLen(size int, enc Encoding) int {
+	switch enc {
+	case Hex:
+		return size * 2
+	case Base64:
+		return (size + 2) / 3 * 4
+	default:
+		return (size*8 + 5) / 6
+	}
+}
+
+// decodeHex decodes the hex string in constant time.
+func decodeHex(s string) ([]byte, error) {
+	if len(s)%2 != 0 {
+		return []byte{}, ErrInvalidEncoding
+	}
+
+	result := make([]byte, len(s)/2)
+	valid := 1
+
+	for i := range result {
+		hi, hiValid := hexValue(s[i*2])
+		lo, loValid := hexValue(s[i*2+1])
+
+		result[i] = hi<<4 | lo
+		valid &= hiValid & loValid
+	}
+
+	if valid != 1 {
+		clear(result)
+		return []byte{}, ErrInvalidEncoding
+	}
+
+	return result, nil
+}
+
+// hexChar returns the hex character of the 4 bit value without branching on the
+// value.
+func hexChar(v int) byte {
+	// Values above 9 are shifted from the digits to the letters 'a' - 'f'.
+	return byte(v + '0' + (((9 - v) >> 8) & ('a' - '0' - 10)))
+}
+
+// hexValue returns the value of the hex character and 1 if it's valid (0
+// otherwise) without branching on the character.
+func hexValue(c byte) (byte, int) {
+	// Digits: c ^ '0' is between 0 and 9.
+	num := int(c) ^ '0'
+	isNum := ((num - 10) >> 8) & 1
+
+	// Letters: Clearing the lowercase bit maps 'a'-'f' to 'A'-'F' which are then
+	// shifted to the values 10 to 15.
+	alpha := int(c&^0x20) - 55
+	isAlpha := (((alpha - 10) ^ (alpha - 16)) >> 8) & 1
+
+	return byte((-isNum & num) | (-isAlpha & alpha)), isNum | isAlpha
+}
+
+// decodeBase64 decodes the base64 string in constant time.
+// The url flag selects the URL and filename safe alphabet and the padded flag
+// whether padding is required (or forbidden).
+func decodeBase64(s string, url bool, padded bool) ([]byte, error) {
+	if padded {
+		if len(s)%4 != 0 {
+			return []byte{}, ErrInvalidEncoding
+		}
+
+		// At most two padding characters are valid.
+		for range 2 {
+			s = strings.TrimSuffix(s, "=")
+		}
+	}
+
+	if len(s)%4 == 1 || strings.Contains(s, "=") {
+		return []byte{}, ErrInvalidEncoding
+	}
+
+	result := make([]byte, 0, len(s)*6/8)
+	invalid := 0
+
+	// acc accumulates the 6 bit values of which bits haven't been written yet.
+	var acc uint
+	var bits uint
+
+	for i := range len(s) {
+		v := base64Value(s[i], url)
+
+		// v is -1 for invalid characters.
+		invalid |= v >> 8
+		acc = acc<<6 | uint(v&0x3f)
+		bits += 6
+
+		if bits >= 8 {
+			bits -= 8
+			result = append(result, byte(acc>>bits))
+		}
+	}
+
+	// The left over bits need to be zero for the encoding to be canonical.
+	invalid |= int(acc & (1<<bits - 1))
+
+	if invalid != 0 {
+		clear(result)
+		return []byte{}, ErrInvalidEncoding
+	}
+
+	return result, nil
+}
+
+// base64Char returns the base64 character of the 6 bit value without branching
+// on the value.
+// See: https://github.com/paragonie/constant_time_encoding
+func base64Char(v int, url bool) byte {
+	// Start at 'A' and shift to the next range of characters whenever the value
+	// is above its lower range.
+	diff := int('A')
+	// 'a' - 'z' (26 - 51).
+	diff += ((25 - v) >> 8) & ('a' - 'A' - 26)
+	// '0' - '9' (52 - 61).
+	diff -= ((51 - v) >> 8) & ('a' + 26 - '0')
+
+	// Only the alphabet (not the value) decides which branch is taken.
+	if url {
+		// '-' (62).
+		diff -= ((61 - v) >> 8) & ('0' + 10 - '-')
+		// '_' (63).
+		diff += ((62 - v) >> 8) & ('_' - '-' - 1)
+	} else {
+		// '+' (62).
+		diff -= ((61 - v) >> 8) & ('0' + 10 - '+')
+		// '/' (63).
+		diff += ((62 - v) >> 8) & ('/' - '+' - 1)
+	}
+
+	return byte(v + diff)
+}
+
+// base64Value returns the value of the base64 character or -1 if it's invalid
+// without branching on the character.
+// See: https://github.com/paragonie/constant_time_encoding
+func base64Value(c byte, url bool) int {
+	src := int(c)
+	result := -1
+
+	// 'A' - 'Z' (0 - 25).
+	result += (((0x40 - src) & (src - 0x5b)) >> 8) & (src - 64)
+	// 'a' - 'z' (26 - 51).
+	result += (((0x60 - src) & (src - 0x7b)) >> 8) & (src - 70)
+	// '0' - '9' (52 - 61).
+	result += (((0x2f - src) & (src - 0x3a)) >> 8) & (src + 5)
+
+	// Only the alphabet (not the character) decides which branch is taken.
+	if url {
+		// '-' (62).
+		result += (((0x2c - src) & (src - 0x2e)) >> 8) & 63
+		// '_' (63).
+		result += (((0x5e - src) & (src - 0x60)) >> 8) & 64
+	} else {
+		// '+' (62).
+		result += (((0x2a - src) & (src - 0x2c)) >> 8) & 63
+		// '/' (63).
+		result += (((0x2e - src) & (src - 0x30)) >> 8) & 64
+	}
+
+	return result
+}
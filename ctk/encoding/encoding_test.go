@@ -0,0 +1,191 @@
+package encoding_test
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/encoding"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	t.Run("RFC 4648 - Test Vectors", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]struct {
+			hex       string
+			base64    string
+			base64URL string
+		}{
+			"":       {hex: "", base64: "", base64URL: ""},
+			"f":      {hex: "66", base64: "Zg==", base64URL: "Zg"},
+			"fo":     {hex: "666f", base64: "Zm8=", base64URL: "Zm8"},
+			"foo":    {hex: "666f6f", base64: "Zm9v", base64URL: "Zm9v"},
+			"foob":   {hex: "666f6f62", base64: "Zm9vYg==", base64URL: "Zm9vYg"},
+			"fooba":  {hex: "666f6f6261", base64: "Zm9vYmE=", base64URL: "Zm9vYmE"},
+			"foobar": {hex: "666f6f626172", base64: "Zm9vYmFy", base64URL: "Zm9vYmFy"},
+		}
+
+		for data, tc := range tt {
+			encodings := map[encoding.Encoding]string{
+				encoding.Hex:       tc.hex,
+				encoding.Base64:    tc.base64,
+				encoding.Base64URL: tc.base64URL,
+			}
+
+			for enc, want := range encodings {
+				got := encoding.Encode([]byte(data), enc)
+
+				if got != want {
+					t.Errorf("%q (%v): want %v, got %v", data, enc, want, got)
+				}
+
+				decoded, err := encoding.Decode(want, enc)
+				if err != nil {
+					t.Fatalf("%q (%v): want error %v, got %v", data, enc, nil, err)
+				}
+
+				if string(decoded) != data {
+					t.Errorf("%q (%v): want %v, got %v", data, enc, data, string(decoded))
+				}
+			}
+		}
+	})
+
+	t.Run("Matches Standard Library", func(t *testing.T) {
+		t.Parallel()
+
+		for length := range 100 {
+			data := make([]byte, length)
+			rand.Read(data)
+
+			tt := map[encoding.Encoding]string{
+				encoding.Hex:       hex.EncodeToString(data),
+				encoding.Base64:    base64.StdEncoding.EncodeToString(data),
+				encoding.Base64URL: base64.RawURLEncoding.EncodeToString(data),
+			}
+
+			for enc, want := range tt {
+				got := encoding.Encode(data, enc)
+
+				if got != want {
+					t.Errorf("%v: want %v, got %v", enc, want, got)
+				}
+
+				decoded, _ := encoding.Decode(want, enc)
+
+				if !slices.Equal(decoded, data) {
+					t.Errorf("%v: want %v, got %v", enc, data, decoded)
+				}
+			}
+		}
+	})
+
+	t.Run("Uppercase Hex", func(t *testing.T) {
+		t.Parallel()
+
+		got, err := encoding.Decode("DEADbeef", encoding.Hex)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		want := []byte{0xde, 0xad, 0xbe, 0xef}
+
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Invalid Encodings", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]struct {
+			s   string
+			enc encoding.Encoding
+		}{
+			"Hex - Odd Length":            {s: "abc", enc: encoding.Hex},
+			"Hex - Invalid Character":     {s: "0g", enc: encoding.Hex},
+			"Base64 - Missing Padding":    {s: "Zg", enc: encoding.Base64},
+			"Base64 - Too Much Padding":   {s: "Z===", enc: encoding.Base64},
+			"Base64 - URL Character":      {s: "Zm-_", enc: encoding.Base64},
+			"Base64 - Non-Canonical":      {s: "Zh==", enc: encoding.Base64},
+			"Base64URL - Padding":         {s: "Zg==", enc: encoding.Base64URL},
+			"Base64URL - Std Character":   {s: "Zm+/", enc: encoding.Base64URL},
+			"Base64URL - Invalid Length":  {s: "Zm9vY", enc: encoding.Base64URL},
+			"Base64URL - Non-Canonical":   {s: "Zh", enc: encoding.Base64URL},
+			"Base64URL - Invalid Padding": {s: "Z=g", enc: encoding.Base64URL},
+		}
+
+		for name, tc := range tt {
+			_, err := encoding.Decode(tc.s, tc.enc)
+
+			if !errors.Is(err, encoding.ErrInvalidEncoding) {
+				t.Errorf("%v: want error %v, got %v", name, encoding.ErrInvalidEncoding, err)
+			}
+		}
+	})
+}
+
+func TestParse(t *testing.T) {
+	t.Run("Key", func(t *testing.T) {
+		t.Parallel()
+
+		var want [32]byte
+		rand.Read(want[:])
+
+		inputs := []string{
+			encoding.FormatKey(want),
+			base64.StdEncoding.EncodeToString(want[:]),
+			base64.RawURLEncoding.EncodeToString(want[:]),
+		}
+
+		for _, input := range inputs {
+			got, err := encoding.ParseKey(input)
+			if err != nil {
+				t.Fatalf("%v: want error %v, got %v", input, nil, err)
+			}
+
+			if got != want {
+				t.Errorf("%v: want %v, got %v", input, want, got)
+			}
+		}
+	})
+
+	t.Run("Nonce + Tag", func(t *testing.T) {
+		t.Parallel()
+
+		nonce := [12]byte{0x01}
+		xnonce := [24]byte{0x02}
+		tag := [16]byte{0x03}
+
+		gotNonce, _ := encoding.ParseNonce(encoding.FormatNonce(nonce[:]))
+		gotXNonce, _ := encoding.ParseXNonce(encoding.FormatNonce(xnonce[:]))
+		gotTag, _ := encoding.ParseTag(encoding.FormatTag(tag))
+
+		if gotNonce != nonce || gotXNonce != xnonce || gotTag != tag {
+			t.Errorf("want %v %v %v, got %v %v %v", nonce, xnonce, tag, gotNonce, gotXNonce, gotTag)
+		}
+	})
+
+	t.Run("Invalid Length", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]string{
+			"Short Hex":    "00112233",
+			"Long Hex":     encoding.Encode(make([]byte, 33), encoding.Hex),
+			"Short Base64": encoding.Encode(make([]byte, 31), encoding.Base64),
+			"Invalid Hex":  encoding.Encode(make([]byte, 31), encoding.Hex) + "zz",
+		}
+
+		for name, input := range tt {
+			_, err := encoding.ParseKey(input)
+
+			if !errors.Is(err, encoding.ErrInvalidLength) {
+				t.Errorf("%v: want error %v, got %v", name, encoding.ErrInvalidLength, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package encoding
+
+import "encoding/json"
+
+// Key is a 32 byte key that's hex encoded when marshalled.
+// It can be converted to and from the [32]byte keys used throughout the toolkit.
+type Key [32]byte
+
+// Nonce is a 12 byte (ChaCha20) nonce that's hex encoded when marshalled.
+type Nonce [12]byte
+
+// XNonce is a 24 byte (XChaCha20) nonce that's hex encoded when marshalled.
+type XNonce [24]byte
+
+// Tag is a 16 byte (Poly1305) tag that's hex encoded when marshalled.
+type Tag [16]byte
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (k Key) MarshalText() ([]byte, error) {
+	return []byte(Encode(k[:], Hex)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+// The text can be hex, base64 or base64url encoded.
+func (k *Key) UnmarshalText(text []byte) error {
+	key, err := ParseKey(string(text))
+	if err != nil {
+		return err
+	}
+
+	*k = key
+
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (k Key) MarshalJSON() ([]byte, error) {
+	return marshalJSON(k[:])
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (k *Key) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, k)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (n Nonce) MarshalText() ([]byte, error) {
+	return []byte(Encode(n[:], Hex)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+// The text can be hex, base64 or base64url encoded.
+func (n *Nonce) UnmarshalText(text []byte) error {
+	nonce, err := ParseNonce(string(text))
+	if err != nil {
+		return err
+	}
+
+	*n = nonce
+
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (n Nonce) MarshalJSON() ([]byte, error) {
+	return marshalJSON(n[:])
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (n *Nonce) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (n XNonce) MarshalText() ([]byte, error) {
+	return []byte(Encode(n[:], Hex)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+// The text can be hex, base64 or base64url encoded.
+func (n *XNonce) UnmarshalText(text []byte) error {
+	nonce, err := ParseXNonce(string(text))
+	if err != nil {
+		return err
+	}
+
+	*n = nonce
+
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (n XNonce) MarshalJSON() ([]byte, error) {
+	return marshalJSON(n[:])
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (n *XNonce) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, n)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (t Tag) MarshalText() ([]byte, error) {
+	return []byte(Encode(t[:], Hex)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+// The text can be hex, base64 or base64url encoded.
+func (t *Tag) UnmarshalText(text []byte) error {
+	tag, err := ParseTag(string(text))
+	if err != nil {
+		return err
+	}
+
+	*t = tag
+
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (t Tag) MarshalJSON() ([]byte, error) {
+	return marshalJSON(t[:])
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (t *Tag) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, t)
+}
+
+// marshalJSON encodes the data as a hex encoded JSON string.
+func marshalJSON(data []byte) ([]byte, error) {
+	return json.Marshal(Encode(data, Hex))
+}
+
+// textUnmarshaler is implemented by the pointers to the types.
+type textUnmarshaler interface {
+	UnmarshalText(text []byte) error
+}
+
+// unmarshalJSON decodes the JSON string and unmarshals its text into v.
+func unmarshalJSON(data []byte, v textUnmarshaler) error {
+	var s string
+
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+
+	return v.UnmarshalText([]byte(s))
+}
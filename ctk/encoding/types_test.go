@@ -0,0 +1,92 @@
+package encoding_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/encoding"
+)
+
+func TestTypes(t *testing.T) {
+	t.Run("JSON", func(t *testing.T) {
+		t.Parallel()
+
+		type metadata struct {
+			Key    encoding.Key    `json:"key"`
+			Nonce  encoding.Nonce  `json:"nonce"`
+			XNonce encoding.XNonce `json:"xnonce"`
+			Tag    encoding.Tag    `json:"tag"`
+		}
+
+		want := metadata{
+			Key:    encoding.Key{0x01},
+			Nonce:  encoding.Nonce{0x02},
+			XNonce: encoding.XNonce{0x03},
+			Tag:    encoding.Tag{0x04},
+		}
+
+		data, err := json.Marshal(want)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		var got metadata
+
+		err = json.Unmarshal(data, &got)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if got != want {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Text", func(t *testing.T) {
+		t.Parallel()
+
+		tag := encoding.Tag{0xde, 0xad, 0xbe, 0xef}
+
+		text, _ := tag.MarshalText()
+
+		got := string(text)
+		want := "deadbeef000000000000000000000000"
+
+		if got != want {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Base64", func(t *testing.T) {
+		t.Parallel()
+
+		var got encoding.Key
+
+		err := got.UnmarshalText([]byte(encoding.Encode(make([]byte, 32), encoding.Base64URL)))
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		want := encoding.Key{}
+
+		if got != want {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Invalid Length", func(t *testing.T) {
+		t.Parallel()
+
+		var n encoding.Nonce
+
+		err := json.Unmarshal([]byte(`"00112233"`), &n)
+
+		gotError := err
+		wantError := encoding.ErrInvalidLength
+
+		if !errors.Is(gotError, wantError) {
+			t.Errorf("want error %v, got %v", wantError, gotError)
+		}
+	})
+}
@@ -0,0 +1,409 @@
+// Package envelope implements envelope encryption where the payload is
+// encrypted with a random data-encryption key (DEK) which is then wrapped under
+// a key-encryption key (KEK) or for an X25519 recipient.
+//
+// The payload is encrypted via XChaCha20-Poly1305 with the envelope's header
+// (the algorithm, the wrapped DEK, the key ID, etc.) bound to it as part of the
+// additional authenticated data (AAD). For X25519 recipients an ephemeral key
+// pair is generated and the KEK is derived from the shared secret via
+// HKDF-SHA256. DEKs can also be wrapped by an external kms.KeyProvider.
+package envelope
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+
+	"github.com/pmuens/ctk-go/ctk/hkdf"
+	"github.com/pmuens/ctk-go/ctk/internal/debug"
+	"github.com/pmuens/ctk-go/ctk/keywrap"
+	"github.com/pmuens/ctk-go/ctk/kms"
+	"github.com/pmuens/ctk-go/ctk/random"
+	"github.com/pmuens/ctk-go/ctk/x25519"
+	"github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
+)
+
+const (
+	// ErrInvalidEnvelope is returned by Parse if the envelope is malformed or
+	// uses an unsupported version or algorithm. The decryption methods report
+	// such envelopes as ErrDecryption (see ErrDecryption).
+	ErrInvalidEnvelope = Error("invalid envelope")
+
+	// ErrWrongKeyType is returned if the envelope is decrypted with a key
+	// type that doesn't match the one it was encrypted for.
+	ErrWrongKeyType = Error("wrong key type")
+
+	// ErrDecryption is returned if the envelope is malformed, the DEK can't be
+	// unwrapped or the payload can't be authenticated. The cause is only added
+	// in builds with the ctkdebug build tag so that the errors don't tell
+	// attackers which part of a forged envelope was rejected.
+	ErrDecryption = Error("decryption failed")
+)
+
+// Version is the version of the envelope format.
+const Version = 1
+
+// Algorithm is the identifier of the payload encryption algorithm.
+const Algorithm = "xchacha20poly1305"
+
+// Identifiers of the key encryption methods.
+const (
+	// KeyWrap identifies DEKs that are wrapped under a symmetric KEK.
+	KeyWrap = "keywrap"
+
+	// X25519 identifies DEKs that are wrapped for an X25519 recipient.
+	X25519 = "x25519"
+
+	// Provider identifies DEKs that are wrapped by a kms.KeyProvider.
+	Provider = "kms"
+)
+
+// DEKSize is the size (in bytes) of the data-encryption key.
+const DEKSize = 32
+
+// x25519Info is used for domain separation when deriving a KEK from an X25519
+// shared secret.
+var x25519Info = []byte("ctk-go envelope x25519")
+
+// Envelope is an encrypted payload together with its wrapped DEK.
+type Envelope struct {
+	// Version is the version of the envelope format.
+	Version int `json:"version"`
+
+	// Algorithm identifies the payload encryption algorithm.
+	Algorithm string `json:"alg"`
+
+	// KeyEncryption identifies how the DEK was wrapped.
+	KeyEncryption string `json:"key_enc"`
+
+	// KeyID is the ID of the provider's key the DEK was wrapped under
+	// (Provider only).
+	KeyID string `json:"kid,omitempty"`
+
+	// EphemeralPublicKey is the ephemeral X25519 public key (X25519 only).
+	EphemeralPublicKey []byte `json:"epk,omitempty"`
+
+	// WrappedKey is the wrapped DEK.
+	WrappedKey []byte `json:"wrapped_key"`
+
+	// Nonce is the nonce used to encrypt the payload.
+	Nonce []byte `json:"nonce"`
+
+	// Ciphertext is the encrypted payload followed by its tag.
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// header is the part of the envelope that describes how the payload is
+// encrypted. Its JSON encoding is authenticated in front of the AAD so that
+// the algorithm, the wrapped DEK or the key ID can't be swapped without the
+// payload failing to decrypt (the encoding of the object can't be extended,
+// which keeps the concatenation unambiguous).
+type header struct {
+	Version            int    `json:"version"`
+	Algorithm          string `json:"alg"`
+	KeyEncryption      string `json:"key_enc"`
+	KeyID              string `json:"kid,omitempty"`
+	EphemeralPublicKey []byte `json:"epk,omitempty"`
+	WrappedKey         []byte `json:"wrapped_key"`
+	Nonce              []byte `json:"nonce"`
+}
+
+// Encrypt encrypts the plaintext with a random DEK which is wrapped under the KEK.
+// The additional authenticated data (AAD) is bound to the payload.
+func Encrypt(kek [32]byte, plaintext []byte, aad []byte) (*Envelope, error) {
+	dek, err := newDEK()
+	if err != nil {
+		return nil, err
+	}
+	defer clear(dek[:])
+
+	wrapped, err := keywrap.Wrap(kek, dek[:])
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Envelope{
+		KeyEncryption: KeyWrap,
+		WrappedKey:    wrapped,
+	}
+
+	err = e.seal(dek, plaintext, aad)
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// EncryptFor encrypts the plaintext with a random DEK which is wrapped for the
+// X25519 recipient's public key.
+// The additional authenticated data (AAD) is bound to the payload.
+func EncryptFor(recipient [32]byte, plaintext []byte, aad []byte) (*Envelope, error) {
+	ephemeralPrivate, ephemeralPublic, err := x25519.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	defer clear(ephemeralPrivate[:])
+
+	shared, err := x25519.SharedSecret(ephemeralPrivate, recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	kek := recipientKEK(shared, ephemeralPublic, recipient)
+
+	dek, err := newDEK()
+	if err != nil {
+		return nil, err
+	}
+	defer clear(dek[:])
+
+	wrapped, err := keywrap.Wrap(kek, dek[:])
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Envelope{
+		KeyEncryption:      X25519,
+		EphemeralPublicKey: ephemeralPublic[:],
+		WrappedKey:         wrapped,
+	}
+
+	err = e.seal(dek, plaintext, aad)
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// EncryptWithProvider encrypts the plaintext with a random DEK which is wrapped
+// by the provider under the key with the key ID.
+// The additional authenticated data (AAD) is bound to the payload.
+func EncryptWithProvider(ctx context.Context, provider kms.KeyProvider, keyID string, plaintext []byte, aad []byte) (*Envelope, error) {
+	dek, err := newDEK()
+	if err != nil {
+		return nil, err
+	}
+	defer clear(dek[:])
+
+	wrapped, err := provider.WrapKey(ctx, keyID, dek[:])
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Envelope{
+		KeyEncryption: Provider,
+		KeyID:         keyID,
+		WrappedKey:    wrapped,
+	}
+
+	err = e.seal(dek, plaintext, aad)
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// Decrypt unwraps the DEK with the KEK and decrypts the payload.
+// Returns an error if the envelope is malformed, wasn't wrapped under a KEK or
+// can't be decrypted.
+func (e *Envelope) Decrypt(kek [32]byte, aad []byte) ([]byte, error) {
+	err := e.validate()
+	if err != nil {
+		return []byte{}, debug.Detail(ErrDecryption, err)
+	}
+
+	if e.KeyEncryption != KeyWrap {
+		return []byte{}, ErrWrongKeyType
+	}
+
+	dek, err := keywrap.Unwrap(kek, e.WrappedKey)
+	if err != nil {
+		return []byte{}, ErrDecryption
+	}
+
+	return e.open(dek, aad)
+}
+
+// DecryptWithIdentity unwraps the DEK with the recipient's X25519 private key
+// and decrypts the payload.
+// Returns an error if the envelope is malformed, wasn't wrapped for an X25519
+// recipient or can't be decrypted.
+func (e *Envelope) DecryptWithIdentity(private [32]byte, aad []byte) ([]byte, error) {
+	err := e.validate()
+	if err != nil {
+		return []byte{}, debug.Detail(ErrDecryption, err)
+	}
+
+	if e.KeyEncryption != X25519 {
+		return []byte{}, ErrWrongKeyType
+	}
+
+	if len(e.EphemeralPublicKey) != x25519.KeySize {
+		return []byte{}, debug.Detail(ErrDecryption, ErrInvalidEnvelope)
+	}
+
+	ephemeralPublic := [32]byte(e.EphemeralPublicKey)
+
+	shared, err := x25519.SharedSecret(private, ephemeralPublic)
+	if err != nil {
+		return []byte{}, ErrDecryption
+	}
+
+	kek := recipientKEK(shared, ephemeralPublic, x25519.PublicKey(private))
+
+	dek, err := keywrap.Unwrap(kek, e.WrappedKey)
+	if err != nil {
+		return []byte{}, ErrDecryption
+	}
+
+	return e.open(dek, aad)
+}
+
+// DecryptWithProvider unwraps the DEK via the provider and decrypts the payload.
+// Returns an error if the envelope is malformed, wasn't wrapped by a provider
+// or can't be decrypted (ErrDecryption also covers the provider's errors).
+func (e *Envelope) DecryptWithProvider(ctx context.Context, provider kms.KeyProvider, aad []byte) ([]byte, error) {
+	err := e.validate()
+	if err != nil {
+		return []byte{}, debug.Detail(ErrDecryption, err)
+	}
+
+	if e.KeyEncryption != Provider {
+		return []byte{}, ErrWrongKeyType
+	}
+
+	// The provider's error (e.g. for a tampered wrapped key) would tell which
+	// part of a forged envelope was rejected.
+	dek, err := provider.UnwrapKey(ctx, e.KeyID, e.WrappedKey)
+	if err != nil {
+		return []byte{}, debug.Detail(ErrDecryption, err)
+	}
+
+	return e.open(dek, aad)
+}
+
+// Bytes returns the JSON encoding of the envelope.
+func (e *Envelope) Bytes() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// Parse parses the JSON encoding of an envelope.
+// Returns an error if the envelope is malformed.
+func Parse(data []byte) (*Envelope, error) {
+	var e Envelope
+
+	err := json.Unmarshal(data, &e)
+	if err != nil {
+		return nil, ErrInvalidEnvelope
+	}
+
+	err = e.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// seal encrypts the plaintext with the DEK and stores the result in the envelope.
+func (e *Envelope) seal(dek [32]byte, plaintext []byte, aad []byte) error {
+	var nonce [24]byte
+
+	err := random.Read(nonce[:])
+	if err != nil {
+		return err
+	}
+
+	e.Version = Version
+	e.Algorithm = Algorithm
+	e.Nonce = nonce[:]
+
+	h, err := e.header()
+	if err != nil {
+		return err
+	}
+
+	xchaPoly := xchacha20poly1305.NewXChaCha20Poly1305(dek, nonce)
+	xchaPoly.AddAAD(h)
+	ciphertext, tag := xchaPoly.Encrypt(plaintext, aad)
+
+	e.Ciphertext = append(ciphertext, tag[:]...)
+
+	return nil
+}
+
+// open decrypts the payload with the (unwrapped) DEK which is wiped afterwards.
+func (e *Envelope) open(dek []byte, aad []byte) ([]byte, error) {
+	defer clear(dek)
+
+	if len(dek) != DEKSize {
+		return []byte{}, ErrDecryption
+	}
+
+	h, err := e.header()
+	if err != nil {
+		return []byte{}, ErrDecryption
+	}
+
+	ciphertext := e.Ciphertext[:len(e.Ciphertext)-16]
+	tag := [16]byte(e.Ciphertext[len(e.Ciphertext)-16:])
+
+	xchaPoly := xchacha20poly1305.NewXChaCha20Poly1305([32]byte(dek), [24]byte(e.Nonce))
+	xchaPoly.AddAAD(h)
+	plaintext, err := xchaPoly.Decrypt(ciphertext, aad, tag)
+	if err != nil {
+		return []byte{}, ErrDecryption
+	}
+
+	return plaintext, nil
+}
+
+// header returns the JSON encoding of the envelope's header.
+func (e *Envelope) header() ([]byte, error) {
+	return json.Marshal(header{
+		Version:            e.Version,
+		Algorithm:          e.Algorithm,
+		KeyEncryption:      e.KeyEncryption,
+		KeyID:              e.KeyID,
+		EphemeralPublicKey: e.EphemeralPublicKey,
+		WrappedKey:         e.WrappedKey,
+		Nonce:              e.Nonce,
+	})
+}
+
+// validate checks that the envelope is well-formed.
+func (e *Envelope) validate() error {
+	if e.Version != Version || e.Algorithm != Algorithm || len(e.Nonce) != 24 || len(e.Ciphertext) < 16 {
+		return ErrInvalidEnvelope
+	}
+
+	return nil
+}
+
+// newDEK generates a random data-encryption key.
+func newDEK() ([32]byte, error) {
+	var dek [32]byte
+
+	err := random.Read(dek[:])
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	return dek, nil
+}
+
+// recipientKEK derives the KEK from the X25519 shared secret. Both public keys
+// are bound into the derivation.
+func recipientKEK(shared [32]byte, ephemeralPublic [32]byte, recipient [32]byte) [32]byte {
+	salt := make([]byte, 0, 2*x25519.KeySize)
+	salt = append(salt, ephemeralPublic[:]...)
+	salt = append(salt, recipient[:]...)
+
+	// The output length is well below the limit so that no error can occur.
+	kek, _ := hkdf.Key(sha256.New, shared[:], salt, x25519Info, 32)
+
+	return [32]byte(kek)
+}
@@ -0,0 +1,183 @@
+package envelope_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/envelope"
+	"github.com/pmuens/ctk-go/ctk/kms"
+	"github.com/pmuens/ctk-go/ctk/x25519"
+)
+
+var kek = [32]byte{
+	0x80, 0x81, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87,
+	0x88, 0x89, 0x8a, 0x8b, 0x8c, 0x8d, 0x8e, 0x8f,
+	0x90, 0x91, 0x92, 0x93, 0x94, 0x95, 0x96, 0x97,
+	0x98, 0x99, 0x9a, 0x9b, 0x9c, 0x9d, 0x9e, 0x9f,
+}
+
+func TestEnvelope(t *testing.T) {
+	data := []byte("Ladies and Gentlemen of the class of '99")
+	aad := []byte("aad")
+
+	t.Run("KEK", func(t *testing.T) {
+		t.Parallel()
+
+		e, err := envelope.Encrypt(kek, data, aad)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		got, err := e.Decrypt(kek, aad)
+
+		if !slices.Equal(got, data) {
+			t.Errorf("want %v, got %v (error %v)", data, got, err)
+		}
+	})
+
+	t.Run("X25519 Recipient", func(t *testing.T) {
+		t.Parallel()
+
+		private, public, _ := x25519.GenerateKey()
+
+		e, err := envelope.EncryptFor(public, data, aad)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		got, err := e.DecryptWithIdentity(private, aad)
+
+		if !slices.Equal(got, data) {
+			t.Errorf("want %v, got %v (error %v)", data, got, err)
+		}
+
+		other, _, _ := x25519.GenerateKey()
+		_, err = e.DecryptWithIdentity(other, aad)
+
+		if !errors.Is(err, envelope.ErrDecryption) {
+			t.Errorf("want error %v, got %v", envelope.ErrDecryption, err)
+		}
+	})
+
+	t.Run("Key Provider", func(t *testing.T) {
+		t.Parallel()
+
+		provider := kms.NewMemory()
+		provider.AddKey("kek", kek)
+
+		e, err := envelope.EncryptWithProvider(context.Background(), provider, "kek", data, aad)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if e.KeyID != "kek" {
+			t.Errorf("want key ID %v, got %v", "kek", e.KeyID)
+		}
+
+		got, err := e.DecryptWithProvider(context.Background(), provider, aad)
+
+		if !slices.Equal(got, data) {
+			t.Errorf("want %v, got %v (error %v)", data, got, err)
+		}
+
+		_, err = e.Decrypt(kek, aad)
+
+		if !errors.Is(err, envelope.ErrWrongKeyType) {
+			t.Errorf("want error %v, got %v", envelope.ErrWrongKeyType, err)
+		}
+	})
+
+	t.Run("Tampered Header", func(t *testing.T) {
+		t.Parallel()
+
+		// Both key IDs unwrap the DEK so that only the header binding detects
+		// the swapped key ID.
+		provider := kms.NewMemory()
+		provider.AddKey("kek", kek)
+		provider.AddKey("other", kek)
+
+		e, _ := envelope.EncryptWithProvider(context.Background(), provider, "kek", data, aad)
+
+		tampered := *e
+		tampered.KeyID = "other"
+
+		_, err := tampered.DecryptWithProvider(context.Background(), provider, aad)
+		if !errors.Is(err, envelope.ErrDecryption) {
+			t.Errorf("want error %v, got %v", envelope.ErrDecryption, err)
+		}
+	})
+
+	t.Run("Tampered Wrapped Key", func(t *testing.T) {
+		t.Parallel()
+
+		provider := kms.NewMemory()
+		provider.AddKey("kek", kek)
+
+		e, _ := envelope.EncryptWithProvider(context.Background(), provider, "kek", data, aad)
+
+		tampered := *e
+		tampered.WrappedKey = slices.Clone(e.WrappedKey)
+		tampered.WrappedKey[0] ^= 0x01
+
+		_, err := tampered.DecryptWithProvider(context.Background(), provider, aad)
+		if !errors.Is(err, envelope.ErrDecryption) {
+			t.Errorf("want error %v, got %v", envelope.ErrDecryption, err)
+		}
+	})
+
+	t.Run("Bytes + Parse", func(t *testing.T) {
+		t.Parallel()
+
+		e, _ := envelope.Encrypt(kek, data, aad)
+		encoded, _ := e.Bytes()
+
+		parsed, err := envelope.Parse(encoded)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		got, err := parsed.Decrypt(kek, aad)
+
+		if !slices.Equal(got, data) {
+			t.Errorf("want %v, got %v (error %v)", data, got, err)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		e, _ := envelope.Encrypt(kek, data, aad)
+
+		wrongKek := kek
+		wrongKek[0] ^= 0x01
+
+		tampered := *e
+		tampered.Ciphertext = slices.Clone(e.Ciphertext)
+		tampered.Ciphertext[0] ^= 0x01
+
+		_, wrongKekErr := e.Decrypt(wrongKek, aad)
+		_, wrongAadErr := e.Decrypt(kek, []byte("other"))
+		_, tamperedErr := tampered.Decrypt(kek, aad)
+		_, wrongTypeErr := e.DecryptWithIdentity([32]byte{}, aad)
+		_, parseErr := envelope.Parse([]byte(`{"version":2}`))
+
+		tt := map[string]struct {
+			err  error
+			want error
+		}{
+			"Wrong KEK":  {err: wrongKekErr, want: envelope.ErrDecryption},
+			"Wrong AAD":  {err: wrongAadErr, want: envelope.ErrDecryption},
+			"Tampered":   {err: tamperedErr, want: envelope.ErrDecryption},
+			"Wrong Type": {err: wrongTypeErr, want: envelope.ErrWrongKeyType},
+			"Malformed":  {err: parseErr, want: envelope.ErrInvalidEnvelope},
+		}
+
+		for name, tc := range tt {
+			if !errors.Is(tc.err, tc.want) {
+				t.Errorf("%v: want error %v, got %v", name, tc.want, tc.err)
+			}
+		}
+	})
+}
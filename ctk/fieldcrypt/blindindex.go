@@ -0,0 +1,90 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+
+	"github.com/pmuens/ctk-go/ctk/hkdf"
+)
+
+// ErrInvalidIndexSize is returned if the size of a blind index is out of range.
+const ErrInvalidIndexSize = Error("invalid blind index size")
+
+const (
+	// BlindIndexSize is the default size (in bytes) of a blind index.
+	BlindIndexSize = 16
+
+	// MaxBlindIndexSize is the maximum size (in bytes) of a blind index (the
+	// size of an HMAC-SHA-256 output).
+	MaxBlindIndexSize = sha256.Size
+)
+
+// blindIndexLabel is used for domain separation when deriving the blind index
+// key of a column from the master key.
+var blindIndexLabel = []byte("ctk-go fieldcrypt blind index")
+
+// BlindIndex returns the blind index of the (normalized) value in the column
+// with the default size. See BlindIndexSized.
+func (f *FieldCrypt) BlindIndex(column []byte, value []byte) []byte {
+	index, _ := f.BlindIndexSized(column, value, BlindIndexSize)
+
+	return index
+}
+
+// BlindIndexSized returns the blind index of the value in the column as the
+// HMAC-SHA-256 of the value truncated to size bytes. The HMAC key is derived
+// from the master key and the column name via HKDF so that equal values in
+// different columns have unrelated indexes.
+//
+// A blind index is stored next to the encrypted field and allows exact-match
+// lookups (WHERE index = ?) without decrypting the fields. Values should be
+// normalized first (see NormalizeValue) so that equivalent spellings match.
+//
+// Leakage: the index is deterministic, so anyone with access to the stored
+// indexes learns which rows of a column share the same value and how often
+// each value occurs. For low-entropy columns (e.g. booleans, countries or
+// birth years) this is often enough to recover the values via frequency
+// analysis and the column shouldn't be indexed. Truncating the index (smaller
+// size) maps different values to the same index (false positives which have
+// to be filtered after decryption) and reduces, but doesn't remove, this
+// leakage. The index doesn't leak anything about the value to someone without
+// the master key beyond that.
+//
+// Returns ErrInvalidIndexSize if the size isn't within 1 and
+// MaxBlindIndexSize.
+func (f *FieldCrypt) BlindIndexSized(column []byte, value []byte, size int) ([]byte, error) {
+	if size < 1 || size > MaxBlindIndexSize {
+		return []byte{}, ErrInvalidIndexSize
+	}
+
+	mac, err := f.columnMAC(blindIndexLabel, column, value)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return mac[:size], nil
+}
+
+// columnMAC returns the HMAC-SHA-256 of the data with a key derived from the
+// master key, the label and the column name via HKDF.
+func (f *FieldCrypt) columnMAC(label []byte, column []byte, data []byte) ([]byte, error) {
+	key, err := hkdf.Key(sha256.New, f.masterKey[:], column, label, 32)
+	if err != nil {
+		return []byte{}, err
+	}
+	defer clear(key)
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+
+	return mac.Sum(nil), nil
+}
+
+// NormalizeValue normalizes the value for a case-insensitive exact-match lookup
+// by trimming leading and trailing white space and mapping Unicode letters to
+// lower case. Applications with other notions of equivalence (e.g. Unicode
+// normalization forms) should apply their own normalization instead.
+func NormalizeValue(value []byte) []byte {
+	return bytes.ToLower(bytes.TrimSpace(value))
+}
@@ -0,0 +1,122 @@
+package fieldcrypt
+
+import "encoding/binary"
+
+const (
+	// ErrInvalidBucketWidth is returned if the width of a bucket isn't positive.
+	ErrInvalidBucketWidth = Error("invalid bucket width")
+
+	// ErrInvalidRange is returned if the lower bound of a range is greater than
+	// the upper bound.
+	ErrInvalidRange = Error("invalid range")
+
+	// ErrTooManyBuckets is returned if a range covers more than MaxRangeBuckets
+	// buckets.
+	ErrTooManyBuckets = Error("too many buckets")
+)
+
+const (
+	// BucketSize is the size (in bytes) of a bucket ID.
+	BucketSize = 16
+
+	// MaxRangeBuckets is the maximum number of buckets a range can cover.
+	MaxRangeBuckets = 1024
+)
+
+// bucketLabel is used for domain separation when deriving the bucket key of a
+// column from the master key.
+var bucketLabel = []byte("ctk-go fieldcrypt bucket")
+
+// Bucket returns the ID of the bucket the value in the column falls into. The
+// values are split into buckets of the width ([0, width), [width, 2*width),
+// ...) and the bucket's number is turned into the ID via HMAC-SHA-256 with a key
+// derived from the master key and the column name (truncated to BucketSize
+// bytes). The width is bound to the ID so that buckets of different widths
+// never share an ID.
+//
+// A bucket ID is stored next to the encrypted field and allows range queries
+// (WHERE bucket IN (...), see BucketRange) without decrypting the fields. The
+// results include all values of the boundary buckets and have to be filtered
+// after decryption.
+//
+// Security: the ID is deterministic, so anyone with access to the stored IDs
+// learns which rows of a column fall into the same bucket and how many rows
+// each bucket holds. Unlike order-preserving or order-revealing encryption the
+// IDs don't reveal the order of the buckets, but the order can often be
+// inferred from the queries (ranges cover consecutive buckets) or from known
+// distributions (e.g. of salaries or ages). Wider buckets leak less and cost
+// more filtering. Pick the width so that every bucket holds many values and
+// don't bucketize columns where the bucket itself is sensitive.
+//
+// Returns ErrInvalidBucketWidth if the width isn't positive.
+func (f *FieldCrypt) Bucket(column []byte, value int64, width int64) ([]byte, error) {
+	if width < 1 {
+		return []byte{}, ErrInvalidBucketWidth
+	}
+
+	return f.bucketID(column, bucketNumber(value, width), width)
+}
+
+// BucketRange returns the IDs of all buckets which contain values of the range
+// [low, high] (see Bucket).
+// Returns ErrInvalidBucketWidth if the width isn't positive, ErrInvalidRange if
+// low is greater than high and ErrTooManyBuckets if the range covers more than
+// MaxRangeBuckets buckets.
+func (f *FieldCrypt) BucketRange(column []byte, low int64, high int64, width int64) ([][]byte, error) {
+	if width < 1 {
+		return [][]byte{}, ErrInvalidBucketWidth
+	}
+
+	if low > high {
+		return [][]byte{}, ErrInvalidRange
+	}
+
+	first := bucketNumber(low, width)
+	last := bucketNumber(high, width)
+
+	// The bucket numbers are within [MinInt64, MaxInt64] so the difference
+	// might overflow an int64 but not an uint64.
+	if uint64(last)-uint64(first) >= MaxRangeBuckets {
+		return [][]byte{}, ErrTooManyBuckets
+	}
+
+	ids := make([][]byte, 0, last-first+1)
+	for n := first; ; n++ {
+		id, err := f.bucketID(column, n, width)
+		if err != nil {
+			return [][]byte{}, err
+		}
+		ids = append(ids, id)
+
+		if n == last {
+			break
+		}
+	}
+
+	return ids, nil
+}
+
+// bucketID returns the ID of the bucket with the number and the width.
+func (f *FieldCrypt) bucketID(column []byte, number int64, width int64) ([]byte, error) {
+	data := make([]byte, 0, 16)
+	data = binary.BigEndian.AppendUint64(data, uint64(width))
+	data = binary.BigEndian.AppendUint64(data, uint64(number))
+
+	mac, err := f.columnMAC(bucketLabel, column, data)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return mac[:BucketSize], nil
+}
+
+// bucketNumber returns the number of the bucket the value falls into (rounded
+// towards negative infinity so that all buckets have the same width).
+func bucketNumber(value int64, width int64) int64 {
+	n := value / width
+	if value%width < 0 {
+		n--
+	}
+
+	return n
+}
@@ -0,0 +1,119 @@
+// Package fieldcrypt implements application-layer encryption of (small) database
+// fields with a key per record.
+//
+// The key of a record is derived from the master key and the record's ID via
+// HChaCha20 and the fields are encrypted with XChaCha20-Poly1305 using a random
+// nonce. The record ID is also bound to the ciphertext as additional
+// authenticated data (AAD) so that a field can't be moved to another record
+// without being detected.
+//
+// Blind indexes (see FieldCrypt.BlindIndex) allow exact-match lookups of
+// encrypted fields at the cost of revealing which fields share the same value.
+// Buckets (see FieldCrypt.Bucket) allow range queries at the cost of revealing
+// which fields fall into the same range.
+package fieldcrypt
+
+import (
+	"github.com/pmuens/ctk-go/ctk/blake2b"
+	"github.com/pmuens/ctk-go/ctk/internal/debug"
+	"github.com/pmuens/ctk-go/ctk/random"
+	"github.com/pmuens/ctk-go/ctk/xchacha20"
+	"github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
+)
+
+const (
+	// ErrInvalidField is the cause of ErrDecryption if the encrypted field is
+	// malformed (only added in builds with the ctkdebug build tag).
+	ErrInvalidField = Error("invalid encrypted field")
+
+	// ErrDecryption is returned if the encrypted field is malformed or can't be
+	// authenticated (e.g. because it belongs to another record).
+	ErrDecryption = Error("decryption failed")
+)
+
+// NonceSize is the size (in bytes) of the nonce that's prepended to an
+// encrypted field.
+const NonceSize = 24
+
+// TagSize is the size (in bytes) of the tag that's appended to an encrypted
+// field.
+const TagSize = 16
+
+// Overhead is the number of bytes an encrypted field is longer than the field
+// itself.
+const Overhead = NonceSize + TagSize
+
+// recordLabel is used for domain separation when turning a record ID into the
+// HChaCha20 nonce.
+var recordLabel = []byte("ctk-go fieldcrypt record")
+
+// FieldCrypt encrypts and decrypts fields with keys derived from a master key.
+// An instance isn't modified after its creation and is safe for concurrent use.
+type FieldCrypt struct {
+	// masterKey is the key the record keys are derived from.
+	masterKey [32]byte
+}
+
+// NewFieldCrypt creates a new instance which derives the record keys from the
+// master key.
+func NewFieldCrypt(masterKey [32]byte) *FieldCrypt {
+	return &FieldCrypt{
+		masterKey: masterKey,
+	}
+}
+
+// EncryptField encrypts the field of the record with the record's key.
+// The result is the nonce followed by the encrypted field and the tag.
+func (f *FieldCrypt) EncryptField(recordID []byte, field []byte) ([]byte, error) {
+	nonce, err := random.XNonce()
+	if err != nil {
+		return []byte{}, err
+	}
+
+	key := f.recordKey(recordID)
+	defer clear(key[:])
+
+	xchaPoly := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce)
+	ciphertext, tag := xchaPoly.Encrypt(field, recordID)
+
+	result := make([]byte, 0, len(field)+Overhead)
+	result = append(result, nonce[:]...)
+	result = append(result, ciphertext...)
+	result = append(result, tag[:]...)
+
+	return result, nil
+}
+
+// DecryptField decrypts the encrypted field of the record with the record's key.
+// Returns an error if the encrypted field is malformed or can't be authenticated.
+func (f *FieldCrypt) DecryptField(recordID []byte, encrypted []byte) ([]byte, error) {
+	if len(encrypted) < Overhead {
+		return []byte{}, debug.Detail(ErrDecryption, ErrInvalidField)
+	}
+
+	nonce := [24]byte(encrypted[0:NonceSize])
+	ciphertext := encrypted[NonceSize : len(encrypted)-TagSize]
+	tag := [16]byte(encrypted[len(encrypted)-TagSize:])
+
+	key := f.recordKey(recordID)
+	defer clear(key[:])
+
+	xchaPoly := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce)
+	field, err := xchaPoly.Decrypt(ciphertext, recordID, tag)
+	if err != nil {
+		return []byte{}, ErrDecryption
+	}
+
+	return field, nil
+}
+
+// recordKey derives the key of the record via HChaCha20. Record IDs of any
+// length are supported by hashing them to the 16 byte HChaCha20 nonce (via
+// BLAKE2b keyed with the domain separation label).
+func (f *FieldCrypt) recordKey(recordID []byte) [32]byte {
+	h, _ := blake2b.NewBlake2b(16, recordLabel)
+	h.Write(recordID)
+	nonce := [16]byte(h.Sum(nil))
+
+	return xchacha20.NewHChaCha20(f.masterKey, nonce).GenerateSubKey()
+}
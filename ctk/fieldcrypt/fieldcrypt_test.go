@@ -0,0 +1,296 @@
+package fieldcrypt_test
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/fieldcrypt"
+)
+
+func TestFieldCrypt(t *testing.T) {
+	masterKey := [32]byte{0x01, 0x02, 0x03}
+	recordID := []byte("user:42")
+	field := []byte("alice@example.com")
+
+	t.Run("Encrypt + Decrypt", func(t *testing.T) {
+		t.Parallel()
+
+		fc := fieldcrypt.NewFieldCrypt(masterKey)
+
+		encrypted, err := fc.EncryptField(recordID, field)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if len(encrypted) != len(field)+fieldcrypt.Overhead {
+			t.Errorf("want length %v, got %v", len(field)+fieldcrypt.Overhead, len(encrypted))
+		}
+
+		got, err := fc.DecryptField(recordID, encrypted)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if !slices.Equal(got, field) {
+			t.Errorf("want %v, got %v", field, got)
+		}
+	})
+
+	t.Run("Random Nonce", func(t *testing.T) {
+		t.Parallel()
+
+		fc := fieldcrypt.NewFieldCrypt(masterKey)
+
+		first, _ := fc.EncryptField(recordID, field)
+		second, _ := fc.EncryptField(recordID, field)
+
+		if slices.Equal(first, second) {
+			t.Errorf("want different ciphertexts, got %v twice", first)
+		}
+	})
+
+	t.Run("Empty Field", func(t *testing.T) {
+		t.Parallel()
+
+		fc := fieldcrypt.NewFieldCrypt(masterKey)
+
+		encrypted, _ := fc.EncryptField(recordID, []byte{})
+
+		got, err := fc.DecryptField(recordID, encrypted)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if len(got) != 0 {
+			t.Errorf("want length %v, got %v", 0, len(got))
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		fc := fieldcrypt.NewFieldCrypt(masterKey)
+
+		encrypted, _ := fc.EncryptField(recordID, field)
+
+		tampered := slices.Clone(encrypted)
+		tampered[fieldcrypt.NonceSize] ^= 0x01
+
+		tt := map[string]struct {
+			fc        *fieldcrypt.FieldCrypt
+			recordID  []byte
+			encrypted []byte
+			want      error
+		}{
+			"Other Record":     {fc: fc, recordID: []byte("user:43"), encrypted: encrypted, want: fieldcrypt.ErrDecryption},
+			"Other Master Key": {fc: fieldcrypt.NewFieldCrypt([32]byte{0x04}), recordID: recordID, encrypted: encrypted, want: fieldcrypt.ErrDecryption},
+			"Tampered":         {fc: fc, recordID: recordID, encrypted: tampered, want: fieldcrypt.ErrDecryption},
+			"Truncated":        {fc: fc, recordID: recordID, encrypted: encrypted[:fieldcrypt.Overhead-1], want: fieldcrypt.ErrDecryption},
+		}
+
+		for name, tc := range tt {
+			_, err := tc.fc.DecryptField(tc.recordID, tc.encrypted)
+
+			if !errors.Is(err, tc.want) {
+				t.Errorf("%v: want error %v, got %v", name, tc.want, err)
+			}
+		}
+	})
+}
+
+func TestBlindIndex(t *testing.T) {
+	masterKey := [32]byte{0x01, 0x02, 0x03}
+	column := []byte("users.email")
+
+	t.Run("Deterministic", func(t *testing.T) {
+		t.Parallel()
+
+		fc := fieldcrypt.NewFieldCrypt(masterKey)
+
+		first := fc.BlindIndex(column, []byte("alice@example.com"))
+		second := fc.BlindIndex(column, []byte("alice@example.com"))
+
+		if len(first) != fieldcrypt.BlindIndexSize {
+			t.Errorf("want length %v, got %v", fieldcrypt.BlindIndexSize, len(first))
+		}
+
+		if !slices.Equal(first, second) {
+			t.Errorf("want %v, got %v", first, second)
+		}
+	})
+
+	t.Run("Separation", func(t *testing.T) {
+		t.Parallel()
+
+		fc := fieldcrypt.NewFieldCrypt(masterKey)
+		value := []byte("alice@example.com")
+		want := fc.BlindIndex(column, value)
+
+		tt := map[string][]byte{
+			"Other Value":      fc.BlindIndex(column, []byte("bob@example.com")),
+			"Other Column":     fc.BlindIndex([]byte("users.backup_email"), value),
+			"Other Master Key": fieldcrypt.NewFieldCrypt([32]byte{0x04}).BlindIndex(column, value),
+		}
+
+		for name, got := range tt {
+			if slices.Equal(got, want) {
+				t.Errorf("%v: want different indexes, got %v twice", name, got)
+			}
+		}
+	})
+
+	t.Run("Normalization", func(t *testing.T) {
+		t.Parallel()
+
+		fc := fieldcrypt.NewFieldCrypt(masterKey)
+
+		want := fc.BlindIndex(column, fieldcrypt.NormalizeValue([]byte("alice@example.com")))
+		got := fc.BlindIndex(column, fieldcrypt.NormalizeValue([]byte("  Alice@Example.COM\n")))
+
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Sized", func(t *testing.T) {
+		t.Parallel()
+
+		fc := fieldcrypt.NewFieldCrypt(masterKey)
+		value := []byte("alice@example.com")
+		full, _ := fc.BlindIndexSized(column, value, fieldcrypt.MaxBlindIndexSize)
+
+		for _, size := range []int{1, 4, fieldcrypt.BlindIndexSize} {
+			got, err := fc.BlindIndexSized(column, value, size)
+			if err != nil {
+				t.Fatalf("%v: want error %v, got %v", size, nil, err)
+			}
+
+			if !slices.Equal(got, full[:size]) {
+				t.Errorf("%v: want %v, got %v", size, full[:size], got)
+			}
+		}
+
+		for _, size := range []int{0, -1, fieldcrypt.MaxBlindIndexSize + 1} {
+			_, err := fc.BlindIndexSized(column, value, size)
+			if !errors.Is(err, fieldcrypt.ErrInvalidIndexSize) {
+				t.Errorf("%v: want error %v, got %v", size, fieldcrypt.ErrInvalidIndexSize, err)
+			}
+		}
+	})
+}
+
+func TestBucket(t *testing.T) {
+	masterKey := [32]byte{0x01, 0x02, 0x03}
+	column := []byte("users.salary")
+
+	t.Run("Same Bucket", func(t *testing.T) {
+		t.Parallel()
+
+		fc := fieldcrypt.NewFieldCrypt(masterKey)
+
+		tt := map[string]struct {
+			a    int64
+			b    int64
+			same bool
+		}{
+			"Same Bucket":       {a: 1000, b: 1999, same: true},
+			"Next Bucket":       {a: 1999, b: 2000, same: false},
+			"Negative":          {a: -1, b: -1000, same: true},
+			"Zero and Negative": {a: 0, b: -1, same: false},
+		}
+
+		for name, tc := range tt {
+			a, err := fc.Bucket(column, tc.a, 1000)
+			if err != nil {
+				t.Fatalf("%v: want error %v, got %v", name, nil, err)
+			}
+
+			b, _ := fc.Bucket(column, tc.b, 1000)
+
+			if len(a) != fieldcrypt.BucketSize {
+				t.Errorf("%v: want length %v, got %v", name, fieldcrypt.BucketSize, len(a))
+			}
+
+			if slices.Equal(a, b) != tc.same {
+				t.Errorf("%v: want same bucket %v, got %v", name, tc.same, !tc.same)
+			}
+		}
+	})
+
+	t.Run("Separation", func(t *testing.T) {
+		t.Parallel()
+
+		fc := fieldcrypt.NewFieldCrypt(masterKey)
+		want, _ := fc.Bucket(column, 0, 1000)
+
+		otherColumn, _ := fc.Bucket([]byte("users.bonus"), 0, 1000)
+		otherWidth, _ := fc.Bucket(column, 0, 100)
+		blindIndex := fc.BlindIndex(column, make([]byte, 16))
+
+		for name, got := range map[string][]byte{"Other Column": otherColumn, "Other Width": otherWidth, "Blind Index": blindIndex} {
+			if slices.Equal(got, want) {
+				t.Errorf("%v: want different IDs, got %v twice", name, got)
+			}
+		}
+	})
+
+	t.Run("Range", func(t *testing.T) {
+		t.Parallel()
+
+		fc := fieldcrypt.NewFieldCrypt(masterKey)
+
+		ids, err := fc.BucketRange(column, -1500, 2500, 1000)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if len(ids) != 5 {
+			t.Fatalf("want %v buckets, got %v", 5, len(ids))
+		}
+
+		for i, value := range []int64{-2000, -1000, 0, 1000, 2000} {
+			want, _ := fc.Bucket(column, value, 1000)
+
+			if !slices.Equal(ids[i], want) {
+				t.Errorf("%v: want %v, got %v", value, want, ids[i])
+			}
+		}
+
+		ids, _ = fc.BucketRange(column, math.MaxInt64-10, math.MaxInt64, 1)
+		if len(ids) != 11 {
+			t.Errorf("want %v buckets, got %v", 11, len(ids))
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		fc := fieldcrypt.NewFieldCrypt(masterKey)
+
+		_, err := fc.Bucket(column, 42, 0)
+		if !errors.Is(err, fieldcrypt.ErrInvalidBucketWidth) {
+			t.Errorf("want error %v, got %v", fieldcrypt.ErrInvalidBucketWidth, err)
+		}
+
+		tt := map[string]struct {
+			low   int64
+			high  int64
+			width int64
+			want  error
+		}{
+			"Width":        {low: 0, high: 10, width: -1, want: fieldcrypt.ErrInvalidBucketWidth},
+			"Range":        {low: 10, high: 0, width: 1, want: fieldcrypt.ErrInvalidRange},
+			"Many Buckets": {low: 0, high: fieldcrypt.MaxRangeBuckets, width: 1, want: fieldcrypt.ErrTooManyBuckets},
+			"Full Range":   {low: math.MinInt64, high: math.MaxInt64, width: 1, want: fieldcrypt.ErrTooManyBuckets},
+		}
+
+		for name, tc := range tt {
+			_, err := fc.BucketRange(column, tc.low, tc.high, tc.width)
+			if !errors.Is(err, tc.want) {
+				t.Errorf("%v: want error %v, got %v", name, tc.want, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,291 @@
+// Package fpe implements format-preserving encryption (FPE) via FF3-1 as
+// specified in
+// https://nvlpubs.nist.gov/nistpubs/SpecialPublications/NIST.SP.800-38Gr1-draft.pdf.
+//
+// FPE encrypts a string over an alphabet (e.g. the 16 digits of a credit card
+// number) to a string of the same length over the same alphabet so that the
+// ciphertext fits into the schema of the plaintext (e.g. a database column or
+// a legacy protocol field).
+//
+// Security: FPE is deterministic (the same plaintext and tweak always result in
+// the same ciphertext) and not authenticated. Varying the tweak (e.g. with the
+// record ID or the non-secret part of the value) reduces the leakage of equal
+// values. Small domains are weak by construction which is why the alphabet
+// size to the power of the length has to be at least one million. Use the
+// fieldcrypt package instead whenever the format doesn't need to be preserved.
+//
+// FF3-1 is a Feistel network with 8 rounds using AES as the round function.
+// The 56 bit tweak of FF3-1 (instead of the 64 bit tweak of FF3) fixes the
+// attack described in https://eprint.iacr.org/2017/521.
+package fpe
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"math/big"
+	"slices"
+	"unicode/utf8"
+)
+
+const (
+	// ErrInvalidKeySize is returned if the key isn't an AES key.
+	ErrInvalidKeySize = Error("invalid key size")
+
+	// ErrInvalidAlphabet is returned if the alphabet has less than 2 or more
+	// than MaxRadix characters or if a character appears more than once.
+	ErrInvalidAlphabet = Error("invalid alphabet")
+
+	// ErrInvalidLength is returned if the input is too short or too long for
+	// the alphabet.
+	ErrInvalidLength = Error("invalid input length")
+
+	// ErrInvalidCharacter is returned if the input contains a character that
+	// isn't part of the alphabet.
+	ErrInvalidCharacter = Error("invalid input character")
+)
+
+const (
+	// Digits is the alphabet of decimal numbers.
+	Digits = "0123456789"
+
+	// Alphanumeric is the alphabet of digits and lower case letters.
+	Alphanumeric = "0123456789abcdefghijklmnopqrstuvwxyz"
+)
+
+const (
+	// TweakSize is the size (in bytes) of a tweak.
+	TweakSize = 7
+
+	// MaxRadix is the maximum number of characters in an alphabet.
+	MaxRadix = 1 << 16
+
+	// minDomainSize is the minimum number of possible inputs.
+	minDomainSize = 1_000_000
+
+	// rounds is the number of Feistel rounds.
+	rounds = 8
+)
+
+// Cipher encrypts and decrypts strings over an alphabet.
+// An instance isn't modified after its creation and is safe for concurrent use.
+type Cipher struct {
+	// block is AES with the byte-reversed key.
+	block cipher.Block
+
+	// alphabet are the characters of the alphabet.
+	alphabet []rune
+
+	// numerals maps a character to its index in the alphabet.
+	numerals map[rune]uint16
+
+	// radix is the number of characters in the alphabet.
+	radix *big.Int
+
+	// minLength is the minimum length of an input.
+	minLength int
+
+	// maxLength is the maximum length of an input.
+	maxLength int
+}
+
+// NewCipher creates a new instance with the AES key (16, 24 or 32 bytes) for
+// strings over the alphabet.
+// Returns an error if the key size or the alphabet is invalid.
+func NewCipher(key []byte, alphabet string) (*Cipher, error) {
+	if len(key) != 16 && len(key) != 24 && len(key) != 32 {
+		return nil, ErrInvalidKeySize
+	}
+
+	runes := []rune(alphabet)
+	if len(runes) < 2 || len(runes) > MaxRadix || !utf8.ValidString(alphabet) {
+		return nil, ErrInvalidAlphabet
+	}
+
+	numerals := make(map[rune]uint16, len(runes))
+	for i, r := range runes {
+		if _, ok := numerals[r]; ok {
+			return nil, ErrInvalidAlphabet
+		}
+		numerals[r] = uint16(i)
+	}
+
+	// FF3-1 encrypts with the byte-reversed key.
+	reversed := slices.Clone(key)
+	slices.Reverse(reversed)
+	defer clear(reversed)
+
+	block, err := aes.NewCipher(reversed)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cipher{
+		block:    block,
+		alphabet: runes,
+		numerals: numerals,
+		radix:    big.NewInt(int64(len(runes))),
+	}
+
+	// The minimum length is the smallest length with radix^length >= 10^6 and
+	// the maximum length is 2 * floor(log_radix(2^96)).
+	c.minLength = 1
+	for n := new(big.Int).Set(c.radix); n.Cmp(big.NewInt(minDomainSize)) < 0; n.Mul(n, c.radix) {
+		c.minLength++
+	}
+
+	limit := new(big.Int).Lsh(big.NewInt(1), 96)
+	for n := new(big.Int).Set(c.radix); n.Cmp(limit) <= 0; n.Mul(n, c.radix) {
+		c.maxLength += 2
+	}
+
+	return c, nil
+}
+
+// Encrypt encrypts the plaintext with the tweak. The ciphertext has the same
+// length and alphabet as the plaintext.
+// Returns an error if the plaintext's length is out of range or if it contains
+// characters that aren't part of the alphabet.
+func (c *Cipher) Encrypt(tweak [TweakSize]byte, plaintext string) (string, error) {
+	return c.crypt(tweak, plaintext, false)
+}
+
+// Decrypt decrypts the ciphertext with the tweak.
+// Returns an error if the ciphertext's length is out of range or if it
+// contains characters that aren't part of the alphabet.
+func (c *Cipher) Decrypt(tweak [TweakSize]byte, ciphertext string) (string, error) {
+	return c.crypt(tweak, ciphertext, true)
+}
+
+// MinLength returns the minimum length (in characters) of an input.
+func (c *Cipher) MinLength() int {
+	return c.minLength
+}
+
+// MaxLength returns the maximum length (in characters) of an input.
+func (c *Cipher) MaxLength() int {
+	return c.maxLength
+}
+
+// crypt converts the input to numerals, encrypts or decrypts them and converts
+// the result back to a string.
+func (c *Cipher) crypt(tweak [TweakSize]byte, input string, decrypt bool) (string, error) {
+	runes := []rune(input)
+	if len(runes) < c.minLength || len(runes) > c.maxLength {
+		return "", ErrInvalidLength
+	}
+
+	x := make([]uint16, len(runes))
+	for i, r := range runes {
+		n, ok := c.numerals[r]
+		if !ok {
+			return "", ErrInvalidCharacter
+		}
+		x[i] = n
+	}
+
+	y := c.ff3(expandTweak(tweak), x, decrypt)
+
+	for i, n := range y {
+		runes[i] = c.alphabet[n]
+	}
+
+	return string(runes), nil
+}
+
+// expandTweak expands the 56 bit FF3-1 tweak to the 64 bit tweak of FF3 with
+// TL = T[0..27] || 0^4 and TR = T[32..55] || T[28..31] || 0^4.
+func expandTweak(tweak [TweakSize]byte) [8]byte {
+	return [8]byte{
+		tweak[0], tweak[1], tweak[2], tweak[3] & 0xf0,
+		tweak[4], tweak[5], tweak[6], tweak[3] << 4,
+	}
+}
+
+// ff3 runs the FF3 Feistel network over the numerals with the 64 bit tweak.
+func (c *Cipher) ff3(tweak [8]byte, x []uint16, decrypt bool) []uint16 {
+	n := len(x)
+	u := (n + 1) / 2
+	v := n - u
+
+	a := slices.Clone(x[:u])
+	b := slices.Clone(x[u:])
+
+	for j := range rounds {
+		i := j
+		if decrypt {
+			i = rounds - 1 - j
+		}
+
+		// Even rounds update the left half with the right half of the tweak and
+		// odd rounds the right half with the left half of the tweak.
+		m, w := u, tweak[4:8]
+		if i%2 == 1 {
+			m, w = v, tweak[0:4]
+		}
+
+		// The round function is applied to B when encrypting and to A when
+		// decrypting (which holds the previous B).
+		src, dst := b, a
+		if decrypt {
+			src, dst = a, b
+		}
+
+		y := c.round(w, byte(i), src)
+		num := c.num(dst)
+		if decrypt {
+			num.Sub(num, y)
+		} else {
+			num.Add(num, y)
+		}
+		num.Mod(num, new(big.Int).Exp(c.radix, big.NewInt(int64(m)), nil))
+
+		if decrypt {
+			a, b = c.str(num, m), a
+		} else {
+			a, b = b, c.str(num, m)
+		}
+	}
+
+	return append(a, b...)
+}
+
+// round returns the output of the round function
+// NUM(REVB(CIPH(REVB(W ^ [i]^4 || [NUM_radix(REV(x))]^12)))).
+func (c *Cipher) round(w []byte, i byte, x []uint16) *big.Int {
+	var p [16]byte
+	copy(p[0:4], w)
+	p[3] ^= i
+	c.num(x).FillBytes(p[4:16])
+
+	slices.Reverse(p[:])
+	c.block.Encrypt(p[:], p[:])
+	slices.Reverse(p[:])
+
+	return new(big.Int).SetBytes(p[:])
+}
+
+// num returns the number represented by the reversed numerals
+// (NUM_radix(REV(x))), i.e. the first numeral is the least significant one.
+func (c *Cipher) num(x []uint16) *big.Int {
+	n := new(big.Int)
+	for i := len(x) - 1; i >= 0; i-- {
+		n.Mul(n, c.radix)
+		n.Add(n, big.NewInt(int64(x[i])))
+	}
+
+	return n
+}
+
+// str returns the m reversed numerals of the number (REV(STR^m_radix(n))).
+func (c *Cipher) str(n *big.Int, m int) []uint16 {
+	n = new(big.Int).Set(n)
+	r := new(big.Int)
+
+	x := make([]uint16, m)
+	for i := range x {
+		n.DivMod(n, c.radix, r)
+		x[i] = uint16(r.Uint64())
+	}
+
+	return x
+}
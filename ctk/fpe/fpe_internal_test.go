@@ -0,0 +1,67 @@
+package fpe
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestFF3(t *testing.T) {
+	// See: NIST SP 800-38G - FF3 Samples (with the 64 bit tweak of FF3).
+	tt := map[string]struct {
+		key        string
+		tweak      string
+		plaintext  string
+		ciphertext string
+	}{
+		"Sample 1": {
+			key:        "ef4359d8d580aa4f7f036d6f04fc6a94",
+			tweak:      "d8e7920afa330a73",
+			plaintext:  "890121234567890000",
+			ciphertext: "750918814058654607",
+		},
+		"Sample 2": {
+			key:        "ef4359d8d580aa4f7f036d6f04fc6a94",
+			tweak:      "9a768a92f60e12d8",
+			plaintext:  "890121234567890000",
+			ciphertext: "018989839189395384",
+		},
+		"Sample 3": {
+			key:        "ef4359d8d580aa4f7f036d6f04fc6a94",
+			tweak:      "d8e7920afa330a73",
+			plaintext:  "89012123456789000000789000000",
+			ciphertext: "48598367162252569629397416226",
+		},
+		"Sample 4": {
+			key:        "ef4359d8d580aa4f7f036d6f04fc6a94",
+			tweak:      "0000000000000000",
+			plaintext:  "89012123456789000000789000000",
+			ciphertext: "34695224821734535122613701434",
+		},
+	}
+
+	for name, tc := range tt {
+		key, _ := hex.DecodeString(tc.key)
+		tweak, _ := hex.DecodeString(tc.tweak)
+
+		c, err := NewCipher(key, Digits)
+		if err != nil {
+			t.Fatalf("%v: want error %v, got %v", name, nil, err)
+		}
+
+		x := make([]uint16, len(tc.plaintext))
+		for i, r := range tc.plaintext {
+			x[i] = c.numerals[r]
+		}
+
+		y := c.ff3([8]byte(tweak), x, false)
+
+		got := make([]rune, len(y))
+		for i, n := range y {
+			got[i] = c.alphabet[n]
+		}
+
+		if string(got) != tc.ciphertext {
+			t.Errorf("%v: want %v, got %v", name, tc.ciphertext, string(got))
+		}
+	}
+}
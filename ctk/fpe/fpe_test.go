@@ -0,0 +1,193 @@
+package fpe_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/fpe"
+)
+
+func TestFPE(t *testing.T) {
+	key, _ := hex.DecodeString("ef4359d8d580aa4f7f036d6f04fc6a94")
+	tweak := [fpe.TweakSize]byte{0xd8, 0xe7, 0x92, 0x0a, 0xfa, 0x33, 0x0a}
+
+	t.Run("Test Vectors", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]struct {
+			key        string
+			tweak      string
+			plaintext  string
+			ciphertext string
+		}{
+			"AES-128 1": {
+				key:        "ef4359d8d580aa4f7f036d6f04fc6a94",
+				tweak:      "d8e7920afa330a",
+				plaintext:  "890121234567890000",
+				ciphertext: "477064185124354662",
+			},
+			"AES-128 2": {
+				key:        "2de79d232df5585d68ce47882ae256d6",
+				tweak:      "cbd09280979564",
+				plaintext:  "3992520240",
+				ciphertext: "8901801106",
+			},
+		}
+
+		for name, tc := range tt {
+			key, _ := hex.DecodeString(tc.key)
+			tweak, _ := hex.DecodeString(tc.tweak)
+
+			c, err := fpe.NewCipher(key, fpe.Digits)
+			if err != nil {
+				t.Fatalf("%v: want error %v, got %v", name, nil, err)
+			}
+
+			got, err := c.Encrypt([fpe.TweakSize]byte(tweak), tc.plaintext)
+			if err != nil {
+				t.Fatalf("%v: want error %v, got %v", name, nil, err)
+			}
+
+			if got != tc.ciphertext {
+				t.Errorf("%v: want %v, got %v", name, tc.ciphertext, got)
+			}
+
+			got, err = c.Decrypt([fpe.TweakSize]byte(tweak), tc.ciphertext)
+			if err != nil {
+				t.Fatalf("%v: want error %v, got %v", name, nil, err)
+			}
+
+			if got != tc.plaintext {
+				t.Errorf("%v: want %v, got %v", name, tc.plaintext, got)
+			}
+		}
+	})
+
+	t.Run("Encrypt + Decrypt", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]struct {
+			alphabet  string
+			plaintext string
+		}{
+			"Card Number":  {alphabet: fpe.Digits, plaintext: "4111111111111111"},
+			"Odd Length":   {alphabet: fpe.Digits, plaintext: "1234567"},
+			"Alphanumeric": {alphabet: fpe.Alphanumeric, plaintext: "0123456789abcdefghi"},
+			"Binary":       {alphabet: "01", plaintext: "10110011100011110000"},
+			"Unicode":      {alphabet: "αβγδεζηθικ", plaintext: "αβγδεζηθικ"},
+		}
+
+		for name, tc := range tt {
+			c, _ := fpe.NewCipher(key, tc.alphabet)
+
+			ciphertext, err := c.Encrypt(tweak, tc.plaintext)
+			if err != nil {
+				t.Fatalf("%v: want error %v, got %v", name, nil, err)
+			}
+
+			if ciphertext == tc.plaintext {
+				t.Errorf("%v: want ciphertext different from plaintext, got %v", name, ciphertext)
+			}
+
+			for _, r := range ciphertext {
+				if !strings.ContainsRune(tc.alphabet, r) {
+					t.Errorf("%v: want characters of %v, got %v", name, tc.alphabet, ciphertext)
+				}
+			}
+
+			if len([]rune(ciphertext)) != len([]rune(tc.plaintext)) {
+				t.Errorf("%v: want length %v, got %v", name, len([]rune(tc.plaintext)), len([]rune(ciphertext)))
+			}
+
+			got, err := c.Decrypt(tweak, ciphertext)
+			if err != nil {
+				t.Fatalf("%v: want error %v, got %v", name, nil, err)
+			}
+
+			if got != tc.plaintext {
+				t.Errorf("%v: want %v, got %v", name, tc.plaintext, got)
+			}
+		}
+	})
+
+	t.Run("Tweak", func(t *testing.T) {
+		t.Parallel()
+
+		c, _ := fpe.NewCipher(key, fpe.Digits)
+
+		first, _ := c.Encrypt(tweak, "4111111111111111")
+		second, _ := c.Encrypt([fpe.TweakSize]byte{}, "4111111111111111")
+
+		if first == second {
+			t.Errorf("want different ciphertexts, got %v twice", first)
+		}
+	})
+
+	t.Run("Lengths", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]struct {
+			alphabet string
+			min      int
+			max      int
+		}{
+			"Digits":       {alphabet: fpe.Digits, min: 6, max: 56},
+			"Alphanumeric": {alphabet: fpe.Alphanumeric, min: 4, max: 36},
+			"Binary":       {alphabet: "01", min: 20, max: 192},
+		}
+
+		for name, tc := range tt {
+			c, _ := fpe.NewCipher(key, tc.alphabet)
+
+			if c.MinLength() != tc.min || c.MaxLength() != tc.max {
+				t.Errorf("%v: want lengths %v to %v, got %v to %v", name, tc.min, tc.max, c.MinLength(), c.MaxLength())
+			}
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		c, _ := fpe.NewCipher(key, fpe.Digits)
+
+		tt := map[string]struct {
+			input string
+			want  error
+		}{
+			"Too Short":         {input: "12345", want: fpe.ErrInvalidLength},
+			"Too Long":          {input: strings.Repeat("1", 57), want: fpe.ErrInvalidLength},
+			"Invalid Character": {input: "4111-1111", want: fpe.ErrInvalidCharacter},
+		}
+
+		for name, tc := range tt {
+			_, err := c.Encrypt(tweak, tc.input)
+			if !errors.Is(err, tc.want) {
+				t.Errorf("%v: want error %v, got %v", name, tc.want, err)
+			}
+
+			_, err = c.Decrypt(tweak, tc.input)
+			if !errors.Is(err, tc.want) {
+				t.Errorf("%v: want error %v, got %v", name, tc.want, err)
+			}
+		}
+
+		_, err := c.Encrypt(tweak, strings.Repeat("1", 56))
+		if err != nil {
+			t.Errorf("want error %v, got %v", nil, err)
+		}
+
+		_, err = fpe.NewCipher(key[:15], fpe.Digits)
+		if !errors.Is(err, fpe.ErrInvalidKeySize) {
+			t.Errorf("want error %v, got %v", fpe.ErrInvalidKeySize, err)
+		}
+
+		for _, alphabet := range []string{"", "0", "00123", "\xff\xfe"} {
+			_, err = fpe.NewCipher(key, alphabet)
+			if !errors.Is(err, fpe.ErrInvalidAlphabet) {
+				t.Errorf("%q: want error %v, got %v", alphabet, fpe.ErrInvalidAlphabet, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,66 @@
+// Package hkdf implements the HMAC-based Extract-and-Expand Key Derivation
+// Function (HKDF) as specified in https://datatracker.ietf.org/doc/html/rfc5869.
+package hkdf
+
+import (
+	"crypto/hmac"
+	"hash"
+)
+
+const (
+	// ErrInvalidLength is returned if more than 255 * hash length bytes of
+	// output keying material are requested.
+	ErrInvalidLength = Error("invalid output length")
+)
+
+// Extract creates a pseudorandom key (PRK) from the input keying material (IKM)
+// and the (optional) salt.
+func Extract(h func() hash.Hash, ikm []byte, salt []byte) []byte {
+	// If the salt isn't provided it's set to a string of hash length zeros.
+	if len(salt) == 0 {
+		salt = make([]byte, h().Size())
+	}
+
+	mac := hmac.New(h, salt)
+	mac.Write(ikm)
+
+	return mac.Sum(nil)
+}
+
+// Expand expands the pseudorandom key (PRK) into length bytes of output keying
+// material (OKM) bound to the (optional) info.
+// Returns an error if length exceeds 255 * hash length.
+func Expand(h func() hash.Hash, prk []byte, info []byte, length int) ([]byte, error) {
+	mac := hmac.New(h, prk)
+
+	if length < 0 || length > 255*mac.Size() {
+		return []byte{}, ErrInvalidLength
+	}
+
+	result := make([]byte, 0, length+mac.Size())
+
+	// T(0) is the empty string.
+	var t []byte
+
+	// T(i) = HMAC-Hash(PRK, T(i-1) | info | i).
+	for i := byte(1); len(result) < length; i++ {
+		mac.Reset()
+		mac.Write(t)
+		mac.Write(info)
+		mac.Write([]byte{i})
+		t = mac.Sum(nil)
+
+		result = append(result, t...)
+	}
+
+	return result[:length], nil
+}
+
+// Key runs Extract followed by Expand to derive length bytes of output keying
+// material (OKM) from the input keying material (IKM).
+// Returns an error if length exceeds 255 * hash length.
+func Key(h func() hash.Hash, ikm []byte, salt []byte, info []byte, length int) ([]byte, error) {
+	prk := Extract(h, ikm, salt)
+
+	return Expand(h, prk, info, length)
+}
@@ -0,0 +1,196 @@
+package httpcrypt
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/pmuens/ctk-go/ctk/stream"
+)
+
+// Handler returns a handler which decrypts the request bodies and encrypts the
+// response bodies of next with the keys returned by keys.
+// Requests are rejected with http.StatusBadRequest if the Header is missing
+// or malformed or if the body doesn't start with a container header, with
+// http.StatusUnsupportedMediaType (and the AlgorithmsHeader) if the algorithm
+// is unknown and with http.StatusUnauthorized if keys returns an error. The
+// rejections aren't encrypted.
+// Handler needs to wrap next before any handler that rewrites the request URI
+// (e.g. http.StripPrefix) as the URI is bound to the keys.
+func Handler(next http.Handler, keys KeyFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value := r.Header.Get(Header)
+		if value == "" {
+			http.Error(w, ErrMissingHeader.Error(), http.StatusBadRequest)
+			return
+		}
+
+		p, err := parseParams(value)
+		if errors.Is(err, ErrUnsupportedAlgorithm) {
+			w.Header().Set(AlgorithmsHeader, Algorithm)
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		key, err := keys(p.keyID)
+		if err != nil {
+			http.Error(w, ErrUnknownKey.Error(), http.StatusUnauthorized)
+			return
+		}
+		defer clear(key[:])
+
+		uri := r.URL.RequestURI()
+
+		body, err := stream.NewReader(r.Body, deriveKey(key, requestInfo, p, r.Method, uri))
+		if err != nil {
+			http.Error(w, stream.ErrDecryption.Error(), http.StatusBadRequest)
+			return
+		}
+
+		decrypted := r.WithContext(r.Context())
+		decrypted.Body = &decryptedBody{r: body, body: r.Body}
+		decrypted.ContentLength = -1
+		decrypted.Header = r.Header.Clone()
+		decrypted.Header.Del("Content-Length")
+
+		rw := &responseWriter{
+			ResponseWriter: w,
+			method:         r.Method,
+			key:            deriveKey(key, responseInfo, p, r.Method, uri),
+		}
+		defer clear(rw.key[:])
+
+		next.ServeHTTP(rw, decrypted)
+
+		// The error can't be reported as the response was already sent. The
+		// client detects the truncated container.
+		_ = rw.close()
+	})
+}
+
+// decryptedBody decrypts a request or response body.
+type decryptedBody struct {
+	// r decrypts the body.
+	r *stream.Reader
+
+	// body is the encrypted body.
+	body io.ReadCloser
+}
+
+// Read reads the decrypted body.
+func (b *decryptedBody) Read(p []byte) (int, error) {
+	return b.r.Read(p)
+}
+
+// Close closes the encrypted body.
+func (b *decryptedBody) Close() error {
+	return b.body.Close()
+}
+
+// responseWriter encrypts a response body.
+type responseWriter struct {
+	http.ResponseWriter
+
+	// method is the method of the request.
+	method string
+
+	// key is the response key.
+	key [32]byte
+
+	// w encrypts the body (nil until the body is written).
+	w *stream.Writer
+
+	// wroteHeader indicates whether the status code was written.
+	wroteHeader bool
+
+	// encrypt indicates whether the response has a body which is encrypted.
+	encrypt bool
+}
+
+// WriteHeader writes the status code and the Header (if the response has a
+// body).
+func (rw *responseWriter) WriteHeader(status int) {
+	if rw.wroteHeader {
+		return
+	}
+
+	// Informational responses (e.g. 103 Early Hints) precede the actual
+	// response.
+	if status >= 100 && status < 200 {
+		rw.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	rw.wroteHeader = true
+	rw.encrypt = hasBody(rw.method, status)
+
+	if rw.encrypt {
+		h := rw.Header()
+		h.Set(Header, "alg="+Algorithm)
+		h.Del("Content-Length")
+
+		// The content type can't be sniffed from the encrypted body.
+		if _, ok := h["Content-Type"]; !ok {
+			h.Set("Content-Type", "application/octet-stream")
+		}
+	}
+
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+// Write encrypts the data and writes it to the body.
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	rw.WriteHeader(http.StatusOK)
+
+	if !rw.encrypt {
+		return rw.ResponseWriter.Write(p)
+	}
+
+	err := rw.init()
+	if err != nil {
+		return 0, err
+	}
+
+	return rw.w.Write(p)
+}
+
+// Unwrap returns the underlying ResponseWriter (for http.ResponseController).
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// init creates the stream.Writer unless it exists.
+func (rw *responseWriter) init() error {
+	if rw.w != nil {
+		return nil
+	}
+
+	w, err := stream.NewWriter(rw.ResponseWriter, rw.key)
+	if err != nil {
+		return err
+	}
+	rw.w = w
+
+	return nil
+}
+
+// close writes the last chunk of the body (which is empty if the handler
+// didn't write anything).
+func (rw *responseWriter) close() error {
+	rw.WriteHeader(http.StatusOK)
+
+	if !rw.encrypt {
+		return nil
+	}
+
+	err := rw.init()
+	if err != nil {
+		return err
+	}
+
+	return rw.w.Close()
+}
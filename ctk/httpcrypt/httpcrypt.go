@@ -0,0 +1,203 @@
+// Package httpcrypt implements the encryption of HTTP request and response
+// bodies for service-to-service communication. Handler decrypts the request
+// bodies and encrypts the response bodies of an http.Handler, Transport
+// encrypts the request bodies and decrypts the response bodies of an
+// http.Client.
+//
+// Both parties share keys which are identified by key IDs, e.g. a static
+// shared key (see SharedKey) or session keys which were established out of
+// band (e.g. via a handshake). Every request carries the Header:
+//
+//	Ctk-Encryption: alg=<algorithm>; kid=<key ID>; nonce=<base64url nonce>
+//
+// A request key and a response key are derived from the shared key via
+// HKDF-SHA256 with the random nonce as the salt. The algorithm, the key ID,
+// the method and the request URI (path and query) are part of the info so that
+// an encrypted body can't be replayed to another endpoint and a response can't
+// be returned for another request. The server answers with the algorithm it
+// used:
+//
+//	Ctk-Encryption: alg=<algorithm>
+//
+// Bodies are stream containers (see the stream package) so that they're
+// encrypted and decrypted on the fly. Requests without a body carry an empty
+// container so that a body can't be stripped unnoticed. Responses without a
+// body (e.g. to HEAD requests or with the status 204 or 304) aren't encrypted.
+//
+// Headers, the status code and the request URI are sent in plaintext. The
+// bodies are authenticated while they're read which is why handlers and
+// clients need to treat read errors (stream.ErrDecryption) as tampering and
+// discard the data read so far.
+package httpcrypt
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/pmuens/ctk-go/ctk/hkdf"
+)
+
+const (
+	// ErrMissingHeader is returned if a request or response doesn't carry
+	// the Header.
+	ErrMissingHeader = Error("missing encryption header")
+
+	// ErrInvalidHeader is returned if the Header is malformed.
+	ErrInvalidHeader = Error("invalid encryption header")
+
+	// ErrUnsupportedAlgorithm is returned if the Header selects an unknown
+	// algorithm.
+	ErrUnsupportedAlgorithm = Error("unsupported encryption algorithm")
+
+	// ErrInvalidKeyID is returned if a key ID is empty or contains characters
+	// other than letters, digits, '-', '_' and '.'.
+	ErrInvalidKeyID = Error("invalid key ID")
+
+	// ErrUnknownKey is returned by the key funcs of SharedKey if the key ID
+	// isn't the expected one.
+	ErrUnknownKey = Error("unknown key ID")
+)
+
+// Header is the name of the HTTP header that carries the encryption
+// parameters.
+const Header = "Ctk-Encryption"
+
+// AlgorithmsHeader is the name of the HTTP header which lists the supported
+// algorithms (comma separated) if a request is rejected with
+// http.StatusUnsupportedMediaType.
+const AlgorithmsHeader = "Ctk-Encryption-Algorithms"
+
+// Algorithm is the algorithm that encrypts the bodies as stream containers
+// (XChaCha20-Poly1305).
+const Algorithm = "xchacha20poly1305-stream"
+
+// NonceSize is the size (in bytes) of a (decoded) nonce.
+const NonceSize = 32
+
+// Info strings used for domain separation in the key derivations.
+var (
+	requestInfo  = []byte("ctk-go httpcrypt request")
+	responseInfo = []byte("ctk-go httpcrypt response")
+)
+
+// KeyFunc returns the key with the ID.
+type KeyFunc func(id string) ([32]byte, error)
+
+// SharedKey returns a KeyFunc which returns the key for the ID and
+// ErrUnknownKey for any other ID.
+func SharedKey(id string, key [32]byte) KeyFunc {
+	return func(got string) ([32]byte, error) {
+		if got != id {
+			return [32]byte{}, ErrUnknownKey
+		}
+
+		return key, nil
+	}
+}
+
+// params are the parameters of a request's Header.
+type params struct {
+	// algorithm is the encryption algorithm.
+	algorithm string
+
+	// keyID is the ID of the shared key.
+	keyID string
+
+	// nonce is the salt of the key derivations.
+	nonce []byte
+}
+
+// String encodes the parameters as the value of the Header.
+func (p params) String() string {
+	return "alg=" + p.algorithm + "; kid=" + p.keyID + "; nonce=" + base64.RawURLEncoding.EncodeToString(p.nonce)
+}
+
+// parseParams parses the value of a request's Header.
+// Returns an error if it's malformed or selects an unknown algorithm.
+func parseParams(value string) (params, error) {
+	fields, err := parseHeader(value)
+	if err != nil {
+		return params{}, err
+	}
+
+	if fields["alg"] != Algorithm {
+		return params{}, ErrUnsupportedAlgorithm
+	}
+
+	nonce, err := base64.RawURLEncoding.DecodeString(fields["nonce"])
+	if err != nil || len(nonce) != NonceSize || checkKeyID(fields["kid"]) != nil {
+		return params{}, ErrInvalidHeader
+	}
+
+	return params{
+		algorithm: fields["alg"],
+		keyID:     fields["kid"],
+		nonce:     nonce,
+	}, nil
+}
+
+// parseHeader parses the semicolon separated key=value pairs of a Header.
+// Returns ErrInvalidHeader if it's malformed or contains duplicate keys.
+func parseHeader(value string) (map[string]string, error) {
+	fields := map[string]string{}
+
+	for _, field := range strings.Split(value, ";") {
+		k, v, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			return nil, ErrInvalidHeader
+		}
+
+		if _, ok := fields[k]; ok {
+			return nil, ErrInvalidHeader
+		}
+		fields[k] = v
+	}
+
+	return fields, nil
+}
+
+// checkKeyID returns ErrInvalidKeyID unless the key ID can be sent in the
+// Header.
+func checkKeyID(id string) error {
+	if id == "" {
+		return ErrInvalidKeyID
+	}
+
+	for _, c := range id {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return ErrInvalidKeyID
+		}
+	}
+
+	return nil
+}
+
+// deriveKey derives the request or response key (depending on the label) of a
+// request from the shared key.
+// The parameters, the method and the request URI are encoded with length
+// prefixes.
+func deriveKey(key [32]byte, label []byte, p params, method string, uri string) [32]byte {
+	info := slices.Clone(label)
+	for _, field := range []string{p.algorithm, p.keyID, method, uri} {
+		info = binary.BigEndian.AppendUint32(info, uint32(len(field)))
+		info = append(info, field...)
+	}
+
+	// The output length is valid so that no error can occur.
+	derived, _ := hkdf.Key(sha256.New, key[:], p.nonce, info, 32)
+	defer clear(derived)
+
+	return [32]byte(derived)
+}
+
+// hasBody reports whether a response to a request with the method and the
+// status code has a body.
+func hasBody(method string, status int) bool {
+	return method != http.MethodHead && status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
@@ -0,0 +1,310 @@
+package httpcrypt_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/httpcrypt"
+	"github.com/pmuens/ctk-go/ctk/stream"
+)
+
+// echo responds with the method, the request URI and the request body.
+var echo = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.URL.Path == "/empty" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	io.WriteString(w, r.Method+" "+r.URL.RequestURI()+" ")
+	w.Write(body)
+})
+
+// recorder is a RoundTripper which records the requests and responses sent
+// over the network and allows to modify them.
+type recorder struct {
+	// modifyRequest modifies the encrypted requests (if set).
+	modifyRequest func(*http.Request)
+
+	// modifyResponse modifies the encrypted responses (if set).
+	modifyResponse func([]byte) []byte
+
+	// requests holds the encrypted request bodies.
+	requests [][]byte
+
+	// responses holds the encrypted response bodies.
+	responses [][]byte
+}
+
+// RoundTrip records and sends the request.
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	r.requests = append(r.requests, body)
+
+	if r.modifyRequest != nil {
+		r.modifyRequest(req)
+	}
+
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	r.responses = append(r.responses, body)
+
+	if r.modifyResponse != nil {
+		body = r.modifyResponse(body)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	return resp, nil
+}
+
+// newClient starts a server with the handler and returns a client with the
+// key whose transport sends the requests via rec.
+func newClient(t *testing.T, handler http.Handler, keyID string, key [32]byte, rec *recorder) (*http.Client, string) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	transport := &httpcrypt.Transport{Base: rec, KeyID: keyID, Key: key}
+
+	return &http.Client{Transport: transport}, server.URL
+}
+
+// read reads the body of the response.
+func read(resp *http.Response) (string, error) {
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+
+	return string(body), err
+}
+
+func TestHTTPCrypt(t *testing.T) {
+	key := [32]byte{0x01}
+	handler := httpcrypt.Handler(echo, httpcrypt.SharedKey("k1", key))
+
+	t.Run("Round Trip", func(t *testing.T) {
+		t.Parallel()
+
+		rec := &recorder{}
+		client, url := newClient(t, handler, "k1", key, rec)
+
+		payload := strings.Repeat("secret payload ", 10000)
+
+		resp, err := client.Post(url+"/echo?x=1", "text/plain", strings.NewReader(payload))
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		got, err := read(resp)
+		want := "POST /echo?x=1 " + payload
+		if got != want || err != nil {
+			t.Errorf("want %v bytes, got %v (error %v)", len(want), len(got), err)
+		}
+
+		if ct := resp.Header.Get("Content-Type"); ct != "text/plain" {
+			t.Errorf("want %v, got %v", "text/plain", ct)
+		}
+
+		for _, body := range append(rec.requests, rec.responses...) {
+			if bytes.Contains(body, []byte("secret")) {
+				t.Error("body isn't encrypted")
+			}
+		}
+	})
+
+	t.Run("Without Body", func(t *testing.T) {
+		t.Parallel()
+
+		client, url := newClient(t, handler, "k1", key, &recorder{})
+
+		resp, err := client.Get(url + "/get")
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		got, err := read(resp)
+		if got != "GET /get " || err != nil {
+			t.Errorf("want %q, got %q (error %v)", "GET /get ", got, err)
+		}
+
+		resp, err = client.Get(url + "/empty")
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if resp.StatusCode != http.StatusNoContent || resp.Header.Get(httpcrypt.Header) != "" {
+			t.Errorf("want status %v, got %v", http.StatusNoContent, resp.StatusCode)
+		}
+		resp.Body.Close()
+
+		resp, err = client.Head(url + "/head")
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("want status %v, got %v", http.StatusOK, resp.StatusCode)
+		}
+		resp.Body.Close()
+	})
+
+	t.Run("Modified Request", func(t *testing.T) {
+		t.Parallel()
+
+		tests := map[string]func(*http.Request){
+			"Path": func(req *http.Request) {
+				req.URL.Path = "/other"
+			},
+			"Method": func(req *http.Request) {
+				req.Method = http.MethodPut
+			},
+			"Body": func(req *http.Request) {
+				body, _ := io.ReadAll(req.Body)
+				body[len(body)-1] ^= 1
+				req.Body = io.NopCloser(bytes.NewReader(body))
+			},
+			"Stripped Body": func(req *http.Request) {
+				req.Body = http.NoBody
+				req.ContentLength = 0
+			},
+		}
+
+		for name, modify := range tests {
+			client, url := newClient(t, handler, "k1", key, &recorder{modifyRequest: modify})
+
+			// The server rejects the request (which is why the response
+			// isn't encrypted) or the handler fails to read the body.
+			resp, err := client.Post(url+"/echo", "text/plain", strings.NewReader("payload"))
+			if err != nil {
+				if !errors.Is(err, httpcrypt.ErrMissingHeader) {
+					t.Errorf("%v: want error %v, got %v", name, httpcrypt.ErrMissingHeader, err)
+				}
+
+				continue
+			}
+
+			_, err = read(resp)
+			if resp.StatusCode != http.StatusBadRequest && err == nil {
+				t.Errorf("%v: want status %v, got %v", name, http.StatusBadRequest, resp.StatusCode)
+			}
+		}
+	})
+
+	t.Run("Modified Response", func(t *testing.T) {
+		t.Parallel()
+
+		modify := func(body []byte) []byte {
+			body[len(body)-1] ^= 1
+			return body
+		}
+
+		client, url := newClient(t, handler, "k1", key, &recorder{modifyResponse: modify})
+
+		resp, err := client.Post(url+"/echo", "text/plain", strings.NewReader("payload"))
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		_, err = read(resp)
+		if !errors.Is(err, stream.ErrDecryption) {
+			t.Errorf("want error %v, got %v", stream.ErrDecryption, err)
+		}
+	})
+
+	t.Run("Rejected Requests", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		tests := map[string]struct {
+			header string
+			want   int
+		}{
+			"Missing Header":        {"", http.StatusBadRequest},
+			"Malformed Header":      {"alg", http.StatusBadRequest},
+			"Malformed Nonce":       {"alg=" + httpcrypt.Algorithm + "; kid=k1; nonce=AAAA", http.StatusBadRequest},
+			"Unsupported Algorithm": {"alg=aes-gcm; kid=k1; nonce=AAAA", http.StatusUnsupportedMediaType},
+			"Unknown Key": {
+				"alg=" + httpcrypt.Algorithm + "; kid=k2; nonce=" + strings.Repeat("A", 43),
+				http.StatusUnauthorized,
+			},
+			"Malformed Body": {
+				"alg=" + httpcrypt.Algorithm + "; kid=k1; nonce=" + strings.Repeat("A", 43),
+				http.StatusBadRequest,
+			},
+		}
+
+		for name, tc := range tests {
+			req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("plaintext"))
+			if err != nil {
+				t.Fatalf("want error %v, got %v", nil, err)
+			}
+
+			if tc.header != "" {
+				req.Header.Set(httpcrypt.Header, tc.header)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("%v: want error %v, got %v", name, nil, err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tc.want {
+				t.Errorf("%v: want status %v, got %v", name, tc.want, resp.StatusCode)
+			}
+		}
+	})
+
+	t.Run("Wrong Key", func(t *testing.T) {
+		t.Parallel()
+
+		client, url := newClient(t, handler, "k1", [32]byte{0x02}, &recorder{})
+
+		resp, err := client.Post(url+"/echo", "text/plain", strings.NewReader("payload"))
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		_, err = read(resp)
+		if !errors.Is(err, stream.ErrDecryption) {
+			t.Errorf("want error %v, got %v", stream.ErrDecryption, err)
+		}
+	})
+
+	t.Run("Invalid Key ID", func(t *testing.T) {
+		t.Parallel()
+
+		client, url := newClient(t, handler, "k 1", key, &recorder{})
+
+		_, err := client.Get(url)
+		if !errors.Is(err, httpcrypt.ErrInvalidKeyID) {
+			t.Errorf("want error %v, got %v", httpcrypt.ErrInvalidKeyID, err)
+		}
+	})
+}
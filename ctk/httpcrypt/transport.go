@@ -0,0 +1,166 @@
+package httpcrypt
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/pmuens/ctk-go/ctk/random"
+	"github.com/pmuens/ctk-go/ctk/stream"
+)
+
+// Transport is an http.RoundTripper which encrypts the request bodies and
+// decrypts the response bodies with a shared key.
+// RoundTrip returns ErrMissingHeader if a response with a body isn't
+// encrypted (e.g. if the server rejected the request) and ErrUnsupportedAlgorithm
+// if it's encrypted with an unknown algorithm. Reading the response body
+// returns stream.ErrDecryption if it can't be authenticated.
+type Transport struct {
+	// Base sends the encrypted requests (nil uses http.DefaultTransport).
+	Base http.RoundTripper
+
+	// KeyID is the ID of the key that's sent to the server. See ErrInvalidKeyID
+	// for the allowed characters.
+	KeyID string
+
+	// Key is the shared key.
+	Key [32]byte
+}
+
+// RoundTrip encrypts the request's body, sends it and decrypts the response's
+// body. The request isn't modified.
+// Returns ErrInvalidKeyID if the key ID can't be sent in the Header.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	err := checkKeyID(t.KeyID)
+	if err != nil {
+		return nil, closeBody(req, err)
+	}
+
+	nonce, err := random.Bytes(NonceSize)
+	if err != nil {
+		return nil, closeBody(req, err)
+	}
+
+	p := params{
+		algorithm: Algorithm,
+		keyID:     t.KeyID,
+		nonce:     nonce,
+	}
+
+	uri := req.URL.RequestURI()
+
+	encrypted := req.Clone(req.Context())
+	encrypted.Header.Set(Header, p.String())
+	encrypted.Header.Del("Content-Length")
+	encrypted.GetBody = nil
+
+	err = encryptBody(encrypted, req.Body, deriveKey(t.Key, requestInfo, p, req.Method, uri))
+	if err != nil {
+		return nil, closeBody(req, err)
+	}
+
+	resp, err := t.base().RoundTrip(encrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hasBody(req.Method, resp.StatusCode) {
+		return resp, nil
+	}
+
+	err = checkResponse(resp.Header.Get(Header))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	body, err := stream.NewReader(resp.Body, deriveKey(t.Key, responseInfo, p, req.Method, uri))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	resp.Body = &decryptedBody{r: body, body: resp.Body}
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Length")
+
+	return resp, nil
+}
+
+// base returns the configured (or default) round tripper.
+func (t *Transport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+
+	return t.Base
+}
+
+// encryptBody sets the body of the request to the encrypted body. Bodies are
+// encrypted while they're sent. A missing body is encrypted as an empty
+// container.
+func encryptBody(req *http.Request, body io.ReadCloser, key [32]byte) error {
+	if body == nil || body == http.NoBody {
+		container, err := stream.Encrypt(key, nil)
+		if err != nil {
+			return err
+		}
+
+		req.Body = io.NopCloser(bytes.NewReader(container))
+		req.ContentLength = int64(len(container))
+
+		return nil
+	}
+
+	pr, pw := io.Pipe()
+
+	go func() {
+		defer body.Close()
+
+		w, err := stream.NewWriter(pw, key)
+		if err == nil {
+			_, err = io.Copy(w, body)
+		}
+		if err == nil {
+			err = w.Close()
+		}
+
+		// The transport closes the pipe's reader once it's done which stops
+		// the copying if the body isn't sent completely.
+		pw.CloseWithError(err)
+	}()
+
+	req.Body = pr
+	req.ContentLength = -1
+
+	return nil
+}
+
+// checkResponse checks the value of a response's Header.
+// Returns an error if it's missing, malformed or selects an unknown algorithm.
+func checkResponse(value string) error {
+	if value == "" {
+		return ErrMissingHeader
+	}
+
+	fields, err := parseHeader(value)
+	if err != nil {
+		return err
+	}
+
+	if fields["alg"] != Algorithm {
+		return ErrUnsupportedAlgorithm
+	}
+
+	return nil
+}
+
+// closeBody closes the request's body (as RoundTrip needs to do even if it
+// fails) and returns err.
+func closeBody(req *http.Request, err error) error {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	return err
+}
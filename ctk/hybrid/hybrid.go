@@ -0,0 +1,177 @@
+// Package hybrid implements a key exchange that combines X25519 and ML-KEM-768
+// so that the derived key stays secret as long as either of them is secure.
+// This hedges against attacks on X25519 by quantum computers (e.g. on traffic
+// that's recorded today) and against weaknesses in the comparatively young
+// ML-KEM.
+//
+// The scheme follows the hybrid key exchanges that are deployed in TLS
+// (X25519MLKEM768) and SSH (mlkem768x25519-sha256). The public key and the
+// ciphertext are the concatenation of the ML-KEM and the X25519 parts:
+//
+//	public key: ML-KEM-768 encapsulation key (1184) | X25519 public key (32)
+//	ciphertext: ML-KEM-768 ciphertext (1088) | X25519 ephemeral public key (32)
+//
+// The shared key is derived via HKDF-SHA256 from the concatenation of both
+// shared secrets followed by the X25519 ephemeral and static public keys (the
+// ML-KEM ciphertext and encapsulation key are already bound by ML-KEM itself).
+package hybrid
+
+import (
+	"crypto/sha256"
+	"slices"
+
+	"github.com/pmuens/ctk-go/ctk/hkdf"
+	"github.com/pmuens/ctk-go/ctk/mlkem"
+	"github.com/pmuens/ctk-go/ctk/x25519"
+)
+
+const (
+	// ErrInvalidPrivateKey is returned if an encoded private key doesn't have
+	// PrivateKeySize bytes.
+	ErrInvalidPrivateKey = Error("invalid private key")
+
+	// ErrInvalidPublicKey is returned if a public key has the wrong size or its
+	// ML-KEM part is invalid.
+	ErrInvalidPublicKey = Error("invalid public key")
+
+	// ErrInvalidCiphertext is returned if a ciphertext has the wrong size.
+	ErrInvalidCiphertext = Error("invalid ciphertext")
+)
+
+// PrivateKeySize is the size (in bytes) of an encoded private key.
+const PrivateKeySize = mlkem.SeedSize + x25519.KeySize
+
+// PublicKeySize is the size (in bytes) of a public key.
+const PublicKeySize = mlkem.EncapsulationKeySize + x25519.KeySize
+
+// CiphertextSize is the size (in bytes) of a ciphertext.
+const CiphertextSize = mlkem.CiphertextSize + x25519.KeySize
+
+// SharedKeySize is the size (in bytes) of the shared key.
+const SharedKeySize = 32
+
+// info is used for domain separation in the key derivation.
+var info = []byte("ctk-go hybrid")
+
+// PrivateKey is the private key of the hybrid key exchange.
+type PrivateKey struct {
+	// mlkem is the ML-KEM-768 decapsulation key.
+	mlkem *mlkem.DecapsulationKey
+
+	// x25519 is the X25519 private key.
+	x25519 [32]byte
+}
+
+// GenerateKey generates a new private key.
+func GenerateKey() (*PrivateKey, error) {
+	dk, err := mlkem.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	private, _, err := x25519.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return &PrivateKey{mlkem: dk, x25519: private}, nil
+}
+
+// NewPrivateKey decodes a private key that was encoded via Bytes.
+// Returns an error if the key doesn't have PrivateKeySize bytes.
+func NewPrivateKey(b []byte) (*PrivateKey, error) {
+	if len(b) != PrivateKeySize {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	dk, err := mlkem.NewDecapsulationKey(b[:mlkem.SeedSize])
+	if err != nil {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	return &PrivateKey{mlkem: dk, x25519: [32]byte(b[mlkem.SeedSize:])}, nil
+}
+
+// Bytes encodes the private key (ML-KEM-768 seed followed by the X25519
+// private key).
+func (pk *PrivateKey) Bytes() []byte {
+	return slices.Concat(pk.mlkem.Bytes(), pk.x25519[:])
+}
+
+// PublicKey returns the public key that corresponds to the private key.
+func (pk *PrivateKey) PublicKey() []byte {
+	x25519Public := x25519.PublicKey(pk.x25519)
+
+	return slices.Concat(pk.mlkem.EncapsulationKey().Bytes(), x25519Public[:])
+}
+
+// Encapsulate generates a shared key and the ciphertext which allows the owner
+// of the peer's private key to derive the same shared key.
+// Returns an error if the peer's public key is invalid.
+func Encapsulate(peerPublicKey []byte) ([SharedKeySize]byte, []byte, error) {
+	if len(peerPublicKey) != PublicKeySize {
+		return [SharedKeySize]byte{}, []byte{}, ErrInvalidPublicKey
+	}
+
+	ek, err := mlkem.NewEncapsulationKey(peerPublicKey[:mlkem.EncapsulationKeySize])
+	if err != nil {
+		return [SharedKeySize]byte{}, []byte{}, ErrInvalidPublicKey
+	}
+
+	peerX25519 := [32]byte(peerPublicKey[mlkem.EncapsulationKeySize:])
+
+	mlkemSecret, mlkemCiphertext, err := ek.Encapsulate()
+	if err != nil {
+		return [SharedKeySize]byte{}, []byte{}, err
+	}
+
+	ephemeralPrivate, ephemeralPublic, err := x25519.GenerateKey()
+	if err != nil {
+		return [SharedKeySize]byte{}, []byte{}, err
+	}
+
+	x25519Secret, err := x25519.SharedSecret(ephemeralPrivate, peerX25519)
+	if err != nil {
+		return [SharedKeySize]byte{}, []byte{}, ErrInvalidPublicKey
+	}
+
+	key := deriveKey(mlkemSecret, x25519Secret, ephemeralPublic, peerX25519)
+
+	return key, slices.Concat(mlkemCiphertext, ephemeralPublic[:]), nil
+}
+
+// Decapsulate derives the shared key from the ciphertext. A ciphertext that was
+// tampered with results in a different (pseudo random) key which is why the
+// key should be confirmed (e.g. by using it with an AEAD).
+// Returns an error if the ciphertext has the wrong size or its X25519 part is a
+// low order point.
+func (pk *PrivateKey) Decapsulate(ciphertext []byte) ([SharedKeySize]byte, error) {
+	if len(ciphertext) != CiphertextSize {
+		return [SharedKeySize]byte{}, ErrInvalidCiphertext
+	}
+
+	mlkemSecret, err := pk.mlkem.Decapsulate(ciphertext[:mlkem.CiphertextSize])
+	if err != nil {
+		return [SharedKeySize]byte{}, ErrInvalidCiphertext
+	}
+
+	ephemeralPublic := [32]byte(ciphertext[mlkem.CiphertextSize:])
+
+	x25519Secret, err := x25519.SharedSecret(pk.x25519, ephemeralPublic)
+	if err != nil {
+		return [SharedKeySize]byte{}, ErrInvalidCiphertext
+	}
+
+	return deriveKey(mlkemSecret, x25519Secret, ephemeralPublic, x25519.PublicKey(pk.x25519)), nil
+}
+
+// deriveKey derives the shared key from both shared secrets and the X25519
+// public keys (see the package documentation).
+func deriveKey(mlkemSecret []byte, x25519Secret [32]byte, ephemeralPublic [32]byte, staticPublic [32]byte) [SharedKeySize]byte {
+	ikm := slices.Concat(mlkemSecret, x25519Secret[:], ephemeralPublic[:], staticPublic[:])
+
+	key, _ := hkdf.Key(sha256.New, ikm, nil, info, SharedKeySize)
+	clear(ikm)
+
+	return [SharedKeySize]byte(key)
+}
@@ -0,0 +1,118 @@
+package hybrid_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/hybrid"
+)
+
+func TestHybrid(t *testing.T) {
+	t.Run("Encapsulate + Decapsulate", func(t *testing.T) {
+		t.Parallel()
+
+		private, err := hybrid.GenerateKey()
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		public := private.PublicKey()
+		if len(public) != hybrid.PublicKeySize {
+			t.Errorf("want public key size %v, got %v", hybrid.PublicKeySize, len(public))
+		}
+
+		sharedKey, ciphertext, err := hybrid.Encapsulate(public)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if len(ciphertext) != hybrid.CiphertextSize {
+			t.Errorf("want ciphertext size %v, got %v", hybrid.CiphertextSize, len(ciphertext))
+		}
+
+		got, err := private.Decapsulate(ciphertext)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if got != sharedKey {
+			t.Errorf("want %v, got %v", sharedKey, got)
+		}
+
+		// A private key restored from its encoding derives the same shared key.
+		restored, err := hybrid.NewPrivateKey(private.Bytes())
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if !slices.Equal(restored.PublicKey(), public) {
+			t.Errorf("want %v, got %v", public, restored.PublicKey())
+		}
+
+		got, _ = restored.Decapsulate(ciphertext)
+		if got != sharedKey {
+			t.Errorf("want %v, got %v", sharedKey, got)
+		}
+	})
+
+	t.Run("Tampered Ciphertext", func(t *testing.T) {
+		t.Parallel()
+
+		private, _ := hybrid.GenerateKey()
+		sharedKey, ciphertext, _ := hybrid.Encapsulate(private.PublicKey())
+
+		// Tampering with either part results in a different key.
+		tt := map[string]int{
+			"ML-KEM": 0,
+			"X25519": hybrid.CiphertextSize - 1,
+		}
+
+		for name, index := range tt {
+			tampered := slices.Clone(ciphertext)
+			tampered[index] ^= 0x01
+
+			got, err := private.Decapsulate(tampered)
+			if err != nil {
+				t.Fatalf("%v: want error %v, got %v", name, nil, err)
+			}
+
+			if got == sharedKey {
+				t.Errorf("%v: want different keys, got %v twice", name, got)
+			}
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		private, _ := hybrid.GenerateKey()
+		public := private.PublicKey()
+
+		_, err := hybrid.NewPrivateKey(make([]byte, hybrid.PrivateKeySize-1))
+		if !errors.Is(err, hybrid.ErrInvalidPrivateKey) {
+			t.Errorf("want error %v, got %v", hybrid.ErrInvalidPrivateKey, err)
+		}
+
+		// The X25519 public key is a low order point (zero).
+		lowOrder := slices.Clone(public)
+		clear(lowOrder[len(lowOrder)-32:])
+
+		tt := map[string][]byte{
+			"Short":     public[:hybrid.PublicKeySize-1],
+			"Low Order": lowOrder,
+		}
+
+		for name, publicKey := range tt {
+			_, _, err := hybrid.Encapsulate(publicKey)
+			if !errors.Is(err, hybrid.ErrInvalidPublicKey) {
+				t.Errorf("%v: want error %v, got %v", name, hybrid.ErrInvalidPublicKey, err)
+			}
+		}
+
+		_, err = private.Decapsulate(make([]byte, hybrid.CiphertextSize+1))
+		if !errors.Is(err, hybrid.ErrInvalidCiphertext) {
+			t.Errorf("want error %v, got %v", hybrid.ErrInvalidCiphertext, err)
+		}
+	})
+}
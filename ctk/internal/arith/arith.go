@@ -1,4 +1,5 @@
-package ctk
+// Package arith provides integer arithmetic helpers.
+package arith
 
 const (
 	// ErrDivisionByZero is returned if there's an attempt to divide by zero.
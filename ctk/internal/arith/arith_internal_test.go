@@ -1,4 +1,4 @@
-package ctk
+package arith
 
 import "testing"
 
@@ -0,0 +1,159 @@
+// Package bech32 implements the Bech32 encoding as specified in
+// https://github.com/bitcoin/bips/blob/master/bip-0173.mediawiki.
+//
+// The encoding is made up of a human-readable part (HRP), the separator "1",
+// the data and a six character checksum which detects up to four substituted
+// characters. Unlike BIP 173 the length of the encoding isn't limited to 90
+// characters (like in age) so that larger keys can be encoded.
+package bech32
+
+import "strings"
+
+// charset is the alphabet of the data part.
+const charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// generator are the coefficients of the BCH code of the checksum.
+var generator = [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+// Encode encodes the data with the human-readable part.
+// The case of the result matches the case of hrp (which must not be mixed case
+// and only contain the characters 33 to 126 of US-ASCII).
+func Encode(hrp string, data []byte) string {
+	lower := strings.ToLower(hrp)
+	values := convertBits(data, 8, 5, true)
+
+	var b strings.Builder
+	b.Grow(len(hrp) + 1 + len(values) + 6)
+	b.WriteString(lower)
+	b.WriteByte('1')
+	for _, v := range values {
+		b.WriteByte(charset[v])
+	}
+	for _, v := range checksum(lower, values) {
+		b.WriteByte(charset[v])
+	}
+
+	if lower != hrp {
+		return strings.ToUpper(b.String())
+	}
+
+	return b.String()
+}
+
+// Decode decodes the encoding and returns the (lowercase) human-readable part
+// and the data.
+// Returns false if the encoding is mixed case, contains invalid characters or
+// padding or if the checksum is invalid.
+func Decode(s string) (string, []byte, bool) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", []byte{}, false
+	}
+	s = strings.ToLower(s)
+
+	separator := strings.LastIndexByte(s, '1')
+	if separator < 1 || separator+7 > len(s) {
+		return "", []byte{}, false
+	}
+
+	hrp := s[:separator]
+	for i := range len(hrp) {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return "", []byte{}, false
+		}
+	}
+
+	values := make([]byte, 0, len(s)-separator-1)
+	for i := separator + 1; i < len(s); i++ {
+		v := strings.IndexByte(charset, s[i])
+		if v < 0 {
+			return "", []byte{}, false
+		}
+		values = append(values, byte(v))
+	}
+
+	if polymod(append(expandHRP(hrp), values...)) != 1 {
+		return "", []byte{}, false
+	}
+
+	data := convertBits(values[:len(values)-6], 5, 8, false)
+	if data == nil {
+		return "", []byte{}, false
+	}
+
+	return hrp, data, true
+}
+
+// checksum computes the six checksum values of the human-readable part and the
+// data values.
+func checksum(hrp string, values []byte) []byte {
+	input := append(expandHRP(hrp), values...)
+	input = append(input, 0, 0, 0, 0, 0, 0)
+	mod := polymod(input) ^ 1
+
+	result := make([]byte, 6)
+	for i := range result {
+		result[i] = byte(mod>>(5*(5-i))) & 31
+	}
+
+	return result
+}
+
+// polymod computes the BCH checksum of the values.
+func polymod(values []byte) uint32 {
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i, g := range generator {
+			if (top>>i)&1 == 1 {
+				chk ^= g
+			}
+		}
+	}
+
+	return chk
+}
+
+// expandHRP expands the human-readable part into values for the checksum.
+func expandHRP(hrp string) []byte {
+	result := make([]byte, 0, 2*len(hrp)+1)
+	for i := range len(hrp) {
+		result = append(result, hrp[i]>>5)
+	}
+	result = append(result, 0)
+	for i := range len(hrp) {
+		result = append(result, hrp[i]&31)
+	}
+
+	return result
+}
+
+// convertBits regroups the values of fromBits bits into values of toBits bits.
+// Returns nil if pad is false and the input has more than 4 bits of padding or
+// non-zero padding.
+func convertBits(data []byte, fromBits uint, toBits uint, pad bool) []byte {
+	result := make([]byte, 0, (len(data)*int(fromBits)+int(toBits)-1)/int(toBits))
+
+	var acc uint32
+	var bits uint
+	maxValue := uint32(1)<<toBits - 1
+
+	for _, v := range data {
+		acc = acc<<fromBits | uint32(v)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			result = append(result, byte(acc>>bits&maxValue))
+		}
+	}
+
+	if pad {
+		if bits > 0 {
+			result = append(result, byte(acc<<(toBits-bits)&maxValue))
+		}
+	} else if bits >= fromBits || acc<<(toBits-bits)&maxValue != 0 {
+		return nil
+	}
+
+	return result
+}
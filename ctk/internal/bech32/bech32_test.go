@@ -0,0 +1,99 @@
+package bech32_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/internal/bech32"
+)
+
+func TestBech32(t *testing.T) {
+	t.Run("BIP 173 - Valid", func(t *testing.T) {
+		t.Parallel()
+
+		valid := []string{
+			"A12UEL5L",
+			"a12uel5l",
+			"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw",
+			"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w",
+			"?1ezyfcl",
+		}
+
+		for _, s := range valid {
+			hrp, data, ok := bech32.Decode(s)
+			if !ok {
+				t.Fatalf("%v: want %v, got %v", s, true, ok)
+			}
+
+			want := s
+			if strings.ToUpper(s) != s {
+				want = strings.ToLower(s)
+			} else {
+				hrp = strings.ToUpper(hrp)
+			}
+
+			got := bech32.Encode(hrp, data)
+			if got != want {
+				t.Errorf("want %v, got %v", want, got)
+			}
+		}
+	})
+
+	t.Run("BIP 173 - Invalid", func(t *testing.T) {
+		t.Parallel()
+
+		invalid := map[string]string{
+			"Mixed Case":             "A12uEL5L",
+			"No Separator":           "pzry9x0s0muk",
+			"Empty HRP":              "1pzry9x0s0muk",
+			"Invalid Character":      "x1b4n0q5v",
+			"Too Short Checksum":     "li1dgmt3",
+			"Invalid HRP Character":  "\x801eym55h",
+			"Uppercase Checksum":     "A1G7SGD8",
+			"Empty HRP and Checksum": "10a06t8",
+			"Invalid Checksum":       "a12uel5m",
+		}
+
+		for name, s := range invalid {
+			_, _, ok := bech32.Decode(s)
+			if ok {
+				t.Errorf("%v: want %v, got %v", name, false, ok)
+			}
+		}
+	})
+
+	t.Run("Round Trip", func(t *testing.T) {
+		t.Parallel()
+
+		for n := range 65 {
+			data := make([]byte, n)
+			for i := range data {
+				data[i] = byte(i * 7)
+			}
+
+			hrp, got, ok := bech32.Decode(bech32.Encode("ctk", data))
+			if !ok || hrp != "ctk" || !slices.Equal(got, data) {
+				t.Errorf("%v: want %v, got %v (%v, %v)", n, data, got, hrp, ok)
+			}
+		}
+	})
+
+	t.Run("Typo", func(t *testing.T) {
+		t.Parallel()
+
+		s := []byte(bech32.Encode("ctk", []byte("Ladies and Gentlemen of the class of '99")))
+		for i := len("ctk1"); i < len(s); i++ {
+			typo := slices.Clone(s)
+			typo[i] = 'q'
+			if s[i] == 'q' {
+				typo[i] = 'p'
+			}
+
+			_, _, ok := bech32.Decode(string(typo))
+			if ok {
+				t.Errorf("%v: want %v, got %v", i, false, ok)
+			}
+		}
+	})
+}
@@ -0,0 +1,4 @@
+// Package buildall imports every package of the toolkit so that a package
+// which doesn't compile (e.g. because of an import path that doesn't belong
+// to this module) breaks `go test ./...` and not only the programs using it.
+package buildall
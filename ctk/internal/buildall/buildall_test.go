@@ -0,0 +1,277 @@
+package buildall_test
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	_ "github.com/pmuens/ctk-go/ctk"
+	_ "github.com/pmuens/ctk-go/ctk/aad"
+	_ "github.com/pmuens/ctk-go/ctk/argon2"
+	_ "github.com/pmuens/ctk-go/ctk/balloon"
+	_ "github.com/pmuens/ctk-go/ctk/blake2b"
+	_ "github.com/pmuens/ctk-go/ctk/blobstore"
+	_ "github.com/pmuens/ctk-go/ctk/chacha20"
+	_ "github.com/pmuens/ctk-go/ctk/chacha20poly1305"
+	_ "github.com/pmuens/ctk-go/ctk/ciphertext"
+	_ "github.com/pmuens/ctk-go/ctk/clock"
+	_ "github.com/pmuens/ctk-go/ctk/dgram"
+	_ "github.com/pmuens/ctk-go/ctk/encfs"
+	_ "github.com/pmuens/ctk-go/ctk/encoding"
+	_ "github.com/pmuens/ctk-go/ctk/envelope"
+	_ "github.com/pmuens/ctk-go/ctk/fieldcrypt"
+	_ "github.com/pmuens/ctk-go/ctk/fpe"
+	_ "github.com/pmuens/ctk-go/ctk/hkdf"
+	_ "github.com/pmuens/ctk-go/ctk/httpcrypt"
+	_ "github.com/pmuens/ctk-go/ctk/hybrid"
+	_ "github.com/pmuens/ctk-go/ctk/internal/arith"
+	_ "github.com/pmuens/ctk-go/ctk/internal/bech32"
+	_ "github.com/pmuens/ctk-go/ctk/internal/checkpoint"
+	_ "github.com/pmuens/ctk-go/ctk/internal/chunk"
+	_ "github.com/pmuens/ctk-go/ctk/internal/debug"
+	_ "github.com/pmuens/ctk-go/ctk/internal/edgecase"
+	_ "github.com/pmuens/ctk-go/ctk/internal/leutil"
+	_ "github.com/pmuens/ctk-go/ctk/internal/libsodium"
+	_ "github.com/pmuens/ctk-go/ctk/internal/parallel"
+	_ "github.com/pmuens/ctk-go/ctk/internal/race"
+	_ "github.com/pmuens/ctk-go/ctk/internal/trace"
+	_ "github.com/pmuens/ctk-go/ctk/jwk"
+	_ "github.com/pmuens/ctk-go/ctk/keystore"
+	_ "github.com/pmuens/ctk-go/ctk/keytree"
+	_ "github.com/pmuens/ctk-go/ctk/keywrap"
+	_ "github.com/pmuens/ctk-go/ctk/kms"
+	_ "github.com/pmuens/ctk-go/ctk/logging"
+	_ "github.com/pmuens/ctk-go/ctk/merkle"
+	_ "github.com/pmuens/ctk-go/ctk/metrics"
+	_ "github.com/pmuens/ctk-go/ctk/mlkem"
+	_ "github.com/pmuens/ctk-go/ctk/multirecipient"
+	_ "github.com/pmuens/ctk-go/ctk/noncestore"
+	_ "github.com/pmuens/ctk-go/ctk/objcrypt"
+	_ "github.com/pmuens/ctk-go/ctk/openpgp"
+	_ "github.com/pmuens/ctk-go/ctk/padding"
+	_ "github.com/pmuens/ctk-go/ctk/paserk"
+	_ "github.com/pmuens/ctk-go/ctk/passhash"
+	_ "github.com/pmuens/ctk-go/ctk/pkcs8"
+	_ "github.com/pmuens/ctk-go/ctk/poly1305"
+	_ "github.com/pmuens/ctk-go/ctk/random"
+	_ "github.com/pmuens/ctk-go/ctk/ratchet"
+	_ "github.com/pmuens/ctk-go/ctk/rotation"
+	_ "github.com/pmuens/ctk-go/ctk/seclog"
+	_ "github.com/pmuens/ctk-go/ctk/secretbox"
+	_ "github.com/pmuens/ctk-go/ctk/secretstream"
+	_ "github.com/pmuens/ctk-go/ctk/securecookie"
+	_ "github.com/pmuens/ctk-go/ctk/selftest"
+	_ "github.com/pmuens/ctk-go/ctk/session"
+	_ "github.com/pmuens/ctk-go/ctk/sha3"
+	_ "github.com/pmuens/ctk-go/ctk/shamir"
+	_ "github.com/pmuens/ctk-go/ctk/stream"
+	_ "github.com/pmuens/ctk-go/ctk/subtle"
+	_ "github.com/pmuens/ctk-go/ctk/x25519"
+	_ "github.com/pmuens/ctk-go/ctk/xchacha20"
+	_ "github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
+)
+
+const module = "github.com/pmuens/ctk-go"
+
+// packages lists the packages that are imported above.
+var packages = []string{
+	"ctk",
+	"ctk/aad",
+	"ctk/argon2",
+	"ctk/balloon",
+	"ctk/blake2b",
+	"ctk/blobstore",
+	"ctk/chacha20",
+	"ctk/chacha20poly1305",
+	"ctk/ciphertext",
+	"ctk/clock",
+	"ctk/dgram",
+	"ctk/encfs",
+	"ctk/encoding",
+	"ctk/envelope",
+	"ctk/fieldcrypt",
+	"ctk/fpe",
+	"ctk/hkdf",
+	"ctk/httpcrypt",
+	"ctk/hybrid",
+	"ctk/internal/arith",
+	"ctk/internal/bech32",
+	"ctk/internal/checkpoint",
+	"ctk/internal/chunk",
+	"ctk/internal/debug",
+	"ctk/internal/edgecase",
+	"ctk/internal/leutil",
+	"ctk/internal/libsodium",
+	"ctk/internal/parallel",
+	"ctk/internal/race",
+	"ctk/internal/trace",
+	"ctk/jwk",
+	"ctk/keystore",
+	"ctk/keytree",
+	"ctk/keywrap",
+	"ctk/kms",
+	"ctk/logging",
+	"ctk/merkle",
+	"ctk/metrics",
+	"ctk/mlkem",
+	"ctk/multirecipient",
+	"ctk/noncestore",
+	"ctk/objcrypt",
+	"ctk/openpgp",
+	"ctk/padding",
+	"ctk/paserk",
+	"ctk/passhash",
+	"ctk/pkcs8",
+	"ctk/poly1305",
+	"ctk/random",
+	"ctk/ratchet",
+	"ctk/rotation",
+	"ctk/seclog",
+	"ctk/secretbox",
+	"ctk/secretstream",
+	"ctk/securecookie",
+	"ctk/selftest",
+	"ctk/session",
+	"ctk/sha3",
+	"ctk/shamir",
+	"ctk/stream",
+	"ctk/subtle",
+	"ctk/x25519",
+	"ctk/xchacha20",
+	"ctk/xchacha20poly1305",
+}
+
+func TestImports(t *testing.T) {
+	root, err := filepath.Abs(filepath.Join("..", "..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = filepath.WalkDir(filepath.Join(root, "ctk"), func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+
+		if d.Name() == "testdata" {
+			return filepath.SkipDir
+		}
+
+		pkg, err := build.ImportDir(path, 0)
+		if err != nil {
+			// Directories without Go files aren't packages.
+			if _, ok := err.(*build.NoGoError); ok {
+				return nil
+			}
+
+			return err
+		}
+
+		rel, _ := filepath.Rel(root, path)
+		rel = filepath.ToSlash(rel)
+
+		if pkg.Name == "buildall" {
+			return nil
+		}
+
+		for _, imp := range pkg.Imports {
+			if strings.HasPrefix(imp, "github.com/") && !strings.HasPrefix(imp, module+"/") {
+				t.Errorf("%v: want imports of %v, got %v", rel, module, imp)
+			}
+		}
+
+		if !slices.Contains(packages, rel) {
+			t.Errorf("%v: want package to be imported by buildall", rel)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPurego(t *testing.T) {
+	root, err := filepath.Abs(filepath.Join("..", "..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The purego build tag needs to exclude all assembly so that the portable
+	// implementations can be used on every platform (e.g. WebAssembly).
+	err = filepath.WalkDir(filepath.Join(root, "ctk"), func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".s" {
+			return err
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		rel, _ := filepath.Rel(root, path)
+		line, _, _ := strings.Cut(string(data), "\n")
+
+		if !strings.HasPrefix(line, "//go:build ") || !strings.Contains(line, "!purego") {
+			t.Errorf("%v: want build constraint with !purego, got %q", filepath.ToSlash(rel), line)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// tinygoPackages are the packages (and their dependencies within the module)
+// which need to build with TinyGo and therefore can't use math/big or reflect.
+var tinygoPackages = []string{
+	"ctk/chacha20",
+	"ctk/chacha20poly1305",
+	"ctk/poly1305",
+	"ctk/secretbox",
+	"ctk/xchacha20",
+	"ctk/xchacha20poly1305",
+}
+
+func TestTinyGo(t *testing.T) {
+	root, err := filepath.Abs(filepath.Join("..", "..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// TinyGo sets the tinygo build tag and doesn't support assembly.
+	ctx := build.Default
+	ctx.BuildTags = []string{"tinygo", "purego"}
+
+	seen := map[string]bool{}
+
+	var check func(rel string, from string)
+	check = func(rel string, from string) {
+		if seen[rel] {
+			return
+		}
+		seen[rel] = true
+
+		pkg, err := ctx.ImportDir(filepath.Join(root, filepath.FromSlash(rel)), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, imp := range pkg.Imports {
+			if imp == "math/big" || imp == "reflect" {
+				t.Errorf("%v (imported by %v): want no import of %v", rel, from, imp)
+			}
+
+			if dep, ok := strings.CutPrefix(imp, module+"/"); ok {
+				check(dep, rel)
+			}
+		}
+	}
+
+	for _, rel := range tinygoPackages {
+		check(rel, rel)
+	}
+}
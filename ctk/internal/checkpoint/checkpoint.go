@@ -0,0 +1,94 @@
+// Package checkpoint encodes the state of stateful primitives (e.g. a cipher in
+// the middle of a stream) so that it can be stored and restored later.
+//
+// An encoding is made up of a label that identifies the primitive, a version,
+// the state and a tag:
+//
+//	label | version (1) | state | tag (32)
+//
+// The tag is a keyed BLAKE2b-256 MAC over everything before it. Its key is
+// derived from a checkpoint key (see Key) that the caller supplies and keeps
+// apart from the encodings so that anyone holding an encoding can't modify it
+// (e.g. roll back a counter) and compute a valid tag. The MAC key must never be
+// taken from the state itself. States that contain key material need to be
+// stored as confidentially as the key.
+package checkpoint
+
+import (
+	"slices"
+
+	"github.com/pmuens/ctk-go/ctk/blake2b"
+	"github.com/pmuens/ctk-go/ctk/subtle"
+)
+
+// Version is the version of the encoding.
+const Version = 1
+
+// TagSize is the size (in bytes) of the tag.
+const TagSize = 32
+
+// keyLabel is used for domain separation when deriving MAC keys.
+const keyLabel = "ctk-go checkpoint "
+
+// Overhead is the number of bytes an encoding adds to a state with the label.
+func Overhead(label string) int {
+	return len(label) + 1 + TagSize
+}
+
+// Key derives the MAC key of the encodings with the label from the checkpoint
+// key.
+func Key(label string, checkpointKey [32]byte) []byte {
+	// The key and digest sizes are valid so that no error can occur.
+	h, _ := blake2b.NewBlake2b(TagSize, checkpointKey[:])
+	h.Write([]byte(keyLabel + label))
+
+	return h.Sum(nil)
+}
+
+// Encode encodes the state with the label and appends the tag which is keyed
+// with macKey (at most 64 bytes).
+func Encode(label string, state []byte, macKey []byte) []byte {
+	data := slices.Concat([]byte(label), []byte{Version}, state)
+
+	return append(data, tag(data, macKey)...)
+}
+
+// Decode returns the state of the encoding after checking the label, the
+// version and that the state has at least minStateSize bytes. The state isn't
+// authenticated yet (see Verify).
+// Returns false if the encoding is malformed.
+func Decode(label string, data []byte, minStateSize int) ([]byte, bool) {
+	if len(data) < Overhead(label)+minStateSize {
+		return []byte{}, false
+	}
+
+	if string(data[:len(label)]) != label || data[len(label)] != Version {
+		return []byte{}, false
+	}
+
+	return data[len(label)+1 : len(data)-TagSize], true
+}
+
+// Verify reports whether the tag of the encoding is valid for macKey. The tags
+// are compared in constant time.
+func Verify(data []byte, macKey []byte) bool {
+	if len(data) < TagSize {
+		return false
+	}
+
+	body := data[:len(data)-TagSize]
+
+	return subtle.ConstantTimeCompare(data[len(body):], tag(body, macKey)) == 1
+}
+
+// tag computes the keyed BLAKE2b-256 MAC of the data.
+func tag(data []byte, macKey []byte) []byte {
+	mac, err := blake2b.NewBlake2b(TagSize, macKey)
+	if err != nil {
+		panic("checkpoint: invalid MAC key")
+	}
+
+	mac.Write(data)
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,81 @@
+package checkpoint_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/internal/checkpoint"
+)
+
+func TestCheckpoint(t *testing.T) {
+	label := "test"
+	state := []byte{0x01, 0x02, 0x03}
+	macKey := []byte{0x04, 0x05, 0x06}
+
+	t.Run("Encode + Decode", func(t *testing.T) {
+		t.Parallel()
+
+		data := checkpoint.Encode(label, state, macKey)
+		if len(data) != checkpoint.Overhead(label)+len(state) {
+			t.Errorf("want length %v, got %v", checkpoint.Overhead(label)+len(state), len(data))
+		}
+
+		got, ok := checkpoint.Decode(label, data, len(state))
+		if !ok || !slices.Equal(got, state) {
+			t.Errorf("want %v, got %v (ok %v)", state, got, ok)
+		}
+
+		if !checkpoint.Verify(data, macKey) {
+			t.Errorf("want %v, got %v", true, false)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		t.Parallel()
+
+		data := checkpoint.Encode(label, state, macKey)
+
+		tt := map[string]struct {
+			data   []byte
+			decode bool
+			verify bool
+		}{
+			"Other Label":   {data: checkpoint.Encode("tset", state, macKey), decode: false, verify: true},
+			"Other Version": {data: slices.Concat([]byte(label), []byte{0x02}, data[len(label)+1:]), decode: false, verify: false},
+			"Too Short":     {data: data[:len(data)-1], decode: false, verify: false},
+			"Other Key":     {data: checkpoint.Encode(label, state, []byte{0x07}), decode: true, verify: false},
+			"Other State":   {data: slices.Concat(data[:len(label)+1], []byte{0x00}, data[len(label)+2:]), decode: true, verify: false},
+		}
+
+		for name, tc := range tt {
+			_, ok := checkpoint.Decode(label, tc.data, len(state))
+			if ok != tc.decode {
+				t.Errorf("%v: want decode %v, got %v", name, tc.decode, ok)
+			}
+
+			ok = checkpoint.Verify(tc.data, macKey)
+			if ok != tc.verify {
+				t.Errorf("%v: want verify %v, got %v", name, tc.verify, ok)
+			}
+		}
+	})
+
+	t.Run("Key", func(t *testing.T) {
+		t.Parallel()
+
+		key := checkpoint.Key(label, [32]byte{0x01})
+		if len(key) != checkpoint.TagSize {
+			t.Errorf("want length %v, got %v", checkpoint.TagSize, len(key))
+		}
+
+		// The MAC keys are separated by label and checkpoint key.
+		for name, other := range map[string][]byte{
+			"Other Label": checkpoint.Key("tset", [32]byte{0x01}),
+			"Other Key":   checkpoint.Key(label, [32]byte{0x02}),
+		} {
+			if slices.Equal(key, other) {
+				t.Errorf("%v: want different keys, got %x", name, key)
+			}
+		}
+	})
+}
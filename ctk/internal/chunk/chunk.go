@@ -0,0 +1,35 @@
+// Package chunk splits data into fixed size blocks which is the way block based
+// primitives such as ChaCha20 and Poly1305 process their input.
+package chunk
+
+import "iter"
+
+// Blocks returns an iterator over the consecutive size byte blocks of the data.
+// Every block but the last one is exactly size bytes long. The last block holds
+// the remaining 1 to size bytes. No block is yielded if the data is empty.
+//
+// The blocks are sub-slices of the data (rather than copies) so that callers
+// can modify the data in place.
+//
+// Blocks panics if the size isn't positive.
+func Blocks(data []byte, size int) iter.Seq[[]byte] {
+	if size <= 0 {
+		panic("chunk: block size must be positive")
+	}
+
+	return func(yield func([]byte) bool) {
+		// The remaining data is re-sliced (rather than computing offsets via
+		// (i+1)*size) so that no computation can overflow.
+		for len(data) > 0 {
+			n := min(size, len(data))
+
+			// The capacity is limited so that appending to a block can't overwrite
+			// the block that follows.
+			if !yield(data[:n:n]) {
+				return
+			}
+
+			data = data[n:]
+		}
+	}
+}
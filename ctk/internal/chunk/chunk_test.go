@@ -0,0 +1,117 @@
+package chunk_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/internal/chunk"
+)
+
+func TestBlocks(t *testing.T) {
+	t.Run("Lengths", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]struct {
+			length int
+			size   int
+			want   []int
+		}{
+			"Empty":             {length: 0, size: 16, want: nil},
+			"Single Byte":       {length: 1, size: 16, want: []int{1}},
+			"Partial Block":     {length: 15, size: 16, want: []int{15}},
+			"Exact Block":       {length: 16, size: 16, want: []int{16}},
+			"Block + 1":         {length: 17, size: 16, want: []int{16, 1}},
+			"Exact Blocks":      {length: 128, size: 64, want: []int{64, 64}},
+			"Blocks - 1":        {length: 127, size: 64, want: []int{64, 63}},
+			"Blocks + 1":        {length: 129, size: 64, want: []int{64, 64, 1}},
+			"Block Size 1":      {length: 3, size: 1, want: []int{1, 1, 1}},
+			"Larger Block Size": {length: 5, size: 64, want: []int{5}},
+		}
+
+		for name, tc := range tt {
+			var got []int
+			for block := range chunk.Blocks(make([]byte, tc.length), tc.size) {
+				got = append(got, len(block))
+			}
+
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("%v: want %v, got %v", name, tc.want, got)
+			}
+		}
+	})
+
+	t.Run("Content", func(t *testing.T) {
+		t.Parallel()
+
+		data := []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06}
+
+		var got []byte
+		for block := range chunk.Blocks(data, 3) {
+			got = append(got, block...)
+		}
+
+		if !slices.Equal(got, data) {
+			t.Errorf("want %v, got %v", data, got)
+		}
+	})
+
+	t.Run("In Place", func(t *testing.T) {
+		t.Parallel()
+
+		data := []byte{0x00, 0x01, 0x02, 0x03, 0x04}
+
+		for block := range chunk.Blocks(data, 2) {
+			block[0] = 0xff
+		}
+
+		want := []byte{0xff, 0x01, 0xff, 0x03, 0xff}
+
+		if !slices.Equal(data, want) {
+			t.Errorf("want %v, got %v", want, data)
+		}
+	})
+
+	t.Run("Append", func(t *testing.T) {
+		t.Parallel()
+
+		data := []byte{0x00, 0x01, 0x02, 0x03}
+
+		for block := range chunk.Blocks(data, 2) {
+			_ = append(block, 0xff)
+		}
+
+		want := []byte{0x00, 0x01, 0x02, 0x03}
+
+		if !slices.Equal(data, want) {
+			t.Errorf("want %v, got %v", want, data)
+		}
+	})
+
+	t.Run("Break", func(t *testing.T) {
+		t.Parallel()
+
+		count := 0
+		for range chunk.Blocks(make([]byte, 64), 16) {
+			count++
+			if count == 2 {
+				break
+			}
+		}
+
+		if count != 2 {
+			t.Errorf("want %v, got %v", 2, count)
+		}
+	})
+
+	t.Run("Invalid Size", func(t *testing.T) {
+		t.Parallel()
+
+		defer func() {
+			if recover() == nil {
+				t.Errorf("want panic, got none")
+			}
+		}()
+
+		chunk.Blocks([]byte{0x00}, 0)
+	})
+}
@@ -0,0 +1,29 @@
+// Package debug implements runtime checks that detect misuse of the AEAD
+// instances in development builds. The checks are enabled via the ctkdebug build
+// tag (e.g. go run -tags ctkdebug ./cmd/app) and panic loudly once a misuse is
+// detected:
+//
+//   - Nonce reuse: A key and nonce pair that encrypts different messages. The
+//     pairs of the most recent MaxTrackedNonces messages are tracked.
+//   - Zero keys: A key which is all zeros (usually an uninitialized key).
+//   - Instance reuse: An instance that's used to encrypt after it was used to
+//     decrypt (detected before the plaintext is processed, while the instance
+//     itself only panics once it computes the tag).
+//
+// The build tag also reveals why a decryption failed. Decryption paths report
+// every failure (e.g. a malformed header, a truncated message or an invalid
+// tag) as a single uniform error so that attackers who can observe the errors
+// (e.g. of a network service) don't learn which part of a forged message was
+// rejected (format oracles). Detail wraps the uniform error with the actual
+// cause in debug builds.
+//
+// Without the build tag the checks are no-ops which the compiler removes.
+//
+// Note that the toolkit's own tests intentionally trigger some of the checks
+// (e.g. the RFC 8439 test vectors use all zero keys) which is why only the
+// tests of this package are run with the build tag (make test-debug).
+package debug
+
+// MaxTrackedNonces is the number of key and nonce pairs that are tracked to
+// detect nonce reuse.
+const MaxTrackedNonces = 1 << 16
@@ -0,0 +1,24 @@
+//go:build !ctkdebug
+
+package debug
+
+// Enabled indicates whether the checks are enabled.
+const Enabled = false
+
+// Instance tracks the use of an AEAD instance (nothing without the ctkdebug
+// build tag).
+type Instance struct{}
+
+// Reset is a no-op without the ctkdebug build tag.
+func (i *Instance) Reset(key []byte, nonce []byte) {}
+
+// Encrypt is a no-op without the ctkdebug build tag.
+func (i *Instance) Encrypt(plaintext []byte, aad ...[]byte) {}
+
+// Decrypt is a no-op without the ctkdebug build tag.
+func (i *Instance) Decrypt() {}
+
+// Detail returns err (the detail is dropped without the ctkdebug build tag).
+func Detail(err error, detail error) error {
+	return err
+}
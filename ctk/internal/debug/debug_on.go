@@ -0,0 +1,118 @@
+//go:build ctkdebug
+
+package debug
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"slices"
+	"sync"
+)
+
+// Enabled indicates whether the checks are enabled.
+const Enabled = true
+
+// nonces tracks the messages that were encrypted with key and nonce pairs.
+var nonces = tracker{messages: make(map[[32]byte][32]byte)}
+
+// tracker maps the fingerprints of key and nonce pairs to the fingerprints of
+// the messages they encrypted. The oldest pairs are evicted once
+// MaxTrackedNonces pairs are tracked.
+type tracker struct {
+	// mu guards messages and order.
+	mu sync.Mutex
+
+	// messages maps key and nonce fingerprints to message fingerprints.
+	messages map[[32]byte][32]byte
+
+	// order is a ring buffer of the key and nonce fingerprints in the order
+	// they were added.
+	order [][32]byte
+
+	// next is the index of the oldest fingerprint in order (once it's full).
+	next int
+}
+
+// Instance tracks the use of an AEAD instance.
+type Instance struct {
+	// fingerprint is the fingerprint of the key and nonce pair.
+	fingerprint [32]byte
+
+	// decrypted indicates whether the instance was used to decrypt.
+	decrypted bool
+}
+
+// Reset checks the key and remembers the key and nonce pair of the instance.
+// Panics if the key is all zeros.
+func (i *Instance) Reset(key []byte, nonce []byte) {
+	if !slices.ContainsFunc(key, func(b byte) bool { return b != 0 }) {
+		panic("ctkdebug: all zero key")
+	}
+
+	i.fingerprint = sha256.Sum256(slices.Concat([]byte("key"), key, []byte("nonce"), nonce))
+	i.decrypted = false
+}
+
+// Encrypt checks that the instance wasn't used to decrypt and that its key and
+// nonce pair didn't encrypt a different message. The AAD can be passed as
+// multiple segments.
+// Panics if a misuse is detected.
+func (i *Instance) Encrypt(plaintext []byte, aad ...[]byte) {
+	if i.decrypted {
+		panic("ctkdebug: encrypt after decrypt on the same instance")
+	}
+
+	message := sha256.Sum256(slices.Concat(binary.BigEndian.AppendUint64(nil, uint64(len(plaintext))), plaintext, slices.Concat(aad...)))
+
+	nonces.mu.Lock()
+	defer nonces.mu.Unlock()
+
+	previous, ok := nonces.messages[i.fingerprint]
+	if ok && previous != message {
+		panic("ctkdebug: nonce reused for a different message")
+	}
+	if ok {
+		return
+	}
+
+	nonces.messages[i.fingerprint] = message
+
+	if len(nonces.order) < MaxTrackedNonces {
+		nonces.order = append(nonces.order, i.fingerprint)
+		return
+	}
+
+	delete(nonces.messages, nonces.order[nonces.next])
+	nonces.order[nonces.next] = i.fingerprint
+	nonces.next = (nonces.next + 1) % MaxTrackedNonces
+}
+
+// Decrypt records that the instance was used to decrypt.
+func (i *Instance) Decrypt() {
+	i.decrypted = true
+}
+
+// Detail returns an error that wraps the uniform error err and the detail so
+// that both match via errors.Is.
+func Detail(err error, detail error) error {
+	return &detailError{err: err, detail: detail}
+}
+
+// detailError is a uniform error that carries the actual cause.
+type detailError struct {
+	// err is the uniform error.
+	err error
+
+	// detail is the actual cause.
+	detail error
+}
+
+// Error implements the error interface.
+func (e *detailError) Error() string {
+	return e.err.Error() + " (" + e.detail.Error() + ")"
+}
+
+// Unwrap returns the uniform error and the detail.
+func (e *detailError) Unwrap() []error {
+	return []error{e.err, e.detail}
+}
@@ -0,0 +1,106 @@
+//go:build ctkdebug
+
+package debug_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/internal/debug"
+)
+
+// panics reports whether f panics.
+func panics(f func()) (panicked bool) {
+	defer func() {
+		panicked = recover() != nil
+	}()
+
+	f()
+
+	return false
+}
+
+func TestDebug(t *testing.T) {
+	key := []byte{0x01, 0x02, 0x03}
+
+	tt := map[string]struct {
+		f    func()
+		want bool
+	}{
+		"Zero Key": {
+			f: func() {
+				var i debug.Instance
+				i.Reset(make([]byte, 32), []byte{0x01})
+			},
+			want: true,
+		},
+		"Nonce Reuse": {
+			f: func() {
+				var i debug.Instance
+				i.Reset(key, []byte{0x02})
+				i.Encrypt([]byte("attack at dawn"), nil)
+				i.Reset(key, []byte{0x02})
+				i.Encrypt([]byte("attack at dusk"), nil)
+			},
+			want: true,
+		},
+		"Same Message": {
+			f: func() {
+				var i debug.Instance
+				i.Reset(key, []byte{0x03})
+				i.Encrypt([]byte("attack at dawn"), nil)
+				i.Reset(key, []byte{0x03})
+				i.Encrypt([]byte("attack at dawn"), nil)
+			},
+			want: false,
+		},
+		"Encrypt After Decrypt": {
+			f: func() {
+				var i debug.Instance
+				i.Reset(key, []byte{0x04})
+				i.Decrypt()
+				i.Encrypt([]byte("attack at dawn"), nil)
+			},
+			want: true,
+		},
+		"Evicted": {
+			f: func() {
+				var i debug.Instance
+				i.Reset(key, []byte{0x05})
+				i.Encrypt([]byte("attack at dawn"), nil)
+
+				for n := range debug.MaxTrackedNonces {
+					i.Reset(key, []byte{0x06, byte(n), byte(n >> 8)})
+					i.Encrypt(nil, nil)
+				}
+
+				i.Reset(key, []byte{0x05})
+				i.Encrypt([]byte("attack at dusk"), nil)
+			},
+			want: false,
+		},
+	}
+
+	for name, tc := range tt {
+		got := panics(tc.f)
+		if got != tc.want {
+			t.Errorf("%v: want panic %v, got %v", name, tc.want, got)
+		}
+	}
+}
+
+func TestDetail(t *testing.T) {
+	uniform := errors.New("decryption failed")
+	detail := errors.New("truncated")
+
+	err := debug.Detail(uniform, detail)
+
+	if !errors.Is(err, uniform) || !errors.Is(err, detail) {
+		t.Errorf("want error %v and %v, got %v", uniform, detail, err)
+	}
+
+	want := "decryption failed (truncated)"
+	if err.Error() != want {
+		t.Errorf("want %v, got %v", want, err.Error())
+	}
+}
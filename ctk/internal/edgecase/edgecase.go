@@ -0,0 +1,85 @@
+// Package edgecase provides the inputs of the edge case test matrix that's run
+// against all ciphers and AEADs.
+//
+// The matrix covers empty inputs, one byte inputs, inputs around the Poly1305
+// (16 byte) and ChaCha20 (64 byte) block boundaries and a large input.
+package edgecase
+
+import "fmt"
+
+// MaxLength is the length of the largest input used in tests.
+const MaxLength = 1 << 16
+
+// Lengths are the plaintext lengths of the matrix.
+var Lengths = []int{0, 1, 15, 16, 17, 31, 32, 33, 63, 64, 65, 127, 128, 129, MaxLength}
+
+// AADLengths are the additional authenticated data (AAD) lengths of the matrix.
+var AADLengths = []int{0, 1, 15, 16, 17, 64}
+
+// Case is a combination of a plaintext and additional authenticated data.
+type Case struct {
+	// Name describes the case.
+	Name string
+
+	// Plaintext is the plaintext.
+	Plaintext []byte
+
+	// AAD is the additional authenticated data.
+	AAD []byte
+}
+
+// Data returns length bytes that count up from 0x00 (wrapping around at 0xff).
+func Data(length int) []byte {
+	data := make([]byte, length)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	return data
+}
+
+// Key returns the key used in the matrix which consists of the bytes 0x80 to
+// 0x9f.
+func Key() [32]byte {
+	var key [32]byte
+	for i := range key {
+		key[i] = byte(0x80 + i)
+	}
+
+	return key
+}
+
+// Nonce returns a nonce of the size which consists of the bytes counting up
+// from 0x40.
+func Nonce(size int) []byte {
+	nonce := make([]byte, size)
+	for i := range nonce {
+		nonce[i] = byte(0x40 + i)
+	}
+
+	return nonce
+}
+
+// AEADCases returns all combinations of the plaintext and AAD lengths in a
+// deterministic order (plaintext length first).
+func AEADCases() []Case {
+	cases := make([]Case, 0, len(Lengths)*len(AADLengths))
+
+	for _, length := range Lengths {
+		for _, aadLength := range AADLengths {
+			aad := Data(aadLength)
+			// Use different bytes for the AAD than for the plaintext.
+			for i := range aad {
+				aad[i] ^= 0xff
+			}
+
+			cases = append(cases, Case{
+				Name:      fmt.Sprintf("plaintext %v bytes, aad %v bytes", length, aadLength),
+				Plaintext: Data(length),
+				AAD:       aad,
+			})
+		}
+	}
+
+	return cases
+}
@@ -0,0 +1,44 @@
+// Package leutil converts between bytes and 32 bit words in little endian
+// order which is the byte order used by ChaCha20 and its variants.
+package leutil
+
+import "encoding/binary"
+
+// WordSize is the size (in bytes) of a word.
+const WordSize = 4
+
+// ReadWords fills dst with the words that are read from src in little endian
+// order. The i-th word is read from the bytes src[4*i:4*i+4].
+//
+// ReadWords panics if src is shorter than 4 * len(dst) bytes.
+func ReadWords(dst []uint32, src []byte) {
+	if len(src) < len(dst)*WordSize {
+		panic("leutil: source too short")
+	}
+
+	for i := range dst {
+		dst[i] = binary.LittleEndian.Uint32(src[i*WordSize:])
+	}
+}
+
+// PutWords writes the words of src to dst in little endian order. The i-th word
+// is written to the bytes dst[4*i:4*i+4].
+//
+// PutWords panics if dst is shorter than 4 * len(src) bytes.
+func PutWords(dst []byte, src []uint32) {
+	if len(dst) < len(src)*WordSize {
+		panic("leutil: destination too short")
+	}
+
+	for i, word := range src {
+		binary.LittleEndian.PutUint32(dst[i*WordSize:], word)
+	}
+}
+
+// WordsToBytes returns the words as bytes in little endian order.
+func WordsToBytes(words []uint32) []byte {
+	result := make([]byte, len(words)*WordSize)
+	PutWords(result, words)
+
+	return result
+}
@@ -0,0 +1,129 @@
+package leutil_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/internal/leutil"
+)
+
+// bytes and words are the RFC 8439 (2.3.2) key in its byte and word form.
+var (
+	bytes = []byte{
+		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07,
+		0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+		0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17,
+		0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f,
+	}
+	words = []uint32{
+		0x03020100, 0x07060504, 0x0b0a0908, 0x0f0e0d0c,
+		0x13121110, 0x17161514, 0x1b1a1918, 0x1f1e1d1c,
+	}
+)
+
+func TestReadWords(t *testing.T) {
+	t.Run("Key", func(t *testing.T) {
+		t.Parallel()
+
+		got := make([]uint32, len(words))
+		leutil.ReadWords(got, bytes)
+
+		if !slices.Equal(got, words) {
+			t.Errorf("want %v, got %v", words, got)
+		}
+	})
+
+	t.Run("Longer Source", func(t *testing.T) {
+		t.Parallel()
+
+		got := make([]uint32, 2)
+		leutil.ReadWords(got, bytes)
+
+		if !slices.Equal(got, words[:2]) {
+			t.Errorf("want %v, got %v", words[:2], got)
+		}
+	})
+
+	t.Run("Short Source", func(t *testing.T) {
+		t.Parallel()
+
+		defer func() {
+			if recover() == nil {
+				t.Errorf("want panic, got none")
+			}
+		}()
+
+		leutil.ReadWords(make([]uint32, 2), bytes[:7])
+	})
+}
+
+func TestPutWords(t *testing.T) {
+	t.Run("Key", func(t *testing.T) {
+		t.Parallel()
+
+		got := make([]byte, len(bytes))
+		leutil.PutWords(got, words)
+
+		if !slices.Equal(got, bytes) {
+			t.Errorf("want %v, got %v", bytes, got)
+		}
+	})
+
+	t.Run("Longer Destination", func(t *testing.T) {
+		t.Parallel()
+
+		got := make([]byte, 10)
+		leutil.PutWords(got, words[:2])
+
+		want := append(slices.Clone(bytes[:8]), 0x00, 0x00)
+
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Short Destination", func(t *testing.T) {
+		t.Parallel()
+
+		defer func() {
+			if recover() == nil {
+				t.Errorf("want panic, got none")
+			}
+		}()
+
+		leutil.PutWords(make([]byte, 7), words[:2])
+	})
+}
+
+func TestWordsToBytes(t *testing.T) {
+	t.Run("Key", func(t *testing.T) {
+		t.Parallel()
+
+		got := leutil.WordsToBytes(words)
+
+		if !slices.Equal(got, bytes) {
+			t.Errorf("want %v, got %v", bytes, got)
+		}
+	})
+
+	t.Run("Round Trip", func(t *testing.T) {
+		t.Parallel()
+
+		got := make([]uint32, len(words))
+		leutil.ReadWords(got, leutil.WordsToBytes(words))
+
+		if !slices.Equal(got, words) {
+			t.Errorf("want %v, got %v", words, got)
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		t.Parallel()
+
+		got := leutil.WordsToBytes(nil)
+
+		if len(got) != 0 {
+			t.Errorf("want length %v, got %v", 0, len(got))
+		}
+	})
+}
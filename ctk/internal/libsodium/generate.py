@@ -0,0 +1,115 @@
+#!/usr/bin/env python3
+"""Generates the libsodium interoperability test vectors (testdata/vectors.json).
+
+The vectors are computed by calling into the system's libsodium via ctypes so
+that no Python bindings or C toolchain are needed. The inputs are derived
+deterministically from BLAKE2b so that re-running the script on the same
+libsodium version reproduces the file byte-for-byte.
+
+Usage (from the repository root):
+
+    python3 ctk/internal/libsodium/generate.py
+"""
+
+import ctypes
+import ctypes.util
+import hashlib
+import json
+import os
+
+# Plaintext and AAD lengths around the Poly1305 (16 byte) and ChaCha20 (64 byte)
+# block boundaries.
+LENGTHS = [0, 1, 15, 16, 17, 31, 32, 33, 63, 64, 65, 127, 128, 129, 1000]
+AAD_LENGTHS = [0, 1, 16, 17, 64]
+
+
+def load():
+    path = ctypes.util.find_library("sodium") or "libsodium.so.23"
+    sodium = ctypes.CDLL(path)
+    if sodium.sodium_init() < 0:
+        raise RuntimeError("sodium_init failed")
+    sodium.sodium_version_string.restype = ctypes.c_char_p
+    return sodium
+
+
+def derive(label, length):
+    """Returns length deterministic bytes for the label."""
+    out = b""
+    counter = 0
+    while len(out) < length:
+        out += hashlib.blake2b(f"{label} {counter}".encode(), digest_size=64).digest()
+        counter += 1
+    return out[:length]
+
+
+def xchacha20poly1305(sodium, key, nonce, plaintext, aad):
+    ciphertext = ctypes.create_string_buffer(len(plaintext))
+    tag = ctypes.create_string_buffer(16)
+    tag_len = ctypes.c_ulonglong()
+    result = sodium.crypto_aead_xchacha20poly1305_ietf_encrypt_detached(
+        ciphertext, tag, ctypes.byref(tag_len),
+        plaintext, ctypes.c_ulonglong(len(plaintext)),
+        aad, ctypes.c_ulonglong(len(aad)),
+        None, nonce, key)
+    if result != 0:
+        raise RuntimeError("crypto_aead_xchacha20poly1305_ietf_encrypt_detached failed")
+    return ciphertext.raw, tag.raw
+
+
+def secretbox(sodium, key, nonce, message):
+    box = ctypes.create_string_buffer(16 + len(message))
+    result = sodium.crypto_secretbox_xchacha20poly1305_easy(
+        box, message, ctypes.c_ulonglong(len(message)), nonce, key)
+    if result != 0:
+        raise RuntimeError("crypto_secretbox_xchacha20poly1305_easy failed")
+    return box.raw
+
+
+def main():
+    sodium = load()
+
+    aead = []
+    for length in LENGTHS:
+        for aad_length in AAD_LENGTHS:
+            label = f"xchacha20poly1305 {length} {aad_length}"
+            key = derive(label + " key", 32)
+            nonce = derive(label + " nonce", 24)
+            plaintext = derive(label + " plaintext", length)
+            aad = derive(label + " aad", aad_length)
+            ciphertext, tag = xchacha20poly1305(sodium, key, nonce, plaintext, aad)
+            aead.append({
+                "key": key.hex(),
+                "nonce": nonce.hex(),
+                "aad": aad.hex(),
+                "plaintext": plaintext.hex(),
+                "ciphertext": ciphertext.hex(),
+                "tag": tag.hex(),
+            })
+
+    boxes = []
+    for length in LENGTHS:
+        label = f"secretbox {length}"
+        key = derive(label + " key", 32)
+        nonce = derive(label + " nonce", 24)
+        message = derive(label + " message", length)
+        boxes.append({
+            "key": key.hex(),
+            "nonce": nonce.hex(),
+            "message": message.hex(),
+            "box": secretbox(sodium, key, nonce, message).hex(),
+        })
+
+    vectors = {
+        "libsodium_version": sodium.sodium_version_string().decode(),
+        "xchacha20poly1305": aead,
+        "secretbox": boxes,
+    }
+
+    path = os.path.join(os.path.dirname(os.path.abspath(__file__)), "testdata", "vectors.json")
+    with open(path, "w") as f:
+        json.dump(vectors, f, indent=2)
+        f.write("\n")
+
+
+if __name__ == "__main__":
+    main()
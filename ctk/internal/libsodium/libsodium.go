@@ -0,0 +1,93 @@
+// Package libsodium provides test vectors that were generated with libsodium so
+// that the toolkit's output can be checked for interoperability byte-for-byte.
+//
+// The vectors are generated via generate.py (which calls into the system's
+// libsodium) and embedded from testdata/vectors.json.
+//
+// With the libsodium build tag, a Generator which computes outputs for arbitrary
+// inputs with the system's libsodium is available as well.
+package libsodium
+
+import (
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+)
+
+//go:embed testdata/vectors.json
+var vectorsJSON []byte
+
+// Bytes are bytes which are hex encoded in JSON.
+type Bytes []byte
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (b *Bytes) UnmarshalText(text []byte) error {
+	decoded, err := hex.DecodeString(string(text))
+	if err != nil {
+		return err
+	}
+
+	*b = decoded
+
+	return nil
+}
+
+// AEADVector is an XChaCha20-Poly1305 (IETF) test vector.
+type AEADVector struct {
+	// Key is the key.
+	Key Bytes `json:"key"`
+
+	// Nonce is the nonce.
+	Nonce Bytes `json:"nonce"`
+
+	// AAD is the additional authenticated data.
+	AAD Bytes `json:"aad"`
+
+	// Plaintext is the plaintext.
+	Plaintext Bytes `json:"plaintext"`
+
+	// Ciphertext is the ciphertext (without the tag).
+	Ciphertext Bytes `json:"ciphertext"`
+
+	// Tag is the detached Poly1305 tag.
+	Tag Bytes `json:"tag"`
+}
+
+// SecretBoxVector is an XChaCha20-Poly1305 secretbox test vector.
+type SecretBoxVector struct {
+	// Key is the key.
+	Key Bytes `json:"key"`
+
+	// Nonce is the nonce.
+	Nonce Bytes `json:"nonce"`
+
+	// Message is the message.
+	Message Bytes `json:"message"`
+
+	// Box is the tag followed by the ciphertext.
+	Box Bytes `json:"box"`
+}
+
+// Vectors are the test vectors of all covered algorithms.
+type Vectors struct {
+	// Version is the version of libsodium the vectors were generated with.
+	Version string `json:"libsodium_version"`
+
+	// XChaCha20Poly1305 are the crypto_aead_xchacha20poly1305_ietf vectors.
+	XChaCha20Poly1305 []AEADVector `json:"xchacha20poly1305"`
+
+	// SecretBox are the crypto_secretbox_xchacha20poly1305 vectors.
+	SecretBox []SecretBoxVector `json:"secretbox"`
+}
+
+// Load parses the embedded test vectors.
+func Load() (*Vectors, error) {
+	var v Vectors
+
+	err := json.Unmarshal(vectorsJSON, &v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
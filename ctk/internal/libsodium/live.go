@@ -0,0 +1,193 @@
+//go:build libsodium
+
+package libsodium
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+//go:embed testdata/vectorgen.c
+var vectorgenSource []byte
+
+// Generator computes outputs with the system's libsodium by running a small C
+// program (testdata/vectorgen.c) which is compiled on demand. Talking to the
+// program via stdin and stdout keeps the toolkit free of cgo.
+//
+// The program is compiled with $CC (cc by default) and linked with
+// $CTK_LIBSODIUM_LDFLAGS (-lsodium by default, e.g. -l:libsodium.so.23 if only
+// the shared library is installed).
+// A Generator is safe for concurrent use.
+type Generator struct {
+	// dir is the temporary directory of the compiled program.
+	dir string
+
+	// cmd is the running program.
+	cmd *exec.Cmd
+
+	// stdin receives the requests.
+	stdin io.WriteCloser
+
+	// stdout provides the responses.
+	stdout *bufio.Reader
+
+	// mu serializes the requests.
+	mu sync.Mutex
+}
+
+// NewGenerator compiles and starts the generator program.
+// Returns an error if the program can't be compiled (e.g. because no C
+// compiler or libsodium is installed).
+func NewGenerator() (*Generator, error) {
+	dir, err := os.MkdirTemp("", "ctk-libsodium")
+	if err != nil {
+		return nil, err
+	}
+
+	source := filepath.Join(dir, "vectorgen.c")
+	binary := filepath.Join(dir, "vectorgen")
+
+	err = os.WriteFile(source, vectorgenSource, 0o600)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	cc := os.Getenv("CC")
+	if cc == "" {
+		cc = "cc"
+	}
+
+	ldflags := strings.Fields(os.Getenv("CTK_LIBSODIUM_LDFLAGS"))
+	if len(ldflags) == 0 {
+		ldflags = []string{"-lsodium"}
+	}
+
+	args := append([]string{"-O2", "-o", binary, source}, ldflags...)
+	out, err := exec.Command(cc, args...).CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, errors.New("libsodium: compiling the generator failed: " + strings.TrimSpace(string(out)))
+	}
+
+	g := &Generator{
+		dir: dir,
+		cmd: exec.Command(binary),
+	}
+
+	g.stdin, err = g.cmd.StdinPipe()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	stdout, err := g.cmd.StdoutPipe()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	g.stdout = bufio.NewReader(stdout)
+
+	err = g.cmd.Start()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// XChaCha20Poly1305 encrypts the plaintext via
+// crypto_aead_xchacha20poly1305_ietf_encrypt_detached and returns the
+// ciphertext and the tag.
+func (g *Generator) XChaCha20Poly1305(key [32]byte, nonce [24]byte, plaintext []byte, aad []byte) ([]byte, [16]byte, error) {
+	fields, err := g.request(2, "aead", key[:], nonce[:], aad, plaintext)
+	if err != nil || len(fields[1]) != 16 {
+		return []byte{}, [16]byte{}, errors.Join(errInvalidResponse, err)
+	}
+
+	return fields[0], [16]byte(fields[1]), nil
+}
+
+// SecretBox seals the message via crypto_secretbox_xchacha20poly1305_easy and
+// returns the box (the tag followed by the ciphertext).
+func (g *Generator) SecretBox(key [32]byte, nonce [24]byte, message []byte) ([]byte, error) {
+	fields, err := g.request(1, "secretbox", key[:], nonce[:], message)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return fields[0], nil
+}
+
+// Close stops the program and removes its temporary directory.
+func (g *Generator) Close() error {
+	g.stdin.Close()
+	err := g.cmd.Wait()
+	os.RemoveAll(g.dir)
+
+	return err
+}
+
+// errInvalidResponse is returned if the program's response is malformed (e.g.
+// because the program failed).
+var errInvalidResponse = errors.New("libsodium: invalid generator response")
+
+// request sends the operation with the hex encoded arguments and decodes the
+// response's fields.
+func (g *Generator) request(fields int, op string, args ...[]byte) ([][]byte, error) {
+	line := []string{op}
+	for _, arg := range args {
+		line = append(line, encodeField(arg))
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	_, err := io.WriteString(g.stdin, strings.Join(line, " ")+"\n")
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := g.stdout.ReadString('\n')
+	if err != nil {
+		return nil, errInvalidResponse
+	}
+
+	parts := strings.Fields(response)
+	if len(parts) != fields {
+		return nil, errInvalidResponse
+	}
+
+	decoded := make([][]byte, len(parts))
+	for i, part := range parts {
+		if part == "-" {
+			decoded[i] = []byte{}
+			continue
+		}
+
+		decoded[i], err = hex.DecodeString(part)
+		if err != nil {
+			return nil, errInvalidResponse
+		}
+	}
+
+	return decoded, nil
+}
+
+// encodeField hex encodes the field ("-" if it's empty).
+func encodeField(b []byte) string {
+	if len(b) == 0 {
+		return "-"
+	}
+
+	return hex.EncodeToString(b)
+}
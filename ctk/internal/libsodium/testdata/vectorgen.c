@@ -0,0 +1,120 @@
+/*
+ * vectorgen computes XChaCha20-Poly1305 and secretbox outputs with libsodium
+ * for the live comparison harness (see live.go, build tag libsodium).
+ *
+ * It reads one request per line from stdin and writes one response per line to
+ * stdout. All fields are hex encoded and empty fields are written as "-":
+ *
+ *   aead <key> <nonce> <aad> <plaintext>  ->  <ciphertext> <tag>
+ *   secretbox <key> <nonce> <message>     ->  <box>
+ *
+ * The prototypes are declared here so that only the shared library (and not
+ * the development headers) needs to be installed.
+ */
+#define _POSIX_C_SOURCE 200809L
+
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+int sodium_init(void);
+
+int crypto_aead_xchacha20poly1305_ietf_encrypt_detached(
+    unsigned char *c, unsigned char *mac, unsigned long long *maclen_p,
+    const unsigned char *m, unsigned long long mlen,
+    const unsigned char *ad, unsigned long long adlen,
+    const unsigned char *nsec, const unsigned char *npub,
+    const unsigned char *k);
+
+int crypto_secretbox_xchacha20poly1305_easy(
+    unsigned char *c, const unsigned char *m, unsigned long long mlen,
+    const unsigned char *n, const unsigned char *k);
+
+/* decode decodes the hex field ("-" is empty) into a new buffer. */
+static unsigned char *decode(const char *field, size_t *len) {
+    size_t n = strcmp(field, "-") == 0 ? 0 : strlen(field) / 2;
+    unsigned char *out = malloc(n + 1);
+
+    for (size_t i = 0; i < n; i++) {
+        unsigned int byte;
+        sscanf(field + 2 * i, "%2x", &byte);
+        out[i] = (unsigned char) byte;
+    }
+
+    *len = n;
+    return out;
+}
+
+/* encode writes the bytes hex encoded ("-" if empty). */
+static void encode(const unsigned char *data, size_t len) {
+    if (len == 0) {
+        fputs("-", stdout);
+    }
+
+    for (size_t i = 0; i < len; i++) {
+        printf("%02x", data[i]);
+    }
+}
+
+int main(void) {
+    char *line = NULL;
+    size_t cap = 0;
+
+    if (sodium_init() < 0) {
+        return 1;
+    }
+
+    while (getline(&line, &cap, stdin) > 0) {
+        char *fields[5] = {0};
+        int count = 0;
+
+        for (char *f = strtok(line, " \n"); f != NULL && count < 5; f = strtok(NULL, " \n")) {
+            fields[count++] = f;
+        }
+
+        if (count == 5 && strcmp(fields[0], "aead") == 0) {
+            size_t klen, nlen, alen, mlen;
+            unsigned char *k = decode(fields[1], &klen);
+            unsigned char *n = decode(fields[2], &nlen);
+            unsigned char *a = decode(fields[3], &alen);
+            unsigned char *m = decode(fields[4], &mlen);
+            unsigned char *c = malloc(mlen + 1);
+            unsigned char tag[16];
+
+            if (klen != 32 || nlen != 24 ||
+                crypto_aead_xchacha20poly1305_ietf_encrypt_detached(c, tag, NULL, m, mlen, a, alen, NULL, n, k) != 0) {
+                return 1;
+            }
+
+            encode(c, mlen);
+            fputs(" ", stdout);
+            encode(tag, sizeof(tag));
+            fputs("\n", stdout);
+
+            free(k), free(n), free(a), free(m), free(c);
+        } else if (count == 4 && strcmp(fields[0], "secretbox") == 0) {
+            size_t klen, nlen, mlen;
+            unsigned char *k = decode(fields[1], &klen);
+            unsigned char *n = decode(fields[2], &nlen);
+            unsigned char *m = decode(fields[3], &mlen);
+            unsigned char *c = malloc(16 + mlen);
+
+            if (klen != 32 || nlen != 24 ||
+                crypto_secretbox_xchacha20poly1305_easy(c, m, mlen, n, k) != 0) {
+                return 1;
+            }
+
+            encode(c, 16 + mlen);
+            fputs("\n", stdout);
+
+            free(k), free(n), free(m), free(c);
+        } else {
+            return 1;
+        }
+
+        fflush(stdout);
+    }
+
+    free(line);
+    return 0;
+}
@@ -0,0 +1,50 @@
+// Package parallel splits work over a range of items into contiguous parts which
+// are processed concurrently.
+package parallel
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Workers returns the number of workers to use for the requested concurrency.
+// A concurrency below 1 uses one worker per available CPU (GOMAXPROCS).
+func Workers(concurrency int) int {
+	if concurrency < 1 {
+		return runtime.GOMAXPROCS(0)
+	}
+
+	return concurrency
+}
+
+// Range splits [0, n) into at most workers contiguous ranges of (almost) equal
+// size and calls f for every range [start, end). The calls run concurrently and
+// Range returns after all of them returned. With a single worker f is called
+// on the calling goroutine.
+//
+// Every call of f processes a whole range so that it can amortize its setup
+// (e.g. the allocation of an instance) over many items.
+func Range(n int, workers int, f func(start int, end int)) {
+	workers = min(max(workers, 1), n)
+	if workers <= 1 {
+		if n > 0 {
+			f(0, n)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+
+	for i := range workers {
+		start := i * n / workers
+		end := (i + 1) * n / workers
+
+		go func() {
+			defer wg.Done()
+			f(start, end)
+		}()
+	}
+
+	wg.Wait()
+}
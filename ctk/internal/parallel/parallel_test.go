@@ -0,0 +1,53 @@
+package parallel_test
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/internal/parallel"
+)
+
+func TestRange(t *testing.T) {
+	tt := map[string]struct {
+		n       int
+		workers int
+	}{
+		"Empty":               {n: 0, workers: 4},
+		"Single Worker":       {n: 10, workers: 1},
+		"More Workers":        {n: 3, workers: 8},
+		"Uneven":              {n: 1001, workers: 7},
+		"Non-Positive Worker": {n: 5, workers: 0},
+	}
+
+	for name, tc := range tt {
+		seen := make([]atomic.Int32, tc.n)
+		var calls atomic.Int32
+
+		parallel.Range(tc.n, tc.workers, func(start int, end int) {
+			calls.Add(1)
+			for i := start; i < end; i++ {
+				seen[i].Add(1)
+			}
+		})
+
+		for i := range seen {
+			if seen[i].Load() != 1 {
+				t.Errorf("%v: want item %v processed %v time, got %v", name, i, 1, seen[i].Load())
+			}
+		}
+
+		if int(calls.Load()) > max(tc.workers, 1) {
+			t.Errorf("%v: want at most %v calls, got %v", name, max(tc.workers, 1), calls.Load())
+		}
+	}
+}
+
+func TestWorkers(t *testing.T) {
+	if parallel.Workers(3) != 3 {
+		t.Errorf("want %v, got %v", 3, parallel.Workers(3))
+	}
+
+	if parallel.Workers(0) < 1 {
+		t.Errorf("want at least %v, got %v", 1, parallel.Workers(0))
+	}
+}
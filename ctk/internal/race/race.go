@@ -0,0 +1,4 @@
+// Package race reports whether the race detector is enabled (e.g. for tests
+// that count allocations which sync.Pool doesn't avoid reliably with the race
+// detector as it drops items randomly).
+package race
@@ -0,0 +1,6 @@
+//go:build race
+
+package race
+
+// Enabled indicates whether the race detector is enabled.
+const Enabled = true
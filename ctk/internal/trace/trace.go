@@ -0,0 +1,60 @@
+// Package trace formats intermediate values of the primitives in the style of
+// the worked examples of RFC 8439 so that they can be compared side by side.
+//
+// Tracing is best-effort which is why write errors are ignored.
+package trace
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// State writes the title followed by the 16 words of the state as a 4x4 matrix
+// of hex values (one row per line).
+func State(w io.Writer, title string, state [16]uint32) {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s:\n", title)
+	for row := range 4 {
+		fmt.Fprintf(&b, "    %08x  %08x  %08x  %08x\n", state[row*4], state[row*4+1], state[row*4+2], state[row*4+3])
+	}
+
+	io.WriteString(w, b.String())
+}
+
+// Bytes writes the title followed by a hex dump of the data with 16 bytes per
+// line. Every line starts with the offset and ends with the printable ASCII
+// characters of the bytes.
+func Bytes(w io.Writer, title string, data []byte) {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s:\n", title)
+	for offset := 0; offset < len(data); offset += 16 {
+		line := data[offset:min(offset+16, len(data))]
+
+		fmt.Fprintf(&b, "    %03x ", offset)
+		for _, value := range line {
+			fmt.Fprintf(&b, " %02x", value)
+		}
+		// Align the ASCII column of a partial last line.
+		b.WriteString(strings.Repeat("   ", 16-len(line)))
+
+		b.WriteString("  ")
+		for _, value := range line {
+			if value >= 0x20 && value < 0x7f {
+				b.WriteByte(value)
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	io.WriteString(w, b.String())
+}
+
+// Value writes the name followed by the value.
+func Value(w io.Writer, name string, value any) {
+	fmt.Fprintf(w, "%s = %x\n", name, value)
+}
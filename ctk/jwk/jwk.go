@@ -0,0 +1,331 @@
+// Package jwk implements JSON Web Keys as specified in
+// https://datatracker.ietf.org/doc/html/rfc7517 for symmetric keys (oct) and
+// the X25519 and Ed25519 keys of RFC 8037 (OKP) so that keys can be exchanged
+// with JOSE tooling and cloud services.
+//
+// A JWK is created from a key via NewSymmetric, NewX25519 or NewEd25519 (and
+// their public key variants) and converted back via the methods of the same
+// names which check that the JWK has the expected type and is well-formed.
+// JWKs are (un)marshaled via encoding/json (see Parse for unmarshaling and
+// validation in one step). Thumbprint computes the RFC 7638 thumbprint which
+// is commonly used as the key ID.
+//
+// Note that the key material of private JWKs is held in strings which can't be
+// wiped from memory.
+package jwk
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/pmuens/ctk-go/ctk/subtle"
+	"github.com/pmuens/ctk-go/ctk/x25519"
+)
+
+const (
+	// ErrInvalidKey is returned if a JWK is malformed (e.g. if a member is
+	// missing or has the wrong size).
+	ErrInvalidKey = Error("invalid jwk")
+
+	// ErrWrongKeyType is returned if a JWK has another key type or curve than
+	// the expected one.
+	ErrWrongKeyType = Error("wrong jwk key type")
+
+	// ErrNoPrivateKey is returned if the private key of a JWK that only
+	// contains the public key is requested.
+	ErrNoPrivateKey = Error("jwk has no private key")
+)
+
+// Key types and curves.
+const (
+	// KeyTypeOct is the key type of symmetric keys.
+	KeyTypeOct = "oct"
+
+	// KeyTypeOKP is the key type of octet key pairs (RFC 8037).
+	KeyTypeOKP = "OKP"
+
+	// CurveX25519 is the curve of X25519 key pairs.
+	CurveX25519 = "X25519"
+
+	// CurveEd25519 is the curve of Ed25519 key pairs.
+	CurveEd25519 = "Ed25519"
+)
+
+// encoding is the encoding of the key material (base64url without padding).
+var encoding = base64.RawURLEncoding.Strict()
+
+// JWK is a JSON Web Key. Only the members that are relevant for the supported
+// key types are represented (others are dropped when unmarshaling).
+type JWK struct {
+	// KeyType is the key type (KeyTypeOct or KeyTypeOKP).
+	KeyType string `json:"kty"`
+
+	// Curve is the curve of an OKP key (CurveX25519 or CurveEd25519).
+	Curve string `json:"crv,omitempty"`
+
+	// K is the base64url encoded symmetric key of an oct key.
+	K string `json:"k,omitempty"`
+
+	// X is the base64url encoded public key of an OKP key.
+	X string `json:"x,omitempty"`
+
+	// D is the base64url encoded private key of an OKP key (empty for public
+	// keys). The private key of an Ed25519 key is its 32 byte seed.
+	D string `json:"d,omitempty"`
+
+	// KeyID is the optional key ID.
+	KeyID string `json:"kid,omitempty"`
+
+	// Algorithm is the optional algorithm the key is used with.
+	Algorithm string `json:"alg,omitempty"`
+
+	// Use is the optional intended use of a public key ("sig" or "enc").
+	Use string `json:"use,omitempty"`
+
+	// KeyOps are the optional operations the key is intended for.
+	KeyOps []string `json:"key_ops,omitempty"`
+}
+
+// Set is a JWK Set (e.g. served by a JWKS endpoint).
+type Set struct {
+	// Keys are the keys of the set.
+	Keys []JWK `json:"keys"`
+}
+
+// Lookup returns the key with the key ID.
+// Returns false if the set has no such key.
+func (s Set) Lookup(keyID string) (JWK, bool) {
+	for _, key := range s.Keys {
+		if key.KeyID == keyID {
+			return key, true
+		}
+	}
+
+	return JWK{}, false
+}
+
+// Parse unmarshals and validates a JWK.
+// Returns ErrInvalidKey if the JSON is malformed or the JWK is invalid and
+// ErrWrongKeyType if it has an unsupported key type or curve.
+func Parse(data []byte) (JWK, error) {
+	var key JWK
+
+	err := json.Unmarshal(data, &key)
+	if err != nil {
+		return JWK{}, ErrInvalidKey
+	}
+
+	err = key.Validate()
+	if err != nil {
+		return JWK{}, err
+	}
+
+	return key, nil
+}
+
+// Validate checks that the JWK is a well-formed key of a supported type (e.g.
+// that the key material has the right size and that the public key of a
+// private OKP key belongs to the private key).
+// Returns ErrInvalidKey if it's malformed and ErrWrongKeyType if it has an
+// unsupported key type or curve.
+func (k JWK) Validate() error {
+	var err error
+
+	switch {
+	case k.KeyType == KeyTypeOct:
+		_, err = k.Symmetric()
+	case k.KeyType == KeyTypeOKP && k.D == "":
+		_, err = k.publicKey(k.Curve)
+	case k.KeyType == KeyTypeOKP && k.Curve == CurveX25519:
+		_, err = k.X25519()
+	case k.KeyType == KeyTypeOKP && k.Curve == CurveEd25519:
+		_, err = k.Ed25519()
+	default:
+		err = ErrWrongKeyType
+	}
+
+	return err
+}
+
+// NewSymmetric returns the oct JWK of the symmetric key.
+func NewSymmetric(key []byte) JWK {
+	return JWK{KeyType: KeyTypeOct, K: encoding.EncodeToString(key)}
+}
+
+// Symmetric returns the key of an oct JWK.
+// Returns ErrWrongKeyType if the JWK isn't an oct JWK and ErrInvalidKey if the
+// key is empty or malformed.
+func (k JWK) Symmetric() ([]byte, error) {
+	if k.KeyType != KeyTypeOct {
+		return []byte{}, ErrWrongKeyType
+	}
+
+	key, err := encoding.DecodeString(k.K)
+	if err != nil || len(key) == 0 {
+		return []byte{}, ErrInvalidKey
+	}
+
+	return key, nil
+}
+
+// NewX25519Public returns the OKP JWK of the X25519 public key.
+func NewX25519Public(public [32]byte) JWK {
+	return JWK{KeyType: KeyTypeOKP, Curve: CurveX25519, X: encoding.EncodeToString(public[:])}
+}
+
+// NewX25519 returns the OKP JWK of the X25519 private key (which includes its
+// public key).
+func NewX25519(private [32]byte) JWK {
+	key := NewX25519Public(x25519.PublicKey(private))
+	key.D = encoding.EncodeToString(private[:])
+
+	return key
+}
+
+// X25519Public returns the public key of an X25519 JWK (which may contain the
+// private key as well).
+// Returns ErrWrongKeyType if the JWK isn't an X25519 JWK and ErrInvalidKey if
+// it's malformed.
+func (k JWK) X25519Public() ([32]byte, error) {
+	return k.publicKey(CurveX25519)
+}
+
+// X25519 returns the private key of an X25519 JWK.
+// Returns ErrWrongKeyType if the JWK isn't an X25519 JWK, ErrNoPrivateKey if it
+// only contains the public key and ErrInvalidKey if it's malformed or if the
+// public key doesn't belong to the private key.
+func (k JWK) X25519() ([32]byte, error) {
+	public, private, err := k.keyPair(CurveX25519)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	computed := x25519.PublicKey(private)
+	if subtle.ConstantTimeCompare(computed[:], public[:]) != 1 {
+		clear(private[:])
+		return [32]byte{}, ErrInvalidKey
+	}
+
+	return private, nil
+}
+
+// NewEd25519Public returns the OKP JWK of the Ed25519 public key.
+func NewEd25519Public(public [32]byte) JWK {
+	return JWK{KeyType: KeyTypeOKP, Curve: CurveEd25519, X: encoding.EncodeToString(public[:])}
+}
+
+// NewEd25519 returns the OKP JWK of the Ed25519 private key (the seed followed
+// by the public key as in crypto/ed25519).
+func NewEd25519(private [64]byte) JWK {
+	key := NewEd25519Public([32]byte(private[32:]))
+	key.D = encoding.EncodeToString(private[:32])
+
+	return key
+}
+
+// Ed25519Public returns the public key of an Ed25519 JWK (which may contain the
+// private key as well).
+// Returns ErrWrongKeyType if the JWK isn't an Ed25519 JWK and ErrInvalidKey if
+// it's malformed.
+func (k JWK) Ed25519Public() ([32]byte, error) {
+	return k.publicKey(CurveEd25519)
+}
+
+// Ed25519 returns the private key (the seed followed by the public key as in
+// crypto/ed25519) of an Ed25519 JWK.
+// Returns ErrWrongKeyType if the JWK isn't an Ed25519 JWK, ErrNoPrivateKey if
+// it only contains the public key and ErrInvalidKey if it's malformed or if the
+// public key doesn't belong to the seed.
+func (k JWK) Ed25519() ([64]byte, error) {
+	public, seed, err := k.keyPair(CurveEd25519)
+	if err != nil {
+		return [64]byte{}, err
+	}
+	defer clear(seed[:])
+
+	private := ed25519.NewKeyFromSeed(seed[:])
+	defer clear(private)
+
+	if subtle.ConstantTimeCompare(private[32:], public[:]) != 1 {
+		return [64]byte{}, ErrInvalidKey
+	}
+
+	return [64]byte(private), nil
+}
+
+// Public returns the JWK without the private key (and unchanged if it's an oct
+// JWK given that symmetric keys have no public part).
+func (k JWK) Public() JWK {
+	if k.KeyType == KeyTypeOKP {
+		k.D = ""
+	}
+
+	return k
+}
+
+// Thumbprint returns the base64url encoded SHA-256 thumbprint of the JWK as
+// specified in https://datatracker.ietf.org/doc/html/rfc7638 (and RFC 8037 for
+// OKP keys). The thumbprint only covers the required members so that it's
+// the same for a private key and its public key.
+// Returns an error if the JWK is invalid.
+func (k JWK) Thumbprint() (string, error) {
+	err := k.Validate()
+	if err != nil {
+		return "", err
+	}
+
+	// The required members in lexicographic order without whitespace. The
+	// values are base64url or fixed strings so that they don't need escaping.
+	var canonical string
+	if k.KeyType == KeyTypeOct {
+		canonical = `{"k":"` + k.K + `","kty":"oct"}`
+	} else {
+		canonical = `{"crv":"` + k.Curve + `","kty":"OKP","x":"` + k.X + `"}`
+	}
+
+	sum := sha256.Sum256([]byte(canonical))
+
+	return encoding.EncodeToString(sum[:]), nil
+}
+
+// publicKey decodes the public key of an OKP JWK with the curve.
+// Returns ErrWrongKeyType if the JWK has another type or curve and
+// ErrInvalidKey if the public key is malformed.
+func (k JWK) publicKey(curve string) ([32]byte, error) {
+	if k.KeyType != KeyTypeOKP || k.Curve != curve || (curve != CurveX25519 && curve != CurveEd25519) {
+		return [32]byte{}, ErrWrongKeyType
+	}
+
+	public, err := encoding.DecodeString(k.X)
+	if err != nil || len(public) != 32 {
+		return [32]byte{}, ErrInvalidKey
+	}
+
+	return [32]byte(public), nil
+}
+
+// keyPair decodes the public and private key of an OKP JWK with the curve.
+// Returns ErrWrongKeyType if the JWK has another type or curve,
+// ErrNoPrivateKey if it has no private key and ErrInvalidKey if a key is
+// malformed.
+func (k JWK) keyPair(curve string) ([32]byte, [32]byte, error) {
+	public, err := k.publicKey(curve)
+	if err != nil {
+		return [32]byte{}, [32]byte{}, err
+	}
+
+	if k.D == "" {
+		return [32]byte{}, [32]byte{}, ErrNoPrivateKey
+	}
+
+	private, err := encoding.DecodeString(k.D)
+	if err != nil || len(private) != 32 {
+		clear(private)
+		return [32]byte{}, [32]byte{}, ErrInvalidKey
+	}
+	defer clear(private)
+
+	return public, [32]byte(private), nil
+}
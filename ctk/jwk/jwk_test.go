@@ -0,0 +1,220 @@
+package jwk_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/jwk"
+)
+
+// ed25519Key is the Ed25519 key of https://datatracker.ietf.org/doc/html/rfc8037#appendix-A.1.
+const ed25519Key = `{"kty":"OKP","crv":"Ed25519",
+	"d":"nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A",
+	"x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`
+
+// equal reports whether the JWKs are equal.
+func equal(a, b jwk.JWK) bool {
+	return slices.Equal(a.KeyOps, b.KeyOps) && a.KeyType == b.KeyType && a.Curve == b.Curve &&
+		a.K == b.K && a.X == b.X && a.D == b.D && a.KeyID == b.KeyID && a.Algorithm == b.Algorithm && a.Use == b.Use
+}
+
+func TestEd25519(t *testing.T) {
+	t.Run("RFC 8037 Test Vectors - Appendix A.1 - A.3", func(t *testing.T) {
+		t.Parallel()
+
+		key, err := jwk.Parse([]byte(ed25519Key))
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		private, err := key.Ed25519()
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		seed := []byte{
+			0x9d, 0x61, 0xb1, 0x9d, 0xef, 0xfd, 0x5a, 0x60, 0xba, 0x84, 0x4a, 0xf4, 0x92, 0xec, 0x2c, 0xc4,
+			0x44, 0x49, 0xc5, 0x69, 0x7b, 0x32, 0x69, 0x19, 0x70, 0x3b, 0xac, 0x03, 0x1c, 0xae, 0x7f, 0x60,
+		}
+		want := ed25519.NewKeyFromSeed(seed)
+		if !bytes.Equal(private[:], want) {
+			t.Errorf("want %x, got %x", want, private)
+		}
+
+		if got := jwk.NewEd25519(private); !equal(got, key) {
+			t.Errorf("want %v, got %v", key, got)
+		}
+
+		thumbprint, err := key.Thumbprint()
+		if wantThumbprint := "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k"; thumbprint != wantThumbprint || err != nil {
+			t.Errorf("want %v, got %v (error %v)", wantThumbprint, thumbprint, err)
+		}
+	})
+
+	t.Run("Public", func(t *testing.T) {
+		t.Parallel()
+
+		public, private, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		key := jwk.NewEd25519([64]byte(private)).Public()
+		if !equal(key, jwk.NewEd25519Public([32]byte(public))) {
+			t.Errorf("want %v, got %v", jwk.NewEd25519Public([32]byte(public)), key)
+		}
+
+		got, err := key.Ed25519Public()
+		if !bytes.Equal(got[:], public) || err != nil {
+			t.Errorf("want %x, got %x (error %v)", public, got, err)
+		}
+
+		_, err = key.Ed25519()
+		if !errors.Is(err, jwk.ErrNoPrivateKey) {
+			t.Errorf("want error %v, got %v", jwk.ErrNoPrivateKey, err)
+		}
+	})
+}
+
+func TestX25519(t *testing.T) {
+	t.Run("RFC 8037 Test Vectors - Appendix A.6", func(t *testing.T) {
+		t.Parallel()
+
+		key, err := jwk.Parse([]byte(`{"kty":"OKP","crv":"X25519",
+			"d":"dwdtCnMYpX08FsFyUbJmRd9ML4frwJkqsXf7pR25LCo",
+			"x":"hSDwCYkwp1R0i33ctD73Wg2_Og0mOBr066SpjqqbTmo"}`))
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		private, err := key.X25519()
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if got := jwk.NewX25519(private); !equal(got, key) {
+			t.Errorf("want %v, got %v", key, got)
+		}
+
+		public, err := key.X25519Public()
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if got := jwk.NewX25519Public(public); !equal(got, key.Public()) {
+			t.Errorf("want %v, got %v", key.Public(), got)
+		}
+	})
+
+	t.Run("Mismatched Public Key", func(t *testing.T) {
+		t.Parallel()
+
+		key := jwk.NewX25519([32]byte{8})
+		key.X = jwk.NewX25519([32]byte{16}).X
+
+		_, err := key.X25519()
+		if !errors.Is(err, jwk.ErrInvalidKey) {
+			t.Errorf("want error %v, got %v", jwk.ErrInvalidKey, err)
+		}
+	})
+}
+
+func TestSymmetric(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	data, err := json.Marshal(jwk.NewSymmetric(key))
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	want := `{"kty":"oct","k":"MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY"}`
+	if string(data) != want {
+		t.Errorf("want %v, got %s", want, data)
+	}
+
+	parsed, err := jwk.Parse(data)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	got, err := parsed.Symmetric()
+	if !bytes.Equal(got, key) || err != nil {
+		t.Errorf("want %x, got %x (error %v)", key, got, err)
+	}
+
+	if got := parsed.Public(); !equal(got, parsed) {
+		t.Errorf("want %v, got %v", parsed, got)
+	}
+}
+
+func TestThumbprint(t *testing.T) {
+	private, err := jwk.Parse([]byte(ed25519Key))
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	// The optional members and the private key don't affect the thumbprint.
+	public := private.Public()
+	public.KeyID = "key-1"
+	public.Use = "sig"
+
+	want, _ := private.Thumbprint()
+	got, err := public.Thumbprint()
+	if got != want || err != nil {
+		t.Errorf("want %v, got %v (error %v)", want, got, err)
+	}
+}
+
+func TestSet(t *testing.T) {
+	var set jwk.Set
+	err := json.Unmarshal([]byte(`{"keys":[
+		{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo","kid":"a","use":"sig"},
+		{"kty":"oct","k":"AAEC","kid":"b","key_ops":["encrypt","decrypt"]}
+	]}`), &set)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	key, ok := set.Lookup("b")
+	if !ok || key.KeyType != jwk.KeyTypeOct || len(key.KeyOps) != 2 {
+		t.Errorf("want %v, got %v (found %v)", "b", key, ok)
+	}
+
+	_, ok = set.Lookup("c")
+	if ok {
+		t.Errorf("want %v, got %v", false, ok)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		data string
+		want error
+	}{
+		"Malformed JSON":          {`{"kty":`, jwk.ErrInvalidKey},
+		"Missing Key Type":        {`{"k":"AAEC"}`, jwk.ErrWrongKeyType},
+		"Unsupported Key Type":    {`{"kty":"RSA","n":"AQAB","e":"AQAB"}`, jwk.ErrWrongKeyType},
+		"Unsupported Curve":       {`{"kty":"OKP","crv":"X448","x":"AAEC"}`, jwk.ErrWrongKeyType},
+		"Empty Symmetric Key":     {`{"kty":"oct","k":""}`, jwk.ErrInvalidKey},
+		"Padded Symmetric Key":    {`{"kty":"oct","k":"AAE="}`, jwk.ErrInvalidKey},
+		"Short Public Key":        {`{"kty":"OKP","crv":"Ed25519","x":"AAEC"}`, jwk.ErrInvalidKey},
+		"Short Private Key":       {`{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo","d":"AAEC"}`, jwk.ErrInvalidKey},
+		"Mismatched Private Key":  {`{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo","d":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"}`, jwk.ErrInvalidKey},
+		"Standard Base64 Encoded": {`{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`, jwk.ErrInvalidKey},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := jwk.Parse([]byte(tc.data))
+			if !errors.Is(err, tc.want) {
+				t.Errorf("%v: want error %v, got %v", name, tc.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,735 @@
+// Package keystore implements a passphrase protected store of named keys.
+//
+// The keys are kept in a single file that's encrypted via XChaCha20-Poly1305
+// with a key that's derived from the passphrase via Argon2id. Every key can
+// have multiple versions so that it can be rotated while older versions stay
+// available for decryption.
+//
+// Instead of a passphrase a kms.KeyProvider can protect the keystore in which
+// case a random encryption key is wrapped by the provider.
+//
+// WithLogger logs the keystore's events (e.g. failed unlocks and added or
+// rotated keys). Key material and passphrases are never logged.
+//
+// A Keystore holds an exclusive lock on the file (via a lock file next to it)
+// until it's closed.
+package keystore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/pmuens/ctk-go/ctk/argon2"
+	"github.com/pmuens/ctk-go/ctk/clock"
+	"github.com/pmuens/ctk-go/ctk/kms"
+	"github.com/pmuens/ctk-go/ctk/logging"
+	"github.com/pmuens/ctk-go/ctk/random"
+	"github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
+)
+
+const (
+	// ErrExists is returned if a keystore should be created at a path where a
+	// file already exists.
+	ErrExists = Error("keystore already exists")
+
+	// ErrInvalidPassphrase is returned if the keystore can't be decrypted with
+	// the passphrase (or if it was tampered with).
+	ErrInvalidPassphrase = Error("invalid passphrase")
+
+	// ErrInvalidFormat is returned if the keystore file is malformed.
+	ErrInvalidFormat = Error("invalid keystore format")
+
+	// ErrLocked is returned if the keystore is locked by another process.
+	ErrLocked = Error("keystore is locked")
+
+	// ErrClosed is returned if the keystore is used after it was closed.
+	ErrClosed = Error("keystore is closed")
+
+	// ErrKeyNotFound is returned if there's no key with the given name.
+	ErrKeyNotFound = Error("key not found")
+
+	// ErrKeyExists is returned if a key with the given name already exists.
+	ErrKeyExists = Error("key already exists")
+
+	// ErrProtectionMismatch is returned if a passphrase protected keystore is
+	// opened with a key provider or vice versa.
+	ErrProtectionMismatch = Error("keystore uses a different protection")
+
+	// ErrParamsTooLarge is returned if the Argon2id parameters exceed
+	// MaxMemory, MaxTime or MaxParallelism.
+	ErrParamsTooLarge = Error("KDF parameters exceed the maximums")
+)
+
+// The maximum Argon2id parameters. Keystore files with larger parameters are
+// rejected as malformed before the key is derived so that a tampered file can't
+// make Open allocate or compute an arbitrary amount.
+const (
+	// MaxMemory is the maximum memory (in KiB), i.e. 4 GiB.
+	MaxMemory = 4 * 1024 * 1024
+
+	// MaxTime is the maximum number of passes over the memory.
+	MaxTime = 64
+
+	// MaxParallelism is the maximum number of lanes.
+	MaxParallelism = 255
+)
+
+// FormatVersion is the version of the keystore file format.
+const FormatVersion = 1
+
+// KeySize is the size (in bytes) of keys generated by the keystore.
+const KeySize = 32
+
+// saltSize is the size (in bytes) of the Argon2id salt.
+const saltSize = 16
+
+// lockSuffix is appended to the keystore path to get the path of the lock file.
+const lockSuffix = ".lock"
+
+// Key is a version of a named key.
+type Key struct {
+	// Version is the version of the key (starting at 1).
+	Version int `json:"version"`
+
+	// Material is the key material.
+	Material []byte `json:"material"`
+
+	// Created is the time the key version was added.
+	Created time.Time `json:"created"`
+}
+
+// Format implements the fmt.Formatter interface so that formatting a key (with
+// any verb) doesn't reveal its material.
+func (k Key) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, "{Version:%v Material:%v Created:%v}", k.Version, logging.Redacted, k.Created)
+}
+
+// LogValue implements the slog.LogValuer interface so that logging a key
+// doesn't reveal its material.
+func (k Key) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("version", k.Version),
+		slog.String("material", logging.Redacted),
+		slog.Time("created", k.Created),
+	)
+}
+
+// kdfParams are the (public) parameters used to derive the encryption key.
+type kdfParams struct {
+	Time        uint32 `json:"time"`
+	Memory      uint32 `json:"memory"`
+	Parallelism uint32 `json:"parallelism"`
+	Salt        []byte `json:"salt"`
+}
+
+// bounded reports whether the parameters don't exceed the maximums.
+func (p kdfParams) bounded() bool {
+	return p.Memory <= MaxMemory && p.Time <= MaxTime && p.Parallelism <= MaxParallelism
+}
+
+// kmsParams are the (public) parameters used to unwrap the encryption key via
+// a key provider.
+type kmsParams struct {
+	KeyID      string `json:"key_id"`
+	WrappedKey []byte `json:"wrapped_key"`
+}
+
+// header is the unencrypted part of the keystore file which is authenticated
+// as additional authenticated data (AAD).
+// Exactly one of KDF and KMS is set.
+type header struct {
+	Version int        `json:"version"`
+	KDF     *kdfParams `json:"kdf,omitempty"`
+	KMS     *kmsParams `json:"kms,omitempty"`
+}
+
+// file is the structure of the keystore file.
+type file struct {
+	header
+
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Keystore is an opened keystore.
+type Keystore struct {
+	// path is the path of the keystore file.
+	path string
+
+	// header is the header of the keystore file.
+	header header
+
+	// key is the encryption key derived from the passphrase.
+	key [32]byte
+
+	// keys maps key names to their versions (oldest first).
+	keys map[string][]Key
+
+	// lock is the held lock file (nil once the keystore is closed).
+	lock *os.File
+
+	// logger receives the keystore's events.
+	logger logging.Logger
+
+	// clock tells the creation time of new key versions.
+	clock clock.Clock
+}
+
+// options are the configured options of a keystore.
+type options struct {
+	// logger receives the keystore's events.
+	logger logging.Logger
+
+	// clock tells the creation time of new key versions.
+	clock clock.Clock
+}
+
+// Option configures a keystore.
+type Option func(*options)
+
+// WithLogger logs the keystore's events to the logger.
+func WithLogger(logger logging.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithClock sets the clock that tells the creation time of new key versions
+// (clock.System by default), e.g. to test key rotation schedules without
+// sleeping.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = clock.OrSystem(c)
+	}
+}
+
+// newOptions applies the options to the defaults.
+func newOptions(opts []Option) options {
+	o := options{logger: logging.Discard, clock: clock.System}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// Create creates a new, empty keystore at path which is protected by the
+// passphrase. The parameters are used to derive the encryption key via Argon2id
+// (its variant, key length, secret and associated data are ignored).
+// Returns ErrParamsTooLarge if the parameters exceed the maximums and an error
+// if a file already exists at path.
+func Create(path string, passphrase []byte, params argon2.Params, opts ...Option) (*Keystore, error) {
+	return CreateContext(context.Background(), path, passphrase, params, opts...)
+}
+
+// CreateContext is Create which stops deriving the encryption key once the
+// context is done.
+// Returns the context's error if it's done before the keystore was created.
+func CreateContext(ctx context.Context, path string, passphrase []byte, params argon2.Params, opts ...Option) (*Keystore, error) {
+	return create(path, newOptions(opts), func() (header, [32]byte, error) {
+		salt := make([]byte, saltSize)
+
+		err := random.Read(salt)
+		if err != nil {
+			return header{}, [32]byte{}, err
+		}
+
+		kdf := &kdfParams{
+			Time:        params.Time,
+			Memory:      params.Memory,
+			Parallelism: params.Parallelism,
+			Salt:        salt,
+		}
+		if !kdf.bounded() {
+			return header{}, [32]byte{}, ErrParamsTooLarge
+		}
+
+		key, err := deriveKey(ctx, passphrase, *kdf)
+		if err != nil {
+			return header{}, [32]byte{}, err
+		}
+
+		return header{Version: FormatVersion, KDF: kdf}, key, nil
+	})
+}
+
+// CreateWithProvider creates a new, empty keystore at path which is protected
+// by a random encryption key that's wrapped by the provider under the key with
+// the key ID.
+// Returns an error if a file already exists at path.
+func CreateWithProvider(ctx context.Context, path string, provider kms.KeyProvider, keyID string, opts ...Option) (*Keystore, error) {
+	return create(path, newOptions(opts), func() (header, [32]byte, error) {
+		var key [32]byte
+
+		err := random.Read(key[:])
+		if err != nil {
+			return header{}, [32]byte{}, err
+		}
+
+		wrapped, err := provider.WrapKey(ctx, keyID, key[:])
+		if err != nil {
+			return header{}, [32]byte{}, err
+		}
+
+		return header{
+			Version: FormatVersion,
+			KMS:     &kmsParams{KeyID: keyID, WrappedKey: wrapped},
+		}, key, nil
+	})
+}
+
+// create creates a new, empty keystore at path with the header and encryption
+// key returned by protect.
+func create(path string, o options, protect func() (header, [32]byte, error)) (*Keystore, error) {
+	l, err := lock(path + lockSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = os.Stat(path)
+	if err == nil {
+		unlock(l)
+		return nil, ErrExists
+	}
+
+	h, key, err := protect()
+	if err != nil {
+		unlock(l)
+		return nil, err
+	}
+
+	k := &Keystore{
+		path:   path,
+		header: h,
+		key:    key,
+		keys:   make(map[string][]Key),
+		lock:   l,
+		logger: o.logger,
+		clock:  o.clock,
+	}
+
+	err = k.save()
+	if err != nil {
+		unlock(l)
+		return nil, err
+	}
+
+	k.log(slog.LevelInfo, "keystore created", slog.String("protection", k.protection()))
+
+	return k, nil
+}
+
+// Open opens the keystore at path and decrypts it with the passphrase.
+// Returns an error if the keystore is locked, malformed or the passphrase is
+// invalid.
+func Open(path string, passphrase []byte, opts ...Option) (*Keystore, error) {
+	return OpenContext(context.Background(), path, passphrase, opts...)
+}
+
+// OpenContext is Open which stops deriving the encryption key (which takes a
+// while with strong Argon2id parameters) once the context is done.
+// Returns the context's error if it's done before the keystore was unlocked.
+func OpenContext(ctx context.Context, path string, passphrase []byte, opts ...Option) (*Keystore, error) {
+	return open(path, newOptions(opts), func(h header) ([32]byte, error) {
+		if h.KDF == nil {
+			return [32]byte{}, ErrProtectionMismatch
+		}
+
+		key, err := deriveKey(ctx, passphrase, *h.KDF)
+		if ctx.Err() != nil {
+			return [32]byte{}, ctx.Err()
+		}
+		if err != nil {
+			return [32]byte{}, ErrInvalidFormat
+		}
+
+		return key, nil
+	})
+}
+
+// OpenWithProvider opens the keystore at path and decrypts it with the
+// encryption key that's unwrapped by the provider.
+// Returns an error if the keystore is locked, malformed or the encryption key
+// can't be unwrapped.
+func OpenWithProvider(ctx context.Context, path string, provider kms.KeyProvider, opts ...Option) (*Keystore, error) {
+	return open(path, newOptions(opts), func(h header) ([32]byte, error) {
+		if h.KMS == nil {
+			return [32]byte{}, ErrProtectionMismatch
+		}
+
+		key, err := provider.UnwrapKey(ctx, h.KMS.KeyID, h.KMS.WrappedKey)
+		if err != nil {
+			return [32]byte{}, err
+		}
+		defer clear(key)
+
+		if len(key) != 32 {
+			return [32]byte{}, ErrInvalidFormat
+		}
+
+		return [32]byte(key), nil
+	})
+}
+
+// open locks, reads and decrypts the keystore file at path with the encryption
+// key returned by unprotect.
+func open(path string, o options, unprotect func(h header) ([32]byte, error)) (*Keystore, error) {
+	l, err := lock(path + lockSuffix)
+	if err != nil {
+		o.logger.LogAttrs(context.Background(), slog.LevelWarn, "keystore open failed", slog.String("path", path), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	k, err := read(path, unprotect)
+	if err != nil {
+		unlock(l)
+		o.logger.LogAttrs(context.Background(), slog.LevelWarn, "keystore open failed", slog.String("path", path), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	k.lock = l
+	k.logger = o.logger
+	k.clock = o.clock
+
+	k.log(slog.LevelInfo, "keystore opened", slog.String("protection", k.protection()), slog.Int("keys", len(k.keys)))
+
+	return k, nil
+}
+
+// read reads and decrypts the keystore file at path.
+func read(path string, unprotect func(h header) ([32]byte, error)) (*Keystore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f file
+
+	err = json.Unmarshal(data, &f)
+	if err != nil || f.Version != FormatVersion || (f.KDF == nil) == (f.KMS == nil) ||
+		(f.KDF != nil && !f.KDF.bounded()) || len(f.Nonce) != 24 || len(f.Ciphertext) < 16 {
+		return nil, ErrInvalidFormat
+	}
+
+	key, err := unprotect(f.header)
+	if err != nil {
+		return nil, err
+	}
+
+	aad, err := json.Marshal(f.header)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext := f.Ciphertext[:len(f.Ciphertext)-16]
+	tag := [16]byte(f.Ciphertext[len(f.Ciphertext)-16:])
+
+	xchaPoly := xchacha20poly1305.NewXChaCha20Poly1305(key, [24]byte(f.Nonce))
+	plaintext, err := xchaPoly.Decrypt(ciphertext, aad, tag)
+	if err != nil {
+		return nil, ErrInvalidPassphrase
+	}
+
+	keys := make(map[string][]Key)
+
+	err = json.Unmarshal(plaintext, &keys)
+	clear(plaintext)
+	if err != nil {
+		return nil, ErrInvalidFormat
+	}
+
+	return &Keystore{
+		path:   path,
+		header: f.header,
+		key:    key,
+		keys:   keys,
+	}, nil
+}
+
+// Close wipes the keys from memory and releases the lock.
+func (k *Keystore) Close() error {
+	if k.lock == nil {
+		return ErrClosed
+	}
+
+	for _, versions := range k.keys {
+		for _, v := range versions {
+			clear(v.Material)
+		}
+	}
+
+	k.keys = nil
+	k.key = [32]byte{}
+
+	l := k.lock
+	k.lock = nil
+
+	k.log(slog.LevelDebug, "keystore closed")
+
+	return unlock(l)
+}
+
+// Names returns the sorted names of all keys.
+// Returns an error if the keystore was closed.
+func (k *Keystore) Names() ([]string, error) {
+	if k.lock == nil {
+		return []string{}, ErrClosed
+	}
+
+	return slices.Sorted(maps.Keys(k.keys)), nil
+}
+
+// AddKey adds the key material under the name and persists the keystore.
+// A random key of KeySize bytes is generated if the material is empty.
+// Returns an error if a key with the name already exists.
+func (k *Keystore) AddKey(name string, material []byte) error {
+	if k.lock == nil {
+		return ErrClosed
+	}
+
+	if _, ok := k.keys[name]; ok {
+		return ErrKeyExists
+	}
+
+	key, err := newKey(1, material, k.clock.Now())
+	if err != nil {
+		return err
+	}
+
+	k.keys[name] = []Key{key}
+
+	err = k.save()
+	if err != nil {
+		delete(k.keys, name)
+		return err
+	}
+
+	k.log(slog.LevelInfo, "key added", slog.String("name", name), slog.Int("version", key.Version))
+
+	return nil
+}
+
+// GetKey returns the latest version of the key with the name.
+// Returns an error if there's no such key or if the keystore was closed.
+func (k *Keystore) GetKey(name string) (Key, error) {
+	if k.lock == nil {
+		return Key{}, ErrClosed
+	}
+
+	versions, ok := k.keys[name]
+	if !ok {
+		return Key{}, ErrKeyNotFound
+	}
+
+	key := versions[len(versions)-1]
+	k.log(slog.LevelDebug, "key accessed", slog.String("name", name), slog.Int("version", key.Version))
+
+	return cloneKey(key), nil
+}
+
+// GetKeyVersion returns the version of the key with the name.
+// Returns an error if there's no such key or version or if the keystore was
+// closed.
+func (k *Keystore) GetKeyVersion(name string, version int) (Key, error) {
+	if k.lock == nil {
+		return Key{}, ErrClosed
+	}
+
+	for _, v := range k.keys[name] {
+		if v.Version == version {
+			k.log(slog.LevelDebug, "key accessed", slog.String("name", name), slog.Int("version", v.Version))
+			return cloneKey(v), nil
+		}
+	}
+
+	return Key{}, ErrKeyNotFound
+}
+
+// Versions returns all versions of the key with the name (oldest first).
+// Returns an error if there's no such key or if the keystore was closed.
+func (k *Keystore) Versions(name string) ([]Key, error) {
+	if k.lock == nil {
+		return []Key{}, ErrClosed
+	}
+
+	versions, ok := k.keys[name]
+	if !ok {
+		return []Key{}, ErrKeyNotFound
+	}
+
+	result := make([]Key, 0, len(versions))
+	for _, v := range versions {
+		result = append(result, cloneKey(v))
+	}
+
+	return result, nil
+}
+
+// Rotate adds a new, randomly generated version of the key with the name and
+// persists the keystore. Older versions are kept so that data encrypted with
+// them can still be decrypted.
+// Returns an error if there's no such key.
+func (k *Keystore) Rotate(name string) (Key, error) {
+	if k.lock == nil {
+		return Key{}, ErrClosed
+	}
+
+	versions, ok := k.keys[name]
+	if !ok {
+		return Key{}, ErrKeyNotFound
+	}
+
+	key, err := newKey(versions[len(versions)-1].Version+1, nil, k.clock.Now())
+	if err != nil {
+		return Key{}, err
+	}
+
+	k.keys[name] = append(versions, key)
+
+	err = k.save()
+	if err != nil {
+		k.keys[name] = versions
+		return Key{}, err
+	}
+
+	k.log(slog.LevelInfo, "key rotated", slog.String("name", name), slog.Int("version", key.Version))
+
+	return cloneKey(key), nil
+}
+
+// protection returns how the keystore is protected ("passphrase" or "kms").
+func (k *Keystore) protection() string {
+	if k.header.KMS != nil {
+		return "kms"
+	}
+
+	return "passphrase"
+}
+
+// log logs the event with the keystore's path.
+func (k *Keystore) log(level slog.Level, msg string, attrs ...slog.Attr) {
+	if k.logger == nil {
+		return
+	}
+
+	k.logger.LogAttrs(context.Background(), level, msg, append([]slog.Attr{slog.String("path", k.path)}, attrs...)...)
+}
+
+// save encrypts the keys and atomically replaces the keystore file.
+func (k *Keystore) save() error {
+	plaintext, err := json.Marshal(k.keys)
+	if err != nil {
+		return err
+	}
+	defer clear(plaintext)
+
+	aad, err := json.Marshal(k.header)
+	if err != nil {
+		return err
+	}
+
+	// A fresh nonce is used every time the keystore is saved.
+	var nonce [24]byte
+
+	err = random.Read(nonce[:])
+	if err != nil {
+		return err
+	}
+
+	xchaPoly := xchacha20poly1305.NewXChaCha20Poly1305(k.key, nonce)
+	ciphertext, tag := xchaPoly.Encrypt(plaintext, aad)
+
+	data, err := json.MarshalIndent(file{
+		header:     k.header,
+		Nonce:      nonce[:],
+		Ciphertext: append(ciphertext, tag[:]...),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFile(k.path, data)
+}
+
+// writeFile atomically replaces the file at path by writing the data to a
+// temporary file which is then renamed.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		err = tmp.Chmod(0o600)
+	}
+
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return nil
+}
+
+// deriveKey derives the encryption key from the passphrase via Argon2id.
+func deriveKey(ctx context.Context, passphrase []byte, params kdfParams) ([32]byte, error) {
+	key, err := argon2.KeyContext(ctx, passphrase, params.Salt, argon2.Params{
+		Variant:     argon2.Argon2id,
+		Time:        params.Time,
+		Memory:      params.Memory,
+		Parallelism: params.Parallelism,
+		KeyLength:   32,
+	})
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	return [32]byte(key), nil
+}
+
+// newKey creates a new key version with a copy of the material (or random
+// material if it's empty) that was created at the time.
+func newKey(version int, material []byte, created time.Time) (Key, error) {
+	if len(material) == 0 {
+		material = make([]byte, KeySize)
+
+		err := random.Read(material)
+		if err != nil {
+			return Key{}, err
+		}
+	} else {
+		material = slices.Clone(material)
+	}
+
+	return Key{
+		Version:  version,
+		Material: material,
+		Created:  created.UTC().Truncate(time.Second),
+	}, nil
+}
+
+// cloneKey returns a copy of the key so that callers can't modify the keystore.
+func cloneKey(k Key) Key {
+	k.Material = slices.Clone(k.Material)
+	return k
+}
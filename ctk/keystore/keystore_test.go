@@ -0,0 +1,401 @@
+package keystore_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pmuens/ctk-go/ctk/argon2"
+	"github.com/pmuens/ctk-go/ctk/clock"
+	"github.com/pmuens/ctk-go/ctk/keystore"
+	"github.com/pmuens/ctk-go/ctk/kms"
+	"github.com/pmuens/ctk-go/ctk/logging"
+)
+
+// params are cheap Argon2id parameters to keep the tests fast.
+var params = argon2.Params{Time: 1, Memory: 64, Parallelism: 1}
+
+var passphrase = []byte("correct horse battery staple")
+
+func TestKeystore(t *testing.T) {
+	t.Run("Create + Open", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "keystore.json")
+
+		ks, err := keystore.Create(path, passphrase, params)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		material := []byte{0x01, 0x02, 0x03, 0x04}
+		ks.AddKey("imported", material)
+		ks.AddKey("generated", nil)
+		ks.Close()
+
+		ks, err = keystore.Open(path, passphrase)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+		defer ks.Close()
+
+		gotNames, _ := ks.Names()
+		wantNames := []string{"generated", "imported"}
+
+		if !slices.Equal(gotNames, wantNames) {
+			t.Errorf("want %v, got %v", wantNames, gotNames)
+		}
+
+		imported, _ := ks.GetKey("imported")
+
+		if !slices.Equal(imported.Material, material) {
+			t.Errorf("want %v, got %v", material, imported.Material)
+		}
+
+		generated, _ := ks.GetKey("generated")
+
+		if len(generated.Material) != keystore.KeySize {
+			t.Errorf("want length %v, got %v", keystore.KeySize, len(generated.Material))
+		}
+	})
+
+	t.Run("Rotate", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "keystore.json")
+
+		ks, _ := keystore.Create(path, passphrase, params)
+		ks.AddKey("data", nil)
+		first, _ := ks.GetKey("data")
+
+		rotated, err := ks.Rotate("data")
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+		ks.Close()
+
+		ks, _ = keystore.Open(path, passphrase)
+		defer ks.Close()
+
+		latest, _ := ks.GetKey("data")
+
+		if latest.Version != 2 || !slices.Equal(latest.Material, rotated.Material) {
+			t.Errorf("want %v, got %v", rotated, latest)
+		}
+
+		old, _ := ks.GetKeyVersion("data", 1)
+
+		if !slices.Equal(old.Material, first.Material) {
+			t.Errorf("want %v, got %v", first.Material, old.Material)
+		}
+
+		versions, _ := ks.Versions("data")
+
+		if len(versions) != 2 {
+			t.Errorf("want %v versions, got %v", 2, len(versions))
+		}
+	})
+
+	t.Run("Clock", func(t *testing.T) {
+		t.Parallel()
+
+		start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		c := clock.NewManual(start)
+
+		ks, _ := keystore.Create(filepath.Join(t.TempDir(), "keystore.json"), passphrase, params, keystore.WithClock(c))
+		defer ks.Close()
+
+		ks.AddKey("data", nil)
+		c.Advance(24 * time.Hour)
+		ks.Rotate("data")
+
+		versions, _ := ks.Versions("data")
+		got := []time.Time{}
+		for _, version := range versions {
+			got = append(got, version.Created)
+		}
+
+		want := []time.Time{start, start.Add(24 * time.Hour)}
+		if !slices.EqualFunc(got, want, time.Time.Equal) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Invalid Passphrase", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "keystore.json")
+
+		ks, _ := keystore.Create(path, passphrase, params)
+		ks.Close()
+
+		_, err := keystore.Open(path, []byte("wrong"))
+
+		gotError := err
+		wantError := keystore.ErrInvalidPassphrase
+
+		if !errors.Is(gotError, wantError) {
+			t.Errorf("want error %v, got %v", wantError, gotError)
+		}
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "keystore.json")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := keystore.CreateContext(ctx, path, passphrase, params)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("want error %v, got %v", context.Canceled, err)
+		}
+
+		ks, err := keystore.CreateContext(context.Background(), path, passphrase, params)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+		ks.Close()
+
+		_, err = keystore.OpenContext(ctx, path, passphrase)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("want error %v, got %v", context.Canceled, err)
+		}
+
+		ks, err = keystore.OpenContext(context.Background(), path, passphrase)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+		ks.Close()
+	})
+
+	t.Run("Tampered Header", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "keystore.json")
+
+		ks, _ := keystore.Create(path, passphrase, params)
+		ks.Close()
+
+		// Changing the KDF parameters changes the derived key as well as the AAD.
+		data, _ := os.ReadFile(path)
+		data = []byte(strings.Replace(string(data), `"time": 1`, `"time": 2`, 1))
+		os.WriteFile(path, data, 0o600)
+
+		_, err := keystore.Open(path, passphrase)
+
+		gotError := err
+		wantError := keystore.ErrInvalidPassphrase
+
+		if !errors.Is(gotError, wantError) {
+			t.Errorf("want error %v, got %v", wantError, gotError)
+		}
+	})
+
+	t.Run("Params Too Large", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "keystore.json")
+
+		large := params
+		large.Memory = keystore.MaxMemory + 1
+
+		_, err := keystore.Create(path, passphrase, large)
+		if !errors.Is(err, keystore.ErrParamsTooLarge) {
+			t.Errorf("want error %v, got %v", keystore.ErrParamsTooLarge, err)
+		}
+
+		ks, _ := keystore.Create(path, passphrase, params)
+		ks.Close()
+
+		// The parameters are checked before the (expensive) key derivation.
+		data, _ := os.ReadFile(path)
+		data = []byte(strings.Replace(string(data), `"memory": 64`, `"memory": 4294967295`, 1))
+		os.WriteFile(path, data, 0o600)
+
+		_, err = keystore.Open(path, passphrase)
+		if !errors.Is(err, keystore.ErrInvalidFormat) {
+			t.Errorf("want error %v, got %v", keystore.ErrInvalidFormat, err)
+		}
+	})
+
+	t.Run("Key Provider", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		path := filepath.Join(t.TempDir(), "keystore.json")
+
+		provider := kms.NewMemory()
+		provider.AddKey("master", [32]byte{0x01})
+
+		ks, err := keystore.CreateWithProvider(ctx, path, provider, "master")
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		ks.AddKey("data", nil)
+		ks.Close()
+
+		ks, err = keystore.OpenWithProvider(ctx, path, provider)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		gotNames, _ := ks.Names()
+		wantNames := []string{"data"}
+
+		if !slices.Equal(gotNames, wantNames) {
+			t.Errorf("want %v, got %v", wantNames, gotNames)
+		}
+
+		ks.Close()
+
+		_, err = keystore.Open(path, passphrase)
+
+		gotError := err
+		wantError := keystore.ErrProtectionMismatch
+
+		if !errors.Is(gotError, wantError) {
+			t.Errorf("want error %v, got %v", wantError, gotError)
+		}
+
+		other := kms.NewMemory()
+		other.AddKey("master", [32]byte{0x02})
+
+		_, err = keystore.OpenWithProvider(ctx, path, other)
+
+		if !errors.Is(err, kms.ErrUnwrap) {
+			t.Errorf("want error %v, got %v", kms.ErrUnwrap, err)
+		}
+	})
+
+	t.Run("Logger", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "keystore.json")
+
+		var buf bytes.Buffer
+		logger := slog.New(logging.Redact(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+		ks, err := keystore.Create(path, passphrase, params, keystore.WithLogger(logger))
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		material := []byte{0xde, 0xad, 0xbe, 0xef}
+		ks.AddKey("imported", material)
+		ks.Rotate("imported")
+
+		key, _ := ks.GetKey("imported")
+		logger.Info("application event", "key", key)
+		fmt.Fprintf(&buf, "%v %+v %x\n", key, key, key)
+		ks.Close()
+
+		keystore.Open(path, []byte("wrong"), keystore.WithLogger(logger))
+
+		for _, want := range []string{
+			`msg="keystore created"`,
+			`msg="key added" path=` + path + ` name=imported version=1`,
+			`msg="key rotated" path=` + path + ` name=imported version=2`,
+			`msg="key accessed"`,
+			`key.material=` + logging.Redacted,
+			`msg="keystore closed"`,
+			`msg="keystore open failed" path=` + path + ` error="invalid passphrase"`,
+		} {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("want %v in %v", want, buf.String())
+			}
+		}
+
+		for _, secret := range []string{"deadbeef", hex.EncodeToString(key.Material), "wrong"} {
+			if strings.Contains(buf.String(), secret) {
+				t.Errorf("want %v to be redacted in %v", secret, buf.String())
+			}
+		}
+	})
+
+	t.Run("Locked", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "keystore.json")
+
+		ks, _ := keystore.Create(path, passphrase, params)
+
+		_, err := keystore.Open(path, passphrase)
+
+		gotError := err
+		wantError := keystore.ErrLocked
+
+		if !errors.Is(gotError, wantError) {
+			t.Errorf("want error %v, got %v", wantError, gotError)
+		}
+
+		ks.Close()
+
+		ks, err = keystore.Open(path, passphrase)
+		if err != nil {
+			t.Errorf("want error %v, got %v", nil, err)
+		}
+		ks.Close()
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "keystore.json")
+
+		ks, _ := keystore.Create(path, passphrase, params)
+		ks.AddKey("data", nil)
+
+		tt := map[string]struct {
+			err  error
+			want error
+		}{
+			"Duplicate Key":  {err: ks.AddKey("data", nil), want: keystore.ErrKeyExists},
+			"Missing Key":    {err: second(ks.GetKey("missing")), want: keystore.ErrKeyNotFound},
+			"Rotate Missing": {err: second(ks.Rotate("missing")), want: keystore.ErrKeyNotFound},
+		}
+
+		ks.Close()
+
+		_, err := keystore.Create(path, passphrase, params)
+		tt["Already Exists"] = struct {
+			err  error
+			want error
+		}{err: err, want: keystore.ErrExists}
+
+		for name, err := range map[string]error{
+			"Closed":             ks.AddKey("other", nil),
+			"Closed Names":       second(ks.Names()),
+			"Closed Get":         second(ks.GetKey("data")),
+			"Closed Get Version": second(ks.GetKeyVersion("data", 1)),
+			"Closed Versions":    second(ks.Versions("data")),
+		} {
+			tt[name] = struct {
+				err  error
+				want error
+			}{err: err, want: keystore.ErrClosed}
+		}
+
+		for name, tc := range tt {
+			if !errors.Is(tc.err, tc.want) {
+				t.Errorf("%v: want error %v, got %v", name, tc.want, tc.err)
+			}
+		}
+	})
+}
+
+// second returns the second of two return values.
+func second[T any](_ T, err error) error {
+	return err
+}
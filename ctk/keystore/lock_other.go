@@ -0,0 +1,33 @@
+//go:build !unix
+
+package keystore
+
+import (
+	"errors"
+	"os"
+)
+
+// lock acquires an exclusive lock by creating the lock file at path.
+// Returns ErrLocked if the lock file already exists. Note that a lock file
+// left behind by a crashed process needs to be removed manually.
+func lock(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, ErrLocked
+		}
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// unlock releases the lock acquired via lock.
+func unlock(f *os.File) error {
+	err := f.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,35 @@
+//go:build unix
+
+package keystore
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lock acquires an exclusive, advisory lock on the lock file at path.
+// Returns ErrLocked if the lock is held by another process.
+func lock(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, ErrLocked
+		}
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// unlock releases the lock acquired via lock.
+func unlock(f *os.File) error {
+	// Closing the file releases the lock.
+	return f.Close()
+}
@@ -0,0 +1,59 @@
+// Package keytree implements the deterministic derivation of purpose-bound keys
+// from a single master key.
+//
+// Keys are addressed by slash separated paths (e.g. "app/db/encryption"). Every
+// path segment derives a child key from its parent key via HKDF-Expand
+// (SHA-256) with the segment as the info so that keys of different paths are
+// independent of each other:
+//
+//	child = HKDF-Expand(parent, "ctk-go keytree " | segment, 32)
+//
+// As the derivation is applied segment by segment, the key of a path can be
+// handed to a subsystem which can then derive the keys below it without
+// learning the master key or the keys of other branches (e.g. the key of
+// "app/db" derives "app/db/encryption" via the path "encryption").
+package keytree
+
+import (
+	"crypto/sha256"
+	"slices"
+	"strings"
+
+	"github.com/pmuens/ctk-go/ctk/hkdf"
+)
+
+const (
+	// ErrInvalidPath is returned if the path is empty or contains an empty
+	// segment (e.g. "a//b" or "/a").
+	ErrInvalidPath = Error("invalid path")
+)
+
+// Separator separates the segments of a path.
+const Separator = "/"
+
+// info is used for domain separation in the key derivation.
+var info = []byte("ctk-go keytree ")
+
+// Derive derives the key of the path from the master key. The master key needs
+// to be random (use a KDF such as Argon2 for passphrases).
+// Returns an error if the path is invalid.
+func Derive(master [32]byte, path string) ([32]byte, error) {
+	segments := strings.Split(path, Separator)
+	if slices.Contains(segments, "") {
+		return [32]byte{}, ErrInvalidPath
+	}
+
+	key := master
+	for _, segment := range segments {
+		key = child(key, segment)
+	}
+
+	return key, nil
+}
+
+// child derives the child key of the segment from the parent key.
+func child(parent [32]byte, segment string) [32]byte {
+	okm, _ := hkdf.Expand(sha256.New, parent[:], slices.Concat(info, []byte(segment)), 32)
+
+	return [32]byte(okm)
+}
@@ -0,0 +1,75 @@
+package keytree_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/keytree"
+)
+
+func TestKeyTree(t *testing.T) {
+	master := [32]byte{
+		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+		0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f,
+	}
+
+	t.Run("Derive", func(t *testing.T) {
+		t.Parallel()
+
+		got, err := keytree.Derive(master, "app/db/encryption")
+		want := [32]byte{
+			0x31, 0xcd, 0x09, 0xe8, 0x00, 0xb7, 0x1b, 0xc1, 0x31, 0x87, 0x5f, 0xe5, 0xa2, 0xb7, 0x6a, 0xcd,
+			0xd0, 0xbb, 0x00, 0x6e, 0x3a, 0xc5, 0x1b, 0x47, 0xa0, 0x7d, 0xef, 0xf5, 0x2c, 0xb7, 0xe9, 0xf6,
+		}
+
+		if got != want {
+			t.Errorf("want %v, got %v", want, got)
+		}
+
+		if !errors.Is(err, nil) {
+			t.Errorf("want error %v, got %v", nil, err)
+		}
+	})
+
+	t.Run("Subtree", func(t *testing.T) {
+		t.Parallel()
+
+		parent, _ := keytree.Derive(master, "app/db")
+		got, _ := keytree.Derive(parent, "encryption")
+		want, _ := keytree.Derive(master, "app/db/encryption")
+
+		if got != want {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Domain Separation", func(t *testing.T) {
+		t.Parallel()
+
+		paths := []string{"app", "app/db", "app/dbx", "appdb", "app/db/encryption", "app/db/signing", "db/app"}
+		seen := make(map[[32]byte]string)
+
+		for _, path := range paths {
+			key, err := keytree.Derive(master, path)
+			if err != nil {
+				t.Fatalf("%v: want error %v, got %v", path, nil, err)
+			}
+
+			if other, ok := seen[key]; ok {
+				t.Errorf("want different keys, got %v for %v and %v", key, other, path)
+			}
+			seen[key] = path
+		}
+	})
+
+	t.Run("Invalid Path", func(t *testing.T) {
+		t.Parallel()
+
+		for _, path := range []string{"", "/app", "app/", "app//db"} {
+			_, err := keytree.Derive(master, path)
+			if !errors.Is(err, keytree.ErrInvalidPath) {
+				t.Errorf("%q: want error %v, got %v", path, keytree.ErrInvalidPath, err)
+			}
+		}
+	})
+}
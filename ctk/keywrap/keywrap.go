@@ -0,0 +1,83 @@
+// Package keywrap implements the wrapping of (symmetric) keys under a
+// key-encryption key (KEK) using XChaCha20-Poly1305 with a random nonce that's
+// embedded in the wrapped key.
+//
+// Note that AES Key Wrap (RFC 3394) isn't supported given that the toolkit
+// doesn't implement AES.
+package keywrap
+
+import (
+	"github.com/pmuens/ctk-go/ctk/random"
+	"github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
+)
+
+const (
+	// ErrInvalidKeySize is returned if the key to be wrapped is too short.
+	ErrInvalidKeySize = Error("invalid key size")
+
+	// ErrInvalidWrappedKey is returned if the wrapped key is malformed or its
+	// tag is invalid.
+	ErrInvalidWrappedKey = Error("invalid wrapped key")
+)
+
+// MinKeySize is the minimum size (in bytes) of a key that can be wrapped.
+const MinKeySize = 16
+
+// NonceSize is the size (in bytes) of the nonce that's prepended to the wrapped key.
+const NonceSize = 24
+
+// TagSize is the size (in bytes) of the tag that's appended to the wrapped key.
+const TagSize = 16
+
+// Overhead is the number of bytes a wrapped key is longer than the key itself.
+const Overhead = NonceSize + TagSize
+
+// aad is the additional authenticated data that's used for domain separation so
+// that wrapped keys can't be confused with other ciphertexts created with the KEK.
+var aad = []byte("ctk-go keywrap")
+
+// Wrap encrypts the key under the key-encryption key (KEK).
+// The result is the nonce followed by the encrypted key and the tag.
+// Returns an error if the key is shorter than MinKeySize.
+func Wrap(kek [32]byte, key []byte) ([]byte, error) {
+	if len(key) < MinKeySize {
+		return []byte{}, ErrInvalidKeySize
+	}
+
+	var nonce [24]byte
+
+	err := random.Read(nonce[:])
+	if err != nil {
+		return []byte{}, err
+	}
+
+	xchaPoly := xchacha20poly1305.NewXChaCha20Poly1305(kek, nonce)
+	ciphertext, tag := xchaPoly.Encrypt(key, aad)
+
+	result := make([]byte, 0, len(key)+Overhead)
+	result = append(result, nonce[:]...)
+	result = append(result, ciphertext...)
+	result = append(result, tag[:]...)
+
+	return result, nil
+}
+
+// Unwrap decrypts the wrapped key with the key-encryption key (KEK).
+// Returns an error if the wrapped key is malformed or its tag is invalid.
+func Unwrap(kek [32]byte, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < MinKeySize+Overhead {
+		return []byte{}, ErrInvalidWrappedKey
+	}
+
+	nonce := [24]byte(wrapped[0:NonceSize])
+	ciphertext := wrapped[NonceSize : len(wrapped)-TagSize]
+	tag := [16]byte(wrapped[len(wrapped)-TagSize:])
+
+	xchaPoly := xchacha20poly1305.NewXChaCha20Poly1305(kek, nonce)
+	key, err := xchaPoly.Decrypt(ciphertext, aad, tag)
+	if err != nil {
+		return []byte{}, ErrInvalidWrappedKey
+	}
+
+	return key, nil
+}
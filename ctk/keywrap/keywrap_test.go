@@ -0,0 +1,106 @@
+package keywrap_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/keywrap"
+)
+
+func TestKeyWrap(t *testing.T) {
+	kek := [32]byte{
+		0x80, 0x81, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87,
+		0x88, 0x89, 0x8a, 0x8b, 0x8c, 0x8d, 0x8e, 0x8f,
+		0x90, 0x91, 0x92, 0x93, 0x94, 0x95, 0x96, 0x97,
+		0x98, 0x99, 0x9a, 0x9b, 0x9c, 0x9d, 0x9e, 0x9f,
+	}
+
+	key := []byte{
+		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07,
+		0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+		0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17,
+		0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f,
+	}
+
+	t.Run("Wrap + Unwrap", func(t *testing.T) {
+		t.Parallel()
+
+		wrapped, err := keywrap.Wrap(kek, key)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if len(wrapped) != len(key)+keywrap.Overhead {
+			t.Errorf("want length %v, got %v", len(key)+keywrap.Overhead, len(wrapped))
+		}
+
+		got, err := keywrap.Unwrap(kek, wrapped)
+		want := key
+
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+
+		if !errors.Is(err, nil) {
+			t.Errorf("want error %v, got %v", nil, err)
+		}
+	})
+
+	t.Run("Random Nonce", func(t *testing.T) {
+		t.Parallel()
+
+		wrapped1, _ := keywrap.Wrap(kek, key)
+		wrapped2, _ := keywrap.Wrap(kek, key)
+
+		if slices.Equal(wrapped1, wrapped2) {
+			t.Errorf("want different wrapped keys, got %v twice", wrapped1)
+		}
+	})
+
+	t.Run("Invalid Key Size", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := keywrap.Wrap(kek, key[:keywrap.MinKeySize-1])
+
+		gotError := err
+		wantError := keywrap.ErrInvalidKeySize
+
+		if !errors.Is(gotError, wantError) {
+			t.Errorf("want error %v, got %v", wantError, gotError)
+		}
+	})
+
+	t.Run("Wrong KEK", func(t *testing.T) {
+		t.Parallel()
+
+		wrapped, _ := keywrap.Wrap(kek, key)
+
+		otherKek := kek
+		otherKek[0] ^= 0x01
+
+		_, err := keywrap.Unwrap(otherKek, wrapped)
+
+		gotError := err
+		wantError := keywrap.ErrInvalidWrappedKey
+
+		if !errors.Is(gotError, wantError) {
+			t.Errorf("want error %v, got %v", wantError, gotError)
+		}
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		t.Parallel()
+
+		wrapped, _ := keywrap.Wrap(kek, key)
+
+		_, err := keywrap.Unwrap(kek, wrapped[:keywrap.MinKeySize+keywrap.Overhead-1])
+
+		gotError := err
+		wantError := keywrap.ErrInvalidWrappedKey
+
+		if !errors.Is(gotError, wantError) {
+			t.Errorf("want error %v, got %v", wantError, gotError)
+		}
+	})
+}
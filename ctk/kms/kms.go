@@ -0,0 +1,117 @@
+// Package kms defines the interface for key providers such as external key
+// management services (KMS) or hardware security modules (HSM) and provides an
+// in-memory implementation.
+//
+// Subsystems like envelope and keystore consume a KeyProvider so that backends
+// (e.g. AWS KMS or HashiCorp Vault) can be plugged in without changes to them.
+package kms
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/pmuens/ctk-go/ctk/keywrap"
+)
+
+const (
+	// ErrKeyNotFound is returned if the provider doesn't know the key ID.
+	ErrKeyNotFound = Error("key not found")
+
+	// ErrNotSupported is returned by providers that don't support an operation
+	// (e.g. exporting key material from an HSM).
+	ErrNotSupported = Error("operation not supported")
+
+	// ErrUnwrap is returned if a wrapped key can't be unwrapped.
+	ErrUnwrap = Error("unwrap failed")
+)
+
+// KeyProvider provides access to keys identified by key IDs.
+// Implementations need to be safe for concurrent use.
+type KeyProvider interface {
+	// GetKey returns the key material of the key.
+	// Providers that don't allow exporting keys return ErrNotSupported.
+	GetKey(ctx context.Context, keyID string) ([]byte, error)
+
+	// WrapKey encrypts the (data-encryption) key under the key with the key ID.
+	WrapKey(ctx context.Context, keyID string, key []byte) ([]byte, error)
+
+	// UnwrapKey decrypts the wrapped key with the key with the key ID.
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// Memory is an in-memory KeyProvider that wraps keys via the keywrap package.
+// It's meant for tests and local development.
+type Memory struct {
+	// mu guards keys.
+	mu sync.RWMutex
+
+	// keys maps key IDs to key-encryption keys.
+	keys map[string][32]byte
+}
+
+// NewMemory creates a new, empty in-memory KeyProvider.
+func NewMemory() *Memory {
+	return &Memory{keys: make(map[string][32]byte)}
+}
+
+// AddKey adds (or replaces) the key with the key ID.
+func (m *Memory) AddKey(keyID string, key [32]byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.keys[keyID] = key
+}
+
+// GetKey returns the key material of the key.
+func (m *Memory) GetKey(ctx context.Context, keyID string) ([]byte, error) {
+	key, err := m.key(ctx, keyID)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return slices.Clone(key[:]), nil
+}
+
+// WrapKey encrypts the key under the key with the key ID.
+func (m *Memory) WrapKey(ctx context.Context, keyID string, key []byte) ([]byte, error) {
+	kek, err := m.key(ctx, keyID)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return keywrap.Wrap(kek, key)
+}
+
+// UnwrapKey decrypts the wrapped key with the key with the key ID.
+func (m *Memory) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	kek, err := m.key(ctx, keyID)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	key, err := keywrap.Unwrap(kek, wrapped)
+	if err != nil {
+		return []byte{}, ErrUnwrap
+	}
+
+	return key, nil
+}
+
+// key looks up the key with the key ID.
+func (m *Memory) key(ctx context.Context, keyID string) ([32]byte, error) {
+	err := ctx.Err()
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	key, ok := m.keys[keyID]
+	if !ok {
+		return [32]byte{}, ErrKeyNotFound
+	}
+
+	return key, nil
+}
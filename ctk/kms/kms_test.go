@@ -0,0 +1,84 @@
+package kms_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/kms"
+)
+
+func TestMemory(t *testing.T) {
+	key := [32]byte{0x01, 0x02, 0x03}
+	dek := []byte{
+		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07,
+		0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+	}
+
+	// Ensure that Memory implements the KeyProvider interface.
+	var _ kms.KeyProvider = kms.NewMemory()
+
+	t.Run("Wrap + Unwrap", func(t *testing.T) {
+		t.Parallel()
+
+		m := kms.NewMemory()
+		m.AddKey("kek", key)
+
+		wrapped, err := m.WrapKey(context.Background(), "kek", dek)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		got, err := m.UnwrapKey(context.Background(), "kek", wrapped)
+
+		if !slices.Equal(got, dek) {
+			t.Errorf("want %v, got %v (error %v)", dek, got, err)
+		}
+	})
+
+	t.Run("GetKey", func(t *testing.T) {
+		t.Parallel()
+
+		m := kms.NewMemory()
+		m.AddKey("kek", key)
+
+		got, _ := m.GetKey(context.Background(), "kek")
+
+		if !slices.Equal(got, key[:]) {
+			t.Errorf("want %v, got %v", key, got)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		m := kms.NewMemory()
+		m.AddKey("kek", key)
+		m.AddKey("other", [32]byte{0xff})
+
+		wrapped, _ := m.WrapKey(context.Background(), "kek", dek)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, unknownErr := m.GetKey(context.Background(), "unknown")
+		_, unwrapErr := m.UnwrapKey(context.Background(), "other", wrapped)
+		_, canceledErr := m.WrapKey(ctx, "kek", dek)
+
+		tt := map[string]struct {
+			err  error
+			want error
+		}{
+			"Unknown Key": {err: unknownErr, want: kms.ErrKeyNotFound},
+			"Wrong Key":   {err: unwrapErr, want: kms.ErrUnwrap},
+			"Canceled":    {err: canceledErr, want: context.Canceled},
+		}
+
+		for name, tc := range tt {
+			if !errors.Is(tc.err, tc.want) {
+				t.Errorf("%v: want error %v, got %v", name, tc.want, tc.err)
+			}
+		}
+	})
+}
@@ -0,0 +1,141 @@
+// Package logging provides hooks to log the operational events of the
+// higher-level subsystems (e.g. session connections and keystores) without the
+// risk of leaking key material.
+//
+// The subsystems log to a Logger which is satisfied by *slog.Logger. Secret
+// values are wrapped in Secret which refuses to be formatted, marshaled or
+// logged. As a second line of defense, Redact wraps a slog.Handler so that raw
+// bytes (e.g. keys and nonces that weren't wrapped) are redacted as well:
+//
+//	logger := slog.New(logging.Redact(slog.NewTextHandler(os.Stderr, nil)))
+//
+//	conn, err := session.Dial("tcp", address, session.Config{..., Logger: logger})
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+)
+
+// Redacted replaces secret values in the output.
+const Redacted = "[REDACTED]"
+
+// Logger receives the operational events of a subsystem. *slog.Logger
+// implements it.
+type Logger interface {
+	// LogAttrs logs the event with the message and the attributes.
+	LogAttrs(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr)
+}
+
+// Discard is a Logger which drops all events. It's used if no logger is
+// configured.
+var Discard Logger = discard{}
+
+// discard implements Discard.
+type discard struct{}
+
+// LogAttrs implements the Logger interface.
+func (discard) LogAttrs(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {}
+
+// Secret is secret material (e.g. a key or a nonce) which is always formatted,
+// marshaled and logged as Redacted.
+type Secret []byte
+
+// String implements the fmt.Stringer interface.
+func (s Secret) String() string {
+	return Redacted
+}
+
+// GoString implements the fmt.GoStringer interface.
+func (s Secret) GoString() string {
+	return Redacted
+}
+
+// Format implements the fmt.Formatter interface so that no verb (e.g. %x)
+// reveals the material.
+func (s Secret) Format(f fmt.State, verb rune) {
+	io.WriteString(f, Redacted)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface (which is also
+// used when the secret is encoded as JSON).
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(Redacted), nil
+}
+
+// LogValue implements the slog.LogValuer interface.
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(Redacted)
+}
+
+// redactHandler implements Redact.
+type redactHandler struct {
+	// handler receives the redacted records.
+	handler slog.Handler
+}
+
+// Redact wraps the handler so that attribute values which are byte slices or
+// byte arrays (e.g. [32]byte keys) are replaced with Redacted before the
+// handler receives them.
+func Redact(handler slog.Handler) slog.Handler {
+	return redactHandler{handler: handler}
+}
+
+// Enabled implements the slog.Handler interface.
+func (h redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle implements the slog.Handler interface.
+func (h redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	redacted := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+
+	r.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(redact(a))
+		return true
+	})
+
+	return h.handler.Handle(ctx, redacted)
+}
+
+// WithAttrs implements the slog.Handler interface.
+func (h redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		redacted = append(redacted, redact(a))
+	}
+
+	return redactHandler{handler: h.handler.WithAttrs(redacted)}
+}
+
+// WithGroup implements the slog.Handler interface.
+func (h redactHandler) WithGroup(name string) slog.Handler {
+	return redactHandler{handler: h.handler.WithGroup(name)}
+}
+
+// redact replaces the attribute's value with Redacted if it's a byte slice or
+// a byte array (groups are redacted recursively).
+func redact(a slog.Attr) slog.Attr {
+	value := a.Value.Resolve()
+
+	switch value.Kind() {
+	case slog.KindGroup:
+		group := value.Group()
+		redacted := make([]any, 0, len(group))
+		for _, attr := range group {
+			redacted = append(redacted, redact(attr))
+		}
+
+		return slog.Group(a.Key, redacted...)
+	case slog.KindAny:
+		v := reflect.ValueOf(value.Any())
+		if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() == reflect.Uint8 {
+			return slog.String(a.Key, Redacted)
+		}
+	}
+
+	return slog.Attr{Key: a.Key, Value: value}
+}
@@ -0,0 +1,95 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/logging"
+)
+
+func TestSecret(t *testing.T) {
+	secret := logging.Secret{0xde, 0xad, 0xbe, 0xef}
+
+	t.Run("Format", func(t *testing.T) {
+		t.Parallel()
+
+		for _, verb := range []string{"%v", "%+v", "%#v", "%s", "%q", "%x", "%X", "%d", "%08b"} {
+			got := fmt.Sprintf(verb, secret)
+
+			if got != logging.Redacted {
+				t.Errorf("%v: want %v, got %v", verb, logging.Redacted, got)
+			}
+		}
+
+		got := fmt.Sprintf("%v", struct{ Key logging.Secret }{secret})
+		want := "{" + logging.Redacted + "}"
+
+		if got != want {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		t.Parallel()
+
+		got, err := json.Marshal(map[string]logging.Secret{"key": secret})
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		want := `{"key":"` + logging.Redacted + `"}`
+
+		if string(got) != want {
+			t.Errorf("want %v, got %s", want, got)
+		}
+	})
+
+	t.Run("slog", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		slog.New(slog.NewTextHandler(&buf, nil)).Info("event", "key", secret)
+
+		if !strings.Contains(buf.String(), "key="+logging.Redacted) || strings.Contains(buf.String(), "deadbeef") {
+			t.Errorf("want redacted key, got %v", buf.String())
+		}
+	})
+}
+
+func TestRedact(t *testing.T) {
+	tt := map[string]struct {
+		attr slog.Attr
+		want string
+	}{
+		"Byte Slice":  {attr: slog.Any("nonce", []byte{0x01, 0x02}), want: "nonce=" + logging.Redacted},
+		"Byte Array":  {attr: slog.Any("key", [32]byte{0x01}), want: "key=" + logging.Redacted},
+		"Group":       {attr: slog.Group("session", slog.Any("key", [32]byte{0x01})), want: "session.key=" + logging.Redacted},
+		"String":      {attr: slog.String("name", "signing"), want: "name=signing"},
+		"Int":         {attr: slog.Int("version", 2), want: "version=2"},
+		"Other Slice": {attr: slog.Any("versions", []int{1, 2}), want: `versions="[1 2]"`},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			logger := slog.New(logging.Redact(slog.NewTextHandler(&buf, nil)))
+
+			logger.LogAttrs(context.Background(), slog.LevelInfo, "event", tc.attr)
+			logger.With(tc.attr).Info("event")
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			for _, line := range lines {
+				if !strings.HasSuffix(line, " "+tc.want) {
+					t.Errorf("want suffix %v, got %v", tc.want, line)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+// Package merkle implements Merkle trees as specified in
+// https://datatracker.ietf.org/doc/html/rfc9162#section-2.1 (Certificate
+// Transparency) with SHA-256 or BLAKE2b-256 as the hash function.
+//
+// The leaves are usually the encrypted chunks of a container (see
+// stream.Chunks). The root commits to all chunks and an inclusion proof shows
+// that a single chunk is part of the tree so that remote storage can verify
+// individual chunks without the key and without the other chunks.
+//
+// Leaves and inner nodes are hashed with different prefixes (0x00 and 0x01) so
+// that a leaf can't be passed off as an inner node (second preimage attack).
+package merkle
+
+import (
+	"crypto/sha256"
+	"hash"
+
+	"github.com/pmuens/ctk-go/ctk/blake2b"
+	"github.com/pmuens/ctk-go/ctk/subtle"
+)
+
+const (
+	// ErrInvalidHash is returned if the hash function is unknown.
+	ErrInvalidHash = Error("invalid hash function")
+
+	// ErrInvalidIndex is returned if a leaf index is out of range.
+	ErrInvalidIndex = Error("invalid leaf index")
+
+	// ErrInvalidProof is returned if an inclusion proof is malformed or doesn't
+	// match the root.
+	ErrInvalidProof = Error("invalid inclusion proof")
+)
+
+// Size is the size (in bytes) of a hash.
+const Size = 32
+
+// Hash is the hash function of a tree.
+type Hash uint32
+
+const (
+	// SHA256 is SHA-256 (as specified for Certificate Transparency).
+	SHA256 Hash = 0
+
+	// BLAKE2b256 is BLAKE2b with a 32 byte digest.
+	BLAKE2b256 Hash = 1
+)
+
+// String returns the name of the hash function.
+func (h Hash) String() string {
+	switch h {
+	case SHA256:
+		return "sha256"
+	case BLAKE2b256:
+		return "blake2b256"
+	default:
+		return "unknown"
+	}
+}
+
+// new returns a new instance of the hash function.
+func (h Hash) new() hash.Hash {
+	if h == BLAKE2b256 {
+		return blake2b.New256()
+	}
+
+	return sha256.New()
+}
+
+// leafHash returns the hash of the leaf data.
+func (h Hash) leafHash(data []byte) [Size]byte {
+	d := h.new()
+	d.Write([]byte{0x00})
+	d.Write(data)
+
+	return [Size]byte(d.Sum(nil))
+}
+
+// nodeHash returns the hash of the inner node with the children.
+func (h Hash) nodeHash(left [Size]byte, right [Size]byte) [Size]byte {
+	d := h.new()
+	d.Write([]byte{0x01})
+	d.Write(left[:])
+	d.Write(right[:])
+
+	return [Size]byte(d.Sum(nil))
+}
+
+// Tree is a Merkle tree over a list of leaves. Only the leaf hashes are kept so
+// that the leaves themselves don't need to stay in memory.
+type Tree struct {
+	// hash is the hash function.
+	hash Hash
+
+	// leaves are the hashes of the leaves.
+	leaves [][Size]byte
+
+	// root is the root hash.
+	root [Size]byte
+}
+
+// New creates the tree over the leaves.
+// Returns ErrInvalidHash if the hash function is unknown.
+func New(h Hash, leaves [][]byte) (*Tree, error) {
+	if h > BLAKE2b256 {
+		return nil, ErrInvalidHash
+	}
+
+	t := &Tree{
+		hash:   h,
+		leaves: make([][Size]byte, len(leaves)),
+	}
+
+	for i, leaf := range leaves {
+		t.leaves[i] = h.leafHash(leaf)
+	}
+
+	if len(leaves) == 0 {
+		// The root of an empty tree is the hash of the empty string.
+		t.root = [Size]byte(h.new().Sum(nil))
+	} else {
+		t.root = t.subtreeHash(t.leaves)
+	}
+
+	return t, nil
+}
+
+// Root returns the root hash.
+func (t *Tree) Root() [Size]byte {
+	return t.root
+}
+
+// Len returns the number of leaves.
+func (t *Tree) Len() int {
+	return len(t.leaves)
+}
+
+// Proof returns the inclusion proof of the leaf with the index.
+// Returns ErrInvalidIndex if there's no such leaf.
+func (t *Tree) Proof(index int) (Proof, error) {
+	if index < 0 || index >= len(t.leaves) {
+		return Proof{}, ErrInvalidIndex
+	}
+
+	return Proof{
+		Index:    uint64(index),
+		TreeSize: uint64(len(t.leaves)),
+		Path:     t.path(index, t.leaves),
+	}, nil
+}
+
+// subtreeHash returns the hash of the subtree over the (non-empty) leaves. The
+// left subtree holds the largest power of two number of leaves that's smaller
+// than the number of leaves.
+func (t *Tree) subtreeHash(leaves [][Size]byte) [Size]byte {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+
+	k := split(len(leaves))
+
+	return t.hash.nodeHash(t.subtreeHash(leaves[:k]), t.subtreeHash(leaves[k:]))
+}
+
+// path returns the hashes of the siblings on the path from the leaf with the
+// index to the root of the subtree over the leaves (from the bottom up).
+func (t *Tree) path(index int, leaves [][Size]byte) [][Size]byte {
+	if len(leaves) == 1 {
+		return [][Size]byte{}
+	}
+
+	k := split(len(leaves))
+	if index < k {
+		return append(t.path(index, leaves[:k]), t.subtreeHash(leaves[k:]))
+	}
+
+	return append(t.path(index-k, leaves[k:]), t.subtreeHash(leaves[:k]))
+}
+
+// split returns the largest power of two that's smaller than n (n > 1).
+func split(n int) int {
+	k := 1
+	for k*2 < n {
+		k *= 2
+	}
+
+	return k
+}
+
+// Verify checks that the leaf is part of the tree with the root via the
+// inclusion proof.
+// Returns ErrInvalidProof if the proof doesn't match the root and
+// ErrInvalidHash if the hash function is unknown.
+func Verify(h Hash, root [Size]byte, leaf []byte, proof Proof) error {
+	if h > BLAKE2b256 {
+		return ErrInvalidHash
+	}
+
+	if proof.Index >= proof.TreeSize {
+		return ErrInvalidProof
+	}
+
+	// See https://datatracker.ietf.org/doc/html/rfc9162#section-2.1.3.2.
+	fn := proof.Index
+	sn := proof.TreeSize - 1
+	r := h.leafHash(leaf)
+
+	for _, p := range proof.Path {
+		if sn == 0 {
+			return ErrInvalidProof
+		}
+
+		if fn&1 == 1 || fn == sn {
+			r = h.nodeHash(p, r)
+
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = h.nodeHash(r, p)
+		}
+
+		fn >>= 1
+		sn >>= 1
+	}
+
+	if sn != 0 || subtle.ConstantTimeCompare(r[:], root[:]) != 1 {
+		return ErrInvalidProof
+	}
+
+	return nil
+}
@@ -0,0 +1,284 @@
+package merkle_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/merkle"
+	"github.com/pmuens/ctk-go/ctk/stream"
+)
+
+// leaves are the leaves of the Certificate Transparency reference tests.
+func leaves() [][]byte {
+	return [][]byte{
+		{},
+		{0x00},
+		{0x10},
+		{0x20, 0x21},
+		{0x30, 0x31},
+		{0x40, 0x41, 0x42, 0x43},
+		{0x50, 0x51, 0x52, 0x53, 0x54, 0x55, 0x56, 0x57},
+		{0x60, 0x61, 0x62, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69, 0x6a, 0x6b, 0x6c, 0x6d, 0x6e, 0x6f},
+	}
+}
+
+func TestRoot(t *testing.T) {
+	tt := map[string]struct {
+		hash   merkle.Hash
+		leaves int
+		want   string
+	}{
+		"SHA-256 0": {merkle.SHA256, 0, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		"SHA-256 1": {merkle.SHA256, 1, "6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d"},
+		"SHA-256 2": {merkle.SHA256, 2, "fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125"},
+		"SHA-256 3": {merkle.SHA256, 3, "aeb6bcfe274b70a14fb067a5e5578264db0fa9b51af5e0ba159158f329e06e77"},
+		"SHA-256 4": {merkle.SHA256, 4, "d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7"},
+		"SHA-256 5": {merkle.SHA256, 5, "4e3bbb1f7b478dcfe71fb631631519a3bca12c9aefca1612bfce4c13a86264d4"},
+		"SHA-256 6": {merkle.SHA256, 6, "76e67dadbcdf1e10e1b74ddc608abd2f98dfb16fbce75277b5232a127f2087ef"},
+		"SHA-256 7": {merkle.SHA256, 7, "ddb89be403809e325750d3d263cd78929c2942b7942a34b77e122c9594a74c8c"},
+		"SHA-256 8": {merkle.SHA256, 8, "5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328"},
+		"BLAKE2b 8": {merkle.BLAKE2b256, 8, "59cc7108743d34853ea37ea07558da3407712c7f0fdb76e59753eb243e0c438e"},
+	}
+
+	for name, tc := range tt {
+		tree, err := merkle.New(tc.hash, leaves()[:tc.leaves])
+		if err != nil {
+			t.Fatalf("%v: want error %v, got %v", name, nil, err)
+		}
+
+		root := tree.Root()
+		if got := hex.EncodeToString(root[:]); got != tc.want {
+			t.Errorf("%v: want %v, got %v", name, tc.want, got)
+		}
+
+		if tree.Len() != tc.leaves {
+			t.Errorf("%v: want %v leaves, got %v", name, tc.leaves, tree.Len())
+		}
+	}
+}
+
+func TestProof(t *testing.T) {
+	t.Run("Reference", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]struct {
+			index  int
+			leaves int
+			want   []string
+		}{
+			"0 of 8": {0, 8, []string{
+				"96a296d224f285c67bee93c30f8a309157f0daa35dc5b87e410b78630a09cfc7",
+				"5f083f0a1a33ca076a95279832580db3e0ef4584bdff1f54c8a360f50de3031e",
+				"6b47aaf29ee3c2af9af889bc1fb9254dabd31177f16232dd6aab035ca39bf6e4",
+			}},
+			"5 of 8": {5, 8, []string{
+				"bc1a0643b12e4d2d7c77918f44e0f4f79a838b6cf9ec5b5c283e1f4d88599e6b",
+				"ca854ea128ed050b41b35ffc1b87b8eb2bde461e9e3b5596ece6b9d5975a0ae0",
+				"d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7",
+			}},
+			"2 of 3": {2, 3, []string{
+				"fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125",
+			}},
+			"0 of 1": {0, 1, []string{}},
+		}
+
+		for name, tc := range tt {
+			tree, _ := merkle.New(merkle.SHA256, leaves()[:tc.leaves])
+
+			proof, err := tree.Proof(tc.index)
+			if err != nil {
+				t.Fatalf("%v: want error %v, got %v", name, nil, err)
+			}
+
+			got := []string{}
+			for _, h := range proof.Path {
+				got = append(got, hex.EncodeToString(h[:]))
+			}
+
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("%v: want %v, got %v", name, tc.want, got)
+			}
+		}
+	})
+
+	t.Run("Verify", func(t *testing.T) {
+		t.Parallel()
+
+		for _, h := range []merkle.Hash{merkle.SHA256, merkle.BLAKE2b256} {
+			for n := 1; n <= len(leaves()); n++ {
+				tree, _ := merkle.New(h, leaves()[:n])
+
+				for i := range n {
+					proof, _ := tree.Proof(i)
+
+					err := merkle.Verify(h, tree.Root(), leaves()[i], proof)
+					if err != nil {
+						t.Errorf("%v %v of %v: want error %v, got %v", h, i, n, nil, err)
+					}
+				}
+			}
+		}
+	})
+
+	t.Run("Tampering", func(t *testing.T) {
+		t.Parallel()
+
+		tree, _ := merkle.New(merkle.SHA256, leaves())
+		root := tree.Root()
+		proof, _ := tree.Proof(5)
+
+		wrongIndex := proof
+		wrongIndex.Index = 4
+
+		wrongSize := proof
+		wrongSize.TreeSize = 6
+
+		wrongPath := proof
+		wrongPath.Path = slices.Clone(proof.Path)
+		wrongPath.Path[1][0] ^= 0x01
+
+		shortPath := proof
+		shortPath.Path = proof.Path[:2]
+
+		longPath := proof
+		longPath.Path = append(slices.Clone(proof.Path), [merkle.Size]byte{})
+
+		tt := map[string]struct {
+			leaf  []byte
+			proof merkle.Proof
+		}{
+			"Leaf":       {[]byte{0x40, 0x41, 0x42, 0x44}, proof},
+			"Index":      {leaves()[5], wrongIndex},
+			"Tree Size":  {leaves()[5], wrongSize},
+			"Path":       {leaves()[5], wrongPath},
+			"Short Path": {leaves()[5], shortPath},
+			"Long Path":  {leaves()[5], longPath},
+			"Out of Range": {leaves()[5], merkle.Proof{
+				Index:    8,
+				TreeSize: 8,
+				Path:     proof.Path,
+			}},
+		}
+
+		for name, tc := range tt {
+			err := merkle.Verify(merkle.SHA256, root, tc.leaf, tc.proof)
+			if !errors.Is(err, merkle.ErrInvalidProof) {
+				t.Errorf("%v: want error %v, got %v", name, merkle.ErrInvalidProof, err)
+			}
+		}
+
+		err := merkle.Verify(merkle.BLAKE2b256, root, leaves()[5], proof)
+		if !errors.Is(err, merkle.ErrInvalidProof) {
+			t.Errorf("want error %v, got %v", merkle.ErrInvalidProof, err)
+		}
+	})
+
+	t.Run("Invalid Index", func(t *testing.T) {
+		t.Parallel()
+
+		tree, _ := merkle.New(merkle.SHA256, leaves())
+
+		for _, index := range []int{-1, 8} {
+			_, err := tree.Proof(index)
+			if !errors.Is(err, merkle.ErrInvalidIndex) {
+				t.Errorf("%v: want error %v, got %v", index, merkle.ErrInvalidIndex, err)
+			}
+		}
+	})
+
+	t.Run("Invalid Hash", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := merkle.New(merkle.Hash(2), leaves())
+		if !errors.Is(err, merkle.ErrInvalidHash) {
+			t.Errorf("want error %v, got %v", merkle.ErrInvalidHash, err)
+		}
+	})
+}
+
+func TestProofMarshalBinary(t *testing.T) {
+	tree, _ := merkle.New(merkle.SHA256, leaves())
+	proof, _ := tree.Proof(5)
+
+	encoded, err := proof.MarshalBinary()
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	if len(encoded) != 16+3*merkle.Size {
+		t.Errorf("want %v bytes, got %v", 16+3*merkle.Size, len(encoded))
+	}
+
+	var decoded merkle.Proof
+	err = decoded.UnmarshalBinary(encoded)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	err = merkle.Verify(merkle.SHA256, tree.Root(), leaves()[5], decoded)
+	if err != nil {
+		t.Errorf("want error %v, got %v", nil, err)
+	}
+
+	for _, size := range []int{0, 15, 17, len(encoded) - 1} {
+		err = decoded.UnmarshalBinary(encoded[:size])
+		if !errors.Is(err, merkle.ErrInvalidProof) {
+			t.Errorf("%v bytes: want error %v, got %v", size, merkle.ErrInvalidProof, err)
+		}
+	}
+}
+
+func TestContainerChunks(t *testing.T) {
+	key := [32]byte{0x01, 0x02, 0x03}
+
+	plaintext := bytes.Repeat([]byte{0x2a}, 3*1024+100)
+
+	var buf bytes.Buffer
+	w, err := stream.NewWriter(&buf, key, stream.WithChunkSize(1024))
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	w.Write(plaintext)
+	w.Close()
+
+	container := buf.Bytes()
+
+	// The tree is built by the producer (with access to the plaintext).
+	chunks, err := stream.Chunks(container)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	if len(chunks) != 4 {
+		t.Fatalf("want %v chunks, got %v", 4, len(chunks))
+	}
+
+	tree, _ := merkle.New(merkle.BLAKE2b256, chunks)
+	root := tree.Root()
+
+	// The storage only sees the root, the chunks and the proofs.
+	for i, chunk := range chunks {
+		proof, _ := tree.Proof(i)
+
+		err := merkle.Verify(merkle.BLAKE2b256, root, chunk, proof)
+		if err != nil {
+			t.Errorf("chunk %v: want error %v, got %v", i, nil, err)
+		}
+
+		chunk[0] ^= 0x01
+		err = merkle.Verify(merkle.BLAKE2b256, root, chunk, proof)
+		if !errors.Is(err, merkle.ErrInvalidProof) {
+			t.Errorf("chunk %v: want error %v, got %v", i, merkle.ErrInvalidProof, err)
+		}
+		chunk[0] ^= 0x01
+	}
+
+	_, err = stream.Chunks(container[:10])
+	if err == nil {
+		t.Errorf("want error, got %v", err)
+	}
+}
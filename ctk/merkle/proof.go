@@ -0,0 +1,54 @@
+package merkle
+
+import "encoding/binary"
+
+// maxPathLength is the maximum number of hashes in an inclusion proof (the
+// height of a tree with 2^64 leaves).
+const maxPathLength = 64
+
+// Proof is an inclusion proof of a leaf.
+type Proof struct {
+	// Index is the index of the leaf.
+	Index uint64
+
+	// TreeSize is the number of leaves in the tree.
+	TreeSize uint64
+
+	// Path are the hashes of the siblings on the path from the leaf to the root
+	// (from the bottom up).
+	Path [][Size]byte
+}
+
+// MarshalBinary encodes the proof as index (8 bytes BE) | tree size (8 bytes
+// BE) | path hashes.
+func (p Proof) MarshalBinary() ([]byte, error) {
+	if len(p.Path) > maxPathLength {
+		return []byte{}, ErrInvalidProof
+	}
+
+	out := make([]byte, 0, 16+len(p.Path)*Size)
+	out = binary.BigEndian.AppendUint64(out, p.Index)
+	out = binary.BigEndian.AppendUint64(out, p.TreeSize)
+	for _, h := range p.Path {
+		out = append(out, h[:]...)
+	}
+
+	return out, nil
+}
+
+// UnmarshalBinary decodes a proof encoded via MarshalBinary.
+// Returns ErrInvalidProof if the data is malformed.
+func (p *Proof) UnmarshalBinary(data []byte) error {
+	if len(data) < 16 || (len(data)-16)%Size != 0 || (len(data)-16)/Size > maxPathLength {
+		return ErrInvalidProof
+	}
+
+	p.Index = binary.BigEndian.Uint64(data[:8])
+	p.TreeSize = binary.BigEndian.Uint64(data[8:16])
+	p.Path = make([][Size]byte, 0, (len(data)-16)/Size)
+	for data = data[16:]; len(data) > 0; data = data[Size:] {
+		p.Path = append(p.Path, [Size]byte(data[:Size]))
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+// Package metrics provides hooks to instrument the layers that are embedded in
+// servers (e.g. session and secretstream connections).
+//
+// The layers report their operations to a Sink. Prometheus exposes the
+// reported metrics in the Prometheus text format without depending on the
+// Prometheus client library:
+//
+//	sink := metrics.NewPrometheus()
+//	http.Handle("/metrics", sink)
+//
+//	conn, err := session.Dial("tcp", address, session.Config{..., Metrics: sink})
+//
+// The metrics don't contain any data or keys but reveal the traffic volume.
+package metrics
+
+import "time"
+
+// Layer names which are reported by the toolkit.
+const (
+	// SessionLayer is reported by session connections.
+	SessionLayer = "session"
+
+	// SecretStreamLayer is reported by secretstream channels.
+	SecretStreamLayer = "secretstream"
+)
+
+// Sink receives the metrics of a layer. Its methods are called synchronously
+// (and possibly concurrently) for every operation, so they need to be fast and
+// safe for concurrent use.
+type Sink interface {
+	// Sealed reports that n plaintext bytes were encrypted and authenticated.
+	Sealed(layer string, n int)
+
+	// Opened reports that n plaintext bytes were authenticated and decrypted.
+	Opened(layer string, n int)
+
+	// AuthFailed reports that a message or a peer failed authentication.
+	AuthFailed(layer string)
+
+	// KeyAge reports the age of the key that was used by the last operation
+	// (i.e. the time since the key was derived).
+	KeyAge(layer string, age time.Duration)
+}
+
+// Discard is a Sink which drops all metrics. It's used if no sink is
+// configured.
+var Discard Sink = discard{}
+
+// discard implements Discard.
+type discard struct{}
+
+// Sealed implements the Sink interface.
+func (discard) Sealed(layer string, n int) {}
+
+// Opened implements the Sink interface.
+func (discard) Opened(layer string, n int) {}
+
+// AuthFailed implements the Sink interface.
+func (discard) AuthFailed(layer string) {}
+
+// KeyAge implements the Sink interface.
+func (discard) KeyAge(layer string, age time.Duration) {}
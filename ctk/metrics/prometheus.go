@@ -0,0 +1,165 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContentType is the content type of the Prometheus text format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// layerMetrics are the metrics of a single layer.
+type layerMetrics struct {
+	// sealed is the number of seal operations.
+	sealed uint64
+
+	// opened is the number of open operations.
+	opened uint64
+
+	// sealedBytes is the number of plaintext bytes that were sealed.
+	sealedBytes uint64
+
+	// openedBytes is the number of plaintext bytes that were opened.
+	openedBytes uint64
+
+	// authFailures is the number of authentication failures.
+	authFailures uint64
+
+	// keyAge is the last reported key age.
+	keyAge time.Duration
+
+	// hasKeyAge indicates whether a key age was reported.
+	hasKeyAge bool
+}
+
+// Prometheus is a Sink which exposes the metrics in the Prometheus text format
+// via its ServeHTTP method (or WriteTo):
+//
+//   - ctk_operations_total{layer, operation="seal|open"} (counter)
+//   - ctk_bytes_total{layer, operation="seal|open"} (counter)
+//   - ctk_auth_failures_total{layer} (counter)
+//   - ctk_key_age_seconds{layer} (gauge, age of the most recently used key)
+//
+// It's safe for concurrent use.
+type Prometheus struct {
+	// mu guards layers.
+	mu sync.Mutex
+
+	// layers are the metrics per layer.
+	layers map[string]*layerMetrics
+}
+
+// NewPrometheus returns a Prometheus sink without any metrics.
+func NewPrometheus() *Prometheus {
+	return &Prometheus{layers: map[string]*layerMetrics{}}
+}
+
+// Sealed implements the Sink interface.
+func (p *Prometheus) Sealed(layer string, n int) {
+	p.update(layer, func(m *layerMetrics) {
+		m.sealed++
+		m.sealedBytes += uint64(n)
+	})
+}
+
+// Opened implements the Sink interface.
+func (p *Prometheus) Opened(layer string, n int) {
+	p.update(layer, func(m *layerMetrics) {
+		m.opened++
+		m.openedBytes += uint64(n)
+	})
+}
+
+// AuthFailed implements the Sink interface.
+func (p *Prometheus) AuthFailed(layer string) {
+	p.update(layer, func(m *layerMetrics) {
+		m.authFailures++
+	})
+}
+
+// KeyAge implements the Sink interface.
+func (p *Prometheus) KeyAge(layer string, age time.Duration) {
+	p.update(layer, func(m *layerMetrics) {
+		m.keyAge = age
+		m.hasKeyAge = true
+	})
+}
+
+// WriteTo writes the metrics in the Prometheus text format to w.
+func (p *Prometheus) WriteTo(w io.Writer) (int64, error) {
+	p.mu.Lock()
+	layers := make(map[string]layerMetrics, len(p.layers))
+	for name, m := range p.layers {
+		layers[name] = *m
+	}
+	p.mu.Unlock()
+
+	names := slices.Sorted(maps.Keys(layers))
+
+	var b strings.Builder
+
+	b.WriteString("# HELP ctk_operations_total Number of sealed and opened messages.\n")
+	b.WriteString("# TYPE ctk_operations_total counter\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "ctk_operations_total{layer=%v,operation=\"seal\"} %v\n", quote(name), layers[name].sealed)
+		fmt.Fprintf(&b, "ctk_operations_total{layer=%v,operation=\"open\"} %v\n", quote(name), layers[name].opened)
+	}
+
+	b.WriteString("# HELP ctk_bytes_total Number of sealed and opened plaintext bytes.\n")
+	b.WriteString("# TYPE ctk_bytes_total counter\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "ctk_bytes_total{layer=%v,operation=\"seal\"} %v\n", quote(name), layers[name].sealedBytes)
+		fmt.Fprintf(&b, "ctk_bytes_total{layer=%v,operation=\"open\"} %v\n", quote(name), layers[name].openedBytes)
+	}
+
+	b.WriteString("# HELP ctk_auth_failures_total Number of messages or peers that failed authentication.\n")
+	b.WriteString("# TYPE ctk_auth_failures_total counter\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "ctk_auth_failures_total{layer=%v} %v\n", quote(name), layers[name].authFailures)
+	}
+
+	b.WriteString("# HELP ctk_key_age_seconds Age of the most recently used key.\n")
+	b.WriteString("# TYPE ctk_key_age_seconds gauge\n")
+	for _, name := range names {
+		if layers[name].hasKeyAge {
+			fmt.Fprintf(&b, "ctk_key_age_seconds{layer=%v} %v\n", quote(name), strconv.FormatFloat(layers[name].keyAge.Seconds(), 'g', -1, 64))
+		}
+	}
+
+	n, err := io.WriteString(w, b.String())
+
+	return int64(n), err
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	p.WriteTo(w)
+}
+
+// update applies f to the metrics of the layer.
+func (p *Prometheus) update(layer string, f func(m *layerMetrics)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	m, ok := p.layers[layer]
+	if !ok {
+		m = &layerMetrics{}
+		p.layers[layer] = m
+	}
+
+	f(m)
+}
+
+// quote returns the label value as a quoted string (escaping backslashes,
+// double quotes and line feeds).
+func quote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
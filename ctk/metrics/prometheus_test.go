@@ -0,0 +1,77 @@
+package metrics_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pmuens/ctk-go/ctk/metrics"
+)
+
+func TestPrometheus(t *testing.T) {
+	sink := metrics.NewPrometheus()
+
+	sink.Sealed(metrics.SessionLayer, 10)
+	sink.Sealed(metrics.SessionLayer, 5)
+	sink.Opened(metrics.SessionLayer, 7)
+	sink.AuthFailed(metrics.SecretStreamLayer)
+	sink.KeyAge(metrics.SessionLayer, 1500*time.Millisecond)
+	sink.AuthFailed(`quoted "layer"`)
+
+	want := `# HELP ctk_operations_total Number of sealed and opened messages.
+# TYPE ctk_operations_total counter
+ctk_operations_total{layer="quoted \"layer\"",operation="seal"} 0
+ctk_operations_total{layer="quoted \"layer\"",operation="open"} 0
+ctk_operations_total{layer="secretstream",operation="seal"} 0
+ctk_operations_total{layer="secretstream",operation="open"} 0
+ctk_operations_total{layer="session",operation="seal"} 2
+ctk_operations_total{layer="session",operation="open"} 1
+# HELP ctk_bytes_total Number of sealed and opened plaintext bytes.
+# TYPE ctk_bytes_total counter
+ctk_bytes_total{layer="quoted \"layer\"",operation="seal"} 0
+ctk_bytes_total{layer="quoted \"layer\"",operation="open"} 0
+ctk_bytes_total{layer="secretstream",operation="seal"} 0
+ctk_bytes_total{layer="secretstream",operation="open"} 0
+ctk_bytes_total{layer="session",operation="seal"} 15
+ctk_bytes_total{layer="session",operation="open"} 7
+# HELP ctk_auth_failures_total Number of messages or peers that failed authentication.
+# TYPE ctk_auth_failures_total counter
+ctk_auth_failures_total{layer="quoted \"layer\""} 1
+ctk_auth_failures_total{layer="secretstream"} 1
+ctk_auth_failures_total{layer="session"} 0
+# HELP ctk_key_age_seconds Age of the most recently used key.
+# TYPE ctk_key_age_seconds gauge
+ctk_key_age_seconds{layer="session"} 1.5
+`
+
+	t.Run("WriteTo", func(t *testing.T) {
+		t.Parallel()
+
+		var got strings.Builder
+
+		n, err := sink.WriteTo(&got)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if got.String() != want || n != int64(len(want)) {
+			t.Errorf("want %v, got %v", want, got.String())
+		}
+	})
+
+	t.Run("ServeHTTP", func(t *testing.T) {
+		t.Parallel()
+
+		w := httptest.NewRecorder()
+		sink.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+		if got := w.Header().Get("Content-Type"); got != metrics.ContentType {
+			t.Errorf("want %v, got %v", metrics.ContentType, got)
+		}
+
+		if got := w.Body.String(); got != want {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+}
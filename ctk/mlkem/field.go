@@ -0,0 +1,234 @@
+package mlkem
+
+// q is the prime modulus of the field.
+const q = 3329
+
+// n is the number of coefficients of a polynomial.
+const n = 256
+
+// fieldElement is an element of the field Z_q (always reduced).
+type fieldElement uint16
+
+// poly is a polynomial in R_q (or its NTT representation).
+type poly [n]fieldElement
+
+// zetas are the powers of the primitive 256-th root of unity 17 in bit reversed
+// order (zeta^BitRev7(i)) which are used in the NTT.
+var zetas [128]fieldElement
+
+// gammas are the powers zeta^(2 * BitRev7(i) + 1) which are used to multiply
+// polynomials in their NTT representation.
+var gammas [128]fieldElement
+
+func init() {
+	for i := range 128 {
+		zetas[i] = pow(17, bitRev7(i))
+		gammas[i] = pow(17, 2*bitRev7(i)+1)
+	}
+}
+
+// bitRev7 reverses the 7 least significant bits of i.
+func bitRev7(i int) int {
+	result := 0
+	for b := range 7 {
+		result |= (i >> b & 1) << (6 - b)
+	}
+
+	return result
+}
+
+// pow computes base^exp mod q.
+func pow(base fieldElement, exp int) fieldElement {
+	result := fieldElement(1)
+	for range exp {
+		result = mul(result, base)
+	}
+
+	return result
+}
+
+// add computes a + b mod q.
+func add(a, b fieldElement) fieldElement {
+	return fieldElement((uint32(a) + uint32(b)) % q)
+}
+
+// sub computes a - b mod q.
+func sub(a, b fieldElement) fieldElement {
+	return fieldElement((uint32(a) + q - uint32(b)) % q)
+}
+
+// mul computes a * b mod q.
+func mul(a, b fieldElement) fieldElement {
+	return fieldElement(uint32(a) * uint32(b) % q)
+}
+
+// addPoly computes the coefficient-wise sum of the polynomials.
+func addPoly(f, g poly) poly {
+	var h poly
+	for i := range h {
+		h[i] = add(f[i], g[i])
+	}
+
+	return h
+}
+
+// subPoly computes the coefficient-wise difference of the polynomials.
+func subPoly(f, g poly) poly {
+	var h poly
+	for i := range h {
+		h[i] = sub(f[i], g[i])
+	}
+
+	return h
+}
+
+// ntt computes the NTT representation of the polynomial (FIPS 203,
+// Algorithm 9).
+func ntt(f poly) poly {
+	i := 1
+	for length := 128; length >= 2; length /= 2 {
+		for start := 0; start < n; start += 2 * length {
+			zeta := zetas[i]
+			i++
+
+			for j := start; j < start+length; j++ {
+				t := mul(zeta, f[j+length])
+				f[j+length] = sub(f[j], t)
+				f[j] = add(f[j], t)
+			}
+		}
+	}
+
+	return f
+}
+
+// inverseNTT computes the polynomial of the NTT representation (FIPS 203,
+// Algorithm 10).
+func inverseNTT(f poly) poly {
+	i := 127
+	for length := 2; length <= 128; length *= 2 {
+		for start := 0; start < n; start += 2 * length {
+			zeta := zetas[i]
+			i--
+
+			for j := start; j < start+length; j++ {
+				t := f[j]
+				f[j] = add(t, f[j+length])
+				f[j+length] = mul(zeta, sub(f[j+length], t))
+			}
+		}
+	}
+
+	// 3303 is 128^-1 mod q.
+	for j := range f {
+		f[j] = mul(f[j], 3303)
+	}
+
+	return f
+}
+
+// multiplyNTTs multiplies two polynomials in their NTT representation (FIPS
+// 203, Algorithms 11 and 12).
+func multiplyNTTs(f, g poly) poly {
+	var h poly
+	for i := range 128 {
+		a0, a1 := f[2*i], f[2*i+1]
+		b0, b1 := g[2*i], g[2*i+1]
+
+		h[2*i] = add(mul(a0, b0), mul(mul(a1, b1), gammas[i]))
+		h[2*i+1] = add(mul(a0, b1), mul(a1, b0))
+	}
+
+	return h
+}
+
+// compress maps the field element to d bits (round(2^d / q * x) mod 2^d).
+func compress(x fieldElement, d int) uint16 {
+	// As q is odd, the exact result is never halfway between two integers
+	// which is why adding (q - 1) / 2 before dividing rounds correctly.
+	return uint16(((uint32(x)<<d)+(q-1)/2)/q) & (1<<d - 1)
+}
+
+// decompress maps the d bit value back to a field element (round(q / 2^d * y)).
+func decompress(y uint16, d int) fieldElement {
+	return fieldElement((uint32(y)*q + 1<<(d-1)) >> d)
+}
+
+// byteEncode encodes the d bit values of the polynomial (FIPS 203,
+// Algorithm 5). The values are packed in little endian bit order.
+func byteEncode(f [n]uint16, d int) []byte {
+	out := make([]byte, n*d/8)
+
+	bit := 0
+	for _, a := range f {
+		for j := range d {
+			out[bit/8] |= byte(a>>j&1) << (bit % 8)
+			bit++
+		}
+	}
+
+	return out
+}
+
+// byteDecode decodes d bit values (FIPS 203, Algorithm 6).
+func byteDecode(b []byte, d int) [n]uint16 {
+	var f [n]uint16
+
+	bit := 0
+	for i := range f {
+		for j := range d {
+			f[i] |= uint16(b[bit/8]>>(bit%8)&1) << j
+			bit++
+		}
+	}
+
+	return f
+}
+
+// encodePoly encodes the (reduced) coefficients of the polynomial with 12 bits
+// each.
+func encodePoly(f poly) []byte {
+	var values [n]uint16
+	for i, c := range f {
+		values[i] = uint16(c)
+	}
+
+	return byteEncode(values, 12)
+}
+
+// decodePoly decodes the 12 bit coefficients of a polynomial.
+// Returns false if a coefficient isn't reduced (i.e. not less than q).
+func decodePoly(b []byte) (poly, bool) {
+	var f poly
+
+	for i, v := range byteDecode(b, 12) {
+		if v >= q {
+			return poly{}, false
+		}
+		f[i] = fieldElement(v)
+	}
+
+	return f, true
+}
+
+// compressPoly compresses and encodes the coefficients of the polynomial with d
+// bits each.
+func compressPoly(f poly, d int) []byte {
+	var values [n]uint16
+	for i, c := range f {
+		values[i] = compress(c, d)
+	}
+
+	return byteEncode(values, d)
+}
+
+// decompressPoly decodes and decompresses the d bit coefficients of a
+// polynomial.
+func decompressPoly(b []byte, d int) poly {
+	var f poly
+	for i, v := range byteDecode(b, d) {
+		f[i] = decompress(v, d)
+	}
+
+	return f
+}
@@ -0,0 +1,369 @@
+// Package mlkem implements the ML-KEM-768 key encapsulation mechanism as
+// specified in https://nvlpubs.nist.gov/nistpubs/FIPS/NIST.FIPS.203.pdf.
+//
+// ML-KEM is based on the hardness of the module learning with errors (MLWE)
+// problem which is believed to be secure against attacks by quantum computers.
+// ML-KEM-768 targets NIST security category 3.
+package mlkem
+
+import (
+	"slices"
+
+	"github.com/pmuens/ctk-go/ctk/random"
+	"github.com/pmuens/ctk-go/ctk/sha3"
+	"github.com/pmuens/ctk-go/ctk/subtle"
+)
+
+const (
+	// ErrInvalidSeed is returned if a decapsulation key seed doesn't have
+	// SeedSize bytes.
+	ErrInvalidSeed = Error("invalid seed")
+
+	// ErrInvalidEncapsulationKey is returned if an encapsulation key has the
+	// wrong size or isn't properly reduced.
+	ErrInvalidEncapsulationKey = Error("invalid encapsulation key")
+
+	// ErrInvalidCiphertext is returned if a ciphertext has the wrong size.
+	ErrInvalidCiphertext = Error("invalid ciphertext")
+)
+
+// Parameters of ML-KEM-768.
+const (
+	// k is the module rank.
+	k = 3
+
+	// eta1 is the parameter of the distribution of the secret and the noise of
+	// the key generation.
+	eta1 = 2
+
+	// eta2 is the parameter of the distribution of the noise of the encryption.
+	eta2 = 2
+
+	// du is the number of bits the coefficients of u are compressed to.
+	du = 10
+
+	// dv is the number of bits the coefficients of v are compressed to.
+	dv = 4
+)
+
+// SeedSize is the size (in bytes) of the seed a decapsulation key is derived
+// from.
+const SeedSize = 64
+
+// SharedKeySize is the size (in bytes) of the shared key.
+const SharedKeySize = 32
+
+// EncapsulationKeySize is the size (in bytes) of an encoded encapsulation key.
+const EncapsulationKeySize = 384*k + 32
+
+// CiphertextSize is the size (in bytes) of a ciphertext.
+const CiphertextSize = 32 * (du*k + dv)
+
+// EncapsulationKey is the public key of ML-KEM-768.
+type EncapsulationKey struct {
+	// encoded is the encoded key.
+	encoded []byte
+
+	// h is the SHA3-256 hash of the encoded key.
+	h [32]byte
+
+	// t is the public vector (in NTT representation).
+	t [k]poly
+
+	// a is the matrix derived from rho (in NTT representation).
+	a [k][k]poly
+}
+
+// DecapsulationKey is the private key of ML-KEM-768.
+type DecapsulationKey struct {
+	// seed is the seed (d || z) the key was derived from.
+	seed [SeedSize]byte
+
+	// s is the secret vector (in NTT representation).
+	s [k]poly
+
+	// z is the implicit rejection value.
+	z [32]byte
+
+	// ek is the corresponding encapsulation key.
+	ek *EncapsulationKey
+}
+
+// GenerateKey generates a new decapsulation key using randomness from
+// crypto/rand (see random.Read for the health checks).
+func GenerateKey() (*DecapsulationKey, error) {
+	seed, err := random.Bytes(SeedSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDecapsulationKey(seed)
+}
+
+// NewDecapsulationKey derives a decapsulation key from the seed (the d || z
+// form of FIPS 203).
+// Returns an error if the seed doesn't have SeedSize bytes.
+func NewDecapsulationKey(seed []byte) (*DecapsulationKey, error) {
+	if len(seed) != SeedSize {
+		return nil, ErrInvalidSeed
+	}
+
+	dk := &DecapsulationKey{
+		seed: [SeedSize]byte(seed),
+		z:    [32]byte(seed[32:]),
+		ek:   &EncapsulationKey{},
+	}
+
+	// K-PKE.KeyGen (FIPS 203, Algorithm 13).
+	g := sha3.Sum512(append(slices.Clone(seed[:32]), k))
+	rho, sigma := g[:32], g[32:]
+
+	dk.ek.a = expandMatrix(rho)
+
+	var counter byte
+	for i := range k {
+		dk.s[i] = ntt(samplePolyCBD(prf(sigma, counter, eta1), eta1))
+		counter++
+	}
+
+	var e [k]poly
+	for i := range k {
+		e[i] = ntt(samplePolyCBD(prf(sigma, counter, eta1), eta1))
+		counter++
+	}
+
+	for i := range k {
+		t := e[i]
+		for j := range k {
+			t = addPoly(t, multiplyNTTs(dk.ek.a[i][j], dk.s[j]))
+		}
+		dk.ek.t[i] = t
+	}
+
+	encoded := make([]byte, 0, EncapsulationKeySize)
+	for i := range k {
+		encoded = append(encoded, encodePoly(dk.ek.t[i])...)
+	}
+	encoded = append(encoded, rho...)
+
+	dk.ek.encoded = encoded
+	dk.ek.h = sha3.Sum256(encoded)
+
+	return dk, nil
+}
+
+// Bytes returns the seed of the decapsulation key.
+func (dk *DecapsulationKey) Bytes() []byte {
+	return slices.Clone(dk.seed[:])
+}
+
+// EncapsulationKey returns the corresponding encapsulation key.
+func (dk *DecapsulationKey) EncapsulationKey() *EncapsulationKey {
+	return dk.ek
+}
+
+// Decapsulate returns the shared key of the ciphertext. A ciphertext that
+// wasn't created for the key results in a pseudorandom shared key (implicit
+// rejection) rather than an error (FIPS 203, Algorithm 18).
+// Returns an error if the ciphertext has the wrong size.
+func (dk *DecapsulationKey) Decapsulate(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) != CiphertextSize {
+		return []byte{}, ErrInvalidCiphertext
+	}
+
+	m := dk.decrypt(ciphertext)
+
+	g := sha3.Sum512(slices.Concat(m, dk.ek.h[:]))
+	sharedKey, r := g[:32], g[32:]
+
+	rejectionKey := sha3.SumShake256(slices.Concat(dk.z[:], ciphertext), SharedKeySize)
+
+	// Re-encrypt the message to check that the ciphertext is valid.
+	reencrypted := dk.ek.encrypt(m, r)
+	valid := subtle.ConstantTimeCompare(ciphertext, reencrypted)
+	subtle.ConstantTimeCopy(1-valid, sharedKey, rejectionKey)
+
+	return slices.Clone(sharedKey), nil
+}
+
+// decrypt decrypts the ciphertext (K-PKE.Decrypt, FIPS 203, Algorithm 15).
+func (dk *DecapsulationKey) decrypt(ciphertext []byte) []byte {
+	var u [k]poly
+	for i := range k {
+		u[i] = decompressPoly(ciphertext[32*du*i:32*du*(i+1)], du)
+	}
+	v := decompressPoly(ciphertext[32*du*k:], dv)
+
+	var sTu poly
+	for i := range k {
+		sTu = addPoly(sTu, multiplyNTTs(dk.s[i], ntt(u[i])))
+	}
+
+	w := subPoly(v, inverseNTT(sTu))
+
+	return compressPoly(w, 1)
+}
+
+// NewEncapsulationKey decodes an encapsulation key.
+// Returns an error if the key has the wrong size or isn't properly reduced
+// (FIPS 203, section 7.2).
+func NewEncapsulationKey(encoded []byte) (*EncapsulationKey, error) {
+	if len(encoded) != EncapsulationKeySize {
+		return nil, ErrInvalidEncapsulationKey
+	}
+
+	ek := &EncapsulationKey{
+		encoded: slices.Clone(encoded),
+		h:       sha3.Sum256(encoded),
+	}
+
+	for i := range k {
+		t, ok := decodePoly(encoded[384*i : 384*(i+1)])
+		if !ok {
+			return nil, ErrInvalidEncapsulationKey
+		}
+		ek.t[i] = t
+	}
+
+	ek.a = expandMatrix(encoded[384*k:])
+
+	return ek, nil
+}
+
+// Bytes returns the encoded encapsulation key.
+func (ek *EncapsulationKey) Bytes() []byte {
+	return slices.Clone(ek.encoded)
+}
+
+// Encapsulate generates a shared key and a ciphertext which encapsulates it
+// for the owner of the corresponding decapsulation key (FIPS 203,
+// Algorithm 17).
+func (ek *EncapsulationKey) Encapsulate() ([]byte, []byte, error) {
+	m, err := random.Bytes(32)
+	if err != nil {
+		return []byte{}, []byte{}, err
+	}
+
+	sharedKey, ciphertext := ek.encapsulate(m)
+
+	return sharedKey, ciphertext, nil
+}
+
+// encapsulate encapsulates the message m (ML-KEM.Encaps_internal).
+func (ek *EncapsulationKey) encapsulate(m []byte) ([]byte, []byte) {
+	g := sha3.Sum512(slices.Concat(m, ek.h[:]))
+	sharedKey, r := g[:32], g[32:]
+
+	return slices.Clone(sharedKey), ek.encrypt(m, r)
+}
+
+// encrypt encrypts the message with the randomness r (K-PKE.Encrypt, FIPS 203,
+// Algorithm 14).
+func (ek *EncapsulationKey) encrypt(m []byte, r []byte) []byte {
+	var counter byte
+
+	var y [k]poly
+	for i := range k {
+		y[i] = ntt(samplePolyCBD(prf(r, counter, eta1), eta1))
+		counter++
+	}
+
+	var e1 [k]poly
+	for i := range k {
+		e1[i] = samplePolyCBD(prf(r, counter, eta2), eta2)
+		counter++
+	}
+
+	e2 := samplePolyCBD(prf(r, counter, eta2), eta2)
+
+	ciphertext := make([]byte, 0, CiphertextSize)
+
+	// u = NTT^-1(A^T * y) + e1
+	for i := range k {
+		var u poly
+		for j := range k {
+			u = addPoly(u, multiplyNTTs(ek.a[j][i], y[j]))
+		}
+		u = addPoly(inverseNTT(u), e1[i])
+
+		ciphertext = append(ciphertext, compressPoly(u, du)...)
+	}
+
+	// v = NTT^-1(t^T * y) + e2 + mu
+	var v poly
+	for i := range k {
+		v = addPoly(v, multiplyNTTs(ek.t[i], y[i]))
+	}
+	mu := decompressPoly(m, 1)
+	v = addPoly(addPoly(inverseNTT(v), e2), mu)
+
+	return append(ciphertext, compressPoly(v, dv)...)
+}
+
+// expandMatrix samples the matrix A (in NTT representation) from the seed rho.
+func expandMatrix(rho []byte) [k][k]poly {
+	var a [k][k]poly
+	for i := range k {
+		for j := range k {
+			a[i][j] = sampleNTT(rho, byte(j), byte(i))
+		}
+	}
+
+	return a
+}
+
+// prf is the pseudorandom function PRF_eta (SHAKE256 of s || b with 64 * eta
+// bytes of output).
+func prf(s []byte, b byte, eta int) []byte {
+	return sha3.SumShake256(append(slices.Clone(s), b), 64*eta)
+}
+
+// sampleNTT samples a uniformly random polynomial in NTT representation from
+// the seed and the two indices via rejection sampling (FIPS 203, Algorithm 7).
+func sampleNTT(rho []byte, j byte, i byte) poly {
+	xof := sha3.NewShake128()
+	xof.Write(rho)
+	xof.Write([]byte{j, i})
+
+	var a poly
+	var c [3]byte
+
+	for count := 0; count < n; {
+		xof.Read(c[:])
+
+		d1 := uint16(c[0]) | uint16(c[1]&0x0f)<<8
+		d2 := uint16(c[1]>>4) | uint16(c[2])<<4
+
+		if d1 < q {
+			a[count] = fieldElement(d1)
+			count++
+		}
+
+		if d2 < q && count < n {
+			a[count] = fieldElement(d2)
+			count++
+		}
+	}
+
+	return a
+}
+
+// samplePolyCBD samples a polynomial from the centered binomial distribution
+// with the parameter eta using 64 * eta random bytes (FIPS 203, Algorithm 8).
+func samplePolyCBD(b []byte, eta int) poly {
+	bit := func(i int) fieldElement {
+		return fieldElement(b[i/8] >> (i % 8) & 1)
+	}
+
+	var f poly
+	for i := range f {
+		var x, y fieldElement
+		for j := range eta {
+			x += bit(2*i*eta + j)
+			y += bit(2*i*eta + eta + j)
+		}
+		f[i] = sub(x, y)
+	}
+
+	return f
+}
@@ -0,0 +1,103 @@
+package mlkem
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/sha3"
+)
+
+func TestMLKEMDeterministic(t *testing.T) {
+	t.Parallel()
+
+	// The expected values were generated with Go's crypto/mlkem (and
+	// crypto/mlkem/mlkemtest for the deterministic encapsulation).
+	seed := make([]byte, SeedSize)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+
+	m := make([]byte, 32)
+	for i := range m {
+		m[i] = byte(64 + i)
+	}
+
+	wantEncapsulationKeyHash := [32]byte{
+		0xa2, 0x4e, 0x16, 0xd8, 0xf8, 0xf9, 0x38, 0x3a, 0x95, 0xb7, 0x70, 0x50, 0xf4, 0xd9, 0xfd, 0x2f,
+		0x57, 0x33, 0xee, 0xc1, 0xd6, 0x3e, 0xf3, 0xc2, 0x3e, 0xbf, 0x99, 0x18, 0x17, 0x36, 0x69, 0xa7,
+	}
+
+	wantSharedKey := []byte{
+		0x9c, 0xdd, 0xd0, 0x89, 0xff, 0xe7, 0x0e, 0x39, 0x96, 0xe7, 0x6f, 0x7c, 0x8d, 0x06, 0x74, 0x6d,
+		0xf3, 0x4d, 0x07, 0xe8, 0x65, 0x7b, 0xc0, 0xfc, 0xf2, 0xbb, 0x0e, 0x1c, 0x30, 0x84, 0xae, 0xa1,
+	}
+
+	wantCiphertextHash := [32]byte{
+		0xb4, 0xcf, 0xbd, 0x24, 0xce, 0xf6, 0x7a, 0xfd, 0x37, 0x64, 0x27, 0x6c, 0x69, 0x80, 0xe0, 0xf8,
+		0x8f, 0x8e, 0x9c, 0xa5, 0x7f, 0x59, 0xb7, 0xf1, 0x2f, 0xe1, 0xa9, 0xc1, 0xe7, 0x2f, 0x47, 0x10,
+	}
+
+	// The shared key that's returned if the first bit of the ciphertext is
+	// flipped (implicit rejection).
+	wantRejectionKey := []byte{
+		0xdc, 0xfc, 0x80, 0xc6, 0xdb, 0x46, 0xff, 0x70, 0x28, 0xe3, 0xa4, 0x39, 0x86, 0x51, 0xc0, 0x63,
+		0xae, 0x7a, 0x42, 0xc1, 0x07, 0xa6, 0xdc, 0x8c, 0xb0, 0x71, 0x41, 0x86, 0x16, 0x98, 0xab, 0x92,
+	}
+
+	dk, err := NewDecapsulationKey(seed)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	ek := dk.EncapsulationKey()
+	if got := sha3.Sum256(ek.Bytes()); got != wantEncapsulationKeyHash {
+		t.Errorf("want encapsulation key hash %v, got %v", wantEncapsulationKeyHash, got)
+	}
+
+	sharedKey, ciphertext := ek.encapsulate(m)
+	if !slices.Equal(sharedKey, wantSharedKey) {
+		t.Errorf("want shared key %v, got %v", wantSharedKey, sharedKey)
+	}
+
+	if got := sha3.Sum256(ciphertext); got != wantCiphertextHash {
+		t.Errorf("want ciphertext hash %v, got %v", wantCiphertextHash, got)
+	}
+
+	got, err := dk.Decapsulate(ciphertext)
+	if err != nil || !slices.Equal(got, wantSharedKey) {
+		t.Errorf("want shared key %v, got %v (error %v)", wantSharedKey, got, err)
+	}
+
+	ciphertext[0] ^= 0x01
+
+	got, err = dk.Decapsulate(ciphertext)
+	if err != nil || !slices.Equal(got, wantRejectionKey) {
+		t.Errorf("want rejection key %v, got %v (error %v)", wantRejectionKey, got, err)
+	}
+}
+
+func TestMLKEMCompress(t *testing.T) {
+	t.Parallel()
+
+	// Decompressing and compressing again is the identity for all d.
+	for _, d := range []int{1, 4, 10, 11} {
+		for y := range uint16(1 << d) {
+			if got := compress(decompress(y, d), d); got != y {
+				t.Errorf("d = %v: want %v, got %v", d, y, got)
+			}
+		}
+	}
+}
+
+func TestMLKEMNTT(t *testing.T) {
+	t.Parallel()
+
+	var f poly
+	for i := range f {
+		f[i] = fieldElement(i * 13 % q)
+	}
+
+	if got := inverseNTT(ntt(f)); got != f {
+		t.Errorf("want %v, got %v", f, got)
+	}
+}
@@ -0,0 +1,107 @@
+package mlkem_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/mlkem"
+)
+
+func TestMLKEM(t *testing.T) {
+	t.Run("Encapsulate + Decapsulate", func(t *testing.T) {
+		t.Parallel()
+
+		dk, err := mlkem.GenerateKey()
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		ek, err := mlkem.NewEncapsulationKey(dk.EncapsulationKey().Bytes())
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		sharedKey, ciphertext, err := ek.Encapsulate()
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if len(sharedKey) != mlkem.SharedKeySize || len(ciphertext) != mlkem.CiphertextSize {
+			t.Errorf("want sizes %v and %v, got %v and %v", mlkem.SharedKeySize, mlkem.CiphertextSize, len(sharedKey), len(ciphertext))
+		}
+
+		got, err := dk.Decapsulate(ciphertext)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if !slices.Equal(got, sharedKey) {
+			t.Errorf("want %v, got %v", sharedKey, got)
+		}
+
+		// A decapsulation key restored from its seed derives the same shared key.
+		restored, err := mlkem.NewDecapsulationKey(dk.Bytes())
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		got, _ = restored.Decapsulate(ciphertext)
+		if !slices.Equal(got, sharedKey) {
+			t.Errorf("want %v, got %v", sharedKey, got)
+		}
+	})
+
+	t.Run("Implicit Rejection", func(t *testing.T) {
+		t.Parallel()
+
+		dk, _ := mlkem.GenerateKey()
+		other, _ := mlkem.GenerateKey()
+
+		sharedKey, ciphertext, _ := dk.EncapsulationKey().Encapsulate()
+
+		got, err := other.Decapsulate(ciphertext)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if slices.Equal(got, sharedKey) {
+			t.Errorf("want different shared keys, got %v twice", got)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		dk, _ := mlkem.GenerateKey()
+		ek := dk.EncapsulationKey().Bytes()
+
+		_, err := mlkem.NewDecapsulationKey(make([]byte, mlkem.SeedSize-1))
+		if !errors.Is(err, mlkem.ErrInvalidSeed) {
+			t.Errorf("want error %v, got %v", mlkem.ErrInvalidSeed, err)
+		}
+
+		// The first coefficient is set to 0xfff which isn't reduced.
+		unreduced := slices.Clone(ek)
+		unreduced[0] = 0xff
+		unreduced[1] |= 0x0f
+
+		tt := map[string][]byte{
+			"Short":     ek[:mlkem.EncapsulationKeySize-1],
+			"Long":      append(slices.Clone(ek), 0x00),
+			"Unreduced": unreduced,
+		}
+
+		for name, encoded := range tt {
+			_, err := mlkem.NewEncapsulationKey(encoded)
+			if !errors.Is(err, mlkem.ErrInvalidEncapsulationKey) {
+				t.Errorf("%v: want error %v, got %v", name, mlkem.ErrInvalidEncapsulationKey, err)
+			}
+		}
+
+		_, err = dk.Decapsulate(make([]byte, mlkem.CiphertextSize-1))
+		if !errors.Is(err, mlkem.ErrInvalidCiphertext) {
+			t.Errorf("want error %v, got %v", mlkem.ErrInvalidCiphertext, err)
+		}
+	})
+}
@@ -0,0 +1,161 @@
+package multirecipient_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/multirecipient"
+	"github.com/pmuens/ctk-go/ctk/pkcs8"
+	"github.com/pmuens/ctk-go/ctk/x25519"
+)
+
+// RFC 7748 (Section 6.1) - Alice's key pair.
+var (
+	rfc7748Private = [32]byte{
+		0x77, 0x07, 0x6d, 0x0a, 0x73, 0x18, 0xa5, 0x7d, 0x3c, 0x16, 0xc1, 0x72, 0x51, 0xb2, 0x66, 0x45,
+		0xdf, 0x4c, 0x2f, 0x87, 0xeb, 0xc0, 0x99, 0x2a, 0xb1, 0x77, 0xfb, 0xa5, 0x1d, 0xb9, 0x2c, 0x2a,
+	}
+	aliceIdentity  = "CTK-SECRET-KEY-1WURK6ZNNRZJH60QKC9E9RVNXGH05CTU8A0QFJ243WLA628DE9S4QXSEPT3"
+	aliceRecipient = "ctk1s5s0qzvfxzn4gayt0hwtg0hhtgxm7wsdycup4a8t5j5ca25mfe4qy5ugy5"
+)
+
+func TestFormat(t *testing.T) {
+	t.Run("Encode", func(t *testing.T) {
+		t.Parallel()
+
+		identity := multirecipient.NewX25519Identity(rfc7748Private)
+
+		if got := identity.String(); got != aliceIdentity {
+			t.Errorf("want %v, got %v", aliceIdentity, got)
+		}
+
+		if got := identity.Recipient().String(); got != aliceRecipient {
+			t.Errorf("want %v, got %v", aliceRecipient, got)
+		}
+	})
+
+	t.Run("Parse", func(t *testing.T) {
+		t.Parallel()
+
+		identity, err := multirecipient.ParseX25519Identity(aliceIdentity)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		recipient, err := multirecipient.ParseX25519Recipient(aliceRecipient)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if got := identity.Recipient().String(); got != recipient.String() {
+			t.Errorf("want %v, got %v", recipient.String(), got)
+		}
+	})
+
+	t.Run("Generate", func(t *testing.T) {
+		t.Parallel()
+
+		identity, err := multirecipient.GenerateX25519Identity()
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		parsed, err := multirecipient.ParseX25519Identity(identity.String())
+		if err != nil || parsed.String() != identity.String() {
+			t.Errorf("want %v, got %v (error %v)", identity, parsed, err)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		typo := []byte(aliceRecipient)
+		typo[10] = 'q'
+
+		recipients := map[string]string{
+			"Typo":             string(typo),
+			"Uppercase":        strings.ToUpper(aliceRecipient),
+			"Identity":         aliceIdentity,
+			"Truncated":        aliceRecipient[:len(aliceRecipient)-1],
+			"Wrong Key Length": "ctk1qqqsyqcyq5rqwzqfpg9scrgwpuphz3fz",
+		}
+
+		for name, s := range recipients {
+			_, err := multirecipient.ParseX25519Recipient(s)
+			if !errors.Is(err, multirecipient.ErrInvalidRecipient) {
+				t.Errorf("%v: want error %v, got %v", name, multirecipient.ErrInvalidRecipient, err)
+			}
+		}
+
+		identities := map[string]string{
+			"Lowercase": strings.ToLower(aliceIdentity),
+			"Recipient": aliceRecipient,
+		}
+
+		for name, s := range identities {
+			_, err := multirecipient.ParseX25519Identity(s)
+			if !errors.Is(err, multirecipient.ErrInvalidIdentity) {
+				t.Errorf("%v: want error %v, got %v", name, multirecipient.ErrInvalidIdentity, err)
+			}
+		}
+	})
+
+	t.Run("Files", func(t *testing.T) {
+		t.Parallel()
+
+		recipients, err := multirecipient.ParseRecipients(strings.NewReader(
+			"# Alice\n" + aliceRecipient + "\n\n  " + aliceRecipient + "  \n",
+		))
+		if err != nil || len(recipients) != 2 {
+			t.Errorf("want %v recipients, got %v (error %v)", 2, len(recipients), err)
+		}
+
+		identities, err := multirecipient.ParseIdentities(strings.NewReader(
+			"# public key: " + aliceRecipient + "\n" + aliceIdentity + "\n",
+		))
+		if err != nil || len(identities) != 1 {
+			t.Errorf("want %v identities, got %v (error %v)", 1, len(identities), err)
+		}
+
+		_, err = multirecipient.ParseRecipients(strings.NewReader("# no recipients\n"))
+		if !errors.Is(err, multirecipient.ErrNoRecipients) {
+			t.Errorf("want error %v, got %v", multirecipient.ErrNoRecipients, err)
+		}
+
+		_, err = multirecipient.ParseIdentities(strings.NewReader(aliceRecipient))
+		if !errors.Is(err, multirecipient.ErrInvalidIdentity) {
+			t.Errorf("want error %v, got %v", multirecipient.ErrInvalidIdentity, err)
+		}
+	})
+
+	t.Run("PEM Files", func(t *testing.T) {
+		t.Parallel()
+
+		public := x25519.PublicKey(rfc7748Private)
+
+		recipients, err := multirecipient.ParseRecipients(bytes.NewReader(pkcs8.MarshalX25519PublicKey(public)))
+		if err != nil || len(recipients) != 1 {
+			t.Fatalf("want %v recipients, got %v (error %v)", 1, len(recipients), err)
+		}
+
+		if got := recipients[0].(*multirecipient.X25519Recipient).String(); got != aliceRecipient {
+			t.Errorf("want %v, got %v", aliceRecipient, got)
+		}
+
+		identities, err := multirecipient.ParseIdentities(bytes.NewReader(pkcs8.MarshalX25519PrivateKey(rfc7748Private)))
+		if err != nil || len(identities) != 1 {
+			t.Fatalf("want %v identities, got %v (error %v)", 1, len(identities), err)
+		}
+
+		if got := identities[0].(*multirecipient.X25519Identity).String(); got != aliceIdentity {
+			t.Errorf("want %v, got %v", aliceIdentity, got)
+		}
+
+		_, err = multirecipient.ParseRecipients(bytes.NewReader(pkcs8.MarshalEd25519PublicKey(public)))
+		if !errors.Is(err, multirecipient.ErrInvalidRecipient) {
+			t.Errorf("want error %v, got %v", multirecipient.ErrInvalidRecipient, err)
+		}
+	})
+}
@@ -0,0 +1,293 @@
+// Package multirecipient implements the encryption of a payload for several
+// recipients (X25519 public keys and / or passphrases) where every recipient
+// can decrypt the payload on its own (like age or OpenPGP).
+//
+// The payload is encrypted once with a random file key. The file key is then
+// wrapped for every recipient in a stanza. A recipient tries its identity on
+// every stanza until one unwraps the file key. The stanzas don't identify the
+// recipients they were created for.
+//
+// Container format:
+//
+//	magic (4) | version (1) | stanza count (2, big endian) | stanzas | header MAC (32) | payload
+//
+// Every stanza is encoded as:
+//
+//	type (1) | body length (2, big endian) | body
+//
+// The header MAC is an HMAC-SHA256 over everything before it with a key derived
+// from the file key so that recipients can't be added, removed or changed
+// without being detected by the other recipients. Note that every recipient
+// knows the file key and could therefore create a new container for the other
+// recipients (the container doesn't authenticate the sender).
+//
+// X25519 recipients and identities are encoded as Bech32 strings with a
+// checksum that detects typos ("ctk1..." for recipients and
+// "CTK-SECRET-KEY-1..." for identities). Recipient and identity files contain
+// one encoded key per line where empty lines and comments ("#") are ignored.
+// PEM encoded X25519 keys (PKCS#8 private keys and SPKI public keys as written
+// by OpenSSL) can be used as identity and recipient files as well.
+//
+// SSH keys can be used as well: ssh-ed25519 public keys are converted to X25519
+// public keys and ssh-rsa public keys wrap the file key with RSA-OAEP. Their
+// stanzas are tagged with a hash prefix of the public key which reveals whether a
+// container was encrypted for a known SSH public key. The corresponding
+// identities are parsed from unencrypted OpenSSH private keys.
+//
+// The payload is a stream container (see the stream package) which is
+// encrypted with a key derived from the file key so that large payloads can be
+// processed without keeping them in memory.
+package multirecipient
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/pmuens/ctk-go/ctk/hkdf"
+	"github.com/pmuens/ctk-go/ctk/random"
+	"github.com/pmuens/ctk-go/ctk/stream"
+)
+
+const (
+	// ErrNoRecipients is returned if a payload is encrypted without recipients.
+	ErrNoRecipients = Error("no recipients")
+
+	// ErrTooManyRecipients is returned if a payload is encrypted for more than
+	// MaxRecipients recipients.
+	ErrTooManyRecipients = Error("too many recipients")
+
+	// ErrInvalidHeader is returned if the container header is malformed, uses
+	// an unsupported version or its MAC is invalid.
+	ErrInvalidHeader = Error("invalid multirecipient header")
+
+	// ErrNoIdentityMatched is returned if none of the identities can unwrap the
+	// file key.
+	ErrNoIdentityMatched = Error("no identity matched a recipient")
+
+	// ErrNoIdentities is returned if an identity file has no identities.
+	ErrNoIdentities = Error("no identities")
+
+	// ErrInvalidRecipient is returned if an encoded recipient can't be parsed.
+	ErrInvalidRecipient = Error("invalid recipient")
+
+	// ErrInvalidIdentity is returned if an encoded identity can't be parsed.
+	ErrInvalidIdentity = Error("invalid identity")
+
+	// ErrEncryptedSSHKey is returned if an SSH private key is encrypted with a
+	// passphrase (which isn't supported).
+	ErrEncryptedSSHKey = Error("encrypted SSH private keys are not supported")
+
+	// ErrIncorrectIdentity is returned by an Identity if it can't unwrap the
+	// file key of a stanza (e.g. because the stanza was created for another
+	// recipient).
+	ErrIncorrectIdentity = Error("incorrect identity")
+)
+
+// Magic identifies the container format.
+const Magic = "CTKM"
+
+// Version is the version of the container format.
+const Version = 1
+
+// FileKeySize is the size (in bytes) of the file key.
+const FileKeySize = 32
+
+// MaxRecipients is the maximum number of recipients of a container which bounds
+// the work that's needed to parse a header.
+const MaxRecipients = 1024
+
+// MACSize is the size (in bytes) of the header MAC.
+const MACSize = sha256.Size
+
+// Info strings used for domain separation in the key derivations.
+var (
+	headerInfo  = []byte("ctk-go multirecipient header")
+	payloadInfo = []byte("ctk-go multirecipient payload")
+)
+
+// Stanza is the file key wrapped for a single recipient.
+type Stanza struct {
+	// Type identifies the kind of recipient (e.g. StanzaX25519).
+	Type byte
+
+	// Body holds the wrapped file key and the parameters to unwrap it.
+	Body []byte
+}
+
+// Recipient wraps file keys for a recipient.
+type Recipient interface {
+	// Wrap wraps the file key in a stanza.
+	Wrap(fileKey []byte) (Stanza, error)
+}
+
+// Identity unwraps file keys that were wrapped for a recipient.
+type Identity interface {
+	// Unwrap unwraps the file key of the stanza.
+	// Returns ErrIncorrectIdentity if the stanza wasn't created for the identity
+	// (other errors abort the decryption).
+	Unwrap(stanza Stanza) ([]byte, error)
+}
+
+// Encrypt writes the header for the recipients to w and returns a writer which
+// encrypts the payload that's written to it. Close needs to be called to write
+// the end of the payload (the underlying writer isn't closed).
+// Returns an error if there are no or too many recipients or if wrapping the
+// file key fails.
+func Encrypt(w io.Writer, recipients ...Recipient) (io.WriteCloser, error) {
+	if len(recipients) == 0 {
+		return nil, ErrNoRecipients
+	}
+
+	if len(recipients) > MaxRecipients {
+		return nil, ErrTooManyRecipients
+	}
+
+	fileKey := make([]byte, FileKeySize)
+
+	err := random.Read(fileKey)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(fileKey)
+
+	header := make([]byte, 0, len(Magic)+3)
+	header = append(header, Magic...)
+	header = append(header, Version)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(recipients)))
+
+	for _, recipient := range recipients {
+		stanza, err := recipient.Wrap(fileKey)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(stanza.Body) > 0xffff {
+			return nil, ErrInvalidHeader
+		}
+
+		header = append(header, stanza.Type)
+		header = binary.BigEndian.AppendUint16(header, uint16(len(stanza.Body)))
+		header = append(header, stanza.Body...)
+	}
+
+	header = append(header, headerMAC(fileKey, header)...)
+
+	_, err = w.Write(header)
+	if err != nil {
+		return nil, err
+	}
+
+	return stream.NewWriter(w, payloadKey(fileKey))
+}
+
+// Decrypt reads the header from r, unwraps the file key with the first identity
+// that matches a stanza and returns a reader of the decrypted payload (see
+// stream.Reader for how errors in the payload are reported).
+// Returns an error if the header is malformed or if no identity matches.
+func Decrypt(r io.Reader, identities ...Identity) (io.Reader, error) {
+	br := bufio.NewReader(r)
+
+	stanzas, header, err := readHeader(br)
+	if err != nil {
+		return nil, err
+	}
+
+	fileKey, err := unwrap(stanzas, identities)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(fileKey)
+
+	mac := make([]byte, MACSize)
+
+	_, err = io.ReadFull(br, mac)
+	if err != nil || !hmac.Equal(mac, headerMAC(fileKey, header)) {
+		return nil, ErrInvalidHeader
+	}
+
+	return stream.NewReader(br, payloadKey(fileKey))
+}
+
+// readHeader reads the header (without the MAC) and returns its stanzas and
+// the raw header bytes.
+func readHeader(r io.Reader) ([]Stanza, []byte, error) {
+	header := make([]byte, len(Magic)+3)
+
+	_, err := io.ReadFull(r, header)
+	if err != nil || string(header[:len(Magic)]) != Magic || header[len(Magic)] != Version {
+		return []Stanza{}, []byte{}, ErrInvalidHeader
+	}
+
+	count := int(binary.BigEndian.Uint16(header[len(Magic)+1:]))
+	if count == 0 || count > MaxRecipients {
+		return []Stanza{}, []byte{}, ErrInvalidHeader
+	}
+
+	stanzas := make([]Stanza, 0, count)
+	for range count {
+		prefix := make([]byte, 3)
+
+		_, err := io.ReadFull(r, prefix)
+		if err != nil {
+			return []Stanza{}, []byte{}, ErrInvalidHeader
+		}
+
+		body := make([]byte, binary.BigEndian.Uint16(prefix[1:]))
+
+		_, err = io.ReadFull(r, body)
+		if err != nil {
+			return []Stanza{}, []byte{}, ErrInvalidHeader
+		}
+
+		stanzas = append(stanzas, Stanza{Type: prefix[0], Body: body})
+		header = append(header, prefix...)
+		header = append(header, body...)
+	}
+
+	return stanzas, header, nil
+}
+
+// unwrap tries the identities on the stanzas and returns the first file key
+// that's unwrapped.
+func unwrap(stanzas []Stanza, identities []Identity) ([]byte, error) {
+	for _, stanza := range stanzas {
+		for _, identity := range identities {
+			fileKey, err := identity.Unwrap(stanza)
+			if errors.Is(err, ErrIncorrectIdentity) {
+				continue
+			}
+			if err != nil {
+				return []byte{}, err
+			}
+
+			if len(fileKey) != FileKeySize {
+				return []byte{}, ErrInvalidHeader
+			}
+
+			return fileKey, nil
+		}
+	}
+
+	return []byte{}, ErrNoIdentityMatched
+}
+
+// headerMAC computes the MAC of the header with a key derived from the file key.
+func headerMAC(fileKey []byte, header []byte) []byte {
+	key, _ := hkdf.Key(sha256.New, fileKey, nil, headerInfo, 32)
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(header)
+
+	return mac.Sum(nil)
+}
+
+// payloadKey derives the key of the payload from the file key.
+func payloadKey(fileKey []byte) [32]byte {
+	key, _ := hkdf.Key(sha256.New, fileKey, nil, payloadInfo, 32)
+
+	return [32]byte(key)
+}
@@ -0,0 +1,203 @@
+package multirecipient_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/argon2"
+	"github.com/pmuens/ctk-go/ctk/multirecipient"
+	"github.com/pmuens/ctk-go/ctk/x25519"
+)
+
+var params = argon2.Params{
+	Variant:     argon2.Argon2id,
+	Time:        1,
+	Memory:      64,
+	Parallelism: 1,
+}
+
+func encrypt(t *testing.T, data []byte, recipients ...multirecipient.Recipient) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	w, err := multirecipient.Encrypt(&buf, recipients...)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	_, err = w.Write(data)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	return buf.Bytes()
+}
+
+func decrypt(container []byte, identities ...multirecipient.Identity) ([]byte, error) {
+	r, err := multirecipient.Decrypt(bytes.NewReader(container), identities...)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return io.ReadAll(r)
+}
+
+func TestMultirecipient(t *testing.T) {
+	data := bytes.Repeat([]byte("Ladies and Gentlemen of the class of '99"), 2000)
+
+	alicePrivate, alicePublic, _ := x25519.GenerateKey()
+	bobPrivate, bobPublic, _ := x25519.GenerateKey()
+	evePrivate, _, _ := x25519.GenerateKey()
+
+	passphrase, err := multirecipient.NewPassphraseRecipient([]byte("passphrase"), params)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	container := encrypt(t, data,
+		multirecipient.NewX25519Recipient(alicePublic),
+		multirecipient.NewX25519Recipient(bobPublic),
+		passphrase,
+	)
+
+	t.Run("Recipients", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]multirecipient.Identity{
+			"Alice":      multirecipient.NewX25519Identity(alicePrivate),
+			"Bob":        multirecipient.NewX25519Identity(bobPrivate),
+			"Passphrase": multirecipient.NewPassphraseIdentity([]byte("passphrase")),
+		}
+
+		for name, identity := range tt {
+			got, err := decrypt(container, identity)
+
+			if !bytes.Equal(got, data) {
+				t.Errorf("%v: want %v bytes, got %v (error %v)", name, len(data), len(got), err)
+			}
+		}
+	})
+
+	t.Run("Multiple Identities", func(t *testing.T) {
+		t.Parallel()
+
+		got, err := decrypt(container,
+			multirecipient.NewX25519Identity(evePrivate),
+			multirecipient.NewPassphraseIdentity([]byte("wrong")),
+			multirecipient.NewX25519Identity(bobPrivate),
+		)
+
+		if !bytes.Equal(got, data) {
+			t.Errorf("want %v bytes, got %v (error %v)", len(data), len(got), err)
+		}
+	})
+
+	t.Run("Identity Recipient", func(t *testing.T) {
+		t.Parallel()
+
+		identity := multirecipient.NewX25519Identity(alicePrivate)
+		other := encrypt(t, data, identity.Recipient())
+
+		got, err := decrypt(other, identity)
+
+		if !bytes.Equal(got, data) {
+			t.Errorf("want %v bytes, got %v (error %v)", len(data), len(got), err)
+		}
+	})
+
+	stanzaSize := 3 + x25519.KeySize + multirecipient.FileKeySize + 40
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		// Alice can still unwrap the file key but Bob's stanza was changed.
+		tampered := bytes.Clone(container)
+		tampered[len(multirecipient.Magic)+3+stanzaSize+3] ^= 0x01
+
+		truncated := container[:len(container)-1]
+
+		wrongVersion := bytes.Clone(container)
+		wrongVersion[len(multirecipient.Magic)] = multirecipient.Version + 1
+
+		alice := multirecipient.NewX25519Identity(alicePrivate)
+
+		tt := map[string]struct {
+			container  []byte
+			identities []multirecipient.Identity
+			err        error
+		}{
+			"No Identity Matched": {
+				container,
+				[]multirecipient.Identity{
+					multirecipient.NewX25519Identity(evePrivate),
+					multirecipient.NewPassphraseIdentity([]byte("wrong")),
+				},
+				multirecipient.ErrNoIdentityMatched,
+			},
+			"Tampered Stanza": {tampered, []multirecipient.Identity{alice}, multirecipient.ErrInvalidHeader},
+			"Wrong Version":   {wrongVersion, []multirecipient.Identity{alice}, multirecipient.ErrInvalidHeader},
+			"Empty":           {[]byte{}, []multirecipient.Identity{alice}, multirecipient.ErrInvalidHeader},
+		}
+
+		for name, tc := range tt {
+			_, err := decrypt(tc.container, tc.identities...)
+
+			if !errors.Is(err, tc.err) {
+				t.Errorf("%v: want error %v, got %v", name, tc.err, err)
+			}
+		}
+
+		_, err := decrypt(truncated, alice)
+		if err == nil {
+			t.Errorf("want error, got %v", err)
+		}
+	})
+
+	t.Run("Removed Recipient", func(t *testing.T) {
+		t.Parallel()
+
+		// Drop the first stanza (Alice) and decrement the stanza count.
+		offset := len(multirecipient.Magic) + 3
+
+		removed := bytes.Clone(container[:offset])
+		removed[offset-1]--
+		removed = append(removed, container[offset+stanzaSize:]...)
+
+		_, err := decrypt(removed, multirecipient.NewX25519Identity(bobPrivate))
+
+		if !errors.Is(err, multirecipient.ErrInvalidHeader) {
+			t.Errorf("want error %v, got %v", multirecipient.ErrInvalidHeader, err)
+		}
+	})
+
+	t.Run("No Recipients", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := multirecipient.Encrypt(io.Discard)
+
+		if !errors.Is(err, multirecipient.ErrNoRecipients) {
+			t.Errorf("want error %v, got %v", multirecipient.ErrNoRecipients, err)
+		}
+	})
+
+	t.Run("Passphrase Params", func(t *testing.T) {
+		t.Parallel()
+
+		expensive := params
+		expensive.Memory = multirecipient.MaxPassphraseMemory + 1
+
+		_, err := multirecipient.NewPassphraseRecipient([]byte("passphrase"), expensive)
+
+		if !errors.Is(err, argon2.ErrInvalidParams) {
+			t.Errorf("want error %v, got %v", argon2.ErrInvalidParams, err)
+		}
+	})
+}
@@ -0,0 +1,292 @@
+package multirecipient
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/pmuens/ctk-go/ctk/argon2"
+	"github.com/pmuens/ctk-go/ctk/hkdf"
+	"github.com/pmuens/ctk-go/ctk/keywrap"
+	"github.com/pmuens/ctk-go/ctk/random"
+	"github.com/pmuens/ctk-go/ctk/x25519"
+)
+
+const (
+	// StanzaX25519 is the type of stanzas for X25519 recipients.
+	StanzaX25519 byte = 1
+
+	// StanzaPassphrase is the type of stanzas for passphrase recipients.
+	StanzaPassphrase byte = 2
+)
+
+// SaltSize is the size (in bytes) of the salt of passphrase stanzas.
+const SaltSize = 16
+
+// Limits of the Argon2 parameters of passphrase stanzas that are enforced when
+// decrypting so that a container can't force excessive work onto a recipient.
+const (
+	// MaxPassphraseTime is the maximum number of passes.
+	MaxPassphraseTime = 16
+
+	// MaxPassphraseMemory is the maximum memory size in KiB (1 GiB).
+	MaxPassphraseMemory = 1 << 20
+
+	// MaxPassphraseParallelism is the maximum number of lanes.
+	MaxPassphraseParallelism = 16
+)
+
+// Sizes of the stanza bodies.
+const (
+	x25519BodySize     = x25519.KeySize + FileKeySize + keywrap.Overhead
+	passphraseBodySize = SaltSize + 13 + FileKeySize + keywrap.Overhead
+)
+
+// x25519Info is the info string used to derive the KEK of X25519 stanzas.
+var x25519Info = []byte("ctk-go multirecipient x25519")
+
+// X25519Recipient wraps file keys for the owner of an X25519 public key.
+type X25519Recipient struct {
+	// public is the public key of the recipient.
+	public [32]byte
+}
+
+// NewX25519Recipient creates a new recipient for the X25519 public key.
+func NewX25519Recipient(public [32]byte) *X25519Recipient {
+	return &X25519Recipient{public: public}
+}
+
+// Wrap wraps the file key with a KEK that's derived from the shared secret of
+// a fresh ephemeral key pair and the recipient's public key.
+// The stanza body is the ephemeral public key followed by the wrapped file key.
+func (r *X25519Recipient) Wrap(fileKey []byte) (Stanza, error) {
+	ephemeralPrivate, ephemeralPublic, err := x25519.GenerateKey()
+	if err != nil {
+		return Stanza{}, err
+	}
+	defer clear(ephemeralPrivate[:])
+
+	shared, err := x25519.SharedSecret(ephemeralPrivate, r.public)
+	if err != nil {
+		return Stanza{}, err
+	}
+	defer clear(shared[:])
+
+	kek, err := x25519KEK(shared, ephemeralPublic, r.public)
+	if err != nil {
+		return Stanza{}, err
+	}
+
+	wrapped, err := keywrap.Wrap(kek, fileKey)
+	if err != nil {
+		return Stanza{}, err
+	}
+
+	body := make([]byte, 0, x25519BodySize)
+	body = append(body, ephemeralPublic[:]...)
+	body = append(body, wrapped...)
+
+	return Stanza{Type: StanzaX25519, Body: body}, nil
+}
+
+// X25519Identity unwraps file keys that were wrapped for an X25519 public key.
+type X25519Identity struct {
+	// private is the private key of the recipient.
+	private [32]byte
+
+	// public is the public key of the recipient.
+	public [32]byte
+}
+
+// NewX25519Identity creates a new identity for the X25519 private key.
+func NewX25519Identity(private [32]byte) *X25519Identity {
+	return &X25519Identity{private: private, public: x25519.PublicKey(private)}
+}
+
+// Recipient returns the recipient that corresponds to the identity.
+func (i *X25519Identity) Recipient() *X25519Recipient {
+	return NewX25519Recipient(i.public)
+}
+
+// Unwrap unwraps the file key of an X25519 stanza.
+// Returns ErrIncorrectIdentity if the stanza isn't an X25519 stanza or if it
+// was created for another public key.
+func (i *X25519Identity) Unwrap(stanza Stanza) ([]byte, error) {
+	if stanza.Type != StanzaX25519 {
+		return []byte{}, ErrIncorrectIdentity
+	}
+
+	if len(stanza.Body) != x25519BodySize {
+		return []byte{}, ErrInvalidHeader
+	}
+
+	ephemeralPublic := [32]byte(stanza.Body[:x25519.KeySize])
+
+	shared, err := x25519.SharedSecret(i.private, ephemeralPublic)
+	if err != nil {
+		return []byte{}, ErrInvalidHeader
+	}
+	defer clear(shared[:])
+
+	kek, err := x25519KEK(shared, ephemeralPublic, i.public)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	fileKey, err := keywrap.Unwrap(kek, stanza.Body[x25519.KeySize:])
+	if err != nil {
+		return []byte{}, ErrIncorrectIdentity
+	}
+
+	return fileKey, nil
+}
+
+// x25519KEK derives the KEK of an X25519 stanza from the shared secret which is
+// bound to the ephemeral and the recipient's public key.
+func x25519KEK(shared [32]byte, ephemeralPublic [32]byte, recipientPublic [32]byte) ([32]byte, error) {
+	salt := make([]byte, 0, 2*x25519.KeySize)
+	salt = append(salt, ephemeralPublic[:]...)
+	salt = append(salt, recipientPublic[:]...)
+
+	kek, err := hkdf.Key(sha256.New, shared[:], salt, x25519Info, 32)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	return [32]byte(kek), nil
+}
+
+// PassphraseRecipient wraps file keys with a KEK that's derived from a
+// passphrase using Argon2.
+//
+// Note that every recipient can decrypt the container on its own so that
+// mixing passphrase and public key recipients limits the security of the
+// container to the strength of the passphrase.
+type PassphraseRecipient struct {
+	// passphrase is the passphrase the KEK is derived from.
+	passphrase []byte
+
+	// params are the Argon2 parameters that are used to derive the KEK.
+	params argon2.Params
+}
+
+// NewPassphraseRecipient creates a new recipient for the passphrase which
+// derives the KEK with the Argon2 parameters (the key length, secret and
+// associated data of the parameters are ignored).
+// Returns an error if the parameters exceed the limits that are enforced when
+// decrypting.
+func NewPassphraseRecipient(passphrase []byte, params argon2.Params) (*PassphraseRecipient, error) {
+	params = argon2.Params{
+		Variant:     params.Variant,
+		Time:        params.Time,
+		Memory:      params.Memory,
+		Parallelism: params.Parallelism,
+		KeyLength:   32,
+	}
+
+	if !validParams(params) {
+		return nil, argon2.ErrInvalidParams
+	}
+
+	return &PassphraseRecipient{passphrase: passphrase, params: params}, nil
+}
+
+// Wrap wraps the file key with a KEK that's derived from the passphrase and a
+// random salt.
+// The stanza body is the salt, the Argon2 parameters (variant, time, memory
+// and parallelism) and the wrapped file key.
+func (r *PassphraseRecipient) Wrap(fileKey []byte) (Stanza, error) {
+	salt := make([]byte, SaltSize)
+
+	err := random.Read(salt)
+	if err != nil {
+		return Stanza{}, err
+	}
+
+	kek, err := passphraseKEK(r.passphrase, salt, r.params)
+	if err != nil {
+		return Stanza{}, err
+	}
+
+	wrapped, err := keywrap.Wrap(kek, fileKey)
+	if err != nil {
+		return Stanza{}, err
+	}
+
+	body := make([]byte, 0, passphraseBodySize)
+	body = append(body, salt...)
+	body = append(body, byte(r.params.Variant))
+	body = binary.BigEndian.AppendUint32(body, r.params.Time)
+	body = binary.BigEndian.AppendUint32(body, r.params.Memory)
+	body = binary.BigEndian.AppendUint32(body, r.params.Parallelism)
+	body = append(body, wrapped...)
+
+	return Stanza{Type: StanzaPassphrase, Body: body}, nil
+}
+
+// PassphraseIdentity unwraps file keys that were wrapped for a passphrase.
+type PassphraseIdentity struct {
+	// passphrase is the passphrase the KEK is derived from.
+	passphrase []byte
+}
+
+// NewPassphraseIdentity creates a new identity for the passphrase.
+func NewPassphraseIdentity(passphrase []byte) *PassphraseIdentity {
+	return &PassphraseIdentity{passphrase: passphrase}
+}
+
+// Unwrap unwraps the file key of a passphrase stanza.
+// Returns ErrIncorrectIdentity if the stanza isn't a passphrase stanza or if
+// the passphrase is incorrect and ErrInvalidHeader if the stanza's Argon2
+// parameters exceed the limits.
+func (i *PassphraseIdentity) Unwrap(stanza Stanza) ([]byte, error) {
+	if stanza.Type != StanzaPassphrase {
+		return []byte{}, ErrIncorrectIdentity
+	}
+
+	if len(stanza.Body) != passphraseBodySize {
+		return []byte{}, ErrInvalidHeader
+	}
+
+	salt := stanza.Body[:SaltSize]
+	params := argon2.Params{
+		Variant:     argon2.Variant(stanza.Body[SaltSize]),
+		Time:        binary.BigEndian.Uint32(stanza.Body[SaltSize+1:]),
+		Memory:      binary.BigEndian.Uint32(stanza.Body[SaltSize+5:]),
+		Parallelism: binary.BigEndian.Uint32(stanza.Body[SaltSize+9:]),
+		KeyLength:   32,
+	}
+
+	if !validParams(params) {
+		return []byte{}, ErrInvalidHeader
+	}
+
+	kek, err := passphraseKEK(i.passphrase, salt, params)
+	if err != nil {
+		return []byte{}, ErrInvalidHeader
+	}
+
+	fileKey, err := keywrap.Unwrap(kek, stanza.Body[SaltSize+13:])
+	if err != nil {
+		return []byte{}, ErrIncorrectIdentity
+	}
+
+	return fileKey, nil
+}
+
+// passphraseKEK derives the KEK of a passphrase stanza.
+func passphraseKEK(passphrase []byte, salt []byte, params argon2.Params) ([32]byte, error) {
+	kek, err := argon2.Key(passphrase, salt, params)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	return [32]byte(kek), nil
+}
+
+// validParams checks whether the Argon2 parameters are within the limits.
+func validParams(params argon2.Params) bool {
+	return params.Variant <= argon2.Argon2id &&
+		params.Time >= 1 && params.Time <= MaxPassphraseTime &&
+		params.Parallelism >= 1 && params.Parallelism <= MaxPassphraseParallelism &&
+		params.Memory >= 8*params.Parallelism && params.Memory <= MaxPassphraseMemory
+}
@@ -0,0 +1,126 @@
+package multirecipient_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/multirecipient"
+)
+
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	return data
+}
+
+func TestSSH(t *testing.T) {
+	data := []byte("Ladies and Gentlemen of the class of '99")
+
+	tt := map[string]struct {
+		public  string
+		private string
+		other   string
+	}{
+		"ssh-ed25519": {"testdata/ssh_ed25519.pub", "testdata/ssh_ed25519", "testdata/ssh_rsa"},
+		"ssh-rsa":     {"testdata/ssh_rsa.pub", "testdata/ssh_rsa", "testdata/ssh_ed25519"},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			recipients, err := multirecipient.ParseRecipients(bytes.NewReader(readFile(t, tc.public)))
+			if err != nil {
+				t.Fatalf("want error %v, got %v", nil, err)
+			}
+
+			identities, err := multirecipient.ParseIdentities(bytes.NewReader(readFile(t, tc.private)))
+			if err != nil {
+				t.Fatalf("want error %v, got %v", nil, err)
+			}
+
+			other, err := multirecipient.ParseIdentities(bytes.NewReader(readFile(t, tc.other)))
+			if err != nil {
+				t.Fatalf("want error %v, got %v", nil, err)
+			}
+
+			container := encrypt(t, data, recipients...)
+
+			got, err := decrypt(container, identities...)
+			if !bytes.Equal(got, data) {
+				t.Errorf("want %v, got %v (error %v)", data, got, err)
+			}
+
+			_, err = decrypt(container, other...)
+			if !errors.Is(err, multirecipient.ErrNoIdentityMatched) {
+				t.Errorf("want error %v, got %v", multirecipient.ErrNoIdentityMatched, err)
+			}
+		})
+	}
+
+	t.Run("Mixed Recipients", func(t *testing.T) {
+		t.Parallel()
+
+		ed25519, err := multirecipient.ParseRecipient(string(readFile(t, "testdata/ssh_ed25519.pub")))
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		x25519, err := multirecipient.ParseRecipient(aliceRecipient)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		ssh, err := multirecipient.ParseSSHIdentity(readFile(t, "testdata/ssh_ed25519"))
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		container := encrypt(t, data, x25519, ed25519)
+
+		for _, identity := range []multirecipient.Identity{multirecipient.NewX25519Identity(rfc7748Private), ssh} {
+			got, err := decrypt(container, identity)
+			if !bytes.Equal(got, data) {
+				t.Errorf("want %v, got %v (error %v)", data, got, err)
+			}
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		public := strings.Fields(string(readFile(t, "testdata/ssh_ed25519.pub")))
+
+		recipients := map[string]string{
+			"Type Mismatch":  "ssh-rsa " + public[1],
+			"Unsupported":    "ecdsa-sha2-nistp256 " + public[1],
+			"Invalid Base64": "ssh-ed25519 " + public[1][1:],
+			"Missing Key":    "ssh-ed25519",
+		}
+
+		for name, s := range recipients {
+			_, err := multirecipient.ParseSSHRecipient(s)
+			if !errors.Is(err, multirecipient.ErrInvalidRecipient) {
+				t.Errorf("%v: want error %v, got %v", name, multirecipient.ErrInvalidRecipient, err)
+			}
+		}
+
+		_, err := multirecipient.ParseSSHIdentity(readFile(t, "testdata/ssh_ed25519_encrypted"))
+		if !errors.Is(err, multirecipient.ErrEncryptedSSHKey) {
+			t.Errorf("want error %v, got %v", multirecipient.ErrEncryptedSSHKey, err)
+		}
+
+		_, err = multirecipient.ParseSSHIdentity(readFile(t, "testdata/ssh_ed25519.pub"))
+		if !errors.Is(err, multirecipient.ErrInvalidIdentity) {
+			t.Errorf("want error %v, got %v", multirecipient.ErrInvalidIdentity, err)
+		}
+	})
+}
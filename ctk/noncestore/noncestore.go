@@ -0,0 +1,431 @@
+// Package noncestore implements a persistent nonce counter so that counter
+// nonces are never reused under the same key, even across restarts and
+// crashes of the application.
+//
+// Nonces are handed out from reservations (blocks of ReservationSize
+// consecutive counter values) which are written ahead to the store's file and
+// synced to disk before any nonce of the block is used. A single sync
+// therefore covers many nonces. The unused nonces of a reservation are skipped
+// when the store is reopened.
+//
+// File format:
+//
+//	header: magic (8) | version (1) | reservation size (8) | base (8) | key ID (32) | checksum (4)
+//	record: limit (8) | checksum (4)
+//
+// All integers are big endian and the checksums are CRC-32C checksums of the
+// preceding fields. The base is the counter value the store was (re)opened
+// with and every record reserves the next block, i.e. the limit of the n-th
+// record is base + n * reservation size. The file is rewritten with the
+// current limit as the base once it holds MaxRecords records.
+//
+// Recovery: A crash while a record is written leaves a partial or corrupt last
+// record. Its reservation might have been persisted which is why Open skips
+// it as well. Any other damage (a corrupt header or a corrupt record before
+// the last one) can't be caused by a crash and is reported as ErrCorrupted
+// rather than guessed at. The checksums only detect accidental corruption;
+// the file needs to be protected against attackers who could roll it back.
+// A store that can't be recovered needs to be replaced via Create together
+// with a new key.
+package noncestore
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/pmuens/ctk-go/ctk/blake2b"
+)
+
+const (
+	// ErrExists is returned by Create if the file already exists.
+	ErrExists = Error("nonce store already exists")
+
+	// ErrNotFound is returned by Open if the file doesn't exist.
+	ErrNotFound = Error("nonce store not found")
+
+	// ErrCorrupted is returned if the file is damaged beyond what a crash can
+	// cause.
+	ErrCorrupted = Error("nonce store corrupted")
+
+	// ErrKeyMismatch is returned if the store belongs to another key.
+	ErrKeyMismatch = Error("nonce store key mismatch")
+
+	// ErrExhausted is returned if all nonces of the store were used.
+	ErrExhausted = Error("nonce store exhausted")
+
+	// ErrClosed is returned if a closed store is used.
+	ErrClosed = Error("nonce store closed")
+)
+
+// magic identifies the file format.
+const magic = "CTKNONCE"
+
+// version is the version of the file format.
+const version = 1
+
+// headerSize is the size (in bytes) of the file header.
+const headerSize = len(magic) + 1 + 8 + 8 + 32 + 4
+
+// recordSize is the size (in bytes) of a record.
+const recordSize = 8 + 4
+
+// DefaultReservationSize is the default number of nonces that are reserved per
+// write.
+const DefaultReservationSize = 1 << 16
+
+// MaxRecords is the number of records after which the file is rewritten.
+const MaxRecords = 1024
+
+// keyIDLabel is used for domain separation when deriving key IDs.
+var keyIDLabel = []byte("ctk-go noncestore key id")
+
+// castagnoli is the CRC-32C table.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// KeyID returns the ID of the key that a store is bound to. It's a keyed
+// BLAKE2b-256 hash which doesn't reveal the key.
+func KeyID(key [32]byte) [32]byte {
+	// The key and digest sizes are valid so that no error can occur.
+	h, _ := blake2b.NewBlake2b(32, key[:])
+	h.Write(keyIDLabel)
+
+	return [32]byte(h.Sum(nil))
+}
+
+// options holds the options of a store.
+type options struct {
+	// reservationSize is the number of nonces reserved per write.
+	reservationSize uint64
+}
+
+// Option configures a store.
+type Option func(*options)
+
+// WithReservationSize sets the number of nonces that are reserved per write
+// (DefaultReservationSize by default). Larger reservations need fewer syncs
+// but skip more nonces when the store is reopened.
+// The option panics if the size is 0.
+func WithReservationSize(size uint64) Option {
+	if size == 0 {
+		panic("noncestore: invalid reservation size")
+	}
+
+	return func(o *options) {
+		o.reservationSize = size
+	}
+}
+
+// Store hands out unique counter values which are persisted in a file.
+// A Store is safe for concurrent use. A file must only be used by a single
+// Store at a time.
+type Store struct {
+	// mu guards the fields below.
+	mu sync.Mutex
+
+	// path is the path of the file.
+	path string
+
+	// f is the file (opened for appending records).
+	f *os.File
+
+	// keyID is the ID of the key the store belongs to.
+	keyID [32]byte
+
+	// reservationSize is the number of nonces reserved per record.
+	reservationSize uint64
+
+	// next is the next counter value to hand out.
+	next uint64
+
+	// limit is the end (exclusive) of the current reservation.
+	limit uint64
+
+	// records is the number of records in the file.
+	records int
+
+	// err is the error of a failed write after which the store can't be used
+	// anymore (as it's unknown whether the reservation was persisted).
+	err error
+}
+
+// Create creates a new store at path (with counter values starting at 0) for
+// the key with the ID (see KeyID).
+// Returns ErrExists if the file already exists so that a store is never
+// silently reset.
+func Create(path string, keyID [32]byte, opts ...Option) (*Store, error) {
+	_, err := os.Lstat(path)
+	if err == nil {
+		return nil, ErrExists
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	return newStore(path, keyID, 0, opts)
+}
+
+// Open opens the store at path for the key with the ID (see KeyID) and
+// recovers from a crash while a reservation was written. The counter values
+// continue after the last (possibly partially) written reservation.
+// Returns ErrNotFound if there's no such file, ErrCorrupted if it's damaged and
+// ErrKeyMismatch if it belongs to another key.
+func Open(path string, keyID [32]byte, opts ...Option) (*Store, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := recoverLimit(data, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return newStore(path, keyID, limit, opts)
+}
+
+// recoverLimit returns the limit of the last (possibly partially written)
+// reservation of the file's data.
+// Returns ErrCorrupted if the data is damaged and ErrKeyMismatch if it
+// belongs to another key.
+func recoverLimit(data []byte, keyID [32]byte) (uint64, error) {
+	if len(data) < headerSize || !validChecksum(data[:headerSize]) {
+		return 0, ErrCorrupted
+	}
+
+	header := data[:headerSize]
+	if string(header[:len(magic)]) != magic || header[len(magic)] != version {
+		return 0, ErrCorrupted
+	}
+
+	fields := header[len(magic)+1:]
+	reservationSize := binary.BigEndian.Uint64(fields)
+	base := binary.BigEndian.Uint64(fields[8:])
+
+	if reservationSize == 0 {
+		return 0, ErrCorrupted
+	}
+
+	if [32]byte(fields[16:48]) != keyID {
+		return 0, ErrKeyMismatch
+	}
+
+	records := data[headerSize:]
+
+	// A partial record counts as a reservation as well.
+	n := uint64((len(records) + recordSize - 1) / recordSize)
+
+	for i := uint64(0); i < n; i++ {
+		record := records[i*recordSize:]
+		last := i == n-1
+
+		if len(record) < recordSize || !validChecksum(record[:recordSize]) {
+			if last {
+				break
+			}
+
+			return 0, ErrCorrupted
+		}
+
+		// The limits only increase by the reservation size.
+		if i+1 > (math.MaxUint64-base)/reservationSize || binary.BigEndian.Uint64(record) != base+(i+1)*reservationSize {
+			return 0, ErrCorrupted
+		}
+	}
+
+	if n > (math.MaxUint64-base)/reservationSize {
+		return 0, ErrCorrupted
+	}
+
+	return base + n*reservationSize, nil
+}
+
+// newStore writes a new file at path which starts at the base and opens it.
+func newStore(path string, keyID [32]byte, base uint64, opts []Option) (*Store, error) {
+	o := options{reservationSize: DefaultReservationSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	s := &Store{
+		path:            path,
+		keyID:           keyID,
+		reservationSize: o.reservationSize,
+		next:            base,
+		limit:           base,
+	}
+
+	err := s.rewrite(base)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Next returns the next counter value. A new reservation is written (and
+// synced) if the current one is used up.
+// Returns ErrExhausted if all counter values were used, ErrClosed if the store
+// was closed and the error of a failed write (after which the store can't be
+// used anymore).
+func (s *Store) Next() (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return 0, s.err
+	}
+
+	if s.next == s.limit {
+		err := s.reserve()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	next := s.next
+	s.next++
+
+	return next, nil
+}
+
+// reserve writes the next reservation ahead. The file is rewritten instead if
+// it holds MaxRecords records.
+func (s *Store) reserve() error {
+	if s.limit > math.MaxUint64-s.reservationSize {
+		return ErrExhausted
+	}
+	limit := s.limit + s.reservationSize
+
+	var err error
+
+	if s.records >= MaxRecords {
+		err = s.rewrite(limit)
+	} else {
+		record := binary.BigEndian.AppendUint64(nil, limit)
+		record = appendChecksum(record)
+
+		_, err = s.f.Write(record)
+		if err == nil {
+			err = s.f.Sync()
+		}
+		s.records++
+	}
+
+	if err != nil {
+		s.err = err
+		return err
+	}
+
+	s.limit = limit
+
+	return nil
+}
+
+// rewrite atomically replaces the file with a file that starts at the base
+// (without records) and reopens it. All counter values below the base count
+// as used once the file is reopened.
+func (s *Store) rewrite(base uint64) error {
+	header := make([]byte, 0, headerSize)
+	header = append(header, magic...)
+	header = append(header, version)
+	header = binary.BigEndian.AppendUint64(header, s.reservationSize)
+	header = binary.BigEndian.AppendUint64(header, base)
+	header = append(header, s.keyID[:]...)
+	header = appendChecksum(header)
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(header)
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	err = syncDir(filepath.Dir(s.path))
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+
+	if s.f != nil {
+		s.f.Close()
+	}
+
+	s.f = f
+	s.records = 0
+
+	return nil
+}
+
+// Close closes the store's file. The unused counter values of the current
+// reservation are skipped when the store is reopened.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if errors.Is(s.err, ErrClosed) {
+		return nil
+	}
+	s.err = ErrClosed
+
+	return s.f.Close()
+}
+
+// appendChecksum appends the CRC-32C checksum of the data to it.
+func appendChecksum(data []byte) []byte {
+	return binary.BigEndian.AppendUint32(data, crc32.Checksum(data, castagnoli))
+}
+
+// validChecksum reports whether the last 4 bytes of the data are the CRC-32C
+// checksum of the preceding bytes.
+func validChecksum(data []byte) bool {
+	n := len(data) - 4
+
+	return crc32.Checksum(data[:n], castagnoli) == binary.BigEndian.Uint32(data[n:])
+}
+
+// syncDir syncs the directory so that a rename in it is persisted.
+func syncDir(dir string) error {
+	// Directories can't be synced via os.File.Sync on Windows.
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
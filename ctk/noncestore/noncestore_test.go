@@ -0,0 +1,358 @@
+package noncestore_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/chacha20poly1305"
+	"github.com/pmuens/ctk-go/ctk/noncestore"
+)
+
+// next returns the next n counter values of the store.
+func next(t *testing.T, s *noncestore.Store, n int) []uint64 {
+	t.Helper()
+
+	values := make([]uint64, n)
+	for i := range values {
+		value, err := s.Next()
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+		values[i] = value
+	}
+
+	return values
+}
+
+// reopen closes the store and opens it again.
+func reopen(t *testing.T, s *noncestore.Store, path string, keyID [32]byte, opts ...noncestore.Option) *noncestore.Store {
+	t.Helper()
+
+	err := s.Close()
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	s, err = noncestore.Open(path, keyID, opts...)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+// modify modifies the file at path.
+func modify(t *testing.T, path string, f func([]byte) []byte) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	err = os.WriteFile(path, f(data), 0o600)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+}
+
+func TestStore(t *testing.T) {
+	keyID := noncestore.KeyID([32]byte{0x01})
+	size := noncestore.WithReservationSize(10)
+
+	t.Run("Reopen", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "nonces")
+
+		s, err := noncestore.Create(path, keyID, size)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		got := next(t, s, 3)
+		if got[0] != 0 || got[2] != 2 {
+			t.Errorf("want %v, got %v", []uint64{0, 1, 2}, got)
+		}
+
+		// The unused values of the reservation are skipped.
+		s = reopen(t, s, path, keyID, size)
+
+		got = next(t, s, 11)
+		if got[0] != 10 || got[10] != 20 {
+			t.Errorf("want values from %v to %v, got %v", 10, 20, got)
+		}
+
+		s = reopen(t, s, path, keyID, size)
+
+		got = next(t, s, 1)
+		if got[0] != 30 {
+			t.Errorf("want %v, got %v", 30, got[0])
+		}
+
+		_, err = s.Next()
+		if err != nil {
+			t.Errorf("want error %v, got %v", nil, err)
+		}
+
+		s.Close()
+
+		_, err = s.Next()
+		if !errors.Is(err, noncestore.ErrClosed) {
+			t.Errorf("want error %v, got %v", noncestore.ErrClosed, err)
+		}
+	})
+
+	t.Run("Rewrite", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "nonces")
+
+		s, err := noncestore.Create(path, keyID, noncestore.WithReservationSize(1))
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+		t.Cleanup(func() { s.Close() })
+
+		got := next(t, s, 3*noncestore.MaxRecords)
+		for i, value := range got {
+			if value != uint64(i) {
+				t.Fatalf("want %v, got %v", i, value)
+			}
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if info.Size() > 100+12*noncestore.MaxRecords {
+			t.Errorf("file wasn't rewritten (%v bytes)", info.Size())
+		}
+
+		s = reopen(t, s, path, keyID)
+
+		value := next(t, s, 1)[0]
+		if value != 3*noncestore.MaxRecords {
+			t.Errorf("want %v, got %v", 3*noncestore.MaxRecords, value)
+		}
+	})
+
+	t.Run("Concurrent Use", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := noncestore.Create(filepath.Join(t.TempDir(), "nonces"), keyID, size)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+		defer s.Close()
+
+		var mu sync.Mutex
+		seen := map[uint64]bool{}
+
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				for range 100 {
+					value, err := s.Next()
+					if err != nil {
+						t.Errorf("want error %v, got %v", nil, err)
+						return
+					}
+
+					mu.Lock()
+					if seen[value] {
+						t.Errorf("value %v was handed out twice", value)
+					}
+					seen[value] = true
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("Crash Recovery", func(t *testing.T) {
+		t.Parallel()
+
+		tests := map[string]struct {
+			modify func([]byte) []byte
+			want   uint64
+		}{
+			"Partial Record": {
+				modify: func(data []byte) []byte {
+					return append(data, 0x00, 0x00, 0x00)
+				},
+				want: 30,
+			},
+			"Corrupt Last Record": {
+				modify: func(data []byte) []byte {
+					data[len(data)-1] ^= 1
+					return data
+				},
+				want: 20,
+			},
+		}
+
+		for name, tc := range tests {
+			path := filepath.Join(t.TempDir(), "nonces")
+
+			s, err := noncestore.Create(path, keyID, size)
+			if err != nil {
+				t.Fatalf("want error %v, got %v", nil, err)
+			}
+			next(t, s, 15)
+			s.Close()
+
+			modify(t, path, tc.modify)
+
+			s, err = noncestore.Open(path, keyID, size)
+			if err != nil {
+				t.Fatalf("%v: want error %v, got %v", name, nil, err)
+			}
+
+			// The damaged reservation is skipped as it might have been used.
+			value := next(t, s, 1)[0]
+			if value != tc.want {
+				t.Errorf("%v: want %v, got %v", name, tc.want, value)
+			}
+			s.Close()
+		}
+	})
+
+	t.Run("Corruption", func(t *testing.T) {
+		t.Parallel()
+
+		tests := map[string]func([]byte) []byte{
+			"Corrupt Header": func(data []byte) []byte {
+				data[10] ^= 1
+				return data
+			},
+			"Corrupt Record": func(data []byte) []byte {
+				data[len(data)-20] ^= 1
+				return data
+			},
+			"Reordered Records": func(data []byte) []byte {
+				n := len(data)
+				last := bytes.Clone(data[n-12:])
+				copy(data[n-12:], data[n-24:n-12])
+				copy(data[n-24:n-12], last)
+				return data
+			},
+			"Truncated Header": func(data []byte) []byte {
+				return data[:20]
+			},
+			"Empty": func(data []byte) []byte {
+				return nil
+			},
+		}
+
+		for name, f := range tests {
+			path := filepath.Join(t.TempDir(), "nonces")
+
+			s, err := noncestore.Create(path, keyID, size)
+			if err != nil {
+				t.Fatalf("want error %v, got %v", nil, err)
+			}
+			next(t, s, 25)
+			s.Close()
+
+			modify(t, path, f)
+
+			_, err = noncestore.Open(path, keyID)
+			if !errors.Is(err, noncestore.ErrCorrupted) {
+				t.Errorf("%v: want error %v, got %v", name, noncestore.ErrCorrupted, err)
+			}
+		}
+	})
+
+	t.Run("Invalid Files", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "nonces")
+
+		_, err := noncestore.Open(path, keyID)
+		if !errors.Is(err, noncestore.ErrNotFound) {
+			t.Errorf("want error %v, got %v", noncestore.ErrNotFound, err)
+		}
+
+		s, err := noncestore.Create(path, keyID)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+		s.Close()
+
+		_, err = noncestore.Create(path, keyID)
+		if !errors.Is(err, noncestore.ErrExists) {
+			t.Errorf("want error %v, got %v", noncestore.ErrExists, err)
+		}
+
+		_, err = noncestore.Open(path, noncestore.KeyID([32]byte{0x02}))
+		if !errors.Is(err, noncestore.ErrKeyMismatch) {
+			t.Errorf("want error %v, got %v", noncestore.ErrKeyMismatch, err)
+		}
+	})
+}
+
+func TestSealer(t *testing.T) {
+	key := [32]byte{0x01}
+	path := filepath.Join(t.TempDir(), "nonces")
+
+	store, err := noncestore.Create(path, noncestore.KeyID(key))
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	sealer, err := noncestore.NewSealer(chacha20poly1305.NewPool(key), store)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	first, err := sealer.Seal([]byte("message"), []byte("aad"))
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	store = reopen(t, store, path, noncestore.KeyID(key))
+
+	sealer, err = noncestore.NewSealer(chacha20poly1305.NewPool(key), store)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	second, err := sealer.Seal([]byte("message"), []byte("aad"))
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	if bytes.Equal(first[:12], second[:12]) {
+		t.Errorf("nonce %x was reused after a restart", first[:12])
+	}
+
+	for _, sealed := range [][]byte{first, second} {
+		got, err := sealer.Open(sealed, []byte("aad"))
+		if string(got) != "message" || err != nil {
+			t.Errorf("want %v, got %s (error %v)", "message", got, err)
+		}
+	}
+
+	first[len(first)-1] ^= 1
+
+	_, err = sealer.Open(first, []byte("aad"))
+	if err == nil {
+		t.Errorf("want an error, got %v", err)
+	}
+
+	_, err = sealer.Open(first[:20], []byte("aad"))
+	if !errors.Is(err, noncestore.ErrInvalidMessage) {
+		t.Errorf("want error %v, got %v", noncestore.ErrInvalidMessage, err)
+	}
+}
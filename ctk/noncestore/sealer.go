@@ -0,0 +1,82 @@
+package noncestore
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+)
+
+const (
+	// ErrInvalidNonceSize is returned if the AEAD's nonces are too short for
+	// the counter values.
+	ErrInvalidNonceSize = Error("invalid nonce size")
+
+	// ErrInvalidMessage is returned if a sealed message is too short.
+	ErrInvalidMessage = Error("invalid message")
+)
+
+// counterSize is the size (in bytes) of a counter value in a nonce.
+const counterSize = 8
+
+// Sealer encrypts messages with an AEAD (e.g. ChaCha20-Poly1305 or AES-GCM)
+// and counter nonces from a Store so that no nonce is ever reused under the
+// key, even across restarts. The Store needs to be bound to the AEAD's key
+// (see KeyID).
+// A Sealer is safe for concurrent use if the AEAD is.
+//
+// Sealed message format:
+//
+//	nonce (zero padding | counter (8, big endian)) | ciphertext | tag
+type Sealer struct {
+	// aead encrypts the messages.
+	aead cipher.AEAD
+
+	// store hands out the counter values.
+	store *Store
+}
+
+// NewSealer creates a Sealer which encrypts messages with the AEAD and takes
+// the nonces from the store.
+// Returns ErrInvalidNonceSize if the AEAD's nonces are shorter than 8 bytes.
+func NewSealer(aead cipher.AEAD, store *Store) (*Sealer, error) {
+	if aead.NonceSize() < counterSize {
+		return nil, ErrInvalidNonceSize
+	}
+
+	return &Sealer{aead: aead, store: store}, nil
+}
+
+// Seal encrypts the plaintext with the next nonce of the store and binds the
+// additional authenticated data (AAD) to it. The nonce is prepended to the
+// result.
+// Returns the errors of Store.Next.
+func (s *Sealer) Seal(plaintext []byte, aad []byte) ([]byte, error) {
+	counter, err := s.store.Next()
+	if err != nil {
+		return []byte{}, err
+	}
+
+	nonceSize := s.aead.NonceSize()
+
+	result := make([]byte, nonceSize, nonceSize+len(plaintext)+s.aead.Overhead())
+	binary.BigEndian.PutUint64(result[nonceSize-counterSize:], counter)
+
+	return s.aead.Seal(result, result[:nonceSize], plaintext, aad), nil
+}
+
+// Open decrypts the sealed message and authenticates the additional
+// authenticated data (AAD).
+// Returns ErrInvalidMessage if the message is too short and the AEAD's error
+// if it can't be authenticated.
+func (s *Sealer) Open(sealed []byte, aad []byte) ([]byte, error) {
+	nonceSize := s.aead.NonceSize()
+	if len(sealed) < nonceSize+s.aead.Overhead() {
+		return []byte{}, ErrInvalidMessage
+	}
+
+	plaintext, err := s.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], aad)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,339 @@
+// Package objcrypt implements client-side encryption of objects for object
+// storage (e.g. S3, GCS or Azure Blob Storage) following the pattern of the
+// AWS S3 encryption client but with XChaCha20-Poly1305.
+//
+// An object is encrypted with a random data-encryption key (DEK) which is
+// wrapped by a kms.KeyProvider. The encrypted DEK, the key ID and the
+// encryption context are returned as Metadata which is meant to be stored in
+// the object's metadata (e.g. as x-amz-meta-* headers) so that the object
+// itself only contains the ciphertext. The object is a stream container (see
+// the stream package) so that uploads and downloads are encrypted and
+// decrypted on the fly without buffering the whole object.
+//
+// The payload key is derived from the DEK and all metadata entries of the
+// package via HKDF-SHA256 so that the metadata (e.g. the encryption context)
+// can't be modified without the decryption failing.
+//
+// Metadata keys are lowercase (as S3 returns them) and are looked up case
+// insensitively. Entries without the MetadataPrefix (e.g. a content type) are
+// ignored.
+package objcrypt
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/pmuens/ctk-go/ctk/hkdf"
+	"github.com/pmuens/ctk-go/ctk/kms"
+	"github.com/pmuens/ctk-go/ctk/random"
+	"github.com/pmuens/ctk-go/ctk/stream"
+)
+
+const (
+	// ErrInvalidMetadata is returned if the metadata of an object is missing
+	// entries or is malformed.
+	ErrInvalidMetadata = Error("invalid object encryption metadata")
+
+	// ErrUnsupportedAlgorithm is returned if an object was encrypted with an
+	// unknown algorithm.
+	ErrUnsupportedAlgorithm = Error("unsupported object encryption algorithm")
+)
+
+// MetadataPrefix is the prefix of the metadata keys of the package.
+const MetadataPrefix = "ctk-"
+
+// Metadata keys (comparable to x-amz-key-v2, x-amz-wrap-alg, x-amz-cek-alg and
+// x-amz-matdesc of the AWS S3 encryption client).
+const (
+	// MetadataWrappedKey is the key of the base64 encoded wrapped DEK.
+	MetadataWrappedKey = MetadataPrefix + "key"
+
+	// MetadataKeyID is the key of the ID of the provider's key the DEK was
+	// wrapped under.
+	MetadataKeyID = MetadataPrefix + "key-id"
+
+	// MetadataWrapAlgorithm is the key of the DEK wrapping algorithm.
+	MetadataWrapAlgorithm = MetadataPrefix + "wrap-alg"
+
+	// MetadataContentAlgorithm is the key of the object encryption algorithm.
+	MetadataContentAlgorithm = MetadataPrefix + "cek-alg"
+
+	// MetadataEncryptionContext is the key of the JSON encoded encryption
+	// context.
+	MetadataEncryptionContext = MetadataPrefix + "matdesc"
+)
+
+// Algorithm identifiers.
+const (
+	// WrapAlgorithm identifies DEKs that are wrapped by a kms.KeyProvider.
+	WrapAlgorithm = "kms"
+
+	// ContentAlgorithm identifies objects that are stream containers.
+	ContentAlgorithm = "xchacha20poly1305-stream"
+)
+
+// DEKSize is the size (in bytes) of the data-encryption key.
+const DEKSize = 32
+
+// keyLabel is used for domain separation when deriving the payload key.
+var keyLabel = []byte("ctk-go objcrypt")
+
+// Metadata are the entries that are stored in the metadata of an encrypted
+// object.
+type Metadata map[string]string
+
+// EncryptionContext returns the decoded encryption context.
+// Returns ErrInvalidMetadata if it's malformed.
+func (m Metadata) EncryptionContext() (map[string]string, error) {
+	encoded, ok := m.get(MetadataEncryptionContext)
+	if !ok {
+		return map[string]string{}, nil
+	}
+
+	var encryptionContext map[string]string
+
+	err := json.Unmarshal([]byte(encoded), &encryptionContext)
+	if err != nil {
+		return map[string]string{}, ErrInvalidMetadata
+	}
+
+	return encryptionContext, nil
+}
+
+// get looks up the entry with the key case insensitively.
+func (m Metadata) get(key string) (string, bool) {
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+
+	return "", false
+}
+
+// options are the configured encryption context and chunk size.
+type options struct {
+	// encryptionContext is the encryption context.
+	encryptionContext map[string]string
+
+	// chunkSize is the chunk size of the stream container.
+	chunkSize int
+}
+
+// Option configures the encryption.
+type Option func(*options)
+
+// WithEncryptionContext stores the (non-secret) encryption context (e.g. the
+// bucket and object key) in the metadata and binds it to the object. The
+// context can be checked via Metadata.EncryptionContext before decrypting.
+func WithEncryptionContext(encryptionContext map[string]string) Option {
+	return func(o *options) {
+		o.encryptionContext = encryptionContext
+	}
+}
+
+// WithChunkSize sets the chunk size of the stream container (see
+// stream.WithChunkSize).
+// EncryptReader returns stream.ErrInvalidChunkSize if it's out of range.
+func WithChunkSize(size int) Option {
+	return func(o *options) {
+		o.chunkSize = size
+	}
+}
+
+// EncryptReader encrypts the object that's read from r with a random DEK
+// which is wrapped by the provider under the key with the key ID. It returns a
+// reader of the encrypted object (which encrypts r on the fly while it's
+// read, e.g. by an upload) and the metadata that has to be stored with it.
+// The context is used to wrap the DEK and stops the encryption once it's done.
+// Returns an error if the DEK can't be generated or wrapped or if an option is
+// invalid.
+func EncryptReader(ctx context.Context, r io.Reader, provider kms.KeyProvider, keyID string, opts ...Option) (io.Reader, Metadata, error) {
+	o := options{chunkSize: stream.DefaultChunkSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.chunkSize < 1 || o.chunkSize > stream.MaxChunkSize {
+		return nil, nil, stream.ErrInvalidChunkSize
+	}
+
+	dek := make([]byte, DEKSize)
+	defer clear(dek)
+
+	err := random.Read(dek)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wrapped, err := provider.WrapKey(ctx, keyID, dek)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	metadata := Metadata{
+		MetadataWrappedKey:       base64.StdEncoding.EncodeToString(wrapped),
+		MetadataKeyID:            keyID,
+		MetadataWrapAlgorithm:    WrapAlgorithm,
+		MetadataContentAlgorithm: ContentAlgorithm,
+	}
+
+	if len(o.encryptionContext) > 0 {
+		encoded, err := json.Marshal(o.encryptionContext)
+		if err != nil {
+			return nil, nil, err
+		}
+		metadata[MetadataEncryptionContext] = string(encoded)
+	}
+
+	key, err := payloadKey(dek, metadata)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer clear(key[:])
+
+	er := &encryptReader{r: r, chunk: make([]byte, o.chunkSize)}
+
+	er.w, err = stream.NewWriter(&er.buf, key, stream.WithChunkSize(o.chunkSize), stream.WithContext(ctx))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return er, metadata, nil
+}
+
+// DecryptReader unwraps the DEK of the object's metadata via the provider and
+// returns a reader of the object's plaintext which decrypts r on the fly
+// (e.g. while it's downloaded). The options configure the stream.Reader.
+// The context is used to unwrap the DEK.
+// Returns ErrInvalidMetadata if the metadata is missing entries or is
+// malformed, ErrUnsupportedAlgorithm if it names an unknown algorithm and an
+// error if the DEK can't be unwrapped. Reading returns stream.ErrDecryption
+// if the object or its metadata was modified.
+func DecryptReader(ctx context.Context, r io.Reader, metadata Metadata, provider kms.KeyProvider, opts ...stream.ReaderOption) (io.Reader, error) {
+	wrapAlgorithm, _ := metadata.get(MetadataWrapAlgorithm)
+	contentAlgorithm, _ := metadata.get(MetadataContentAlgorithm)
+	if wrapAlgorithm != WrapAlgorithm || contentAlgorithm != ContentAlgorithm {
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	keyID, ok := metadata.get(MetadataKeyID)
+	if !ok {
+		return nil, ErrInvalidMetadata
+	}
+
+	encoded, _ := metadata.get(MetadataWrappedKey)
+	wrapped, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(wrapped) == 0 {
+		return nil, ErrInvalidMetadata
+	}
+
+	dek, err := provider.UnwrapKey(ctx, keyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(dek)
+
+	if len(dek) != DEKSize {
+		return nil, ErrInvalidMetadata
+	}
+
+	key, err := payloadKey(dek, metadata)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(key[:])
+
+	return stream.NewReader(r, key, opts...)
+}
+
+// payloadKey derives the key of the stream container from the DEK and the
+// metadata entries with the MetadataPrefix which are sorted by their
+// (lowercased) keys and encoded with length prefixes.
+func payloadKey(dek []byte, metadata Metadata) ([32]byte, error) {
+	entries := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		k = strings.ToLower(k)
+		if strings.HasPrefix(k, MetadataPrefix) {
+			entries[k] = v
+		}
+	}
+
+	info := slices.Clone(keyLabel)
+	for _, k := range slices.Sorted(maps.Keys(entries)) {
+		info = binary.BigEndian.AppendUint32(info, uint32(len(k)))
+		info = append(info, k...)
+		info = binary.BigEndian.AppendUint32(info, uint32(len(entries[k])))
+		info = append(info, entries[k]...)
+	}
+
+	key, err := hkdf.Key(sha256.New, dek, nil, info, 32)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	defer clear(key)
+
+	return [32]byte(key), nil
+}
+
+// encryptReader encrypts the data of the underlying reader while it's read.
+type encryptReader struct {
+	// r is the plaintext reader.
+	r io.Reader
+
+	// w encrypts the plaintext into buf.
+	w *stream.Writer
+
+	// buf holds the encrypted data that wasn't read yet.
+	buf bytes.Buffer
+
+	// chunk is the buffer the plaintext is read into.
+	chunk []byte
+
+	// done is true once the plaintext was read completely and the Writer was
+	// closed.
+	done bool
+
+	// err is the first error of the underlying reader or the Writer.
+	err error
+}
+
+// Read reads the encrypted data.
+// Returns an error if the plaintext can't be read or encrypted.
+func (e *encryptReader) Read(p []byte) (int, error) {
+	for e.buf.Len() == 0 && !e.done && e.err == nil {
+		n, err := e.r.Read(e.chunk)
+		if n > 0 {
+			_, e.err = e.w.Write(e.chunk[:n])
+		}
+
+		switch {
+		case e.err != nil:
+		case err == io.EOF:
+			e.done = true
+			e.err = e.w.Close()
+		case err != nil:
+			e.err = err
+		}
+	}
+
+	if e.buf.Len() > 0 {
+		return e.buf.Read(p)
+	}
+
+	if e.err != nil {
+		return 0, e.err
+	}
+
+	clear(e.chunk)
+
+	return 0, io.EOF
+}
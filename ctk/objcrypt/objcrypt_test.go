@@ -0,0 +1,148 @@
+package objcrypt_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/kms"
+	"github.com/pmuens/ctk-go/ctk/objcrypt"
+	"github.com/pmuens/ctk-go/ctk/stream"
+)
+
+// encrypt encrypts the plaintext and returns the encrypted object and its
+// metadata.
+func encrypt(t *testing.T, provider kms.KeyProvider, plaintext []byte, opts ...objcrypt.Option) ([]byte, objcrypt.Metadata) {
+	t.Helper()
+
+	r, metadata, err := objcrypt.EncryptReader(context.Background(), bytes.NewReader(plaintext), provider, "master", opts...)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	object, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	return object, metadata
+}
+
+// decrypt decrypts the object with its metadata.
+func decrypt(provider kms.KeyProvider, object []byte, metadata objcrypt.Metadata) ([]byte, error) {
+	r, err := objcrypt.DecryptReader(context.Background(), bytes.NewReader(object), metadata, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(r)
+}
+
+func TestObjcrypt(t *testing.T) {
+	provider := kms.NewMemory()
+	provider.AddKey("master", [32]byte{0x01})
+
+	plaintext := bytes.Repeat([]byte("object"), 10000)
+
+	t.Run("Round Trip", func(t *testing.T) {
+		t.Parallel()
+
+		for _, size := range []int{0, 1, 1024, len(plaintext)} {
+			object, metadata := encrypt(t, provider, plaintext[:size], objcrypt.WithChunkSize(1024))
+
+			if bytes.Contains(object, []byte("objectobject")) {
+				t.Errorf("size %v: object contains the plaintext", size)
+			}
+
+			got, err := decrypt(provider, object, metadata)
+			if !bytes.Equal(got, plaintext[:size]) || err != nil {
+				t.Errorf("size %v: want %v bytes, got %v (error %v)", size, size, len(got), err)
+			}
+		}
+	})
+
+	t.Run("Encryption Context", func(t *testing.T) {
+		t.Parallel()
+
+		want := map[string]string{"bucket": "photos", "key": "2024/cat.jpg"}
+		object, metadata := encrypt(t, provider, plaintext, objcrypt.WithEncryptionContext(want))
+
+		got, err := metadata.EncryptionContext()
+		if !maps.Equal(got, want) || err != nil {
+			t.Errorf("want %v, got %v (error %v)", want, got, err)
+		}
+
+		// Metadata keys are case insensitive (e.g. when they're read from
+		// HTTP headers) and unrelated entries are ignored.
+		headers := objcrypt.Metadata{"Content-Type": "image/jpeg"}
+		for k, v := range metadata {
+			headers[strings.ToUpper(k)] = v
+		}
+
+		decrypted, err := decrypt(provider, object, headers)
+		if !bytes.Equal(decrypted, plaintext) || err != nil {
+			t.Errorf("want %v bytes, got %v (error %v)", len(plaintext), len(decrypted), err)
+		}
+
+		modified := maps.Clone(metadata)
+		modified[objcrypt.MetadataEncryptionContext] = `{"bucket":"photos","key":"2024/dog.jpg"}`
+
+		_, err = decrypt(provider, object, modified)
+		if !errors.Is(err, stream.ErrDecryption) {
+			t.Errorf("want error %v, got %v", stream.ErrDecryption, err)
+		}
+	})
+
+	t.Run("Modified Object", func(t *testing.T) {
+		t.Parallel()
+
+		object, metadata := encrypt(t, provider, plaintext)
+		object[len(object)-1] ^= 1
+
+		_, err := decrypt(provider, object, metadata)
+		if !errors.Is(err, stream.ErrDecryption) {
+			t.Errorf("want error %v, got %v", stream.ErrDecryption, err)
+		}
+	})
+
+	t.Run("Invalid Metadata", func(t *testing.T) {
+		t.Parallel()
+
+		object, metadata := encrypt(t, provider, plaintext)
+
+		tests := map[string]struct {
+			key   string
+			value string
+			want  error
+		}{
+			"Unknown Wrap Algorithm":    {objcrypt.MetadataWrapAlgorithm, "rsa", objcrypt.ErrUnsupportedAlgorithm},
+			"Unknown Content Algorithm": {objcrypt.MetadataContentAlgorithm, "aes-gcm", objcrypt.ErrUnsupportedAlgorithm},
+			"Malformed Wrapped Key":     {objcrypt.MetadataWrappedKey, "not base64", objcrypt.ErrInvalidMetadata},
+			"Modified Wrapped Key":      {objcrypt.MetadataWrappedKey, "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA", kms.ErrUnwrap},
+			"Unknown Key ID":            {objcrypt.MetadataKeyID, "other", kms.ErrKeyNotFound},
+		}
+
+		for name, tc := range tests {
+			modified := maps.Clone(metadata)
+			modified[tc.key] = tc.value
+
+			_, err := decrypt(provider, object, modified)
+			if !errors.Is(err, tc.want) {
+				t.Errorf("%v: want error %v, got %v", name, tc.want, err)
+			}
+		}
+	})
+
+	t.Run("Invalid Chunk Size", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, err := objcrypt.EncryptReader(context.Background(), bytes.NewReader(plaintext), provider, "master", objcrypt.WithChunkSize(0))
+		if !errors.Is(err, stream.ErrInvalidChunkSize) {
+			t.Errorf("want error %v, got %v", stream.ErrInvalidChunkSize, err)
+		}
+	})
+}
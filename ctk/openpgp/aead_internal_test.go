@@ -0,0 +1,151 @@
+package openpgp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestOCB(t *testing.T) {
+	// The test vectors of https://datatracker.ietf.org/doc/html/rfc7253#appendix-A
+	// (which share the key).
+	key := decodeHex(t, "000102030405060708090A0B0C0D0E0F")
+
+	tests := []struct {
+		nonce, header, plaintext, ciphertext string
+	}{
+		{"BBAA99887766554433221100", "", "", "785407BFFFC8AD9EDCC5520AC9111EE6"},
+		{"BBAA99887766554433221101", "0001020304050607", "0001020304050607", "6820B3657B6F615A5725BDA0D3B4EB3A257C9AF1F8F03009"},
+		{"BBAA99887766554433221102", "0001020304050607", "", "81017F8203F081277152FADE694A0A00"},
+		{"BBAA99887766554433221103", "", "0001020304050607", "45DD69F8F5AAE72414054CD1F35D82760B2CD00D2F99BFA9"},
+		{"BBAA99887766554433221104", "000102030405060708090A0B0C0D0E0F", "000102030405060708090A0B0C0D0E0F", "571D535B60B277188BE5147170A9A22C3AD7A4FF3835B8C5701C1CCEC8FC3358"},
+		{"BBAA99887766554433221105", "000102030405060708090A0B0C0D0E0F", "", "8CF761B6902EF764462AD86498CA6B97"},
+		{"BBAA99887766554433221106", "", "000102030405060708090A0B0C0D0E0F", "5CE88EC2E0692706A915C00AEB8B2396F40E1C743F52436BDF06D8FA1ECA343D"},
+		{"BBAA99887766554433221107", "000102030405060708090A0B0C0D0E0F1011121314151617", "000102030405060708090A0B0C0D0E0F1011121314151617", "1CA2207308C87C010756104D8840CE1952F09673A448A122C92C62241051F57356D7F3C90BB0E07F"},
+		{"BBAA99887766554433221108", "000102030405060708090A0B0C0D0E0F1011121314151617", "", "6DC225A071FC1B9F7C69F93B0F1E10DE"},
+		{"BBAA99887766554433221109", "", "000102030405060708090A0B0C0D0E0F1011121314151617", "221BD0DE7FA6FE993ECCD769460A0AF2D6CDED0C395B1C3CE725F32494B9F914D85C0B1EB38357FF"},
+		{"BBAA9988776655443322110A", "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F", "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F", "BD6F6C496201C69296C11EFD138A467ABD3C707924B964DEAFFC40319AF5A48540FBBA186C5553C68AD9F592A79A4240"},
+		{"BBAA9988776655443322110D", "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F2021222324252627", "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F2021222324252627", "D5CA91748410C1751FF8A2F618255B68A0A12E093FF454606E59F9C1D0DDC54B65E8628E568BAD7AED07BA06A4A69483A7035490C5769E60"},
+		{"BBAA9988776655443322110F", "", "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F2021222324252627", "4412923493C57D5DE0D700F753CCE0D1D2D95060122E9F15A5DDBFC5787E50B5CC55EE507BCB084E479AD363AC366B95A98CA5F3000B1479"},
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	for _, tc := range tests {
+		nonce := decodeHex(t, tc.nonce)
+		aead := newOCB(block, len(nonce))
+
+		checkAEAD(t, tc.nonce, aead.Seal, aead.Open, nonce, decodeHex(t, tc.header), decodeHex(t, tc.plaintext), decodeHex(t, tc.ciphertext))
+	}
+}
+
+func TestEAX(t *testing.T) {
+	// The test vectors of https://web.cs.ucdavis.edu/~rogaway/papers/eax.pdf.
+	tests := []struct {
+		plaintext, key, nonce, header, ciphertext string
+	}{
+		{"", "233952DEE4D5ED5F9B9C6D6FF80FF478", "62EC67F9C3A4A407FCB2A8C49031A8B3", "6BFB914FD07EAE6B", "E037830E8389F27B025A2D6527E79D01"},
+		{"F7FB", "91945D3F4DCBEE0BF45EF52255F095A4", "BECAF043B0A23D843194BA972C66DEBD", "FA3BFD4806EB53FA", "19DD5C4C9331049D0BDAB0277408F67967E5"},
+		{"1A47CB4933", "01F74AD64077F2E704C0F60ADA3DD523", "70C3DB4F0D26368400A10ED05D2BFF5E", "234A3463C1264AC6", "D851D5BAE03A59F238A23E39199DC9266626C40F80"},
+		{"481C9E39B1", "D07CF6CBB7F313BDDE66B727AFD3C5E8", "8408DFFF3C1A2B1292DC199E46B7D617", "33CCE2EABFF5A79D", "632A9D131AD4C168A4225D8E1FF755939974A7BEDE"},
+		{"40D0C07DA5E4", "35B6D0580005BBC12B0587124557D2C2", "FDB6B06676EEDC5C61D74276E1F8E816", "AEB96EAEBE2970E9", "071DFE16C675CB0677E536F73AFE6A14B74EE49844DD"},
+		{"4DE3B35C3FC039245BD1FB7D", "BD8E6E11475E60B268784C38C62FEB22", "6EAC5C93072D8E8513F750935E46DA1B", "D4482D1CA78DCE0F", "835BB4F15D743E350E728414ABB8644FD6CCB86947C5E10590210A4F"},
+		{"8B0A79306C9CE7ED99DAE4F87F8DD61636", "7C77D6E813BED5AC98BAA417477A2E7D", "1A8C98DCD73D38393B2BF1569DEEFC19", "65D2017990D62528", "02083E3979DA014812F59F11D52630DA30137327D10649B0AA6E1C181DB617D7F2"},
+		{"1BDA122BCE8A8DBAF1877D962B8592DD2D56", "5FFF20CAFAB119CA2FC73549E20F5B0D", "DDE59B97D722156D4D9AFF2BC7559826", "54B9F04E6A09189A", "2EC47B2C4954A489AFC7BA4897EDCDAE8CC33B60450599BD02C96382902AEF7F832A"},
+		{"6CF36720872B8513F6EAB1A8A44438D5EF11", "A4A4782BCFFD3EC5E7EF6D8C34A56123", "B781FCF2F75FA5A8DE97A9CA48E522EC", "899A175897561D7E", "0DE18FD0FDD91E7AF19F1D8EE8733938B1E8E7F6D2231618102FDB7FE55FF1991700"},
+		{"CA40D7446E545FFAED3BD12A740A659FFBBB3CEAB7", "8395FCF1E95BEBD697BD010BC766AAC3", "22E7ADD93CFC6393C57EC0B3C17D6B44", "126735FCC320D25A", "CB8920F87A6C75CFF39627B56E3ED197C552D295A7CFC46AFC253B4652B1AF3795B124AB6E"},
+	}
+
+	for _, tc := range tests {
+		block, err := aes.NewCipher(decodeHex(t, tc.key))
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		nonce := decodeHex(t, tc.nonce)
+		aead := newEAX(block, len(nonce))
+
+		checkAEAD(t, tc.nonce, aead.Seal, aead.Open, nonce, decodeHex(t, tc.header), decodeHex(t, tc.plaintext), decodeHex(t, tc.ciphertext))
+	}
+}
+
+func TestDecryptData(t *testing.T) {
+	sessionKey := bytes.Repeat([]byte{1}, 32)
+	data := bytes.Repeat([]byte{2}, 3*64)
+
+	seipd, err := encryptData(sessionKey, data, AES256, OCB, 0)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	got, err := decryptData(sessionKey, seipd)
+	if !bytes.Equal(got, data) || err != nil {
+		t.Errorf("want %x, got %x (error %v)", data, got, err)
+	}
+
+	// The chunks (with their tags) follow the header and salt and are followed
+	// by the final tag.
+	header := 4 + saltSize
+	chunk := func(i int) []byte {
+		return seipd[header+i*(64+tagSize) : header+(i+1)*(64+tagSize)]
+	}
+	finalTag := seipd[len(seipd)-tagSize:]
+
+	tests := map[string][]byte{
+		"Missing Chunk":     slices.Concat(seipd[:header], chunk(0), chunk(1), finalTag),
+		"Reordered Chunks":  slices.Concat(seipd[:header], chunk(1), chunk(0), chunk(2), finalTag),
+		"Missing Final Tag": slices.Concat(seipd[:header], chunk(0), chunk(1), chunk(2)),
+	}
+
+	for name, modified := range tests {
+		_, err := decryptData(sessionKey, modified)
+		if !errors.Is(err, ErrDecryption) {
+			t.Errorf("%v: want error %v, got %v", name, ErrDecryption, err)
+		}
+	}
+}
+
+// checkAEAD checks that sealing the plaintext results in the ciphertext, that
+// opening the ciphertext results in the plaintext and that opening a tampered
+// ciphertext fails.
+func checkAEAD(
+	t *testing.T,
+	name string,
+	seal func(dst, nonce, plaintext, additionalData []byte) []byte,
+	open func(dst, nonce, ciphertext, additionalData []byte) ([]byte, error),
+	nonce, header, plaintext, ciphertext []byte,
+) {
+	t.Helper()
+
+	if got := seal(nil, nonce, plaintext, header); !bytes.Equal(got, ciphertext) {
+		t.Errorf("%v: want %x, got %x", name, ciphertext, got)
+	}
+
+	if got, err := open(nil, nonce, ciphertext, header); !bytes.Equal(got, plaintext) || err != nil {
+		t.Errorf("%v: want %x, got %x (error %v)", name, plaintext, got, err)
+	}
+
+	tampered := bytes.Clone(ciphertext)
+	tampered[0] ^= 1
+	if _, err := open(nil, nonce, tampered, header); err == nil {
+		t.Errorf("%v: want error, got %v", name, err)
+	}
+}
+
+// decodeHex decodes the hex string.
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	return b
+}
@@ -0,0 +1,83 @@
+package openpgp
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+const (
+	// armorHeader is the first line of an armored message.
+	armorHeader = "-----BEGIN PGP MESSAGE-----"
+
+	// armorFooter is the last line of an armored message.
+	armorFooter = "-----END PGP MESSAGE-----"
+
+	// armorLineLength is the length of the base64 lines of an armored message.
+	armorLineLength = 64
+)
+
+// Armor encodes the binary message with the ASCII armor of RFC 9580 section
+// 6.2 (without armor headers and CRC24 checksum).
+func Armor(message []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(message)
+
+	var b strings.Builder
+	b.WriteString(armorHeader + "\n\n")
+	for len(encoded) > armorLineLength {
+		b.WriteString(encoded[:armorLineLength] + "\n")
+		encoded = encoded[armorLineLength:]
+	}
+	if encoded != "" {
+		b.WriteString(encoded + "\n")
+	}
+	b.WriteString(armorFooter + "\n")
+
+	return b.String()
+}
+
+// Dearmor decodes the ASCII armored message. Armor headers (e.g. "Comment:")
+// and the optional CRC24 checksum are ignored as required by RFC 9580.
+// Returns ErrInvalidMessage if the armor is malformed.
+func Dearmor(armored string) ([]byte, error) {
+	armored = strings.TrimSpace(armored)
+
+	body, ok := strings.CutPrefix(armored, armorHeader)
+	if !ok {
+		return []byte{}, ErrInvalidMessage
+	}
+
+	body, ok = strings.CutSuffix(body, armorFooter)
+	if !ok {
+		return []byte{}, ErrInvalidMessage
+	}
+
+	lines := strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n")
+
+	// The armor headers end with the first empty line.
+	start := -1
+	for i, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			start = i + 2
+			break
+		}
+	}
+	if start < 0 {
+		return []byte{}, ErrInvalidMessage
+	}
+
+	var encoded strings.Builder
+	for _, line := range lines[start:] {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "=") && len(line) == 5 {
+			break
+		}
+		encoded.WriteString(line)
+	}
+
+	message, err := base64.StdEncoding.DecodeString(encoded.String())
+	if err != nil {
+		return []byte{}, ErrInvalidMessage
+	}
+
+	return message, nil
+}
@@ -0,0 +1,129 @@
+package openpgp
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+)
+
+// eax implements EAX as specified in
+// https://web.cs.ucdavis.edu/~rogaway/papers/eax.pdf with 128 bit tags.
+type eax struct {
+	// block is the block cipher.
+	block cipher.Block
+
+	// nonceSize is the size (in bytes) of the nonce.
+	nonceSize int
+
+	// k1 is the first CMAC subkey (the double of the encryption of the zero
+	// block).
+	k1 [blockSize]byte
+
+	// k2 is the second CMAC subkey (the double of k1).
+	k2 [blockSize]byte
+}
+
+// newEAX returns EAX with the block cipher (which must have a 128 bit block
+// size) and the nonce size.
+func newEAX(block cipher.Block, nonceSize int) cipher.AEAD {
+	e := &eax{block: block, nonceSize: nonceSize}
+
+	var l [blockSize]byte
+	block.Encrypt(l[:], l[:])
+	e.k1 = double(l)
+	e.k2 = double(e.k1)
+
+	return e
+}
+
+// NonceSize implements the cipher.AEAD interface.
+func (e *eax) NonceSize() int {
+	return e.nonceSize
+}
+
+// Overhead implements the cipher.AEAD interface.
+func (e *eax) Overhead() int {
+	return blockSize
+}
+
+// Seal implements the cipher.AEAD interface.
+func (e *eax) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != e.nonceSize {
+		panic("openpgp: invalid eax nonce size")
+	}
+
+	ret, out := sliceForAppend(dst, len(plaintext)+blockSize)
+
+	n := e.omac(0, nonce)
+	h := e.omac(1, additionalData)
+	cipher.NewCTR(e.block, n[:]).XORKeyStream(out[:len(plaintext)], plaintext)
+	c := e.omac(2, out[:len(plaintext)])
+
+	tag := out[len(plaintext):]
+	subtle.XORBytes(tag, n[:], h[:])
+	xorBlock(tag, c[:])
+
+	return ret
+}
+
+// Open implements the cipher.AEAD interface.
+func (e *eax) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != e.nonceSize {
+		panic("openpgp: invalid eax nonce size")
+	}
+
+	if len(ciphertext) < blockSize {
+		return nil, errOpen
+	}
+
+	tag := ciphertext[len(ciphertext)-blockSize:]
+	ciphertext = ciphertext[:len(ciphertext)-blockSize]
+
+	n := e.omac(0, nonce)
+	h := e.omac(1, additionalData)
+	c := e.omac(2, ciphertext)
+
+	var expected [blockSize]byte
+	subtle.XORBytes(expected[:], n[:], h[:])
+	xorBlock(expected[:], c[:])
+	if subtle.ConstantTimeCompare(expected[:], tag) != 1 {
+		return nil, errOpen
+	}
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	cipher.NewCTR(e.block, n[:]).XORKeyStream(out, ciphertext)
+
+	return ret, nil
+}
+
+// omac computes OMAC^t (the CMAC of the block encoding t followed by the data).
+func (e *eax) omac(t byte, data []byte) [blockSize]byte {
+	var mac [blockSize]byte
+	mac[blockSize-1] = t
+
+	// The first block is always complete so that the data's last block is
+	// the message's last block (unless the data is empty).
+	if len(data) == 0 {
+		xorBlock(mac[:], e.k1[:])
+		e.block.Encrypt(mac[:], mac[:])
+
+		return mac
+	}
+	e.block.Encrypt(mac[:], mac[:])
+
+	for len(data) > blockSize {
+		xorBlock(mac[:], data[:blockSize])
+		e.block.Encrypt(mac[:], mac[:])
+		data = data[blockSize:]
+	}
+
+	xorBlock(mac[:len(data)], data)
+	if len(data) == blockSize {
+		xorBlock(mac[:], e.k1[:])
+	} else {
+		mac[len(data)] ^= 0x80
+		xorBlock(mac[:], e.k2[:])
+	}
+	e.block.Encrypt(mac[:], mac[:])
+
+	return mac
+}
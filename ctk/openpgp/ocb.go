@@ -0,0 +1,244 @@
+package openpgp
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"math/bits"
+)
+
+// blockSize is the block size (in bytes) of the block ciphers OCB and EAX are
+// used with (AES).
+const blockSize = 16
+
+// ocb implements OCB3 as specified in https://datatracker.ietf.org/doc/html/rfc7253
+// with 128 bit tags (which is the mode OpenPGP implementations are required to
+// support).
+type ocb struct {
+	// block is the block cipher.
+	block cipher.Block
+
+	// nonceSize is the size (in bytes) of the nonce.
+	nonceSize int
+
+	// lStar is L_* (the encryption of the zero block).
+	lStar [blockSize]byte
+
+	// lDollar is L_$ (the double of L_*).
+	lDollar [blockSize]byte
+
+	// l are L_0, L_1, ... (L_0 is the double of L_$ and L_i the double of
+	// L_i-1) which are sufficient for 2^64 blocks.
+	l [64][blockSize]byte
+}
+
+// newOCB returns OCB3 with the block cipher (which must have a 128 bit block
+// size) and the nonce size (1 to 15 bytes).
+func newOCB(block cipher.Block, nonceSize int) cipher.AEAD {
+	o := &ocb{block: block, nonceSize: nonceSize}
+
+	block.Encrypt(o.lStar[:], o.lStar[:])
+	o.lDollar = double(o.lStar)
+	o.l[0] = double(o.lDollar)
+	for i := 1; i < len(o.l); i++ {
+		o.l[i] = double(o.l[i-1])
+	}
+
+	return o
+}
+
+// NonceSize implements the cipher.AEAD interface.
+func (o *ocb) NonceSize() int {
+	return o.nonceSize
+}
+
+// Overhead implements the cipher.AEAD interface.
+func (o *ocb) Overhead() int {
+	return blockSize
+}
+
+// Seal implements the cipher.AEAD interface.
+func (o *ocb) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != o.nonceSize {
+		panic("openpgp: invalid ocb nonce size")
+	}
+
+	ret, out := sliceForAppend(dst, len(plaintext)+blockSize)
+	tag := o.crypt(out[:len(plaintext)], plaintext, nonce, additionalData, true)
+	copy(out[len(plaintext):], tag[:])
+
+	return ret
+}
+
+// Open implements the cipher.AEAD interface.
+func (o *ocb) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != o.nonceSize {
+		panic("openpgp: invalid ocb nonce size")
+	}
+
+	if len(ciphertext) < blockSize {
+		return nil, errOpen
+	}
+
+	tag := ciphertext[len(ciphertext)-blockSize:]
+	ciphertext = ciphertext[:len(ciphertext)-blockSize]
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	expected := o.crypt(out, ciphertext, nonce, additionalData, false)
+	if subtle.ConstantTimeCompare(expected[:], tag) != 1 {
+		clear(out)
+		return nil, errOpen
+	}
+
+	return ret, nil
+}
+
+// crypt en- or decrypts the input into the output and returns the tag.
+func (o *ocb) crypt(out, in, nonce, additionalData []byte, encrypt bool) [blockSize]byte {
+	// The nonce is formatted as 0^(127 - bitlen(N)) || 1 || N (the leading 7
+	// bits encode the tag length modulo 128 which is 0).
+	var formatted [blockSize]byte
+	copy(formatted[blockSize-len(nonce):], nonce)
+	formatted[blockSize-len(nonce)-1] |= 1
+
+	bottom := uint(formatted[blockSize-1] & 63)
+	formatted[blockSize-1] &^= 63
+
+	var kTop [blockSize]byte
+	o.block.Encrypt(kTop[:], formatted[:])
+
+	var stretch [blockSize + 8]byte
+	copy(stretch[:], kTop[:])
+	for i := range 8 {
+		stretch[blockSize+i] = kTop[i] ^ kTop[i+1]
+	}
+
+	// The initial offset are the 128 bits of the stretch starting at bit
+	// position bottom.
+	var offset [blockSize]byte
+	byteShift, bitShift := bottom/8, bottom%8
+	for i := range offset {
+		offset[i] = stretch[uint(i)+byteShift] << bitShift
+		if bitShift != 0 {
+			offset[i] |= stretch[uint(i)+byteShift+1] >> (8 - bitShift)
+		}
+	}
+
+	var checksum, buf [blockSize]byte
+
+	i := 0
+	for ; len(in)-i*blockSize >= blockSize; i++ {
+		inBlock := in[i*blockSize : (i+1)*blockSize]
+		outBlock := out[i*blockSize : (i+1)*blockSize]
+
+		xorBlock(offset[:], o.l[bits.TrailingZeros(uint(i+1))][:])
+
+		if encrypt {
+			xorBlock(checksum[:], inBlock)
+		}
+
+		subtle.XORBytes(buf[:], inBlock, offset[:])
+		if encrypt {
+			o.block.Encrypt(buf[:], buf[:])
+		} else {
+			o.block.Decrypt(buf[:], buf[:])
+		}
+		subtle.XORBytes(outBlock, buf[:], offset[:])
+
+		if !encrypt {
+			xorBlock(checksum[:], outBlock)
+		}
+	}
+
+	if rest := len(in) - i*blockSize; rest > 0 {
+		xorBlock(offset[:], o.lStar[:])
+
+		var pad [blockSize]byte
+		o.block.Encrypt(pad[:], offset[:])
+		subtle.XORBytes(out[i*blockSize:], in[i*blockSize:], pad[:rest])
+
+		plaintext := in[i*blockSize:]
+		if !encrypt {
+			plaintext = out[i*blockSize:]
+		}
+
+		var padded [blockSize]byte
+		copy(padded[:], plaintext)
+		padded[rest] = 0x80
+		xorBlock(checksum[:], padded[:])
+	}
+
+	var tag [blockSize]byte
+	subtle.XORBytes(tag[:], checksum[:], offset[:])
+	xorBlock(tag[:], o.lDollar[:])
+	o.block.Encrypt(tag[:], tag[:])
+
+	hash := o.hash(additionalData)
+	xorBlock(tag[:], hash[:])
+
+	return tag
+}
+
+// hash computes HASH(K, A) of the additional data.
+func (o *ocb) hash(additionalData []byte) [blockSize]byte {
+	var sum, offset, buf [blockSize]byte
+
+	i := 0
+	for ; len(additionalData)-i*blockSize >= blockSize; i++ {
+		xorBlock(offset[:], o.l[bits.TrailingZeros(uint(i+1))][:])
+
+		subtle.XORBytes(buf[:], additionalData[i*blockSize:(i+1)*blockSize], offset[:])
+		o.block.Encrypt(buf[:], buf[:])
+		xorBlock(sum[:], buf[:])
+	}
+
+	if rest := len(additionalData) - i*blockSize; rest > 0 {
+		xorBlock(offset[:], o.lStar[:])
+
+		buf = [blockSize]byte{}
+		copy(buf[:], additionalData[i*blockSize:])
+		buf[rest] = 0x80
+		xorBlock(buf[:], offset[:])
+		o.block.Encrypt(buf[:], buf[:])
+		xorBlock(sum[:], buf[:])
+	}
+
+	return sum
+}
+
+// double doubles the block in GF(2^128) (with the polynomial
+// x^128 + x^7 + x^2 + x + 1).
+func double(b [blockSize]byte) [blockSize]byte {
+	var doubled [blockSize]byte
+
+	carry := b[0] >> 7
+	for i := range blockSize - 1 {
+		doubled[i] = b[i]<<1 | b[i+1]>>7
+	}
+	doubled[blockSize-1] = b[blockSize-1]<<1 ^ carry*0x87
+
+	return doubled
+}
+
+// xorBlock sets dst to dst XOR src.
+func xorBlock(dst, src []byte) {
+	subtle.XORBytes(dst, dst, src)
+}
+
+// sliceForAppend extends the slice by n bytes and returns the extended slice
+// and the n bytes that were appended.
+func sliceForAppend(in []byte, n int) ([]byte, []byte) {
+	total := len(in) + n
+	if cap(in) >= total {
+		head := in[:total]
+		return head, head[len(in):]
+	}
+
+	head := make([]byte, total)
+	copy(head, in)
+
+	return head, head[len(in):]
+}
+
+// errOpen is returned by the AEADs if the authentication fails.
+var errOpen = errors.New("openpgp: message authentication failed")
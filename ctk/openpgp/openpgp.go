@@ -0,0 +1,590 @@
+// Package openpgp implements the passphrase based encryption of OpenPGP
+// messages as specified in https://www.rfc-editor.org/rfc/rfc9580 (OpenPGP v6)
+// so that ciphertexts can be exchanged with modern OpenPGP implementations.
+//
+// Encrypt produces a version 6 Symmetric-Key Encrypted Session Key (SKESK)
+// packet whose session key is derived from the passphrase via Argon2 followed
+// by a version 2 Symmetrically Encrypted and Integrity Protected Data (SEIPD)
+// packet which contains the plaintext as a binary Literal Data packet.
+// The data is encrypted with AES in chunks with one of the AEAD modes of
+// RFC 9580 (OCB by default which every implementation has to support as
+// well as EAX and GCM).
+//
+// Decrypt accepts such messages with any of the AEAD modes, Argon2 or
+// iterated and salted (SHA-256 / SHA-512) S2K specifiers, several SKESK packets
+// and Padding packets. Everything else (e.g. public key encrypted session keys,
+// version 1 SEIPD packets, compressed or signed data) is reported as
+// ErrUnsupported. Wrong passphrases and modified messages are reported as
+// ErrDecryption (see the ctkdebug build tag for the cause).
+//
+// Note that GnuPG implements LibrePGP rather than RFC 9580 and therefore
+// doesn't support version 6 SKESK and version 2 SEIPD packets.
+//
+// Messages are binary. Armor and Dearmor convert them from and to the ASCII
+// armor ("-----BEGIN PGP MESSAGE-----") most tools use.
+//
+// Message format:
+//
+//	SKESK: 6 | count | cipher | mode | S2K size | S2K | nonce | encrypted session key | tag
+//	SEIPD: 2 | cipher | mode | chunk size | salt (32) | encrypted chunks with tags | final tag
+package openpgp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/pmuens/ctk-go/ctk/argon2"
+	"github.com/pmuens/ctk-go/ctk/hkdf"
+	"github.com/pmuens/ctk-go/ctk/internal/debug"
+	"github.com/pmuens/ctk-go/ctk/random"
+)
+
+const (
+	// ErrInvalidMessage is returned if a message is malformed.
+	ErrInvalidMessage = Error("invalid openpgp message")
+
+	// ErrUnsupported is returned if a message uses a feature (e.g. a packet
+	// type or algorithm) that isn't supported.
+	ErrUnsupported = Error("unsupported openpgp message")
+
+	// ErrDecryption is returned if a message can't be decrypted (e.g. because
+	// the passphrase is wrong or the message was modified).
+	ErrDecryption = Error("openpgp decryption failed")
+
+	// ErrInvalidParams is returned if the Argon2 parameters can't be encoded
+	// (e.g. if the memory isn't a power of two).
+	ErrInvalidParams = Error("invalid openpgp argon2 params")
+)
+
+// Cipher is a symmetric cipher (RFC 9580 section 9.3).
+type Cipher byte
+
+const (
+	// AES128 is AES with a 128 bit key.
+	AES128 Cipher = 7
+
+	// AES192 is AES with a 192 bit key.
+	AES192 Cipher = 8
+
+	// AES256 is AES with a 256 bit key.
+	AES256 Cipher = 9
+)
+
+// keySize returns the key size (in bytes) of the cipher (0 if it's
+// unsupported).
+func (c Cipher) keySize() int {
+	switch c {
+	case AES128:
+		return 16
+	case AES192:
+		return 24
+	case AES256:
+		return 32
+	default:
+		return 0
+	}
+}
+
+// Mode is an AEAD mode (RFC 9580 section 9.6).
+type Mode byte
+
+const (
+	// EAX is the EAX mode.
+	EAX Mode = 1
+
+	// OCB is the OCB mode (RFC 7253).
+	OCB Mode = 2
+
+	// GCM is the GCM mode.
+	GCM Mode = 3
+)
+
+// nonceSize returns the nonce size (in bytes) of the mode (0 if it's
+// unsupported).
+func (m Mode) nonceSize() int {
+	switch m {
+	case EAX:
+		return 16
+	case OCB:
+		return 15
+	case GCM:
+		return 12
+	default:
+		return 0
+	}
+}
+
+// tagSize is the size (in bytes) of the tags of all AEAD modes.
+const tagSize = 16
+
+// saltSize is the size (in bytes) of the salt of SEIPD packets.
+const saltSize = 32
+
+// maxChunkSizeOctet is the largest chunk size octet (chunks of 4 MiB).
+const maxChunkSizeOctet = 16
+
+// options are the configured algorithms and parameters.
+type options struct {
+	// cipher is the cipher.
+	cipher Cipher
+
+	// mode is the AEAD mode.
+	mode Mode
+
+	// chunkSizeOctet is the encoded chunk size (2^(octet + 6) bytes).
+	chunkSizeOctet byte
+
+	// params are the Argon2 parameters.
+	params argon2.Params
+}
+
+// Option configures the encryption.
+type Option func(*options)
+
+// WithCipher sets the cipher (AES256 by default).
+// Panics if the cipher isn't supported.
+func WithCipher(c Cipher) Option {
+	if c.keySize() == 0 {
+		panic("openpgp: unsupported cipher")
+	}
+
+	return func(o *options) {
+		o.cipher = c
+	}
+}
+
+// WithMode sets the AEAD mode (OCB by default).
+// Panics if the mode isn't supported.
+func WithMode(m Mode) Option {
+	if m.nonceSize() == 0 {
+		panic("openpgp: unsupported aead mode")
+	}
+
+	return func(o *options) {
+		o.mode = m
+	}
+}
+
+// WithChunkSize sets the size (in bytes) of the chunks the data is encrypted
+// in (256 KiB by default).
+// Panics if the size isn't a power of two between 64 bytes and 4 MiB.
+func WithChunkSize(size int) Option {
+	octet := -1
+	for i := range maxChunkSizeOctet + 1 {
+		if size == 1<<(i+6) {
+			octet = i
+		}
+	}
+
+	if octet < 0 {
+		panic("openpgp: chunk size needs to be a power of two between 64 bytes and 4 MiB")
+	}
+
+	return func(o *options) {
+		o.chunkSizeOctet = byte(octet)
+	}
+}
+
+// WithArgon2Params sets the time, memory and parallelism of the Argon2 S2K
+// (argon2.DefaultParams by default). The memory has to be a power of two and
+// the time and parallelism can't exceed 255 (otherwise Encrypt returns
+// ErrInvalidParams).
+func WithArgon2Params(params argon2.Params) Option {
+	return func(o *options) {
+		o.params = params
+	}
+}
+
+// Encrypt encrypts the plaintext with the passphrase and returns the binary
+// OpenPGP message.
+// Returns ErrInvalidParams if the Argon2 parameters can't be encoded and an
+// error if the key derivation or randomness fails.
+func Encrypt(plaintext []byte, passphrase []byte, opts ...Option) ([]byte, error) {
+	o := options{cipher: AES256, mode: OCB, chunkSizeOctet: 12, params: argon2.DefaultParams}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	s, err := newArgon2S2K(o.params)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	sessionKey := make([]byte, o.cipher.keySize())
+	defer clear(sessionKey)
+
+	err = random.Read(sessionKey)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	skesk, err := encryptSessionKey(sessionKey, passphrase, s, o.cipher, o.mode)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	// A binary Literal Data packet without a file name and date.
+	literal := appendPacket(nil, tagLiteral, append([]byte{'b', 0, 0, 0, 0, 0}, plaintext...))
+	defer clear(literal)
+
+	seipd, err := encryptData(sessionKey, literal, o.cipher, o.mode, o.chunkSizeOctet)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	message := appendPacket(nil, tagSKESK, skesk)
+
+	return appendPacket(message, tagSEIPD, seipd), nil
+}
+
+// Decrypt decrypts the binary OpenPGP message with the passphrase and returns
+// the content of its Literal Data packet. Every SKESK packet is tried until one
+// can be decrypted with the passphrase.
+// Returns ErrInvalidMessage if the message is malformed, ErrUnsupported if it
+// uses an unsupported feature and ErrDecryption if the passphrase is wrong or
+// the message was modified.
+func Decrypt(message []byte, passphrase []byte) ([]byte, error) {
+	var skesks [][]byte
+
+	for {
+		p, rest, err := readPacket(message)
+		if err != nil {
+			return []byte{}, err
+		}
+		message = rest
+
+		switch p.tag {
+		case tagSKESK:
+			skesks = append(skesks, p.body)
+		case tagPKESK, tagMarker, tagPadding:
+			// Public key encrypted session keys are unsupported but other
+			// session keys might work.
+		case tagSEIPD:
+			err = skipPadding(message)
+			if err != nil {
+				return []byte{}, err
+			}
+
+			return decryptMessage(p.body, skesks, passphrase)
+		default:
+			return []byte{}, ErrUnsupported
+		}
+	}
+}
+
+// decryptMessage decrypts the SEIPD packet body with the first session key
+// that can be decrypted with the passphrase and returns the content of its
+// Literal Data packet.
+// Returns an error if no session key can be decrypted or if the data or its
+// packets are invalid.
+func decryptMessage(seipd []byte, skesks [][]byte, passphrase []byte) ([]byte, error) {
+	if len(seipd) < 1 || seipd[0] != 2 {
+		return []byte{}, ErrUnsupported
+	}
+
+	if len(skesks) == 0 {
+		return []byte{}, ErrUnsupported
+	}
+
+	var err error
+	for _, skesk := range skesks {
+		var sessionKey []byte
+		sessionKey, err = decryptSessionKey(skesk, passphrase)
+		if err != nil {
+			continue
+		}
+
+		var data []byte
+		data, err = decryptData(sessionKey, seipd)
+		clear(sessionKey)
+		if err != nil {
+			return []byte{}, err
+		}
+		defer clear(data)
+
+		return readLiteral(data)
+	}
+
+	return []byte{}, err
+}
+
+// encryptSessionKey encrypts the session key with a key that's derived from
+// the passphrase via the S2K and returns the SKESK packet body.
+// Returns an error if the key derivation fails.
+func encryptSessionKey(sessionKey []byte, passphrase []byte, s s2k, c Cipher, m Mode) ([]byte, error) {
+	info := []byte{0xc0 | tagSKESK, 6, byte(c), byte(m)}
+
+	aead, err := sessionKeyAEAD(passphrase, s, info)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	spec := s.encode()
+	nonce := make([]byte, m.nonceSize())
+
+	err = random.Read(nonce)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	body := []byte{6, byte(3 + len(spec) + len(nonce)), byte(c), byte(m), byte(len(spec))}
+	body = append(body, spec...)
+	body = append(body, nonce...)
+
+	return aead.Seal(body, nonce, sessionKey, info), nil
+}
+
+// decryptSessionKey decrypts the session key of the SKESK packet body with a
+// key that's derived from the passphrase.
+// Returns ErrUnsupported if the packet has an unsupported version or
+// algorithm, ErrInvalidMessage if it's malformed and ErrDecryption if the
+// passphrase is wrong.
+func decryptSessionKey(skesk []byte, passphrase []byte) ([]byte, error) {
+	if len(skesk) < 5 || skesk[0] != 6 {
+		return []byte{}, ErrUnsupported
+	}
+
+	c, m := Cipher(skesk[2]), Mode(skesk[3])
+	if c.keySize() == 0 || m.nonceSize() == 0 {
+		return []byte{}, ErrUnsupported
+	}
+
+	specSize := int(skesk[4])
+	if int(skesk[1]) != 3+specSize+m.nonceSize() || len(skesk) != 2+int(skesk[1])+c.keySize()+tagSize {
+		return []byte{}, ErrInvalidMessage
+	}
+
+	s, err := parseS2K(skesk[5 : 5+specSize])
+	if err != nil {
+		return []byte{}, err
+	}
+
+	info := []byte{0xc0 | tagSKESK, 6, byte(c), byte(m)}
+
+	aead, err := sessionKeyAEAD(passphrase, s, info)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	nonce := skesk[5+specSize : 5+specSize+m.nonceSize()]
+
+	sessionKey, err := aead.Open(nil, nonce, skesk[5+specSize+m.nonceSize():], info)
+	if err != nil {
+		return []byte{}, debug.Detail(ErrDecryption, err)
+	}
+
+	return sessionKey, nil
+}
+
+// sessionKeyAEAD returns the AEAD of the SKESK packet with the info (the packet
+// tag, version, cipher and mode) whose key is derived from the passphrase via
+// the S2K and HKDF-SHA256.
+// Returns an error if the key derivation fails.
+func sessionKeyAEAD(passphrase []byte, s s2k, info []byte) (cipher.AEAD, error) {
+	c, m := Cipher(info[2]), Mode(info[3])
+
+	ikm, err := s.key(passphrase, c.keySize())
+	if err != nil {
+		return nil, err
+	}
+	defer clear(ikm)
+
+	kek, err := hkdf.Key(sha256.New, ikm, nil, info, c.keySize())
+	if err != nil {
+		return nil, err
+	}
+	defer clear(kek)
+
+	return newAEAD(c, m, kek), nil
+}
+
+// encryptData encrypts the data with the session key in chunks of the size and
+// returns the SEIPD packet body.
+// Returns an error if the salt can't be generated.
+func encryptData(sessionKey []byte, data []byte, c Cipher, m Mode, chunkSizeOctet byte) ([]byte, error) {
+	salt := make([]byte, saltSize)
+
+	err := random.Read(salt)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	info := []byte{0xc0 | tagSEIPD, 2, byte(c), byte(m), chunkSizeOctet}
+
+	aead, iv, err := dataAEAD(sessionKey, salt, info)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	chunkSize := 1 << (chunkSizeOctet + 6)
+	chunks := (len(data) + chunkSize - 1) / chunkSize
+
+	body := make([]byte, 0, len(info)-1+saltSize+len(data)+(chunks+1)*tagSize)
+	body = append(body, info[1:]...)
+	body = append(body, salt...)
+
+	nonce := make([]byte, m.nonceSize())
+	copy(nonce, iv)
+
+	for i := range chunks {
+		binary.BigEndian.PutUint64(nonce[len(iv):], uint64(i))
+		body = aead.Seal(body, nonce, data[i*chunkSize:min((i+1)*chunkSize, len(data))], info)
+	}
+
+	// The final tag authenticates the number of chunks and the size of the
+	// data so that the message can't be truncated.
+	binary.BigEndian.PutUint64(nonce[len(iv):], uint64(chunks))
+	final := binary.BigEndian.AppendUint64(append([]byte{}, info...), uint64(len(data)))
+
+	return aead.Seal(body, nonce, nil, final), nil
+}
+
+// decryptData decrypts the SEIPD packet body with the session key.
+// Returns ErrUnsupported if the packet uses an unsupported algorithm,
+// ErrInvalidMessage if it's malformed and ErrDecryption if a chunk or the final
+// tag is invalid (e.g. because the message was modified or truncated).
+func decryptData(sessionKey []byte, seipd []byte) ([]byte, error) {
+	if len(seipd) < 4+saltSize+tagSize {
+		return []byte{}, ErrInvalidMessage
+	}
+
+	c, m, chunkSizeOctet := Cipher(seipd[1]), Mode(seipd[2]), seipd[3]
+	if c.keySize() == 0 || m.nonceSize() == 0 || chunkSizeOctet > maxChunkSizeOctet {
+		return []byte{}, ErrUnsupported
+	}
+
+	if c.keySize() != len(sessionKey) {
+		return []byte{}, debug.Detail(ErrDecryption, ErrInvalidMessage)
+	}
+
+	info := []byte{0xc0 | tagSEIPD, 2, byte(c), byte(m), chunkSizeOctet}
+
+	aead, iv, err := dataAEAD(sessionKey, seipd[4:4+saltSize], info)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	encrypted := seipd[4+saltSize : len(seipd)-tagSize]
+	finalTag := seipd[len(seipd)-tagSize:]
+
+	chunkSize := 1 << (chunkSizeOctet + 6)
+	nonce := make([]byte, m.nonceSize())
+	copy(nonce, iv)
+
+	data := make([]byte, 0, len(encrypted))
+
+	var chunks uint64
+	for ; len(encrypted) > 0; chunks++ {
+		size := min(chunkSize+tagSize, len(encrypted))
+
+		binary.BigEndian.PutUint64(nonce[len(iv):], chunks)
+		data, err = aead.Open(data, nonce, encrypted[:size], info)
+		if err != nil {
+			clear(data)
+			return []byte{}, debug.Detail(ErrDecryption, err)
+		}
+
+		encrypted = encrypted[size:]
+	}
+
+	binary.BigEndian.PutUint64(nonce[len(iv):], chunks)
+	final := binary.BigEndian.AppendUint64(append([]byte{}, info...), uint64(len(data)))
+
+	_, err = aead.Open(nil, nonce, finalTag, final)
+	if err != nil {
+		clear(data)
+		return []byte{}, debug.Detail(ErrDecryption, err)
+	}
+
+	return data, nil
+}
+
+// dataAEAD returns the AEAD and the IV (the first part of the nonces) of the
+// SEIPD packet with the salt and info (the packet tag, version, cipher, mode
+// and chunk size) which are derived from the session key via HKDF-SHA256.
+// Returns an error if the key derivation fails.
+func dataAEAD(sessionKey []byte, salt []byte, info []byte) (cipher.AEAD, []byte, error) {
+	c, m := Cipher(info[2]), Mode(info[3])
+
+	derived, err := hkdf.Key(sha256.New, sessionKey, salt, info, c.keySize()+m.nonceSize()-8)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer clear(derived[:c.keySize()])
+
+	return newAEAD(c, m, derived[:c.keySize()]), derived[c.keySize():], nil
+}
+
+// newAEAD returns the AEAD mode with the cipher and key.
+func newAEAD(c Cipher, m Mode, key []byte) cipher.AEAD {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic("openpgp: " + err.Error())
+	}
+
+	switch m {
+	case EAX:
+		return newEAX(block, m.nonceSize())
+	case OCB:
+		return newOCB(block, m.nonceSize())
+	default:
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			panic("openpgp: " + err.Error())
+		}
+
+		return aead
+	}
+}
+
+// readLiteral parses the decrypted packets and returns the content of the
+// Literal Data packet. Padding and Marker packets are ignored.
+// Returns ErrUnsupported if there are other packets (e.g. compressed or signed
+// data) and ErrInvalidMessage if the packets are malformed or if there isn't
+// exactly one Literal Data packet.
+func readLiteral(data []byte) ([]byte, error) {
+	var content []byte
+
+	for len(data) > 0 {
+		p, rest, err := readPacket(data)
+		if err != nil {
+			return []byte{}, err
+		}
+		data = rest
+
+		switch p.tag {
+		case tagLiteral:
+			if content != nil || len(p.body) < 2 || len(p.body) < 2+int(p.body[1])+4 {
+				return []byte{}, ErrInvalidMessage
+			}
+
+			// The format, file name and date are ignored.
+			content = append([]byte{}, p.body[2+int(p.body[1])+4:]...)
+		case tagPadding, tagMarker:
+		default:
+			return []byte{}, ErrUnsupported
+		}
+	}
+
+	if content == nil {
+		return []byte{}, ErrInvalidMessage
+	}
+
+	return content, nil
+}
+
+// skipPadding checks that the data only contains Padding packets.
+// Returns ErrInvalidMessage if there are other packets.
+func skipPadding(data []byte) error {
+	for len(data) > 0 {
+		p, rest, err := readPacket(data)
+		if err != nil || p.tag != tagPadding {
+			return ErrInvalidMessage
+		}
+		data = rest
+	}
+
+	return nil
+}
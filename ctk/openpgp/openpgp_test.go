@@ -0,0 +1,158 @@
+package openpgp_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/argon2"
+	"github.com/pmuens/ctk-go/ctk/openpgp"
+)
+
+// params are cheap Argon2 parameters to keep the tests fast.
+var params = argon2.Params{Time: 1, Memory: 64, Parallelism: 1}
+
+// rfc9580Message is the message of https://www.rfc-editor.org/rfc/rfc9580#appendix-A.9
+// (AES-128 with EAX and an iterated and salted S2K) which contains "Hello,
+// world!" followed by a Padding packet and is encrypted with "password".
+const rfc9580Message = "c340061e07010b0308a5ae579d1fc5d82bff69224f919993b3506fa3b59a6a73cff8c5efc5f41c57fb" +
+	"54e1c226815d7828f5f92c454eb65ebe00ab5986c68e6e7c55d269020701069ff90e3b321964f3a42913c8dcc66193250152" +
+	"27efb7eaeaa49f04c2e674175d4a3d226ed6afcb9ca9ac122c1470e11c63d4c0ab241c6a938ad48bf99a5a99b90bba8325de" +
+	"61047540258ab7959a95ad051dda96eb15431dfef5f5e2255ca78261546e339a"
+
+// decodeHex decodes the hex string.
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	return b
+}
+
+func TestDecrypt(t *testing.T) {
+	t.Run("RFC 9580 Test Vector - Appendix A.9", func(t *testing.T) {
+		t.Parallel()
+
+		want := []byte("Hello, world!")
+
+		got, err := openpgp.Decrypt(decodeHex(t, rfc9580Message), []byte("password"))
+		if !bytes.Equal(got, want) || err != nil {
+			t.Errorf("want %q, got %q (error %v)", want, got, err)
+		}
+	})
+
+	t.Run("Wrong Passphrase", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := openpgp.Decrypt(decodeHex(t, rfc9580Message), []byte("wrong"))
+		if !errors.Is(err, openpgp.ErrDecryption) {
+			t.Errorf("want error %v, got %v", openpgp.ErrDecryption, err)
+		}
+	})
+
+	t.Run("Modified", func(t *testing.T) {
+		t.Parallel()
+
+		message := decodeHex(t, rfc9580Message)
+		message[len(message)-20] ^= 1
+
+		_, err := openpgp.Decrypt(message, []byte("password"))
+		if !errors.Is(err, openpgp.ErrDecryption) {
+			t.Errorf("want error %v, got %v", openpgp.ErrDecryption, err)
+		}
+	})
+
+	t.Run("Missing Final Tag", func(t *testing.T) {
+		t.Parallel()
+
+		// The SEIPD packet without the final tag (and an adjusted length).
+		message := decodeHex(t, rfc9580Message)
+		message = message[:len(message)-16]
+		message[67] -= 16
+
+		_, err := openpgp.Decrypt(message, []byte("password"))
+		if !errors.Is(err, openpgp.ErrDecryption) {
+			t.Errorf("want error %v, got %v", openpgp.ErrDecryption, err)
+		}
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		t.Parallel()
+
+		tests := map[string]struct {
+			message string
+			want    error
+		}{
+			"Empty":                 {"", openpgp.ErrInvalidMessage},
+			"Truncated":             {rfc9580Message[:len(rfc9580Message)-2], openpgp.ErrInvalidMessage},
+			"Trailing Packet":       {rfc9580Message + "cb0162", openpgp.ErrInvalidMessage},
+			"Missing Session Key":   {rfc9580Message[2*66:], openpgp.ErrUnsupported},
+			"Missing Data":          {rfc9580Message[:2*66], openpgp.ErrInvalidMessage},
+			"Compressed Data":       {"c80101", openpgp.ErrUnsupported},
+			"Version 4 Session Key": {"c30d04090308" + "0102030405060708" + "ff" + rfc9580Message[2*66:], openpgp.ErrUnsupported},
+		}
+
+		for name, tc := range tests {
+			_, err := openpgp.Decrypt(decodeHex(t, tc.message), []byte("password"))
+			if !errors.Is(err, tc.want) {
+				t.Errorf("%v: want error %v, got %v", name, tc.want, err)
+			}
+		}
+	})
+}
+
+func TestEncrypt(t *testing.T) {
+	plaintext := bytes.Repeat([]byte("OpenPGP"), 1000)
+	passphrase := []byte("correct horse battery staple")
+
+	for _, mode := range []openpgp.Mode{openpgp.EAX, openpgp.OCB, openpgp.GCM} {
+		for _, c := range []openpgp.Cipher{openpgp.AES128, openpgp.AES192, openpgp.AES256} {
+			for _, size := range []int{0, 1, 64, len(plaintext)} {
+				message, err := openpgp.Encrypt(plaintext[:size], passphrase,
+					openpgp.WithMode(mode), openpgp.WithCipher(c), openpgp.WithChunkSize(64), openpgp.WithArgon2Params(params))
+				if err != nil {
+					t.Fatalf("want error %v, got %v", nil, err)
+				}
+
+				got, err := openpgp.Decrypt(message, passphrase)
+				if !bytes.Equal(got, plaintext[:size]) || err != nil {
+					t.Errorf("mode %v, cipher %v, size %v: want %x, got %x (error %v)", mode, c, size, plaintext[:size], got, err)
+				}
+
+			}
+		}
+	}
+}
+
+func TestEncryptInvalidParams(t *testing.T) {
+	_, err := openpgp.Encrypt([]byte("plaintext"), []byte("passphrase"),
+		openpgp.WithArgon2Params(argon2.Params{Time: 1, Memory: 100, Parallelism: 1}))
+	if !errors.Is(err, openpgp.ErrInvalidParams) {
+		t.Errorf("want error %v, got %v", openpgp.ErrInvalidParams, err)
+	}
+}
+
+func TestArmor(t *testing.T) {
+	message := decodeHex(t, rfc9580Message)
+
+	armored := openpgp.Armor(message)
+
+	got, err := openpgp.Dearmor(armored)
+	if !bytes.Equal(got, message) || err != nil {
+		t.Errorf("want %x, got %x (error %v)", message, got, err)
+	}
+
+	// Armor headers and the CRC24 checksum are ignored.
+	withHeaders := "-----BEGIN PGP MESSAGE-----\r\nComment: test\r\n\r\nwx4GBwIBChBfu9H9JxTFwAAAAAAAAAAAAAAAAAAAAA==\r\n=ABCD\r\n-----END PGP MESSAGE-----\r\n"
+	if _, err := openpgp.Dearmor(withHeaders); err != nil {
+		t.Errorf("want error %v, got %v", nil, err)
+	}
+
+	if _, err := openpgp.Dearmor("-----BEGIN PGP SIGNATURE-----\n\n-----END PGP SIGNATURE-----"); !errors.Is(err, openpgp.ErrInvalidMessage) {
+		t.Errorf("want error %v, got %v", openpgp.ErrInvalidMessage, err)
+	}
+}
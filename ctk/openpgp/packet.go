@@ -0,0 +1,139 @@
+package openpgp
+
+import "encoding/binary"
+
+// Packet tags (RFC 9580 section 5).
+const (
+	tagPKESK      = 1
+	tagSKESK      = 3
+	tagCompressed = 8
+	tagMarker     = 10
+	tagLiteral    = 11
+	tagSEIPD      = 18
+	tagPadding    = 21
+)
+
+// packet is a packet with its (reassembled) body.
+type packet struct {
+	// tag is the packet tag.
+	tag byte
+
+	// body is the packet body.
+	body []byte
+}
+
+// readPacket parses the packet at the beginning of the data and returns it
+// with the data that follows it. Both the new and the legacy packet format and
+// partial body lengths are supported.
+// Returns ErrInvalidMessage if the packet is malformed or truncated.
+func readPacket(data []byte) (packet, []byte, error) {
+	if len(data) == 0 || data[0]&0x80 == 0 {
+		return packet{}, nil, ErrInvalidMessage
+	}
+
+	header := data[0]
+	data = data[1:]
+
+	if header&0x40 == 0 {
+		return readLegacyPacket(header, data)
+	}
+
+	p := packet{tag: header & 0x3f}
+
+	for {
+		length, partial, n, ok := readLength(data)
+		if !ok || uint64(len(data)-n) < length {
+			return packet{}, nil, ErrInvalidMessage
+		}
+		data = data[n:]
+
+		if !partial && p.body == nil {
+			// The common case of a single body part doesn't need a copy.
+			return packet{tag: p.tag, body: data[:length]}, data[length:], nil
+		}
+
+		p.body = append(p.body, data[:length]...)
+		data = data[length:]
+
+		if !partial {
+			return p, data, nil
+		}
+	}
+}
+
+// readLength parses a new format body length and returns the length, whether
+// it's a partial body length and the size of the encoded length.
+// Returns false if the length is truncated.
+func readLength(data []byte) (uint64, bool, int, bool) {
+	switch {
+	case len(data) < 1:
+		return 0, false, 0, false
+	case data[0] < 192:
+		return uint64(data[0]), false, 1, true
+	case data[0] < 224:
+		if len(data) < 2 {
+			return 0, false, 0, false
+		}
+		return uint64(data[0]-192)<<8 + uint64(data[1]) + 192, false, 2, true
+	case data[0] < 255:
+		return 1 << (data[0] & 0x1f), true, 1, true
+	default:
+		if len(data) < 5 {
+			return 0, false, 0, false
+		}
+		return uint64(binary.BigEndian.Uint32(data[1:5])), false, 5, true
+	}
+}
+
+// readLegacyPacket parses a legacy format packet with the header octet.
+// Returns ErrInvalidMessage if the packet is malformed or truncated.
+func readLegacyPacket(header byte, data []byte) (packet, []byte, error) {
+	p := packet{tag: header >> 2 & 0x0f}
+
+	var length uint64
+	switch header & 0x03 {
+	case 0:
+		if len(data) < 1 {
+			return packet{}, nil, ErrInvalidMessage
+		}
+		length, data = uint64(data[0]), data[1:]
+	case 1:
+		if len(data) < 2 {
+			return packet{}, nil, ErrInvalidMessage
+		}
+		length, data = uint64(binary.BigEndian.Uint16(data)), data[2:]
+	case 2:
+		if len(data) < 4 {
+			return packet{}, nil, ErrInvalidMessage
+		}
+		length, data = uint64(binary.BigEndian.Uint32(data)), data[4:]
+	default:
+		// The packet extends to the end of the data.
+		length = uint64(len(data))
+	}
+
+	if uint64(len(data)) < length {
+		return packet{}, nil, ErrInvalidMessage
+	}
+	p.body = data[:length]
+
+	return p, data[length:], nil
+}
+
+// appendPacket appends a new format packet with the tag and body to dst.
+func appendPacket(dst []byte, tag byte, body []byte) []byte {
+	dst = append(dst, 0xc0|tag)
+
+	switch length := len(body); {
+	case length < 192:
+		dst = append(dst, byte(length))
+	case length < 8384:
+		length -= 192
+		dst = append(dst, byte(length>>8)+192, byte(length))
+	default:
+		dst = append(dst, 0xff)
+		dst = binary.BigEndian.AppendUint32(dst, uint32(length))
+	}
+
+	return append(dst, body...)
+}
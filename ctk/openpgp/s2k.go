@@ -0,0 +1,187 @@
+package openpgp
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"math/bits"
+
+	"github.com/pmuens/ctk-go/ctk/argon2"
+	"github.com/pmuens/ctk-go/ctk/random"
+)
+
+// MaxArgon2Memory is the maximum Argon2 memory (in KiB) that a message may
+// require to be decrypted (2 GiB which is the first recommended option of
+// RFC 9106) so that a malicious message can't exhaust the memory.
+const MaxArgon2Memory = 1 << 21
+
+// MaxArgon2Time is the maximum number of Argon2 passes that a message may
+// require to be decrypted.
+const MaxArgon2Time = 16
+
+// S2K specifier types (RFC 9580 section 3.7.1).
+const (
+	s2kIteratedSalted = 3
+	s2kArgon2         = 4
+)
+
+// Hash algorithm IDs of the iterated and salted S2K (RFC 9580 section 9.5).
+const (
+	hashSHA256 = 8
+	hashSHA512 = 10
+)
+
+// argon2SaltSize is the size (in bytes) of the salt of the Argon2 S2K.
+const argon2SaltSize = 16
+
+// s2k is a string-to-key specifier which derives a key from a passphrase.
+type s2k struct {
+	// kind is the S2K type (s2kIteratedSalted or s2kArgon2).
+	kind byte
+
+	// hash is the hash algorithm ID of the iterated and salted S2K.
+	hash byte
+
+	// salt is the salt (8 bytes for the iterated and salted S2K and 16 bytes
+	// for the Argon2 S2K).
+	salt []byte
+
+	// count is the encoded number of bytes the iterated and salted S2K hashes.
+	count byte
+
+	// params are the parameters of the Argon2 S2K.
+	params argon2.Params
+}
+
+// newArgon2S2K returns an Argon2 S2K with a random salt and the time, memory
+// and parallelism of the parameters.
+// Returns ErrInvalidParams if the memory isn't a power of two or is too small
+// for the parallelism and an error if the salt can't be generated.
+func newArgon2S2K(params argon2.Params) (s2k, error) {
+	if params.Memory == 0 || params.Memory&(params.Memory-1) != 0 || params.Time < 1 || params.Time > 255 ||
+		params.Parallelism < 1 || params.Parallelism > 255 || params.Memory < 8*params.Parallelism {
+		return s2k{}, ErrInvalidParams
+	}
+
+	salt := make([]byte, argon2SaltSize)
+
+	err := random.Read(salt)
+	if err != nil {
+		return s2k{}, err
+	}
+
+	return s2k{
+		kind: s2kArgon2,
+		salt: salt,
+		params: argon2.Params{
+			Variant:     argon2.Argon2id,
+			Time:        params.Time,
+			Memory:      params.Memory,
+			Parallelism: params.Parallelism,
+		},
+	}, nil
+}
+
+// parseS2K parses an S2K specifier.
+// Returns ErrUnsupported if it has an unsupported type or hash algorithm or if
+// its parameters exceed MaxArgon2Memory or MaxArgon2Time and
+// ErrInvalidMessage if it's malformed.
+func parseS2K(data []byte) (s2k, error) {
+	if len(data) < 1 {
+		return s2k{}, ErrInvalidMessage
+	}
+
+	switch data[0] {
+	case s2kIteratedSalted:
+		if len(data) != 11 {
+			return s2k{}, ErrInvalidMessage
+		}
+
+		if data[1] != hashSHA256 && data[1] != hashSHA512 {
+			return s2k{}, ErrUnsupported
+		}
+
+		return s2k{kind: s2kIteratedSalted, hash: data[1], salt: data[2:10], count: data[10]}, nil
+	case s2kArgon2:
+		if len(data) != 1+argon2SaltSize+3 {
+			return s2k{}, ErrInvalidMessage
+		}
+
+		time := uint32(data[17])
+		parallelism := uint32(data[18])
+		exponent := data[19]
+
+		// The memory (2^exponent KiB) has to be at least 8 KiB per lane.
+		if time < 1 || parallelism < 1 || exponent > 31 || 1<<exponent < 8*parallelism {
+			return s2k{}, ErrInvalidMessage
+		}
+
+		if 1<<exponent > MaxArgon2Memory || time > MaxArgon2Time {
+			return s2k{}, ErrUnsupported
+		}
+
+		return s2k{
+			kind: s2kArgon2,
+			salt: data[1:17],
+			params: argon2.Params{
+				Variant:     argon2.Argon2id,
+				Time:        time,
+				Memory:      1 << exponent,
+				Parallelism: parallelism,
+			},
+		}, nil
+	default:
+		return s2k{}, ErrUnsupported
+	}
+}
+
+// encode encodes the S2K specifier.
+func (s s2k) encode() []byte {
+	if s.kind == s2kIteratedSalted {
+		return append(append([]byte{s.kind, s.hash}, s.salt...), s.count)
+	}
+
+	exponent := byte(bits.TrailingZeros32(s.params.Memory))
+
+	return append(append([]byte{s.kind}, s.salt...), byte(s.params.Time), byte(s.params.Parallelism), exponent)
+}
+
+// key derives a key of the size from the passphrase.
+// Returns an error if the Argon2 computation fails.
+func (s s2k) key(passphrase []byte, size int) ([]byte, error) {
+	if s.kind == s2kArgon2 {
+		params := s.params
+		params.KeyLength = uint32(size)
+
+		return argon2.Key(passphrase, s.salt, params)
+	}
+
+	newHash := sha256.New
+	if s.hash == hashSHA512 {
+		newHash = sha512.New
+	}
+
+	// The salt and passphrase are hashed repeatedly until count bytes were
+	// hashed (but at least once). Keys that are larger than the hash use
+	// further hash contexts which are preloaded with one more zero byte each.
+	input := append(append([]byte{}, s.salt...), passphrase...)
+	defer clear(input)
+
+	count := (16 + int(s.count&15)) << (s.count>>4 + 6)
+	count = max(count, len(input))
+
+	var key []byte
+	for preload := 0; len(key) < size; preload++ {
+		h := newHash()
+		h.Write(make([]byte, preload))
+
+		for remaining := count; remaining > 0; remaining -= len(input) {
+			h.Write(input[:min(remaining, len(input))])
+		}
+
+		key = h.Sum(key)
+	}
+
+	clear(key[size:])
+
+	return key[:size], nil
+}
@@ -0,0 +1,87 @@
+// Package padding implements padding schemes that hide the exact length of a
+// plaintext before it's encrypted.
+//
+// The padded data is the data followed by a 0x80 byte and as many zero bytes as
+// necessary to reach the padded length (ISO/IEC 7816-4). The Mode determines the
+// padded length:
+//
+//   - PadToBlock pads to a multiple of a block size.
+//   - Padme pads as described in https://bford.info/pub/sec/purb.pdf which
+//     leaks at most O(log log n) bits of the length at an overhead of at most
+//     12%.
+package padding
+
+import "math/bits"
+
+const (
+	// ErrInvalidPadding is returned if the padding can't be removed.
+	ErrInvalidPadding = Error("invalid padding")
+)
+
+// marker is the byte that separates the data from the zero padding.
+const marker = 0x80
+
+// Mode computes the padded length for data with the length.
+// The padded length needs to be greater than the length to fit the marker.
+type Mode func(length int) int
+
+// PadToBlock returns a mode that pads to the next multiple of the block size.
+// Data whose length is a multiple of the block size is padded by a full block.
+// A block size smaller than one is treated as one.
+func PadToBlock(blockSize int) Mode {
+	blockSize = max(blockSize, 1)
+
+	return func(length int) int {
+		return (length/blockSize + 1) * blockSize
+	}
+}
+
+// Padme is the mode that rounds the length (including the marker) up so that
+// only the most significant bits of it are revealed.
+func Padme(length int) int {
+	l := uint(length + 1)
+	if l < 2 {
+		return int(l)
+	}
+
+	// e is the position of the most significant bit and s is the number of bits
+	// needed to represent e.
+	e := uint(bits.Len(l)) - 1
+	s := uint(bits.Len(e))
+
+	// Zero the lowest e - s bits (rounding up).
+	mask := uint(1)<<(e-s) - 1
+
+	return int((l + mask) &^ mask)
+}
+
+// Pad pads the data according to the mode.
+// Note that the data that's passed-in won't be mutated, but a padded copy will be
+// returned.
+func Pad(data []byte, mode Mode) []byte {
+	size := max(mode(len(data)), len(data)+1)
+
+	result := make([]byte, size)
+	copy(result, data)
+	result[len(data)] = marker
+
+	return result
+}
+
+// Unpad removes the padding from the padded data.
+// The mode isn't needed as the marker determines where the data ends.
+// Returns an error if there's no marker.
+func Unpad(padded []byte) ([]byte, error) {
+	for i := len(padded) - 1; i >= 0; i-- {
+		switch padded[i] {
+		case 0x00:
+			continue
+		case marker:
+			return padded[:i], nil
+		}
+
+		break
+	}
+
+	return []byte{}, ErrInvalidPadding
+}
@@ -0,0 +1,110 @@
+package padding_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/padding"
+)
+
+func TestPad(t *testing.T) {
+	t.Run("Pad To Block", func(t *testing.T) {
+		t.Parallel()
+
+		data := []byte{0x01, 0x02, 0x03}
+
+		got := padding.Pad(data, padding.PadToBlock(8))
+		want := []byte{0x01, 0x02, 0x03, 0x80, 0x00, 0x00, 0x00, 0x00}
+
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Pad To Block - Full Block", func(t *testing.T) {
+		t.Parallel()
+
+		data := []byte{0x01, 0x02, 0x03, 0x04}
+
+		got := padding.Pad(data, padding.PadToBlock(4))
+		want := []byte{0x01, 0x02, 0x03, 0x04, 0x80, 0x00, 0x00, 0x00}
+
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Round Trip", func(t *testing.T) {
+		t.Parallel()
+
+		modes := map[string]padding.Mode{
+			"Pad To Block": padding.PadToBlock(16),
+			"Padme":        padding.Padme,
+		}
+
+		for name, mode := range modes {
+			for length := range 300 {
+				data := make([]byte, length)
+				for i := range data {
+					data[i] = byte(i)
+				}
+
+				padded := padding.Pad(data, mode)
+				got, err := padding.Unpad(padded)
+				if err != nil {
+					t.Fatalf("%v (%v): want error %v, got %v", name, length, nil, err)
+				}
+
+				if !slices.Equal(got, data) {
+					t.Errorf("%v (%v): want %v, got %v", name, length, data, got)
+				}
+			}
+		}
+	})
+}
+
+func TestPadme(t *testing.T) {
+	t.Parallel()
+
+	// The lengths include the marker byte (i.e. Padme(n) pads n + 1 bytes).
+	tt := map[int]int{
+		0:    1,
+		1:    2,
+		8:    10,
+		9:    10,
+		99:   104,
+		999:  1024,
+		1000: 1024,
+		1024: 1088,
+	}
+
+	for length, want := range tt {
+		got := padding.Padme(length)
+
+		if got != want {
+			t.Errorf("%v: want %v, got %v", length, want, got)
+		}
+	}
+}
+
+func TestUnpad(t *testing.T) {
+	t.Parallel()
+
+	tt := map[string][]byte{
+		"Empty":     {},
+		"No Marker": {0x01, 0x00, 0x00},
+		"Zeros":     {0x00, 0x00, 0x00},
+	}
+
+	for name, padded := range tt {
+		_, err := padding.Unpad(padded)
+
+		gotError := err
+		wantError := padding.ErrInvalidPadding
+
+		if !errors.Is(gotError, wantError) {
+			t.Errorf("%v: want error %v, got %v", name, wantError, gotError)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+// Package paserk implements the version 4 key serialization formats of PASERK
+// (Platform-Agnostic Serialized Keys) as specified in
+// https://github.com/paseto-standard/paserk so that keys can be exchanged with
+// PASETO implementations.
+//
+// The supported types are:
+//
+//   - k4.local, k4.public and k4.secret: Plain symmetric keys, Ed25519 public
+//     keys and Ed25519 secret keys (see EncodeLocal, EncodePublic and
+//     EncodeSecret).
+//   - k4.lid, k4.pid and k4.sid: Identifiers of such keys (see ID).
+//   - k4.seal: A symmetric key encrypted for the owner of an Ed25519 key pair
+//     (see Seal and Unseal).
+//   - k4.local-wrap.pie and k4.secret-wrap.pie: Keys wrapped with a symmetric
+//     wrapping key (see WrapLocal and WrapSecret).
+//   - k4.local-pw and k4.secret-pw: Keys wrapped with a password via Argon2id
+//     (see PasswordWrapLocal and PasswordWrapSecret).
+//
+// Unsealing and unwrapping report every failure (e.g. a malformed body or an
+// invalid tag) as ErrDecryption (see the ctkdebug build tag for the cause).
+//
+// Ed25519 secret keys are 64 bytes (the seed followed by the public key) as in
+// crypto/ed25519.
+package paserk
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+
+	"github.com/pmuens/ctk-go/ctk/blake2b"
+	"github.com/pmuens/ctk-go/ctk/subtle"
+)
+
+const (
+	// ErrInvalidPASERK is returned if a PASERK is malformed.
+	ErrInvalidPASERK = Error("invalid paserk")
+
+	// ErrWrongType is returned if a PASERK has another type than the expected
+	// one (e.g. a k4.public where a k4.local is expected).
+	ErrWrongType = Error("wrong paserk type")
+
+	// ErrDecryption is returned if a sealed or wrapped key can't be
+	// authenticated.
+	ErrDecryption = Error("decryption failed")
+)
+
+// Headers of the supported types (the version, the type and a trailing dot).
+// Every PASERK starts with the header of its type.
+const (
+	// TypeLocal is the header of symmetric keys.
+	TypeLocal = "k4.local."
+
+	// TypePublic is the header of Ed25519 public keys.
+	TypePublic = "k4.public."
+
+	// TypeSecret is the header of Ed25519 secret keys.
+	TypeSecret = "k4.secret."
+
+	// TypeLocalID is the header of symmetric key identifiers.
+	TypeLocalID = "k4.lid."
+
+	// TypePublicID is the header of public key identifiers.
+	TypePublicID = "k4.pid."
+
+	// TypeSecretID is the header of secret key identifiers.
+	TypeSecretID = "k4.sid."
+
+	// TypeSeal is the header of symmetric keys that are sealed with a public key.
+	TypeSeal = "k4.seal."
+
+	// TypeLocalWrap is the header of symmetric keys that are wrapped with a
+	// symmetric key.
+	TypeLocalWrap = "k4.local-wrap.pie."
+
+	// TypeSecretWrap is the header of secret keys that are wrapped with a
+	// symmetric key.
+	TypeSecretWrap = "k4.secret-wrap.pie."
+
+	// TypeLocalPassword is the header of symmetric keys that are wrapped with a
+	// password.
+	TypeLocalPassword = "k4.local-pw."
+
+	// TypeSecretPassword is the header of secret keys that are wrapped with a
+	// password.
+	TypeSecretPassword = "k4.secret-pw."
+)
+
+// idSize is the size (in bytes) of the hash of a key identifier.
+const idSize = 33
+
+// encoding is the encoding of the data after the header (base64url without
+// padding).
+var encoding = base64.RawURLEncoding.Strict()
+
+// EncodeLocal returns the k4.local PASERK of the symmetric key.
+func EncodeLocal(key [32]byte) string {
+	return TypeLocal + encoding.EncodeToString(key[:])
+}
+
+// DecodeLocal returns the symmetric key of the k4.local PASERK.
+// Returns ErrWrongType if the PASERK has another type and ErrInvalidPASERK if
+// it's malformed.
+func DecodeLocal(paserk string) ([32]byte, error) {
+	data, err := decode(paserk, TypeLocal, 32)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	defer clear(data)
+
+	return [32]byte(data), nil
+}
+
+// EncodePublic returns the k4.public PASERK of the Ed25519 public key.
+func EncodePublic(key [32]byte) string {
+	return TypePublic + encoding.EncodeToString(key[:])
+}
+
+// DecodePublic returns the Ed25519 public key of the k4.public PASERK.
+// Returns ErrWrongType if the PASERK has another type and ErrInvalidPASERK if
+// it's malformed.
+func DecodePublic(paserk string) ([32]byte, error) {
+	data, err := decode(paserk, TypePublic, 32)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	return [32]byte(data), nil
+}
+
+// EncodeSecret returns the k4.secret PASERK of the Ed25519 secret key.
+func EncodeSecret(key [64]byte) string {
+	return TypeSecret + encoding.EncodeToString(key[:])
+}
+
+// DecodeSecret returns the Ed25519 secret key of the k4.secret PASERK.
+// Returns ErrWrongType if the PASERK has another type and ErrInvalidPASERK if
+// it's malformed or if the public key doesn't belong to the seed.
+func DecodeSecret(paserk string) ([64]byte, error) {
+	data, err := decode(paserk, TypeSecret, 64)
+	if err != nil {
+		return [64]byte{}, err
+	}
+	defer clear(data)
+
+	key := [64]byte(data)
+	if !validSecret(key) {
+		clear(key[:])
+		return [64]byte{}, ErrInvalidPASERK
+	}
+
+	return key, nil
+}
+
+// ID returns the identifier (k4.lid, k4.pid or k4.sid) of the k4.local,
+// k4.public or k4.secret PASERK. Identifiers can be shared (e.g. in the kid
+// footer of a PASETO) without revealing the key.
+// Returns ErrWrongType if the PASERK has another type and ErrInvalidPASERK if
+// it's malformed.
+func ID(paserk string) (string, error) {
+	var header string
+	var err error
+
+	switch {
+	case strings.HasPrefix(paserk, TypeLocal):
+		header = TypeLocalID
+		_, err = DecodeLocal(paserk)
+	case strings.HasPrefix(paserk, TypePublic):
+		header = TypePublicID
+		_, err = DecodePublic(paserk)
+	case strings.HasPrefix(paserk, TypeSecret):
+		header = TypeSecretID
+		_, err = DecodeSecret(paserk)
+	default:
+		return "", ErrWrongType
+	}
+	if err != nil {
+		return "", err
+	}
+
+	hash, _ := blake2b.Sum([]byte(header+paserk), idSize)
+
+	return header + encoding.EncodeToString(hash), nil
+}
+
+// decode returns the size bytes after the header of the PASERK.
+// Returns ErrWrongType if the PASERK has another header and ErrInvalidPASERK if
+// the data isn't size bytes of base64url.
+func decode(paserk string, header string, size int) ([]byte, error) {
+	data, err := decodeAny(paserk, header)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	if len(data) != size {
+		clear(data)
+		return []byte{}, ErrInvalidPASERK
+	}
+
+	return data, nil
+}
+
+// decodeAny returns the data after the header of the PASERK.
+// Returns ErrWrongType if the PASERK has another header and ErrInvalidPASERK if
+// the data isn't base64url.
+func decodeAny(paserk string, header string) ([]byte, error) {
+	encoded, ok := strings.CutPrefix(paserk, header)
+	if !ok {
+		return []byte{}, ErrWrongType
+	}
+
+	data, err := encoding.DecodeString(encoded)
+	if err != nil {
+		return []byte{}, ErrInvalidPASERK
+	}
+
+	return data, nil
+}
+
+// validSecret reports whether the public key half of the secret key belongs to
+// its seed.
+func validSecret(key [64]byte) bool {
+	private := ed25519.NewKeyFromSeed(key[:32])
+	defer clear(private)
+
+	return subtle.ConstantTimeCompare(private, key[:]) == 1
+}
+
+// mac returns the 32 byte BLAKE2b MAC of the message under the key.
+func mac(key []byte, message ...[]byte) []byte {
+	h, _ := blake2b.NewBlake2b(32, key)
+	for _, m := range message {
+		h.Write(m)
+	}
+
+	return h.Sum(nil)
+}
@@ -0,0 +1,325 @@
+package paserk_test
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/argon2"
+	"github.com/pmuens/ctk-go/ctk/paserk"
+)
+
+// params are cheap Argon2id parameters to keep the tests fast.
+var params = argon2.Params{Time: 1, Memory: 64, Parallelism: 1}
+
+// keyPair returns a new Ed25519 key pair.
+func keyPair(t *testing.T) ([32]byte, [64]byte) {
+	t.Helper()
+
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	return [32]byte(public), [64]byte(private)
+}
+
+// tamper flips a bit of the last character's value of the PASERK.
+func tamper(paserk string) string {
+	last := paserk[len(paserk)-1]
+	replacement := byte('A')
+	if last == 'A' {
+		replacement = 'B'
+	}
+
+	return paserk[:len(paserk)-1] + string(replacement)
+}
+
+func TestEncode(t *testing.T) {
+	// The key and PASERKs are the ones of the k4.local-1 and k4.lid-1 test
+	// vectors of https://github.com/paseto-standard/test-vectors.
+	var key [32]byte
+	for i := range key {
+		key[i] = byte(0x70 + i)
+	}
+
+	t.Run("PASERK Test Vectors - k4.local", func(t *testing.T) {
+		t.Parallel()
+
+		want := "k4.local.cHFyc3R1dnd4eXp7fH1-f4CBgoOEhYaHiImKi4yNjo8"
+		got := paserk.EncodeLocal(key)
+
+		if got != want {
+			t.Errorf("want %v, got %v", want, got)
+		}
+
+		decoded, err := paserk.DecodeLocal(got)
+		if decoded != key || err != nil {
+			t.Errorf("want %v, got %v (error %v)", key, decoded, err)
+		}
+	})
+
+	t.Run("PASERK Test Vectors - k4.lid", func(t *testing.T) {
+		t.Parallel()
+
+		want := "k4.lid.iVtYQDjr5gEijCSjJC3fQaJm7nCeQSeaty0Jixy8dbsk"
+		got, err := paserk.ID(paserk.EncodeLocal(key))
+
+		if got != want || err != nil {
+			t.Errorf("want %v, got %v (error %v)", want, got, err)
+		}
+	})
+
+	t.Run("Public + Secret", func(t *testing.T) {
+		t.Parallel()
+
+		public, secret := keyPair(t)
+
+		decodedPublic, err := paserk.DecodePublic(paserk.EncodePublic(public))
+		if decodedPublic != public || err != nil {
+			t.Errorf("want %v, got %v (error %v)", public, decodedPublic, err)
+		}
+
+		decodedSecret, err := paserk.DecodeSecret(paserk.EncodeSecret(secret))
+		if decodedSecret != secret || err != nil {
+			t.Errorf("want %v, got %v (error %v)", secret, decodedSecret, err)
+		}
+
+		for encoded, prefix := range map[string]string{
+			paserk.EncodePublic(public): paserk.TypePublicID,
+			paserk.EncodeSecret(secret): paserk.TypeSecretID,
+		} {
+			id, err := paserk.ID(encoded)
+			if !strings.HasPrefix(id, prefix) || err != nil {
+				t.Errorf("want %v prefix, got %v (error %v)", prefix, id, err)
+			}
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		_, secret := keyPair(t)
+		mismatched := secret
+		mismatched[63] ^= 0x01
+
+		decodeLocal := func(encoded string) error { _, err := paserk.DecodeLocal(encoded); return err }
+		decodePublic := func(encoded string) error { _, err := paserk.DecodePublic(encoded); return err }
+		decodeSecret := func(encoded string) error { _, err := paserk.DecodeSecret(encoded); return err }
+		id := func(encoded string) error { _, err := paserk.ID(encoded); return err }
+
+		tt := map[string]struct {
+			err  error
+			want error
+		}{
+			"Wrong Type":        {err: decodeLocal(paserk.EncodeSecret(secret)), want: paserk.ErrWrongType},
+			"Wrong Version":     {err: decodeLocal("k3.local.cHFyc3R1dnd4eXp7fH1-f4CBgoOEhYaHiImKi4yNjo8"), want: paserk.ErrWrongType},
+			"Short Key":         {err: decodeLocal("k4.local.cHFyc3R1dnd4eXp7fH1-f4CBgoOEhYaHiImKi4yNjo"), want: paserk.ErrInvalidPASERK},
+			"Padding":           {err: decodePublic("k4.public.cHFyc3R1dnd4eXp7fH1-f4CBgoOEhYaHiImKi4yNjo8="), want: paserk.ErrInvalidPASERK},
+			"Mismatched Secret": {err: decodeSecret(paserk.EncodeSecret(mismatched)), want: paserk.ErrInvalidPASERK},
+			"ID Of Sealed Key":  {err: id("k4.seal.AAAA"), want: paserk.ErrWrongType},
+		}
+
+		for name, tc := range tt {
+			if !errors.Is(tc.err, tc.want) {
+				t.Errorf("%v: want error %v, got %v", name, tc.want, tc.err)
+			}
+		}
+	})
+}
+
+func TestSeal(t *testing.T) {
+	key := [32]byte{0x01, 0x02, 0x03}
+	public, secret := keyPair(t)
+
+	sealed, err := paserk.Seal(key, public)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	t.Run("Unseal", func(t *testing.T) {
+		t.Parallel()
+
+		if !strings.HasPrefix(sealed, paserk.TypeSeal) {
+			t.Errorf("want %v prefix, got %v", paserk.TypeSeal, sealed)
+		}
+
+		got, err := paserk.Unseal(sealed, secret)
+		if got != key || err != nil {
+			t.Errorf("want %v, got %v (error %v)", key, got, err)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		_, otherSecret := keyPair(t)
+
+		tt := map[string]struct {
+			paserk string
+			secret [64]byte
+			want   error
+		}{
+			"Wrong Key":  {paserk: sealed, secret: otherSecret, want: paserk.ErrDecryption},
+			"Tampered":   {paserk: tamper(sealed), secret: secret, want: paserk.ErrDecryption},
+			"Truncated":  {paserk: sealed[:len(sealed)-4], secret: secret, want: paserk.ErrDecryption},
+			"Not Base64": {paserk: paserk.TypeSeal + "!", secret: secret, want: paserk.ErrDecryption},
+			"Wrong Type": {paserk: paserk.EncodeLocal(key), secret: secret, want: paserk.ErrWrongType},
+		}
+
+		for name, tc := range tt {
+			got, err := paserk.Unseal(tc.paserk, tc.secret)
+			if !errors.Is(err, tc.want) || got != [32]byte{} {
+				t.Errorf("%v: want error %v, got %v (key %v)", name, tc.want, err, got)
+			}
+		}
+	})
+
+	t.Run("Invalid Public Key", func(t *testing.T) {
+		t.Parallel()
+
+		// The identity point can't be converted to an X25519 public key.
+		_, err := paserk.Seal(key, [32]byte{0x01})
+		if err == nil {
+			t.Errorf("want error, got %v", err)
+		}
+	})
+}
+
+func TestWrap(t *testing.T) {
+	wrappingKey := [32]byte{0x42}
+	otherKey := [32]byte{0x43}
+
+	t.Run("Local", func(t *testing.T) {
+		t.Parallel()
+
+		key := [32]byte{0x01, 0x02, 0x03}
+
+		wrapped, err := paserk.WrapLocal(key, wrappingKey)
+		if !strings.HasPrefix(wrapped, paserk.TypeLocalWrap) || err != nil {
+			t.Fatalf("want %v prefix, got %v (error %v)", paserk.TypeLocalWrap, wrapped, err)
+		}
+
+		got, err := paserk.UnwrapLocal(wrapped, wrappingKey)
+		if got != key || err != nil {
+			t.Errorf("want %v, got %v (error %v)", key, got, err)
+		}
+
+		for name, tc := range map[string]struct {
+			paserk string
+			key    [32]byte
+			want   error
+		}{
+			"Wrong Key":  {paserk: wrapped, key: otherKey, want: paserk.ErrDecryption},
+			"Tampered":   {paserk: tamper(wrapped), key: wrappingKey, want: paserk.ErrDecryption},
+			"Truncated":  {paserk: wrapped[:len(wrapped)-4], key: wrappingKey, want: paserk.ErrDecryption},
+			"Wrong Type": {paserk: strings.Replace(wrapped, "local", "secret", 1), key: wrappingKey, want: paserk.ErrWrongType},
+		} {
+			_, err := paserk.UnwrapLocal(tc.paserk, tc.key)
+			if !errors.Is(err, tc.want) {
+				t.Errorf("%v: want error %v, got %v", name, tc.want, err)
+			}
+		}
+	})
+
+	t.Run("Secret", func(t *testing.T) {
+		t.Parallel()
+
+		_, secret := keyPair(t)
+
+		wrapped, err := paserk.WrapSecret(secret, wrappingKey)
+		if !strings.HasPrefix(wrapped, paserk.TypeSecretWrap) || err != nil {
+			t.Fatalf("want %v prefix, got %v (error %v)", paserk.TypeSecretWrap, wrapped, err)
+		}
+
+		got, err := paserk.UnwrapSecret(wrapped, wrappingKey)
+		if got != secret || err != nil {
+			t.Errorf("want %v, got %v (error %v)", secret, got, err)
+		}
+
+		_, err = paserk.UnwrapSecret(wrapped, otherKey)
+		if !errors.Is(err, paserk.ErrDecryption) {
+			t.Errorf("want error %v, got %v", paserk.ErrDecryption, err)
+		}
+	})
+}
+
+func TestPasswordWrap(t *testing.T) {
+	password := []byte("correct horse battery staple")
+
+	t.Run("Local", func(t *testing.T) {
+		t.Parallel()
+
+		key := [32]byte{0x01, 0x02, 0x03}
+
+		wrapped, err := paserk.PasswordWrapLocal(key, password, params)
+		if !strings.HasPrefix(wrapped, paserk.TypeLocalPassword) || err != nil {
+			t.Fatalf("want %v prefix, got %v (error %v)", paserk.TypeLocalPassword, wrapped, err)
+		}
+
+		got, err := paserk.PasswordUnwrapLocal(wrapped, password)
+		if got != key || err != nil {
+			t.Errorf("want %v, got %v (error %v)", key, got, err)
+		}
+
+		for name, tc := range map[string]struct {
+			paserk   string
+			password []byte
+			want     error
+		}{
+			"Wrong Password": {paserk: wrapped, password: []byte("wrong"), want: paserk.ErrDecryption},
+			"Tampered":       {paserk: tamper(wrapped), password: password, want: paserk.ErrDecryption},
+			"Truncated":      {paserk: wrapped[:len(wrapped)-4], password: password, want: paserk.ErrDecryption},
+			"Wrong Type":     {paserk: paserk.EncodeLocal(key), password: password, want: paserk.ErrWrongType},
+		} {
+			_, err := paserk.PasswordUnwrapLocal(tc.paserk, tc.password)
+			if !errors.Is(err, tc.want) {
+				t.Errorf("%v: want error %v, got %v", name, tc.want, err)
+			}
+		}
+	})
+
+	t.Run("Secret", func(t *testing.T) {
+		t.Parallel()
+
+		_, secret := keyPair(t)
+
+		wrapped, err := paserk.PasswordWrapSecret(secret, password, params)
+		if !strings.HasPrefix(wrapped, paserk.TypeSecretPassword) || err != nil {
+			t.Fatalf("want %v prefix, got %v (error %v)", paserk.TypeSecretPassword, wrapped, err)
+		}
+
+		got, err := paserk.PasswordUnwrapSecret(wrapped, password)
+		if got != secret || err != nil {
+			t.Errorf("want %v, got %v (error %v)", secret, got, err)
+		}
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		t.Parallel()
+
+		// The parameters are only checked once the PASERK is unwrapped.
+		expensive := params
+		expensive.Time = paserk.MaxPasswordTime + 1
+
+		wrapped, err := paserk.PasswordWrapLocal([32]byte{0x01}, password, expensive)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		_, err = paserk.PasswordUnwrapLocal(wrapped, password)
+		if !errors.Is(err, paserk.ErrDecryption) {
+			t.Errorf("want error %v, got %v", paserk.ErrDecryption, err)
+		}
+	})
+
+	t.Run("Invalid Parameters", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := paserk.PasswordWrapLocal([32]byte{0x01}, password, argon2.Params{})
+		if !errors.Is(err, argon2.ErrInvalidParams) {
+			t.Errorf("want error %v, got %v", argon2.ErrInvalidParams, err)
+		}
+	})
+}
@@ -0,0 +1,218 @@
+package paserk
+
+import (
+	"encoding/binary"
+	"errors"
+	"slices"
+
+	"github.com/pmuens/ctk-go/ctk/argon2"
+	"github.com/pmuens/ctk-go/ctk/blake2b"
+	"github.com/pmuens/ctk-go/ctk/internal/debug"
+	"github.com/pmuens/ctk-go/ctk/random"
+	"github.com/pmuens/ctk-go/ctk/subtle"
+	"github.com/pmuens/ctk-go/ctk/xchacha20"
+)
+
+// MaxPasswordMemory is the maximum Argon2id memory (in KiB) that a password
+// wrapped key may require to be unwrapped (1 GiB) so that a malicious PASERK
+// can't exhaust the memory.
+const MaxPasswordMemory = 1 << 20
+
+// MaxPasswordTime is the maximum number of Argon2id passes that a password
+// wrapped key may require to be unwrapped.
+const MaxPasswordTime = 16
+
+// Sizes of the parts of a password wrapped key (the salt, the Argon2id
+// parameters and the nonce which are followed by the encrypted key and the
+// tag).
+const (
+	// passwordSaltSize is the size (in bytes) of the salt.
+	passwordSaltSize = 16
+
+	// passwordParamsSize is the size (in bytes) of the encoded parameters (the
+	// memory in bytes, the time and the parallelism).
+	passwordParamsSize = 8 + 4 + 4
+
+	// passwordNonceSize is the size (in bytes) of the nonce.
+	passwordNonceSize = 24
+
+	// passwordTagSize is the size (in bytes) of the tag.
+	passwordTagSize = 32
+
+	// passwordOverhead is the size (in bytes) of a body without the key.
+	passwordOverhead = passwordSaltSize + passwordParamsSize + passwordNonceSize + passwordTagSize
+)
+
+// PasswordWrapLocal wraps the symmetric key with a key that's derived from the
+// password via Argon2id and returns the k4.local-pw PASERK. The parameters'
+// time, memory and parallelism are used (the other fields are ignored).
+// Returns an error if the parameters are invalid.
+func PasswordWrapLocal(key [32]byte, password []byte, params argon2.Params) (string, error) {
+	return passwordWrap(TypeLocalPassword, key[:], password, params)
+}
+
+// PasswordUnwrapLocal unwraps the symmetric key of the k4.local-pw PASERK with
+// the password.
+// Returns ErrWrongType if the PASERK has another type and ErrDecryption if it
+// can't be authenticated or if it requires more than MaxPasswordMemory or
+// MaxPasswordTime.
+func PasswordUnwrapLocal(paserk string, password []byte) ([32]byte, error) {
+	key, err := passwordUnwrap(paserk, TypeLocalPassword, 32, password)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	defer clear(key)
+
+	return [32]byte(key), nil
+}
+
+// PasswordWrapSecret wraps the Ed25519 secret key with a key that's derived
+// from the password via Argon2id and returns the k4.secret-pw PASERK (see
+// PasswordWrapLocal).
+func PasswordWrapSecret(key [64]byte, password []byte, params argon2.Params) (string, error) {
+	return passwordWrap(TypeSecretPassword, key[:], password, params)
+}
+
+// PasswordUnwrapSecret unwraps the Ed25519 secret key of the k4.secret-pw
+// PASERK with the password (see PasswordUnwrapLocal).
+func PasswordUnwrapSecret(paserk string, password []byte) ([64]byte, error) {
+	key, err := passwordUnwrap(paserk, TypeSecretPassword, 64, password)
+	if err != nil {
+		return [64]byte{}, err
+	}
+	defer clear(key)
+
+	if !validSecret([64]byte(key)) {
+		return [64]byte{}, debug.Detail(ErrDecryption, ErrInvalidPASERK)
+	}
+
+	return [64]byte(key), nil
+}
+
+// passwordWrap encrypts and authenticates the key (see PasswordWrapLocal).
+func passwordWrap(header string, key []byte, password []byte, params argon2.Params) (string, error) {
+	var salt [passwordSaltSize]byte
+	var nonce [passwordNonceSize]byte
+
+	err := random.Read(salt[:])
+	if err != nil {
+		return "", err
+	}
+
+	err = random.Read(nonce[:])
+	if err != nil {
+		return "", err
+	}
+
+	params = argon2.Params{
+		Variant:     argon2.Argon2id,
+		Time:        params.Time,
+		Memory:      params.Memory,
+		Parallelism: params.Parallelism,
+		KeyLength:   32,
+	}
+	encodedParams := encodePasswordParams(params)
+
+	ek, ak, err := passwordKeys(password, salt[:], params)
+	if err != nil {
+		return "", err
+	}
+	defer clear(ek[:])
+	defer clear(ak)
+
+	edk := xchacha20.NewXChaCha20WithCounter(ek, nonce, 0).XORWithKeyStream(key)
+	tag := mac(ak, []byte(header), salt[:], encodedParams, nonce[:], edk)
+
+	return header + encoding.EncodeToString(slices.Concat(salt[:], encodedParams, nonce[:], edk, tag)), nil
+}
+
+// passwordUnwrap authenticates and decrypts the key of size bytes (see
+// PasswordUnwrapLocal).
+func passwordUnwrap(paserk string, header string, size int, password []byte) ([]byte, error) {
+	body, err := decodeAny(paserk, header)
+	if errors.Is(err, ErrWrongType) {
+		return []byte{}, err
+	}
+	if err != nil {
+		return []byte{}, debug.Detail(ErrDecryption, err)
+	}
+
+	if len(body) != passwordOverhead+size {
+		return []byte{}, debug.Detail(ErrDecryption, ErrInvalidPASERK)
+	}
+
+	salt := body[:passwordSaltSize]
+	encodedParams := body[passwordSaltSize : passwordSaltSize+passwordParamsSize]
+	nonce := [passwordNonceSize]byte(body[passwordSaltSize+passwordParamsSize : passwordSaltSize+passwordParamsSize+passwordNonceSize])
+	edk := body[passwordSaltSize+passwordParamsSize+passwordNonceSize : len(body)-passwordTagSize]
+	tag := body[len(body)-passwordTagSize:]
+
+	params, err := decodePasswordParams(encodedParams)
+	if err != nil {
+		return []byte{}, debug.Detail(ErrDecryption, err)
+	}
+
+	ek, ak, err := passwordKeys(password, salt, params)
+	if err != nil {
+		return []byte{}, debug.Detail(ErrDecryption, err)
+	}
+	defer clear(ek[:])
+	defer clear(ak)
+
+	if subtle.ConstantTimeCompare(tag, mac(ak, []byte(header), salt, encodedParams, nonce[:], edk)) != 1 {
+		return []byte{}, debug.Detail(ErrDecryption, ErrInvalidPASERK)
+	}
+
+	return xchacha20.NewXChaCha20WithCounter(ek, nonce, 0).XORWithKeyStream(edk), nil
+}
+
+// passwordKeys derives the encryption and authentication keys from the
+// password.
+func passwordKeys(password []byte, salt []byte, params argon2.Params) ([32]byte, []byte, error) {
+	preKey, err := argon2.Key(password, salt, params)
+	if err != nil {
+		return [32]byte{}, []byte{}, err
+	}
+	defer clear(preKey)
+
+	input := slices.Concat([]byte{0xff}, preKey)
+	defer clear(input)
+
+	ek := blake2b.Sum256(input)
+	input[0] = 0xfe
+	ak := blake2b.Sum256(input)
+
+	return ek, ak[:], nil
+}
+
+// encodePasswordParams encodes the memory (in bytes) as a 64 bit and the time
+// and the parallelism as 32 bit big endian integers.
+func encodePasswordParams(params argon2.Params) []byte {
+	encoded := make([]byte, passwordParamsSize)
+	binary.BigEndian.PutUint64(encoded[0:8], uint64(params.Memory)*1024)
+	binary.BigEndian.PutUint32(encoded[8:12], params.Time)
+	binary.BigEndian.PutUint32(encoded[12:16], params.Parallelism)
+
+	return encoded
+}
+
+// decodePasswordParams decodes the parameters that were encoded via
+// encodePasswordParams.
+// Returns ErrInvalidPASERK if the memory isn't a multiple of 1 KiB or if the
+// parameters exceed MaxPasswordMemory or MaxPasswordTime.
+func decodePasswordParams(encoded []byte) (argon2.Params, error) {
+	memory := binary.BigEndian.Uint64(encoded[0:8])
+	time := binary.BigEndian.Uint32(encoded[8:12])
+
+	if memory%1024 != 0 || memory/1024 > MaxPasswordMemory || time > MaxPasswordTime {
+		return argon2.Params{}, ErrInvalidPASERK
+	}
+
+	return argon2.Params{
+		Variant:     argon2.Argon2id,
+		Time:        time,
+		Memory:      uint32(memory / 1024),
+		Parallelism: binary.BigEndian.Uint32(encoded[12:16]),
+		KeyLength:   32,
+	}, nil
+}
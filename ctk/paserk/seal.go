@@ -0,0 +1,117 @@
+package paserk
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/pmuens/ctk-go/ctk/blake2b"
+	"github.com/pmuens/ctk-go/ctk/internal/debug"
+	"github.com/pmuens/ctk-go/ctk/subtle"
+	"github.com/pmuens/ctk-go/ctk/x25519"
+	"github.com/pmuens/ctk-go/ctk/xchacha20"
+)
+
+// Sizes of the parts of a k4.seal body (the tag, the ephemeral public key and
+// the encrypted key).
+const (
+	// sealTagSize is the size (in bytes) of the tag.
+	sealTagSize = 32
+
+	// sealBodySize is the size (in bytes) of the body.
+	sealBodySize = sealTagSize + x25519.KeySize + 32
+)
+
+// Seal encrypts the symmetric key for the owner of the Ed25519 public key and
+// returns the k4.seal PASERK. The public key is converted to an X25519 public
+// key that's used for an ephemeral key exchange.
+// Returns an error if the public key is invalid.
+func Seal(key [32]byte, public [32]byte) (string, error) {
+	xpk, err := x25519.FromEd25519PublicKey(public)
+	if err != nil {
+		return "", err
+	}
+
+	esk, epk, err := x25519.GenerateKey()
+	if err != nil {
+		return "", err
+	}
+	defer clear(esk[:])
+
+	xk, err := x25519.SharedSecret(esk, xpk)
+	if err != nil {
+		return "", err
+	}
+	defer clear(xk[:])
+
+	ek, ak, nonce := sealKeys(xk, epk, xpk)
+	defer clear(ek[:])
+	defer clear(ak)
+
+	edk := xchacha20.NewXChaCha20WithCounter(ek, nonce, 0).XORWithKeyStream(key[:])
+	tag := mac(ak, []byte(TypeSeal), epk[:], edk)
+
+	return TypeSeal + encoding.EncodeToString(slices.Concat(tag, epk[:], edk)), nil
+}
+
+// Unseal decrypts the symmetric key of the k4.seal PASERK with the Ed25519
+// secret key whose public key it was sealed for.
+// Returns ErrWrongType if the PASERK has another type and ErrDecryption if it
+// can't be authenticated.
+func Unseal(paserk string, secret [64]byte) ([32]byte, error) {
+	body, err := decodeAny(paserk, TypeSeal)
+	if errors.Is(err, ErrWrongType) {
+		return [32]byte{}, err
+	}
+	if err != nil {
+		return [32]byte{}, debug.Detail(ErrDecryption, err)
+	}
+
+	if len(body) != sealBodySize {
+		return [32]byte{}, debug.Detail(ErrDecryption, ErrInvalidPASERK)
+	}
+
+	tag := body[:sealTagSize]
+	epk := [32]byte(body[sealTagSize : sealTagSize+x25519.KeySize])
+	edk := body[sealTagSize+x25519.KeySize:]
+
+	xsk := x25519.FromEd25519PrivateKey([32]byte(secret[:32]))
+	defer clear(xsk[:])
+
+	xpk, err := x25519.FromEd25519PublicKey([32]byte(secret[32:]))
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	xk, err := x25519.SharedSecret(xsk, epk)
+	if err != nil {
+		return [32]byte{}, debug.Detail(ErrDecryption, err)
+	}
+	defer clear(xk[:])
+
+	ek, ak, nonce := sealKeys(xk, epk, xpk)
+	defer clear(ek[:])
+	defer clear(ak)
+
+	if subtle.ConstantTimeCompare(tag, mac(ak, []byte(TypeSeal), epk[:], edk)) != 1 {
+		return [32]byte{}, debug.Detail(ErrDecryption, ErrInvalidPASERK)
+	}
+
+	decrypted := xchacha20.NewXChaCha20WithCounter(ek, nonce, 0).XORWithKeyStream(edk)
+	defer clear(decrypted)
+
+	return [32]byte(decrypted), nil
+}
+
+// sealKeys derives the encryption key, the authentication key and the nonce of
+// a k4.seal from the shared secret and the public keys.
+func sealKeys(xk [32]byte, epk [32]byte, xpk [32]byte) ([32]byte, []byte, [24]byte) {
+	input := slices.Concat([]byte{0x01}, []byte(TypeSeal), xk[:], epk[:], xpk[:])
+	defer clear(input)
+
+	ek := blake2b.Sum256(input)
+	input[0] = 0x02
+	ak := blake2b.Sum256(input)
+	nonce, _ := blake2b.Sum(slices.Concat(epk[:], xpk[:]), 24)
+
+	return ek, ak[:], [24]byte(nonce)
+}
@@ -0,0 +1,130 @@
+package paserk
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/pmuens/ctk-go/ctk/blake2b"
+	"github.com/pmuens/ctk-go/ctk/internal/debug"
+	"github.com/pmuens/ctk-go/ctk/random"
+	"github.com/pmuens/ctk-go/ctk/subtle"
+	"github.com/pmuens/ctk-go/ctk/xchacha20"
+)
+
+// Sizes of the parts of a PIE wrapped key (the tag and the nonce which are
+// followed by the encrypted key).
+const (
+	// wrapTagSize is the size (in bytes) of the tag.
+	wrapTagSize = 32
+
+	// wrapNonceSize is the size (in bytes) of the nonce.
+	wrapNonceSize = 32
+)
+
+// WrapLocal wraps the symmetric key with the wrapping key via the PIE protocol
+// and returns the k4.local-wrap.pie PASERK.
+// Returns an error if the nonce can't be generated.
+func WrapLocal(key [32]byte, wrappingKey [32]byte) (string, error) {
+	return wrap(TypeLocalWrap, key[:], wrappingKey)
+}
+
+// UnwrapLocal unwraps the symmetric key of the k4.local-wrap.pie PASERK with
+// the wrapping key.
+// Returns ErrWrongType if the PASERK has another type and ErrDecryption if it
+// can't be authenticated.
+func UnwrapLocal(paserk string, wrappingKey [32]byte) ([32]byte, error) {
+	key, err := unwrap(paserk, TypeLocalWrap, 32, wrappingKey)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	defer clear(key)
+
+	return [32]byte(key), nil
+}
+
+// WrapSecret wraps the Ed25519 secret key with the wrapping key via the PIE
+// protocol and returns the k4.secret-wrap.pie PASERK.
+// Returns an error if the nonce can't be generated.
+func WrapSecret(key [64]byte, wrappingKey [32]byte) (string, error) {
+	return wrap(TypeSecretWrap, key[:], wrappingKey)
+}
+
+// UnwrapSecret unwraps the Ed25519 secret key of the k4.secret-wrap.pie PASERK
+// with the wrapping key.
+// Returns ErrWrongType if the PASERK has another type and ErrDecryption if it
+// can't be authenticated or if the unwrapped key is invalid.
+func UnwrapSecret(paserk string, wrappingKey [32]byte) ([64]byte, error) {
+	key, err := unwrap(paserk, TypeSecretWrap, 64, wrappingKey)
+	if err != nil {
+		return [64]byte{}, err
+	}
+	defer clear(key)
+
+	if !validSecret([64]byte(key)) {
+		return [64]byte{}, debug.Detail(ErrDecryption, ErrInvalidPASERK)
+	}
+
+	return [64]byte(key), nil
+}
+
+// wrap encrypts and authenticates the key (see WrapLocal).
+func wrap(header string, key []byte, wrappingKey [32]byte) (string, error) {
+	var nonce [wrapNonceSize]byte
+
+	err := random.Read(nonce[:])
+	if err != nil {
+		return "", err
+	}
+
+	ek, ak, n2 := wrapKeys(wrappingKey, nonce)
+	defer clear(ek[:])
+	defer clear(ak)
+
+	c := xchacha20.NewXChaCha20WithCounter(ek, n2, 0).XORWithKeyStream(key)
+	tag := mac(ak, []byte(header), nonce[:], c)
+
+	return header + encoding.EncodeToString(slices.Concat(tag, nonce[:], c)), nil
+}
+
+// unwrap authenticates and decrypts the key of size bytes (see UnwrapLocal).
+func unwrap(paserk string, header string, size int, wrappingKey [32]byte) ([]byte, error) {
+	body, err := decodeAny(paserk, header)
+	if errors.Is(err, ErrWrongType) {
+		return []byte{}, err
+	}
+	if err != nil {
+		return []byte{}, debug.Detail(ErrDecryption, err)
+	}
+
+	if len(body) != wrapTagSize+wrapNonceSize+size {
+		return []byte{}, debug.Detail(ErrDecryption, ErrInvalidPASERK)
+	}
+
+	tag := body[:wrapTagSize]
+	nonce := [wrapNonceSize]byte(body[wrapTagSize : wrapTagSize+wrapNonceSize])
+	c := body[wrapTagSize+wrapNonceSize:]
+
+	ek, ak, n2 := wrapKeys(wrappingKey, nonce)
+	defer clear(ek[:])
+	defer clear(ak)
+
+	if subtle.ConstantTimeCompare(tag, mac(ak, []byte(header), nonce[:], c)) != 1 {
+		return []byte{}, debug.Detail(ErrDecryption, ErrInvalidPASERK)
+	}
+
+	return xchacha20.NewXChaCha20WithCounter(ek, n2, 0).XORWithKeyStream(c), nil
+}
+
+// wrapKeys derives the encryption key, the authentication key and the
+// XChaCha20 nonce of a PIE wrapped key from the wrapping key and the nonce.
+func wrapKeys(wrappingKey [32]byte, nonce [wrapNonceSize]byte) ([32]byte, []byte, [24]byte) {
+	h, _ := blake2b.NewBlake2b(56, wrappingKey[:])
+	h.Write([]byte{0x80})
+	h.Write(nonce[:])
+	x := h.Sum(nil)
+	defer clear(x)
+
+	ak := mac(wrappingKey[:], []byte{0x81}, nonce[:])
+
+	return [32]byte(x[:32]), ak, [24]byte(x[32:])
+}
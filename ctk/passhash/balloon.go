@@ -0,0 +1,133 @@
+package passhash
+
+import (
+	"github.com/pmuens/ctk-go/ctk/balloon"
+	"github.com/pmuens/ctk-go/ctk/random"
+)
+
+// balloonPrefix is the prefix of the identifiers of Balloon hash strings which
+// is followed by the name of the hash function (e.g. "balloon-sha256").
+const balloonPrefix = "balloon-"
+
+// balloonMinSaltSize is the minimum size (in bytes) of the salt of Balloon hash
+// strings (same as for Argon2).
+const balloonMinSaltSize = 8
+
+// BalloonVersion is the version of Balloon hash strings.
+const BalloonVersion = 1
+
+// The maximum Balloon parameters (see MaxMemory).
+const (
+	// MaxBalloonSpace is the maximum number of blocks, i.e. 4 GiB with the
+	// 32 byte blocks.
+	MaxBalloonSpace = 128 * 1024 * 1024
+
+	// MaxBalloonTime is the maximum number of rounds.
+	MaxBalloonTime = 64
+
+	// MaxBalloonDelta is the maximum number of dependencies per block.
+	MaxBalloonDelta = 64
+)
+
+// HashBalloon hashes the password with a random salt using the Balloon
+// parameters and returns the hash string.
+// Returns ErrParamsTooLarge if the parameters exceed MaxBalloonSpace,
+// MaxBalloonTime or MaxBalloonDelta and an error if they are invalid.
+func HashBalloon(password []byte, params balloon.Params) (string, error) {
+	if !balloonBounded(params) {
+		return "", ErrParamsTooLarge
+	}
+
+	salt, err := random.Bytes(SaltSize)
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := balloon.Key(password, salt, params)
+	if err != nil {
+		return "", err
+	}
+
+	return formatBalloon(params, salt, hash), nil
+}
+
+// NeedsRehashBalloon reports whether the hash string was created with Balloon
+// parameters other than params (or a shorter salt) and should therefore be
+// replaced by a new hash of the password. Hash strings that can't be parsed
+// (or use another algorithm) need a rehash as well.
+func NeedsRehashBalloon(hash string, params balloon.Params) bool {
+	current, salt, _, err := parseBalloon(hash)
+	if err != nil {
+		return true
+	}
+
+	return current != params || len(salt) < SaltSize
+}
+
+// deriveBalloon hashes the password with the parameters and salt of the
+// Balloon hash string and returns the result and the hash of the hash string.
+func deriveBalloon(password []byte, hash string) ([]byte, []byte, error) {
+	params, salt, want, err := parseBalloon(hash)
+	if err != nil {
+		return []byte{}, []byte{}, err
+	}
+
+	got, err := balloon.Key(password, salt, params)
+	if err != nil {
+		return []byte{}, []byte{}, ErrInvalidHash
+	}
+
+	return got, want, nil
+}
+
+// formatBalloon encodes the Balloon parameters, salt and hash as a hash string.
+func formatBalloon(params balloon.Params, salt []byte, hash []byte) string {
+	return phc{
+		id:      balloonPrefix + params.Hash.String(),
+		version: BalloonVersion,
+		params: []param{
+			{name: "s", value: params.Space},
+			{name: "t", value: params.Time},
+			{name: "d", value: params.Delta},
+		},
+		salt: salt,
+		hash: hash,
+	}.String()
+}
+
+// parseBalloon decodes a Balloon hash string into the parameters, the salt and
+// the hash. Only the parameters in the order s, t, d (up to the maximums) are
+// accepted.
+func parseBalloon(hash string) (balloon.Params, []byte, []byte, error) {
+	p, ok := parsePHC(hash, "s", "t", "d")
+	if !ok || p.version != BalloonVersion || len(p.salt) < balloonMinSaltSize || len(p.hash) != balloon.Size {
+		return balloon.Params{}, []byte{}, []byte{}, ErrInvalidHash
+	}
+
+	var params balloon.Params
+
+	switch p.id {
+	case balloonPrefix + balloon.SHA256.String():
+		params.Hash = balloon.SHA256
+	case balloonPrefix + balloon.BLAKE2b256.String():
+		params.Hash = balloon.BLAKE2b256
+	default:
+		return balloon.Params{}, []byte{}, []byte{}, ErrInvalidHash
+	}
+
+	params.Space = p.params[0].value
+	params.Time = p.params[1].value
+	params.Delta = p.params[2].value
+
+	if !balloonBounded(params) {
+		return balloon.Params{}, []byte{}, []byte{}, ErrInvalidHash
+	}
+
+	return params, p.salt, p.hash, nil
+}
+
+// balloonBounded reports whether the Balloon parameters don't exceed the
+// maximums.
+func balloonBounded(params balloon.Params) bool {
+	return params.Space <= MaxBalloonSpace && params.Time <= MaxBalloonTime && params.Delta <= MaxBalloonDelta
+}
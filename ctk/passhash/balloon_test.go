@@ -0,0 +1,117 @@
+package passhash_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/balloon"
+	"github.com/pmuens/ctk-go/ctk/passhash"
+)
+
+var balloonParams = balloon.Params{
+	Hash:  balloon.SHA256,
+	Space: 16,
+	Time:  3,
+	Delta: 3,
+}
+
+func TestPasshashBalloon(t *testing.T) {
+	password := []byte("password")
+
+	t.Run("Test Vectors", func(t *testing.T) {
+		t.Parallel()
+
+		hashes := []string{
+			"$balloon-sha256$v=1$s=16,t=3,d=3$c29tZXNhbHQ$YTJy56lWKylqgDhKaW62RYdt+LKDA/ZyPTbWfqYqNqg",
+			"$balloon-blake2b256$v=1$s=16,t=3,d=3$c29tZXNhbHQ$aouiS8J6imbm2w7RB5d4fdmZCPwfC6icQdA1kI/44Io",
+		}
+
+		for _, hash := range hashes {
+			err := passhash.Verify(password, hash)
+			if err != nil {
+				t.Errorf("%v: want error %v, got %v", hash, nil, err)
+			}
+
+			err = passhash.Verify([]byte("wrong"), hash)
+			if !errors.Is(err, passhash.ErrMismatchedHash) {
+				t.Errorf("%v: want error %v, got %v", hash, passhash.ErrMismatchedHash, err)
+			}
+		}
+	})
+
+	t.Run("Hash and Verify", func(t *testing.T) {
+		t.Parallel()
+
+		hash, err := passhash.HashBalloon(password, balloonParams)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if !strings.HasPrefix(hash, "$balloon-sha256$v=1$s=16,t=3,d=3$") {
+			t.Errorf("want prefix %v, got %v", "$balloon-sha256$v=1$s=16,t=3,d=3$", hash)
+		}
+
+		err = passhash.Verify(password, hash)
+		if err != nil {
+			t.Errorf("want error %v, got %v", nil, err)
+		}
+	})
+
+	t.Run("Params Too Large", func(t *testing.T) {
+		t.Parallel()
+
+		large := balloonParams
+		large.Space = passhash.MaxBalloonSpace + 1
+
+		_, err := passhash.HashBalloon(password, large)
+		if !errors.Is(err, passhash.ErrParamsTooLarge) {
+			t.Errorf("want error %v, got %v", passhash.ErrParamsTooLarge, err)
+		}
+	})
+
+	t.Run("Needs Rehash", func(t *testing.T) {
+		t.Parallel()
+
+		hash, _ := passhash.HashBalloon(password, balloonParams)
+		argon2Hash, _ := passhash.Hash(password, params)
+
+		stronger := balloonParams
+		stronger.Space *= 2
+
+		if passhash.NeedsRehashBalloon(hash, balloonParams) {
+			t.Errorf("want %v, got %v", false, true)
+		}
+
+		if !passhash.NeedsRehashBalloon(hash, stronger) {
+			t.Errorf("want %v, got %v", true, false)
+		}
+
+		// Migrating from one algorithm to the other requires a rehash.
+		if !passhash.NeedsRehashBalloon(argon2Hash, balloonParams) || !passhash.NeedsRehash(hash, params) {
+			t.Errorf("want %v, got %v", true, false)
+		}
+	})
+
+	t.Run("Invalid Hashes", func(t *testing.T) {
+		t.Parallel()
+
+		hashes := map[string]string{
+			"Unknown Hash Function": "$balloon-md5$v=1$s=16,t=3,d=3$c29tZXNhbHQ$YTJy56lWKylqgDhKaW62RYdt+LKDA/ZyPTbWfqYqNqg",
+			"Unknown Version":       "$balloon-sha256$v=2$s=16,t=3,d=3$c29tZXNhbHQ$YTJy56lWKylqgDhKaW62RYdt+LKDA/ZyPTbWfqYqNqg",
+			"Parameter Order":       "$balloon-sha256$v=1$t=3,s=16,d=3$c29tZXNhbHQ$YTJy56lWKylqgDhKaW62RYdt+LKDA/ZyPTbWfqYqNqg",
+			"Zero Space":            "$balloon-sha256$v=1$s=0,t=3,d=3$c29tZXNhbHQ$YTJy56lWKylqgDhKaW62RYdt+LKDA/ZyPTbWfqYqNqg",
+			"Short Hash":            "$balloon-sha256$v=1$s=16,t=3,d=3$c29tZXNhbHQ$YTJy56lWKylqgDhKaW62RYdt+LKD",
+			"Space Too Large":       "$balloon-sha256$v=1$s=4294967295,t=3,d=3$c29tZXNhbHQ$YTJy56lWKylqgDhKaW62RYdt+LKDA/ZyPTbWfqYqNqg",
+			"Time Too Large":        "$balloon-sha256$v=1$s=16,t=4294967295,d=3$c29tZXNhbHQ$YTJy56lWKylqgDhKaW62RYdt+LKDA/ZyPTbWfqYqNqg",
+			"Delta Too Large":       "$balloon-sha256$v=1$s=16,t=3,d=4294967295$c29tZXNhbHQ$YTJy56lWKylqgDhKaW62RYdt+LKDA/ZyPTbWfqYqNqg",
+		}
+
+		for name, hash := range hashes {
+			err := passhash.Verify(password, hash)
+			if !errors.Is(err, passhash.ErrInvalidHash) {
+				t.Errorf("%v: want error %v, got %v", name, passhash.ErrInvalidHash, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,196 @@
+// Package passhash implements the hashing of passwords for storage (e.g. user
+// credentials of a web application) with Argon2 or Balloon and hash strings in
+// the PHC string format as specified in
+// https://github.com/P-H-C/phc-string-format/blob/master/phc-sf-spec.md.
+//
+// A hash string contains the algorithm, the version, the parameters, the salt
+// and the hash (the salt and the hash are encoded as unpadded base64):
+//
+//	$argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>
+//	$balloon-sha256$v=1$s=524288,t=3,d=3$<salt>$<hash>
+//
+// Hash strings are self-contained so that the parameters can be increased over
+// time. NeedsRehash reports whether a stored hash string uses outdated
+// parameters in which case the password should be rehashed after it was
+// verified successfully.
+package passhash
+
+import (
+	"strings"
+
+	"github.com/pmuens/ctk-go/ctk/argon2"
+	"github.com/pmuens/ctk-go/ctk/random"
+	"github.com/pmuens/ctk-go/ctk/subtle"
+)
+
+const (
+	// ErrInvalidHash is returned if a hash string can't be parsed.
+	ErrInvalidHash = Error("invalid hash string")
+
+	// ErrMismatchedHash is returned if the password doesn't match the hash.
+	ErrMismatchedHash = Error("password doesn't match hash")
+
+	// ErrParamsTooLarge is returned if the parameters exceed the maximums which
+	// hash strings are accepted with.
+	ErrParamsTooLarge = Error("parameters exceed the maximums")
+)
+
+// SaltSize is the size (in bytes) of the random salt.
+const SaltSize = 16
+
+// The maximum Argon2 parameters. Hash strings with larger parameters are
+// rejected before any work is done so that a forged (or corrupted) hash string
+// can't make Verify allocate or compute an arbitrary amount.
+const (
+	// MaxMemory is the maximum memory (in KiB), i.e. 4 GiB.
+	MaxMemory = 4 * 1024 * 1024
+
+	// MaxTime is the maximum number of passes over the memory.
+	MaxTime = 64
+
+	// MaxParallelism is the maximum number of lanes.
+	MaxParallelism = 255
+)
+
+// Hash hashes the password with a random salt using the Argon2 parameters and
+// returns the hash string (the secret and associated data of the parameters
+// can't be encoded and are therefore ignored).
+// Returns ErrParamsTooLarge if the parameters exceed MaxMemory, MaxTime or
+// MaxParallelism and an error if they are invalid.
+func Hash(password []byte, params argon2.Params) (string, error) {
+	if !argon2Bounded(params) {
+		return "", ErrParamsTooLarge
+	}
+
+	salt, err := random.Bytes(SaltSize)
+	if err != nil {
+		return "", err
+	}
+
+	params = argon2.Params{
+		Variant:     params.Variant,
+		Time:        params.Time,
+		Memory:      params.Memory,
+		Parallelism: params.Parallelism,
+		KeyLength:   params.KeyLength,
+	}
+
+	hash, err := argon2.Key(password, salt, params)
+	if err != nil {
+		return "", err
+	}
+
+	return formatArgon2(params, salt, hash), nil
+}
+
+// Verify checks whether the password matches the (Argon2 or Balloon) hash
+// string.
+// Returns ErrMismatchedHash if the password doesn't match and ErrInvalidHash if
+// the hash string can't be parsed (which includes parameters above the
+// maximums).
+func Verify(password []byte, hash string) error {
+	derive := deriveArgon2
+	if strings.HasPrefix(id(hash), balloonPrefix) {
+		derive = deriveBalloon
+	}
+
+	got, want, err := derive(password, hash)
+	if err != nil {
+		return err
+	}
+
+	if subtle.ConstantTimeCompare(got, want) != 1 {
+		return ErrMismatchedHash
+	}
+
+	return nil
+}
+
+// NeedsRehash reports whether the hash string was created with Argon2
+// parameters other than params (or a shorter salt) and should therefore be
+// replaced by a new hash of the password. Hash strings that can't be parsed
+// (or use another algorithm) need a rehash as well.
+func NeedsRehash(hash string, params argon2.Params) bool {
+	current, salt, _, err := parseArgon2(hash)
+	if err != nil {
+		return true
+	}
+
+	return current.Variant != params.Variant ||
+		current.Time != params.Time ||
+		current.Memory != params.Memory ||
+		current.Parallelism != params.Parallelism ||
+		current.KeyLength != params.KeyLength ||
+		len(salt) < SaltSize
+}
+
+// deriveArgon2 hashes the password with the parameters and salt of the Argon2
+// hash string and returns the result and the hash of the hash string.
+func deriveArgon2(password []byte, hash string) ([]byte, []byte, error) {
+	params, salt, want, err := parseArgon2(hash)
+	if err != nil {
+		return []byte{}, []byte{}, err
+	}
+
+	got, err := argon2.Key(password, salt, params)
+	if err != nil {
+		return []byte{}, []byte{}, ErrInvalidHash
+	}
+
+	return got, want, nil
+}
+
+// formatArgon2 encodes the Argon2 parameters, salt and hash as a hash string.
+func formatArgon2(params argon2.Params, salt []byte, hash []byte) string {
+	return phc{
+		id:      params.Variant.String(),
+		version: argon2.Version,
+		params: []param{
+			{name: "m", value: params.Memory},
+			{name: "t", value: params.Time},
+			{name: "p", value: params.Parallelism},
+		},
+		salt: salt,
+		hash: hash,
+	}.String()
+}
+
+// parseArgon2 decodes an Argon2 hash string into the parameters, the salt and
+// the hash. Only the version 19 (0x13) and the parameters in the order m, t, p
+// (up to the maximums) are accepted.
+func parseArgon2(hash string) (argon2.Params, []byte, []byte, error) {
+	p, ok := parsePHC(hash, "m", "t", "p")
+	if !ok || p.version != argon2.Version || len(p.salt) < argon2.MinSaltSize || len(p.hash) < 4 {
+		return argon2.Params{}, []byte{}, []byte{}, ErrInvalidHash
+	}
+
+	var params argon2.Params
+
+	switch p.id {
+	case argon2.Argon2d.String():
+		params.Variant = argon2.Argon2d
+	case argon2.Argon2i.String():
+		params.Variant = argon2.Argon2i
+	case argon2.Argon2id.String():
+		params.Variant = argon2.Argon2id
+	default:
+		return argon2.Params{}, []byte{}, []byte{}, ErrInvalidHash
+	}
+
+	params.Memory = p.params[0].value
+	params.Time = p.params[1].value
+	params.Parallelism = p.params[2].value
+	params.KeyLength = uint32(len(p.hash))
+
+	if !argon2Bounded(params) {
+		return argon2.Params{}, []byte{}, []byte{}, ErrInvalidHash
+	}
+
+	return params, p.salt, p.hash, nil
+}
+
+// argon2Bounded reports whether the Argon2 parameters don't exceed the
+// maximums.
+func argon2Bounded(params argon2.Params) bool {
+	return params.Memory <= MaxMemory && params.Time <= MaxTime && params.Parallelism <= MaxParallelism
+}
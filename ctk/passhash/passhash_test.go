@@ -0,0 +1,133 @@
+package passhash_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/argon2"
+	"github.com/pmuens/ctk-go/ctk/passhash"
+)
+
+var params = argon2.Params{
+	Variant:     argon2.Argon2id,
+	Time:        2,
+	Memory:      256,
+	Parallelism: 1,
+	KeyLength:   32,
+}
+
+func TestPasshash(t *testing.T) {
+	password := []byte("password")
+
+	t.Run("Test Vectors", func(t *testing.T) {
+		t.Parallel()
+
+		hashes := []string{
+			"$argon2id$v=19$m=256,t=2,p=1$c29tZXNhbHQ$nf65EOgLrQMR/uIPnA4rEsF5h7TKyQwu9U1bMCHGi/4",
+			"$argon2i$v=19$m=256,t=2,p=2$c29tZXNhbHQ$T/XOJ2mh1/TIpJHfCdQan76Q5esCFVoT5MAeIM1Oq2E",
+		}
+
+		for _, hash := range hashes {
+			err := passhash.Verify(password, hash)
+			if err != nil {
+				t.Errorf("%v: want error %v, got %v", hash, nil, err)
+			}
+
+			err = passhash.Verify([]byte("wrong"), hash)
+			if !errors.Is(err, passhash.ErrMismatchedHash) {
+				t.Errorf("%v: want error %v, got %v", hash, passhash.ErrMismatchedHash, err)
+			}
+		}
+	})
+
+	t.Run("Hash and Verify", func(t *testing.T) {
+		t.Parallel()
+
+		hash, err := passhash.Hash(password, params)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if !strings.HasPrefix(hash, "$argon2id$v=19$m=256,t=2,p=1$") {
+			t.Errorf("want prefix %v, got %v", "$argon2id$v=19$m=256,t=2,p=1$", hash)
+		}
+
+		err = passhash.Verify(password, hash)
+		if err != nil {
+			t.Errorf("want error %v, got %v", nil, err)
+		}
+
+		other, _ := passhash.Hash(password, params)
+		if other == hash {
+			t.Errorf("want different hashes, got %v", hash)
+		}
+	})
+
+	t.Run("Params Too Large", func(t *testing.T) {
+		t.Parallel()
+
+		large := params
+		large.Memory = passhash.MaxMemory + 1
+
+		_, err := passhash.Hash(password, large)
+		if !errors.Is(err, passhash.ErrParamsTooLarge) {
+			t.Errorf("want error %v, got %v", passhash.ErrParamsTooLarge, err)
+		}
+	})
+
+	t.Run("Needs Rehash", func(t *testing.T) {
+		t.Parallel()
+
+		hash, _ := passhash.Hash(password, params)
+
+		stronger := params
+		stronger.Time++
+
+		tt := map[string]struct {
+			hash   string
+			params argon2.Params
+			want   bool
+		}{
+			"Same Params":      {hash, params, false},
+			"Stronger Params":  {hash, stronger, true},
+			"Short Salt":       {"$argon2id$v=19$m=256,t=2,p=1$c29tZXNhbHQ$nf65EOgLrQMR/uIPnA4rEsF5h7TKyQwu9U1bMCHGi/4", params, true},
+			"Invalid Hash":     {"invalid", params, true},
+			"Different Length": {hash, argon2.Params{Variant: argon2.Argon2id, Time: 2, Memory: 256, Parallelism: 1, KeyLength: 16}, true},
+		}
+
+		for name, tc := range tt {
+			got := passhash.NeedsRehash(tc.hash, tc.params)
+			if got != tc.want {
+				t.Errorf("%v: want %v, got %v", name, tc.want, got)
+			}
+		}
+	})
+
+	t.Run("Invalid Hashes", func(t *testing.T) {
+		t.Parallel()
+
+		hashes := map[string]string{
+			"Empty":             "",
+			"Unknown Algorithm": "$scrypt$v=19$m=256,t=2,p=1$c29tZXNhbHQ$nf65EOgLrQMR/uIPnA4rEsF5h7TKyQwu9U1bMCHGi/4",
+			"Unknown Version":   "$argon2id$v=16$m=256,t=2,p=1$c29tZXNhbHQ$nf65EOgLrQMR/uIPnA4rEsF5h7TKyQwu9U1bMCHGi/4",
+			"Missing Version":   "$argon2id$m=256,t=2,p=1$c29tZXNhbHQ$nf65EOgLrQMR/uIPnA4rEsF5h7TKyQwu9U1bMCHGi/4",
+			"Parameter Order":   "$argon2id$v=19$t=2,m=256,p=1$c29tZXNhbHQ$nf65EOgLrQMR/uIPnA4rEsF5h7TKyQwu9U1bMCHGi/4",
+			"Leading Zero":      "$argon2id$v=19$m=0256,t=2,p=1$c29tZXNhbHQ$nf65EOgLrQMR/uIPnA4rEsF5h7TKyQwu9U1bMCHGi/4",
+			"Padded Salt":       "$argon2id$v=19$m=256,t=2,p=1$c29tZXNhbHQ=$nf65EOgLrQMR/uIPnA4rEsF5h7TKyQwu9U1bMCHGi/4",
+			"Short Salt":        "$argon2id$v=19$m=256,t=2,p=1$c29tZQ$nf65EOgLrQMR/uIPnA4rEsF5h7TKyQwu9U1bMCHGi/4",
+			"Invalid Params":    "$argon2id$v=19$m=256,t=0,p=1$c29tZXNhbHQ$nf65EOgLrQMR/uIPnA4rEsF5h7TKyQwu9U1bMCHGi/4",
+			"Trailing Field":    "$argon2id$v=19$m=256,t=2,p=1$c29tZXNhbHQ$nf65EOgLrQMR/uIPnA4rEsF5h7TKyQwu9U1bMCHGi/4$",
+			"Memory Too Large":  "$argon2id$v=19$m=4294967295,t=2,p=1$c29tZXNhbHQ$nf65EOgLrQMR/uIPnA4rEsF5h7TKyQwu9U1bMCHGi/4",
+			"Time Too Large":    "$argon2id$v=19$m=256,t=4294967295,p=1$c29tZXNhbHQ$nf65EOgLrQMR/uIPnA4rEsF5h7TKyQwu9U1bMCHGi/4",
+			"Lanes Too Large":   "$argon2id$v=19$m=256,t=2,p=256$c29tZXNhbHQ$nf65EOgLrQMR/uIPnA4rEsF5h7TKyQwu9U1bMCHGi/4",
+		}
+
+		for name, hash := range hashes {
+			err := passhash.Verify(password, hash)
+			if !errors.Is(err, passhash.ErrInvalidHash) {
+				t.Errorf("%v: want error %v, got %v", name, passhash.ErrInvalidHash, err)
+			}
+		}
+	})
+}
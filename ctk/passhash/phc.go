@@ -0,0 +1,127 @@
+package passhash
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+)
+
+// encoding is the unpadded base64 encoding of the salt and the hash.
+var encoding = base64.RawStdEncoding.Strict()
+
+// param is a named (decimal) parameter of a hash string.
+type param struct {
+	// name is the name of the parameter (e.g. "m").
+	name string
+
+	// value is the value of the parameter.
+	value uint32
+}
+
+// phc is a hash string in the PHC string format:
+//
+//	$<id>$v=<version>$<name>=<value>(,<name>=<value>)*$<salt>$<hash>
+type phc struct {
+	// id identifies the algorithm (e.g. "argon2id").
+	id string
+
+	// version is the version of the algorithm.
+	version int
+
+	// params are the parameters of the algorithm.
+	params []param
+
+	// salt is the salt.
+	salt []byte
+
+	// hash is the hash.
+	hash []byte
+}
+
+// String encodes the hash string.
+func (p phc) String() string {
+	var b strings.Builder
+
+	b.WriteString("$" + p.id + "$v=" + strconv.Itoa(p.version) + "$")
+	for i, param := range p.params {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(param.name + "=" + strconv.FormatUint(uint64(param.value), 10))
+	}
+	b.WriteString("$" + encoding.EncodeToString(p.salt) + "$" + encoding.EncodeToString(p.hash))
+
+	return b.String()
+}
+
+// parsePHC decodes a hash string whose parameters have the names (in the given
+// order). The parameter values need to be decimal uint32s without leading
+// zeros so that every hash has a single encoding.
+// Returns false if the hash string is malformed.
+func parsePHC(s string, names ...string) (phc, bool) {
+	fields := strings.Split(s, "$")
+	if len(fields) != 6 || fields[0] != "" {
+		return phc{}, false
+	}
+
+	version, ok := strings.CutPrefix(fields[2], "v=")
+	if !ok {
+		return phc{}, false
+	}
+
+	v, ok := parseUint32(version)
+	if !ok {
+		return phc{}, false
+	}
+
+	values := strings.Split(fields[3], ",")
+	if len(values) != len(names) {
+		return phc{}, false
+	}
+
+	params := make([]param, len(names))
+	for i, name := range names {
+		value, ok := strings.CutPrefix(values[i], name+"=")
+		if !ok {
+			return phc{}, false
+		}
+
+		params[i] = param{name: name}
+		params[i].value, ok = parseUint32(value)
+		if !ok {
+			return phc{}, false
+		}
+	}
+
+	salt, err := encoding.DecodeString(fields[4])
+	if err != nil {
+		return phc{}, false
+	}
+
+	hash, err := encoding.DecodeString(fields[5])
+	if err != nil {
+		return phc{}, false
+	}
+
+	return phc{id: fields[1], version: int(v), params: params, salt: salt, hash: hash}, true
+}
+
+// id returns the algorithm identifier of the hash string.
+func id(s string) string {
+	fields := strings.SplitN(s, "$", 3)
+	if len(fields) < 3 || fields[0] != "" {
+		return ""
+	}
+
+	return fields[1]
+}
+
+// parseUint32 parses a decimal uint32 without leading zeros.
+func parseUint32(s string) (uint32, bool) {
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil || strconv.FormatUint(n, 10) != s {
+		return 0, false
+	}
+
+	return uint32(n), true
+}
@@ -0,0 +1,9 @@
+package pkcs8
+
+// Error defines an error.
+type Error string
+
+// Error implements the error interface.
+func (e Error) Error() string {
+	return string(e)
+}
@@ -0,0 +1,73 @@
+package poly1305
+
+import (
+	"github.com/pmuens/ctk-go/ctk/internal/parallel"
+	"github.com/pmuens/ctk-go/ctk/subtle"
+)
+
+// BatchItem is a message and its tag to be verified with the one-time key.
+type BatchItem struct {
+	// Key is the one-time key of the message.
+	Key OneTimeKey
+
+	// Message is the authenticated message.
+	Message []byte
+
+	// Tag is the tag to verify.
+	Tag [16]byte
+}
+
+// BatchOption configures the verification of a batch.
+type BatchOption func(*BatchOptions)
+
+// BatchOptions are the options of a batch verification.
+type BatchOptions struct {
+	// Concurrency is the number of goroutines verifying the items (1 by default,
+	// below 1 uses one goroutine per available CPU).
+	Concurrency int
+}
+
+// WithConcurrency verifies the items of a batch with n goroutines. A value
+// below 1 uses one goroutine per available CPU (GOMAXPROCS).
+func WithConcurrency(n int) BatchOption {
+	return func(o *BatchOptions) {
+		o.Concurrency = n
+	}
+}
+
+// NewBatchOptions returns the batch options with the defaults and the options
+// applied so that other packages offering batch operations can share them.
+func NewBatchOptions(opts ...BatchOption) BatchOptions {
+	o := BatchOptions{
+		Concurrency: 1,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// VerifyBatch reports for every item whether its tag authenticates its message
+// with its one-time key. The tags are compared in constant time.
+// Every goroutine reuses a single instance (see Poly1305.Reset) for all of its
+// items so that the allocations are amortized over the batch.
+func VerifyBatch(items []BatchItem, opts ...BatchOption) []bool {
+	o := NewBatchOptions(opts...)
+	valid := make([]bool, len(items))
+
+	parallel.Range(len(items), parallel.Workers(o.Concurrency), func(start int, end int) {
+		p := NewPoly1305(items[start].Key)
+
+		for i := start; i < end; i++ {
+			p.Reset(items[i].Key)
+
+			// A reset instance can't return an error.
+			tag, _ := p.GenerateTag(items[i].Message)
+			valid[i] = subtle.ConstantTimeCompare(tag[:], items[i].Tag[:]) == 1
+		}
+	})
+
+	return valid
+}
@@ -0,0 +1,57 @@
+package poly1305_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/poly1305"
+)
+
+func TestVerifyBatch(t *testing.T) {
+	items := make([]poly1305.BatchItem, 100)
+	for i := range items {
+		key := poly1305.UnsafeOneTimeKeyFromBytes([32]byte{byte(i), 0x01, 0x02})
+		message := make([]byte, i)
+
+		items[i] = poly1305.BatchItem{
+			Key:     key,
+			Message: message,
+			Tag:     poly1305.OneTimeAuth(key, message),
+		}
+	}
+
+	// Every 7th item is invalid.
+	want := make([]bool, len(items))
+	for i := range items {
+		want[i] = i%7 != 0
+		if !want[i] {
+			items[i].Tag[0] ^= 0x01
+		}
+	}
+
+	tt := map[string][]poly1305.BatchOption{
+		"Sequential": {},
+		"Concurrent": {poly1305.WithConcurrency(4)},
+		"All CPUs":   {poly1305.WithConcurrency(0)},
+	}
+
+	for name, opts := range tt {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := poly1305.VerifyBatch(items, opts...)
+			if !slices.Equal(got, want) {
+				t.Errorf("want %v, got %v", want, got)
+			}
+		})
+	}
+
+	t.Run("Empty", func(t *testing.T) {
+		t.Parallel()
+
+		got := poly1305.VerifyBatch([]poly1305.BatchItem{})
+		if len(got) != 0 {
+			t.Errorf("want length %v, got %v", 0, len(got))
+		}
+	})
+}
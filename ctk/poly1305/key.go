@@ -0,0 +1,61 @@
+package poly1305
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pmuens/ctk-go/ctk/internal/leutil"
+)
+
+// KeySize is the size (in bytes) of a one-time key.
+const KeySize = 32
+
+// OneTimeKey is an opaque Poly1305 one-time key.
+//
+// The key bytes can't be read back which reduces the chance that a key is
+// logged or reused by accident. Formatting a key (e.g. via fmt.Println or %x)
+// prints a redacted placeholder.
+//
+// A key is usually derived from a stream cipher's keystream (see KeyFromBlock
+// and ReadOneTimeKey) rather than created from raw bytes.
+type OneTimeKey struct {
+	// key are the key bytes (r followed by s).
+	key [KeySize]byte
+}
+
+// KeyFromBlock derives the one-time key from the first 256 bit (8 words) of a
+// ChaCha20 block as specified in RFC 8439 (section 2.6).
+func KeyFromBlock(block [16]uint32) OneTimeKey {
+	var k OneTimeKey
+	leutil.PutWords(k.key[:], block[0:8])
+
+	return k
+}
+
+// ReadOneTimeKey reads the one-time key from r (e.g. the first 32 bytes of a
+// keystream).
+// Returns an error if fewer than 32 bytes could be read.
+func ReadOneTimeKey(r io.Reader) (OneTimeKey, error) {
+	var k OneTimeKey
+
+	_, err := io.ReadFull(r, k.key[:])
+	if err != nil {
+		return OneTimeKey{}, err
+	}
+
+	return k, nil
+}
+
+// UnsafeOneTimeKeyFromBytes turns the raw bytes into a one-time key.
+// It's meant for test vectors and interoperability with other implementations.
+// It's the caller's responsibility to ensure that the bytes are secret and are
+// never used to authenticate more than one message.
+func UnsafeOneTimeKeyFromBytes(key [32]byte) OneTimeKey {
+	return OneTimeKey{key: key}
+}
+
+// Format implements the fmt.Formatter interface and redacts the key for all
+// verbs.
+func (k OneTimeKey) Format(f fmt.State, verb rune) {
+	io.WriteString(f, "poly1305.OneTimeKey(REDACTED)")
+}
@@ -0,0 +1,264 @@
+package poly1305
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// limbs is the state of the block function with the accumulator h and the
+// clamped r split into 64 bit limbs (least significant limb first). The layout
+// is shared with the assembly implementation.
+type limbs struct {
+	// h is the (partially reduced) accumulator.
+	h [3]uint64
+
+	// r is the clamped r.
+	r [2]uint64
+}
+
+// p0, p1 and p2 are the limbs of the prime P = 2^130-5.
+const (
+	p0 = 0xfffffffffffffffb
+	p1 = 0xffffffffffffffff
+	p2 = 0x3
+)
+
+// updateGeneric adds the full blocks of the message (its length is a multiple
+// of BlockSize) to the accumulator.
+func updateGeneric(state *limbs, msg []byte) {
+	h0, h1, h2 := state.h[0], state.h[1], state.h[2]
+	r0, r1 := state.r[0], state.r[1]
+
+	for len(msg) >= BlockSize {
+		var c uint64
+
+		// h += block + 2^128 (the added 0x01 byte).
+		h0, c = bits.Add64(h0, binary.LittleEndian.Uint64(msg[0:8]), 0)
+		h1, c = bits.Add64(h1, binary.LittleEndian.Uint64(msg[8:16]), c)
+		h2 += c + 1
+
+		h0, h1, h2 = reduce(mul(h0, h1, h2, r0, r1))
+
+		msg = msg[BlockSize:]
+	}
+
+	state.h = [3]uint64{h0, h1, h2}
+}
+
+// multiBlocks is the number of blocks that are processed per reduction of the
+// multi-block path.
+const multiBlocks = 4
+
+// updateMultiBlocks adds the full blocks of the message (its length is a
+// multiple of BlockSize) to the accumulator and processes multiBlocks blocks
+// per reduction. The remaining blocks are processed by updateGeneric.
+//
+// Unrolling the Horner evaluation for 4 blocks m1 to m4 results in
+//
+//	h = ((h+m1)*r^4 + m2*r^3 + m3*r^2 + m4*r) % P
+//
+// so that (with the precomputed powers of r) the group needs a single modular
+// reduction instead of one per block. The products are independent of each
+// other which is what SIMD implementations exploit.
+func updateMultiBlocks(state *limbs, msg []byte) {
+	groups := len(msg) / (multiBlocks * BlockSize)
+	if groups == 0 {
+		updateGeneric(state, msg)
+		return
+	}
+
+	// powers are r^4, r^3, r^2 and r (the factors of m1 to m4). They are only
+	// partially reduced (and therefore smaller than 2*P).
+	var powers [multiBlocks][3]uint64
+	powers[multiBlocks-1] = [3]uint64{state.r[0], state.r[1], 0}
+	for i := multiBlocks - 2; i >= 0; i-- {
+		powers[i] = reduceWide(mulWide(powers[i+1], powers[multiBlocks-1]))
+	}
+
+	h := state.h
+
+	for range groups {
+		var sum [6]uint64
+
+		for i := range multiBlocks {
+			// The block with the added 0x01 byte (the 2^128 bit).
+			n := [3]uint64{
+				binary.LittleEndian.Uint64(msg[i*BlockSize : i*BlockSize+8]),
+				binary.LittleEndian.Uint64(msg[i*BlockSize+8 : i*BlockSize+16]),
+				1,
+			}
+
+			// The accumulator is added to the first block.
+			if i == 0 {
+				n = add(h, n)
+			}
+
+			sum = addWide(sum, mulWide(n, powers[i]))
+		}
+
+		h = reduceWide(sum)
+		msg = msg[multiBlocks*BlockSize:]
+	}
+
+	state.h = h
+
+	updateGeneric(state, msg)
+}
+
+// block adds the block (with hibit as the 2^128 bit) to the accumulator and
+// multiplies the accumulator by r.
+func (l *limbs) block(b [BlockSize]byte, hibit uint64) {
+	n := [3]uint64{
+		binary.LittleEndian.Uint64(b[0:8]),
+		binary.LittleEndian.Uint64(b[8:16]),
+		hibit,
+	}
+
+	h := add(l.h, n)
+	l.h[0], l.h[1], l.h[2] = reduce(mul(h[0], h[1], h[2], l.r[0], l.r[1]))
+}
+
+// add returns a + b.
+func add(a [3]uint64, b [3]uint64) [3]uint64 {
+	var c uint64
+	var sum [3]uint64
+
+	sum[0], c = bits.Add64(a[0], b[0], 0)
+	sum[1], c = bits.Add64(a[1], b[1], c)
+	sum[2] = a[2] + b[2] + c
+
+	return sum
+}
+
+// mul returns the limbs of h * r. As the limbs of the clamped r are smaller
+// than 2^60 and the highest limb of h is small, the products of the highest
+// limb fit into 64 bits.
+func mul(h0, h1, h2, r0, r1 uint64) (uint64, uint64, uint64, uint64) {
+	h0r0Hi, h0r0Lo := bits.Mul64(h0, r0)
+	h1r0Hi, h1r0Lo := bits.Mul64(h1, r0)
+	h0r1Hi, h0r1Lo := bits.Mul64(h0, r1)
+	h1r1Hi, h1r1Lo := bits.Mul64(h1, r1)
+
+	var w1, w2, c0, c1, c2, c3 uint64
+
+	w1, c0 = bits.Add64(h0r0Hi, h1r0Lo, 0)
+	w1, c1 = bits.Add64(w1, h0r1Lo, 0)
+	w2, c2 = bits.Add64(h1r0Hi, h0r1Hi, c0)
+	w2, c3 = bits.Add64(w2, h1r1Lo, c1)
+	w2, c1 = bits.Add64(w2, h2*r0, 0)
+	w3 := h1r1Hi + h2*r1 + c1 + c2 + c3
+
+	return h0r0Lo, w1, w2, w3
+}
+
+// reduce partially reduces the product w modulo P = 2^130-5 by adding 5 times
+// the bits above 2^130 (as 2^130 = 5 mod P) to the lowest 130 bits. The result
+// is smaller than 2*P.
+func reduce(w0, w1, w2, w3 uint64) (uint64, uint64, uint64) {
+	var c uint64
+
+	h0, h1, h2 := w0, w1, w2&3
+
+	// Add 4 * (w >> 130) (the bits above 2^130 in place).
+	c4lo, c4hi := w2&^3, w3
+	h0, c = bits.Add64(h0, c4lo, 0)
+	h1, c = bits.Add64(h1, c4hi, c)
+	h2 += c
+
+	// Add w >> 130.
+	h0, c = bits.Add64(h0, c4lo>>2|c4hi<<62, 0)
+	h1, c = bits.Add64(h1, c4hi>>2, c)
+	h2 += c
+
+	return h0, h1, h2
+}
+
+// mulWide returns the limbs of a * b. Other than mul it doesn't make any
+// assumptions about the size of the limbs (e.g. for the powers of r).
+func mulWide(a [3]uint64, b [3]uint64) [6]uint64 {
+	var w [6]uint64
+
+	for i := range a {
+		var carry uint64
+		for j := range b {
+			var c uint64
+
+			hi, lo := bits.Mul64(a[i], b[j])
+			lo, c = bits.Add64(lo, w[i+j], 0)
+			hi += c
+			lo, c = bits.Add64(lo, carry, 0)
+			hi += c
+
+			w[i+j] = lo
+			carry = hi
+		}
+		w[i+len(b)] = carry
+	}
+
+	return w
+}
+
+// addWide returns a + b.
+func addWide(a [6]uint64, b [6]uint64) [6]uint64 {
+	var c uint64
+	var sum [6]uint64
+
+	for i := range sum {
+		sum[i], c = bits.Add64(a[i], b[i], c)
+	}
+
+	return sum
+}
+
+// reduceWide partially reduces the sum of the products of the multi-block path
+// (which is smaller than 2^264) modulo P. The result is smaller than 2*P.
+func reduceWide(w [6]uint64) [3]uint64 {
+	// The lowest 130 bits and 4 * (w >> 130) (the bits above 2^130 in place).
+	lo := [3]uint64{w[0], w[1], w[2] & 3}
+	hi4 := [3]uint64{w[2] &^ 3, w[3], w[4]}
+	hi := [3]uint64{w[2]>>2 | w[3]<<62, w[3]>>2 | w[4]<<62, w[4] >> 2}
+
+	// As 2^130 = 5 mod P the sum is small enough for the second reduction.
+	h := add(add(lo, hi4), hi)
+
+	h[0], h[1], h[2] = reduce(h[0], h[1], h[2], 0)
+
+	return h
+}
+
+// finalize fully reduces the partially reduced accumulator (which is smaller
+// than 2*P) modulo P in constant time.
+func finalize(h [3]uint64) [3]uint64 {
+	var b uint64
+	var t [3]uint64
+
+	t[0], b = bits.Sub64(h[0], p0, 0)
+	t[1], b = bits.Sub64(h[1], p1, b)
+	t[2], b = bits.Sub64(h[2], p2, b)
+
+	// mask is all ones if h >= P (no borrow) and zero otherwise.
+	mask := b - 1
+	for i := range h {
+		h[i] = t[i]&mask | h[i]&^mask
+	}
+
+	return h
+}
+
+// value formats a number given as limbs (least significant limb first) as a
+// hex number without leading zeros (e.g. for the trace).
+type value []uint64
+
+// Format implements the fmt.Formatter interface.
+func (v value) Format(f fmt.State, verb rune) {
+	i := len(v) - 1
+	for i > 0 && v[i] == 0 {
+		i--
+	}
+
+	fmt.Fprintf(f, "%x", v[i])
+	for i--; i >= 0; i-- {
+		fmt.Fprintf(f, "%016x", v[i])
+	}
+}
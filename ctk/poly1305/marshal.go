@@ -0,0 +1,107 @@
+package poly1305
+
+import (
+	"encoding/binary"
+	"slices"
+
+	"github.com/pmuens/ctk-go/ctk/internal/checkpoint"
+)
+
+const (
+	// ErrInvalidState is returned if an encoded state is malformed or was
+	// modified.
+	ErrInvalidState = Error("invalid poly1305 state")
+
+	// ErrPartialBlock is returned if a state with a buffered partial block
+	// should be encoded.
+	ErrPartialBlock = Error("poly1305 state has a partial block")
+)
+
+// stateLabel identifies encoded Poly1305 states.
+const stateLabel = "ctk-go poly1305"
+
+// stateSize is the size (in bytes) of an encoded state without the checkpoint
+// overhead.
+const stateSize = 16 + 16 + 17 + 1
+
+// MarshalCheckpoint encodes the instance's (clamped) r, s, accumulator and
+// whether a tag was generated so that it can be checkpointed and restored via
+// UnmarshalCheckpoint:
+//
+//	r (16, big endian) | s (16, big endian) | accumulator (17, big endian) | used (1)
+//
+// The state is versioned and authenticated with a MAC whose key is derived
+// from the checkpoint key so that modifications (e.g. resetting the used flag)
+// are rejected. The checkpoint key needs to be kept apart from the encoding as
+// anyone who knows it can forge states.
+//
+// Warning: The encoding contains the one-time key and needs to be stored as
+// confidentially as the key itself. Restoring the same state more than once
+// allows to authenticate more than one message with the key.
+// Returns an error if the data that was added via Update doesn't end on a block
+// boundary.
+func (p *Poly1305) MarshalCheckpoint(checkpointKey [32]byte) ([]byte, error) {
+	if p.n > 0 {
+		return []byte{}, ErrPartialBlock
+	}
+
+	state := make([]byte, stateSize)
+
+	binary.BigEndian.PutUint64(state[0:8], p.state.r[1])
+	binary.BigEndian.PutUint64(state[8:16], p.state.r[0])
+	binary.BigEndian.PutUint64(state[16:24], p.s[1])
+	binary.BigEndian.PutUint64(state[24:32], p.s[0])
+
+	// The accumulator is only reduced partially while processing the blocks.
+	h := finalize(p.state.h)
+	state[32] = byte(h[2])
+	binary.BigEndian.PutUint64(state[33:41], h[1])
+	binary.BigEndian.PutUint64(state[41:49], h[0])
+	if p.used {
+		state[49] = 1
+	}
+
+	return checkpoint.Encode(stateLabel, state, checkpoint.Key(stateLabel, checkpointKey)), nil
+}
+
+// UnmarshalCheckpoint restores a state that was encoded via MarshalCheckpoint
+// with the checkpoint key.
+// The options that aren't part of the state (e.g. the trace) are kept.
+// Returns an error if the encoding is malformed, was modified or belongs to
+// another checkpoint key.
+func (p *Poly1305) UnmarshalCheckpoint(checkpointKey [32]byte, data []byte) error {
+	state, ok := checkpoint.Decode(stateLabel, data, stateSize)
+	if !ok || len(state) != stateSize || !checkpoint.Verify(data, checkpoint.Key(stateLabel, checkpointKey)) {
+		return ErrInvalidState
+	}
+
+	// r needs to be clamped (in little endian order).
+	r := [16]byte(state[0:16])
+	slices.Reverse(r[:])
+	if clamp(r) != r {
+		return ErrInvalidState
+	}
+
+	// The accumulator needs to be smaller than P (i.e. unchanged by a
+	// reduction).
+	accum := [3]uint64{
+		binary.BigEndian.Uint64(state[41:49]),
+		binary.BigEndian.Uint64(state[33:41]),
+		uint64(state[32]),
+	}
+	if state[32] > p2 || finalize(accum) != accum || state[49] > 1 {
+		return ErrInvalidState
+	}
+
+	p.state.r[1] = binary.BigEndian.Uint64(state[0:8])
+	p.state.r[0] = binary.BigEndian.Uint64(state[8:16])
+	p.s[1] = binary.BigEndian.Uint64(state[16:24])
+	p.s[0] = binary.BigEndian.Uint64(state[24:32])
+	p.state.h = accum
+	p.used = state[49] == 1
+	clear(p.buf[:])
+	p.n = 0
+	p.blocks = -1
+
+	return nil
+}
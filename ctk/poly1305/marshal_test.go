@@ -0,0 +1,144 @@
+package poly1305_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/internal/checkpoint"
+	"github.com/pmuens/ctk-go/ctk/poly1305"
+)
+
+func TestPoly1305MarshalCheckpoint(t *testing.T) {
+	key := poly1305.UnsafeOneTimeKeyFromBytes([32]byte{
+		0x85, 0xd6, 0xbe, 0x78, 0x57, 0x55, 0x6d, 0x33,
+		0x7f, 0x44, 0x52, 0xfe, 0x42, 0xd5, 0x06, 0xa8,
+		0x01, 0x03, 0x80, 0x8a, 0xfb, 0x0d, 0xb2, 0xfd,
+		0x4a, 0xbf, 0xf6, 0xaf, 0x41, 0x49, 0xf5, 0x1b,
+	})
+
+	data := []byte("Cryptographic Forum Research Group")
+	checkpointKey := [32]byte{0x01}
+
+	t.Run("Restore", func(t *testing.T) {
+		t.Parallel()
+
+		state, err := poly1305.NewPoly1305(key).MarshalCheckpoint(checkpointKey)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		var restored poly1305.Poly1305
+
+		err = restored.UnmarshalCheckpoint(checkpointKey, state)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		got, err := restored.GenerateTag(data)
+		want := [16]byte{
+			0xa8, 0x06, 0x1d, 0xc1, 0x30, 0x51, 0x36, 0xc6,
+			0xc2, 0x2b, 0x8b, 0xaf, 0x0c, 0x01, 0x27, 0xa9,
+		}
+
+		if got != want || err != nil {
+			t.Errorf("want %v, got %v (error %v)", want, got, err)
+		}
+	})
+
+	t.Run("Used", func(t *testing.T) {
+		t.Parallel()
+
+		p := poly1305.NewPoly1305(key)
+		p.GenerateTag(data)
+
+		state, _ := p.MarshalCheckpoint(checkpointKey)
+
+		restored := poly1305.NewPoly1305(key)
+		restored.UnmarshalCheckpoint(checkpointKey, state)
+
+		_, err := restored.GenerateTag(data)
+		if !errors.Is(err, poly1305.ErrKeyReused) {
+			t.Errorf("want error %v, got %v", poly1305.ErrKeyReused, err)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		t.Parallel()
+
+		p := poly1305.NewPoly1305(key)
+		p.Update(data[:16])
+
+		state, err := p.MarshalCheckpoint(checkpointKey)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		var restored poly1305.Poly1305
+		restored.UnmarshalCheckpoint(checkpointKey, state)
+
+		got, _ := restored.GenerateTag(data[16:])
+		want := poly1305.OneTimeAuth(key, data)
+
+		if got != want {
+			t.Errorf("want %v, got %v", want, got)
+		}
+
+		p.Update(data[16:20])
+
+		_, err = p.MarshalCheckpoint(checkpointKey)
+		if !errors.Is(err, poly1305.ErrPartialBlock) {
+			t.Errorf("want error %v, got %v", poly1305.ErrPartialBlock, err)
+		}
+	})
+
+	t.Run("Invalid State", func(t *testing.T) {
+		t.Parallel()
+
+		p := poly1305.NewPoly1305(key)
+		p.GenerateTag(data)
+
+		state, _ := p.MarshalCheckpoint(checkpointKey)
+
+		tt := map[string]func(state []byte) []byte{
+			"Label":     func(state []byte) []byte { state[0] ^= 0x01; return state },
+			"Version":   func(state []byte) []byte { state[len("ctk-go poly1305")]++; return state },
+			"Used":      func(state []byte) []byte { state[len(state)-33] = 0; return state },
+			"Tag":       func(state []byte) []byte { state[len(state)-1] ^= 0x01; return state },
+			"Truncated": func(state []byte) []byte { return state[:len(state)-1] },
+		}
+
+		for name, tamper := range tt {
+			err := poly1305.NewPoly1305(key).UnmarshalCheckpoint(checkpointKey, tamper(slices.Clone(state)))
+			if !errors.Is(err, poly1305.ErrInvalidState) {
+				t.Errorf("%v: want error %v, got %v", name, poly1305.ErrInvalidState, err)
+			}
+		}
+	})
+
+	t.Run("Forged State", func(t *testing.T) {
+		t.Parallel()
+
+		p := poly1305.NewPoly1305(key)
+		p.GenerateTag(data)
+		encoded, _ := p.MarshalCheckpoint(checkpointKey)
+
+		// The used flag is reset and the state is tagged with the one-time key
+		// the encoding reveals.
+		state, _ := checkpoint.Decode("ctk-go poly1305", encoded, 0)
+		state = slices.Clone(state)
+		state[len(state)-1] = 0
+
+		forged := checkpoint.Encode("ctk-go poly1305", state, state[0:32])
+
+		err := poly1305.NewPoly1305(key).UnmarshalCheckpoint(checkpointKey, forged)
+		if !errors.Is(err, poly1305.ErrInvalidState) {
+			t.Errorf("want error %v, got %v", poly1305.ErrInvalidState, err)
+		}
+
+		err = poly1305.NewPoly1305(key).UnmarshalCheckpoint([32]byte{0x02}, encoded)
+		if !errors.Is(err, poly1305.ErrInvalidState) {
+			t.Errorf("want error %v, got %v", poly1305.ErrInvalidState, err)
+		}
+	})
+}
@@ -1,128 +1,299 @@
 // Package poly1305 implements the Poly1305 one-time authenticator as specified
 // in https://datatracker.ietf.org/doc/html/rfc8439.
+//
+// The numbers are represented by 64 bit limbs (rather than math/big) so that
+// the package also runs on targets with limited resources (e.g. TinyGo). On
+// amd64 and arm64 the blocks are processed by an assembly implementation (on
+// amd64 the variant is chosen at runtime based on the CPU features, see
+// Backend). The purego build tag disables it in favor of the portable
+// implementation which serves as the reference the assembly implementation is
+// tested against.
 package poly1305
 
 import (
-	"math"
-	"math/big"
-	"slices"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/pmuens/ctk-go/ctk/internal/trace"
+	"github.com/pmuens/ctk-go/ctk/subtle"
+)
+
+const (
+	// ErrKeyReused is returned if an instance is used to authenticate more than
+	// one message.
+	ErrKeyReused = Error("poly1305 key already used")
 )
 
 // BlockSize is the size (in bytes) of the input to be processed at a time.
 const BlockSize = 16
 
-// P is the prime 2^130-5.
-var P, _ = new(big.Int).SetString("3fffffffffffffffffffffffffffffffb", 16)
+// Option configures a Poly1305 instance.
+type Option func(*Poly1305)
+
+// WithTrace writes the intermediate values (the clamped r, s, and the
+// accumulator before and after processing every block) to w. The values are
+// formatted like the worked example of RFC 8439 (section 2.5.2) so that learners
+// can follow the computations.
+// Tracing slows the computation down and is meant for education only.
+func WithTrace(w io.Writer) Option {
+	return func(p *Poly1305) {
+		p.trace = w
+	}
+}
 
 // Poly1305 is a stateful instance of the Poly1305 one-time authenticator.
+// As a key must never be used to authenticate more than one message, an
+// instance can only generate a single tag.
+// An instance isn't safe for concurrent use.
 type Poly1305 struct {
-	// accum is the accumulator which is used to compute the tag.
-	accum *big.Int
+	// state holds the accumulator and the key's first 16 bytes which were
+	// clamped (r).
+	state limbs
+
+	// s are the key's last 16 bytes.
+	s [2]uint64
+
+	// used indicates whether a tag was already generated.
+	used bool
+
+	// buf holds the trailing partial block (the first n bytes) of the data that
+	// was added via Update.
+	buf [BlockSize]byte
 
-	// r are the key's first 16 bytes which were clamped and turned into a big int.
-	r *big.Int
+	// n is the number of bytes in buf.
+	n int
 
-	// s are the key's last 16 bytes turned into a big int.
-	s *big.Int
+	// blocks is the number of blocks that were traced (-1 if the key wasn't
+	// traced yet).
+	blocks int
+
+	// trace receives the intermediate values (if set).
+	trace io.Writer
 }
 
 // NewPoly1305 creates a new instance of the Poly1305 MAC.
-func NewPoly1305(key [32]byte) *Poly1305 {
-	// Extract r from the key by taking its first 16 bytes.
-	var r [16]byte
-	copy(r[:], key[0:16])
-
-	// Clamp r.
-	r = clamp(r)
-
-	// Turn r into a big endian byte slice so that it can be used in a big integer
-	// conversion.
-	rSlice := r[:]
-	slices.Reverse(rSlice)
-	rBigInt := new(big.Int).SetBytes(rSlice)
-
-	// Extract s form the key by taking its last 16 bytes.
-	var s [16]byte
-	copy(s[:], key[16:32])
-
-	// Turn s into a big endian byte slice so that it can be used in a big integer
-	// conversion.
-	sSlice := s[:]
-	slices.Reverse(sSlice)
-	sBigInt := new(big.Int).SetBytes(sSlice)
+func NewPoly1305(key OneTimeKey, opts ...Option) *Poly1305 {
+	p := &Poly1305{}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	p.Reset(key)
+
+	return p
+}
+
+// Reset reinitializes the instance with the (new one-time) key so that it can
+// be reused without allocating a new one.
+// The options (e.g. the trace) are kept.
+func (p *Poly1305) Reset(key OneTimeKey) {
+	// Extract r from the key by taking its first 16 bytes and clamp it.
+	r := clamp([16]byte(key.key[0:16]))
+	p.state.r[0] = binary.LittleEndian.Uint64(r[0:8])
+	p.state.r[1] = binary.LittleEndian.Uint64(r[8:16])
+
+	// Extract s from the key by taking its last 16 bytes.
+	p.s[0] = binary.LittleEndian.Uint64(key.key[16:24])
+	p.s[1] = binary.LittleEndian.Uint64(key.key[24:32])
 
 	// Set the accumulator to zero.
-	accum := big.NewInt(0)
+	p.state.h = [3]uint64{}
 
-	return &Poly1305{
-		r:     rBigInt,
-		s:     sBigInt,
-		accum: accum,
-	}
+	p.used = false
+	clear(p.buf[:])
+	p.n = 0
+	p.blocks = -1
+}
+
+// Clone returns a deep copy of the instance which can be used independently of
+// the original (e.g. to branch a computation).
+// Note that the clone shares the key with the original so that only one of them
+// should be used to generate a tag.
+func (p *Poly1305) Clone() *Poly1305 {
+	c := *p
+
+	return &c
+}
+
+// OneTimeAuth creates the tag to authenticate the message with the one-time key.
+func OneTimeAuth(key OneTimeKey, message []byte) [16]byte {
+	// A fresh instance can't return an error.
+	tag, _ := NewPoly1305(key).GenerateTag(message)
+
+	return tag
+}
+
+// Verify reports whether the tag authenticates the message with the one-time
+// key. The tags are compared in constant time.
+func Verify(key OneTimeKey, message []byte, tag [16]byte) bool {
+	computed := OneTimeAuth(key, message)
+
+	return subtle.ConstantTimeCompare(tag[:], computed[:]) == 1
 }
 
 // GenerateTag creates the tag to authenticate the data.
-func (p *Poly1305) GenerateTag(data []byte) [16]byte {
-	numBlocks := int(math.Ceil(float64(len(data)) / BlockSize))
-
-	for i := range numBlocks {
-		// A block is a BlockSize bytes (or less) block from the input data.
-		// Default to slice from the last sliced block to the end (to handle blocks
-		// that have fewer than BlockSize bytes).
-		block := data[(i * BlockSize):]
-		// Check if an exact BlockSize byte block can be slices and slice it, if so.
-		if (i+1)*BlockSize < len(data) {
-			block = data[(i * BlockSize):((i + 1) * BlockSize)]
+// Returns an error if the instance was already used to generate a tag.
+func (p *Poly1305) GenerateTag(data []byte) ([16]byte, error) {
+	if p.used {
+		return [16]byte{}, ErrKeyReused
+	}
+	p.used = true
+
+	p.add(data)
+
+	if p.trace != nil {
+		p.traceKey()
+	}
+
+	if p.n > 0 {
+		if p.trace != nil {
+			p.processTracedBlocks(p.buf[:p.n])
+		} else {
+			// The last partial block is padded with the 0x01 byte and zeros (and
+			// therefore doesn't get the 2^128 bit).
+			var last [BlockSize]byte
+			copy(last[:], p.buf[:p.n])
+			last[p.n] = 0x01
+
+			p.state.block(last, 0)
+		}
+		clear(p.buf[:])
+		p.n = 0
+	}
+
+	// The accumulator is only reduced partially while processing the blocks.
+	p.state.h = finalize(p.state.h)
+
+	// Add s to the accumulator. The tag are the lowest 128 bits of the sum.
+	sum := add(p.state.h, [3]uint64{p.s[0], p.s[1], 0})
+
+	if p.trace != nil {
+		// The limbs are passed one by one so that sum doesn't escape to the heap
+		// if the instance isn't traced.
+		traceValue(p.trace, "Acc + s", sum[0], sum[1], sum[2])
+	}
+
+	var tag [16]byte
+	binary.LittleEndian.PutUint64(tag[0:8], sum[0])
+	binary.LittleEndian.PutUint64(tag[8:16], sum[1])
+
+	if p.trace != nil {
+		trace.Bytes(p.trace, "Tag", tag[:])
+	}
+
+	return tag, nil
+}
+
+// Update adds the data to the message so that messages which are split across
+// buffers don't need to be concatenated first: the tag GenerateTag creates is
+// the one of the concatenation of the data of all calls to Update followed by
+// GenerateTag's data. The data isn't retained.
+// Has no effect once the tag was generated.
+func (p *Poly1305) Update(data []byte) {
+	if p.used {
+		return
+	}
+
+	p.add(data)
+}
+
+// add adds the full blocks of the data (including the buffered bytes) to the
+// accumulator and buffers the remaining bytes.
+func (p *Poly1305) add(data []byte) {
+	if p.n > 0 {
+		k := copy(p.buf[p.n:], data)
+		p.n += k
+		data = data[k:]
+
+		if p.n < BlockSize {
+			return
 		}
 
-		// Create a copy of the block to ensure that we're not mutating the
-		// original data directly.
-		blockCopy := slices.Clone(block)
+		p.process(p.buf[:])
+		p.n = 0
+	}
+
+	full := len(data) - len(data)%BlockSize
+	p.process(data[:full])
+	p.n = copy(p.buf[:], data[full:])
+}
+
+// process adds the full blocks of the data to the accumulator.
+func (p *Poly1305) process(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+
+	if p.trace != nil {
+		p.processTracedBlocks(data)
+		return
+	}
+
+	update(&p.state, data)
+}
+
+// processTracedBlocks adds the blocks of the data to the accumulator one at a
+// time and traces the intermediate values (with the accumulator reduced
+// modulo P after every block as in the specification).
+func (p *Poly1305) processTracedBlocks(data []byte) {
+	p.traceKey()
+
+	// The blocks are sliced by hand as the data would escape to the heap (for
+	// untraced calls as well) if it was captured by an iterator.
+	for len(data) > 0 {
+		block := data[:min(len(data), BlockSize)]
+		data = data[len(block):]
+		p.blocks++
 
 		// Add one bit to the end of the block.
-		blockCopy = append(blockCopy, 0x01)
+		var padded [BlockSize + 1]byte
+		copy(padded[:], block)
+		padded[len(block)] = 0x01
 
-		// Reverse the block to turn it into a big endian version so that it can be
-		// used in a big integer conversion.
-		slices.Reverse(blockCopy)
-		n := new(big.Int).SetBytes(blockCopy)
+		n := [3]uint64{
+			binary.LittleEndian.Uint64(padded[0:8]),
+			binary.LittleEndian.Uint64(padded[8:16]),
+			uint64(padded[16]),
+		}
 
 		// Add the current, modified block interpreted as a number to the accumulator.
-		accum := new(big.Int).Add(p.accum, n)
+		sum := add(p.state.h, n)
 		// Multiply the accumulator by r.
-		accum = new(big.Int).Mul(accum, p.r)
+		var product [4]uint64
+		product[0], product[1], product[2], product[3] = mul(sum[0], sum[1], sum[2], p.state.r[0], p.state.r[1])
 		// Reduce the accumulator modulo P.
-		accum = new(big.Int).Mod(accum, P)
+		var accum [3]uint64
+		accum[0], accum[1], accum[2] = reduce(product[0], product[1], product[2], product[3])
+		accum = finalize(accum)
+
+		fmt.Fprintf(p.trace, "Block #%d\n", p.blocks)
+		trace.Value(p.trace, "Acc", value(p.state.h[:]))
+		trace.Value(p.trace, "Block with 0x01 byte", value(n[:]))
+		trace.Value(p.trace, "Acc + block", value(sum[:]))
+		trace.Value(p.trace, "(Acc+Block) * r", value(product[:]))
+		trace.Value(p.trace, "Acc = ((Acc+Block)*r) % P", value(accum[:]))
 
 		// Save the updated accumulator.
-		p.accum = accum
+		p.state.h = accum
 	}
+}
 
-	// Add s to the accumulator and access the underlying bytes (in big endian order).
-	result := new(big.Int).Add(p.accum, p.s).Bytes()
-
-	// If there are fewer than 16 bytes we need to add zero padding for the missing
-	// bytes.
-	if len(result) < 16 {
-		toPad := 16 - len(result)
-		for range toPad {
-			// Prepend 0x00 as the padding.
-			// See: https://stackoverflow.com/a/53737602
-			result = append([]byte{0x00}, result...)
-		}
+// traceKey traces the key once before the first block.
+func (p *Poly1305) traceKey() {
+	if p.blocks >= 0 {
+		return
 	}
+	p.blocks = 0
 
-	// Access the last 16 bytes.
-	bytes := result[len(result)-16:]
-
-	// Reverse slice to turn the big endian order into little endian order.
-	slices.Reverse(bytes)
-
-	// Create tag which is an array of the 16 bytes.
-	var tag [16]byte
-	copy(tag[:], bytes)
+	trace.Value(p.trace, "Clamped r", value(p.state.r[:]))
+	trace.Value(p.trace, "s", value(p.s[:]))
+}
 
-	return tag
+// traceValue traces the number given as limbs (least significant limb first).
+func traceValue(w io.Writer, name string, limbs ...uint64) {
+	trace.Value(w, name, value(limbs))
 }
 
 // clamp clamps the r value according to the specification.
@@ -0,0 +1,160 @@
+package poly1305
+
+import (
+	"math/big"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+// referenceTag computes the tag with math/big as described in the
+// specification (RFC 8439 - 2.5.1).
+func referenceTag(key [32]byte, data []byte) [16]byte {
+	p, _ := new(big.Int).SetString("3fffffffffffffffffffffffffffffffb", 16)
+
+	le := func(b []byte) *big.Int {
+		b = slices.Clone(b)
+		slices.Reverse(b)
+
+		return new(big.Int).SetBytes(b)
+	}
+
+	r := clamp([16]byte(key[0:16]))
+	rn := le(r[:])
+	s := le(key[16:32])
+
+	accum := new(big.Int)
+	for len(data) > 0 {
+		n := min(BlockSize, len(data))
+		block := le(append(slices.Clone(data[:n]), 0x01))
+
+		accum.Add(accum, block)
+		accum.Mul(accum, rn)
+		accum.Mod(accum, p)
+
+		data = data[n:]
+	}
+	accum.Add(accum, s)
+
+	var buf [32]byte
+	accum.FillBytes(buf[:])
+	slices.Reverse(buf[:])
+
+	return [16]byte(buf[0:16])
+}
+
+func TestPoly1305Limbs(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	keys := map[string][32]byte{
+		"Zero": {},
+		"Max":  [32]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}
+	for i := range 4 {
+		var key [32]byte
+		for j := range key {
+			key[j] = byte(rng.Uint32())
+		}
+		keys[string(rune('A'+i))] = key
+	}
+
+	for name, key := range keys {
+		for length := range 20*BlockSize + 3 {
+			data := make([]byte, length)
+			for i := range data {
+				data[i] = byte(rng.Uint32())
+			}
+
+			// The maximum block value stresses the (delayed) reduction.
+			if name == "Max" {
+				for i := range data {
+					data[i] = 0xff
+				}
+			}
+
+			want := referenceTag(key, data)
+
+			// The fast path is the assembly implementation (if available).
+			got := OneTimeAuth(UnsafeOneTimeKeyFromBytes(key), data)
+			if got != want {
+				t.Errorf("%v %v bytes: want %x, got %x", name, length, want, got)
+			}
+
+			full := length - length%BlockSize
+
+			generic := NewPoly1305(UnsafeOneTimeKeyFromBytes(key))
+			updateGeneric(&generic.state, data[:full])
+
+			fast := NewPoly1305(UnsafeOneTimeKeyFromBytes(key))
+			update(&fast.state, data[:full])
+
+			if finalize(fast.state.h) != finalize(generic.state.h) {
+				t.Errorf("%v %v bytes: want %x, got %x", name, length, generic.state.h, fast.state.h)
+			}
+		}
+	}
+}
+
+func TestPoly1305MultiBlocks(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+
+	keys := map[string][32]byte{
+		"Zero": {},
+		"Max":  [32]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}
+	for i := range 4 {
+		var key [32]byte
+		for j := range key {
+			key[j] = byte(rng.Uint32())
+		}
+		keys[string(rune('A'+i))] = key
+	}
+
+	for name, key := range keys {
+		for blocks := range 4*multiBlocks + 3 {
+			data := make([]byte, blocks*BlockSize)
+			for i := range data {
+				data[i] = byte(rng.Uint32())
+			}
+
+			// The maximum block value stresses the (delayed) reduction.
+			if name == "Max" {
+				for i := range data {
+					data[i] = 0xff
+				}
+			}
+
+			reference := NewPoly1305(UnsafeOneTimeKeyFromBytes(key))
+			updateGeneric(&reference.state, data)
+
+			multi := NewPoly1305(UnsafeOneTimeKeyFromBytes(key))
+			updateMultiBlocks(&multi.state, data)
+
+			if finalize(multi.state.h) != finalize(reference.state.h) {
+				t.Errorf("%v %v blocks: want %x, got %x", name, blocks, reference.state.h, multi.state.h)
+			}
+		}
+	}
+}
+
+func TestPoly1305Finalize(t *testing.T) {
+	tt := map[string]struct {
+		h    [3]uint64
+		want [3]uint64
+	}{
+		"Zero":      {h: [3]uint64{0, 0, 0}, want: [3]uint64{0, 0, 0}},
+		"P - 1":     {h: [3]uint64{p0 - 1, p1, p2}, want: [3]uint64{p0 - 1, p1, p2}},
+		"P":         {h: [3]uint64{p0, p1, p2}, want: [3]uint64{0, 0, 0}},
+		"P + 1":     {h: [3]uint64{p0 + 1, p1, p2}, want: [3]uint64{1, 0, 0}},
+		"2^130":     {h: [3]uint64{0, 0, 4}, want: [3]uint64{5, 0, 0}},
+		"2 * P - 1": {h: [3]uint64{p0 - 6, p1, 7}, want: [3]uint64{p0 - 1, p1, p2}},
+	}
+
+	for name, tc := range tt {
+		got := finalize(tc.h)
+
+		if got != tc.want {
+			t.Errorf("%v: want %x, got %x", name, tc.want, got)
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package poly1305_test
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
 	"testing"
 
 	"github.com/pmuens/ctk-go/ctk/poly1305"
@@ -10,12 +15,12 @@ func TestPoly1305GenerateTag(t *testing.T) {
 	t.Run("RFC 8439 - Test Vectors - 2.5.2", func(t *testing.T) {
 		t.Parallel()
 
-		key := [32]byte{
+		key := poly1305.UnsafeOneTimeKeyFromBytes([32]byte{
 			0x85, 0xd6, 0xbe, 0x78, 0x57, 0x55, 0x6d, 0x33,
 			0x7f, 0x44, 0x52, 0xfe, 0x42, 0xd5, 0x06, 0xa8,
 			0x01, 0x03, 0x80, 0x8a, 0xfb, 0x0d, 0xb2, 0xfd,
 			0x4a, 0xbf, 0xf6, 0xaf, 0x41, 0x49, 0xf5, 0x1b,
-		}
+		})
 
 		data := []byte{
 			0x43, 0x72, 0x79, 0x70, 0x74, 0x6f, 0x67, 0x72, 0x61, 0x70, 0x68, 0x69, 0x63, 0x20, 0x46, 0x6f,
@@ -24,7 +29,7 @@ func TestPoly1305GenerateTag(t *testing.T) {
 		}
 
 		poly := poly1305.NewPoly1305(key)
-		tag := poly.GenerateTag(data)
+		tag, _ := poly.GenerateTag(data)
 
 		got := tag
 		want := [16]byte{
@@ -40,12 +45,12 @@ func TestPoly1305GenerateTag(t *testing.T) {
 	t.Run("RFC 8439 - Test Vectors - A.3 - #1", func(t *testing.T) {
 		t.Parallel()
 
-		key := [32]byte{
+		key := poly1305.UnsafeOneTimeKeyFromBytes([32]byte{
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		}
+		})
 
 		data := []byte{
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
@@ -55,7 +60,7 @@ func TestPoly1305GenerateTag(t *testing.T) {
 		}
 
 		poly := poly1305.NewPoly1305(key)
-		tag := poly.GenerateTag(data)
+		tag, _ := poly.GenerateTag(data)
 
 		got := tag
 		want := [16]byte{
@@ -71,12 +76,12 @@ func TestPoly1305GenerateTag(t *testing.T) {
 	t.Run("RFC 8439 - Test Vectors - A.3 - #2", func(t *testing.T) {
 		t.Parallel()
 
-		key := [32]byte{
+		key := poly1305.UnsafeOneTimeKeyFromBytes([32]byte{
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0x36, 0xe5, 0xf6, 0xb5, 0xc5, 0xe0, 0x60, 0x70,
 			0xf0, 0xef, 0xca, 0x96, 0x22, 0x7a, 0x86, 0x3e,
-		}
+		})
 
 		data := []byte{
 			0x41, 0x6e, 0x79, 0x20, 0x73, 0x75, 0x62, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x20, 0x74,
@@ -106,7 +111,7 @@ func TestPoly1305GenerateTag(t *testing.T) {
 		}
 
 		poly := poly1305.NewPoly1305(key)
-		tag := poly.GenerateTag(data)
+		tag, _ := poly.GenerateTag(data)
 
 		got := tag
 		want := [16]byte{
@@ -122,12 +127,12 @@ func TestPoly1305GenerateTag(t *testing.T) {
 	t.Run("RFC 8439 - Test Vectors - A.3 - #3", func(t *testing.T) {
 		t.Parallel()
 
-		key := [32]byte{
+		key := poly1305.UnsafeOneTimeKeyFromBytes([32]byte{
 			0x36, 0xe5, 0xf6, 0xb5, 0xc5, 0xe0, 0x60, 0x70,
 			0xf0, 0xef, 0xca, 0x96, 0x22, 0x7a, 0x86, 0x3e,
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		}
+		})
 
 		data := []byte{
 			0x41, 0x6e, 0x79, 0x20, 0x73, 0x75, 0x62, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x20, 0x74,
@@ -157,7 +162,7 @@ func TestPoly1305GenerateTag(t *testing.T) {
 		}
 
 		poly := poly1305.NewPoly1305(key)
-		tag := poly.GenerateTag(data)
+		tag, _ := poly.GenerateTag(data)
 
 		got := tag
 		want := [16]byte{
@@ -173,12 +178,12 @@ func TestPoly1305GenerateTag(t *testing.T) {
 	t.Run("RFC 8439 - Test Vectors - A.3 - #4", func(t *testing.T) {
 		t.Parallel()
 
-		key := [32]byte{
+		key := poly1305.UnsafeOneTimeKeyFromBytes([32]byte{
 			0x1c, 0x92, 0x40, 0xa5, 0xeb, 0x55, 0xd3, 0x8a,
 			0xf3, 0x33, 0x88, 0x86, 0x04, 0xf6, 0xb5, 0xf0,
 			0x47, 0x39, 0x17, 0xc1, 0x40, 0x2b, 0x80, 0x09,
 			0x9d, 0xca, 0x5c, 0xbc, 0x20, 0x70, 0x75, 0xc0,
-		}
+		})
 
 		data := []byte{
 			0x27, 0x54, 0x77, 0x61, 0x73, 0x20, 0x62, 0x72, 0x69, 0x6c, 0x6c, 0x69, 0x67, 0x2c, 0x20, 0x61,
@@ -192,7 +197,7 @@ func TestPoly1305GenerateTag(t *testing.T) {
 		}
 
 		poly := poly1305.NewPoly1305(key)
-		tag := poly.GenerateTag(data)
+		tag, _ := poly.GenerateTag(data)
 
 		got := tag
 		want := [16]byte{
@@ -208,19 +213,19 @@ func TestPoly1305GenerateTag(t *testing.T) {
 	t.Run("RFC 8439 - Test Vectors - A.3 - #5", func(t *testing.T) {
 		t.Parallel()
 
-		key := [32]byte{
+		key := poly1305.UnsafeOneTimeKeyFromBytes([32]byte{
 			0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		}
+		})
 
 		data := []byte{
 			0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
 		}
 
 		poly := poly1305.NewPoly1305(key)
-		tag := poly.GenerateTag(data)
+		tag, _ := poly.GenerateTag(data)
 
 		got := tag
 		want := [16]byte{
@@ -236,19 +241,19 @@ func TestPoly1305GenerateTag(t *testing.T) {
 	t.Run("RFC 8439 - Test Vectors - A.3 - #6", func(t *testing.T) {
 		t.Parallel()
 
-		key := [32]byte{
+		key := poly1305.UnsafeOneTimeKeyFromBytes([32]byte{
 			0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
 			0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
-		}
+		})
 
 		data := []byte{
 			0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 		}
 
 		poly := poly1305.NewPoly1305(key)
-		tag := poly.GenerateTag(data)
+		tag, _ := poly.GenerateTag(data)
 
 		got := tag
 		want := [16]byte{
@@ -264,12 +269,12 @@ func TestPoly1305GenerateTag(t *testing.T) {
 	t.Run("RFC 8439 - Test Vectors - A.3 - #7", func(t *testing.T) {
 		t.Parallel()
 
-		key := [32]byte{
+		key := poly1305.UnsafeOneTimeKeyFromBytes([32]byte{
 			0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		}
+		})
 
 		data := []byte{
 			0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
@@ -278,7 +283,7 @@ func TestPoly1305GenerateTag(t *testing.T) {
 		}
 
 		poly := poly1305.NewPoly1305(key)
-		tag := poly.GenerateTag(data)
+		tag, _ := poly.GenerateTag(data)
 
 		got := tag
 		want := [16]byte{
@@ -294,12 +299,12 @@ func TestPoly1305GenerateTag(t *testing.T) {
 	t.Run("RFC 8439 - Test Vectors - A.3 - #8", func(t *testing.T) {
 		t.Parallel()
 
-		key := [32]byte{
+		key := poly1305.UnsafeOneTimeKeyFromBytes([32]byte{
 			0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		}
+		})
 
 		data := []byte{
 			0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
@@ -308,7 +313,7 @@ func TestPoly1305GenerateTag(t *testing.T) {
 		}
 
 		poly := poly1305.NewPoly1305(key)
-		tag := poly.GenerateTag(data)
+		tag, _ := poly.GenerateTag(data)
 
 		got := tag
 		want := [16]byte{
@@ -324,19 +329,19 @@ func TestPoly1305GenerateTag(t *testing.T) {
 	t.Run("RFC 8439 - Test Vectors - A.3 - #9", func(t *testing.T) {
 		t.Parallel()
 
-		key := [32]byte{
+		key := poly1305.UnsafeOneTimeKeyFromBytes([32]byte{
 			0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		}
+		})
 
 		data := []byte{
 			0xFD, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
 		}
 
 		poly := poly1305.NewPoly1305(key)
-		tag := poly.GenerateTag(data)
+		tag, _ := poly.GenerateTag(data)
 
 		got := tag
 		want := [16]byte{
@@ -352,12 +357,12 @@ func TestPoly1305GenerateTag(t *testing.T) {
 	t.Run("RFC 8439 - Test Vectors - A.3 - #10", func(t *testing.T) {
 		t.Parallel()
 
-		key := [32]byte{
+		key := poly1305.UnsafeOneTimeKeyFromBytes([32]byte{
 			0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		}
+		})
 
 		data := []byte{
 			0xE3, 0x35, 0x94, 0xD7, 0x50, 0x5E, 0x43, 0xB9, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
@@ -367,7 +372,7 @@ func TestPoly1305GenerateTag(t *testing.T) {
 		}
 
 		poly := poly1305.NewPoly1305(key)
-		tag := poly.GenerateTag(data)
+		tag, _ := poly.GenerateTag(data)
 
 		got := tag
 		want := [16]byte{
@@ -383,12 +388,12 @@ func TestPoly1305GenerateTag(t *testing.T) {
 	t.Run("RFC 8439 - Test Vectors - A.3 - #11", func(t *testing.T) {
 		t.Parallel()
 
-		key := [32]byte{
+		key := poly1305.UnsafeOneTimeKeyFromBytes([32]byte{
 			0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		}
+		})
 
 		data := []byte{
 			0xE3, 0x35, 0x94, 0xD7, 0x50, 0x5E, 0x43, 0xB9, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
@@ -397,7 +402,7 @@ func TestPoly1305GenerateTag(t *testing.T) {
 		}
 
 		poly := poly1305.NewPoly1305(key)
-		tag := poly.GenerateTag(data)
+		tag, _ := poly.GenerateTag(data)
 
 		got := tag
 		want := [16]byte{
@@ -410,3 +415,331 @@ func TestPoly1305GenerateTag(t *testing.T) {
 		}
 	})
 }
+
+func TestPoly1305OneTimeKey(t *testing.T) {
+	t.Run("Key Reuse", func(t *testing.T) {
+		t.Parallel()
+
+		poly := poly1305.NewPoly1305(poly1305.UnsafeOneTimeKeyFromBytes([32]byte{0x01}))
+		poly.GenerateTag([]byte{0x01})
+
+		_, err := poly.GenerateTag([]byte{0x02})
+
+		gotError := err
+		wantError := poly1305.ErrKeyReused
+
+		if !errors.Is(gotError, wantError) {
+			t.Errorf("want error %v, got %v", wantError, gotError)
+		}
+	})
+
+	t.Run("OneTimeAuth", func(t *testing.T) {
+		t.Parallel()
+
+		key := poly1305.UnsafeOneTimeKeyFromBytes([32]byte{
+			0x85, 0xd6, 0xbe, 0x78, 0x57, 0x55, 0x6d, 0x33,
+			0x7f, 0x44, 0x52, 0xfe, 0x42, 0xd5, 0x06, 0xa8,
+			0x01, 0x03, 0x80, 0x8a, 0xfb, 0x0d, 0xb2, 0xfd,
+			0x4a, 0xbf, 0xf6, 0xaf, 0x41, 0x49, 0xf5, 0x1b,
+		})
+
+		data := []byte("Cryptographic Forum Research Group")
+
+		got := poly1305.OneTimeAuth(key, data)
+		want := [16]byte{
+			0xa8, 0x06, 0x1d, 0xc1, 0x30, 0x51, 0x36, 0xc6,
+			0xc2, 0x2b, 0x8b, 0xaf, 0x0c, 0x01, 0x27, 0xa9,
+		}
+
+		if got != want {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Verify", func(t *testing.T) {
+		t.Parallel()
+
+		key := poly1305.UnsafeOneTimeKeyFromBytes([32]byte{
+			0x85, 0xd6, 0xbe, 0x78, 0x57, 0x55, 0x6d, 0x33,
+			0x7f, 0x44, 0x52, 0xfe, 0x42, 0xd5, 0x06, 0xa8,
+			0x01, 0x03, 0x80, 0x8a, 0xfb, 0x0d, 0xb2, 0xfd,
+			0x4a, 0xbf, 0xf6, 0xaf, 0x41, 0x49, 0xf5, 0x1b,
+		})
+
+		data := []byte("Cryptographic Forum Research Group")
+		tag := [16]byte{
+			0xa8, 0x06, 0x1d, 0xc1, 0x30, 0x51, 0x36, 0xc6,
+			0xc2, 0x2b, 0x8b, 0xaf, 0x0c, 0x01, 0x27, 0xa9,
+		}
+
+		if !poly1305.Verify(key, data, tag) {
+			t.Errorf("want %v, got %v", true, false)
+		}
+
+		tag[15] ^= 0x01
+
+		if poly1305.Verify(key, data, tag) {
+			t.Errorf("want %v, got %v", false, true)
+		}
+	})
+
+	t.Run("Redacted", func(t *testing.T) {
+		t.Parallel()
+
+		key := poly1305.UnsafeOneTimeKeyFromBytes([32]byte{0xab, 0xcd})
+
+		for _, format := range []string{"%v", "%+v", "%#v", "%x", "%s", "%d"} {
+			got := fmt.Sprintf(format, key)
+
+			if strings.Contains(got, "ab") || strings.Contains(got, "171") {
+				t.Errorf("%v: want redacted key, got %v", format, got)
+			}
+		}
+	})
+
+	t.Run("Read", func(t *testing.T) {
+		t.Parallel()
+
+		keyBytes := [32]byte{0x01, 0x02, 0x03}
+
+		key, err := poly1305.ReadOneTimeKey(bytes.NewReader(keyBytes[:]))
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if key != poly1305.UnsafeOneTimeKeyFromBytes(keyBytes) {
+			t.Errorf("want keys to be equal")
+		}
+
+		_, err = poly1305.ReadOneTimeKey(bytes.NewReader(keyBytes[:31]))
+
+		gotError := err
+		wantError := io.ErrUnexpectedEOF
+
+		if !errors.Is(gotError, wantError) {
+			t.Errorf("want error %v, got %v", wantError, gotError)
+		}
+	})
+}
+
+func TestPoly1305ResetClone(t *testing.T) {
+	t.Run("Reset", func(t *testing.T) {
+		t.Parallel()
+
+		key := poly1305.UnsafeOneTimeKeyFromBytes([32]byte{0x01, 0x02, 0x03})
+		data := []byte("attack at dawn")
+
+		poly := poly1305.NewPoly1305(poly1305.UnsafeOneTimeKeyFromBytes([32]byte{0x04}))
+		poly.GenerateTag(data)
+		poly.Reset(key)
+
+		got, err := poly.GenerateTag(data)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		want := poly1305.OneTimeAuth(key, data)
+
+		if got != want {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Clone", func(t *testing.T) {
+		t.Parallel()
+
+		key := poly1305.UnsafeOneTimeKeyFromBytes([32]byte{0x01, 0x02, 0x03})
+		data := []byte("attack at dawn")
+
+		poly := poly1305.NewPoly1305(key)
+		clone := poly.Clone()
+
+		got, _ := clone.GenerateTag(data)
+		want, _ := poly.GenerateTag(data)
+
+		if got != want {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+}
+
+func TestPoly1305BlockBoundaries(t *testing.T) {
+	t.Parallel()
+
+	// The key consists of the bytes 0x80 to 0x9f and the data of n bytes counting
+	// up from 0x00. The tags were cross-checked with golang.org/x/crypto/poly1305.
+	var keyBytes [32]byte
+	for i := range keyBytes {
+		keyBytes[i] = byte(0x80 + i)
+	}
+	key := poly1305.UnsafeOneTimeKeyFromBytes(keyBytes)
+
+	tt := map[int][16]byte{
+		0: {
+			0x90, 0x91, 0x92, 0x93, 0x94, 0x95, 0x96, 0x97,
+			0x98, 0x99, 0x9a, 0x9b, 0x9c, 0x9d, 0x9e, 0x9f,
+		},
+		1: {
+			0x9f, 0x11, 0x14, 0x16, 0x98, 0x19, 0x1c, 0x1e,
+			0xa0, 0x21, 0x24, 0x26, 0xa8, 0x29, 0x2c, 0x2e,
+		},
+		15: {
+			0xc5, 0xd6, 0xf1, 0xa0, 0xf3, 0xcf, 0xb6, 0x30,
+			0x4d, 0xf2, 0xa0, 0xe1, 0xc3, 0x2d, 0xa0, 0xfb,
+		},
+		16: {
+			0x7e, 0x64, 0x92, 0x2b, 0xf9, 0xa8, 0xa2, 0x06,
+			0x9e, 0x16, 0xd8, 0x02, 0x60, 0x9d, 0x22, 0x10,
+		},
+		17: {
+			0x9d, 0x1f, 0xa1, 0x70, 0x04, 0x28, 0x2e, 0x1b,
+			0xe0, 0x3f, 0xdc, 0x5f, 0x37, 0x1e, 0x06, 0x41,
+		},
+		31: {
+			0x87, 0x76, 0xe2, 0x36, 0x65, 0xa1, 0x4d, 0x7a,
+			0x93, 0xc4, 0xbe, 0x38, 0x1a, 0x87, 0x80, 0xfc,
+		},
+		32: {
+			0x5e, 0x36, 0xc9, 0x11, 0xd9, 0xfc, 0xcf, 0xf0,
+			0xa2, 0xbb, 0xdc, 0x4a, 0xc5, 0x19, 0x3a, 0x12,
+		},
+		33: {
+			0xee, 0x86, 0x33, 0x43, 0xf1, 0x30, 0x68, 0x2b,
+			0xf2, 0xc4, 0x40, 0x3c, 0xc8, 0xc0, 0xd4, 0x93,
+		},
+		63: {
+			0xe1, 0xac, 0xce, 0xfe, 0xf6, 0x9d, 0x7a, 0x93,
+			0xe5, 0xbb, 0x85, 0xc7, 0x33, 0xf2, 0x8e, 0x25,
+		},
+		64: {
+			0xf9, 0xd0, 0x41, 0x7a, 0x47, 0xfe, 0x29, 0x4b,
+			0x72, 0x58, 0x71, 0xbb, 0xfc, 0xca, 0xb6, 0x3d,
+		},
+		65: {
+			0x73, 0x8f, 0xbe, 0x68, 0x04, 0x6b, 0xc3, 0xb0,
+			0xcb, 0x26, 0x1f, 0x0a, 0x18, 0xf7, 0x66, 0xc4,
+		},
+	}
+
+	for length, want := range tt {
+		data := make([]byte, length)
+		for i := range data {
+			data[i] = byte(i)
+		}
+
+		got := poly1305.OneTimeAuth(key, data)
+
+		if got != want {
+			t.Errorf("%v bytes: want %v, got %v", length, want, got)
+		}
+	}
+}
+
+func TestPoly1305Update(t *testing.T) {
+	t.Parallel()
+
+	key := poly1305.UnsafeOneTimeKeyFromBytes([32]byte{0x01, 0x02, 0x03})
+
+	data := make([]byte, 100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	// The data is split into three parts at every combination of offsets (which
+	// covers the parts ending both within and on block boundaries).
+	for i := range len(data) + 1 {
+		for j := i; j <= len(data); j++ {
+			poly := poly1305.NewPoly1305(key)
+			poly.Update(data[:i])
+			poly.Update(data[i:j])
+
+			got, err := poly.GenerateTag(data[j:])
+			want := poly1305.OneTimeAuth(key, data)
+
+			if got != want || err != nil {
+				t.Fatalf("split at %v and %v: want %v, got %v (error %v)", i, j, want, got, err)
+			}
+		}
+	}
+
+	t.Run("Trace", func(t *testing.T) {
+		t.Parallel()
+
+		var want, got strings.Builder
+		poly1305.NewPoly1305(key, poly1305.WithTrace(&want)).GenerateTag(data)
+
+		poly := poly1305.NewPoly1305(key, poly1305.WithTrace(&got))
+		poly.Update(data[:7])
+		poly.Update(data[7:40])
+		poly.GenerateTag(data[40:])
+
+		if got.String() != want.String() {
+			t.Errorf("want %q, got %q", want.String(), got.String())
+		}
+	})
+
+	t.Run("Used", func(t *testing.T) {
+		t.Parallel()
+
+		poly := poly1305.NewPoly1305(key)
+		poly.GenerateTag(data)
+		poly.Update(data)
+
+		_, err := poly.GenerateTag(data)
+		if !errors.Is(err, poly1305.ErrKeyReused) {
+			t.Errorf("want error %v, got %v", poly1305.ErrKeyReused, err)
+		}
+	})
+}
+
+func BenchmarkPoly1305GenerateTag(b *testing.B) {
+	data := make([]byte, 1024)
+
+	b.SetBytes(int64(len(data)))
+
+	for range b.N {
+		poly1305.OneTimeAuth(poly1305.UnsafeOneTimeKeyFromBytes([32]byte{0x01}), data)
+	}
+}
+
+func TestPoly1305Trace(t *testing.T) {
+	t.Parallel()
+
+	key := poly1305.UnsafeOneTimeKeyFromBytes([32]byte{
+		0x85, 0xd6, 0xbe, 0x78, 0x57, 0x55, 0x6d, 0x33,
+		0x7f, 0x44, 0x52, 0xfe, 0x42, 0xd5, 0x06, 0xa8,
+		0x01, 0x03, 0x80, 0x8a, 0xfb, 0x0d, 0xb2, 0xfd,
+		0x4a, 0xbf, 0xf6, 0xaf, 0x41, 0x49, 0xf5, 0x1b,
+	})
+
+	data := []byte("Cryptographic Forum Research Group")
+
+	var trace strings.Builder
+	tag, _ := poly1305.NewPoly1305(key, poly1305.WithTrace(&trace)).GenerateTag(data)
+
+	if tag != poly1305.OneTimeAuth(key, data) {
+		t.Errorf("want %v, got %v", poly1305.OneTimeAuth(key, data), tag)
+	}
+
+	got := trace.String()
+
+	// The excerpts are the ones shown in RFC 8439 (section 2.5.2).
+	for _, want := range []string{
+		"Clamped r = 806d5400e52447c036d555408bed685\n",
+		"s = 1bf54941aff6bf4afdb20dfb8a800301\n",
+		"Block #1\n" +
+			"Acc = 0\n" +
+			"Block with 0x01 byte = 16f4620636968706172676f7470797243\n" +
+			"Acc + block = 16f4620636968706172676f7470797243\n" +
+			"(Acc+Block) * r = b83fe991ca66800489155dcd69e8426ba2779453994ac90ed284034da565ecf\n" +
+			"Acc = ((Acc+Block)*r) % P = 2c88c77849d64ae9147ddeb88e69c83fc\n",
+		"Block #3\n",
+		"Acc = ((Acc+Block)*r) % P = 28d31b7caff946c77c8844335369d03a7\n",
+		"Acc + s = 2a927010caf8b2bc2c6365130c11d06a8\n",
+		"Tag:\n    000  a8 06 1d c1 30 51 36 c6 c2 2b 8b af 0c 01 27 a9  ....0Q6..+....'.\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want trace to contain %q, got %q", want, got)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !tinygo
+
+package poly1305
+
+import "math/big"
+
+// P is the prime 2^130-5.
+// It's not available with TinyGo so that the package doesn't depend on
+// math/big there.
+var P, _ = new(big.Int).SetString("3fffffffffffffffffffffffffffffffb", 16)
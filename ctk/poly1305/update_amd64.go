@@ -0,0 +1,58 @@
+//go:build amd64 && !purego
+
+package poly1305
+
+// Backend is the name of the implementation that processes the message blocks
+// (the amd64 assembly in this build, with the MULX variant if the CPU supports
+// BMI2).
+var Backend = "amd64"
+
+// useBMI2 reports whether the CPU supports the BMI2 instruction set extension
+// (and therefore the MULX instruction used by updateBMI2).
+var useBMI2 = hasBMI2()
+
+func init() {
+	if useBMI2 {
+		Backend = "amd64-bmi2"
+	}
+}
+
+// update adds the full blocks of the message (its length is a multiple of
+// BlockSize) to the accumulator (see updateGeneric). The implementation is
+// chosen at runtime based on the CPU features.
+func update(state *limbs, msg []byte) {
+	if useBMI2 {
+		updateBMI2(state, msg)
+		return
+	}
+
+	updateAMD64(state, msg)
+}
+
+// updateAMD64 is the implementation of update in update_amd64.s which only uses
+// instructions all amd64 CPUs support.
+//
+//go:noescape
+func updateAMD64(state *limbs, msg []byte)
+
+// updateBMI2 is the implementation of update in update_amd64.s which uses the
+// MULX instruction (BMI2) so that the products don't clobber the flags.
+//
+//go:noescape
+func updateBMI2(state *limbs, msg []byte)
+
+// hasBMI2 reports whether the CPU supports BMI2 (CPUID leaf 7, bit 8 of EBX).
+func hasBMI2() bool {
+	maxLeaf, _, _, _ := cpuid(0, 0)
+	if maxLeaf < 7 {
+		return false
+	}
+
+	_, ebx, _, _ := cpuid(7, 0)
+
+	return ebx&(1<<8) != 0
+}
+
+// cpuid executes the CPUID instruction with the leaf eaxArg and the subleaf
+// ecxArg. It's implemented in update_amd64.s.
+func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
@@ -0,0 +1,181 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// func updateAMD64(state *limbs, msg []byte)
+//
+// The accumulator h = h0 + h1*2^64 + h2*2^128 is kept in R8, R9 and R10 and the
+// clamped r = r0 + r1*2^64 in R11 and R12. As r0 and r1 are smaller than 2^60
+// (and h2 is small) the products h2*r0 and h2*r1 fit into 64 bits.
+TEXT ·updateAMD64(SB), NOSPLIT, $0-32
+	MOVQ state+0(FP), DI
+	MOVQ msg_base+8(FP), SI
+	MOVQ msg_len+16(FP), CX
+
+	MOVQ 0(DI), R8
+	MOVQ 8(DI), R9
+	MOVQ 16(DI), R10
+	MOVQ 24(DI), R11
+	MOVQ 32(DI), R12
+
+	CMPQ CX, $16
+	JB   done
+
+loop:
+	// h += block + 2^128 (the added 0x01 byte).
+	ADDQ 0(SI), R8
+	ADCQ 8(SI), R9
+	ADCQ $1, R10
+	LEAQ 16(SI), SI
+
+	// (R13, R14, R15, BX) = h * r.
+	XORQ BX, BX
+
+	MOVQ R11, AX
+	MULQ R8
+	MOVQ AX, R13
+	MOVQ DX, R14
+
+	MOVQ R11, AX
+	MULQ R9
+	ADDQ AX, R14
+	ADCQ $0, DX
+	MOVQ DX, R15
+
+	MOVQ R12, AX
+	MULQ R8
+	ADDQ AX, R14
+	ADCQ DX, R15
+	ADCQ $0, BX
+
+	MOVQ R12, AX
+	MULQ R9
+	ADDQ AX, R15
+	ADCQ DX, BX
+
+	MOVQ R11, AX
+	IMULQ R10, AX
+	ADDQ AX, R15
+	ADCQ $0, BX
+
+	MOVQ R12, AX
+	IMULQ R10, AX
+	ADDQ AX, BX
+
+	// h = (h*r) mod 2^130 + 5 * ((h*r) >> 130) which is computed as the sum of
+	// the lowest 130 bits, c*4 (the bits above with the lowest 2 bits cleared)
+	// and c (c*4 shifted right by 2).
+	MOVQ R13, R8
+	MOVQ R14, R9
+	MOVQ R15, R10
+	ANDQ $3, R10
+
+	ANDQ $-4, R15
+	ADDQ R15, R8
+	ADCQ BX, R9
+	ADCQ $0, R10
+
+	SHRQ $2, BX, R15
+	SHRQ $2, BX
+	ADDQ R15, R8
+	ADCQ BX, R9
+	ADCQ $0, R10
+
+	SUBQ $16, CX
+	CMPQ CX, $16
+	JAE  loop
+
+done:
+	MOVQ R8, 0(DI)
+	MOVQ R9, 8(DI)
+	MOVQ R10, 16(DI)
+	RET
+
+// func updateBMI2(state *limbs, msg []byte)
+//
+// The registers are used as in updateAMD64. MULX multiplies by DX and doesn't
+// modify the flags.
+TEXT ·updateBMI2(SB), NOSPLIT, $0-32
+	MOVQ state+0(FP), DI
+	MOVQ msg_base+8(FP), SI
+	MOVQ msg_len+16(FP), CX
+
+	MOVQ 0(DI), R8
+	MOVQ 8(DI), R9
+	MOVQ 16(DI), R10
+	MOVQ 24(DI), R11
+	MOVQ 32(DI), R12
+
+	CMPQ CX, $16
+	JB   doneBMI2
+
+loopBMI2:
+	// h += block + 2^128 (the added 0x01 byte).
+	ADDQ 0(SI), R8
+	ADCQ 8(SI), R9
+	ADCQ $1, R10
+	LEAQ 16(SI), SI
+
+	// (R13, R14, R15, BX) = h * r.
+	MOVQ  R11, DX
+	MULXQ R8, R13, R14
+	MULXQ R9, AX, R15
+	ADDQ  AX, R14
+	ADCQ  $0, R15
+
+	MOVQ  R12, DX
+	MULXQ R9, AX, BX
+	ADDQ  AX, R15
+	ADCQ  $0, BX
+
+	MULXQ R8, AX, DX
+	ADDQ  AX, R14
+	ADCQ  DX, R15
+	ADCQ  $0, BX
+
+	MOVQ  R11, AX
+	IMULQ R10, AX
+	ADDQ  AX, R15
+	ADCQ  $0, BX
+
+	MOVQ  R12, AX
+	IMULQ R10, AX
+	ADDQ  AX, BX
+
+	// h = (h*r) mod 2^130 + 5 * ((h*r) >> 130) (see updateAMD64).
+	MOVQ R13, R8
+	MOVQ R14, R9
+	MOVQ R15, R10
+	ANDQ $3, R10
+
+	ANDQ $-4, R15
+	ADDQ R15, R8
+	ADCQ BX, R9
+	ADCQ $0, R10
+
+	SHRQ $2, BX, R15
+	SHRQ $2, BX
+	ADDQ R15, R8
+	ADCQ BX, R9
+	ADCQ $0, R10
+
+	SUBQ $16, CX
+	CMPQ CX, $16
+	JAE  loopBMI2
+
+doneBMI2:
+	MOVQ R8, 0(DI)
+	MOVQ R9, 8(DI)
+	MOVQ R10, 16(DI)
+	RET
+
+// func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+TEXT ·cpuid(SB), NOSPLIT, $0-24
+	MOVL eaxArg+0(FP), AX
+	MOVL ecxArg+4(FP), CX
+	CPUID
+	MOVL AX, eax+8(FP)
+	MOVL BX, ebx+12(FP)
+	MOVL CX, ecx+16(FP)
+	MOVL DX, edx+20(FP)
+	RET
@@ -0,0 +1,44 @@
+//go:build amd64 && !purego
+
+package poly1305
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestPoly1305UpdateAMD64(t *testing.T) {
+	rng := rand.New(rand.NewPCG(5, 6))
+
+	implementations := map[string]func(*limbs, []byte){
+		"Baseline": updateAMD64,
+	}
+	// The MULX variant can only be tested on CPUs that support BMI2.
+	if useBMI2 {
+		implementations["BMI2"] = updateBMI2
+	}
+
+	for name, update := range implementations {
+		for blocks := range 20 {
+			var key [32]byte
+			for i := range key {
+				key[i] = byte(rng.Uint32())
+			}
+
+			data := make([]byte, blocks*BlockSize)
+			for i := range data {
+				data[i] = byte(rng.Uint32())
+			}
+
+			generic := NewPoly1305(UnsafeOneTimeKeyFromBytes(key))
+			updateGeneric(&generic.state, data)
+
+			fast := NewPoly1305(UnsafeOneTimeKeyFromBytes(key))
+			update(&fast.state, data)
+
+			if finalize(fast.state.h) != finalize(generic.state.h) {
+				t.Errorf("%v %v blocks: want %x, got %x", name, blocks, generic.state.h, fast.state.h)
+			}
+		}
+	}
+}
@@ -0,0 +1,16 @@
+//go:build arm64 && !purego
+
+package poly1305
+
+// Backend is the name of the implementation that processes the message blocks
+// (the arm64 assembly in this build).
+const Backend = "arm64"
+
+// update adds the full blocks of the message (its length is a multiple of
+// BlockSize) to the accumulator (see updateGeneric).
+// It's implemented in update_arm64.s and only uses instructions of the ARMv8
+// base instruction set (which all arm64 CPUs support) so that there's nothing
+// to choose at runtime.
+//
+//go:noescape
+func update(state *limbs, msg []byte)
@@ -0,0 +1,78 @@
+//go:build arm64 && !purego
+
+#include "textflag.h"
+
+// func update(state *limbs, msg []byte)
+//
+// The accumulator h = h0 + h1*2^64 + h2*2^128 is kept in R3, R4 and R5 and the
+// clamped r = r0 + r1*2^64 in R6 and R7. As r0 and r1 are smaller than 2^60
+// (and h2 is small) the products h2*r0 and h2*r1 fit into 64 bits.
+TEXT ·update(SB), NOSPLIT, $0-32
+	MOVD state+0(FP), R0
+	MOVD msg_base+8(FP), R1
+	MOVD msg_len+16(FP), R2
+
+	LDP  0(R0), (R3, R4)
+	MOVD 16(R0), R5
+	LDP  24(R0), (R6, R7)
+
+	CMP $16, R2
+	BLO done
+
+loop:
+	// h += block + 2^128 (the added 0x01 byte).
+	LDP.P 16(R1), (R8, R9)
+	ADDS  R8, R3, R3
+	ADCS  R9, R4, R4
+	ADC   ZR, R5, R5
+	ADD   $1, R5, R5
+
+	// The partial products of h * r.
+	MUL   R6, R3, R10 // h0*r0 (low)
+	UMULH R6, R3, R11 // h0*r0 (high)
+	MUL   R6, R4, R12 // h1*r0 (low)
+	UMULH R6, R4, R13 // h1*r0 (high)
+	MUL   R7, R3, R14 // h0*r1 (low)
+	UMULH R7, R3, R15 // h0*r1 (high)
+	MUL   R7, R4, R16 // h1*r1 (low)
+	UMULH R7, R4, R17 // h1*r1 (high)
+	MUL   R6, R5, R19 // h2*r0
+	MUL   R7, R5, R20 // h2*r1
+
+	// (R10, R21, R22, R24) = h * r with the carries into the highest limb
+	// collected in R23.
+	ADDS R12, R11, R21
+	ADCS R15, R13, R22
+	ADC  ZR, ZR, R23
+	ADDS R14, R21, R21
+	ADCS R16, R22, R22
+	ADC  ZR, R23, R23
+	ADDS R19, R22, R22
+	ADC  ZR, R23, R23
+	ADD  R17, R20, R24
+	ADD  R23, R24, R24
+
+	// h = (h*r) mod 2^130 + 5 * ((h*r) >> 130) which is computed as the sum of
+	// the lowest 130 bits, c*4 (the bits above with the lowest 2 bits cleared)
+	// and c (c*4 shifted right by 2).
+	AND  $3, R22, R5
+	AND  $-4, R22, R25
+	ADDS R25, R10, R3
+	ADCS R24, R21, R4
+	ADC  ZR, R5, R5
+
+	LSR  $2, R25, R19
+	ORR  R24<<62, R19, R19
+	LSR  $2, R24, R20
+	ADDS R19, R3, R3
+	ADCS R20, R4, R4
+	ADC  ZR, R5, R5
+
+	SUB $16, R2, R2
+	CMP $16, R2
+	BHS loop
+
+done:
+	STP  (R3, R4), 0(R0)
+	MOVD R5, 16(R0)
+	RET
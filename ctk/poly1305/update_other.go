@@ -0,0 +1,13 @@
+//go:build (!amd64 && !arm64) || purego
+
+package poly1305
+
+// Backend is the name of the implementation that processes the message blocks
+// (the portable Go implementation in this build).
+const Backend = "generic"
+
+// update adds the full blocks of the message (its length is a multiple of
+// BlockSize) to the accumulator via the portable (multi-block) implementation.
+func update(state *limbs, msg []byte) {
+	updateMultiBlocks(state, msg)
+}
@@ -0,0 +1,268 @@
+// Package random generates keys, nonces and other random bytes.
+//
+// The bytes are read from crypto/rand and go through basic health checks so
+// that a broken source results in an error rather than in predictable (e.g.
+// all-zero) keys. Additional entropy from a user-provided source can be mixed
+// into the output.
+//
+// The packages of the toolkit generate their keys, nonces, salts, data
+// encryption keys, etc. via this package. SetReader replaces crypto/rand for
+// all of them at once so that tests which depend on the generated values can
+// be made deterministic (e.g. via NewDeterministicReader).
+package random
+
+import (
+	"crypto/rand"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pmuens/ctk-go/ctk/blake2b"
+	"github.com/pmuens/ctk-go/ctk/chacha20"
+)
+
+const (
+	// ErrHealthCheck is returned if the output of a source looks broken (e.g.
+	// if all bytes are zero).
+	ErrHealthCheck = Error("random source failed health check")
+)
+
+// MinCheckSize is the minimum number of bytes an output needs to have to be
+// health checked. Shorter outputs (e.g. single bytes) can repeat by chance.
+const MinCheckSize = 8
+
+// SeedSize is the number of bytes that are read from the entropy source when
+// its entropy is mixed into the output.
+const SeedSize = 32
+
+// mixLabel is used for domain separation when mixing the sources.
+var mixLabel = []byte("ctk-go random mix")
+
+// defaultReader is the primary source of the reads that don't configure one via
+// WithReader (crypto/rand if nil).
+var defaultReader atomic.Pointer[io.Reader]
+
+// SetReader replaces crypto/rand as the primary source of all reads that don't
+// configure one via WithReader and returns a function that restores the
+// previous source. A nil reader restores crypto/rand.
+// It's meant for deterministic tests and shouldn't be used in production. The
+// reader needs to be safe for concurrent use if the toolkit is used
+// concurrently.
+func SetReader(r io.Reader) (restore func()) {
+	var previous *io.Reader
+	if r == nil {
+		previous = defaultReader.Swap(nil)
+	} else {
+		previous = defaultReader.Swap(&r)
+	}
+
+	return func() {
+		defaultReader.Store(previous)
+	}
+}
+
+// reader returns the primary source of the reads that don't configure one.
+func reader() io.Reader {
+	r := defaultReader.Load()
+	if r == nil {
+		return rand.Reader
+	}
+
+	return *r
+}
+
+// Reader is a reader whose reads go through Read (i.e. the source set via
+// SetReader and the health checks) for APIs that take an io.Reader as their
+// source of random bytes (e.g. rsa.EncryptOAEP).
+var Reader io.Reader = checkedReader{}
+
+// checkedReader reads via Read.
+type checkedReader struct{}
+
+// Read implements the io.Reader interface.
+func (checkedReader) Read(p []byte) (int, error) {
+	err := Read(p)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// NewDeterministicReader returns a reader whose output is fully determined by
+// the seed (the ChaCha20 keystream with the seed as the key). It's safe for
+// concurrent use, but the order in which concurrent reads receive the output
+// isn't deterministic.
+// It's meant for tests and must never be used to generate production keys.
+func NewDeterministicReader(seed [32]byte) io.Reader {
+	return &lockedReader{reader: chacha20.NewChaCha20WithCounter(seed, [12]byte{}, 0).KeystreamReader()}
+}
+
+// lockedReader serializes the reads of a reader.
+type lockedReader struct {
+	// mu guards reader.
+	mu sync.Mutex
+
+	// reader is the underlying reader.
+	reader io.Reader
+}
+
+// Read implements the io.Reader interface.
+func (l *lockedReader) Read(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.reader.Read(p)
+}
+
+// options are the configured sources.
+type options struct {
+	// reader is the primary source (crypto/rand or the one set via SetReader by
+	// default).
+	reader io.Reader
+
+	// entropy is the optional source that's mixed into the output.
+	entropy io.Reader
+}
+
+// Option configures the sources of random bytes.
+type Option func(*options)
+
+// WithReader replaces crypto/rand as the primary source (e.g. with a hardware
+// random number generator or a deterministic source in tests).
+func WithReader(r io.Reader) Option {
+	return func(o *options) {
+		o.reader = r
+	}
+}
+
+// WithEntropy mixes 32 bytes read from r into the output. The output remains
+// unpredictable as long as one of the sources is.
+func WithEntropy(r io.Reader) Option {
+	return func(o *options) {
+		o.entropy = r
+	}
+}
+
+// Read fills b with random bytes.
+// Returns an error if a source fails or its output fails the health checks.
+func Read(b []byte, opts ...Option) error {
+	o := options{reader: reader()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	err := readChecked(o.reader, b)
+	if err != nil {
+		return err
+	}
+
+	if o.entropy == nil {
+		return nil
+	}
+
+	seed := make([]byte, SeedSize)
+	defer clear(seed)
+
+	err = readChecked(o.entropy, seed)
+	if err != nil {
+		clear(b)
+		return err
+	}
+
+	// Both sources are hashed into a ChaCha20 key whose keystream becomes the
+	// output so that it's unpredictable if either of the sources is.
+	h := blake2b.New256()
+	h.Write(mixLabel)
+	h.Write(seed)
+	h.Write(b)
+	key := [32]byte(h.Sum(nil))
+	defer clear(key[:])
+
+	// The keystream only runs out for outputs larger than 256 GiB.
+	io.ReadFull(chacha20.NewChaCha20WithCounter(key, [12]byte{}, 0).KeystreamReader(), b)
+
+	return nil
+}
+
+// Bytes returns n random bytes (see Read).
+func Bytes(n int, opts ...Option) ([]byte, error) {
+	b := make([]byte, n)
+
+	err := Read(b, opts...)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return b, nil
+}
+
+// Key returns a random 32 byte key (see Read).
+func Key(opts ...Option) ([32]byte, error) {
+	var key [32]byte
+
+	err := Read(key[:], opts...)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	return key, nil
+}
+
+// Nonce returns a random 12 byte nonce (see Read).
+func Nonce(opts ...Option) ([12]byte, error) {
+	var nonce [12]byte
+
+	err := Read(nonce[:], opts...)
+	if err != nil {
+		return [12]byte{}, err
+	}
+
+	return nonce, nil
+}
+
+// XNonce returns a random 24 byte (extended) nonce (see Read).
+func XNonce(opts ...Option) ([24]byte, error) {
+	var nonce [24]byte
+
+	err := Read(nonce[:], opts...)
+	if err != nil {
+		return [24]byte{}, err
+	}
+
+	return nonce, nil
+}
+
+// readChecked fills b with bytes from r and checks them.
+// b is cleared if an error occurs so that no partial output is used by accident.
+func readChecked(r io.Reader, b []byte) error {
+	_, err := io.ReadFull(r, b)
+	if err != nil {
+		clear(b)
+		return err
+	}
+
+	if !healthy(b) {
+		clear(b)
+		return ErrHealthCheck
+	}
+
+	return nil
+}
+
+// healthy reports whether the output passes the health checks. An output of at
+// least MinCheckSize bytes fails if all its bytes are the same (e.g. zero) which
+// is what stuck or uninitialized sources tend to produce.
+func healthy(b []byte) bool {
+	if len(b) < MinCheckSize {
+		return true
+	}
+
+	for _, value := range b[1:] {
+		if value != b[0] {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,223 @@
+package random_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/pmuens/ctk-go/ctk/random"
+)
+
+// errSource is the error returned by the failing source.
+var errSource = errors.New("source failed")
+
+// counting returns a reader of n bytes counting up from 0x00.
+func counting(n int) *bytes.Reader {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	return bytes.NewReader(data)
+}
+
+func TestRandom(t *testing.T) {
+	t.Run("crypto/rand", func(t *testing.T) {
+		t.Parallel()
+
+		first, err := random.Key()
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		second, _ := random.Key()
+
+		if first == second {
+			t.Errorf("want different keys, got %v twice", first)
+		}
+	})
+
+	t.Run("Sizes", func(t *testing.T) {
+		t.Parallel()
+
+		nonce, err := random.Nonce()
+		if err != nil || nonce == [12]byte{} {
+			t.Errorf("want random nonce, got %v (%v)", nonce, err)
+		}
+
+		xNonce, err := random.XNonce()
+		if err != nil || xNonce == [24]byte{} {
+			t.Errorf("want random nonce, got %v (%v)", xNonce, err)
+		}
+
+		b, err := random.Bytes(100)
+		if err != nil || len(b) != 100 {
+			t.Errorf("want %v bytes, got %v (%v)", 100, len(b), err)
+		}
+	})
+
+	t.Run("Custom Reader", func(t *testing.T) {
+		t.Parallel()
+
+		got, err := random.Key(random.WithReader(counting(32)))
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		var want [32]byte
+		for i := range want {
+			want[i] = byte(i)
+		}
+
+		if got != want {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Short Outputs", func(t *testing.T) {
+		t.Parallel()
+
+		// Outputs shorter than MinCheckSize aren't health checked.
+		b, err := random.Bytes(random.MinCheckSize-1, random.WithReader(bytes.NewReader(make([]byte, 8))))
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if len(b) != random.MinCheckSize-1 {
+			t.Errorf("want length %v, got %v", random.MinCheckSize-1, len(b))
+		}
+	})
+
+	t.Run("Entropy", func(t *testing.T) {
+		t.Parallel()
+
+		first, err := random.Key(random.WithReader(counting(32)), random.WithEntropy(bytes.NewReader(bytes.Repeat([]byte{0x01, 0x02}, 16))))
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		second, _ := random.Key(random.WithReader(counting(32)), random.WithEntropy(bytes.NewReader(bytes.Repeat([]byte{0x01, 0x03}, 16))))
+		withoutEntropy, _ := random.Key(random.WithReader(counting(32)))
+
+		if first == second || first == withoutEntropy {
+			t.Errorf("want the entropy to change the output, got %v", first)
+		}
+
+		again, _ := random.Key(random.WithReader(counting(32)), random.WithEntropy(bytes.NewReader(bytes.Repeat([]byte{0x01, 0x02}, 16))))
+
+		if first != again {
+			t.Errorf("want %v, got %v", first, again)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]struct {
+			opts []random.Option
+			want error
+		}{
+			"Failing Reader":  {opts: []random.Option{random.WithReader(iotest.ErrReader(errSource))}, want: errSource},
+			"Short Reader":    {opts: []random.Option{random.WithReader(counting(31))}, want: io.ErrUnexpectedEOF},
+			"Zero Reader":     {opts: []random.Option{random.WithReader(bytes.NewReader(make([]byte, 32)))}, want: random.ErrHealthCheck},
+			"Constant Reader": {opts: []random.Option{random.WithReader(bytes.NewReader(bytes.Repeat([]byte{0xff}, 32)))}, want: random.ErrHealthCheck},
+			"Failing Entropy": {opts: []random.Option{random.WithEntropy(iotest.ErrReader(errSource))}, want: errSource},
+			"Zero Entropy":    {opts: []random.Option{random.WithEntropy(bytes.NewReader(make([]byte, 32)))}, want: random.ErrHealthCheck},
+		}
+
+		for name, tc := range tt {
+			key, err := random.Key(tc.opts...)
+
+			if !errors.Is(err, tc.want) {
+				t.Errorf("%v: want error %v, got %v", name, tc.want, err)
+			}
+
+			if key != [32]byte{} {
+				t.Errorf("%v: want zero key, got %v", name, key)
+			}
+		}
+	})
+}
+
+func TestSetReader(t *testing.T) {
+	// The source is global so the subtests can't run in parallel (the other
+	// tests' subtests have finished when this test starts).
+	seed := [32]byte{0x01}
+
+	t.Run("Deterministic", func(t *testing.T) {
+		restore := random.SetReader(random.NewDeterministicReader(seed))
+		first, err := random.Key()
+		restore()
+
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		restore = random.SetReader(random.NewDeterministicReader(seed))
+		second, _ := random.Key()
+		restore()
+
+		if first != second {
+			t.Errorf("want %v, got %v", first, second)
+		}
+
+		third, _ := random.Key()
+		if third == first {
+			t.Errorf("want crypto/rand key after restore, got %v", third)
+		}
+	})
+
+	t.Run("WithReader Takes Precedence", func(t *testing.T) {
+		restore := random.SetReader(iotest.ErrReader(errSource))
+		defer restore()
+
+		_, err := random.Key()
+		if !errors.Is(err, errSource) {
+			t.Errorf("want error %v, got %v", errSource, err)
+		}
+
+		_, err = random.Key(random.WithReader(random.NewDeterministicReader(seed)))
+		if !errors.Is(err, nil) {
+			t.Errorf("want error %v, got %v", nil, err)
+		}
+	})
+
+	t.Run("Reader", func(t *testing.T) {
+		restore := random.SetReader(random.NewDeterministicReader(seed))
+		want, _ := random.Key()
+		restore()
+
+		restore = random.SetReader(random.NewDeterministicReader(seed))
+		defer restore()
+
+		var got [32]byte
+		_, err := io.ReadFull(random.Reader, got[:])
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if got != want {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Nil Reader", func(t *testing.T) {
+		restore := random.SetReader(iotest.ErrReader(errSource))
+		defer restore()
+
+		restoreNil := random.SetReader(nil)
+		_, err := random.Key()
+		restoreNil()
+
+		if !errors.Is(err, nil) {
+			t.Errorf("want error %v, got %v", nil, err)
+		}
+
+		_, err = random.Key()
+		if !errors.Is(err, errSource) {
+			t.Errorf("want error %v, got %v", errSource, err)
+		}
+	})
+}
@@ -0,0 +1,509 @@
+// Package ratchet implements the Double Ratchet algorithm with header
+// encryption as specified in https://signal.org/docs/specifications/doubleratchet.
+//
+// X25519 is used for the Diffie-Hellman ratchet, HKDF and HMAC (both with
+// SHA-256) are used for the root- and chain key derivations and
+// XChaCha20-Poly1305 is used to encrypt headers and messages.
+package ratchet
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"maps"
+
+	"github.com/pmuens/ctk-go/ctk/hkdf"
+	"github.com/pmuens/ctk-go/ctk/internal/debug"
+	"github.com/pmuens/ctk-go/ctk/random"
+	"github.com/pmuens/ctk-go/ctk/x25519"
+	"github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
+)
+
+const (
+	// ErrInvalidHeader is the cause of ErrInvalidMessage if a header can't be
+	// decrypted with any of the current header keys (only added in builds with
+	// the ctkdebug build tag so that attackers can't tell whether a forged
+	// header was accepted).
+	ErrInvalidHeader = Error("invalid header")
+
+	// ErrInvalidMessage is returned if a message is too short or its header or
+	// tag is invalid.
+	ErrInvalidMessage = Error("invalid message")
+
+	// ErrTooManySkipped is returned if a message would require skipping more
+	// than MaxSkip message keys.
+	ErrTooManySkipped = Error("too many skipped messages")
+
+	// ErrNotInitialized is returned if a session tries to send a message before
+	// it received one from its peer.
+	ErrNotInitialized = Error("sending chain not initialized")
+)
+
+// MaxSkip is the maximum number of message keys that can be skipped in a
+// single chain.
+const MaxSkip = 1000
+
+// headerSize is the size (in bytes) of a plaintext header
+// (DH public key | previous chain length | message number).
+const headerSize = x25519.KeySize + 4 + 4
+
+// nonceSize is the size (in bytes) of the XChaCha20-Poly1305 nonce.
+const nonceSize = 24
+
+// tagSize is the size (in bytes) of the Poly1305 tag.
+const tagSize = 16
+
+// EncryptedHeaderSize is the size (in bytes) of an encrypted header which is
+// prepended to every message (nonce | encrypted header | tag).
+const EncryptedHeaderSize = nonceSize + headerSize + tagSize
+
+// Info strings used for domain separation in the key derivations.
+var (
+	rootInfo    = []byte("ctk-go ratchet root")
+	messageInfo = []byte("ctk-go ratchet message")
+)
+
+// header is the plaintext header that's sent alongside every message.
+type header struct {
+	// dh is the sender's current ratchet public key.
+	dh [32]byte
+
+	// pn is the number of messages in the previous sending chain.
+	pn uint32
+
+	// n is the message number in the current sending chain.
+	n uint32
+}
+
+// skippedKey identifies a skipped message key by its header key and message number.
+type skippedKey struct {
+	// hk is the header key of the chain the message key belongs to.
+	hk [32]byte
+
+	// n is the message number.
+	n uint32
+}
+
+// Session is a stateful Double Ratchet session between two parties.
+type Session struct {
+	// dhsPrivate is the private key of the sending ratchet key pair.
+	dhsPrivate [32]byte
+
+	// dhsPublic is the public key of the sending ratchet key pair.
+	dhsPublic [32]byte
+
+	// dhr is the peer's ratchet public key.
+	dhr [32]byte
+
+	// rk is the root key.
+	rk [32]byte
+
+	// cks is the sending chain key.
+	cks *[32]byte
+
+	// ckr is the receiving chain key.
+	ckr *[32]byte
+
+	// hks is the sending header key.
+	hks *[32]byte
+
+	// hkr is the receiving header key.
+	hkr *[32]byte
+
+	// nhks is the next sending header key.
+	nhks [32]byte
+
+	// nhkr is the next receiving header key.
+	nhkr [32]byte
+
+	// ns is the message number of the sending chain.
+	ns uint32
+
+	// nr is the message number of the receiving chain.
+	nr uint32
+
+	// pn is the number of messages in the previous sending chain.
+	pn uint32
+
+	// skipped stores message keys of skipped messages.
+	skipped map[skippedKey][32]byte
+}
+
+// NewSender creates the session of the party that sends the first message.
+// sharedSecret, sharedHeaderKey and sharedNextHeaderKey need to be agreed upon
+// (e.g. via X3DH) and peerPublic is the peer's ratchet public key.
+func NewSender(sharedSecret [32]byte, peerPublic [32]byte, sharedHeaderKey [32]byte, sharedNextHeaderKey [32]byte) (*Session, error) {
+	private, public, err := x25519.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	dh, err := x25519.SharedSecret(private, peerPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	rk, cks, nhks := kdfRK(sharedSecret, dh)
+	hks := sharedHeaderKey
+
+	return &Session{
+		dhsPrivate: private,
+		dhsPublic:  public,
+		dhr:        peerPublic,
+		rk:         rk,
+		cks:        &cks,
+		hks:        &hks,
+		nhks:       nhks,
+		nhkr:       sharedNextHeaderKey,
+		skipped:    make(map[skippedKey][32]byte),
+	}, nil
+}
+
+// NewReceiver creates the session of the party that receives the first message.
+// sharedSecret, sharedHeaderKey and sharedNextHeaderKey need to be agreed upon
+// (e.g. via X3DH) and private is the private key that corresponds to the
+// ratchet public key known by the peer.
+func NewReceiver(sharedSecret [32]byte, private [32]byte, sharedHeaderKey [32]byte, sharedNextHeaderKey [32]byte) *Session {
+	return &Session{
+		dhsPrivate: private,
+		dhsPublic:  x25519.PublicKey(private),
+		rk:         sharedSecret,
+		nhks:       sharedNextHeaderKey,
+		nhkr:       sharedHeaderKey,
+		skipped:    make(map[skippedKey][32]byte),
+	}
+}
+
+// PublicKey returns the session's current ratchet public key.
+func (s *Session) PublicKey() [32]byte {
+	return s.dhsPublic
+}
+
+// Encrypt encrypts the plaintext and returns a message that consists of the
+// encrypted header followed by the ciphertext and its tag.
+// The additional authenticated data (AAD) is bound to the message.
+// Returns an error if the session can't send messages yet.
+func (s *Session) Encrypt(plaintext []byte, aad []byte) ([]byte, error) {
+	if s.cks == nil || s.hks == nil {
+		return []byte{}, ErrNotInitialized
+	}
+
+	cks, mk := kdfCK(*s.cks)
+	s.cks = &cks
+
+	h := header{dh: s.dhsPublic, pn: s.pn, n: s.ns}
+	encHeader, err := encryptHeader(*s.hks, h)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	s.ns += 1
+
+	ciphertext := encrypt(mk, plaintext, concat(aad, encHeader))
+
+	return append(encHeader, ciphertext...), nil
+}
+
+// Decrypt decrypts the message with the additional authenticated data (AAD)
+// that was used when encrypting it.
+// The session's state stays untouched if an error is returned.
+func (s *Session) Decrypt(message []byte, aad []byte) ([]byte, error) {
+	if len(message) < EncryptedHeaderSize+tagSize {
+		return []byte{}, ErrInvalidMessage
+	}
+
+	encHeader := message[:EncryptedHeaderSize]
+	ciphertext := message[EncryptedHeaderSize:]
+	ad := concat(aad, encHeader)
+
+	plaintext, found, err := s.trySkippedMessageKeys(encHeader, ciphertext, ad)
+	if found {
+		return plaintext, err
+	}
+
+	// Work on a copy of the state so that it can be discarded if decryption fails.
+	next := s.clone()
+
+	h, dhRatchet, err := next.decryptHeader(encHeader)
+	if err != nil {
+		return []byte{}, debug.Detail(ErrInvalidMessage, err)
+	}
+
+	if dhRatchet {
+		err = next.skipMessageKeys(h.pn)
+		if err != nil {
+			return []byte{}, err
+		}
+
+		err = next.dhRatchet(h)
+		if err != nil {
+			return []byte{}, err
+		}
+	}
+
+	err = next.skipMessageKeys(h.n)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	ckr, mk := kdfCK(*next.ckr)
+	next.ckr = &ckr
+	next.nr += 1
+
+	plaintext, err = decrypt(mk, ciphertext, ad)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	*s = *next
+
+	return plaintext, nil
+}
+
+// trySkippedMessageKeys tries to decrypt the message with one of the stored
+// message keys of skipped messages.
+// found reports whether a matching message key was found.
+func (s *Session) trySkippedMessageKeys(encHeader []byte, ciphertext []byte, ad []byte) ([]byte, bool, error) {
+	for key, mk := range s.skipped {
+		h, err := decryptHeader(key.hk, encHeader)
+		if err != nil || h.n != key.n {
+			continue
+		}
+
+		plaintext, err := decrypt(mk, ciphertext, ad)
+		if err != nil {
+			return []byte{}, true, err
+		}
+
+		delete(s.skipped, key)
+
+		return plaintext, true, nil
+	}
+
+	return []byte{}, false, nil
+}
+
+// decryptHeader decrypts the header with the current or the next receiving
+// header key.
+// dhRatchet reports whether the next header key was used which means that a
+// DH ratchet step needs to be performed.
+func (s *Session) decryptHeader(encHeader []byte) (header, bool, error) {
+	if s.hkr != nil {
+		h, err := decryptHeader(*s.hkr, encHeader)
+		if err == nil {
+			return h, false, nil
+		}
+	}
+
+	h, err := decryptHeader(s.nhkr, encHeader)
+	if err == nil {
+		return h, true, nil
+	}
+
+	return header{}, false, ErrInvalidHeader
+}
+
+// skipMessageKeys stores the message keys of the receiving chain up until
+// (but excluding) the message number until.
+func (s *Session) skipMessageKeys(until uint32) error {
+	if uint64(s.nr)+MaxSkip < uint64(until) {
+		return ErrTooManySkipped
+	}
+
+	if s.ckr == nil {
+		return nil
+	}
+
+	for s.nr < until {
+		ckr, mk := kdfCK(*s.ckr)
+		s.ckr = &ckr
+		s.skipped[skippedKey{hk: *s.hkr, n: s.nr}] = mk
+		s.nr += 1
+	}
+
+	return nil
+}
+
+// dhRatchet performs a DH ratchet step using the peer's new ratchet public key.
+func (s *Session) dhRatchet(h header) error {
+	s.pn = s.ns
+	s.ns = 0
+	s.nr = 0
+
+	hks := s.nhks
+	s.hks = &hks
+	hkr := s.nhkr
+	s.hkr = &hkr
+
+	s.dhr = h.dh
+
+	dh, err := x25519.SharedSecret(s.dhsPrivate, s.dhr)
+	if err != nil {
+		return err
+	}
+
+	rk, ckr, nhkr := kdfRK(s.rk, dh)
+	s.rk = rk
+	s.ckr = &ckr
+	s.nhkr = nhkr
+
+	private, public, err := x25519.GenerateKey()
+	if err != nil {
+		return err
+	}
+
+	s.dhsPrivate = private
+	s.dhsPublic = public
+
+	dh, err = x25519.SharedSecret(s.dhsPrivate, s.dhr)
+	if err != nil {
+		return err
+	}
+
+	rk, cks, nhks := kdfRK(s.rk, dh)
+	s.rk = rk
+	s.cks = &cks
+	s.nhks = nhks
+
+	return nil
+}
+
+// clone creates a deep copy of the session.
+func (s *Session) clone() *Session {
+	c := *s
+	c.cks = clonePtr(s.cks)
+	c.ckr = clonePtr(s.ckr)
+	c.hks = clonePtr(s.hks)
+	c.hkr = clonePtr(s.hkr)
+	c.skipped = maps.Clone(s.skipped)
+
+	return &c
+}
+
+// clonePtr returns a pointer to a copy of the value p points to (or nil).
+func clonePtr(p *[32]byte) *[32]byte {
+	if p == nil {
+		return nil
+	}
+
+	c := *p
+
+	return &c
+}
+
+// kdfRK derives a new root key, chain key and next header key from the root
+// key and the output of a Diffie-Hellman computation.
+func kdfRK(rk [32]byte, dh [32]byte) ([32]byte, [32]byte, [32]byte) {
+	// The output length is well below the limit so that no error can occur.
+	okm, _ := hkdf.Key(sha256.New, dh[:], rk[:], rootInfo, 96)
+
+	return [32]byte(okm[0:32]), [32]byte(okm[32:64]), [32]byte(okm[64:96])
+}
+
+// kdfCK derives the next chain key and a message key from the chain key.
+func kdfCK(ck [32]byte) ([32]byte, [32]byte) {
+	mac := hmac.New(sha256.New, ck[:])
+	mac.Write([]byte{0x01})
+	mk := [32]byte(mac.Sum(nil))
+
+	mac.Reset()
+	mac.Write([]byte{0x02})
+	next := [32]byte(mac.Sum(nil))
+
+	return next, mk
+}
+
+// encrypt encrypts the plaintext with a key and nonce derived from the message key.
+// The result is the ciphertext followed by the tag.
+func encrypt(mk [32]byte, plaintext []byte, ad []byte) []byte {
+	key, nonce := messageKeyNonce(mk)
+
+	xchaPoly := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce)
+	ciphertext, tag := xchaPoly.Encrypt(plaintext, ad)
+
+	return append(ciphertext, tag[:]...)
+}
+
+// decrypt decrypts the ciphertext (followed by the tag) with a key and nonce
+// derived from the message key.
+func decrypt(mk [32]byte, ciphertext []byte, ad []byte) ([]byte, error) {
+	if len(ciphertext) < tagSize {
+		return []byte{}, ErrInvalidMessage
+	}
+
+	key, nonce := messageKeyNonce(mk)
+
+	tag := [16]byte(ciphertext[len(ciphertext)-tagSize:])
+	ciphertext = ciphertext[:len(ciphertext)-tagSize]
+
+	xchaPoly := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce)
+	plaintext, err := xchaPoly.Decrypt(ciphertext, ad, tag)
+	if err != nil {
+		return []byte{}, ErrInvalidMessage
+	}
+
+	return plaintext, nil
+}
+
+// messageKeyNonce derives the XChaCha20-Poly1305 key and nonce from the message
+// key. Deriving the nonce is safe given that every message key is only used once.
+func messageKeyNonce(mk [32]byte) ([32]byte, [24]byte) {
+	// The output length is well below the limit so that no error can occur.
+	okm, _ := hkdf.Key(sha256.New, mk[:], nil, messageInfo, 32+nonceSize)
+
+	return [32]byte(okm[0:32]), [24]byte(okm[32:56])
+}
+
+// encryptHeader encrypts the header with the header key using a random nonce.
+// The result is the nonce followed by the encrypted header and its tag.
+func encryptHeader(hk [32]byte, h header) ([]byte, error) {
+	var nonce [24]byte
+
+	err := random.Read(nonce[:])
+	if err != nil {
+		return []byte{}, err
+	}
+
+	plaintext := make([]byte, headerSize)
+	copy(plaintext[0:32], h.dh[:])
+	binary.LittleEndian.PutUint32(plaintext[32:36], h.pn)
+	binary.LittleEndian.PutUint32(plaintext[36:40], h.n)
+
+	xchaPoly := xchacha20poly1305.NewXChaCha20Poly1305(hk, nonce)
+	ciphertext, tag := xchaPoly.Encrypt(plaintext, nil)
+
+	result := make([]byte, 0, EncryptedHeaderSize)
+	result = append(result, nonce[:]...)
+	result = append(result, ciphertext...)
+	result = append(result, tag[:]...)
+
+	return result, nil
+}
+
+// decryptHeader decrypts the encrypted header with the header key.
+func decryptHeader(hk [32]byte, encHeader []byte) (header, error) {
+	nonce := [24]byte(encHeader[0:nonceSize])
+	ciphertext := encHeader[nonceSize : nonceSize+headerSize]
+	tag := [16]byte(encHeader[nonceSize+headerSize:])
+
+	xchaPoly := xchacha20poly1305.NewXChaCha20Poly1305(hk, nonce)
+	plaintext, err := xchaPoly.Decrypt(ciphertext, nil, tag)
+	if err != nil {
+		return header{}, ErrInvalidHeader
+	}
+
+	return header{
+		dh: [32]byte(plaintext[0:32]),
+		pn: binary.LittleEndian.Uint32(plaintext[32:36]),
+		n:  binary.LittleEndian.Uint32(plaintext[36:40]),
+	}, nil
+}
+
+// concat concatenates a and b into a new byte slice.
+func concat(a []byte, b []byte) []byte {
+	result := make([]byte, 0, len(a)+len(b))
+	result = append(result, a...)
+	result = append(result, b...)
+
+	return result
+}
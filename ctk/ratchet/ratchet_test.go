@@ -0,0 +1,204 @@
+package ratchet_test
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/ratchet"
+	"github.com/pmuens/ctk-go/ctk/x25519"
+)
+
+// newSessions creates a connected pair of sessions (Alice sends first).
+func newSessions(t *testing.T) (*ratchet.Session, *ratchet.Session) {
+	t.Helper()
+
+	sharedSecret := [32]byte{0x01}
+	sharedHeaderKey := [32]byte{0x02}
+	sharedNextHeaderKey := [32]byte{0x03}
+
+	bobPrivate, bobPublic, err := x25519.GenerateKey()
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	alice, err := ratchet.NewSender(sharedSecret, bobPublic, sharedHeaderKey, sharedNextHeaderKey)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	bob := ratchet.NewReceiver(sharedSecret, bobPrivate, sharedHeaderKey, sharedNextHeaderKey)
+
+	return alice, bob
+}
+
+func TestRatchet(t *testing.T) {
+	t.Run("Encryption + Decryption", func(t *testing.T) {
+		t.Parallel()
+
+		alice, bob := newSessions(t)
+		aad := []byte("aad")
+
+		for i := range 3 {
+			data := []byte(fmt.Sprintf("alice %d", i))
+
+			message, _ := alice.Encrypt(data, aad)
+			plaintext, err := bob.Decrypt(message, aad)
+
+			if !slices.Equal(plaintext, data) {
+				t.Errorf("want %v, got %v (error %v)", data, plaintext, err)
+			}
+
+			data = []byte(fmt.Sprintf("bob %d", i))
+
+			message, _ = bob.Encrypt(data, aad)
+			plaintext, err = alice.Decrypt(message, aad)
+
+			if !slices.Equal(plaintext, data) {
+				t.Errorf("want %v, got %v (error %v)", data, plaintext, err)
+			}
+		}
+	})
+
+	t.Run("Out Of Order", func(t *testing.T) {
+		t.Parallel()
+
+		alice, bob := newSessions(t)
+
+		var messages [][]byte
+		for i := range 5 {
+			message, _ := alice.Encrypt([]byte{byte(i)}, nil)
+			messages = append(messages, message)
+		}
+
+		for _, i := range []int{3, 0, 4, 2, 1} {
+			plaintext, err := bob.Decrypt(messages[i], nil)
+
+			got := plaintext
+			want := []byte{byte(i)}
+
+			if !slices.Equal(got, want) {
+				t.Errorf("want %v, got %v (error %v)", want, got, err)
+			}
+		}
+
+		// Messages from a previous chain can still be decrypted after a DH
+		// ratchet step.
+		late, _ := alice.Encrypt([]byte("late"), nil)
+		reply, _ := bob.Encrypt([]byte("reply"), nil)
+		alice.Decrypt(reply, nil)
+		next, _ := alice.Encrypt([]byte("next"), nil)
+
+		for _, tc := range []struct {
+			message []byte
+			want    []byte
+		}{
+			{message: next, want: []byte("next")},
+			{message: late, want: []byte("late")},
+		} {
+			got, err := bob.Decrypt(tc.message, nil)
+
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("want %v, got %v (error %v)", tc.want, got, err)
+			}
+		}
+	})
+
+	t.Run("Replay", func(t *testing.T) {
+		t.Parallel()
+
+		alice, bob := newSessions(t)
+
+		message, _ := alice.Encrypt([]byte("hello"), nil)
+		bob.Decrypt(message, nil)
+
+		_, err := bob.Decrypt(message, nil)
+
+		gotError := err
+		wantError := ratchet.ErrInvalidMessage
+
+		if !errors.Is(gotError, wantError) {
+			t.Errorf("want error %v, got %v", wantError, gotError)
+		}
+	})
+
+	t.Run("Invalid AAD", func(t *testing.T) {
+		t.Parallel()
+
+		alice, bob := newSessions(t)
+
+		message, _ := alice.Encrypt([]byte("hello"), []byte("aad"))
+		_, err := bob.Decrypt(message, []byte("other"))
+
+		gotError := err
+		wantError := ratchet.ErrInvalidMessage
+
+		if !errors.Is(gotError, wantError) {
+			t.Errorf("want error %v, got %v", wantError, gotError)
+		}
+
+		// A failed decryption must not modify the session's state.
+		plaintext, err := bob.Decrypt(message, []byte("aad"))
+
+		got := plaintext
+		want := []byte("hello")
+
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v (error %v)", want, got, err)
+		}
+	})
+
+	t.Run("Invalid Header", func(t *testing.T) {
+		t.Parallel()
+
+		alice, bob := newSessions(t)
+
+		message, _ := alice.Encrypt([]byte("hello"), nil)
+		message[ratchet.EncryptedHeaderSize-1] ^= 0x01
+
+		_, err := bob.Decrypt(message, nil)
+
+		gotError := err
+		wantError := ratchet.ErrInvalidMessage
+
+		if !errors.Is(gotError, wantError) {
+			t.Errorf("want error %v, got %v", wantError, gotError)
+		}
+	})
+
+	t.Run("Too Many Skipped", func(t *testing.T) {
+		t.Parallel()
+
+		alice, bob := newSessions(t)
+
+		for range ratchet.MaxSkip + 1 {
+			alice.Encrypt(nil, nil)
+		}
+
+		message, _ := alice.Encrypt(nil, nil)
+		_, err := bob.Decrypt(message, nil)
+
+		gotError := err
+		wantError := ratchet.ErrTooManySkipped
+
+		if !errors.Is(gotError, wantError) {
+			t.Errorf("want error %v, got %v", wantError, gotError)
+		}
+	})
+
+	t.Run("Receiver Not Initialized", func(t *testing.T) {
+		t.Parallel()
+
+		_, bob := newSessions(t)
+
+		_, err := bob.Encrypt([]byte("hello"), nil)
+
+		gotError := err
+		wantError := ratchet.ErrNotInitialized
+
+		if !errors.Is(gotError, wantError) {
+			t.Errorf("want error %v, got %v", wantError, gotError)
+		}
+	})
+}
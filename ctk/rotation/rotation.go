@@ -0,0 +1,189 @@
+// Package rotation implements helpers to rotate keys without breaking the
+// decryption of messages that were encrypted with previous keys.
+//
+// Every message starts with the identifier of the key it was encrypted with
+// which is also bound into the additional authenticated data (AAD). An Opener
+// holds the current key as well as previous keys and picks the candidates with
+// a matching identifier (trying them in order) during decryption.
+//
+// Messages are encrypted via XChaCha20-Poly1305 with a random nonce and have
+// the format key ID (4 bytes, big endian) | nonce (24 bytes) | ciphertext | tag.
+package rotation
+
+import (
+	"encoding/binary"
+
+	"github.com/pmuens/ctk-go/ctk/random"
+	"github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
+)
+
+const (
+	// ErrNoKeys is returned if an Opener without keys is used.
+	ErrNoKeys = Error("no keys")
+
+	// ErrUnknownKey is returned if the message was encrypted with a key that
+	// the Opener doesn't know about.
+	ErrUnknownKey = Error("unknown key")
+
+	// ErrInvalidMessage is returned if the message is malformed or can't be
+	// authenticated with any of the candidate keys.
+	ErrInvalidMessage = Error("invalid message")
+)
+
+// KeyIDSize is the size (in bytes) of the key identifier.
+const KeyIDSize = 4
+
+// NonceSize is the size (in bytes) of the nonce.
+const NonceSize = 24
+
+// TagSize is the size (in bytes) of the tag.
+const TagSize = 16
+
+// Overhead is the number of bytes a message is longer than its plaintext.
+const Overhead = KeyIDSize + NonceSize + TagSize
+
+// Key is a key with its identifier.
+type Key struct {
+	// ID identifies the key.
+	ID uint32
+
+	// Material is the key material.
+	Material [32]byte
+}
+
+// Seal encrypts the plaintext with the key and binds the additional
+// authenticated data (AAD) as well as the key's identifier to it.
+func Seal(key Key, plaintext []byte, aad []byte) ([]byte, error) {
+	var nonce [24]byte
+
+	err := random.Read(nonce[:])
+	if err != nil {
+		return []byte{}, err
+	}
+
+	result := make([]byte, KeyIDSize, len(plaintext)+Overhead)
+	binary.BigEndian.PutUint32(result, key.ID)
+
+	xchaPoly := xchacha20poly1305.NewXChaCha20Poly1305(key.Material, nonce)
+	ciphertext, tag := xchaPoly.Encrypt(plaintext, bindKeyID(result[:KeyIDSize], aad))
+
+	result = append(result, nonce[:]...)
+	result = append(result, ciphertext...)
+	result = append(result, tag[:]...)
+
+	return result, nil
+}
+
+// KeyID returns the identifier of the key the message was encrypted with.
+// Returns an error if the message is too short.
+func KeyID(message []byte) (uint32, error) {
+	if len(message) < Overhead {
+		return 0, ErrInvalidMessage
+	}
+
+	return binary.BigEndian.Uint32(message[:KeyIDSize]), nil
+}
+
+// Opener holds the current key as well as previous keys.
+type Opener struct {
+	// keys are the candidate keys (current key first).
+	keys []Key
+}
+
+// NewOpener creates a new Opener with the current key followed by previous keys.
+func NewOpener(current Key, previous ...Key) *Opener {
+	keys := make([]Key, 0, len(previous)+1)
+	keys = append(keys, current)
+	keys = append(keys, previous...)
+
+	return &Opener{keys: keys}
+}
+
+// Rotate makes the key the current key. The previously current key is kept as
+// the most recent previous key.
+func (o *Opener) Rotate(key Key) {
+	o.keys = append([]Key{key}, o.keys...)
+}
+
+// Retire removes all keys with the identifier (including the current key).
+func (o *Opener) Retire(id uint32) {
+	keys := o.keys[:0]
+	for _, key := range o.keys {
+		if key.ID != id {
+			keys = append(keys, key)
+		}
+	}
+
+	// Wipe the keys that were removed.
+	clear(o.keys[len(keys):])
+	o.keys = keys
+}
+
+// Current returns the current key.
+// Returns an error if the Opener doesn't hold any keys.
+func (o *Opener) Current() (Key, error) {
+	if len(o.keys) == 0 {
+		return Key{}, ErrNoKeys
+	}
+
+	return o.keys[0], nil
+}
+
+// Seal encrypts the plaintext with the current key.
+// Returns an error if the Opener doesn't hold any keys.
+func (o *Opener) Seal(plaintext []byte, aad []byte) ([]byte, error) {
+	key, err := o.Current()
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return Seal(key, plaintext, aad)
+}
+
+// Open decrypts the message by trying all keys with a matching identifier in
+// order (current key first).
+// Returns an error if there's no candidate key or none of them can
+// authenticate the message.
+func (o *Opener) Open(message []byte, aad []byte) ([]byte, error) {
+	id, err := KeyID(message)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	idBytes := message[:KeyIDSize]
+	nonce := [24]byte(message[KeyIDSize : KeyIDSize+NonceSize])
+	ciphertext := message[KeyIDSize+NonceSize : len(message)-TagSize]
+	tag := [16]byte(message[len(message)-TagSize:])
+	boundAad := bindKeyID(idBytes, aad)
+
+	found := false
+
+	for _, key := range o.keys {
+		if key.ID != id {
+			continue
+		}
+		found = true
+
+		xchaPoly := xchacha20poly1305.NewXChaCha20Poly1305(key.Material, nonce)
+		plaintext, err := xchaPoly.Decrypt(ciphertext, boundAad, tag)
+		if err == nil {
+			return plaintext, nil
+		}
+	}
+
+	if !found {
+		return []byte{}, ErrUnknownKey
+	}
+
+	return []byte{}, ErrInvalidMessage
+}
+
+// bindKeyID prepends the encoded key identifier to the additional
+// authenticated data (AAD).
+func bindKeyID(id []byte, aad []byte) []byte {
+	result := make([]byte, 0, len(id)+len(aad))
+	result = append(result, id...)
+	result = append(result, aad...)
+
+	return result
+}
@@ -0,0 +1,120 @@
+package rotation_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/rotation"
+)
+
+var (
+	key1 = rotation.Key{ID: 1, Material: [32]byte{0x01}}
+	key2 = rotation.Key{ID: 2, Material: [32]byte{0x02}}
+	key3 = rotation.Key{ID: 3, Material: [32]byte{0x03}}
+)
+
+func TestOpener(t *testing.T) {
+	t.Run("Rotation", func(t *testing.T) {
+		t.Parallel()
+
+		data := []byte("hello")
+		aad := []byte("aad")
+
+		opener := rotation.NewOpener(key1)
+		old, _ := opener.Seal(data, aad)
+
+		opener.Rotate(key2)
+		current, _ := opener.Seal(data, aad)
+
+		id, _ := rotation.KeyID(current)
+		if id != key2.ID {
+			t.Errorf("want key ID %v, got %v", key2.ID, id)
+		}
+
+		for name, message := range map[string][]byte{"Old": old, "Current": current} {
+			got, err := opener.Open(message, aad)
+
+			if !slices.Equal(got, data) {
+				t.Errorf("%v: want %v, got %v (error %v)", name, data, got, err)
+			}
+		}
+	})
+
+	t.Run("Same ID Candidates", func(t *testing.T) {
+		t.Parallel()
+
+		data := []byte("hello")
+
+		// Both keys use the same identifier so that both need to be tried.
+		other := rotation.Key{ID: key1.ID, Material: [32]byte{0xff}}
+		message, _ := rotation.Seal(key1, data, nil)
+
+		opener := rotation.NewOpener(other, key1)
+		got, err := opener.Open(message, nil)
+
+		if !slices.Equal(got, data) {
+			t.Errorf("want %v, got %v (error %v)", data, got, err)
+		}
+	})
+
+	t.Run("Retire", func(t *testing.T) {
+		t.Parallel()
+
+		opener := rotation.NewOpener(key2, key1)
+		message, _ := rotation.Seal(key1, []byte("hello"), nil)
+
+		opener.Retire(key1.ID)
+
+		_, err := opener.Open(message, nil)
+
+		gotError := err
+		wantError := rotation.ErrUnknownKey
+
+		if !errors.Is(gotError, wantError) {
+			t.Errorf("want error %v, got %v", wantError, gotError)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		opener := rotation.NewOpener(key2, key1)
+
+		sealed, _ := rotation.Seal(key1, []byte("hello"), []byte("aad"))
+		unknown, _ := rotation.Seal(key3, []byte("hello"), nil)
+
+		// Changing the key identifier invalidates the message given that it's
+		// bound into the AAD.
+		relabeled := slices.Clone(sealed)
+		relabeled[3] = byte(key2.ID)
+
+		tt := map[string]struct {
+			message []byte
+			aad     []byte
+			err     error
+		}{
+			"Unknown Key": {message: unknown, aad: nil, err: rotation.ErrUnknownKey},
+			"Wrong AAD":   {message: sealed, aad: []byte("other"), err: rotation.ErrInvalidMessage},
+			"Relabeled":   {message: relabeled, aad: []byte("aad"), err: rotation.ErrInvalidMessage},
+			"Truncated":   {message: sealed[:rotation.Overhead-1], aad: []byte("aad"), err: rotation.ErrInvalidMessage},
+		}
+
+		for name, tc := range tt {
+			_, err := opener.Open(tc.message, tc.aad)
+
+			if !errors.Is(err, tc.err) {
+				t.Errorf("%v: want error %v, got %v", name, tc.err, err)
+			}
+		}
+
+		empty := rotation.NewOpener(key1)
+		empty.Retire(key1.ID)
+
+		_, err := empty.Seal([]byte("hello"), nil)
+
+		if !errors.Is(err, rotation.ErrNoKeys) {
+			t.Errorf("want error %v, got %v", rotation.ErrNoKeys, err)
+		}
+	})
+}
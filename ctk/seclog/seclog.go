@@ -0,0 +1,364 @@
+// Package seclog implements an append-only encrypted log whose records are
+// chained so that modifications of a log file are detected when it's read.
+//
+// Header: A log starts with a header that holds a random log ID:
+//
+//	magic (4) | version (1) | log ID (16)
+//
+// The key of a log is derived via HKDF-SHA256 from the key with the log ID as
+// the salt so that logs which are encrypted with the same key use different
+// keys.
+//
+// Records: Every record (i.e. every call to Write) is stored as a frame:
+//
+//	length (4, big endian) | ciphertext | tag (16)
+//
+// Frames are encrypted via XChaCha20-Poly1305. The nonce is the frame's index
+// and the additional authenticated data (AAD) is the header, the length and the
+// tag of the previous frame. As every frame is chained to the one before it,
+// frames that are reordered, dropped, duplicated or copied from another log
+// fail authentication.
+//
+// Truncation: An empty frame is written when the Writer is closed. A log that
+// doesn't end with such a frame was truncated (or the Writer wasn't closed,
+// e.g. because the process crashed) which the Reader reports via ErrTruncated
+// after all intact records were returned. Note that a log which is truncated
+// right after an empty frame of a previous (resumed) Writer can only be
+// detected by comparing the number of records (see Reader.Records) or the last
+// tag (see Reader.Tag) with a value that's stored elsewhere.
+package seclog
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"slices"
+
+	"github.com/pmuens/ctk-go/ctk/hkdf"
+	"github.com/pmuens/ctk-go/ctk/random"
+	"github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
+)
+
+const (
+	// ErrInvalidHeader is returned if the log header is malformed or uses an
+	// unsupported version.
+	ErrInvalidHeader = Error("invalid log header")
+
+	// ErrDecryption is returned if a record can't be authenticated (e.g. because
+	// the log was tampered with).
+	ErrDecryption = Error("log record authentication failed")
+
+	// ErrTruncated is returned if the log doesn't end with the frame that's
+	// written when the Writer is closed.
+	ErrTruncated = Error("log truncated")
+
+	// ErrRecordTooLarge is returned if a record exceeds MaxRecordSize.
+	ErrRecordTooLarge = Error("log record too large")
+
+	// ErrClosed is returned if a record is written to a closed Writer.
+	ErrClosed = Error("log writer is closed")
+
+	// ErrTooManyRecords is returned if a log exceeds the number of frames that
+	// can be indexed.
+	ErrTooManyRecords = Error("too many log records")
+)
+
+// Magic identifies the log format.
+const Magic = "CTKL"
+
+// Version is the version of the log format.
+const Version = 1
+
+// IDSize is the size (in bytes) of the random log ID.
+const IDSize = 16
+
+// HeaderSize is the size (in bytes) of the log header.
+const HeaderSize = len(Magic) + 1 + IDSize
+
+// TagSize is the size (in bytes) of the tag that's appended to every frame.
+const TagSize = 16
+
+// MaxRecordSize is the largest size (in bytes) of a record.
+const MaxRecordSize = 1024 * 1024
+
+// lengthSize is the size (in bytes) of the frame length.
+const lengthSize = 4
+
+// info is used for domain separation in the derivation of the log key.
+var info = []byte("ctk-go seclog")
+
+// chain holds the state that's shared by a Writer and a Reader of a log.
+type chain struct {
+	// header is the log header.
+	header []byte
+
+	// key is the key of the log.
+	key [32]byte
+
+	// counter is the index of the next frame.
+	counter uint64
+
+	// tag is the tag of the previous frame (zero for the first frame).
+	tag [TagSize]byte
+}
+
+// newChain derives the key of the log with the header.
+func newChain(key [32]byte, header []byte) chain {
+	logKey, _ := hkdf.Key(sha256.New, key[:], header[len(Magic)+1:], info, 32)
+
+	return chain{
+		header: header,
+		key:    [32]byte(logKey),
+	}
+}
+
+// aad returns the AAD of the next frame with the length.
+func (c *chain) aad(length []byte) []byte {
+	return slices.Concat(c.header, length, c.tag[:])
+}
+
+// nonce returns the nonce of the next frame. It's the index (8 bytes, big
+// endian) which is zero padded to 24 bytes.
+func (c *chain) nonce() [24]byte {
+	var nonce [24]byte
+	binary.BigEndian.PutUint64(nonce[16:], c.counter)
+
+	return nonce
+}
+
+// Writer appends encrypted records to a log.
+// A Writer isn't safe for concurrent use (use a log.Logger which serializes its
+// writes to share it).
+type Writer struct {
+	// w is the underlying writer.
+	w io.Writer
+
+	// chain is the state of the log.
+	chain chain
+
+	// closed indicates whether the empty frame was written.
+	closed bool
+}
+
+// NewWriter creates a new log and writes its header to w right away.
+// The records are encrypted with a key that's derived from the key.
+func NewWriter(w io.Writer, key [32]byte) (*Writer, error) {
+	id, err := random.Bytes(IDSize)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, HeaderSize)
+	header = append(header, Magic...)
+	header = append(header, Version)
+	header = append(header, id...)
+
+	_, err = w.Write(header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Writer{
+		w:     w,
+		chain: newChain(key, header),
+	}, nil
+}
+
+// ResumeWriter reads the log from r and returns a Writer which appends records
+// to w (e.g. the same file opened in append mode).
+// Returns an error if the log can't be authenticated or if it wasn't closed
+// (ErrTruncated) so that a truncated log isn't continued as if it was intact.
+func ResumeWriter(r io.Reader, w io.Writer, key [32]byte) (*Writer, error) {
+	lr, err := NewReader(r, key)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		_, err = lr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Writer{
+		w:     w,
+		chain: lr.chain,
+	}, nil
+}
+
+// Write encrypts p as a single record and appends it to the log. Empty records
+// aren't written (an empty frame marks a closed Writer).
+// Returns ErrRecordTooLarge if p exceeds MaxRecordSize.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, ErrClosed
+	}
+
+	if len(p) > MaxRecordSize {
+		return 0, ErrRecordTooLarge
+	}
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	err := w.writeFrame(p)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Close writes the empty frame that marks the end of the log. The underlying
+// writer isn't closed.
+func (w *Writer) Close() error {
+	if w.closed {
+		return ErrClosed
+	}
+
+	err := w.writeFrame([]byte{})
+	if err != nil {
+		return err
+	}
+
+	w.closed = true
+
+	return nil
+}
+
+// writeFrame encrypts and writes a single frame. The frame is written with a
+// single Write call.
+func (w *Writer) writeFrame(plaintext []byte) error {
+	if w.chain.counter == ^uint64(0) {
+		return ErrTooManyRecords
+	}
+
+	length := binary.BigEndian.AppendUint32(nil, uint32(len(plaintext)))
+
+	frame := make([]byte, 0, lengthSize+len(plaintext)+TagSize)
+	frame = append(frame, length...)
+	frame = xchacha20poly1305.NewXChaCha20Poly1305(w.chain.key, w.chain.nonce()).Seal(frame, plaintext, w.chain.aad(length))
+
+	_, err := w.w.Write(frame)
+	if err != nil {
+		return err
+	}
+
+	w.chain.counter++
+	w.chain.tag = [TagSize]byte(frame[len(frame)-TagSize:])
+
+	return nil
+}
+
+// Reader reads the records of a log.
+// A Reader isn't safe for concurrent use.
+type Reader struct {
+	// r is the underlying reader.
+	r io.Reader
+
+	// chain is the state of the log.
+	chain chain
+
+	// closed indicates whether the previous frame was the empty frame.
+	closed bool
+
+	// err is the error that occurred while reading (returned on every call).
+	err error
+}
+
+// NewReader creates a new Reader which reads the log header from r right away.
+// Returns an error if the header is malformed.
+func NewReader(r io.Reader, key [32]byte) (*Reader, error) {
+	header := make([]byte, HeaderSize)
+
+	_, err := io.ReadFull(r, header)
+	if err != nil || string(header[:len(Magic)]) != Magic || header[len(Magic)] != Version {
+		return nil, ErrInvalidHeader
+	}
+
+	return &Reader{
+		r:     r,
+		chain: newChain(key, header),
+	}, nil
+}
+
+// Next returns the next record.
+// Returns io.EOF once the log ends after the frame that's written when the
+// Writer is closed, ErrTruncated if it ends without it and ErrDecryption if a
+// frame can't be authenticated.
+func (r *Reader) Next() ([]byte, error) {
+	for r.err == nil {
+		var record []byte
+
+		record, r.err = r.readFrame()
+		if r.err == nil && len(record) > 0 {
+			return record, nil
+		}
+	}
+
+	return []byte{}, r.err
+}
+
+// Records returns the number of frames (including the empty frames of closed
+// Writers) that were authenticated so far.
+func (r *Reader) Records() uint64 {
+	return r.chain.counter
+}
+
+// Tag returns the tag of the last frame that was authenticated. Storing the tag
+// of the last frame elsewhere (e.g. in a database) allows to detect that a log
+// was truncated to an earlier closed state.
+func (r *Reader) Tag() [TagSize]byte {
+	return r.chain.tag
+}
+
+// readFrame reads and decrypts a single frame.
+func (r *Reader) readFrame() ([]byte, error) {
+	if r.chain.counter == ^uint64(0) {
+		return []byte{}, ErrTooManyRecords
+	}
+
+	length := make([]byte, lengthSize)
+
+	_, err := io.ReadFull(r.r, length)
+	if err == io.EOF && r.closed {
+		return []byte{}, io.EOF
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return []byte{}, ErrTruncated
+	}
+	if err != nil {
+		return []byte{}, err
+	}
+
+	size := binary.BigEndian.Uint32(length)
+	if size > MaxRecordSize {
+		return []byte{}, ErrRecordTooLarge
+	}
+
+	sealed := make([]byte, int(size)+TagSize)
+
+	_, err = io.ReadFull(r.r, sealed)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return []byte{}, ErrTruncated
+	}
+	if err != nil {
+		return []byte{}, err
+	}
+
+	plaintext, err := xchacha20poly1305.NewXChaCha20Poly1305(r.chain.key, r.chain.nonce()).Open(nil, sealed, r.chain.aad(length))
+	if err != nil {
+		return []byte{}, ErrDecryption
+	}
+
+	r.chain.counter++
+	r.chain.tag = [TagSize]byte(sealed[size:])
+	r.closed = len(plaintext) == 0
+
+	return plaintext, nil
+}
@@ -0,0 +1,247 @@
+package seclog_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/seclog"
+)
+
+// frameSize is the size (in bytes) of the frame of a record.
+func frameSize(record string) int {
+	return 4 + len(record) + seclog.TagSize
+}
+
+// createLog writes the records to a new log and closes it.
+func createLog(t *testing.T, key [32]byte, records ...string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	w, err := seclog.NewWriter(&buf, key)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	for _, record := range records {
+		w.Write([]byte(record))
+	}
+
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	return buf.Bytes()
+}
+
+// readLog reads all records of the log and returns them with the error that
+// ended the log.
+func readLog(data []byte, key [32]byte) ([]string, error) {
+	r, err := seclog.NewReader(bytes.NewReader(data), key)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []string
+	for {
+		record, err := r.Next()
+		if err != nil {
+			return records, err
+		}
+
+		records = append(records, string(record))
+	}
+}
+
+func TestLog(t *testing.T) {
+	key := [32]byte{0x01, 0x02, 0x03}
+	records := []string{"user alice logged in", "user bob logged in", "user alice logged out"}
+
+	data := createLog(t, key, records...)
+
+	// Offsets of the frames.
+	first := seclog.HeaderSize
+	second := first + frameSize(records[0])
+	third := second + frameSize(records[1])
+	end := third + frameSize(records[2])
+
+	t.Run("Write + Read", func(t *testing.T) {
+		t.Parallel()
+
+		got, err := readLog(data, key)
+		if err != io.EOF || !slices.Equal(got, records) {
+			t.Errorf("want %v, got %v (error %v)", records, got, err)
+		}
+	})
+
+	t.Run("Logger", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+
+		w, _ := seclog.NewWriter(&buf, key)
+		logger := log.New(w, "", 0)
+		logger.Print("first")
+		logger.Print("second")
+		w.Close()
+
+		got, err := readLog(buf.Bytes(), key)
+		if err != io.EOF || !slices.Equal(got, []string{"first\n", "second\n"}) {
+			t.Errorf("want %v, got %v (error %v)", []string{"first\n", "second\n"}, got, err)
+		}
+	})
+
+	t.Run("Tampering", func(t *testing.T) {
+		t.Parallel()
+
+		tampered := slices.Clone(data)
+		tampered[second+5] ^= 0x01
+
+		tt := map[string]struct {
+			data []byte
+			want []string
+			err  error
+		}{
+			"Modified Record": {tampered, records[:1], seclog.ErrDecryption},
+			"Reordered":       {slices.Concat(data[:first], data[third:end], data[second:third], data[first:second], data[end:]), nil, seclog.ErrDecryption},
+			"Dropped":         {slices.Concat(data[:second], data[third:]), records[:1], seclog.ErrDecryption},
+			"Duplicated":      {slices.Concat(data[:third], data[second:]), records[:2], seclog.ErrDecryption},
+			"Truncated":       {data[:end], records, seclog.ErrTruncated},
+			"Partial Frame":   {data[:end-1], records[:2], seclog.ErrTruncated},
+			"Appended":        {slices.Concat(data, data[first:second]), records, seclog.ErrDecryption},
+		}
+
+		for name, tc := range tt {
+			got, err := readLog(tc.data, key)
+			if !errors.Is(err, tc.err) || !slices.Equal(got, tc.want) {
+				t.Errorf("%v: want %v and error %v, got %v and %v", name, tc.want, tc.err, got, err)
+			}
+		}
+	})
+
+	t.Run("Copied From Another Log", func(t *testing.T) {
+		t.Parallel()
+
+		// Logs have different IDs so that records of one log don't
+		// authenticate in another log with the same key.
+		other := createLog(t, key, records...)
+
+		got, err := readLog(slices.Concat(data[:second], other[second:]), key)
+		if !errors.Is(err, seclog.ErrDecryption) || !slices.Equal(got, records[:1]) {
+			t.Errorf("want %v and error %v, got %v and %v", records[:1], seclog.ErrDecryption, got, err)
+		}
+	})
+
+	t.Run("Wrong Key", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := readLog(data, [32]byte{})
+		if !errors.Is(err, seclog.ErrDecryption) {
+			t.Errorf("want error %v, got %v", seclog.ErrDecryption, err)
+		}
+	})
+
+	t.Run("Invalid Header", func(t *testing.T) {
+		t.Parallel()
+
+		for _, header := range [][]byte{data[:seclog.HeaderSize-1], slices.Concat([]byte("CTKX"), data[4:])} {
+			_, err := seclog.NewReader(bytes.NewReader(header), key)
+			if !errors.Is(err, seclog.ErrInvalidHeader) {
+				t.Errorf("want error %v, got %v", seclog.ErrInvalidHeader, err)
+			}
+		}
+	})
+}
+
+func TestWriter(t *testing.T) {
+	key := [32]byte{0x01, 0x02, 0x03}
+
+	t.Run("Resume", func(t *testing.T) {
+		t.Parallel()
+
+		data := createLog(t, key, "first", "second")
+		buf := bytes.NewBuffer(slices.Clone(data))
+
+		w, err := seclog.ResumeWriter(bytes.NewReader(data), buf, key)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		w.Write([]byte("third"))
+		w.Close()
+
+		r, _ := seclog.NewReader(bytes.NewReader(buf.Bytes()), key)
+
+		var got []string
+		for {
+			record, err := r.Next()
+			if err != nil {
+				if err != io.EOF {
+					t.Errorf("want error %v, got %v", io.EOF, err)
+				}
+				break
+			}
+			got = append(got, string(record))
+		}
+
+		want := []string{"first", "second", "third"}
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+
+		// Three records and two empty frames.
+		if r.Records() != 5 {
+			t.Errorf("want %v, got %v", 5, r.Records())
+		}
+
+		tag := [seclog.TagSize]byte(buf.Bytes()[buf.Len()-seclog.TagSize:])
+		if r.Tag() != tag {
+			t.Errorf("want %v, got %v", tag, r.Tag())
+		}
+	})
+
+	t.Run("Resume Truncated Log", func(t *testing.T) {
+		t.Parallel()
+
+		data := createLog(t, key, "first")
+		data = data[:len(data)-4-seclog.TagSize]
+
+		_, err := seclog.ResumeWriter(bytes.NewReader(data), io.Discard, key)
+		if !errors.Is(err, seclog.ErrTruncated) {
+			t.Errorf("want error %v, got %v", seclog.ErrTruncated, err)
+		}
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		t.Parallel()
+
+		w, _ := seclog.NewWriter(io.Discard, key)
+
+		_, err := w.Write(make([]byte, seclog.MaxRecordSize+1))
+		if !errors.Is(err, seclog.ErrRecordTooLarge) {
+			t.Errorf("want error %v, got %v", seclog.ErrRecordTooLarge, err)
+		}
+
+		n, err := w.Write([]byte{})
+		if n != 0 || err != nil {
+			t.Errorf("want %v and error %v, got %v and %v", 0, nil, n, err)
+		}
+
+		w.Close()
+
+		_, err = w.Write([]byte("after close"))
+		if !errors.Is(err, seclog.ErrClosed) {
+			t.Errorf("want error %v, got %v", seclog.ErrClosed, err)
+		}
+
+		err = w.Close()
+		if !errors.Is(err, seclog.ErrClosed) {
+			t.Errorf("want error %v, got %v", seclog.ErrClosed, err)
+		}
+	})
+}
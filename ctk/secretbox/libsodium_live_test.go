@@ -0,0 +1,46 @@
+//go:build libsodium
+
+package secretbox_test
+
+import (
+	"crypto/rand"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/internal/libsodium"
+	"github.com/pmuens/ctk-go/ctk/secretbox"
+)
+
+func TestSecretBoxLibsodiumLive(t *testing.T) {
+	generator, err := libsodium.NewGenerator()
+	if err != nil {
+		t.Skipf("libsodium isn't available: %v", err)
+	}
+	t.Cleanup(func() { generator.Close() })
+
+	for _, size := range []int{0, 1, 15, 16, 17, 31, 32, 33, 63, 64, 65, 1024, 4096 + 3} {
+		t.Run(fmt.Sprintf("%d byte message", size), func(t *testing.T) {
+			t.Parallel()
+
+			var key [32]byte
+			var nonce [24]byte
+			message := make([]byte, size)
+
+			rand.Read(key[:])
+			rand.Read(nonce[:])
+			rand.Read(message)
+
+			want, err := generator.SecretBox(key, nonce, message)
+			if err != nil {
+				t.Fatalf("want error %v, got %v", nil, err)
+			}
+
+			box := secretbox.Seal(key, nonce, message)
+
+			if !slices.Equal(box, want) {
+				t.Errorf("want %v, got %v", want, box)
+			}
+		})
+	}
+}
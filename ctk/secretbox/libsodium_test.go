@@ -0,0 +1,43 @@
+package secretbox_test
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/internal/libsodium"
+	"github.com/pmuens/ctk-go/ctk/secretbox"
+)
+
+func TestSecretBoxLibsodium(t *testing.T) {
+	vectors, err := libsodium.Load()
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	for i, v := range vectors.SecretBox {
+		name := fmt.Sprintf("#%d (%d byte message)", i, len(v.Message))
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			key := [32]byte(v.Key)
+			nonce := [24]byte(v.Nonce)
+
+			box := secretbox.Seal(key, nonce, v.Message)
+
+			if !slices.Equal(box, v.Box) {
+				t.Errorf("want %v, got %v", []byte(v.Box), box)
+			}
+
+			message, err := secretbox.Open(key, nonce, v.Box)
+			if err != nil {
+				t.Fatalf("want error %v, got %v", nil, err)
+			}
+
+			if !slices.Equal(message, v.Message) {
+				t.Errorf("want %v, got %v", []byte(v.Message), message)
+			}
+		})
+	}
+}
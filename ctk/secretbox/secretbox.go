@@ -0,0 +1,96 @@
+// Package secretbox implements the XChaCha20-Poly1305 variant of NaCl's
+// secretbox as provided by libsodium (crypto_secretbox_xchacha20poly1305).
+//
+// In contrast to the XChaCha20-Poly1305 AEAD, a secret box doesn't support
+// additional authenticated data (AAD) and the Poly1305 tag only covers the
+// ciphertext. The box is the tag followed by the ciphertext (which is libsodium's
+// "easy" format).
+//
+// The original secretbox construction (XSalsa20-Poly1305) isn't supported given
+// that the toolkit doesn't implement Salsa20.
+package secretbox
+
+import (
+	"io"
+
+	"github.com/pmuens/ctk-go/ctk/internal/debug"
+	"github.com/pmuens/ctk-go/ctk/poly1305"
+	"github.com/pmuens/ctk-go/ctk/subtle"
+	"github.com/pmuens/ctk-go/ctk/xchacha20"
+)
+
+const (
+	// ErrInvalidBox is the cause of ErrOpen if the box is too short to contain
+	// a tag (only added in builds with the ctkdebug build tag).
+	ErrInvalidBox = Error("invalid secret box")
+
+	// ErrOpen is returned if the box is malformed or can't be authenticated.
+	ErrOpen = Error("secret box authentication failed")
+)
+
+// KeySize is the size (in bytes) of a key.
+const KeySize = 32
+
+// NonceSize is the size (in bytes) of a nonce.
+const NonceSize = 24
+
+// Overhead is the number of bytes a box is longer than its message (the size of
+// the Poly1305 tag).
+const Overhead = 16
+
+// Seal encrypts and authenticates the message and returns the box (the tag
+// followed by the ciphertext).
+// The nonce must never be reused with the same key. Given its size it can be
+// generated randomly.
+func Seal(key [32]byte, nonce [24]byte, message []byte) []byte {
+	polyKey, keyStream := keyStream(key, nonce)
+
+	ciphertext := make([]byte, len(message))
+	// The keystream only runs out for messages larger than 256 GiB.
+	io.ReadFull(keyStream, ciphertext)
+	subtle.XORBytes(ciphertext, ciphertext, message)
+
+	tag := poly1305.OneTimeAuth(polyKey, ciphertext)
+
+	box := make([]byte, 0, Overhead+len(ciphertext))
+	box = append(box, tag[:]...)
+	box = append(box, ciphertext...)
+
+	return box
+}
+
+// Open authenticates and decrypts the box and returns the message.
+// Returns an error if the box is malformed or can't be authenticated.
+func Open(key [32]byte, nonce [24]byte, box []byte) ([]byte, error) {
+	if len(box) < Overhead {
+		return []byte{}, debug.Detail(ErrOpen, ErrInvalidBox)
+	}
+
+	tag := box[:Overhead]
+	ciphertext := box[Overhead:]
+
+	polyKey, keyStream := keyStream(key, nonce)
+
+	if !poly1305.Verify(polyKey, ciphertext, [16]byte(tag)) {
+		return []byte{}, ErrOpen
+	}
+
+	message := make([]byte, len(ciphertext))
+	io.ReadFull(keyStream, message)
+	subtle.XORBytes(message, message, ciphertext)
+
+	return message, nil
+}
+
+// keyStream returns the Poly1305 key and the keystream the message is encrypted
+// with. The Poly1305 key is made up of the first 32 bytes of the XChaCha20
+// keystream (counter 0) and the message is encrypted with the bytes that follow
+// (rather than starting with the next block as done by the AEAD).
+func keyStream(key [32]byte, nonce [24]byte) (poly1305.OneTimeKey, io.Reader) {
+	keyStream := xchacha20.NewXChaCha20WithCounter(key, nonce, 0).KeystreamReader()
+
+	// A fresh keystream has more than enough bytes for the key.
+	polyKey, _ := poly1305.ReadOneTimeKey(keyStream)
+
+	return polyKey, keyStream
+}
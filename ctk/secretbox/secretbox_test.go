@@ -0,0 +1,63 @@
+package secretbox_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/secretbox"
+)
+
+func TestSecretBox(t *testing.T) {
+	key := [32]byte{0x01}
+	nonce := [24]byte{0x02}
+	message := []byte("Attack at dawn")
+
+	t.Run("Seal + Open", func(t *testing.T) {
+		t.Parallel()
+
+		box := secretbox.Seal(key, nonce, message)
+
+		if len(box) != len(message)+secretbox.Overhead {
+			t.Errorf("want length %v, got %v", len(message)+secretbox.Overhead, len(box))
+		}
+
+		got, err := secretbox.Open(key, nonce, box)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if !slices.Equal(got, message) {
+			t.Errorf("want %v, got %v", message, got)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		box := secretbox.Seal(key, nonce, message)
+
+		tamperedBox := slices.Clone(box)
+		tamperedBox[len(tamperedBox)-1] ^= 0x01
+
+		tt := map[string]struct {
+			key   [32]byte
+			nonce [24]byte
+			box   []byte
+			want  error
+		}{
+			"Tampered Box": {key: key, nonce: nonce, box: tamperedBox, want: secretbox.ErrOpen},
+			"Wrong Key":    {key: [32]byte{0x03}, nonce: nonce, box: box, want: secretbox.ErrOpen},
+			"Wrong Nonce":  {key: key, nonce: [24]byte{0x03}, box: box, want: secretbox.ErrOpen},
+			"Short Box":    {key: key, nonce: nonce, box: box[:secretbox.Overhead-1], want: secretbox.ErrOpen},
+		}
+
+		for name, tc := range tt {
+			_, err := secretbox.Open(tc.key, tc.nonce, tc.box)
+
+			if !errors.Is(err, tc.want) {
+				t.Errorf("%v: want error %v, got %v", name, tc.want, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package secretstream
+
+import (
+	"encoding/binary"
+	"io"
+	"slices"
+
+	"github.com/pmuens/ctk-go/ctk/internal/checkpoint"
+)
+
+const (
+	// ErrInvalidState is returned if an encoded state is malformed or was
+	// modified.
+	ErrInvalidState = Error("invalid secretstream state")
+)
+
+// stateLabel identifies encoded channel states.
+const stateLabel = "ctk-go secretstream"
+
+// stateSize is the minimum size (in bytes) of an encoded state without the
+// checkpoint overhead.
+const stateSize = 32 + 32 + 8 + 8 + 1
+
+// Flags of the encoded state.
+const (
+	flagWriteClosed = 1 << iota
+	flagReadClosed
+)
+
+// MarshalCheckpoint encodes the channel's keys, frame counters, whether a
+// direction was closed and the decrypted data that wasn't read yet so that a
+// long-running transfer can be checkpointed and resumed via Resume:
+//
+//	send key (32) | receive key (32) | send counter (8, big endian) | receive counter (8, big endian) | flags (1) | unread data
+//
+// The state is versioned and authenticated with a MAC whose key is derived
+// from the checkpoint key so that modifications (e.g. rolled back counters) are
+// rejected. The checkpoint key needs to be kept apart from the encoding (and
+// differ from the shared key) as anyone who knows it can forge states.
+//
+// Warning: The encoding contains the keys and needs to be stored as
+// confidentially as the shared key. Resuming the same state more than once and
+// writing different data reuses nonces which breaks the encryption.
+// Returns the read error if reading failed (a broken channel can't be resumed).
+func (c *Conn) MarshalCheckpoint(checkpointKey [32]byte) ([]byte, error) {
+	var flags byte
+	if c.writeClosed {
+		flags |= flagWriteClosed
+	}
+
+	switch c.readErr {
+	case nil:
+	case io.EOF:
+		flags |= flagReadClosed
+	default:
+		return []byte{}, c.readErr
+	}
+
+	state := make([]byte, 0, stateSize+len(c.plaintext))
+	state = append(state, c.sendKey[:]...)
+	state = append(state, c.receiveKey[:]...)
+	state = binary.BigEndian.AppendUint64(state, c.sendCounter)
+	state = binary.BigEndian.AppendUint64(state, c.receiveCounter)
+	state = append(state, flags)
+	state = append(state, c.plaintext...)
+
+	return checkpoint.Encode(stateLabel, state, checkpoint.Key(stateLabel, checkpointKey)), nil
+}
+
+// UnmarshalCheckpoint restores a state that was encoded via MarshalCheckpoint
+// with the checkpoint key. The underlying byte stream is kept (see Resume to
+// create a channel on a new byte stream).
+// Returns an error if the encoding is malformed, was modified or belongs to
+// another checkpoint key.
+func (c *Conn) UnmarshalCheckpoint(checkpointKey [32]byte, data []byte) error {
+	state, ok := checkpoint.Decode(stateLabel, data, stateSize)
+	if !ok || !checkpoint.Verify(data, checkpoint.Key(stateLabel, checkpointKey)) {
+		return ErrInvalidState
+	}
+
+	flags := state[80]
+	if flags&^(flagWriteClosed|flagReadClosed) != 0 || len(state)-stateSize > MaxFrameSize {
+		return ErrInvalidState
+	}
+
+	c.sendKey = [32]byte(state[0:32])
+	c.receiveKey = [32]byte(state[32:64])
+	c.sendCounter = binary.BigEndian.Uint64(state[64:72])
+	c.receiveCounter = binary.BigEndian.Uint64(state[72:80])
+	c.writeClosed = flags&flagWriteClosed != 0
+	c.plaintext = slices.Clone(state[stateSize:])
+	c.established = c.now()
+
+	c.readErr = nil
+	if flags&flagReadClosed != 0 {
+		c.readErr = io.EOF
+	}
+
+	return nil
+}
+
+// Resume restores a channel that was encoded via MarshalCheckpoint with the
+// checkpoint key on the byte stream (e.g. a new connection to the same peer
+// which also resumed its channel). No handshake is performed.
+// Returns an error if the encoding is malformed, was modified or belongs to
+// another checkpoint key.
+func Resume(rw io.ReadWriter, checkpointKey [32]byte, data []byte, opts ...Option) (*Conn, error) {
+	c := &Conn{rw: rw}
+
+	err := c.UnmarshalCheckpoint(checkpointKey, data)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
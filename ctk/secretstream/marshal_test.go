@@ -0,0 +1,136 @@
+package secretstream_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/internal/checkpoint"
+	"github.com/pmuens/ctk-go/ctk/secretstream"
+)
+
+func TestSecretStreamMarshalCheckpoint(t *testing.T) {
+	key := [32]byte{0x01, 0x02, 0x03}
+	checkpointKey := [32]byte{0x04}
+
+	t.Run("Resume", func(t *testing.T) {
+		t.Parallel()
+
+		client, clientRW, server, serverRW := connect(t, key, key)
+
+		var frames bytes.Buffer
+		clientRW.Writer = &frames
+		serverRW.Reader = &frames
+
+		client.Write([]byte("hello"))
+
+		// The server reads part of the first frame before the checkpoint.
+		p := make([]byte, 2)
+		io.ReadFull(server, p)
+
+		clientState, err := client.MarshalCheckpoint(checkpointKey)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		serverState, err := server.MarshalCheckpoint(checkpointKey)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		// Both parties resume on a new byte stream.
+		var resumedFrames bytes.Buffer
+
+		resumedClient, err := secretstream.Resume(&rw{Writer: &resumedFrames}, checkpointKey, clientState)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		resumedServer, err := secretstream.Resume(&rw{Reader: &resumedFrames}, checkpointKey, serverState)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		resumedClient.Write([]byte(" world"))
+		resumedClient.CloseWrite()
+
+		got, err := io.ReadAll(resumedServer)
+		want := []byte("llo world")
+
+		if !slices.Equal(got, want) || err != nil {
+			t.Errorf("want %s, got %s (error %v)", want, got, err)
+		}
+
+		_, err = resumedClient.Write([]byte{0x01})
+		if !errors.Is(err, secretstream.ErrClosed) {
+			t.Errorf("want error %v, got %v", secretstream.ErrClosed, err)
+		}
+	})
+
+	t.Run("Invalid State", func(t *testing.T) {
+		t.Parallel()
+
+		client, _, _, _ := connect(t, key, key)
+		state, _ := client.MarshalCheckpoint(checkpointKey)
+
+		tt := map[string]func(state []byte) []byte{
+			"Label":           func(state []byte) []byte { state[0] ^= 0x01; return state },
+			"Version":         func(state []byte) []byte { state[len("ctk-go secretstream")]++; return state },
+			"Receive Counter": func(state []byte) []byte { state[len(state)-34]++; return state },
+			"Tag":             func(state []byte) []byte { state[len(state)-1] ^= 0x01; return state },
+			"Truncated":       func(state []byte) []byte { return state[:len(state)-1] },
+		}
+
+		for name, tamper := range tt {
+			_, err := secretstream.Resume(&rw{}, checkpointKey, tamper(slices.Clone(state)))
+			if !errors.Is(err, secretstream.ErrInvalidState) {
+				t.Errorf("%v: want error %v, got %v", name, secretstream.ErrInvalidState, err)
+			}
+		}
+	})
+
+	t.Run("Forged State", func(t *testing.T) {
+		t.Parallel()
+
+		client, clientRW, _, _ := connect(t, key, key)
+		clientRW.Writer = io.Discard
+		client.Write([]byte("hello"))
+
+		encoded, _ := client.MarshalCheckpoint(checkpointKey)
+
+		// The send counter is rolled back (which would reuse nonces) and the
+		// state is tagged with the keys the encoding reveals.
+		state, _ := checkpoint.Decode("ctk-go secretstream", encoded, 0)
+		state = slices.Clone(state)
+		binary.BigEndian.PutUint64(state[64:72], 0)
+
+		forged := checkpoint.Encode("ctk-go secretstream", state, state[:64])
+
+		_, err := secretstream.Resume(&rw{}, checkpointKey, forged)
+		if !errors.Is(err, secretstream.ErrInvalidState) {
+			t.Errorf("want error %v, got %v", secretstream.ErrInvalidState, err)
+		}
+
+		_, err = secretstream.Resume(&rw{}, key, encoded)
+		if !errors.Is(err, secretstream.ErrInvalidState) {
+			t.Errorf("want error %v, got %v", secretstream.ErrInvalidState, err)
+		}
+	})
+
+	t.Run("Broken Channel", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, server, serverRW := connect(t, key, key)
+		serverRW.Reader = bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff})
+
+		_, want := server.Read(make([]byte, 1))
+
+		_, err := server.MarshalCheckpoint(checkpointKey)
+		if !errors.Is(err, want) {
+			t.Errorf("want error %v, got %v", want, err)
+		}
+	})
+}
@@ -0,0 +1,361 @@
+// Package secretstream implements an encrypted channel on top of a reliable byte
+// stream (e.g. a TCP connection) between two parties that share a key.
+//
+// Handshake: Both parties send a random 32 byte nonce when the channel is
+// established. A key per direction is derived via HKDF-SHA256 from the shared
+// key with the initiator's and the responder's nonce as the salt. As both
+// parties contribute randomness, a recorded session can't be replayed.
+//
+// Frames: The data is sent as a sequence of frames:
+//
+//	length (4, big endian) | ciphertext | tag (16)
+//
+// Every frame is encrypted via XChaCha20-Poly1305 with the length as the
+// additional authenticated data (AAD). The nonce is the frame's index in its
+// direction which is why frames that are replayed, reordered or dropped fail
+// authentication. An empty frame marks the end of a direction so that a
+// truncated stream is detected.
+//
+// Errors: Frames that are malformed, forged or truncated are all reported as
+// ErrDecryption so that a peer that sees the errors (e.g. because a service
+// forwards them) doesn't learn which check rejected a frame. Builds with the
+// ctkdebug build tag add the actual cause (e.g. ErrTruncated) to the error.
+//
+// Metrics: WithMetrics reports the sealed and opened frames, authentication
+// failures and the age of the keys to a metrics.Sink.
+//
+// Checkpoints: The state of a channel can be encoded via MarshalCheckpoint and
+// restored via Resume so that long-running transfers can continue on a new
+// byte stream without a new handshake. The encodings are authenticated with a
+// separate checkpoint key.
+package secretstream
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/pmuens/ctk-go/ctk/clock"
+	"github.com/pmuens/ctk-go/ctk/hkdf"
+	"github.com/pmuens/ctk-go/ctk/internal/debug"
+	"github.com/pmuens/ctk-go/ctk/metrics"
+	"github.com/pmuens/ctk-go/ctk/random"
+	"github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
+)
+
+const (
+	// ErrDecryption is returned if a frame can't be authenticated, is malformed
+	// or if the stream is truncated.
+	ErrDecryption = Error("frame authentication failed")
+
+	// ErrFrameTooLarge is the cause of ErrDecryption (see Errors) if a frame
+	// exceeds MaxFrameSize.
+	ErrFrameTooLarge = Error("frame too large")
+
+	// ErrTruncated is the cause of ErrDecryption (see Errors) if the stream
+	// ends without an end frame.
+	ErrTruncated = Error("stream truncated")
+
+	// ErrClosed is returned if data is written after CloseWrite was called.
+	ErrClosed = Error("stream closed for writing")
+
+	// ErrTooManyFrames is returned if a direction exceeds the number of frames
+	// that can be indexed.
+	ErrTooManyFrames = Error("too many frames")
+)
+
+// HandshakeNonceSize is the size (in bytes) of the random handshake nonces.
+const HandshakeNonceSize = 32
+
+// MaxFrameSize is the maximum number of plaintext bytes per frame.
+const MaxFrameSize = 64 * 1024
+
+// TagSize is the size (in bytes) of the tag that's appended to every frame.
+const TagSize = 16
+
+// lengthSize is the size (in bytes) of the frame length.
+const lengthSize = 4
+
+// Info strings used for domain separation in the key derivations.
+var (
+	initiatorInfo = []byte("ctk-go secretstream initiator")
+	responderInfo = []byte("ctk-go secretstream responder")
+)
+
+// Conn is an encrypted channel. Reading and writing can happen concurrently,
+// but concurrent calls to Read (or to Write) aren't safe.
+type Conn struct {
+	// rw is the underlying byte stream.
+	rw io.ReadWriter
+
+	// sendKey is the key of the frames that are written.
+	sendKey [32]byte
+
+	// receiveKey is the key of the frames that are read.
+	receiveKey [32]byte
+
+	// sendCounter is the index of the next frame that's written.
+	sendCounter uint64
+
+	// receiveCounter is the index of the next frame that's read.
+	receiveCounter uint64
+
+	// plaintext is the decrypted data that wasn't read yet.
+	plaintext []byte
+
+	// readErr is the error that occurred while reading (returned on every Read).
+	readErr error
+
+	// writeClosed indicates whether the end frame was written.
+	writeClosed bool
+
+	// metrics receives the operations and authentication failures (nil
+	// discards them).
+	metrics metrics.Sink
+
+	// established is the time the keys were derived (or restored).
+	established time.Time
+
+	// clock tells the current time (nil uses clock.System).
+	clock clock.Clock
+}
+
+// Option configures a channel.
+type Option func(*Conn)
+
+// WithMetrics reports the sealed and opened frames, authentication failures
+// and the age of the keys to the sink. The age of restored keys counts from
+// the time they were restored.
+func WithMetrics(sink metrics.Sink) Option {
+	return func(c *Conn) {
+		c.metrics = sink
+	}
+}
+
+// WithClock sets the clock the age of the keys is measured with (clock.System
+// by default), e.g. to test key age metrics without sleeping.
+func WithClock(c clock.Clock) Option {
+	return func(conn *Conn) {
+		conn.clock = c
+	}
+}
+
+// Client establishes a channel as the initiator.
+// Returns an error if the handshake fails.
+func Client(rw io.ReadWriter, key [32]byte, opts ...Option) (*Conn, error) {
+	return handshake(rw, key, true, opts)
+}
+
+// Server establishes a channel as the responder.
+// Returns an error if the handshake fails.
+func Server(rw io.ReadWriter, key [32]byte, opts ...Option) (*Conn, error) {
+	return handshake(rw, key, false, opts)
+}
+
+// handshake exchanges the nonces and derives the keys of both directions.
+func handshake(rw io.ReadWriter, key [32]byte, initiator bool, opts []Option) (*Conn, error) {
+	var ownNonce, peerNonce [HandshakeNonceSize]byte
+
+	err := random.Read(ownNonce[:])
+	if err != nil {
+		return nil, err
+	}
+
+	// The initiator sends its nonce first so that the handshake also works on
+	// unbuffered byte streams (e.g. net.Pipe).
+	if initiator {
+		_, err = rw.Write(ownNonce[:])
+	}
+	if err == nil {
+		_, err = io.ReadFull(rw, peerNonce[:])
+	}
+	if err == nil && !initiator {
+		_, err = rw.Write(ownNonce[:])
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	salt := slices.Concat(peerNonce[:], ownNonce[:])
+	if initiator {
+		salt = slices.Concat(ownNonce[:], peerNonce[:])
+	}
+
+	initiatorKey, _ := hkdf.Key(sha256.New, key[:], salt, initiatorInfo, 32)
+	responderKey, _ := hkdf.Key(sha256.New, key[:], salt, responderInfo, 32)
+
+	c := &Conn{
+		rw:         rw,
+		sendKey:    [32]byte(responderKey),
+		receiveKey: [32]byte(initiatorKey),
+	}
+	if initiator {
+		c.sendKey, c.receiveKey = c.receiveKey, c.sendKey
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.established = c.now()
+
+	return c, nil
+}
+
+// Write encrypts the data and writes it in frames of up to MaxFrameSize bytes.
+func (c *Conn) Write(p []byte) (int, error) {
+	if c.writeClosed {
+		return 0, ErrClosed
+	}
+
+	n := 0
+	for len(p) > 0 {
+		size := min(MaxFrameSize, len(p))
+
+		err := c.writeFrame(p[:size])
+		if err != nil {
+			return n, err
+		}
+		c.report(c.sink().Sealed, size)
+
+		p = p[size:]
+		n += size
+	}
+
+	return n, nil
+}
+
+// CloseWrite writes the end frame. The underlying byte stream isn't closed.
+func (c *Conn) CloseWrite() error {
+	if c.writeClosed {
+		return ErrClosed
+	}
+
+	err := c.writeFrame([]byte{})
+	if err != nil {
+		return err
+	}
+
+	c.writeClosed = true
+
+	return nil
+}
+
+// Read reads the decrypted data.
+// Returns an error if a frame can't be authenticated or if the stream ends
+// without an end frame. io.EOF is only returned after the end frame was read.
+func (c *Conn) Read(p []byte) (int, error) {
+	for len(c.plaintext) == 0 {
+		if c.readErr != nil {
+			return 0, c.readErr
+		}
+
+		c.plaintext, c.readErr = c.readFrame()
+	}
+
+	n := copy(p, c.plaintext)
+	c.plaintext = c.plaintext[n:]
+
+	return n, nil
+}
+
+// writeFrame encrypts and writes a single frame.
+func (c *Conn) writeFrame(plaintext []byte) error {
+	if c.sendCounter == ^uint64(0) {
+		return ErrTooManyFrames
+	}
+
+	length := binary.BigEndian.AppendUint32(nil, uint32(len(plaintext)))
+
+	ciphertext, tag := xchacha20poly1305.NewXChaCha20Poly1305(c.sendKey, frameNonce(c.sendCounter)).Encrypt(plaintext, length)
+	c.sendCounter++
+
+	frame := make([]byte, 0, lengthSize+len(ciphertext)+TagSize)
+	frame = append(frame, length...)
+	frame = append(frame, ciphertext...)
+	frame = append(frame, tag[:]...)
+
+	_, err := c.rw.Write(frame)
+
+	return err
+}
+
+// readFrame reads and decrypts a single frame. io.EOF is returned for the end
+// frame.
+func (c *Conn) readFrame() ([]byte, error) {
+	if c.receiveCounter == ^uint64(0) {
+		return []byte{}, ErrTooManyFrames
+	}
+
+	length := make([]byte, lengthSize)
+
+	_, err := io.ReadFull(c.rw, length)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return []byte{}, debug.Detail(ErrDecryption, ErrTruncated)
+	}
+	if err != nil {
+		return []byte{}, err
+	}
+
+	size := binary.BigEndian.Uint32(length)
+	if size > MaxFrameSize {
+		return []byte{}, debug.Detail(ErrDecryption, ErrFrameTooLarge)
+	}
+
+	frame := make([]byte, int(size)+TagSize)
+
+	_, err = io.ReadFull(c.rw, frame)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return []byte{}, debug.Detail(ErrDecryption, ErrTruncated)
+	}
+	if err != nil {
+		return []byte{}, err
+	}
+
+	ciphertext := frame[:size]
+	tag := [16]byte(frame[size:])
+
+	plaintext, err := xchacha20poly1305.NewXChaCha20Poly1305(c.receiveKey, frameNonce(c.receiveCounter)).Decrypt(ciphertext, length, tag)
+	if err != nil {
+		c.sink().AuthFailed(metrics.SecretStreamLayer)
+		return []byte{}, ErrDecryption
+	}
+	c.receiveCounter++
+
+	if len(plaintext) == 0 {
+		return []byte{}, io.EOF
+	}
+	c.report(c.sink().Opened, len(plaintext))
+
+	return plaintext, nil
+}
+
+// sink returns the configured (or discarding) metrics sink.
+func (c *Conn) sink() metrics.Sink {
+	if c.metrics == nil {
+		return metrics.Discard
+	}
+
+	return c.metrics
+}
+
+// report reports the operation on n bytes and the age of the keys.
+func (c *Conn) report(operation func(layer string, n int), n int) {
+	operation(metrics.SecretStreamLayer, n)
+	c.sink().KeyAge(metrics.SecretStreamLayer, c.now().Sub(c.established))
+}
+
+// now returns the current time of the configured clock.
+func (c *Conn) now() time.Time {
+	return clock.OrSystem(c.clock).Now()
+}
+
+// frameNonce returns the nonce of the frame with the index (8 bytes, big
+// endian) which is zero padded to 24 bytes.
+func frameNonce(index uint64) [24]byte {
+	var nonce [24]byte
+	binary.BigEndian.PutUint64(nonce[16:], index)
+
+	return nonce
+}
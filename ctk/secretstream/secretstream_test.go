@@ -0,0 +1,288 @@
+package secretstream_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pmuens/ctk-go/ctk/clock"
+	"github.com/pmuens/ctk-go/ctk/metrics"
+	"github.com/pmuens/ctk-go/ctk/secretstream"
+)
+
+// rw is a byte stream whose reader and writer can be swapped after the
+// handshake so that the frames can be manipulated.
+type rw struct {
+	io.Reader
+	io.Writer
+}
+
+// connect establishes a channel between a client and a server over pipes. The
+// options are applied to the server.
+func connect(t *testing.T, clientKey [32]byte, serverKey [32]byte, opts ...secretstream.Option) (*secretstream.Conn, *rw, *secretstream.Conn, *rw) {
+	t.Helper()
+
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	clientRW := &rw{Reader: clientReader, Writer: clientWriter}
+	serverRW := &rw{Reader: serverReader, Writer: serverWriter}
+
+	type result struct {
+		conn *secretstream.Conn
+		err  error
+	}
+	done := make(chan result)
+
+	go func() {
+		server, err := secretstream.Server(serverRW, serverKey, opts...)
+		done <- result{conn: server, err: err}
+	}()
+
+	client, err := secretstream.Client(clientRW, clientKey)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	server := <-done
+	if server.err != nil {
+		t.Fatalf("want error %v, got %v", nil, server.err)
+	}
+
+	return client, clientRW, server.conn, serverRW
+}
+
+func TestSecretStream(t *testing.T) {
+	key := [32]byte{0x01, 0x02, 0x03}
+
+	t.Run("Round Trip", func(t *testing.T) {
+		t.Parallel()
+
+		client, server := net.Pipe()
+		defer client.Close()
+		defer server.Close()
+
+		request := bytes.Repeat([]byte("ping "), secretstream.MaxFrameSize)
+		response := []byte("pong")
+
+		go func() {
+			c, err := secretstream.Server(server, key)
+			if err != nil {
+				return
+			}
+
+			got, err := io.ReadAll(c)
+			if err != nil || !slices.Equal(got, request) {
+				return
+			}
+
+			c.Write(response)
+			c.CloseWrite()
+		}()
+
+		c, err := secretstream.Client(client, key)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		n, err := c.Write(request)
+		if err != nil || n != len(request) {
+			t.Fatalf("want %v bytes written, got %v (error %v)", len(request), n, err)
+		}
+
+		err = c.CloseWrite()
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		got, err := io.ReadAll(c)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if !slices.Equal(got, response) {
+			t.Errorf("want %s, got %s", response, got)
+		}
+
+		_, err = c.Write([]byte{0x01})
+		if !errors.Is(err, secretstream.ErrClosed) {
+			t.Errorf("want error %v, got %v", secretstream.ErrClosed, err)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]struct {
+			serverKey [32]byte
+			tamper    func(frames []byte) []byte
+			want      error
+		}{
+			"Wrong Key": {
+				serverKey: [32]byte{0x04},
+				tamper:    func(frames []byte) []byte { return frames },
+				want:      secretstream.ErrDecryption,
+			},
+			"Tampered Frame": {
+				serverKey: key,
+				tamper: func(frames []byte) []byte {
+					frames[5] ^= 0x01
+					return frames
+				},
+				want: secretstream.ErrDecryption,
+			},
+			"Tampered Length": {
+				serverKey: key,
+				tamper: func(frames []byte) []byte {
+					frames[3]--
+					return frames
+				},
+				want: secretstream.ErrDecryption,
+			},
+			"Replayed Frame": {
+				serverKey: key,
+				tamper: func(frames []byte) []byte {
+					frame := 4 + 5 + secretstream.TagSize
+					return append(slices.Clone(frames[:frame]), frames...)
+				},
+				want: secretstream.ErrDecryption,
+			},
+			"Dropped Frame": {
+				serverKey: key,
+				tamper: func(frames []byte) []byte {
+					return frames[4+5+secretstream.TagSize:]
+				},
+				want: secretstream.ErrDecryption,
+			},
+			"Dropped End Frame": {
+				serverKey: key,
+				tamper: func(frames []byte) []byte {
+					return frames[:len(frames)-4-secretstream.TagSize]
+				},
+				want: secretstream.ErrDecryption,
+			},
+			"Truncated Frame": {
+				serverKey: key,
+				tamper: func(frames []byte) []byte {
+					return frames[:len(frames)-1]
+				},
+				want: secretstream.ErrDecryption,
+			},
+			"Frame Too Large": {
+				serverKey: key,
+				tamper: func(frames []byte) []byte {
+					frames[0] = 0xff
+					return frames
+				},
+				want: secretstream.ErrDecryption,
+			},
+		}
+
+		for name, tc := range tt {
+			client, clientRW, server, serverRW := connect(t, key, tc.serverKey)
+
+			var frames bytes.Buffer
+			clientRW.Writer = &frames
+
+			client.Write([]byte("hello"))
+			client.Write([]byte("world"))
+			client.CloseWrite()
+
+			serverRW.Reader = bytes.NewReader(tc.tamper(frames.Bytes()))
+
+			_, err := io.ReadAll(server)
+
+			if !errors.Is(err, tc.want) {
+				t.Errorf("%v: want error %v, got %v", name, tc.want, err)
+			}
+		}
+	})
+
+	t.Run("Metrics", func(t *testing.T) {
+		t.Parallel()
+
+		sink := metrics.NewPrometheus()
+		client, clientRW, server, serverRW := connect(t, key, key, secretstream.WithMetrics(sink))
+
+		var frames bytes.Buffer
+		clientRW.Writer = &frames
+
+		client.Write([]byte("hello"))
+		client.Write([]byte("world"))
+
+		// The second frame is tampered with.
+		tampered := frames.Bytes()
+		tampered[len(tampered)-1] ^= 0x01
+		serverRW.Reader = bytes.NewReader(tampered)
+
+		_, err := io.ReadAll(server)
+		if !errors.Is(err, secretstream.ErrDecryption) {
+			t.Fatalf("want error %v, got %v", secretstream.ErrDecryption, err)
+		}
+
+		var got strings.Builder
+		sink.WriteTo(&got)
+
+		for _, want := range []string{
+			`ctk_operations_total{layer="secretstream",operation="open"} 1`,
+			`ctk_bytes_total{layer="secretstream",operation="open"} 5`,
+			`ctk_auth_failures_total{layer="secretstream"} 1`,
+			`ctk_key_age_seconds{layer="secretstream"} `,
+		} {
+			if !strings.Contains(got.String(), want) {
+				t.Errorf("want %v in %v", want, got.String())
+			}
+		}
+	})
+
+	t.Run("Clock", func(t *testing.T) {
+		t.Parallel()
+
+		sink := metrics.NewPrometheus()
+		c := clock.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+		client, clientRW, server, serverRW := connect(t, key, key, secretstream.WithMetrics(sink), secretstream.WithClock(c))
+
+		var frames bytes.Buffer
+		clientRW.Writer = &frames
+		client.Write([]byte("hello"))
+		serverRW.Reader = bytes.NewReader(frames.Bytes())
+
+		c.Advance(90 * time.Second)
+		io.ReadFull(server, make([]byte, 5))
+
+		var got strings.Builder
+		sink.WriteTo(&got)
+
+		want := `ctk_key_age_seconds{layer="secretstream"} 90`
+		if !strings.Contains(got.String(), want) {
+			t.Errorf("want %v in %v", want, got.String())
+		}
+	})
+
+	t.Run("Fresh Keys", func(t *testing.T) {
+		t.Parallel()
+
+		// Both sessions use the same key, but their frames differ as the keys
+		// are derived from the handshake nonces.
+		var sessions [2][]byte
+
+		for i := range sessions {
+			client, clientRW, _, _ := connect(t, key, key)
+
+			var frames bytes.Buffer
+			clientRW.Writer = &frames
+
+			client.Write([]byte("attack at dawn"))
+			sessions[i] = frames.Bytes()
+		}
+
+		if slices.Equal(sessions[0], sessions[1]) {
+			t.Errorf("want different frames, got %v twice", sessions[0])
+		}
+	})
+}
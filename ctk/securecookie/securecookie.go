@@ -0,0 +1,188 @@
+// Package securecookie implements the sealing of cookie values (e.g. session
+// data) which are stored by the browser but can neither be read nor modified by
+// it.
+//
+// A value is encrypted via XChaCha20-Poly1305 with the current key of a
+// rotation.Opener together with its expiry time. The cookie name is bound to
+// the value as additional authenticated data (AAD) so that a value can't be
+// moved into another cookie. Values sealed with previous keys of the Opener
+// can be opened until the keys are retired which allows to rotate the keys
+// without logging out all users.
+//
+// Cookie value format (base64url encoded without padding):
+//
+//	rotation message (see the rotation package) of: expiry (8, big endian Unix seconds) | value
+//
+// Note that the expiry time only limits how long a value can be opened. A
+// sealed value can be replayed until it expires (e.g. after a logout) unless
+// it's additionally tracked on the server.
+package securecookie
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/pmuens/ctk-go/ctk/clock"
+	"github.com/pmuens/ctk-go/ctk/internal/debug"
+	"github.com/pmuens/ctk-go/ctk/rotation"
+)
+
+const (
+	// ErrInvalidCookie is returned if a cookie value is malformed, was
+	// modified, belongs to another cookie or was sealed with an unknown key.
+	ErrInvalidCookie = Error("invalid cookie")
+
+	// ErrExpired is returned if a cookie value expired.
+	ErrExpired = Error("cookie expired")
+
+	// ErrInvalidMaxAge is returned if the max age is shorter than a second.
+	ErrInvalidMaxAge = Error("invalid cookie max age")
+
+	// ErrTooLarge is returned if a sealed cookie value exceeds MaxSize.
+	ErrTooLarge = Error("cookie too large")
+)
+
+// MaxSize is the largest size (in bytes) of a sealed cookie value. Browsers
+// only store cookies up to 4096 bytes (including the name and the attributes).
+const MaxSize = 4000
+
+// expirySize is the size (in bytes) of the encoded expiry time.
+const expirySize = 8
+
+// nameLabel is used for domain separation of the AAD.
+var nameLabel = []byte("ctk-go securecookie ")
+
+// Option configures a SecureCookie.
+type Option func(*SecureCookie)
+
+// WithClock sets the clock that tells the current time (clock.System by
+// default).
+func WithClock(c clock.Clock) Option {
+	return func(s *SecureCookie) {
+		s.clock = clock.OrSystem(c)
+	}
+}
+
+// SecureCookie seals and opens cookie values.
+// A SecureCookie can be used concurrently as long as its Opener isn't modified
+// (e.g. rotated) at the same time.
+type SecureCookie struct {
+	// opener holds the current and previous keys.
+	opener *rotation.Opener
+
+	// clock tells the current time.
+	clock clock.Clock
+}
+
+// New creates a SecureCookie which seals values with the current key of the
+// opener and opens values sealed with any of its keys.
+func New(opener *rotation.Opener, opts ...Option) *SecureCookie {
+	s := &SecureCookie{
+		opener: opener,
+		clock:  clock.System,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Seal encrypts the value of the cookie with the name which expires after
+// maxAge. The result can be used as the cookie's value as is.
+// Returns ErrInvalidMaxAge if maxAge is shorter than a second (the precision of
+// the expiry time) and ErrTooLarge if the sealed value exceeds MaxSize.
+func (s *SecureCookie) Seal(name string, value []byte, maxAge time.Duration) (string, error) {
+	if maxAge < time.Second {
+		return "", ErrInvalidMaxAge
+	}
+
+	expiry := s.clock.Now().Add(maxAge).Unix()
+
+	plaintext := make([]byte, expirySize, expirySize+len(value))
+	binary.BigEndian.PutUint64(plaintext, uint64(expiry))
+	plaintext = append(plaintext, value...)
+
+	message, err := s.opener.Seal(plaintext, nameAAD(name))
+	if err != nil {
+		return "", err
+	}
+
+	if base64.RawURLEncoding.EncodedLen(len(message)) > MaxSize {
+		return "", ErrTooLarge
+	}
+
+	return base64.RawURLEncoding.EncodeToString(message), nil
+}
+
+// Open decrypts the sealed value of the cookie with the name.
+// Returns ErrInvalidCookie if the value can't be authenticated and ErrExpired
+// if it expired.
+func (s *SecureCookie) Open(name string, sealed string) ([]byte, error) {
+	if len(sealed) > MaxSize {
+		return []byte{}, ErrInvalidCookie
+	}
+
+	message, err := base64.RawURLEncoding.DecodeString(sealed)
+	if err != nil {
+		return []byte{}, debug.Detail(ErrInvalidCookie, err)
+	}
+
+	plaintext, err := s.opener.Open(message, nameAAD(name))
+	if err != nil {
+		return []byte{}, debug.Detail(ErrInvalidCookie, err)
+	}
+
+	if len(plaintext) < expirySize {
+		return []byte{}, ErrInvalidCookie
+	}
+
+	expiry := int64(binary.BigEndian.Uint64(plaintext))
+	if s.clock.Now().Unix() >= expiry {
+		return []byte{}, ErrExpired
+	}
+
+	return plaintext[expirySize:], nil
+}
+
+// Cookie returns a cookie with the name and the sealed value which expires
+// after maxAge. The cookie is only sent via HTTPS (Secure), can't be read by
+// JavaScript (HttpOnly) and isn't sent with cross-site requests other than
+// top-level navigations (SameSite=Lax). Its path is "/".
+// Returns the errors of Seal.
+func (s *SecureCookie) Cookie(name string, value []byte, maxAge time.Duration) (*http.Cookie, error) {
+	sealed, err := s.Seal(name, value, maxAge)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Cookie{
+		Name:     name,
+		Value:    sealed,
+		Path:     "/",
+		MaxAge:   int(maxAge / time.Second),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}, nil
+}
+
+// Read opens the value of the request's cookie with the name.
+// Returns http.ErrNoCookie if there's no such cookie and the errors of Open.
+func (s *SecureCookie) Read(r *http.Request, name string) ([]byte, error) {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return s.Open(name, cookie.Value)
+}
+
+// nameAAD returns the AAD that binds a value to the cookie with the name.
+func nameAAD(name string) []byte {
+	return append(slices.Clone(nameLabel), name...)
+}
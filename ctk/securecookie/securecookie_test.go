@@ -0,0 +1,184 @@
+package securecookie_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pmuens/ctk-go/ctk/clock"
+	"github.com/pmuens/ctk-go/ctk/rotation"
+	"github.com/pmuens/ctk-go/ctk/securecookie"
+)
+
+func TestSecureCookie(t *testing.T) {
+	first := rotation.Key{ID: 1, Material: [32]byte{0x01}}
+	second := rotation.Key{ID: 2, Material: [32]byte{0x02}}
+	value := []byte(`{"user":"alice"}`)
+
+	t.Run("Round Trip", func(t *testing.T) {
+		t.Parallel()
+
+		s := securecookie.New(rotation.NewOpener(first))
+
+		sealed, err := s.Seal("session", value, time.Hour)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if strings.Contains(sealed, "alice") || strings.ContainsAny(sealed, "=+/;, ") {
+			t.Errorf("sealed value isn't an opaque cookie value: %v", sealed)
+		}
+
+		got, err := s.Open("session", sealed)
+		if !bytes.Equal(got, value) || err != nil {
+			t.Errorf("want %s, got %s (error %v)", value, got, err)
+		}
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		t.Parallel()
+
+		c := clock.NewManual(time.Unix(1700000000, 0))
+		s := securecookie.New(rotation.NewOpener(first), securecookie.WithClock(c))
+
+		sealed, err := s.Seal("session", value, time.Hour)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		c.Advance(time.Hour - time.Second)
+
+		_, err = s.Open("session", sealed)
+		if err != nil {
+			t.Errorf("want error %v, got %v", nil, err)
+		}
+
+		c.Advance(time.Second)
+
+		_, err = s.Open("session", sealed)
+		if !errors.Is(err, securecookie.ErrExpired) {
+			t.Errorf("want error %v, got %v", securecookie.ErrExpired, err)
+		}
+	})
+
+	t.Run("Key Rotation", func(t *testing.T) {
+		t.Parallel()
+
+		opener := rotation.NewOpener(first)
+		s := securecookie.New(opener)
+
+		old, err := s.Seal("session", value, time.Hour)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		opener.Rotate(second)
+
+		sealed, err := s.Seal("session", value, time.Hour)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		// Values sealed with the previous key stay valid until it's retired.
+		for _, v := range []string{old, sealed} {
+			got, err := s.Open("session", v)
+			if !bytes.Equal(got, value) || err != nil {
+				t.Errorf("want %s, got %s (error %v)", value, got, err)
+			}
+		}
+
+		opener.Retire(first.ID)
+
+		_, err = s.Open("session", old)
+		if !errors.Is(err, securecookie.ErrInvalidCookie) {
+			t.Errorf("want error %v, got %v", securecookie.ErrInvalidCookie, err)
+		}
+
+		_, err = securecookie.New(rotation.NewOpener(first)).Open("session", sealed)
+		if !errors.Is(err, securecookie.ErrInvalidCookie) {
+			t.Errorf("want error %v, got %v", securecookie.ErrInvalidCookie, err)
+		}
+	})
+
+	t.Run("Invalid Cookies", func(t *testing.T) {
+		t.Parallel()
+
+		s := securecookie.New(rotation.NewOpener(first))
+
+		sealed, err := s.Seal("session", value, time.Hour)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		modified := []byte(sealed)
+		modified[len(modified)/2] ^= 1
+
+		tests := map[string]struct {
+			name  string
+			value string
+		}{
+			"Other Cookie": {"csrf", sealed},
+			"Modified":     {"session", string(modified)},
+			"Truncated":    {"session", sealed[:len(sealed)-4]},
+			"Not Base64":   {"session", "not base64!"},
+			"Empty":        {"session", ""},
+			"Too Large":    {"session", strings.Repeat("A", securecookie.MaxSize+1)},
+		}
+
+		for name, tc := range tests {
+			_, err := s.Open(tc.name, tc.value)
+			if !errors.Is(err, securecookie.ErrInvalidCookie) {
+				t.Errorf("%v: want error %v, got %v", name, securecookie.ErrInvalidCookie, err)
+			}
+		}
+	})
+
+	t.Run("Invalid Seal", func(t *testing.T) {
+		t.Parallel()
+
+		s := securecookie.New(rotation.NewOpener(first))
+
+		_, err := s.Seal("session", value, 0)
+		if !errors.Is(err, securecookie.ErrInvalidMaxAge) {
+			t.Errorf("want error %v, got %v", securecookie.ErrInvalidMaxAge, err)
+		}
+
+		_, err = s.Seal("session", make([]byte, securecookie.MaxSize), time.Hour)
+		if !errors.Is(err, securecookie.ErrTooLarge) {
+			t.Errorf("want error %v, got %v", securecookie.ErrTooLarge, err)
+		}
+	})
+
+	t.Run("HTTP", func(t *testing.T) {
+		t.Parallel()
+
+		s := securecookie.New(rotation.NewOpener(first))
+
+		cookie, err := s.Cookie("session", value, time.Hour)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if !cookie.Secure || !cookie.HttpOnly || cookie.MaxAge != 3600 || cookie.Valid() != nil {
+			t.Errorf("want a valid secure cookie, got %v", cookie)
+		}
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		_, err = s.Read(r, "session")
+		if !errors.Is(err, http.ErrNoCookie) {
+			t.Errorf("want error %v, got %v", http.ErrNoCookie, err)
+		}
+
+		r.AddCookie(cookie)
+
+		got, err := s.Read(r, "session")
+		if !bytes.Equal(got, value) || err != nil {
+			t.Errorf("want %s, got %s (error %v)", value, got, err)
+		}
+	})
+}
@@ -0,0 +1,10 @@
+package ctk
+
+import "github.com/pmuens/ctk-go/ctk/selftest"
+
+// SelfTest runs the known-answer tests of the toolkit's primitives and returns
+// a *selftest.FailureError if one of them fails. Building with
+// -tags ctkselftest runs them automatically at startup (see ctk/selftest).
+func SelfTest() error {
+	return selftest.Run()
+}
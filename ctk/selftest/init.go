@@ -0,0 +1,11 @@
+//go:build ctkselftest
+
+package selftest
+
+// init runs the known-answer tests when the package is initialized and panics
+// if one of them fails so that a broken build never processes data.
+func init() {
+	if err := Run(); err != nil {
+		panic("ctk: " + err.Error())
+	}
+}
@@ -0,0 +1,251 @@
+// Package selftest implements power-on self tests which run known-answer tests
+// for each of the toolkit's primitives.
+//
+// Environments that require a module to test its algorithms before they're
+// used (e.g. FIPS 140 style deployments) can call Run at startup and refuse to
+// operate if it returns an error. Building an application with
+// -tags ctkselftest runs the tests automatically when the package is
+// initialized and panics if one of them fails. The ctk package imports this
+// package so that the tag has an effect for every application that uses it.
+//
+// The test vectors are taken from the RFCs and drafts that specify the
+// primitives.
+package selftest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+
+	"github.com/pmuens/ctk-go/ctk/argon2"
+	"github.com/pmuens/ctk-go/ctk/blake2b"
+	"github.com/pmuens/ctk-go/ctk/chacha20"
+	"github.com/pmuens/ctk-go/ctk/chacha20poly1305"
+	"github.com/pmuens/ctk-go/ctk/hkdf"
+	"github.com/pmuens/ctk-go/ctk/poly1305"
+	"github.com/pmuens/ctk-go/ctk/sha3"
+	"github.com/pmuens/ctk-go/ctk/x25519"
+	"github.com/pmuens/ctk-go/ctk/xchacha20"
+	"github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
+)
+
+const (
+	// ErrFailed is returned if a known-answer test fails.
+	ErrFailed = Error("self test failed")
+)
+
+// FailureError describes a failed known-answer test. It unwraps to ErrFailed.
+type FailureError struct {
+	// Primitive is the name of the primitive whose test failed.
+	Primitive string
+}
+
+// Error implements the error interface.
+func (e *FailureError) Error() string {
+	return string(ErrFailed) + ": " + e.Primitive
+}
+
+// Unwrap returns ErrFailed.
+func (e *FailureError) Unwrap() error {
+	return ErrFailed
+}
+
+// test is a known-answer test of a primitive.
+type test struct {
+	// name is the name of the primitive.
+	name string
+
+	// run computes the primitive's output for the test vector's input. It
+	// returns nil if the primitive rejects the input.
+	run func() []byte
+
+	// want is the expected output (hex encoded).
+	want string
+}
+
+// sunscreen is the plaintext of the RFC 8439 and draft-irtf-cfrg-xchacha-03
+// test vectors.
+const sunscreen = "Ladies and Gentlemen of the class of '99: If I could offer you only one tip for the future, sunscreen would be it."
+
+// tests are the known-answer tests in the order they're run. The primitives
+// that others are built upon come first so that a failure is attributed to
+// the primitive that's actually broken.
+var tests = []test{
+	{
+		// RFC 8439 - Test Vectors - 2.4.2
+		name: "chacha20",
+		run: func() []byte {
+			key := [32]byte(decode("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"))
+			nonce := [12]byte(decode("000000000000004a00000000"))
+
+			return chacha20.NewChaCha20WithCounter(key, nonce, 1).XORWithKeyStream([]byte(sunscreen))
+		},
+		want: "6e2e359a2568f98041ba0728dd0d6981e97e7aec1d4360c20a27afccfd9fae0bf91b65c5524733ab8f593dabcd62b3571639d624e65152ab8f530c359f0861d807ca0dbf500d6a6156a38e088a22b65e52bc514d16ccf806818ce91ab77937365af90bbf74a35be6b40b8eedf2785e42874d",
+	},
+	{
+		// RFC 8439 - Test Vectors - 2.5.2
+		name: "poly1305",
+		run: func() []byte {
+			key := [32]byte(decode("85d6be7857556d337f4452fe42d506a80103808afb0db2fd4abff6af4149f51b"))
+			tag := poly1305.OneTimeAuth(poly1305.UnsafeOneTimeKeyFromBytes(key), []byte("Cryptographic Forum Research Group"))
+
+			return tag[:]
+		},
+		want: "a8061dc1305136c6c22b8baf0c0127a9",
+	},
+	{
+		// RFC 8439 - Test Vectors - 2.8.2
+		name: "chacha20poly1305",
+		run: func() []byte {
+			key := [32]byte(decode("808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f"))
+			nonce := [12]byte(decode("070000004041424344454647"))
+			aad := decode("50515253c0c1c2c3c4c5c6c7")
+
+			ciphertext, tag := chacha20poly1305.NewChaCha20Poly1305(key, nonce).Encrypt([]byte(sunscreen), aad)
+			plaintext, err := chacha20poly1305.NewChaCha20Poly1305(key, nonce).Decrypt(ciphertext, aad, tag)
+			if err != nil || string(plaintext) != sunscreen {
+				return nil
+			}
+
+			return append(ciphertext, tag[:]...)
+		},
+		want: "d31a8d34648e60db7b86afbc53ef7ec2a4aded51296e08fea9e2b5a736ee62d63dbea45e8ca9671282fafb69da92728b1a71de0a9e060b2905d6a5b67ecd3b3692ddbd7f2d778b8c9803aee328091b58fab324e4fad675945585808b4831d7bc3ff4def08e4b7a9de576d26586cec64b6116" + "1ae10b594f09e26a7e902ecbd0600691",
+	},
+	{
+		// draft-irtf-cfrg-xchacha-03 - Test Vectors - 2.2.1
+		name: "hchacha20",
+		run: func() []byte {
+			key := [32]byte(decode("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"))
+			nonce := [16]byte(decode("000000090000004a0000000031415927"))
+			subkey := xchacha20.NewHChaCha20(key, nonce).GenerateSubKey()
+
+			return subkey[:]
+		},
+		want: "82413b4227b27bfed30e42508a877d73a0f9e4d58a74a853c12ec41326d3ecdc",
+	},
+	{
+		// draft-irtf-cfrg-xchacha-03 - Test Vectors - A.1
+		name: "xchacha20poly1305",
+		run: func() []byte {
+			key := [32]byte(decode("808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f"))
+			nonce := [24]byte(decode("404142434445464748494a4b4c4d4e4f5051525354555657"))
+			aad := decode("50515253c0c1c2c3c4c5c6c7")
+
+			ciphertext, tag := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce).Encrypt([]byte(sunscreen), aad)
+			plaintext, err := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce).Decrypt(ciphertext, aad, tag)
+			if err != nil || string(plaintext) != sunscreen {
+				return nil
+			}
+
+			return append(ciphertext, tag[:]...)
+		},
+		want: "bd6d179d3e83d43b9576579493c0e939572a1700252bfaccbed2902c21396cbb731c7f1b0b4aa6440bf3a82f4eda7e39ae64c6708c54c216cb96b72e1213b4522f8c9ba40db5d945b11b69b982c1bb9e3f3fac2bc369488f76b2383565d3fff921f9664c97637da9768812f615c68b13b52e" + "c0875924c1c7987947deafd8780acf49",
+	},
+	{
+		// RFC 7693 - Test Vectors - Appendix A
+		name: "blake2b",
+		run: func() []byte {
+			sum := blake2b.Sum512([]byte("abc"))
+
+			return sum[:]
+		},
+		want: "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923",
+	},
+	{
+		// FIPS 202 - SHA3-256 example
+		name: "sha3",
+		run: func() []byte {
+			sum := sha3.Sum256([]byte("abc"))
+
+			return sum[:]
+		},
+		want: "3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532",
+	},
+	{
+		// RFC 5869 - Test Vectors - A.1
+		name: "hkdf",
+		run: func() []byte {
+			ikm := decode("0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b")
+			salt := decode("000102030405060708090a0b0c")
+			info := decode("f0f1f2f3f4f5f6f7f8f9")
+
+			okm, err := hkdf.Key(sha256.New, ikm, salt, info, 42)
+			if err != nil {
+				return nil
+			}
+
+			return okm
+		},
+		want: "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865",
+	},
+	{
+		// RFC 7748 - Test Vectors - 5.2 - #1
+		name: "x25519",
+		run: func() []byte {
+			scalar := [32]byte(decode("a546e36bf0527c9d3b16154b82465edd62144c0ac1fc5a18506a2244ba449ac4"))
+			u := [32]byte(decode("e6db6867583030db3594c1a424b15f7c726624ec26b3353b10a903a6d0ab1c4c"))
+			point := x25519.ScalarMult(scalar, u)
+
+			return point[:]
+		},
+		want: "c3da55379de9c6908e94ea4df28d084f32eccf03491c71f754b4075577a28552",
+	},
+	{
+		// RFC 9106 - Test Vectors - 5.3
+		name: "argon2",
+		run: func() []byte {
+			params := argon2.Params{
+				Variant:        argon2.Argon2id,
+				Time:           3,
+				Memory:         32,
+				Parallelism:    4,
+				KeyLength:      32,
+				Secret:         decode("0303030303030303"),
+				AssociatedData: decode("040404040404040404040404"),
+			}
+
+			password := decode("0101010101010101010101010101010101010101010101010101010101010101")
+			salt := decode("02020202020202020202020202020202")
+
+			key, err := argon2.Key(password, salt, params)
+			if err != nil {
+				return nil
+			}
+
+			return key
+		},
+		want: "0d640df58d78766c08c037a34a8b53c9d01ef0452d75b65eb52520e96b01e659",
+	},
+}
+
+// Run runs the known-answer tests of all primitives and returns a
+// *FailureError for the first one that fails.
+func Run() error {
+	for _, t := range tests {
+		if err := t.check(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// check runs the test and returns a *FailureError if its output doesn't match
+// the expected output.
+func (t test) check() error {
+	if !slices.Equal(t.run(), decode(t.want)) {
+		return &FailureError{Primitive: t.name}
+	}
+
+	return nil
+}
+
+// decode decodes the hex encoded test vector s and panics if it's malformed.
+func decode(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic("selftest: malformed test vector")
+	}
+
+	return b
+}
@@ -0,0 +1,42 @@
+package selftest
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Flip a bit of the expected output to simulate a broken primitive.
+			want := decode(tc.want)
+			want[0] ^= 0x01
+
+			broken := test{name: tc.name, run: tc.run, want: hex.EncodeToString(want)}
+			err := broken.check()
+
+			var failure *FailureError
+			if !errors.As(err, &failure) || failure.Primitive != tc.name {
+				t.Errorf("want error %v, got %v", &FailureError{Primitive: tc.name}, err)
+			}
+
+			if !errors.Is(tc.check(), nil) {
+				t.Errorf("want error %v, got %v", nil, tc.check())
+			}
+		})
+	}
+
+	t.Run("Rejected Input", func(t *testing.T) {
+		t.Parallel()
+
+		rejected := test{name: "rejected", run: func() []byte { return nil }, want: "00"}
+		err := rejected.check()
+
+		if !errors.Is(err, ErrFailed) {
+			t.Errorf("want error %v, got %v", ErrFailed, err)
+		}
+	})
+}
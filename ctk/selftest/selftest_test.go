@@ -0,0 +1,29 @@
+package selftest_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/selftest"
+)
+
+func TestRun(t *testing.T) {
+	err := selftest.Run()
+
+	if !errors.Is(err, nil) {
+		t.Errorf("want error %v, got %v", nil, err)
+	}
+}
+
+func TestFailureError(t *testing.T) {
+	err := error(&selftest.FailureError{Primitive: "chacha20"})
+
+	if !errors.Is(err, selftest.ErrFailed) {
+		t.Errorf("want error %v, got %v", selftest.ErrFailed, err)
+	}
+
+	want := "self test failed: chacha20"
+	if got := err.Error(); got != want {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,492 @@
+package session
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pmuens/ctk-go/ctk/chacha20poly1305"
+	"github.com/pmuens/ctk-go/ctk/clock"
+	"github.com/pmuens/ctk-go/ctk/internal/debug"
+	"github.com/pmuens/ctk-go/ctk/logging"
+	"github.com/pmuens/ctk-go/ctk/metrics"
+)
+
+const (
+	// ErrDecryption is returned if a record can't be authenticated, exceeds
+	// MaxRecordSize or if the connection ends without a close record. The
+	// cause (ErrInvalidRecord or ErrTruncated) is only added in builds with the
+	// ctkdebug build tag so that peers which see the errors don't learn which
+	// check rejected a forged record.
+	ErrDecryption = Error("record authentication failed")
+
+	// ErrInvalidRecord is returned if an authenticated record has an unknown
+	// type.
+	ErrInvalidRecord = Error("invalid record")
+
+	// ErrTruncated is the cause of ErrDecryption if the connection ends without
+	// a close record.
+	ErrTruncated = Error("session truncated")
+
+	// ErrClosed is returned if the session was closed.
+	ErrClosed = Error("session closed")
+
+	// ErrTooManyRecords is returned if a direction exceeds the number of records
+	// that can be indexed.
+	ErrTooManyRecords = Error("too many records")
+)
+
+// MaxRecordSize is the maximum number of plaintext bytes per record.
+const MaxRecordSize = 16 * 1024
+
+// TagSize is the size (in bytes) of the tag that's appended to every record.
+const TagSize = 16
+
+// recordHeaderSize is the size (in bytes) of the record header.
+const recordHeaderSize = 3
+
+// Record types.
+const (
+	// recordData carries application data.
+	recordData = 0x00
+
+	// recordFinished confirms the handshake.
+	recordFinished = 0x01
+
+	// recordClose marks the end of the session.
+	recordClose = 0x02
+)
+
+// Conn is a session. It implements the net.Conn interface and is safe for
+// concurrent use.
+// The handshake runs on the first Read or Write unless it already ran (via
+// Handshake or the constructors such as Client and Server).
+type Conn struct {
+	// conn is the underlying connection.
+	conn net.Conn
+
+	// config is the config of the handshake.
+	config Config
+
+	// client indicates whether the handshake is run as the client.
+	client bool
+
+	// handshakeMu guards the handshake.
+	handshakeMu sync.Mutex
+
+	// handshakeDone indicates whether the handshake ran.
+	handshakeDone bool
+
+	// handshakeErr is the error of the handshake.
+	handshakeErr error
+
+	// handshakeComplete indicates whether the handshake succeeded (it's set
+	// after the fields the handshake derives).
+	handshakeComplete atomic.Bool
+
+	// peerPublicKey is the peer's static public key (zero in the PSK mode).
+	peerPublicKey [32]byte
+
+	// metrics receives the operations and authentication failures.
+	metrics metrics.Sink
+
+	// logger receives the session's events.
+	logger logging.Logger
+
+	// clock tells the current time.
+	clock clock.Clock
+
+	// established is the time the keys were derived.
+	established time.Time
+
+	// readMu guards the fields that are used for reading.
+	readMu sync.Mutex
+
+	// receiveKey is the key of the records that are read.
+	receiveKey [32]byte
+
+	// receiveCounter is the index of the next record that's read.
+	receiveCounter uint64
+
+	// plaintext is the decrypted data that wasn't read yet.
+	plaintext []byte
+
+	// pending are the bytes of the record that's read which were already
+	// received (kept if a read times out so that the record can be resumed).
+	pending []byte
+
+	// readErr is the error that occurred while reading (returned on every Read).
+	readErr error
+
+	// writeMu guards the fields that are used for writing.
+	writeMu sync.Mutex
+
+	// sendKey is the key of the records that are written.
+	sendKey [32]byte
+
+	// sendCounter is the index of the next record that's written.
+	sendCounter uint64
+
+	// closed indicates whether the close record was written.
+	closed bool
+
+	// deadlineMu guards the deadlines.
+	deadlineMu sync.Mutex
+
+	// readDeadline is the read deadline that was set via the Conn.
+	readDeadline time.Time
+
+	// writeDeadline is the write deadline that was set via the Conn.
+	writeDeadline time.Time
+}
+
+// newConn creates a new session over the connection whose handshake didn't run
+// yet.
+func newConn(conn net.Conn, config Config, client bool) *Conn {
+	return &Conn{
+		conn:    conn,
+		config:  config,
+		client:  client,
+		metrics: config.metrics(),
+		logger:  config.logger(),
+		clock:   clock.OrSystem(config.Clock),
+	}
+}
+
+// Handshake runs the handshake unless it already ran (see HandshakeContext).
+func (c *Conn) Handshake() error {
+	return c.HandshakeContext(context.Background())
+}
+
+// HandshakeContext runs the handshake under the context unless it already ran.
+// Read and Write run the handshake implicitly which is why calling it is only
+// needed to bound the handshake by a context or to handle its errors before any
+// data is exchanged. The handshake ends at the context deadline if that's
+// earlier than the handshake timeout of the config.
+// Returns an error if the config is invalid, the handshake fails or the context
+// is done (the same error is returned once the handshake failed).
+func (c *Conn) HandshakeContext(ctx context.Context) error {
+	c.handshakeMu.Lock()
+	defer c.handshakeMu.Unlock()
+
+	if c.handshakeDone {
+		return c.handshakeErr
+	}
+
+	err := c.handshake(ctx)
+	c.logHandshake(ctx, err)
+
+	c.handshakeDone = true
+	c.handshakeErr = err
+	if err == nil {
+		c.handshakeComplete.Store(true)
+	}
+
+	return err
+}
+
+// PeerPublicKey returns the static public key the peer authenticated with (zero
+// in the PSK mode and before the handshake completed).
+func (c *Conn) PeerPublicKey() [32]byte {
+	if !c.handshakeComplete.Load() {
+		return [32]byte{}
+	}
+
+	return c.peerPublicKey
+}
+
+// Read reads the decrypted data. The handshake runs first if it didn't run yet.
+// A read that times out (see SetReadDeadline) can be retried as the received
+// part of the current record is kept.
+// Returns an error if the handshake fails, a record can't be authenticated or
+// if the connection ends without a close record. io.EOF is only returned after
+// the close record was read.
+func (c *Conn) Read(p []byte) (int, error) {
+	err := c.Handshake()
+	if err != nil {
+		return 0, err
+	}
+
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+
+	for len(c.plaintext) == 0 {
+		if c.readErr != nil {
+			return 0, c.readErr
+		}
+
+		typ, plaintext, err := c.readRecord()
+		switch {
+		case isTimeout(err):
+			// The timeout isn't kept so that the next Read resumes the record.
+			return 0, err
+		case err != nil:
+			c.readErr = err
+		case typ == recordClose:
+			c.readErr = io.EOF
+		case typ == recordData:
+			c.plaintext = plaintext
+			c.report(c.metrics.Opened, len(plaintext))
+		default:
+			c.readErr = ErrInvalidRecord
+		}
+	}
+
+	n := copy(p, c.plaintext)
+	c.plaintext = c.plaintext[n:]
+
+	return n, nil
+}
+
+// Write encrypts the data and writes it in records of up to MaxRecordSize
+// bytes. The handshake runs first if it didn't run yet.
+// Returns an error if the handshake fails or the data can't be written.
+func (c *Conn) Write(p []byte) (int, error) {
+	err := c.Handshake()
+	if err != nil {
+		return 0, err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closed {
+		return 0, ErrClosed
+	}
+
+	n := 0
+	for len(p) > 0 {
+		size := min(MaxRecordSize, len(p))
+
+		err := c.writeRecord(recordData, p[:size])
+		if err != nil {
+			return n, err
+		}
+		c.report(c.metrics.Sealed, size)
+
+		p = p[size:]
+		n += size
+	}
+
+	return n, nil
+}
+
+// CloseWrite writes the close record so that the peer reads io.EOF. The
+// underlying connection stays open so that the peer can still respond. The
+// handshake runs first if it didn't run yet.
+func (c *Conn) CloseWrite() error {
+	err := c.Handshake()
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closed {
+		return ErrClosed
+	}
+
+	c.closed = true
+
+	return c.writeRecord(recordClose, []byte{})
+}
+
+// Close writes the close record (unless CloseWrite was called or the handshake
+// didn't complete) and closes the underlying connection.
+func (c *Conn) Close() error {
+	var err error
+	if c.handshakeComplete.Load() {
+		err = c.CloseWrite()
+		if errors.Is(err, ErrClosed) {
+			err = nil
+		}
+	}
+
+	c.logger.LogAttrs(context.Background(), slog.LevelDebug, "session closed", slog.String("remote", addr(c.conn.RemoteAddr())))
+
+	return errors.Join(err, c.conn.Close())
+}
+
+// LocalAddr returns the local address of the underlying connection.
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// RemoteAddr returns the remote address of the underlying connection.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// SetDeadline sets the read and write deadlines of the underlying connection.
+// The handshake is bound by its own timeout and restores the deadlines once it
+// completes.
+func (c *Conn) SetDeadline(t time.Time) error {
+	c.deadlineMu.Lock()
+	defer c.deadlineMu.Unlock()
+
+	c.readDeadline, c.writeDeadline = t, t
+
+	return c.conn.SetDeadline(t)
+}
+
+// SetReadDeadline sets the read deadline of the underlying connection (see
+// SetDeadline).
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.deadlineMu.Lock()
+	defer c.deadlineMu.Unlock()
+
+	c.readDeadline = t
+
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the write deadline of the underlying connection (see
+// SetDeadline).
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	c.deadlineMu.Lock()
+	defer c.deadlineMu.Unlock()
+
+	c.writeDeadline = t
+
+	return c.conn.SetWriteDeadline(t)
+}
+
+// restoreDeadlines sets the deadlines of the underlying connection to the ones
+// that were set via the Conn (none by default).
+func (c *Conn) restoreDeadlines() error {
+	c.deadlineMu.Lock()
+	defer c.deadlineMu.Unlock()
+
+	return errors.Join(c.conn.SetReadDeadline(c.readDeadline), c.conn.SetWriteDeadline(c.writeDeadline))
+}
+
+// readFinished reads the peer's finished record.
+func (c *Conn) readFinished() error {
+	typ, _, err := c.readRecord()
+	if err != nil {
+		return err
+	}
+
+	if typ != recordFinished {
+		return ErrHandshake
+	}
+
+	return nil
+}
+
+// writeRecord encrypts and writes a single record.
+func (c *Conn) writeRecord(typ byte, plaintext []byte) error {
+	if c.sendCounter == ^uint64(0) {
+		return ErrTooManyRecords
+	}
+
+	header := []byte{typ}
+	header = binary.BigEndian.AppendUint16(header, uint16(len(plaintext)))
+
+	ciphertext, tag := chacha20poly1305.NewChaCha20Poly1305(c.sendKey, recordNonce(c.sendCounter)).Encrypt(plaintext, header)
+	c.sendCounter++
+
+	_, err := c.conn.Write(slices.Concat(header, ciphertext, tag[:]))
+
+	return err
+}
+
+// readRecord reads and decrypts a single record and returns its type and
+// plaintext.
+func (c *Conn) readRecord() (byte, []byte, error) {
+	if c.receiveCounter == ^uint64(0) {
+		return 0, []byte{}, ErrTooManyRecords
+	}
+
+	err := c.fill(recordHeaderSize)
+	if err != nil {
+		return 0, []byte{}, err
+	}
+
+	header := c.pending[:recordHeaderSize]
+
+	size := int(binary.BigEndian.Uint16(header[1:]))
+	if size > MaxRecordSize {
+		return 0, []byte{}, debug.Detail(ErrDecryption, ErrInvalidRecord)
+	}
+
+	err = c.fill(recordHeaderSize + size + TagSize)
+	if err != nil {
+		return 0, []byte{}, err
+	}
+
+	// The record is decrypted into a new buffer which is why the pending bytes
+	// can be reused for the next record.
+	record := c.pending[recordHeaderSize:]
+	c.pending = c.pending[:0]
+
+	ciphertext := record[:size]
+	tag := [16]byte(record[size:])
+
+	plaintext, err := chacha20poly1305.NewChaCha20Poly1305(c.receiveKey, recordNonce(c.receiveCounter)).Decrypt(ciphertext, header, tag)
+	if err != nil {
+		c.metrics.AuthFailed(metrics.SessionLayer)
+		c.logger.LogAttrs(context.Background(), slog.LevelWarn, "session record authentication failed",
+			slog.String("remote", addr(c.conn.RemoteAddr())),
+			slog.Uint64("record", c.receiveCounter),
+		)
+		return 0, []byte{}, ErrDecryption
+	}
+	c.receiveCounter++
+
+	return header[0], plaintext, nil
+}
+
+// fill reads from the underlying connection until n bytes of the current record
+// are pending.
+// Returns ErrDecryption if the connection ends before (or the error of the
+// connection if a read fails, e.g. because it timed out).
+func (c *Conn) fill(n int) error {
+	if cap(c.pending) < n {
+		c.pending = append(make([]byte, 0, n), c.pending...)
+	}
+
+	for len(c.pending) < n {
+		k, err := c.conn.Read(c.pending[len(c.pending):n])
+		c.pending = c.pending[:len(c.pending)+k]
+
+		if err == io.EOF && len(c.pending) < n {
+			return debug.Detail(ErrDecryption, ErrTruncated)
+		}
+		if err != nil && len(c.pending) < n {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isTimeout reports whether the error is a timeout (e.g. because a deadline
+// passed) after which the operation can be retried.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// report reports the operation on n bytes and the age of the keys.
+func (c *Conn) report(operation func(layer string, n int), n int) {
+	operation(metrics.SessionLayer, n)
+	c.metrics.KeyAge(metrics.SessionLayer, c.clock.Now().Sub(c.established))
+}
+
+// recordNonce returns the nonce of the record with the index (8 bytes, big
+// endian) which is zero padded to 12 bytes.
+func recordNonce(index uint64) [12]byte {
+	var nonce [12]byte
+	binary.BigEndian.PutUint64(nonce[4:], index)
+
+	return nonce
+}
@@ -0,0 +1,535 @@
+// Package session implements secure channels over network connections which
+// are established via an X25519 based handshake and protected via
+// ChaCha20-Poly1305.
+//
+// Modes: In the static key mode both parties have a static X25519 key pair and
+// a list of the peer public keys they accept. In the pre-shared key (PSK) mode
+// both parties share a secret key instead. Ephemeral X25519 keys are used in
+// all modes so that past sessions stay secret if the long-term keys leak
+// (forward secrecy).
+//
+// A PSK can also be mixed into the static key mode. The session keys then stay
+// secret as long as either the X25519 keys or the PSK are secure which hedges
+// against attacks on X25519 (e.g. by quantum computers recording the traffic
+// today) and allows to bind sessions to a deployment-wide secret.
+//
+// Handshake: The client sends its handshake message, the server responds with
+// its own:
+//
+//	version (1) | mode (1) | ephemeral public key (32) | static public key (32, static key modes only)
+//
+// The input keying material is the concatenation of the Diffie-Hellman results
+// (ephemeral-ephemeral, client ephemeral-server static and client static-server
+// ephemeral in the static key modes or only ephemeral-ephemeral in the PSK
+// mode) followed by the PSK (if any). A key per direction is derived via HKDF-SHA256
+// with the SHA-256 hash of both handshake messages as the salt. Both parties
+// then send a finished record to confirm that they derived the same keys.
+//
+// Records: The data is sent in records:
+//
+//	type (1) | length (2, big endian) | ciphertext | tag (16)
+//
+// The record header is bound to every record as additional authenticated data
+// (AAD) and the nonce is the record's index in its direction so that records
+// can't be replayed, reordered or dropped. A close record marks the end of the
+// session so that a truncated session is detected.
+package session
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/pmuens/ctk-go/ctk/clock"
+	"github.com/pmuens/ctk-go/ctk/hkdf"
+	"github.com/pmuens/ctk-go/ctk/logging"
+	"github.com/pmuens/ctk-go/ctk/metrics"
+	"github.com/pmuens/ctk-go/ctk/x25519"
+)
+
+const (
+	// ErrInvalidConfig is returned if the config doesn't select a mode or if
+	// the PSK is too short.
+	ErrInvalidConfig = Error("invalid session config")
+
+	// ErrHandshake is returned if the handshake message of the peer is
+	// malformed or uses a different version or mode.
+	ErrHandshake = Error("handshake failed")
+
+	// ErrUnknownPeer is returned if the static public key of the peer isn't
+	// accepted.
+	ErrUnknownPeer = Error("unknown peer")
+)
+
+// Version is the version of the handshake and record format.
+const Version = 1
+
+// MinPreSharedKeySize is the minimum size (in bytes) of a pre-shared key.
+// The key needs to be random (use a KDF such as Argon2 for passphrases).
+const MinPreSharedKeySize = 32
+
+// DefaultHandshakeTimeout is the time a handshake may take if the config
+// doesn't set a timeout.
+const DefaultHandshakeTimeout = 10 * time.Second
+
+// Mode identifies how the parties authenticate each other.
+type Mode uint8
+
+const (
+	// StaticKeyMode authenticates the parties via static X25519 keys.
+	StaticKeyMode Mode = 1
+
+	// PSKMode authenticates the parties via a pre-shared key.
+	PSKMode Mode = 2
+
+	// StaticKeyPSKMode authenticates the parties via static X25519 keys and a
+	// pre-shared key.
+	StaticKeyPSKMode Mode = 3
+)
+
+// String returns the name of the mode.
+func (m Mode) String() string {
+	switch m {
+	case StaticKeyMode:
+		return "static key"
+	case PSKMode:
+		return "psk"
+	case StaticKeyPSKMode:
+		return "static key + psk"
+	default:
+		return "unknown"
+	}
+}
+
+// Info strings used for domain separation in the key derivations.
+var (
+	transcriptLabel = []byte("ctk-go session")
+	clientInfo      = []byte("ctk-go session client")
+	serverInfo      = []byte("ctk-go session server")
+)
+
+// Config configures a session. PrivateKey and PeerPublicKeys (static key mode),
+// PreSharedKey (PSK mode) or all of them (static key mode with PSK) need to be
+// set.
+type Config struct {
+	// PrivateKey is the static X25519 private key.
+	PrivateKey [32]byte
+
+	// PeerPublicKeys are the static public keys of the peers that are accepted.
+	PeerPublicKeys [][32]byte
+
+	// PreSharedKey is the (random) key that's shared between the parties. It
+	// needs to be at least MinPreSharedKeySize bytes long.
+	PreSharedKey []byte
+
+	// HandshakeTimeout is the time the handshake may take (0 uses
+	// DefaultHandshakeTimeout).
+	HandshakeTimeout time.Duration
+
+	// Metrics receives the operations and authentication failures of the
+	// session (nil discards them).
+	Metrics metrics.Sink
+
+	// Logger receives the session's events, e.g. established sessions and
+	// failed handshakes (nil discards them). No key material is logged.
+	Logger logging.Logger
+
+	// Clock tells the current time that the age of the keys (reported to
+	// Metrics) is measured with (nil uses clock.System). The handshake timeout
+	// always uses the system time as it's enforced via the connection's
+	// deadline.
+	Clock clock.Clock
+}
+
+// mode returns the mode the config selects.
+// Returns an error if no mode is selected or if the PSK is too short.
+func (c Config) mode() (Mode, error) {
+	hasPrivateKey := c.PrivateKey != [32]byte{}
+	hasPeers := len(c.PeerPublicKeys) > 0
+	hasPSK := len(c.PreSharedKey) > 0
+
+	if hasPSK && len(c.PreSharedKey) < MinPreSharedKeySize {
+		return 0, ErrInvalidConfig
+	}
+
+	switch {
+	case hasPrivateKey && hasPeers && !hasPSK:
+		return StaticKeyMode, nil
+	case hasPrivateKey && hasPeers && hasPSK:
+		return StaticKeyPSKMode, nil
+	case hasPSK && !hasPrivateKey && !hasPeers:
+		return PSKMode, nil
+	default:
+		return 0, ErrInvalidConfig
+	}
+}
+
+// metrics returns the configured (or discarding) metrics sink.
+func (c Config) metrics() metrics.Sink {
+	if c.Metrics == nil {
+		return metrics.Discard
+	}
+
+	return c.Metrics
+}
+
+// logger returns the configured (or discarding) logger.
+func (c Config) logger() logging.Logger {
+	if c.Logger == nil {
+		return logging.Discard
+	}
+
+	return c.Logger
+}
+
+// handshakeTimeout returns the configured (or default) handshake timeout.
+func (c Config) handshakeTimeout() time.Duration {
+	if c.HandshakeTimeout == 0 {
+		return DefaultHandshakeTimeout
+	}
+
+	return c.HandshakeTimeout
+}
+
+// Dial connects to the address on the network (see net.Dial) and establishes a
+// session as the client.
+// Returns an error if the connection or the handshake fails.
+func Dial(network string, address string, config Config) (*Conn, error) {
+	return DialContext(context.Background(), network, address, config)
+}
+
+// DialContext is like Dial but connects and runs the handshake under the
+// context so that both can be cancelled or bound by a deadline.
+// Returns an error if the connection or the handshake fails or the context is
+// done.
+func DialContext(ctx context.Context, network string, address string, config Config) (*Conn, error) {
+	_, err := config.mode()
+	if err != nil {
+		return nil, err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := ClientContext(ctx, conn, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Listener accepts connections and establishes sessions as the server.
+type Listener struct {
+	// ln is the underlying listener.
+	ln net.Listener
+
+	// config is the config of the sessions.
+	config Config
+}
+
+// Listen listens on the address on the network (see net.Listen).
+// Returns an error if the config is invalid or the address can't be listened
+// on.
+func Listen(network string, address string, config Config) (*Listener, error) {
+	_, err := config.mode()
+	if err != nil {
+		return nil, err
+	}
+
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewListener(ln, config), nil
+}
+
+// NewListener creates a new Listener which accepts connections from ln.
+func NewListener(ln net.Listener, config Config) *Listener {
+	return &Listener{ln: ln, config: config}
+}
+
+// Accept waits for a connection and returns it as a *Conn whose handshake runs
+// on the first Read or Write (or an explicit call to Handshake) like the
+// connections of crypto/tls. The handshake therefore runs in the goroutine that
+// serves the connection so that a slow or misbehaving client can't block the
+// listener. Read and Write return the error of a failed handshake.
+// Returns an error if the underlying listener fails.
+func (l *Listener) Accept() (net.Conn, error) {
+	conn, err := l.ln.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return newConn(conn, l.config, false), nil
+}
+
+// Close closes the underlying listener.
+func (l *Listener) Close() error {
+	return l.ln.Close()
+}
+
+// Addr returns the address of the underlying listener.
+func (l *Listener) Addr() net.Addr {
+	return l.ln.Addr()
+}
+
+// Client establishes a session as the client over the connection.
+// Returns an error if the config is invalid or the handshake fails.
+func Client(conn net.Conn, config Config) (*Conn, error) {
+	return ClientContext(context.Background(), conn, config)
+}
+
+// ClientContext is like Client but runs the handshake under the context. The
+// handshake is aborted once the context is done and ends at the context
+// deadline if that's earlier than the handshake timeout.
+// Returns an error if the config is invalid, the handshake fails or the
+// context is done.
+func ClientContext(ctx context.Context, conn net.Conn, config Config) (*Conn, error) {
+	c := newConn(conn, config, true)
+
+	err := c.HandshakeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Server establishes a session as the server over the connection.
+// Returns an error if the config is invalid or the handshake fails.
+func Server(conn net.Conn, config Config) (*Conn, error) {
+	return ServerContext(context.Background(), conn, config)
+}
+
+// ServerContext is like Server but runs the handshake under the context (see
+// ClientContext).
+// Returns an error if the config is invalid, the handshake fails or the
+// context is done.
+func ServerContext(ctx context.Context, conn net.Conn, config Config) (*Conn, error) {
+	c := newConn(conn, config, false)
+
+	err := c.HandshakeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// logHandshake logs the established session or the failed handshake.
+func (c *Conn) logHandshake(ctx context.Context, err error) {
+	role := "server"
+	if c.client {
+		role = "client"
+	}
+
+	mode, _ := c.config.mode()
+	attrs := []slog.Attr{
+		slog.String("role", role),
+		slog.String("mode", mode.String()),
+		slog.String("remote", addr(c.conn.RemoteAddr())),
+	}
+
+	if err != nil {
+		c.logger.LogAttrs(ctx, slog.LevelWarn, "session handshake failed", append(attrs, slog.String("error", err.Error()))...)
+		return
+	}
+
+	if mode != PSKMode {
+		attrs = append(attrs, slog.String("peer", hex.EncodeToString(c.peerPublicKey[:])))
+	}
+
+	c.logger.LogAttrs(ctx, slog.LevelInfo, "session established", attrs...)
+}
+
+// addr returns the address as a string (empty if there's none, e.g. for pipes
+// in tests).
+func addr(a net.Addr) string {
+	if a == nil {
+		return ""
+	}
+
+	return a.String()
+}
+
+// handshake runs the handshake under the context. A done context interrupts
+// pending reads and writes by moving the connection deadline into the past.
+func (c *Conn) handshake(ctx context.Context) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(c.config.handshakeTimeout())
+	d, ok := ctx.Deadline()
+	ok = ok && d.Before(deadline)
+	if ok {
+		deadline = d
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		c.conn.SetDeadline(time.Unix(1, 0))
+	})
+
+	err = c.exchange(deadline)
+	if !stop() {
+		// The context was done during the handshake (or right after it in
+		// which case the connection deadline is broken).
+		return ctx.Err()
+	}
+	if ok && errors.Is(err, os.ErrDeadlineExceeded) {
+		// The connection deadline can pass before the context notices it.
+		return context.DeadlineExceeded
+	}
+
+	return err
+}
+
+// exchange exchanges the handshake messages, derives the keys of both
+// directions and exchanges the finished records.
+func (c *Conn) exchange(deadline time.Time) error {
+	conn, config, client := c.conn, c.config, c.client
+
+	mode, err := config.mode()
+	if err != nil {
+		return err
+	}
+
+	err = conn.SetDeadline(deadline)
+	if err != nil {
+		return err
+	}
+
+	ephemeralPrivate, ephemeralPublic, err := x25519.GenerateKey()
+	if err != nil {
+		return err
+	}
+
+	own := []byte{Version, byte(mode)}
+	own = append(own, ephemeralPublic[:]...)
+	if mode != PSKMode {
+		staticPublic := x25519.PublicKey(config.PrivateKey)
+		own = append(own, staticPublic[:]...)
+	}
+
+	// The client sends its message first. The version and the mode are read
+	// before the rest of the message as the message size depends on the mode.
+	peer := make([]byte, len(own))
+	if client {
+		_, err = conn.Write(own)
+	}
+	if err == nil {
+		_, err = io.ReadFull(conn, peer[:2])
+	}
+	if err == nil && (peer[0] != Version || Mode(peer[1]) != mode) {
+		err = ErrHandshake
+	}
+	if err == nil {
+		_, err = io.ReadFull(conn, peer[2:])
+	}
+	if err == nil && !client {
+		_, err = conn.Write(own)
+	}
+	if err != nil {
+		return err
+	}
+
+	peerEphemeral := [32]byte(peer[2:34])
+
+	var peerStatic [32]byte
+	if mode != PSKMode {
+		peerStatic = [32]byte(peer[34:66])
+		if !slices.Contains(config.PeerPublicKeys, peerStatic) {
+			c.metrics.AuthFailed(metrics.SessionLayer)
+			return ErrUnknownPeer
+		}
+	}
+
+	ikm, err := inputKeyingMaterial(config, mode, client, ephemeralPrivate, peerEphemeral, peerStatic)
+	if err != nil {
+		return err
+	}
+
+	clientMessage, serverMessage := own, peer
+	if !client {
+		clientMessage, serverMessage = peer, own
+	}
+
+	transcript := sha256.Sum256(slices.Concat(transcriptLabel, clientMessage, serverMessage))
+
+	clientKey, _ := hkdf.Key(sha256.New, ikm, transcript[:], clientInfo, 32)
+	serverKey, _ := hkdf.Key(sha256.New, ikm, transcript[:], serverInfo, 32)
+	clear(ikm)
+
+	c.sendKey = [32]byte(serverKey)
+	c.receiveKey = [32]byte(clientKey)
+	if client {
+		c.sendKey, c.receiveKey = c.receiveKey, c.sendKey
+	}
+	c.peerPublicKey = peerStatic
+	c.established = c.clock.Now()
+
+	// The finished records confirm that both parties derived the same keys.
+	if client {
+		err = c.writeRecord(recordFinished, []byte{})
+	}
+	if err == nil {
+		err = c.readFinished()
+	}
+	if err == nil && !client {
+		err = c.writeRecord(recordFinished, []byte{})
+	}
+	if err != nil {
+		return err
+	}
+
+	// The deadlines that were set via the Conn (e.g. before a Read that ran
+	// the handshake) apply again.
+	return c.restoreDeadlines()
+}
+
+// inputKeyingMaterial computes the Diffie-Hellman results of the mode and
+// concatenates them and the PSK (see the package documentation).
+func inputKeyingMaterial(config Config, mode Mode, client bool, ephemeralPrivate [32]byte, peerEphemeral [32]byte, peerStatic [32]byte) ([]byte, error) {
+	ee, err := x25519.SharedSecret(ephemeralPrivate, peerEphemeral)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	if mode == PSKMode {
+		return slices.Concat(ee[:], config.PreSharedKey), nil
+	}
+
+	// es is the result of the client's ephemeral and the server's static key
+	// and se the one of the client's static and the server's ephemeral key.
+	var es, se [32]byte
+	if client {
+		es, err = x25519.SharedSecret(ephemeralPrivate, peerStatic)
+		if err == nil {
+			se, err = x25519.SharedSecret(config.PrivateKey, peerEphemeral)
+		}
+	} else {
+		es, err = x25519.SharedSecret(config.PrivateKey, peerEphemeral)
+		if err == nil {
+			se, err = x25519.SharedSecret(ephemeralPrivate, peerStatic)
+		}
+	}
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return slices.Concat(ee[:], es[:], se[:], config.PreSharedKey), nil
+}
@@ -0,0 +1,582 @@
+package session_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pmuens/ctk-go/ctk/clock"
+	"github.com/pmuens/ctk-go/ctk/logging"
+	"github.com/pmuens/ctk-go/ctk/metrics"
+	"github.com/pmuens/ctk-go/ctk/session"
+	"github.com/pmuens/ctk-go/ctk/x25519"
+)
+
+// handshake establishes a session over the pipe and returns both ends and the
+// errors of both handshakes. The pipe is closed if a handshake fails so that
+// the other party doesn't block.
+func handshake(clientPipe net.Conn, serverPipe net.Conn, clientConfig session.Config, serverConfig session.Config) (*session.Conn, *session.Conn, error, error) {
+
+	type result struct {
+		conn *session.Conn
+		err  error
+	}
+	clientDone := make(chan result, 1)
+	serverDone := make(chan result, 1)
+
+	go func() {
+		c, err := session.Client(clientPipe, clientConfig)
+		if err != nil {
+			clientPipe.Close()
+		}
+		clientDone <- result{conn: c, err: err}
+	}()
+
+	go func() {
+		s, err := session.Server(serverPipe, serverConfig)
+		if err != nil {
+			serverPipe.Close()
+		}
+		serverDone <- result{conn: s, err: err}
+	}()
+
+	client, server := <-clientDone, <-serverDone
+
+	return client.conn, server.conn, client.err, server.err
+}
+
+// syncBuffer is a bytes.Buffer which is safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// Write implements the io.Writer interface.
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+// String returns the written data.
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+// stallingConn writes the first bytes of every write once stall is set and
+// waits for resume before it writes the rest.
+type stallingConn struct {
+	net.Conn
+
+	stall  atomic.Bool
+	resume chan struct{}
+}
+
+// Write implements the io.Writer interface.
+func (c *stallingConn) Write(p []byte) (int, error) {
+	if !c.stall.Load() || len(p) < 5 {
+		return c.Conn.Write(p)
+	}
+
+	n, err := c.Conn.Write(p[:5])
+	if err != nil {
+		return n, err
+	}
+
+	<-c.resume
+
+	m, err := c.Conn.Write(p[5:])
+
+	return n + m, err
+}
+
+func TestSession(t *testing.T) {
+	clientPrivate, clientPublic, _ := x25519.GenerateKey()
+	serverPrivate, serverPublic, _ := x25519.GenerateKey()
+	_, otherPublic, _ := x25519.GenerateKey()
+
+	clientConfig := session.Config{PrivateKey: clientPrivate, PeerPublicKeys: [][32]byte{serverPublic}}
+	serverConfig := session.Config{PrivateKey: serverPrivate, PeerPublicKeys: [][32]byte{otherPublic, clientPublic}}
+
+	psk := make([]byte, session.MinPreSharedKeySize)
+	psk[0] = 0x01
+	pskConfig := session.Config{PreSharedKey: psk}
+
+	hybridClientConfig := clientConfig
+	hybridClientConfig.PreSharedKey = psk
+	hybridServerConfig := serverConfig
+	hybridServerConfig.PreSharedKey = psk
+
+	t.Run("Modes", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]struct {
+			clientConfig session.Config
+			serverConfig session.Config
+			clientPeer   [32]byte
+			serverPeer   [32]byte
+		}{
+			"Static Key":       {clientConfig: clientConfig, serverConfig: serverConfig, clientPeer: serverPublic, serverPeer: clientPublic},
+			"PSK":              {clientConfig: pskConfig, serverConfig: pskConfig},
+			"Static Key + PSK": {clientConfig: hybridClientConfig, serverConfig: hybridServerConfig, clientPeer: serverPublic, serverPeer: clientPublic},
+		}
+
+		for name, tc := range tt {
+			clientPipe, serverPipe := net.Pipe()
+
+			client, server, clientErr, serverErr := handshake(clientPipe, serverPipe, tc.clientConfig, tc.serverConfig)
+			if clientErr != nil || serverErr != nil {
+				t.Fatalf("%v: want errors %v, got %v and %v", name, nil, clientErr, serverErr)
+			}
+
+			if client.PeerPublicKey() != tc.clientPeer || server.PeerPublicKey() != tc.serverPeer {
+				t.Errorf("%v: want peer public keys to match", name)
+			}
+
+			request := make([]byte, 3*session.MaxRecordSize+1)
+			for i := range request {
+				request[i] = byte(i)
+			}
+
+			go func() {
+				client.Write(request)
+				client.CloseWrite()
+			}()
+
+			got, err := io.ReadAll(server)
+			if err != nil {
+				t.Fatalf("%v: want error %v, got %v", name, nil, err)
+			}
+
+			if !slices.Equal(got, request) {
+				t.Errorf("%v: want request to match", name)
+			}
+
+			go server.Close()
+
+			got, err = io.ReadAll(client)
+			if err != nil || len(got) != 0 {
+				t.Errorf("%v: want empty response and error %v, got %v and %v", name, nil, got, err)
+			}
+
+			_, err = client.Write([]byte{0x01})
+			if !errors.Is(err, session.ErrClosed) {
+				t.Errorf("%v: want error %v, got %v", name, session.ErrClosed, err)
+			}
+		}
+	})
+
+	t.Run("Handshake Errors", func(t *testing.T) {
+		t.Parallel()
+
+		otherPSK := slices.Clone(psk)
+		otherPSK[0] = 0x02
+
+		tt := map[string]struct {
+			clientConfig session.Config
+			serverConfig session.Config
+			want         error
+		}{
+			"Unknown Client": {
+				clientConfig: clientConfig,
+				serverConfig: session.Config{PrivateKey: serverPrivate, PeerPublicKeys: [][32]byte{otherPublic}},
+				want:         session.ErrUnknownPeer,
+			},
+			"Unknown Server": {
+				clientConfig: session.Config{PrivateKey: clientPrivate, PeerPublicKeys: [][32]byte{otherPublic}},
+				serverConfig: serverConfig,
+				want:         session.ErrUnknownPeer,
+			},
+			"Wrong PSK": {
+				clientConfig: pskConfig,
+				serverConfig: session.Config{PreSharedKey: otherPSK},
+				want:         session.ErrDecryption,
+			},
+			"Wrong PSK With Static Keys": {
+				clientConfig: hybridClientConfig,
+				serverConfig: session.Config{PrivateKey: serverPrivate, PeerPublicKeys: [][32]byte{clientPublic}, PreSharedKey: otherPSK},
+				want:         session.ErrDecryption,
+			},
+			"Missing PSK": {
+				clientConfig: clientConfig,
+				serverConfig: hybridServerConfig,
+				want:         session.ErrHandshake,
+			},
+			"Mode Mismatch": {
+				clientConfig: pskConfig,
+				serverConfig: serverConfig,
+				want:         session.ErrHandshake,
+			},
+		}
+
+		for name, tc := range tt {
+			clientPipe, serverPipe := net.Pipe()
+
+			_, _, clientErr, serverErr := handshake(clientPipe, serverPipe, tc.clientConfig, tc.serverConfig)
+
+			if !errors.Is(clientErr, tc.want) && !errors.Is(serverErr, tc.want) {
+				t.Errorf("%v: want error %v, got %v and %v", name, tc.want, clientErr, serverErr)
+			}
+		}
+	})
+
+	t.Run("Invalid Config", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]session.Config{
+			"Empty":                {},
+			"No Peers":             {PrivateKey: clientPrivate},
+			"No Private Key":       {PeerPublicKeys: [][32]byte{serverPublic}},
+			"Short PSK":            {PreSharedKey: psk[:session.MinPreSharedKeySize-1]},
+			"Static And Short PSK": {PrivateKey: clientPrivate, PeerPublicKeys: [][32]byte{serverPublic}, PreSharedKey: psk[:1]},
+			"Private And PSK":      {PrivateKey: clientPrivate, PreSharedKey: psk},
+		}
+
+		for name, config := range tt {
+			client, _ := net.Pipe()
+
+			_, err := session.Client(client, config)
+			if !errors.Is(err, session.ErrInvalidConfig) {
+				t.Errorf("%v: want error %v, got %v", name, session.ErrInvalidConfig, err)
+			}
+
+			_, err = session.Dial("tcp", "127.0.0.1:0", config)
+			if !errors.Is(err, session.ErrInvalidConfig) {
+				t.Errorf("%v: want error %v, got %v", name, session.ErrInvalidConfig, err)
+			}
+		}
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		t.Parallel()
+
+		clientPipe, serverPipe := net.Pipe()
+
+		client, server, clientErr, serverErr := handshake(clientPipe, serverPipe, pskConfig, pskConfig)
+		if clientErr != nil || serverErr != nil {
+			t.Fatalf("want errors %v, got %v and %v", nil, clientErr, serverErr)
+		}
+
+		go func() {
+			client.Write([]byte("attack at"))
+			// Close the underlying connection without a close record.
+			clientPipe.Close()
+		}()
+
+		_, err := io.ReadAll(server)
+		if !errors.Is(err, session.ErrDecryption) {
+			t.Errorf("want error %v, got %v", session.ErrDecryption, err)
+		}
+	})
+
+	t.Run("Clock", func(t *testing.T) {
+		t.Parallel()
+
+		sink := metrics.NewPrometheus()
+		c := clock.NewManual(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+		clockServerConfig := serverConfig
+		clockServerConfig.Metrics = sink
+		clockServerConfig.Clock = c
+
+		clientPipe, serverPipe := net.Pipe()
+
+		client, server, clientErr, serverErr := handshake(clientPipe, serverPipe, clientConfig, clockServerConfig)
+		if clientErr != nil || serverErr != nil {
+			t.Fatalf("want errors %v, got %v and %v", nil, clientErr, serverErr)
+		}
+
+		go func() {
+			client.Write([]byte("ping"))
+			client.CloseWrite()
+		}()
+
+		c.Advance(time.Hour)
+		io.ReadAll(server)
+
+		var got strings.Builder
+		sink.WriteTo(&got)
+
+		want := `ctk_key_age_seconds{layer="session"} 3600`
+		if !strings.Contains(got.String(), want) {
+			t.Errorf("want %v in %v", want, got.String())
+		}
+	})
+
+	t.Run("Metrics", func(t *testing.T) {
+		t.Parallel()
+
+		sink := metrics.NewPrometheus()
+
+		metricsClientConfig := clientConfig
+		metricsClientConfig.Metrics = sink
+		metricsServerConfig := serverConfig
+		metricsServerConfig.Metrics = sink
+
+		clientPipe, serverPipe := net.Pipe()
+
+		client, server, clientErr, serverErr := handshake(clientPipe, serverPipe, metricsClientConfig, metricsServerConfig)
+		if clientErr != nil || serverErr != nil {
+			t.Fatalf("want errors %v, got %v and %v", nil, clientErr, serverErr)
+		}
+
+		go func() {
+			client.Write([]byte("ping"))
+			client.CloseWrite()
+		}()
+
+		_, err := io.ReadAll(server)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		// The server rejects a client with an unknown static key.
+		otherPrivate, _, _ := x25519.GenerateKey()
+		unknownClientConfig := session.Config{PrivateKey: otherPrivate, PeerPublicKeys: [][32]byte{serverPublic}}
+
+		clientPipe, serverPipe = net.Pipe()
+
+		_, _, _, serverErr = handshake(clientPipe, serverPipe, unknownClientConfig, metricsServerConfig)
+		if !errors.Is(serverErr, session.ErrUnknownPeer) {
+			t.Fatalf("want error %v, got %v", session.ErrUnknownPeer, serverErr)
+		}
+
+		var got strings.Builder
+		sink.WriteTo(&got)
+
+		for _, want := range []string{
+			`ctk_operations_total{layer="session",operation="seal"} 1`,
+			`ctk_operations_total{layer="session",operation="open"} 1`,
+			`ctk_bytes_total{layer="session",operation="seal"} 4`,
+			`ctk_auth_failures_total{layer="session"} 1`,
+			`ctk_key_age_seconds{layer="session"} `,
+		} {
+			if !strings.Contains(got.String(), want) {
+				t.Errorf("want %v in %v", want, got.String())
+			}
+		}
+	})
+
+	t.Run("Logger", func(t *testing.T) {
+		t.Parallel()
+
+		var buf syncBuffer
+		logger := slog.New(logging.Redact(slog.NewTextHandler(&buf, nil)))
+
+		loggingServerConfig := serverConfig
+		loggingServerConfig.Logger = logger
+
+		clientPipe, serverPipe := net.Pipe()
+
+		_, _, clientErr, serverErr := handshake(clientPipe, serverPipe, clientConfig, loggingServerConfig)
+		if clientErr != nil || serverErr != nil {
+			t.Fatalf("want errors %v, got %v and %v", nil, clientErr, serverErr)
+		}
+
+		otherPrivate, _, _ := x25519.GenerateKey()
+		unknownClientConfig := session.Config{PrivateKey: otherPrivate, PeerPublicKeys: [][32]byte{serverPublic}}
+
+		clientPipe, serverPipe = net.Pipe()
+		handshake(clientPipe, serverPipe, unknownClientConfig, loggingServerConfig)
+
+		for _, want := range []string{
+			`msg="session established" role=server mode="static key" remote=pipe peer=` + hex.EncodeToString(clientPublic[:]),
+			`msg="session handshake failed" role=server mode="static key" remote=pipe error="unknown peer"`,
+		} {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("want %v in %v", want, buf.String())
+			}
+		}
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		t.Parallel()
+
+		cancelled, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		clientPipe, serverPipe := net.Pipe()
+		defer serverPipe.Close()
+
+		_, err := session.ClientContext(cancelled, clientPipe, clientConfig)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("want error %v, got %v", context.Canceled, err)
+		}
+
+		// The server never responds so that the handshake only ends via the
+		// context.
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+
+		clientPipe, serverPipe = net.Pipe()
+		defer serverPipe.Close()
+		go io.Copy(io.Discard, serverPipe)
+
+		_, err = session.ClientContext(ctx, clientPipe, clientConfig)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("want error %v, got %v", context.Canceled, err)
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		clientPipe, serverPipe = net.Pipe()
+		defer serverPipe.Close()
+
+		_, err = session.ServerContext(ctx, serverPipe, serverConfig)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("want error %v, got %v", context.DeadlineExceeded, err)
+		}
+	})
+
+	t.Run("Read Timeout", func(t *testing.T) {
+		t.Parallel()
+
+		clientPipe, serverPipe := net.Pipe()
+		stalling := &stallingConn{Conn: clientPipe, resume: make(chan struct{})}
+
+		client, server, clientErr, serverErr := handshake(stalling, serverPipe, pskConfig, pskConfig)
+		if clientErr != nil || serverErr != nil {
+			t.Fatalf("want errors %v, got %v and %v", nil, clientErr, serverErr)
+		}
+		defer clientPipe.Close()
+		defer serverPipe.Close()
+
+		// The read times out in the middle of the record.
+		stalling.stall.Store(true)
+		go client.Write([]byte("attack at dawn"))
+
+		server.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+
+		_, err := server.Read(make([]byte, 64))
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Fatalf("want error %v, got %v", os.ErrDeadlineExceeded, err)
+		}
+
+		// The next read resumes the record.
+		close(stalling.resume)
+		server.SetReadDeadline(time.Time{})
+
+		got := make([]byte, 64)
+
+		n, err := server.Read(got)
+		if err != nil || string(got[:n]) != "attack at dawn" {
+			t.Errorf("want %v, got %s (error %v)", "attack at dawn", got[:n], err)
+		}
+	})
+
+	t.Run("Lazy Handshake", func(t *testing.T) {
+		t.Parallel()
+
+		ln, err := session.Listen("tcp", "127.0.0.1:0", serverConfig)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+		defer ln.Close()
+
+		// A client that never sends its handshake message doesn't block Accept.
+		silent, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+		defer silent.Close()
+
+		conn, err := ln.Accept()
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+		defer conn.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err = conn.(*session.Conn).HandshakeContext(ctx)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("want error %v, got %v", context.DeadlineExceeded, err)
+		}
+
+		// The error of the failed handshake is returned by Read and Write.
+		_, err = conn.Write([]byte("ping"))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("want error %v, got %v", context.DeadlineExceeded, err)
+		}
+
+		// The handshake of the next connection runs on its first Read.
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			io.Copy(conn, conn)
+		}()
+
+		client, err := session.Dial("tcp", ln.Addr().String(), clientConfig)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+		defer client.Close()
+
+		client.Write([]byte("ping"))
+		got := make([]byte, 4)
+
+		_, err = io.ReadFull(client, got)
+		if err != nil || string(got) != "ping" {
+			t.Errorf("want %v, got %s (error %v)", "ping", got, err)
+		}
+	})
+
+	t.Run("Dial + Listen", func(t *testing.T) {
+		t.Parallel()
+
+		ln, err := session.Listen("tcp", "127.0.0.1:0", serverConfig)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+		defer ln.Close()
+
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			io.Copy(conn, conn)
+		}()
+
+		conn, err := session.Dial("tcp", ln.Addr().String(), clientConfig)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+		defer conn.Close()
+
+		_, err = conn.Write([]byte("ping"))
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		got := make([]byte, 4)
+
+		_, err = io.ReadFull(conn, got)
+		if err != nil || string(got) != "ping" {
+			t.Errorf("want %v, got %s (error %v)", "ping", got, err)
+		}
+	})
+}
@@ -0,0 +1,309 @@
+// Package sha3 implements the SHA-3 hash functions and the SHAKE extendable
+// output functions (XOFs) as specified in
+// https://nvlpubs.nist.gov/nistpubs/FIPS/NIST.FIPS.202.pdf.
+package sha3
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+const (
+	// ErrWriteAfterRead is returned if data is written to a SHAKE instance
+	// after output was read from it.
+	ErrWriteAfterRead = Error("write after read")
+)
+
+// Size256 is the size (in bytes) of a SHA3-256 digest.
+const Size256 = 32
+
+// Size512 is the size (in bytes) of a SHA3-512 digest.
+const Size512 = 64
+
+// stateSize is the size (in bytes) of the Keccak-f[1600] state.
+const stateSize = 200
+
+// Domain separation suffixes (including the first bit of the padding).
+const (
+	// dsSHA3 is the suffix of the SHA-3 hash functions.
+	dsSHA3 = 0x06
+
+	// dsSHAKE is the suffix of the SHAKE XOFs.
+	dsSHAKE = 0x1f
+)
+
+// roundConstants are the constants of the iota step.
+var roundConstants = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808a, 0x8000000080008000,
+	0x000000000000808b, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008a, 0x0000000000000088, 0x0000000080008009, 0x000000008000000a,
+	0x000000008000808b, 0x800000000000008b, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800a, 0x800000008000000a,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+// rotations are the offsets of the rho step (indexed by x + 5 * y).
+var rotations = [25]int{
+	0, 1, 62, 28, 27,
+	36, 44, 6, 55, 20,
+	3, 10, 43, 25, 39,
+	41, 45, 15, 21, 8,
+	18, 2, 61, 56, 14,
+}
+
+// sponge is an instance of the Keccak sponge construction.
+type sponge struct {
+	// a is the state (lane x, y is at index x + 5 * y).
+	a [25]uint64
+
+	// buf buffers the input that hasn't been absorbed yet (or the output that
+	// wasn't read yet once squeezing started).
+	buf [stateSize]byte
+
+	// n is the number of bytes in buf (or the number of bytes of buf that were
+	// already read once squeezing started).
+	n int
+
+	// rate is the number of bytes absorbed (or squeezed) per permutation.
+	rate int
+
+	// ds is the domain separation suffix.
+	ds byte
+
+	// squeezing indicates whether output was read.
+	squeezing bool
+}
+
+// absorb adds the data to the state.
+func (s *sponge) absorb(data []byte) {
+	for len(data) > 0 {
+		copied := copy(s.buf[s.n:s.rate], data)
+		s.n += copied
+		data = data[copied:]
+
+		if s.n == s.rate {
+			s.xorBuf()
+			keccakF1600(&s.a)
+			s.n = 0
+		}
+	}
+}
+
+// squeeze reads output from the state. The input is padded and the last block
+// is absorbed on the first call.
+func (s *sponge) squeeze(out []byte) {
+	if !s.squeezing {
+		// pad10*1 with the domain separation suffix.
+		clear(s.buf[s.n:s.rate])
+		s.buf[s.n] ^= s.ds
+		s.buf[s.rate-1] ^= 0x80
+		s.xorBuf()
+		keccakF1600(&s.a)
+
+		s.encodeState()
+		s.n = 0
+		s.squeezing = true
+	}
+
+	for len(out) > 0 {
+		if s.n == s.rate {
+			keccakF1600(&s.a)
+			s.encodeState()
+			s.n = 0
+		}
+
+		copied := copy(out, s.buf[s.n:s.rate])
+		s.n += copied
+		out = out[copied:]
+	}
+}
+
+// xorBuf XORs the first rate bytes of buf into the state.
+func (s *sponge) xorBuf() {
+	for i := range s.rate / 8 {
+		s.a[i] ^= binary.LittleEndian.Uint64(s.buf[i*8:])
+	}
+}
+
+// encodeState writes the state to buf in little endian order.
+func (s *sponge) encodeState() {
+	for i, lane := range s.a {
+		binary.LittleEndian.PutUint64(s.buf[i*8:], lane)
+	}
+}
+
+// keccakF1600 applies the Keccak-f[1600] permutation to the state.
+func keccakF1600(a *[25]uint64) {
+	for _, rc := range roundConstants {
+		// θ step.
+		var c [5]uint64
+		for x := range 5 {
+			c[x] = a[x] ^ a[x+5] ^ a[x+10] ^ a[x+15] ^ a[x+20]
+		}
+		for x := range 5 {
+			d := c[(x+4)%5] ^ bits.RotateLeft64(c[(x+1)%5], 1)
+			for y := range 5 {
+				a[x+5*y] ^= d
+			}
+		}
+
+		// ρ and π steps.
+		var b [25]uint64
+		for x := range 5 {
+			for y := range 5 {
+				b[y+5*((2*x+3*y)%5)] = bits.RotateLeft64(a[x+5*y], rotations[x+5*y])
+			}
+		}
+
+		// χ step.
+		for x := range 5 {
+			for y := range 5 {
+				a[x+5*y] = b[x+5*y] ^ (^b[(x+1)%5+5*y] & b[(x+2)%5+5*y])
+			}
+		}
+
+		// ι step.
+		a[0] ^= rc
+	}
+}
+
+// Hash is an instance of a SHA-3 hash function.
+// It implements the hash.Hash interface.
+// An instance isn't safe for concurrent use.
+type Hash struct {
+	// sponge is the underlying sponge.
+	sponge sponge
+
+	// size is the digest size (in bytes).
+	size int
+}
+
+// newHash creates a new instance of the SHA-3 hash function with the digest
+// size.
+func newHash(size int) *Hash {
+	return &Hash{
+		sponge: sponge{rate: stateSize - 2*size, ds: dsSHA3},
+		size:   size,
+	}
+}
+
+// New256 returns a hash.Hash computing SHA3-256 digests.
+func New256() hash.Hash {
+	return newHash(Size256)
+}
+
+// New512 returns a hash.Hash computing SHA3-512 digests.
+func New512() hash.Hash {
+	return newHash(Size512)
+}
+
+// Sum256 returns the SHA3-256 digest of the data.
+func Sum256(data []byte) [32]byte {
+	h := newHash(Size256)
+	h.Write(data)
+
+	return [32]byte(h.Sum(nil))
+}
+
+// Sum512 returns the SHA3-512 digest of the data.
+func Sum512(data []byte) [64]byte {
+	h := newHash(Size512)
+	h.Write(data)
+
+	return [64]byte(h.Sum(nil))
+}
+
+// Write adds the data to the hash. It never returns an error.
+func (h *Hash) Write(data []byte) (int, error) {
+	h.sponge.absorb(data)
+	return len(data), nil
+}
+
+// Sum appends the digest to in and returns the result.
+// The state of the instance isn't modified.
+func (h *Hash) Sum(in []byte) []byte {
+	// Work on a copy so that more data can be written afterwards.
+	s := h.sponge
+
+	digest := make([]byte, h.size)
+	s.squeeze(digest)
+
+	return append(in, digest...)
+}
+
+// Reset resets the instance to its initial state.
+func (h *Hash) Reset() {
+	h.sponge = sponge{rate: h.sponge.rate, ds: dsSHA3}
+}
+
+// Size returns the digest size (in bytes).
+func (h *Hash) Size() int {
+	return h.size
+}
+
+// BlockSize returns the rate (in bytes) of the sponge.
+func (h *Hash) BlockSize() int {
+	return h.sponge.rate
+}
+
+// Shake is an instance of a SHAKE extendable output function. Data is written
+// to it before an arbitrary amount of output is read from it.
+// An instance isn't safe for concurrent use.
+type Shake struct {
+	// sponge is the underlying sponge.
+	sponge sponge
+}
+
+// NewShake128 creates a new instance of SHAKE128.
+func NewShake128() *Shake {
+	return &Shake{sponge: sponge{rate: 168, ds: dsSHAKE}}
+}
+
+// NewShake256 creates a new instance of SHAKE256.
+func NewShake256() *Shake {
+	return &Shake{sponge: sponge{rate: 136, ds: dsSHAKE}}
+}
+
+// Write adds the data to the input.
+// Returns an error if output was already read.
+func (s *Shake) Write(data []byte) (int, error) {
+	if s.sponge.squeezing {
+		return 0, ErrWriteAfterRead
+	}
+
+	s.sponge.absorb(data)
+
+	return len(data), nil
+}
+
+// Read reads the next len(out) bytes of output. It never returns an error.
+func (s *Shake) Read(out []byte) (int, error) {
+	s.sponge.squeeze(out)
+	return len(out), nil
+}
+
+// Reset resets the instance to its initial state.
+func (s *Shake) Reset() {
+	s.sponge = sponge{rate: s.sponge.rate, ds: dsSHAKE}
+}
+
+// SumShake128 returns length bytes of SHAKE128 output for the data.
+func SumShake128(data []byte, length int) []byte {
+	return sumShake(NewShake128(), data, length)
+}
+
+// SumShake256 returns length bytes of SHAKE256 output for the data.
+func SumShake256(data []byte, length int) []byte {
+	return sumShake(NewShake256(), data, length)
+}
+
+// sumShake writes the data to the instance and reads length bytes of output.
+func sumShake(s *Shake, data []byte, length int) []byte {
+	s.Write(data)
+
+	out := make([]byte, length)
+	s.Read(out)
+
+	return out
+}
@@ -0,0 +1,178 @@
+package sha3_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/sha3"
+)
+
+func TestSHA3(t *testing.T) {
+	// data200 spans more than one block of every function.
+	data200 := make([]byte, 200)
+	for i := range data200 {
+		data200[i] = byte(i)
+	}
+
+	// The digests were generated with Python's hashlib (the "abc" vectors match
+	// the FIPS 202 examples).
+	tt := map[string]struct {
+		data     []byte
+		sha3256  [32]byte
+		sha3512  [64]byte
+		shake128 []byte
+		shake256 []byte
+	}{
+		"abc": {
+			data: []byte("abc"),
+			sha3256: [32]byte{
+				0x3a, 0x98, 0x5d, 0xa7, 0x4f, 0xe2, 0x25, 0xb2, 0x04, 0x5c, 0x17, 0x2d, 0x6b, 0xd3, 0x90, 0xbd,
+				0x85, 0x5f, 0x08, 0x6e, 0x3e, 0x9d, 0x52, 0x5b, 0x46, 0xbf, 0xe2, 0x45, 0x11, 0x43, 0x15, 0x32,
+			},
+			sha3512: [64]byte{
+				0xb7, 0x51, 0x85, 0x0b, 0x1a, 0x57, 0x16, 0x8a, 0x56, 0x93, 0xcd, 0x92, 0x4b, 0x6b, 0x09, 0x6e,
+				0x08, 0xf6, 0x21, 0x82, 0x74, 0x44, 0xf7, 0x0d, 0x88, 0x4f, 0x5d, 0x02, 0x40, 0xd2, 0x71, 0x2e,
+				0x10, 0xe1, 0x16, 0xe9, 0x19, 0x2a, 0xf3, 0xc9, 0x1a, 0x7e, 0xc5, 0x76, 0x47, 0xe3, 0x93, 0x40,
+				0x57, 0x34, 0x0b, 0x4c, 0xf4, 0x08, 0xd5, 0xa5, 0x65, 0x92, 0xf8, 0x27, 0x4e, 0xec, 0x53, 0xf0,
+			},
+			shake128: []byte{
+				0x58, 0x81, 0x09, 0x2d, 0xd8, 0x18, 0xbf, 0x5c, 0xf8, 0xa3, 0xdd, 0xb7, 0x93, 0xfb, 0xcb, 0xa7,
+				0x40, 0x97, 0xd5, 0xc5, 0x26, 0xa6, 0xd3, 0x5f, 0x97, 0xb8, 0x33, 0x51, 0x94, 0x0f, 0x2c, 0xc8,
+			},
+			shake256: []byte{
+				0x48, 0x33, 0x66, 0x60, 0x13, 0x60, 0xa8, 0x77, 0x1c, 0x68, 0x63, 0x08, 0x0c, 0xc4, 0x11, 0x4d,
+				0x8d, 0xb4, 0x45, 0x30, 0xf8, 0xf1, 0xe1, 0xee, 0x4f, 0x94, 0xea, 0x37, 0xe7, 0x8b, 0x57, 0x39,
+				0xd5, 0xa1, 0x5b, 0xef, 0x18, 0x6a, 0x53, 0x86, 0xc7, 0x57, 0x44, 0xc0, 0x52, 0x7e, 0x1f, 0xaa,
+				0x9f, 0x87, 0x26, 0xe4, 0x62, 0xa1, 0x2a, 0x4f, 0xeb, 0x06, 0xbd, 0x88, 0x01, 0xe7, 0x51, 0xe4,
+			},
+		},
+		"Empty": {
+			data: []byte{},
+			sha3256: [32]byte{
+				0xa7, 0xff, 0xc6, 0xf8, 0xbf, 0x1e, 0xd7, 0x66, 0x51, 0xc1, 0x47, 0x56, 0xa0, 0x61, 0xd6, 0x62,
+				0xf5, 0x80, 0xff, 0x4d, 0xe4, 0x3b, 0x49, 0xfa, 0x82, 0xd8, 0x0a, 0x4b, 0x80, 0xf8, 0x43, 0x4a,
+			},
+			sha3512: [64]byte{
+				0xa6, 0x9f, 0x73, 0xcc, 0xa2, 0x3a, 0x9a, 0xc5, 0xc8, 0xb5, 0x67, 0xdc, 0x18, 0x5a, 0x75, 0x6e,
+				0x97, 0xc9, 0x82, 0x16, 0x4f, 0xe2, 0x58, 0x59, 0xe0, 0xd1, 0xdc, 0xc1, 0x47, 0x5c, 0x80, 0xa6,
+				0x15, 0xb2, 0x12, 0x3a, 0xf1, 0xf5, 0xf9, 0x4c, 0x11, 0xe3, 0xe9, 0x40, 0x2c, 0x3a, 0xc5, 0x58,
+				0xf5, 0x00, 0x19, 0x9d, 0x95, 0xb6, 0xd3, 0xe3, 0x01, 0x75, 0x85, 0x86, 0x28, 0x1d, 0xcd, 0x26,
+			},
+			shake128: []byte{
+				0x7f, 0x9c, 0x2b, 0xa4, 0xe8, 0x8f, 0x82, 0x7d, 0x61, 0x60, 0x45, 0x50, 0x76, 0x05, 0x85, 0x3e,
+				0xd7, 0x3b, 0x80, 0x93, 0xf6, 0xef, 0xbc, 0x88, 0xeb, 0x1a, 0x6e, 0xac, 0xfa, 0x66, 0xef, 0x26,
+			},
+			shake256: []byte{
+				0x46, 0xb9, 0xdd, 0x2b, 0x0b, 0xa8, 0x8d, 0x13, 0x23, 0x3b, 0x3f, 0xeb, 0x74, 0x3e, 0xeb, 0x24,
+				0x3f, 0xcd, 0x52, 0xea, 0x62, 0xb8, 0x1b, 0x82, 0xb5, 0x0c, 0x27, 0x64, 0x6e, 0xd5, 0x76, 0x2f,
+				0xd7, 0x5d, 0xc4, 0xdd, 0xd8, 0xc0, 0xf2, 0x00, 0xcb, 0x05, 0x01, 0x9d, 0x67, 0xb5, 0x92, 0xf6,
+				0xfc, 0x82, 0x1c, 0x49, 0x47, 0x9a, 0xb4, 0x86, 0x40, 0x29, 0x2e, 0xac, 0xb3, 0xb7, 0xc4, 0xbe,
+			},
+		},
+		"200 Bytes": {
+			data: data200,
+			sha3256: [32]byte{
+				0x5f, 0x72, 0x8f, 0x63, 0xbf, 0x5e, 0xe4, 0x8c, 0x77, 0xf4, 0x53, 0xc0, 0x49, 0x03, 0x98, 0xfa,
+				0x64, 0x5b, 0x8d, 0x4c, 0x4e, 0x56, 0xbe, 0x9a, 0x41, 0xcf, 0xec, 0x34, 0x4d, 0x6c, 0xa8, 0x99,
+			},
+			sha3512: [64]byte{
+				0xea, 0x5d, 0x05, 0xf1, 0x93, 0x48, 0xdd, 0x58, 0x97, 0x93, 0x35, 0x47, 0x93, 0xa1, 0x5f, 0x37,
+				0xa7, 0x3b, 0x4c, 0x0b, 0xb4, 0xe7, 0x50, 0xb9, 0xa0, 0x07, 0x57, 0xdf, 0xce, 0x2f, 0x8b, 0x65,
+				0xa6, 0x41, 0x91, 0xbb, 0x9b, 0x13, 0x7d, 0xe0, 0x0f, 0xee, 0xf6, 0x47, 0x4c, 0xfd, 0x47, 0xab,
+				0xf7, 0x88, 0x0e, 0xfb, 0xc5, 0x16, 0x14, 0xa5, 0x71, 0x5d, 0xf1, 0x2c, 0xfe, 0x0c, 0xae, 0xe3,
+			},
+			shake128: []byte{
+				0x0c, 0x42, 0x34, 0xca, 0x1e, 0x31, 0x80, 0x1a, 0xe6, 0x06, 0xf8, 0xb8, 0xd8, 0xe0, 0x66, 0x5c,
+				0x66, 0xf4, 0x2a, 0x21, 0xd6, 0x01, 0xc2, 0x68, 0x18, 0x58, 0xa9, 0x2c, 0x79, 0xad, 0x5d, 0x69,
+			},
+			shake256: []byte{
+				0x4e, 0xe1, 0xca, 0x03, 0x27, 0x2b, 0x05, 0xd3, 0xbf, 0xb1, 0xe1, 0xc7, 0x9a, 0x96, 0x7f, 0x82,
+				0x3b, 0x9f, 0xc5, 0xe4, 0xbb, 0x39, 0x87, 0xb1, 0xba, 0x9e, 0x9c, 0xb5, 0xaf, 0xb0, 0x7a, 0x5e,
+				0xe3, 0xa0, 0x7f, 0xbd, 0x45, 0x7a, 0x94, 0x36, 0x49, 0x64, 0xa8, 0x41, 0xe7, 0xf4, 0x66, 0xe5,
+				0xa0, 0x22, 0xe2, 0x1a, 0xb7, 0xf6, 0x73, 0xc1, 0x8b, 0xa9, 0x8c, 0xdb, 0x1d, 0x5a, 0xec, 0xfa,
+			},
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := sha3.Sum256(tc.data); got != tc.sha3256 {
+				t.Errorf("SHA3-256: want %v, got %v", tc.sha3256, got)
+			}
+
+			if got := sha3.Sum512(tc.data); got != tc.sha3512 {
+				t.Errorf("SHA3-512: want %v, got %v", tc.sha3512, got)
+			}
+
+			if got := sha3.SumShake128(tc.data, len(tc.shake128)); !slices.Equal(got, tc.shake128) {
+				t.Errorf("SHAKE128: want %v, got %v", tc.shake128, got)
+			}
+
+			if got := sha3.SumShake256(tc.data, len(tc.shake256)); !slices.Equal(got, tc.shake256) {
+				t.Errorf("SHAKE256: want %v, got %v", tc.shake256, got)
+			}
+		})
+	}
+}
+
+func TestSHA3Incremental(t *testing.T) {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	t.Run("Hash", func(t *testing.T) {
+		t.Parallel()
+
+		h := sha3.New256()
+		for chunk := range slices.Chunk(data, 37) {
+			h.Write(chunk)
+		}
+
+		want := sha3.Sum256(data)
+		if got := h.Sum(nil); !slices.Equal(got, want[:]) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+
+		// Sum doesn't modify the state.
+		if got := h.Sum(nil); !slices.Equal(got, want[:]) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+
+		h.Reset()
+		h.Write(data)
+		if got := h.Sum(nil); !slices.Equal(got, want[:]) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("SHAKE", func(t *testing.T) {
+		t.Parallel()
+
+		s := sha3.NewShake128()
+		for chunk := range slices.Chunk(data, 37) {
+			s.Write(chunk)
+		}
+
+		// Read the output in odd sized pieces that cross the rate.
+		var got []byte
+		for range 20 {
+			out := make([]byte, 23)
+			s.Read(out)
+			got = append(got, out...)
+		}
+
+		want := sha3.SumShake128(data, len(got))
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+
+		_, err := s.Write([]byte{0x01})
+		if !errors.Is(err, sha3.ErrWriteAfterRead) {
+			t.Errorf("want error %v, got %v", sha3.ErrWriteAfterRead, err)
+		}
+	})
+}
@@ -0,0 +1,179 @@
+// Package shamir implements Shamir's secret sharing over GF(256) as described
+// in https://dl.acm.org/doi/10.1145/359168.359176.
+//
+// Every byte of the secret is shared independently via a random polynomial of
+// degree threshold - 1. A share consists of its x-coordinate (1 byte) followed
+// by the evaluations of the polynomials at such x-coordinate.
+package shamir
+
+import "github.com/pmuens/ctk-go/ctk/random"
+
+const (
+	// ErrEmptySecret is returned if the secret to be split is empty.
+	ErrEmptySecret = Error("empty secret")
+
+	// ErrInvalidThreshold is returned if the threshold is smaller than 2 or
+	// larger than the number of shares.
+	ErrInvalidThreshold = Error("invalid threshold")
+
+	// ErrInvalidShareCount is returned if more than 255 shares are requested.
+	ErrInvalidShareCount = Error("invalid number of shares")
+
+	// ErrInvalidShares is returned if the shares can't be combined (e.g. because
+	// there are fewer than 2, their lengths differ or their x-coordinates aren't
+	// unique).
+	ErrInvalidShares = Error("invalid shares")
+)
+
+// MaxShares is the maximum number of shares a secret can be split into.
+const MaxShares = 255
+
+// Split splits the secret into n shares, any threshold of which can be used to
+// reconstruct the secret.
+// Returns an error if the secret is empty, n exceeds MaxShares or the
+// threshold isn't in the range [2, n].
+func Split(secret []byte, n int, threshold int) ([][]byte, error) {
+	if len(secret) == 0 {
+		return [][]byte{}, ErrEmptySecret
+	}
+
+	if n > MaxShares {
+		return [][]byte{}, ErrInvalidShareCount
+	}
+
+	if threshold < 2 || threshold > n {
+		return [][]byte{}, ErrInvalidThreshold
+	}
+
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		// The x-coordinates start at 1 given that the secret is the value at 0.
+		shares[i][0] = byte(i + 1)
+	}
+
+	// The coefficients of the polynomial with the secret byte as its constant term.
+	coefficients := make([]byte, threshold)
+
+	for i, b := range secret {
+		coefficients[0] = b
+
+		err := random.Read(coefficients[1:])
+		if err != nil {
+			clear(coefficients)
+			return [][]byte{}, err
+		}
+
+		for _, share := range shares {
+			share[i+1] = evaluate(coefficients, share[0])
+		}
+	}
+
+	// Wipe the coefficients as they can be used to reconstruct the secret.
+	clear(coefficients)
+
+	return shares, nil
+}
+
+// Combine reconstructs the secret from the shares via Lagrange interpolation.
+// Note that combining fewer shares than the threshold that was used when
+// splitting results in a wrong secret rather than an error.
+// Returns an error if the shares are malformed.
+func Combine(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return []byte{}, ErrInvalidShares
+	}
+
+	length := len(shares[0])
+	if length < 2 {
+		return []byte{}, ErrInvalidShares
+	}
+
+	seen := make(map[byte]bool, len(shares))
+	for _, share := range shares {
+		if len(share) != length || share[0] == 0 || seen[share[0]] {
+			return []byte{}, ErrInvalidShares
+		}
+		seen[share[0]] = true
+	}
+
+	secret := make([]byte, length-1)
+
+	for i := range secret {
+		var result byte
+
+		for j, share := range shares {
+			// Compute the Lagrange basis polynomial for share j evaluated at 0.
+			basis := byte(1)
+
+			for k, other := range shares {
+				if j == k {
+					continue
+				}
+
+				// In GF(256) subtraction is the same as addition (XOR) so that
+				// (0 - x_k) / (x_j - x_k) = x_k / (x_j ^ x_k).
+				basis = mul(basis, div(other[0], share[0]^other[0]))
+			}
+
+			result ^= mul(share[i+1], basis)
+		}
+
+		secret[i] = result
+	}
+
+	return secret, nil
+}
+
+// evaluate evaluates the polynomial with the coefficients (lowest degree first)
+// at x using Horner's method.
+func evaluate(coefficients []byte, x byte) byte {
+	var result byte
+
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		result = mul(result, x) ^ coefficients[i]
+	}
+
+	return result
+}
+
+// mul multiplies a and b in GF(256) using the reduction polynomial
+// x^8 + x^4 + x^3 + x + 1 (0x11b).
+// The multiplication doesn't branch on its inputs.
+func mul(a, b byte) byte {
+	var result byte
+
+	for range 8 {
+		// Add a if the lowest bit of b is set.
+		result ^= a & -(b & 1)
+		b >>= 1
+
+		// Multiply a by x and reduce it if the highest bit was set.
+		carry := -(a >> 7)
+		a = (a << 1) ^ (0x1b & carry)
+	}
+
+	return result
+}
+
+// inv computes the multiplicative inverse of a in GF(256) via a^254.
+// The inverse of 0 is defined as 0.
+func inv(a byte) byte {
+	result := byte(1)
+	base := a
+
+	// 254 = 0b11111110.
+	for e := byte(254); e > 0; e >>= 1 {
+		if e&1 == 1 {
+			result = mul(result, base)
+		}
+		base = mul(base, base)
+	}
+
+	return result
+}
+
+// div divides a by b in GF(256).
+func div(a, b byte) byte {
+	return mul(a, inv(b))
+}
@@ -0,0 +1,42 @@
+package shamir
+
+import "testing"
+
+func TestShamirGF256(t *testing.T) {
+	t.Run("Multiplication", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]struct {
+			a    byte
+			b    byte
+			want byte
+		}{
+			// See: FIPS 197 - 4.2.
+			"0x57 * 0x83": {a: 0x57, b: 0x83, want: 0xc1},
+			"0x57 * 0x13": {a: 0x57, b: 0x13, want: 0xfe},
+			"0x00 * 0x13": {a: 0x00, b: 0x13, want: 0x00},
+			"0x01 * 0x13": {a: 0x01, b: 0x13, want: 0x13},
+		}
+
+		for name, tc := range tt {
+			got := mul(tc.a, tc.b)
+
+			if got != tc.want {
+				t.Errorf("%v: want %v, got %v", name, tc.want, got)
+			}
+		}
+	})
+
+	t.Run("Inverse", func(t *testing.T) {
+		t.Parallel()
+
+		for a := 1; a < 256; a++ {
+			got := mul(byte(a), inv(byte(a)))
+			want := byte(1)
+
+			if got != want {
+				t.Errorf("%v: want %v, got %v", a, want, got)
+			}
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package shamir_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/shamir"
+)
+
+func TestShamir(t *testing.T) {
+	secret := []byte{
+		0x80, 0x81, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87,
+		0x88, 0x89, 0x8a, 0x8b, 0x8c, 0x8d, 0x8e, 0x8f,
+		0x90, 0x91, 0x92, 0x93, 0x94, 0x95, 0x96, 0x97,
+		0x98, 0x99, 0x9a, 0x9b, 0x9c, 0x9d, 0x9e, 0x9f,
+	}
+
+	t.Run("Split + Combine", func(t *testing.T) {
+		t.Parallel()
+
+		shares, err := shamir.Split(secret, 5, 3)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		tt := map[string][][]byte{
+			"1, 2, 3":       {shares[0], shares[1], shares[2]},
+			"5, 3, 1":       {shares[4], shares[2], shares[0]},
+			"2, 3, 4, 5":    {shares[1], shares[2], shares[3], shares[4]},
+			"1, 2, 3, 4, 5": shares,
+		}
+
+		for name, subset := range tt {
+			got, err := shamir.Combine(subset)
+			want := secret
+
+			if !slices.Equal(got, want) {
+				t.Errorf("%v: want %v, got %v (error %v)", name, want, got, err)
+			}
+		}
+	})
+
+	t.Run("Below Threshold", func(t *testing.T) {
+		t.Parallel()
+
+		shares, _ := shamir.Split(secret, 5, 3)
+
+		got, _ := shamir.Combine(shares[0:2])
+
+		if slices.Equal(got, secret) {
+			t.Errorf("want a wrong secret, got %v", got)
+		}
+	})
+
+	t.Run("Invalid Parameters", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]struct {
+			secret    []byte
+			n         int
+			threshold int
+			err       error
+		}{
+			"Empty Secret":        {secret: []byte{}, n: 5, threshold: 3, err: shamir.ErrEmptySecret},
+			"Threshold Too Small": {secret: secret, n: 5, threshold: 1, err: shamir.ErrInvalidThreshold},
+			"Threshold Too Large": {secret: secret, n: 5, threshold: 6, err: shamir.ErrInvalidThreshold},
+			"Too Many Shares":     {secret: secret, n: 256, threshold: 3, err: shamir.ErrInvalidShareCount},
+		}
+
+		for name, tc := range tt {
+			_, err := shamir.Split(tc.secret, tc.n, tc.threshold)
+
+			if !errors.Is(err, tc.err) {
+				t.Errorf("%v: want error %v, got %v", name, tc.err, err)
+			}
+		}
+	})
+
+	t.Run("Invalid Shares", func(t *testing.T) {
+		t.Parallel()
+
+		shares, _ := shamir.Split(secret, 3, 2)
+
+		tt := map[string][][]byte{
+			"Single Share":     {shares[0]},
+			"Duplicate Shares": {shares[0], shares[0]},
+			"Length Mismatch":  {shares[0], shares[1][:10]},
+			"Zero Coordinate":  {append([]byte{0x00}, shares[0][1:]...), shares[1]},
+		}
+
+		for name, subset := range tt {
+			_, err := shamir.Combine(subset)
+
+			if !errors.Is(err, shamir.ErrInvalidShares) {
+				t.Errorf("%v: want error %v, got %v", name, shamir.ErrInvalidShares, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package stream
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"slices"
+
+	"github.com/pmuens/ctk-go/ctk/hkdf"
+	"github.com/pmuens/ctk-go/ctk/internal/checkpoint"
+)
+
+// stateLabel identifies encoded Writer states.
+const stateLabel = "ctk-go stream writer"
+
+// stateSize is the size (in bytes) of an encoded Writer state without the
+// checkpoint overhead and the metadata block.
+const stateSize = 4 + NoncePrefixSize + 8
+
+// checkpointInfo is used for domain separation in the derivation of the MAC key
+// of the encoded states.
+var checkpointInfo = []byte("ctk-go stream checkpoint")
+
+// MarshalBinary encodes the state of the Writer at the end of the last chunk
+// that was written to the underlying writer so that the encryption can be
+// resumed via ResumeWriter:
+//
+//	chunk size (4, big endian) | nonce prefix (16) | chunk counter (8, big endian) | metadata
+//
+// The plaintext that's buffered for the next chunk isn't part of the state (it
+// would have to be stored unencrypted). It needs to be written again after the
+// Writer was resumed which is why Processed returns the offset in the plaintext
+// the encryption continues at.
+//
+// The state doesn't contain the key. It's versioned and authenticated with a
+// MAC that's keyed with a key derived from the encryption key so that
+// modifications (e.g. a rolled back counter) are rejected.
+//
+// Warning: Every state must be resumed at most once. Resuming the same state
+// more than once and writing different plaintext reuses the nonces of the
+// following chunks which breaks the encryption.
+// Returns an error if the Writer is closed or compresses the plaintext (the
+// state of the compressor can't be encoded).
+func (w *Writer) MarshalBinary() ([]byte, error) {
+	if w.closed {
+		return []byte{}, ErrClosed
+	}
+
+	if w.compressor != nil {
+		return []byte{}, ErrCompressed
+	}
+
+	state := make([]byte, 0, stateSize+len(w.metadata))
+	state = binary.BigEndian.AppendUint32(state, uint32(w.chunkSize))
+	state = append(state, w.noncePrefix[:]...)
+	state = binary.BigEndian.AppendUint64(state, w.counter)
+	state = append(state, w.metadata...)
+
+	return checkpoint.Encode(stateLabel, state, checkpointKey(w.key)), nil
+}
+
+// ResumeWriter restores a Writer whose state was encoded via MarshalBinary.
+// The header isn't written again. The underlying writer needs to continue
+// right after the last chunk that was written (see Offset), e.g. a file that
+// was truncated to Offset bytes and opened in append mode. The plaintext needs
+// to be written from the offset that's returned by Processed.
+// Returns an error if the state is malformed, was modified or belongs to
+// another key.
+func ResumeWriter(w io.Writer, key [32]byte, state []byte) (*Writer, error) {
+	decoded, ok := checkpoint.Decode(stateLabel, state, stateSize)
+	if !ok || len(decoded) > stateSize+MaxMetadataSize || !checkpoint.Verify(state, checkpointKey(key)) {
+		return nil, ErrInvalidState
+	}
+
+	chunkSize := int(binary.BigEndian.Uint32(decoded[0:4]))
+	counter := binary.BigEndian.Uint64(decoded[4+NoncePrefixSize : stateSize])
+	if chunkSize < 1 || chunkSize > MaxChunkSize || counter >= maxChunks {
+		return nil, ErrInvalidState
+	}
+
+	sw := &Writer{
+		w:           w,
+		key:         key,
+		noncePrefix: [NoncePrefixSize]byte(decoded[4 : 4+NoncePrefixSize]),
+		chunkSize:   chunkSize,
+		buf:         make([]byte, 0, chunkSize),
+		counter:     counter,
+		metadata:    slices.Clone(decoded[stateSize:]),
+		ctx:         context.Background(),
+	}
+	sw.header = encodeHeader(compressionNone, chunkSize, sw.noncePrefix, sw.metadata)
+
+	return sw, nil
+}
+
+// Processed returns the number of plaintext bytes that were encrypted into
+// chunks which were written to the underlying writer (i.e. the offset in the
+// plaintext a resumed Writer continues at). The compressed data is counted if
+// compression is enabled.
+func (w *Writer) Processed() int64 {
+	return int64(w.counter) * int64(w.chunkSize)
+}
+
+// Offset returns the number of container bytes (including the header) that were
+// written to the underlying writer for the chunks that are counted by
+// Processed.
+func (w *Writer) Offset() int64 {
+	return chunkOffset(len(w.header), w.chunkSize, int64(w.counter))
+}
+
+// checkpointKey derives the MAC key of the encoded states from the key.
+func checkpointKey(key [32]byte) []byte {
+	macKey, _ := hkdf.Key(sha256.New, key[:], nil, checkpointInfo, 32)
+
+	return macKey
+}
@@ -0,0 +1,112 @@
+package stream_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/stream"
+)
+
+func TestWriterMarshalBinary(t *testing.T) {
+	key := [32]byte{0x01, 0x02, 0x03}
+	plaintext := bytes.Repeat([]byte("attack at dawn "), stream.DefaultChunkSize/5)
+
+	t.Run("Resume", func(t *testing.T) {
+		t.Parallel()
+
+		var container bytes.Buffer
+
+		w, _ := stream.NewWriter(&container, key)
+		w.Write(plaintext[:len(plaintext)/2])
+
+		state, err := w.MarshalBinary()
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		offset := w.Offset()
+		processed := w.Processed()
+
+		if processed != stream.DefaultChunkSize {
+			t.Errorf("want processed %v, got %v", stream.DefaultChunkSize, processed)
+		}
+
+		// The Writer crashes after it wrote more data. The partial output is
+		// truncated to the offset of the checkpoint.
+		w.Write(plaintext[len(plaintext)/2:])
+		container.Truncate(int(offset))
+
+		resumed, err := stream.ResumeWriter(&container, key, state)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if resumed.Offset() != offset || resumed.Processed() != processed {
+			t.Errorf("want offset %v and processed %v, got %v and %v", offset, processed, resumed.Offset(), resumed.Processed())
+		}
+
+		resumed.Write(plaintext[processed:])
+
+		err = resumed.Close()
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		got, err := stream.Decrypt(key, container.Bytes())
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if !slices.Equal(got, plaintext) {
+			t.Errorf("want %v bytes of plaintext, got %v", len(plaintext), len(got))
+		}
+	})
+
+	t.Run("Invalid State", func(t *testing.T) {
+		t.Parallel()
+
+		w, _ := stream.NewWriter(&bytes.Buffer{}, key)
+		w.Write(plaintext)
+
+		state, _ := w.MarshalBinary()
+
+		tt := map[string]struct {
+			key   [32]byte
+			state []byte
+		}{
+			"Wrong Key": {key: [32]byte{0x04}, state: state},
+			"Counter":   {key: key, state: slices.Concat(state[:len(state)-33], []byte{0x00}, state[len(state)-32:])},
+			"Truncated": {key: key, state: state[:len(state)-1]},
+			"Empty":     {key: key, state: []byte{}},
+		}
+
+		for name, tc := range tt {
+			_, err := stream.ResumeWriter(&bytes.Buffer{}, tc.key, tc.state)
+			if !errors.Is(err, stream.ErrInvalidState) {
+				t.Errorf("%v: want error %v, got %v", name, stream.ErrInvalidState, err)
+			}
+		}
+	})
+
+	t.Run("Not Resumable", func(t *testing.T) {
+		t.Parallel()
+
+		compressed, _ := stream.NewWriter(&bytes.Buffer{}, key, stream.WithCompression(flate.BestSpeed))
+
+		_, err := compressed.MarshalBinary()
+		if !errors.Is(err, stream.ErrCompressed) {
+			t.Errorf("want error %v, got %v", stream.ErrCompressed, err)
+		}
+
+		closed, _ := stream.NewWriter(&bytes.Buffer{}, key)
+		closed.Close()
+
+		_, err = closed.MarshalBinary()
+		if !errors.Is(err, stream.ErrClosed) {
+			t.Errorf("want error %v, got %v", stream.ErrClosed, err)
+		}
+	})
+}
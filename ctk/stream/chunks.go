@@ -0,0 +1,32 @@
+package stream
+
+import "bytes"
+
+// Chunks splits the container into its encrypted chunks (the ciphertext
+// followed by the tag) without decrypting them. Storage that doesn't have the
+// key can store or verify the chunks individually (e.g. via a Merkle tree,
+// see the merkle package).
+// Returns an error if the header is malformed or if the last chunk is shorter
+// than a tag.
+func Chunks(container []byte) ([][]byte, error) {
+	header, fields, err := readHeader(bytes.NewReader(container))
+	if err != nil {
+		return nil, err
+	}
+
+	body := container[len(header):]
+	full := fields.chunkSize + TagSize
+
+	chunks := make([][]byte, 0, (len(body)+full-1)/full)
+	for len(body) > 0 {
+		size := min(full, len(body))
+		chunks = append(chunks, body[:size:size])
+		body = body[size:]
+	}
+
+	if len(chunks) == 0 || len(chunks[len(chunks)-1]) < TagSize {
+		return nil, ErrDecryption
+	}
+
+	return chunks, nil
+}
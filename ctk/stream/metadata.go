@@ -0,0 +1,50 @@
+package stream
+
+import "slices"
+
+const (
+	// ErrMetadataTooLarge is returned if the metadata block exceeds
+	// MaxMetadataSize.
+	ErrMetadataTooLarge = Error("stream metadata too large")
+)
+
+// MetadataVersion is the version of the container format with a metadata block.
+const MetadataVersion = 2
+
+// MaxMetadataSize is the largest size (in bytes) of a metadata block.
+const MaxMetadataSize = 64 * 1024
+
+// WithMetadata stores the metadata (e.g. a file name, a modification time or a
+// content type encoded via the aad package) in the container header. The
+// metadata isn't encrypted, but it's authenticated as part of the header which
+// is the AAD of every chunk. It can be read via Reader.Metadata and
+// Seeker.Metadata before the whole container is decrypted.
+// NewWriter returns ErrMetadataTooLarge if the metadata exceeds
+// MaxMetadataSize.
+func WithMetadata(metadata []byte) Option {
+	return func(w *Writer) {
+		w.metadata = slices.Clone(metadata)
+	}
+}
+
+// Metadata returns the metadata block of the container (empty if there's none).
+// The first chunk is read and authenticated (if that didn't happen yet) so that
+// the returned metadata is authenticated.
+// Returns an error if the first chunk can't be authenticated.
+func (r *Reader) Metadata() ([]byte, error) {
+	if r.counter == 0 && r.err == nil {
+		r.err = r.readChunk()
+	}
+
+	if r.counter == 0 {
+		return []byte{}, r.err
+	}
+
+	return append([]byte{}, r.metadata...), nil
+}
+
+// Metadata returns the metadata block of the container (empty if there's none).
+// It was authenticated when the Seeker was opened.
+func (s *Seeker) Metadata() []byte {
+	return append([]byte{}, s.metadata...)
+}
@@ -0,0 +1,189 @@
+package stream_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/aad"
+	"github.com/pmuens/ctk-go/ctk/stream"
+)
+
+func TestMetadata(t *testing.T) {
+	key := [32]byte{0x01, 0x02, 0x03}
+	plaintext := bytes.Repeat([]byte("attack at dawn "), stream.DefaultChunkSize/5)
+	metadata := aad.Encode(map[string]string{
+		"name":         "report.pdf",
+		"mtime":        "2026-10-17T12:00:00Z",
+		"content-type": "application/pdf",
+	})
+
+	container, err := stream.Encrypt(key, plaintext, stream.WithMetadata(metadata))
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	t.Run("Reader", func(t *testing.T) {
+		t.Parallel()
+
+		r, err := stream.NewReader(bytes.NewReader(container), key)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		got, err := r.Metadata()
+		if err != nil || !slices.Equal(got, metadata) {
+			t.Errorf("want %v, got %v (error %v)", metadata, got, err)
+		}
+
+		// The first chunk that was read for the metadata isn't lost.
+		data, err := io.ReadAll(r)
+		if err != nil || !slices.Equal(data, plaintext) {
+			t.Errorf("want plaintext to match (error %v)", err)
+		}
+	})
+
+	t.Run("Seeker", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := stream.OpenSeeker(bytes.NewReader(container), key)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if !slices.Equal(s.Metadata(), metadata) {
+			t.Errorf("want %v, got %v", metadata, s.Metadata())
+		}
+
+		data, err := io.ReadAll(s)
+		if err != nil || !slices.Equal(data, plaintext) {
+			t.Errorf("want plaintext to match (error %v)", err)
+		}
+	})
+
+	t.Run("Compression", func(t *testing.T) {
+		t.Parallel()
+
+		compressed, err := stream.Encrypt(key, plaintext, stream.WithMetadata(metadata), stream.WithCompression(flate.BestSpeed))
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		r, _ := stream.NewReader(bytes.NewReader(compressed), key)
+
+		got, err := r.Metadata()
+		if err != nil || !slices.Equal(got, metadata) {
+			t.Errorf("want %v, got %v (error %v)", metadata, got, err)
+		}
+
+		data, err := io.ReadAll(r)
+		if err != nil || !slices.Equal(data, plaintext) {
+			t.Errorf("want plaintext to match (error %v)", err)
+		}
+	})
+
+	t.Run("No Metadata", func(t *testing.T) {
+		t.Parallel()
+
+		plain, _ := stream.Encrypt(key, plaintext)
+		if plain[4] != stream.Version {
+			t.Errorf("want version %v, got %v", stream.Version, plain[4])
+		}
+
+		r, _ := stream.NewReader(bytes.NewReader(plain), key)
+
+		got, err := r.Metadata()
+		if err != nil || len(got) != 0 {
+			t.Errorf("want no metadata, got %v (error %v)", got, err)
+		}
+	})
+
+	t.Run("Tampered Metadata", func(t *testing.T) {
+		t.Parallel()
+
+		tampered := slices.Clone(container)
+		tampered[stream.HeaderSize+4] ^= 0x01
+
+		r, err := stream.NewReader(bytes.NewReader(tampered), key)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		_, err = r.Metadata()
+		if !errors.Is(err, stream.ErrDecryption) {
+			t.Errorf("want error %v, got %v", stream.ErrDecryption, err)
+		}
+
+		_, err = stream.OpenSeeker(bytes.NewReader(tampered), key)
+		if !errors.Is(err, stream.ErrDecryption) {
+			t.Errorf("want error %v, got %v", stream.ErrDecryption, err)
+		}
+	})
+
+	t.Run("Malformed Metadata Block", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]struct {
+			container []byte
+		}{
+			"Truncated":   {container[:stream.HeaderSize+4+len(metadata)-1]},
+			"No Length":   {container[:stream.HeaderSize+2]},
+			"Zero Length": {slices.Concat(container[:stream.HeaderSize], []byte{0x00, 0x00, 0x00, 0x00}, container[stream.HeaderSize+4+len(metadata):])},
+			"Too Large":   {slices.Concat(container[:stream.HeaderSize], []byte{0x00, 0x01, 0x00, 0x01}, container[stream.HeaderSize+4:])},
+		}
+
+		for name, tc := range tt {
+			_, err := stream.NewReader(bytes.NewReader(tc.container), key)
+			if !errors.Is(err, stream.ErrDecryption) {
+				t.Errorf("%v: want error %v, got %v", name, stream.ErrDecryption, err)
+			}
+		}
+	})
+
+	t.Run("Too Large", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := stream.NewWriter(io.Discard, key, stream.WithMetadata(make([]byte, stream.MaxMetadataSize+1)))
+		if !errors.Is(err, stream.ErrMetadataTooLarge) {
+			t.Errorf("want error %v, got %v", stream.ErrMetadataTooLarge, err)
+		}
+	})
+
+	t.Run("Resume", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+
+		w, _ := stream.NewWriter(&buf, key, stream.WithMetadata(metadata))
+		w.Write(plaintext[:len(plaintext)/2])
+
+		state, err := w.MarshalBinary()
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+		buf.Truncate(int(w.Offset()))
+
+		resumed, err := stream.ResumeWriter(&buf, key, state)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		resumed.Write(plaintext[resumed.Processed():])
+		resumed.Close()
+
+		r, _ := stream.NewReader(&buf, key)
+
+		got, err := r.Metadata()
+		if err != nil || !slices.Equal(got, metadata) {
+			t.Errorf("want %v, got %v (error %v)", metadata, got, err)
+		}
+
+		data, err := io.ReadAll(r)
+		if err != nil || !slices.Equal(data, plaintext) {
+			t.Errorf("want plaintext to match (error %v)", err)
+		}
+	})
+}
@@ -0,0 +1,247 @@
+package stream
+
+import (
+	"context"
+	"io"
+
+	"github.com/pmuens/ctk-go/ctk/internal/debug"
+)
+
+const (
+	// ErrInvalidOffset is returned if a Seeker is moved to a negative offset.
+	ErrInvalidOffset = Error("invalid offset")
+)
+
+// Seeker decrypts a container with random access. Chunks are read and
+// authenticated on demand so that seeking within large containers (e.g. media
+// files or archives) doesn't require decrypting everything before the offset.
+// A Seeker isn't safe for concurrent use.
+type Seeker struct {
+	// r is the underlying container.
+	r io.ReadSeeker
+
+	// key is the decryption key.
+	key [32]byte
+
+	// header is the container header (used as AAD).
+	header []byte
+
+	// noncePrefix is the nonce prefix read from the header.
+	noncePrefix [NoncePrefixSize]byte
+
+	// chunkSize is the number of plaintext bytes per chunk.
+	chunkSize int
+
+	// metadata is the metadata block of the header.
+	metadata []byte
+
+	// chunks is the number of chunks.
+	chunks int64
+
+	// lastChunkSize is the size (in bytes) of the encrypted last chunk
+	// (including its tag).
+	lastChunkSize int
+
+	// size is the size (in bytes) of the plaintext.
+	size int64
+
+	// offset is the current offset in the plaintext.
+	offset int64
+
+	// buf buffers an encrypted chunk.
+	buf []byte
+
+	// cached is the index of the cached chunk (-1 if none is cached).
+	cached int64
+
+	// plaintext is the plaintext of the cached chunk. Caching the last
+	// authenticated chunk means that sequential and small reads don't
+	// authenticate the same chunk over and over again.
+	plaintext []byte
+
+	// ctx stops the decryption once it's done.
+	ctx context.Context
+}
+
+// OpenSeeker creates a new Seeker which reads the container header from r.
+// The last chunk is authenticated right away so that a truncated container
+// is detected before any data is read.
+// Returns an error if the container is malformed, can't be authenticated or if
+// its chunks exceed the buffer limit.
+func OpenSeeker(r io.ReadSeeker, key [32]byte, opts ...ReaderOption) (*Seeker, error) {
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = r.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+
+	header, fields, err := readHeader(r)
+	if err != nil {
+		return nil, debug.Detail(ErrDecryption, err)
+	}
+	chunkSize := fields.chunkSize
+
+	o := newReaderOptions(opts)
+
+	err = checkChunkSize(chunkSize, o)
+	if err != nil {
+		return nil, err
+	}
+
+	// Offsets in the compressed data don't map to offsets in the plaintext.
+	if fields.compression != compressionNone {
+		return nil, ErrCompressed
+	}
+
+	// Every chunk but the last one has the full size which is why the number
+	// of chunks and the plaintext size can be derived from the container size.
+	body := end - int64(len(header))
+	full := int64(chunkSize + TagSize)
+	chunks := (body + full - 1) / full
+	if chunks < 1 {
+		return nil, debug.Detail(ErrDecryption, errTruncated)
+	}
+	if chunks > maxChunks {
+		return nil, debug.Detail(ErrDecryption, ErrTooLarge)
+	}
+
+	lastChunkSize := body - (chunks-1)*full
+	if lastChunkSize < TagSize {
+		return nil, debug.Detail(ErrDecryption, errTruncated)
+	}
+
+	s := &Seeker{
+		r:             r,
+		key:           key,
+		header:        header,
+		noncePrefix:   fields.noncePrefix,
+		chunkSize:     chunkSize,
+		metadata:      fields.metadata,
+		chunks:        chunks,
+		lastChunkSize: int(lastChunkSize),
+		size:          body - chunks*TagSize,
+		buf:           make([]byte, full),
+		cached:        -1,
+		ctx:           o.ctx,
+	}
+
+	_, err = s.chunk(chunks - 1)
+	if err != nil {
+		return nil, chunkError(len(header), chunkSize, uint64(chunks-1), err)
+	}
+
+	// In strict mode all other chunks are authenticated as well so that no
+	// plaintext of a forged container is released.
+	for index := int64(0); o.strict && index < chunks-1; index++ {
+		_, err = s.chunk(index)
+		if err != nil {
+			return nil, chunkError(len(header), chunkSize, uint64(index), err)
+		}
+	}
+
+	return s, nil
+}
+
+// Size returns the size (in bytes) of the plaintext.
+func (s *Seeker) Size() int64 {
+	return s.size
+}
+
+// Chunks returns the number of chunks of the container.
+func (s *Seeker) Chunks() int64 {
+	return s.chunks
+}
+
+// ChunkOffset returns the offset (in bytes) of the chunk with the index in the
+// container.
+func (s *Seeker) ChunkOffset(index int64) int64 {
+	return chunkOffset(len(s.header), s.chunkSize, index)
+}
+
+// Read reads the decrypted data at the current offset.
+// Returns an error if a chunk can't be authenticated.
+func (s *Seeker) Read(p []byte) (int, error) {
+	if s.offset >= s.size {
+		return 0, io.EOF
+	}
+
+	index := s.offset / int64(s.chunkSize)
+	within := s.offset % int64(s.chunkSize)
+
+	plaintext, err := s.chunk(index)
+	if err != nil {
+		return 0, chunkError(len(s.header), s.chunkSize, uint64(index), err)
+	}
+
+	n := copy(p, plaintext[within:])
+	s.offset += int64(n)
+
+	return n, nil
+}
+
+// Seek sets the offset for the next Read. Seeking beyond the end is allowed
+// (Read returns io.EOF in that case).
+// Returns an error if the resulting offset is negative.
+func (s *Seeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.offset
+	case io.SeekEnd:
+		offset += s.size
+	default:
+		return 0, ErrInvalidOffset
+	}
+
+	if offset < 0 {
+		return 0, ErrInvalidOffset
+	}
+
+	s.offset = offset
+
+	return offset, nil
+}
+
+// chunk returns the plaintext of the chunk with the index which is read and
+// authenticated unless it's cached.
+func (s *Seeker) chunk(index int64) ([]byte, error) {
+	if index == s.cached {
+		return s.plaintext, nil
+	}
+
+	err := s.ctx.Err()
+	if err != nil {
+		return []byte{}, err
+	}
+
+	last := index == s.chunks-1
+
+	size := len(s.buf)
+	if last {
+		size = s.lastChunkSize
+	}
+
+	_, err = s.r.Seek(s.ChunkOffset(index), io.SeekStart)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	_, err = io.ReadFull(s.r, s.buf[:size])
+	if err != nil {
+		return []byte{}, err
+	}
+
+	plaintext, err := decryptChunk(s.key, s.header, s.noncePrefix, uint64(index), last, s.buf[:size])
+	if err != nil {
+		return []byte{}, err
+	}
+
+	s.cached = index
+	s.plaintext = plaintext
+
+	return plaintext, nil
+}
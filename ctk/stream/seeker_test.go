@@ -0,0 +1,249 @@
+package stream_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"testing/iotest"
+
+	"github.com/pmuens/ctk-go/ctk/stream"
+)
+
+func TestSeeker(t *testing.T) {
+	key := [32]byte{0x01, 0x02, 0x03}
+
+	plaintext := make([]byte, 3*stream.DefaultChunkSize+100)
+	for i := range plaintext {
+		plaintext[i] = byte(i * 7)
+	}
+
+	container, err := stream.Encrypt(key, plaintext)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	t.Run("Reader", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := stream.OpenSeeker(bytes.NewReader(container), key)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if s.Size() != int64(len(plaintext)) {
+			t.Errorf("want size %v, got %v", len(plaintext), s.Size())
+		}
+
+		err = iotest.TestReader(s, plaintext)
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("Random Access", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := stream.OpenSeeker(bytes.NewReader(container), key)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		rng := rand.New(rand.NewPCG(1, 2))
+
+		for range 100 {
+			offset := rng.Int64N(int64(len(plaintext)))
+			length := min(rng.Int64N(2*stream.DefaultChunkSize), int64(len(plaintext))-offset)
+
+			got, err := s.Seek(offset, io.SeekStart)
+			if err != nil || got != offset {
+				t.Fatalf("want offset %v, got %v (error %v)", offset, got, err)
+			}
+
+			buf := make([]byte, length)
+
+			_, err = io.ReadFull(s, buf)
+			if err != nil {
+				t.Fatalf("want error %v, got %v", nil, err)
+			}
+
+			if !slices.Equal(buf, plaintext[offset:offset+length]) {
+				t.Fatalf("offset %v: want plaintext to match", offset)
+			}
+		}
+	})
+
+	t.Run("Seek", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := stream.OpenSeeker(bytes.NewReader(container), key)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		got, err := s.Seek(-10, io.SeekEnd)
+		if err != nil || got != int64(len(plaintext))-10 {
+			t.Errorf("want offset %v, got %v (error %v)", len(plaintext)-10, got, err)
+		}
+
+		rest, err := io.ReadAll(s)
+		if err != nil || !slices.Equal(rest, plaintext[len(plaintext)-10:]) {
+			t.Errorf("want last 10 bytes to match (error %v)", err)
+		}
+
+		_, err = s.Seek(-1, io.SeekStart)
+		if !errors.Is(err, stream.ErrInvalidOffset) {
+			t.Errorf("want error %v, got %v", stream.ErrInvalidOffset, err)
+		}
+
+		_, err = s.Seek(1, io.SeekEnd)
+		if err != nil {
+			t.Errorf("want error %v, got %v", nil, err)
+		}
+
+		_, err = s.Read(make([]byte, 1))
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("want error %v, got %v", io.EOF, err)
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		t.Parallel()
+
+		empty, err := stream.Encrypt(key, []byte{})
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		s, err := stream.OpenSeeker(bytes.NewReader(empty), key)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if s.Size() != 0 {
+			t.Errorf("want size %v, got %v", 0, s.Size())
+		}
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		t.Parallel()
+
+		// Dropping the last chunk leaves a container that ends on a chunk boundary.
+		truncated := container[:stream.HeaderSize+3*(stream.DefaultChunkSize+stream.TagSize)]
+
+		_, err := stream.OpenSeeker(bytes.NewReader(truncated), key)
+		if !errors.Is(err, stream.ErrDecryption) {
+			t.Errorf("want error %v, got %v", stream.ErrDecryption, err)
+		}
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		t.Parallel()
+
+		tampered := slices.Clone(container)
+		tampered[stream.HeaderSize+stream.DefaultChunkSize+stream.TagSize+1] ^= 0x01
+
+		s, err := stream.OpenSeeker(bytes.NewReader(tampered), key)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		// The first chunk is intact.
+		_, err = s.Read(make([]byte, 10))
+		if err != nil {
+			t.Errorf("want error %v, got %v", nil, err)
+		}
+
+		_, err = s.Seek(stream.DefaultChunkSize, io.SeekStart)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		_, err = s.Read(make([]byte, 10))
+		if !errors.Is(err, stream.ErrDecryption) {
+			t.Errorf("want error %v, got %v", stream.ErrDecryption, err)
+		}
+
+		var chunkErr *stream.ChunkError
+		if !errors.As(err, &chunkErr) || chunkErr.Index != 1 || chunkErr.Offset != s.ChunkOffset(1) {
+			t.Errorf("want chunk %v at offset %v, got %v", 1, s.ChunkOffset(1), err)
+		}
+	})
+
+	t.Run("Wrong Key", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := stream.OpenSeeker(bytes.NewReader(container), [32]byte{})
+		if !errors.Is(err, stream.ErrDecryption) {
+			t.Errorf("want error %v, got %v", stream.ErrDecryption, err)
+		}
+	})
+}
+
+// countingReadSeeker counts the bytes that are read from the ReadSeeker.
+type countingReadSeeker struct {
+	io.ReadSeeker
+
+	// read is the number of bytes that were read.
+	read int
+}
+
+// Read implements the io.Reader interface.
+func (c *countingReadSeeker) Read(p []byte) (int, error) {
+	n, err := c.ReadSeeker.Read(p)
+	c.read += n
+
+	return n, err
+}
+
+func TestChunkIndex(t *testing.T) {
+	key := [32]byte{0x01, 0x02, 0x03}
+	chunkSize := 1024
+
+	plaintext := make([]byte, 100*chunkSize+1)
+	for i := range plaintext {
+		plaintext[i] = byte(i * 7)
+	}
+
+	container, err := stream.Encrypt(key, plaintext, stream.WithChunkSize(chunkSize))
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	r := &countingReadSeeker{ReadSeeker: bytes.NewReader(container)}
+
+	s, err := stream.OpenSeeker(r, key)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	if s.Chunks() != 101 {
+		t.Errorf("want %v, got %v", 101, s.Chunks())
+	}
+
+	// The last chunk (1 byte of plaintext) is authenticated when the Seeker is
+	// opened, chunk 50 is read directly without reading the chunks before it.
+	offset := int64(50 * chunkSize)
+	_, err = s.Seek(offset, io.SeekStart)
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	got := make([]byte, chunkSize)
+	_, err = io.ReadFull(s, got)
+	if err != nil || !slices.Equal(got, plaintext[offset:offset+int64(chunkSize)]) {
+		t.Errorf("want chunk 50 to match (error %v)", err)
+	}
+
+	want := stream.HeaderSize + (1 + stream.TagSize) + (chunkSize + stream.TagSize)
+	if r.read != want {
+		t.Errorf("want %v bytes read, got %v", want, r.read)
+	}
+
+	wantOffset := int64(stream.HeaderSize + 50*(chunkSize+stream.TagSize))
+	if s.ChunkOffset(50) != wantOffset {
+		t.Errorf("want %v, got %v", wantOffset, s.ChunkOffset(50))
+	}
+}
@@ -0,0 +1,968 @@
+// Package stream implements chunked authenticated encryption of streams (e.g.
+// files that don't fit into memory) and the container format the encrypted
+// streams are stored in.
+//
+// The plaintext is split into chunks which are encrypted via XChaCha20-Poly1305
+// following the STREAM construction (https://eprint.iacr.org/2015/189). The
+// nonce of a chunk consists of a random nonce prefix, the chunk's index and a
+// flag that marks the last chunk so that chunks can't be reordered, dropped or
+// appended without being detected.
+//
+// Container format:
+//
+//	magic (4) | version (1) | compression (1) | chunk size (4, big endian) | nonce prefix (16) | chunks
+//
+// Containers with a metadata block (see WithMetadata) use version 2 whose header
+// is followed by the metadata block:
+//
+//	... | nonce prefix (16) | metadata length (4, big endian) | metadata | chunks
+//
+// Every chunk is the encrypted plaintext followed by its tag. All chunks but the
+// last one hold exactly chunk size bytes of plaintext. The last chunk is only
+// empty if the whole plaintext is empty. The header is bound to every chunk as
+// additional authenticated data (AAD).
+//
+// Chunk index: The container doesn't store an index of its chunks as every
+// chunk but the last one has the same size and its nonce is derived from its
+// index. Chunk N therefore starts at a fixed offset (see Seeker.ChunkOffset) and
+// can be decrypted without reading the chunks before it (which is how a Seeker
+// provides random access). An explicit index couldn't be written to the header
+// by a Writer either given that the number of chunks isn't known up front.
+//
+// Note that a Reader returns the plaintext of a chunk as soon as the chunk is
+// authenticated which means that a truncated stream is only detected once its
+// end is reached.
+// A Seeker provides random access to a container and authenticates the last
+// chunk when it's opened which is why it detects truncation right away.
+//
+// Memory: The chunk size (see WithChunkSize) trades the memory that's needed to
+// encrypt and decrypt a stream for the overhead of the tags. It's stored in the
+// header so that the same chunk size is used for decryption. Readers and
+// Seekers bound the memory they allocate for a container via
+// WithMaxBufferedBytes.
+//
+// Compression: The plaintext can optionally be compressed (via DEFLATE) before
+// it's encrypted (see WithCompression). Compression is disabled by default as
+// the size of the compressed data depends on its content. An attacker who can
+// inject data into the plaintext and observe the container size can therefore
+// recover secrets that are compressed alongside it (see e.g. the CRIME and
+// BREACH attacks on TLS and HTTP). Compressed containers can't be read via a
+// Seeker.
+//
+// Strict verification: A Reader releases the plaintext of a chunk as soon as
+// the chunk is authenticated which is why a forged or truncated container is
+// only detected after the plaintext before the forgery was read. Callers that
+// can't act on such plaintext (e.g. because they execute it) can use
+// WithStrictVerification to authenticate the whole container first.
+//
+// Cancellation: WithContext and WithReaderContext check a context before every
+// chunk so that the encryption or decryption of a large stream can be
+// cancelled or bound by a deadline.
+//
+// Errors: Errors of Writers, Readers and Seekers that concern a chunk are
+// wrapped in a ChunkError which names the chunk's index and offset so that
+// failures in large containers can be located. Readers and Seekers report every
+// malformed or forged container as ErrDecryption no matter whether the header
+// is malformed or a chunk was tampered with, truncated or reordered so that the
+// errors don't tell attackers which part of a forged container was rejected.
+// Builds with the ctkdebug build tag add the actual cause to the error.
+//
+// Checkpoints: The state of a Writer can be encoded via MarshalBinary and
+// restored via ResumeWriter so that an interrupted encryption (e.g. a crashed
+// upload) continues after the last chunk that was written instead of starting
+// from scratch.
+package stream
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/pmuens/ctk-go/ctk/internal/debug"
+	"github.com/pmuens/ctk-go/ctk/random"
+	"github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
+)
+
+const (
+	// ErrInvalidHeader is returned by Chunks if the container header is
+	// malformed or uses an unsupported version. Readers and Seekers report
+	// such headers as ErrDecryption.
+	ErrInvalidHeader = Error("invalid stream header")
+
+	// ErrDecryption is returned if a container can't be authenticated (e.g.
+	// because its header or a chunk was tampered with or because it was
+	// truncated).
+	ErrDecryption = Error("stream chunk authentication failed")
+
+	// ErrClosed is returned if data is written to a closed Writer.
+	ErrClosed = Error("stream writer is closed")
+
+	// ErrTooLarge is returned if a stream exceeds the maximum number of chunks.
+	ErrTooLarge = Error("stream too large")
+
+	// ErrCompressed is returned if a compressed container is opened via a
+	// Seeker or if the state of a compressing Writer is encoded.
+	ErrCompressed = Error("operation not supported on compressed stream")
+
+	// ErrInvalidState is returned if an encoded Writer state is malformed or
+	// was modified.
+	ErrInvalidState = Error("invalid stream writer state")
+
+	// ErrInvalidChunkSize is returned if a chunk size or a buffer limit is out
+	// of range.
+	ErrInvalidChunkSize = Error("invalid stream chunk size")
+
+	// ErrChunkTooLarge is returned if the chunks of a container don't fit into
+	// the buffer limit of a Reader or Seeker.
+	ErrChunkTooLarge = Error("stream chunk exceeds buffer limit")
+)
+
+// The causes of ErrDecryption that are only reported in debug builds.
+const (
+	// errTruncated is the cause if a container ends within a chunk tag or has
+	// no chunks.
+	errTruncated = Error("container truncated")
+
+	// errInvalidTag is the cause if a chunk tag is invalid.
+	errInvalidTag = Error("invalid chunk tag")
+
+	// errInvalidFinalTag is the cause if the tag of the final chunk is invalid
+	// (e.g. because chunks were dropped from the end).
+	errInvalidFinalTag = Error("invalid final chunk tag")
+)
+
+// ChunkError records the chunk at which an error occurred.
+type ChunkError struct {
+	// Index is the index of the chunk.
+	Index uint64
+
+	// Offset is the offset (in bytes) of the chunk in the container.
+	Offset int64
+
+	// Err is the underlying error.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("chunk %d at offset %d: %v", e.Index, e.Offset, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ChunkError) Unwrap() error {
+	return e.Err
+}
+
+// chunkError wraps the error (if any) in a ChunkError for the chunk with the
+// index in a container with the header size and chunk size.
+func chunkError(headerSize int, chunkSize int, index uint64, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &ChunkError{Index: index, Offset: chunkOffset(headerSize, chunkSize, int64(index)), Err: err}
+}
+
+// Magic identifies the container format.
+const Magic = "CTKS"
+
+// Version is the version of the container format.
+const Version = 1
+
+// NoncePrefixSize is the size (in bytes) of the random nonce prefix.
+const NoncePrefixSize = 16
+
+// HeaderSize is the size (in bytes) of the container header without a metadata
+// block.
+const HeaderSize = len(Magic) + 1 + 1 + 4 + NoncePrefixSize
+
+// TagSize is the size (in bytes) of the tag that's appended to every chunk.
+const TagSize = 16
+
+// DefaultChunkSize is the number of plaintext bytes per chunk.
+const DefaultChunkSize = 64 * 1024
+
+// MaxChunkSize is the largest chunk size a Writer uses and a Reader accepts
+// which bounds the memory that's needed to decrypt a stream.
+const MaxChunkSize = 16 * 1024 * 1024
+
+// DefaultMaxBufferedBytes is the default size (in bytes) of the largest
+// encrypted chunk (chunk size + TagSize) a Reader or Seeker buffers.
+const DefaultMaxBufferedBytes = MaxChunkSize + TagSize
+
+// maxChunks is the number of chunks that can be indexed with the 7 byte counter.
+const maxChunks = 1 << 56
+
+const (
+	// compressionNone marks a container whose plaintext isn't compressed.
+	compressionNone = 0x00
+
+	// compressionDeflate marks a container whose plaintext is compressed via
+	// DEFLATE (RFC 1951).
+	compressionDeflate = 0x01
+)
+
+// Option configures a Writer.
+type Option func(*Writer)
+
+// WithCompression compresses the plaintext via DEFLATE with the level (see
+// compress/flate) before it's encrypted. Readers detect compressed containers
+// and decompress them transparently.
+//
+// WARNING: Compression leaks information about the plaintext through the
+// length of the container. Don't use this option if an attacker can influence
+// parts of the plaintext which also contains secrets (e.g. a session cookie
+// next to user input) and observe the container size.
+//
+// NewWriter returns an error if the level is invalid.
+func WithCompression(level int) Option {
+	return func(w *Writer) {
+		w.compression = compressionDeflate
+		w.level = level
+	}
+}
+
+// WithChunkSize sets the number of plaintext bytes per chunk (DefaultChunkSize
+// by default). Smaller chunks need less memory to encrypt and decrypt, larger
+// chunks have less overhead (TagSize bytes per chunk).
+// The chunk size is stored in the container header so that Readers always use
+// the chunk size the container was written with.
+// NewWriter returns ErrInvalidChunkSize if the size isn't between 1 and
+// MaxChunkSize.
+func WithChunkSize(size int) Option {
+	return func(w *Writer) {
+		w.chunkSize = size
+	}
+}
+
+// WithContext stops the encryption once the context is done. The context is
+// checked before every chunk that's written so that the encryption of a large
+// stream can be cancelled or bound by a deadline (Write and Close return the
+// context's error in this case).
+func WithContext(ctx context.Context) Option {
+	return func(w *Writer) {
+		w.ctx = ctx
+	}
+}
+
+// ReaderOption configures a Reader or Seeker.
+type ReaderOption func(*readerOptions)
+
+// readerOptions are the settings of a Reader or Seeker.
+type readerOptions struct {
+	// maxBufferedBytes is the size of the largest encrypted chunk that's
+	// accepted.
+	maxBufferedBytes int
+
+	// ctx stops the decryption once it's done.
+	ctx context.Context
+
+	// strict indicates whether the whole container is authenticated before any
+	// plaintext is released.
+	strict bool
+
+	// maxStrictBytes is the maximum number of plaintext bytes that are
+	// buffered in strict mode if the container can't be read twice.
+	maxStrictBytes int64
+}
+
+// newReaderOptions applies the options to the defaults.
+func newReaderOptions(opts []ReaderOption) readerOptions {
+	o := readerOptions{
+		maxBufferedBytes: DefaultMaxBufferedBytes,
+		ctx:              context.Background(),
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// WithMaxBufferedBytes limits the size (in bytes) of the encrypted chunks
+// (chunk size + TagSize) a Reader or Seeker buffers (DefaultMaxBufferedBytes by
+// default). Containers with larger chunks are rejected with ErrChunkTooLarge
+// which bounds the memory an untrusted container can make a reader allocate.
+// NewReader and OpenSeeker return ErrInvalidChunkSize if the limit can't hold a
+// chunk with at least one byte of plaintext.
+func WithMaxBufferedBytes(n int) ReaderOption {
+	return func(o *readerOptions) {
+		o.maxBufferedBytes = n
+	}
+}
+
+// WithReaderContext stops the decryption once the context is done. The
+// context is checked before every chunk that's read so that the decryption of
+// a large stream can be cancelled or bound by a deadline (Read returns the
+// context's error in this case).
+func WithReaderContext(ctx context.Context) ReaderOption {
+	return func(o *readerOptions) {
+		o.ctx = ctx
+	}
+}
+
+// WithStrictVerification makes a Reader authenticate the whole container
+// (including its end) before the first Read returns any plaintext so that no
+// data of a container that turns out to be tampered with or truncated is ever
+// released. By default a Reader releases the plaintext of every chunk once the
+// chunk is authenticated which means that the plaintext before a forged chunk
+// is read before the forgery is detected.
+//
+// Containers that are read from an io.ReadSeeker (e.g. a file) are read twice:
+// all chunks are authenticated in a first pass before the reader seeks back and
+// decrypts them again (the container must not change in between). The
+// plaintext of other containers (including those of io.ReadSeekers that can't
+// seek such as pipes) is buffered in memory and Read returns ErrTooLarge if it
+// exceeds maxBuffered bytes.
+// Seekers authenticate every chunk when they're opened in strict mode.
+func WithStrictVerification(maxBuffered int64) ReaderOption {
+	return func(o *readerOptions) {
+		o.strict = true
+		o.maxStrictBytes = maxBuffered
+	}
+}
+
+// checkChunkSize checks the chunk size of a container against the limit of the
+// options.
+func checkChunkSize(chunkSize int, o readerOptions) error {
+	if o.maxBufferedBytes < 1+TagSize {
+		return ErrInvalidChunkSize
+	}
+
+	if chunkSize+TagSize > o.maxBufferedBytes {
+		return ErrChunkTooLarge
+	}
+
+	return nil
+}
+
+// Writer encrypts the data written to it and writes the container to the
+// underlying writer. Close needs to be called to write the last chunk.
+// A Writer isn't safe for concurrent use.
+type Writer struct {
+	// w is the underlying writer.
+	w io.Writer
+
+	// key is the encryption key.
+	key [32]byte
+
+	// header is the container header (used as AAD).
+	header []byte
+
+	// noncePrefix is the random nonce prefix.
+	noncePrefix [NoncePrefixSize]byte
+
+	// chunkSize is the number of plaintext bytes per chunk.
+	chunkSize int
+
+	// metadata is the metadata block of the header.
+	metadata []byte
+
+	// buf buffers the plaintext of the current chunk.
+	buf []byte
+
+	// counter is the index of the current chunk.
+	counter uint64
+
+	// closed indicates whether the last chunk was written.
+	closed bool
+
+	// compression identifies the compression algorithm.
+	compression byte
+
+	// level is the compression level.
+	level int
+
+	// compressor compresses the data before it's encrypted (nil if compression
+	// is disabled).
+	compressor *flate.Writer
+
+	// ctx stops the encryption once it's done.
+	ctx context.Context
+}
+
+// NewWriter creates a new Writer which writes the container header to w right
+// away. The chunks are encrypted with the key.
+// Returns an error if an option is invalid.
+func NewWriter(w io.Writer, key [32]byte, opts ...Option) (*Writer, error) {
+	sw := &Writer{
+		w:         w,
+		key:       key,
+		chunkSize: DefaultChunkSize,
+		ctx:       context.Background(),
+	}
+
+	for _, opt := range opts {
+		opt(sw)
+	}
+
+	if sw.chunkSize < 1 || sw.chunkSize > MaxChunkSize {
+		return nil, ErrInvalidChunkSize
+	}
+
+	if len(sw.metadata) > MaxMetadataSize {
+		return nil, ErrMetadataTooLarge
+	}
+	sw.buf = make([]byte, 0, sw.chunkSize)
+
+	if sw.compression == compressionDeflate {
+		compressor, err := flate.NewWriter((*chunkWriter)(sw), sw.level)
+		if err != nil {
+			return nil, err
+		}
+		sw.compressor = compressor
+	}
+
+	err := random.Read(sw.noncePrefix[:])
+	if err != nil {
+		return nil, err
+	}
+
+	sw.header = encodeHeader(sw.compression, sw.chunkSize, sw.noncePrefix, sw.metadata)
+
+	_, err = w.Write(sw.header)
+	if err != nil {
+		return nil, err
+	}
+
+	return sw, nil
+}
+
+// Write encrypts the (compressed) data. Full chunks are written to the
+// underlying writer once it's known that they aren't the last chunk.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, ErrClosed
+	}
+
+	if w.compressor != nil {
+		return w.compressor.Write(p)
+	}
+
+	return w.write(p)
+}
+
+// write splits the data into chunks.
+func (w *Writer) write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		// A full chunk can only be flushed once more data follows given that the
+		// last chunk needs to be flagged as such.
+		if len(w.buf) == w.chunkSize {
+			err := w.flush(false)
+			if err != nil {
+				return n, err
+			}
+		}
+
+		size := min(w.chunkSize-len(w.buf), len(p))
+		w.buf = append(w.buf, p[:size]...)
+		p = p[size:]
+		n += size
+	}
+
+	return n, nil
+}
+
+// Close encrypts and writes the last chunk. The underlying writer isn't closed.
+func (w *Writer) Close() error {
+	if w.closed {
+		return ErrClosed
+	}
+
+	if w.compressor != nil {
+		err := w.compressor.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	err := w.flush(true)
+	if err != nil {
+		return err
+	}
+
+	w.closed = true
+	clear(w.key[:])
+
+	return nil
+}
+
+// flush encrypts the buffered plaintext and writes it as a chunk.
+func (w *Writer) flush(last bool) error {
+	return chunkError(len(w.header), w.chunkSize, w.counter, w.flushChunk(last))
+}
+
+// flushChunk encrypts and writes the chunk (see flush).
+func (w *Writer) flushChunk(last bool) error {
+	err := w.ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	if w.counter >= maxChunks {
+		return ErrTooLarge
+	}
+
+	nonce := chunkNonce(w.noncePrefix, w.counter, last)
+	ciphertext, tag := xchacha20poly1305.NewXChaCha20Poly1305(w.key, nonce).Encrypt(w.buf, w.header)
+
+	_, err = w.w.Write(append(ciphertext, tag[:]...))
+	if err != nil {
+		return err
+	}
+
+	w.counter++
+	w.buf = w.buf[:0]
+
+	return nil
+}
+
+// chunkWriter writes the compressed data of a Writer into chunks.
+type chunkWriter Writer
+
+// Write splits the data into chunks (see Writer.write).
+func (c *chunkWriter) Write(p []byte) (int, error) {
+	return (*Writer)(c).write(p)
+}
+
+// Reader decrypts a container that's read from the underlying reader.
+// A Reader isn't safe for concurrent use.
+type Reader struct {
+	// r is the underlying reader.
+	r io.Reader
+
+	// key is the decryption key.
+	key [32]byte
+
+	// header is the container header (used as AAD).
+	header []byte
+
+	// noncePrefix is the nonce prefix read from the header.
+	noncePrefix [NoncePrefixSize]byte
+
+	// chunkSize is the number of plaintext bytes per chunk.
+	chunkSize int
+
+	// metadata is the metadata block of the header.
+	metadata []byte
+
+	// buf buffers an encrypted chunk and one more byte which is needed to know
+	// whether it's the last chunk.
+	buf []byte
+
+	// carry indicates whether the first byte of buf belongs to the next chunk.
+	carry bool
+
+	// plaintext is the decrypted data that wasn't read yet.
+	plaintext []byte
+
+	// counter is the index of the next chunk.
+	counter uint64
+
+	// done indicates whether the last chunk was decrypted.
+	done bool
+
+	// err is the error that occurred while reading (returned on every Read).
+	err error
+
+	// decompressor decompresses the decrypted data (nil if the container isn't
+	// compressed).
+	decompressor io.ReadCloser
+
+	// ctx stops the decryption once it's done.
+	ctx context.Context
+
+	// strict indicates whether the container still needs to be authenticated
+	// before the plaintext is released (see WithStrictVerification).
+	strict bool
+
+	// maxStrictBytes is the maximum number of plaintext bytes that are
+	// buffered in strict mode.
+	maxStrictBytes int64
+}
+
+// NewReader creates a new Reader which reads the container header from r right
+// away. The chunks are decrypted with the key.
+// Returns an error if the header is malformed or if the chunks exceed the
+// buffer limit.
+func NewReader(r io.Reader, key [32]byte, opts ...ReaderOption) (*Reader, error) {
+	header, fields, err := readHeader(r)
+	if err != nil {
+		return nil, debug.Detail(ErrDecryption, err)
+	}
+
+	o := newReaderOptions(opts)
+
+	err = checkChunkSize(fields.chunkSize, o)
+	if err != nil {
+		return nil, err
+	}
+
+	sr := &Reader{
+		r:              r,
+		key:            key,
+		header:         header,
+		noncePrefix:    fields.noncePrefix,
+		chunkSize:      fields.chunkSize,
+		metadata:       fields.metadata,
+		buf:            make([]byte, fields.chunkSize+TagSize+1),
+		ctx:            o.ctx,
+		strict:         o.strict,
+		maxStrictBytes: o.maxStrictBytes,
+	}
+
+	if fields.compression == compressionDeflate {
+		sr.decompressor = flate.NewReader((*chunkReader)(sr))
+	}
+
+	return sr, nil
+}
+
+// Read reads the decrypted (and decompressed) data.
+// Returns an error if a chunk can't be authenticated. io.EOF is only returned
+// after the last chunk was authenticated.
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.decompressor == nil {
+		return r.read(p)
+	}
+
+	n, err := r.decompressor.Read(p)
+	if errors.Is(err, io.EOF) {
+		// The compressed data ends before the last chunk might have been read
+		// which is why the rest of the stream needs to be authenticated.
+		_, err = io.Copy(io.Discard, (*chunkReader)(r))
+		if err == nil {
+			err = io.EOF
+		}
+	}
+
+	return n, err
+}
+
+// read reads the decrypted data of the chunks.
+func (r *Reader) read(p []byte) (int, error) {
+	if r.strict && r.err == nil {
+		r.err = r.verify()
+		r.strict = false
+	}
+
+	for len(r.plaintext) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		if r.done {
+			return 0, io.EOF
+		}
+
+		r.err = chunkError(len(r.header), r.chunkSize, r.counter, r.readChunk())
+	}
+
+	n := copy(p, r.plaintext)
+	r.plaintext = r.plaintext[n:]
+
+	return n, nil
+}
+
+// readChunk reads and decrypts the next chunk.
+func (r *Reader) readChunk() error {
+	err := r.ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	if r.counter >= maxChunks {
+		return ErrTooLarge
+	}
+
+	offset := 0
+	if r.carry {
+		offset = 1
+	}
+
+	// One byte more than the encrypted chunk is read to find out whether more
+	// chunks follow.
+	n, err := io.ReadFull(r.r, r.buf[offset:])
+	total := offset + n
+
+	last := false
+	switch err {
+	case nil:
+	case io.EOF, io.ErrUnexpectedEOF:
+		last = true
+	default:
+		return err
+	}
+
+	chunk := r.buf[:total]
+	if !last {
+		chunk = r.buf[:total-1]
+	}
+
+	plaintext, err := decryptChunk(r.key, r.header, r.noncePrefix, r.counter, last, chunk)
+	if err != nil {
+		return err
+	}
+
+	if !last {
+		r.buf[0] = r.buf[total-1]
+		r.carry = true
+	}
+
+	r.plaintext = plaintext
+	r.counter++
+	r.done = last
+
+	return nil
+}
+
+// verify authenticates all chunks before any plaintext is released (see
+// WithStrictVerification). Containers that can be read twice are rewound after
+// the chunks were authenticated, the plaintext of other containers is kept.
+func (r *Reader) verify() error {
+	seeker, ok := r.r.(io.Seeker)
+	if !ok {
+		return r.buffer()
+	}
+
+	// Some readers implement io.Seeker but can't seek (e.g. pipes).
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return r.buffer()
+	}
+
+	for !r.done {
+		err := chunkError(len(r.header), r.chunkSize, r.counter, r.readChunk())
+		if err != nil {
+			return err
+		}
+		clear(r.plaintext)
+		r.plaintext = nil
+	}
+
+	_, err = seeker.Seek(start, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	r.counter, r.carry, r.done, r.plaintext = 0, false, false, nil
+
+	return nil
+}
+
+// buffer decrypts all chunks into memory (see verify).
+func (r *Reader) buffer() error {
+	var plaintext []byte
+
+	for !r.done {
+		err := chunkError(len(r.header), r.chunkSize, r.counter, r.readChunk())
+		if err == nil && int64(len(plaintext)+len(r.plaintext)) > r.maxStrictBytes {
+			err = ErrTooLarge
+		}
+		if err != nil {
+			clear(plaintext)
+			clear(r.plaintext)
+			r.plaintext = nil
+			return err
+		}
+
+		plaintext = append(plaintext, r.plaintext...)
+		clear(r.plaintext)
+		r.plaintext = nil
+	}
+
+	r.plaintext = plaintext
+
+	return nil
+}
+
+// chunkReader reads the decrypted (compressed) data of a Reader.
+type chunkReader Reader
+
+// Read reads the decrypted data (see Reader.read).
+func (c *chunkReader) Read(p []byte) (int, error) {
+	return (*Reader)(c).read(p)
+}
+
+// Encrypt encrypts the plaintext and returns the container.
+// Returns an error if an option is invalid.
+func Encrypt(key [32]byte, plaintext []byte, opts ...Option) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w, err := NewWriter(&buf, key, opts...)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	_, err = w.Write(plaintext)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decrypt decrypts the container and returns the plaintext.
+// Returns an error if the container is malformed or can't be authenticated.
+func Decrypt(key [32]byte, container []byte, opts ...ReaderOption) ([]byte, error) {
+	r, err := NewReader(bytes.NewReader(container), key, opts...)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return plaintext, nil
+}
+
+// containerHeader holds the fields of a decoded container header.
+type containerHeader struct {
+	// compression identifies the compression algorithm.
+	compression byte
+
+	// chunkSize is the number of plaintext bytes per chunk.
+	chunkSize int
+
+	// noncePrefix is the random nonce prefix.
+	noncePrefix [NoncePrefixSize]byte
+
+	// metadata is the metadata block (empty for version 1 containers).
+	metadata []byte
+}
+
+// encodeHeader encodes the container header. The version 1 header is used if
+// there's no metadata so that such containers can be read by older readers.
+func encodeHeader(compression byte, chunkSize int, noncePrefix [NoncePrefixSize]byte, metadata []byte) []byte {
+	version := byte(Version)
+	if len(metadata) > 0 {
+		version = MetadataVersion
+	}
+
+	header := make([]byte, 0, HeaderSize+4+len(metadata))
+	header = append(header, Magic...)
+	header = append(header, version, compression)
+	header = binary.BigEndian.AppendUint32(header, uint32(chunkSize))
+	header = append(header, noncePrefix[:]...)
+
+	if version == MetadataVersion {
+		header = binary.BigEndian.AppendUint32(header, uint32(len(metadata)))
+		header = append(header, metadata...)
+	}
+
+	return header
+}
+
+// readHeader reads and decodes the container header and returns the encoded
+// header (which is the AAD of the chunks) and its fields.
+func readHeader(r io.Reader) ([]byte, containerHeader, error) {
+	header := make([]byte, HeaderSize)
+
+	_, err := io.ReadFull(r, header)
+	if err != nil || string(header[0:4]) != Magic {
+		return []byte{}, containerHeader{}, ErrInvalidHeader
+	}
+
+	version := header[4]
+	if version != Version && version != MetadataVersion {
+		return []byte{}, containerHeader{}, ErrInvalidHeader
+	}
+
+	fields := containerHeader{
+		compression: header[5],
+		chunkSize:   int(binary.BigEndian.Uint32(header[6:10])),
+		noncePrefix: [NoncePrefixSize]byte(header[10:HeaderSize]),
+	}
+
+	if fields.compression != compressionNone && fields.compression != compressionDeflate {
+		return []byte{}, containerHeader{}, ErrInvalidHeader
+	}
+
+	if fields.chunkSize < 1 || fields.chunkSize > MaxChunkSize {
+		return []byte{}, containerHeader{}, ErrInvalidHeader
+	}
+
+	if version == MetadataVersion {
+		header = append(header, make([]byte, 4)...)
+
+		_, err = io.ReadFull(r, header[HeaderSize:])
+		if err != nil {
+			return []byte{}, containerHeader{}, ErrInvalidHeader
+		}
+
+		size := binary.BigEndian.Uint32(header[HeaderSize:])
+		if size < 1 || size > MaxMetadataSize {
+			return []byte{}, containerHeader{}, ErrInvalidHeader
+		}
+
+		header = append(header, make([]byte, size)...)
+
+		_, err = io.ReadFull(r, header[HeaderSize+4:])
+		if err != nil {
+			return []byte{}, containerHeader{}, ErrInvalidHeader
+		}
+
+		fields.metadata = header[HeaderSize+4:]
+	}
+
+	return header, fields, nil
+}
+
+// decryptChunk authenticates and decrypts the chunk (the encrypted plaintext
+// followed by its tag) with the index.
+func decryptChunk(key [32]byte, header []byte, noncePrefix [NoncePrefixSize]byte, index uint64, last bool, chunk []byte) ([]byte, error) {
+	if len(chunk) < TagSize {
+		return []byte{}, debug.Detail(ErrDecryption, errTruncated)
+	}
+
+	ciphertext := chunk[:len(chunk)-TagSize]
+	tag := [16]byte(chunk[len(chunk)-TagSize:])
+
+	nonce := chunkNonce(noncePrefix, index, last)
+	plaintext, err := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce).Decrypt(ciphertext, header, tag)
+	if err != nil && last {
+		return []byte{}, debug.Detail(ErrDecryption, errInvalidFinalTag)
+	}
+	if err != nil {
+		return []byte{}, debug.Detail(ErrDecryption, errInvalidTag)
+	}
+
+	return plaintext, nil
+}
+
+// chunkOffset returns the offset (in bytes) of the chunk with the index in a
+// container with the header size and chunk size.
+func chunkOffset(headerSize int, chunkSize int, index int64) int64 {
+	return int64(headerSize) + index*int64(chunkSize+TagSize)
+}
+
+// chunkNonce returns the nonce of the chunk with the index. It's the nonce
+// prefix followed by the index (7 bytes, big endian) and the last chunk flag.
+func chunkNonce(noncePrefix [NoncePrefixSize]byte, index uint64, last bool) [24]byte {
+	var nonce [24]byte
+	copy(nonce[:], noncePrefix[:])
+
+	// The index is less than 2^56 so that its first (big endian) byte is zero.
+	var encodedIndex [8]byte
+	binary.BigEndian.PutUint64(encodedIndex[:], index)
+	copy(nonce[NoncePrefixSize:23], encodedIndex[1:])
+
+	if last {
+		nonce[23] = 0x01
+	}
+
+	return nonce
+}
@@ -0,0 +1,538 @@
+package stream_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+	"testing/iotest"
+
+	"github.com/pmuens/ctk-go/ctk/random"
+	"github.com/pmuens/ctk-go/ctk/stream"
+)
+
+func TestStream(t *testing.T) {
+	key := [32]byte{0x01, 0x02, 0x03}
+
+	t.Run("Encrypt + Decrypt", func(t *testing.T) {
+		t.Parallel()
+
+		lengths := []int{
+			0, 1,
+			stream.DefaultChunkSize - 1, stream.DefaultChunkSize, stream.DefaultChunkSize + 1,
+			2 * stream.DefaultChunkSize, 2*stream.DefaultChunkSize + 1,
+		}
+
+		for _, length := range lengths {
+			plaintext := make([]byte, length)
+			for i := range plaintext {
+				plaintext[i] = byte(i)
+			}
+
+			container, err := stream.Encrypt(key, plaintext)
+			if err != nil {
+				t.Fatalf("%v bytes: want error %v, got %v", length, nil, err)
+			}
+
+			chunks := max(1, (length+stream.DefaultChunkSize-1)/stream.DefaultChunkSize)
+			wantLength := stream.HeaderSize + length + chunks*stream.TagSize
+
+			if len(container) != wantLength {
+				t.Errorf("%v bytes: want length %v, got %v", length, wantLength, len(container))
+			}
+
+			got, err := stream.Decrypt(key, container)
+			if err != nil {
+				t.Fatalf("%v bytes: want error %v, got %v", length, nil, err)
+			}
+
+			if !slices.Equal(got, plaintext) {
+				t.Errorf("%v bytes: want plaintext to match", length)
+			}
+		}
+	})
+
+	t.Run("Writer + Reader", func(t *testing.T) {
+		t.Parallel()
+
+		plaintext := bytes.Repeat([]byte("attack at dawn "), 10000)
+
+		var buf bytes.Buffer
+		w, _ := stream.NewWriter(&buf, key)
+
+		// Write the plaintext in odd sized pieces.
+		for data := plaintext; len(data) > 0; {
+			n, err := w.Write(data[:min(777, len(data))])
+			if err != nil {
+				t.Fatalf("want error %v, got %v", nil, err)
+			}
+			data = data[n:]
+		}
+		w.Close()
+
+		r, err := stream.NewReader(iotest.OneByteReader(&buf), key)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		got, err := io.ReadAll(iotest.HalfReader(r))
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if !slices.Equal(got, plaintext) {
+			t.Errorf("want plaintext to match")
+		}
+	})
+
+	t.Run("Random Nonce Prefix", func(t *testing.T) {
+		t.Parallel()
+
+		first, _ := stream.Encrypt(key, []byte("attack at dawn"))
+		second, _ := stream.Encrypt(key, []byte("attack at dawn"))
+
+		if slices.Equal(first, second) {
+			t.Errorf("want different containers, got %v twice", first)
+		}
+	})
+
+	t.Run("Compression", func(t *testing.T) {
+		t.Parallel()
+
+		incompressible, err := random.Bytes(3*stream.DefaultChunkSize + 1)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		tt := map[string]struct {
+			plaintext []byte
+			smaller   bool
+		}{
+			"Empty":               {plaintext: []byte{}},
+			"Highly Compressible": {plaintext: make([]byte, 3*stream.DefaultChunkSize+1), smaller: true},
+			"Text":                {plaintext: bytes.Repeat([]byte("attack at dawn "), 10000), smaller: true},
+			"Incompressible":      {plaintext: incompressible},
+		}
+
+		for name, tc := range tt {
+			container, err := stream.Encrypt(key, tc.plaintext, stream.WithCompression(flate.BestCompression))
+			if err != nil {
+				t.Fatalf("%v: want error %v, got %v", name, nil, err)
+			}
+
+			if tc.smaller && len(container) >= len(tc.plaintext)/10 {
+				t.Errorf("%v: want container to be compressed, got %v bytes for %v bytes", name, len(container), len(tc.plaintext))
+			}
+
+			r, err := stream.NewReader(iotest.OneByteReader(bytes.NewReader(container)), key)
+			if err != nil {
+				t.Fatalf("%v: want error %v, got %v", name, nil, err)
+			}
+
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("%v: want error %v, got %v", name, nil, err)
+			}
+
+			if !slices.Equal(got, tc.plaintext) {
+				t.Errorf("%v: want plaintext to match", name)
+			}
+		}
+	})
+
+	t.Run("Compression Errors", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := stream.Encrypt(key, []byte{}, stream.WithCompression(42))
+		if err == nil {
+			t.Errorf("want error for invalid level, got %v", nil)
+		}
+
+		plaintext := bytes.Repeat([]byte("attack at dawn "), 100000)
+		container, _ := stream.Encrypt(key, plaintext, stream.WithCompression(flate.DefaultCompression))
+
+		tamperedChunk := slices.Clone(container)
+		tamperedChunk[stream.HeaderSize] ^= 0x01
+
+		// Claiming that a compressed container isn't compressed changes the
+		// header which is authenticated.
+		uncompressed := slices.Clone(container)
+		uncompressed[5] = 0x00
+
+		invalidAlgorithm := slices.Clone(container)
+		invalidAlgorithm[5] = 0x02
+
+		tt := map[string]struct {
+			container []byte
+			want      error
+		}{
+			"Truncated":         {container: container[:len(container)-1], want: stream.ErrDecryption},
+			"Tampered Chunk":    {container: tamperedChunk, want: stream.ErrDecryption},
+			"Compression Flag":  {container: uncompressed, want: stream.ErrDecryption},
+			"Invalid Algorithm": {container: invalidAlgorithm, want: stream.ErrDecryption},
+		}
+
+		for name, tc := range tt {
+			_, err := stream.Decrypt(key, tc.container)
+
+			if !errors.Is(err, tc.want) {
+				t.Errorf("%v: want error %v, got %v", name, tc.want, err)
+			}
+		}
+
+		_, err = stream.OpenSeeker(bytes.NewReader(container), key)
+		if !errors.Is(err, stream.ErrCompressed) {
+			t.Errorf("want error %v, got %v", stream.ErrCompressed, err)
+		}
+	})
+
+	t.Run("Closed", func(t *testing.T) {
+		t.Parallel()
+
+		w, _ := stream.NewWriter(io.Discard, key)
+		w.Close()
+
+		_, err := w.Write([]byte{0x01})
+
+		if !errors.Is(err, stream.ErrClosed) {
+			t.Errorf("want error %v, got %v", stream.ErrClosed, err)
+		}
+
+		err = w.Close()
+
+		if !errors.Is(err, stream.ErrClosed) {
+			t.Errorf("want error %v, got %v", stream.ErrClosed, err)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		plaintext := make([]byte, 2*stream.DefaultChunkSize+1)
+		container, _ := stream.Encrypt(key, plaintext)
+
+		chunk := stream.DefaultChunkSize + stream.TagSize
+
+		tamperedHeader := slices.Clone(container)
+		tamperedHeader[stream.HeaderSize-1] ^= 0x01
+
+		tamperedChunk := slices.Clone(container)
+		tamperedChunk[stream.HeaderSize+chunk] ^= 0x01
+
+		invalidVersion := slices.Clone(container)
+		invalidVersion[4] = 0x02
+
+		invalidChunkSize := slices.Clone(container)
+		copy(invalidChunkSize[5:9], []byte{0x00, 0x00, 0x00, 0x00})
+
+		// Swap the first and second chunk.
+		reordered := slices.Concat(
+			container[:stream.HeaderSize],
+			container[stream.HeaderSize+chunk:stream.HeaderSize+2*chunk],
+			container[stream.HeaderSize:stream.HeaderSize+chunk],
+			container[stream.HeaderSize+2*chunk:],
+		)
+
+		tt := map[string]struct {
+			key       [32]byte
+			container []byte
+			want      error
+		}{
+			"Wrong Key":          {key: [32]byte{0x04}, container: container, want: stream.ErrDecryption},
+			"Tampered Header":    {key: key, container: tamperedHeader, want: stream.ErrDecryption},
+			"Tampered Chunk":     {key: key, container: tamperedChunk, want: stream.ErrDecryption},
+			"Reordered Chunks":   {key: key, container: reordered, want: stream.ErrDecryption},
+			"Dropped Last Chunk": {key: key, container: container[:stream.HeaderSize+2*chunk], want: stream.ErrDecryption},
+			"Truncated Chunk":    {key: key, container: container[:len(container)-1], want: stream.ErrDecryption},
+			"Appended Data":      {key: key, container: append(slices.Clone(container), 0x00), want: stream.ErrDecryption},
+			"Header Only":        {key: key, container: container[:stream.HeaderSize], want: stream.ErrDecryption},
+			"Short Header":       {key: key, container: container[:stream.HeaderSize-1], want: stream.ErrDecryption},
+			"Invalid Magic":      {key: key, container: append([]byte("XXXX"), container[4:]...), want: stream.ErrDecryption},
+			"Invalid Version":    {key: key, container: invalidVersion, want: stream.ErrDecryption},
+			"Invalid Chunk Size": {key: key, container: invalidChunkSize, want: stream.ErrDecryption},
+		}
+
+		for name, tc := range tt {
+			_, err := stream.Decrypt(tc.key, tc.container)
+
+			if !errors.Is(err, tc.want) {
+				t.Errorf("%v: want error %v, got %v", name, tc.want, err)
+			}
+		}
+
+		// The error names the chunk but not why it was rejected.
+		_, err := stream.Decrypt(key, tamperedChunk)
+
+		var chunkErr *stream.ChunkError
+		if !errors.As(err, &chunkErr) || chunkErr.Index != 1 || chunkErr.Offset != int64(stream.HeaderSize+chunk) {
+			t.Fatalf("want chunk %v at offset %v, got %v", 1, stream.HeaderSize+chunk, err)
+		}
+
+		if chunkErr.Err != stream.ErrDecryption {
+			t.Errorf("want error %v, got %v", stream.ErrDecryption, chunkErr.Err)
+		}
+	})
+}
+
+func TestChunkSize(t *testing.T) {
+	key := [32]byte{0x01, 0x02, 0x03}
+	plaintext := bytes.Repeat([]byte("attack at dawn "), 100)
+
+	t.Run("Custom Chunk Sizes", func(t *testing.T) {
+		t.Parallel()
+
+		for _, size := range []int{1, 15, 1024, stream.MaxChunkSize} {
+			container, err := stream.Encrypt(key, plaintext, stream.WithChunkSize(size))
+			if err != nil {
+				t.Fatalf("%v bytes: want error %v, got %v", size, nil, err)
+			}
+
+			chunks := (len(plaintext) + size - 1) / size
+			wantLength := stream.HeaderSize + len(plaintext) + chunks*stream.TagSize
+
+			if len(container) != wantLength {
+				t.Errorf("%v bytes: want length %v, got %v", size, wantLength, len(container))
+			}
+
+			// The Reader and Seeker use the chunk size of the header.
+			got, err := stream.Decrypt(key, container)
+			if err != nil || !slices.Equal(got, plaintext) {
+				t.Errorf("%v bytes: want plaintext to match (error %v)", size, err)
+			}
+
+			s, err := stream.OpenSeeker(bytes.NewReader(container), key)
+			if err != nil {
+				t.Fatalf("%v bytes: want error %v, got %v", size, nil, err)
+			}
+
+			got, err = io.ReadAll(s)
+			if err != nil || !slices.Equal(got, plaintext) {
+				t.Errorf("%v bytes: want plaintext to match (error %v)", size, err)
+			}
+		}
+	})
+
+	t.Run("Invalid Chunk Sizes", func(t *testing.T) {
+		t.Parallel()
+
+		for _, size := range []int{-1, 0, stream.MaxChunkSize + 1} {
+			_, err := stream.NewWriter(io.Discard, key, stream.WithChunkSize(size))
+			if !errors.Is(err, stream.ErrInvalidChunkSize) {
+				t.Errorf("%v bytes: want error %v, got %v", size, stream.ErrInvalidChunkSize, err)
+			}
+		}
+	})
+
+	t.Run("Buffer Limit", func(t *testing.T) {
+		t.Parallel()
+
+		container, err := stream.Encrypt(key, plaintext, stream.WithChunkSize(1024))
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		tt := map[string]struct {
+			limit int
+			err   error
+		}{
+			"Exact Fit":     {1024 + stream.TagSize, nil},
+			"Default Limit": {stream.DefaultMaxBufferedBytes, nil},
+			"Too Small":     {1024 + stream.TagSize - 1, stream.ErrChunkTooLarge},
+			"Invalid Limit": {stream.TagSize, stream.ErrInvalidChunkSize},
+		}
+
+		for name, tc := range tt {
+			_, err := stream.Decrypt(key, container, stream.WithMaxBufferedBytes(tc.limit))
+			if !errors.Is(err, tc.err) {
+				t.Errorf("%v: want error %v, got %v", name, tc.err, err)
+			}
+
+			_, err = stream.OpenSeeker(bytes.NewReader(container), key, stream.WithMaxBufferedBytes(tc.limit))
+			if !errors.Is(err, tc.err) {
+				t.Errorf("%v: want error %v, got %v", name, tc.err, err)
+			}
+		}
+	})
+}
+
+func TestContext(t *testing.T) {
+	key := [32]byte{0x01, 0x02, 0x03}
+	plaintext := bytes.Repeat([]byte("attack at dawn "), 100)
+
+	container, err := stream.Encrypt(key, plaintext, stream.WithChunkSize(64))
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	t.Run("Writer", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		w, err := stream.NewWriter(io.Discard, key, stream.WithChunkSize(64), stream.WithContext(ctx))
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		_, err = w.Write(plaintext[:200])
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		cancel()
+
+		_, err = w.Write(plaintext[200:])
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("want error %v, got %v", context.Canceled, err)
+		}
+
+		err = w.Close()
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("want error %v, got %v", context.Canceled, err)
+		}
+	})
+
+	t.Run("Reader", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		r, err := stream.NewReader(bytes.NewReader(container), key, stream.WithReaderContext(ctx))
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		got := make([]byte, 64)
+
+		_, err = io.ReadFull(r, got)
+		if err != nil || !slices.Equal(got, plaintext[:64]) {
+			t.Fatalf("want first chunk and error %v, got %v", nil, err)
+		}
+
+		cancel()
+
+		_, err = io.ReadAll(r)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("want error %v, got %v", context.Canceled, err)
+		}
+	})
+
+	t.Run("Seeker", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		s, err := stream.OpenSeeker(bytes.NewReader(container), key, stream.WithReaderContext(ctx))
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		cancel()
+
+		_, err = io.ReadAll(s)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("want error %v, got %v", context.Canceled, err)
+		}
+	})
+}
+
+func TestStrictVerification(t *testing.T) {
+	key := [32]byte{0x01, 0x02, 0x03}
+	plaintext := bytes.Repeat([]byte("attack at dawn "), 100)
+
+	container, err := stream.Encrypt(key, plaintext, stream.WithChunkSize(64))
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	compressed, err := stream.Encrypt(key, plaintext, stream.WithChunkSize(64), stream.WithCompression(flate.BestCompression))
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	// The last chunk is tampered with so that all other chunks are intact.
+	tampered := slices.Clone(container)
+	tampered[len(tampered)-1] ^= 0x01
+
+	// streamOnly hides the Seek method so that the Reader needs to buffer.
+	type streamOnly struct {
+		io.Reader
+	}
+
+	tt := map[string]struct {
+		r    func(container []byte) io.Reader
+		opts []stream.ReaderOption
+	}{
+		"Two Passes": {
+			r:    func(c []byte) io.Reader { return bytes.NewReader(c) },
+			opts: []stream.ReaderOption{stream.WithStrictVerification(0)},
+		},
+		"Buffered": {
+			r:    func(c []byte) io.Reader { return streamOnly{bytes.NewReader(c)} },
+			opts: []stream.ReaderOption{stream.WithStrictVerification(int64(len(plaintext)))},
+		},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			for _, c := range [][]byte{container, compressed} {
+				r, err := stream.NewReader(tc.r(c), key, tc.opts...)
+				if err != nil {
+					t.Fatalf("want error %v, got %v", nil, err)
+				}
+
+				got, err := io.ReadAll(r)
+				if err != nil || !slices.Equal(got, plaintext) {
+					t.Errorf("want %v, got %v (error %v)", len(plaintext), len(got), err)
+				}
+			}
+
+			r, err := stream.NewReader(tc.r(tampered), key, tc.opts...)
+			if err != nil {
+				t.Fatalf("want error %v, got %v", nil, err)
+			}
+
+			n, err := r.Read(make([]byte, 64))
+			if n != 0 || !errors.Is(err, stream.ErrDecryption) {
+				t.Errorf("want %v bytes and error %v, got %v bytes and error %v", 0, stream.ErrDecryption, n, err)
+			}
+		})
+	}
+
+	t.Run("Buffer Limit", func(t *testing.T) {
+		t.Parallel()
+
+		r, err := stream.NewReader(streamOnly{bytes.NewReader(container)}, key, stream.WithStrictVerification(int64(len(plaintext)-1)))
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		_, err = r.Read(make([]byte, 64))
+		if !errors.Is(err, stream.ErrTooLarge) {
+			t.Errorf("want error %v, got %v", stream.ErrTooLarge, err)
+		}
+	})
+
+	t.Run("Seeker", func(t *testing.T) {
+		t.Parallel()
+
+		// A tampered first chunk is only detected when it's read by default.
+		firstTampered := slices.Clone(container)
+		firstTampered[stream.HeaderSize] ^= 0x01
+
+		_, err := stream.OpenSeeker(bytes.NewReader(firstTampered), key)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		_, err = stream.OpenSeeker(bytes.NewReader(firstTampered), key, stream.WithStrictVerification(0))
+		if !errors.Is(err, stream.ErrDecryption) {
+			t.Errorf("want error %v, got %v", stream.ErrDecryption, err)
+		}
+	})
+}
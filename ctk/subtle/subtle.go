@@ -0,0 +1,82 @@
+// Package subtle implements functions whose running time doesn't depend on the
+// (secret) values of their inputs. They are used to compare tags and to select
+// between secrets without leaking information via timing side channels.
+//
+// The functions have the same semantics as the ones of crypto/subtle but are
+// implemented (and tested) here so that the toolkit doesn't depend on the
+// guarantees of another implementation.
+//
+// Note that the Go compiler doesn't guarantee that the code it generates runs
+// in constant time. The functions only use operations (e.g. bitwise operations
+// and arithmetic on fixed size integers) which don't branch on their inputs on
+// common architectures.
+package subtle
+
+// ConstantTimeCompare returns 1 if x and y have equal contents and 0 otherwise.
+// The time taken depends on the lengths of the slices but not on their contents
+// (slices of different lengths return 0 immediately).
+func ConstantTimeCompare(x []byte, y []byte) int {
+	if len(x) != len(y) {
+		return 0
+	}
+
+	var v byte
+	for i := range x {
+		v |= x[i] ^ y[i]
+	}
+
+	return ConstantTimeByteEq(v, 0)
+}
+
+// ConstantTimeByteEq returns 1 if x == y and 0 otherwise.
+func ConstantTimeByteEq(x byte, y byte) int {
+	// z - 1 only underflows (setting bit 31) if z is 0.
+	return int((uint32(x^y) - 1) >> 31)
+}
+
+// ConstantTimeSelect returns x if v is 1 and y if v is 0.
+// The behavior is undefined if v takes any other value.
+func ConstantTimeSelect(v int, x int, y int) int {
+	return ^(v-1)&x | (v-1)&y
+}
+
+// ConstantTimeCopy copies the contents of src into dst if v is 1 and leaves dst
+// unchanged if v is 0.
+// The behavior is undefined if v takes any other value.
+//
+// ConstantTimeCopy panics if dst and src have different lengths.
+func ConstantTimeCopy(v int, dst []byte, src []byte) {
+	if len(dst) != len(src) {
+		panic("subtle: slices have different lengths")
+	}
+
+	xmask := byte(v - 1)
+	ymask := byte(^(v - 1))
+	for i := range dst {
+		dst[i] = dst[i]&xmask | src[i]&ymask
+	}
+}
+
+// ConstantTimeLessOrEq returns 1 if x <= y and 0 otherwise.
+// The behavior is undefined if x or y are negative or larger than 2^31 - 1.
+func ConstantTimeLessOrEq(x int, y int) int {
+	// y - x is negative (setting bit 31) if and only if x > y.
+	return int(((int32(y) - int32(x)) >> 31) + 1)
+}
+
+// XORBytes sets dst[i] = x[i] ^ y[i] for all i < n = min(len(x), len(y)) and
+// returns n.
+//
+// XORBytes panics if dst is shorter than n bytes.
+func XORBytes(dst []byte, x []byte, y []byte) int {
+	n := min(len(x), len(y))
+	if len(dst) < n {
+		panic("subtle: destination too short")
+	}
+
+	for i := range n {
+		dst[i] = x[i] ^ y[i]
+	}
+
+	return n
+}
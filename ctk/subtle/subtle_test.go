@@ -0,0 +1,138 @@
+package subtle_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/subtle"
+)
+
+func TestSubtle(t *testing.T) {
+	t.Run("ConstantTimeCompare", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]struct {
+			x    []byte
+			y    []byte
+			want int
+		}{
+			"Equal":           {x: []byte{0x01, 0x02, 0x03}, y: []byte{0x01, 0x02, 0x03}, want: 1},
+			"Empty":           {x: []byte{}, y: nil, want: 1},
+			"First Byte":      {x: []byte{0x01, 0x02, 0x03}, y: []byte{0x00, 0x02, 0x03}, want: 0},
+			"Last Byte":       {x: []byte{0x01, 0x02, 0x03}, y: []byte{0x01, 0x02, 0x83}, want: 0},
+			"Different Sizes": {x: []byte{0x01, 0x02, 0x03}, y: []byte{0x01, 0x02}, want: 0},
+		}
+
+		for name, tc := range tt {
+			got := subtle.ConstantTimeCompare(tc.x, tc.y)
+			if got != tc.want {
+				t.Errorf("%v: want %v, got %v", name, tc.want, got)
+			}
+		}
+	})
+
+	t.Run("ConstantTimeByteEq", func(t *testing.T) {
+		t.Parallel()
+
+		for x := range 256 {
+			for y := range 256 {
+				want := 0
+				if x == y {
+					want = 1
+				}
+
+				got := subtle.ConstantTimeByteEq(byte(x), byte(y))
+				if got != want {
+					t.Errorf("%v == %v: want %v, got %v", x, y, want, got)
+				}
+			}
+		}
+	})
+
+	t.Run("ConstantTimeSelect", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]struct {
+			v    int
+			x    int
+			y    int
+			want int
+		}{
+			"Select X": {v: 1, x: 42, y: 7, want: 42},
+			"Select Y": {v: 0, x: 42, y: 7, want: 7},
+			"Negative": {v: 1, x: -1, y: 0, want: -1},
+		}
+
+		for name, tc := range tt {
+			got := subtle.ConstantTimeSelect(tc.v, tc.x, tc.y)
+			if got != tc.want {
+				t.Errorf("%v: want %v, got %v", name, tc.want, got)
+			}
+		}
+	})
+
+	t.Run("ConstantTimeCopy", func(t *testing.T) {
+		t.Parallel()
+
+		src := []byte{0x01, 0x02, 0x03}
+
+		dst := []byte{0xff, 0xff, 0xff}
+		subtle.ConstantTimeCopy(0, dst, src)
+
+		if want := []byte{0xff, 0xff, 0xff}; !slices.Equal(dst, want) {
+			t.Errorf("want %v, got %v", want, dst)
+		}
+
+		subtle.ConstantTimeCopy(1, dst, src)
+
+		if !slices.Equal(dst, src) {
+			t.Errorf("want %v, got %v", src, dst)
+		}
+
+		defer func() {
+			if recover() == nil {
+				t.Errorf("want panic, got none")
+			}
+		}()
+
+		subtle.ConstantTimeCopy(1, dst, src[:2])
+	})
+
+	t.Run("ConstantTimeLessOrEq", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]struct {
+			x    int
+			y    int
+			want int
+		}{
+			"Less":          {x: 1, y: 2, want: 1},
+			"Equal":         {x: 2, y: 2, want: 1},
+			"Greater":       {x: 3, y: 2, want: 0},
+			"Zero":          {x: 0, y: 0, want: 1},
+			"Max Less":      {x: 0, y: 1<<31 - 1, want: 1},
+			"Max Greater":   {x: 1<<31 - 1, y: 0, want: 0},
+			"Max Equal":     {x: 1<<31 - 1, y: 1<<31 - 1, want: 1},
+			"Adjacent High": {x: 1<<31 - 1, y: 1<<31 - 2, want: 0},
+		}
+
+		for name, tc := range tt {
+			got := subtle.ConstantTimeLessOrEq(tc.x, tc.y)
+			if got != tc.want {
+				t.Errorf("%v: want %v, got %v", name, tc.want, got)
+			}
+		}
+	})
+
+	t.Run("XORBytes", func(t *testing.T) {
+		t.Parallel()
+
+		dst := make([]byte, 4)
+		n := subtle.XORBytes(dst, []byte{0x0f, 0xf0, 0xaa}, []byte{0xff, 0xff, 0x55, 0x01})
+		want := []byte{0xf0, 0x0f, 0xff, 0x00}
+
+		if n != 3 || !slices.Equal(dst, want) {
+			t.Errorf("want %v (n = %v), got %v (n = %v)", want, 3, dst, n)
+		}
+	})
+}
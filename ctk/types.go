@@ -0,0 +1,23 @@
+package ctk
+
+import (
+	"github.com/pmuens/ctk-go/ctk/encoding"
+	"github.com/pmuens/ctk-go/ctk/random"
+)
+
+// Key is a 32 byte key (see encoding.Key for its text and JSON encodings).
+type Key = encoding.Key
+
+// Nonce is a 12 byte (ChaCha20) nonce (see encoding.Nonce).
+type Nonce = encoding.Nonce
+
+// XNonce is a 24 byte (XChaCha20) nonce (see encoding.XNonce).
+type XNonce = encoding.XNonce
+
+// Tag is a 16 byte (Poly1305) tag (see encoding.Tag).
+type Tag = encoding.Tag
+
+// GenerateKey returns a new random key (see random.Read for the health checks).
+func GenerateKey() (Key, error) {
+	return random.Key()
+}
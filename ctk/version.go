@@ -0,0 +1,4 @@
+package ctk
+
+// Version is the version of the toolkit's public API (semantic versioning).
+const Version = "0.1.0"
@@ -0,0 +1,72 @@
+package x25519
+
+import (
+	"crypto/sha512"
+	"math/big"
+	"slices"
+)
+
+// d is the constant -121665 / 121666 of the Edwards curve edwards25519.
+var d = mod(new(big.Int).Mul(
+	big.NewInt(-121665),
+	new(big.Int).ModInverse(big.NewInt(121666), P),
+))
+
+// FromEd25519PublicKey converts an Ed25519 public key to the X25519 public key
+// of the same key pair using the birational map u = (1 + y) / (1 - y) between
+// edwards25519 and Curve25519 (see RFC 7748, Section 4.1).
+// Returns an error if the public key isn't a canonical encoding of a point on
+// the curve or if it's the identity point.
+//
+// Note that using the same key pair for signatures and key exchange is only safe
+// if both protocols are designed for it (e.g. by domain separating all derived
+// keys). Prefer separate key pairs if possible.
+func FromEd25519PublicKey(public [32]byte) ([32]byte, error) {
+	// The most significant bit is the sign of x which isn't needed given that
+	// the map only depends on y.
+	public[31] &= 127
+
+	bytes := public[:]
+	slices.Reverse(bytes)
+
+	y := new(big.Int).SetBytes(bytes)
+	one := big.NewInt(1)
+
+	if y.Cmp(P) >= 0 || y.Cmp(one) == 0 {
+		return [32]byte{}, ErrInvalidEd25519PublicKey
+	}
+
+	// The point is on the curve if x^2 = (y^2 - 1) / (d * y^2 + 1) has a
+	// solution (i.e. if it's zero or a quadratic residue).
+	yy := mod(new(big.Int).Mul(y, y))
+	numerator := mod(new(big.Int).Sub(yy, one))
+	denominator := mod(new(big.Int).Add(mod(new(big.Int).Mul(d, yy)), one))
+	xx := mod(new(big.Int).Mul(numerator, new(big.Int).ModInverse(denominator, P)))
+
+	if xx.Sign() != 0 && big.Jacobi(xx, P) != 1 {
+		return [32]byte{}, ErrInvalidEd25519PublicKey
+	}
+
+	u := mod(new(big.Int).Add(one, y))
+	u = mod(u.Mul(u, new(big.Int).ModInverse(mod(new(big.Int).Sub(one, y)), P)))
+
+	return encodeUCoordinate(u), nil
+}
+
+// FromEd25519PrivateKey converts an Ed25519 private key (the 32 byte seed) to
+// the X25519 private key of the same key pair which is the (clamped) scalar
+// Ed25519 derives from the seed (see RFC 8032, Section 5.1.5).
+// The result corresponds to the X25519 public key computed by
+// FromEd25519PublicKey (see the note about key reuse there).
+func FromEd25519PrivateKey(seed [32]byte) [32]byte {
+	hash := sha512.Sum512(seed[:])
+	defer clear(hash[:])
+
+	var private [32]byte
+	copy(private[:], hash[:32])
+	private[0] &= 248
+	private[31] &= 127
+	private[31] |= 64
+
+	return private
+}
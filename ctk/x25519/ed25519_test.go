@@ -0,0 +1,136 @@
+package x25519_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/x25519"
+)
+
+func TestX25519FromEd25519(t *testing.T) {
+	t.Run("libsodium - ed25519_convert", func(t *testing.T) {
+		t.Parallel()
+
+		seed := [32]byte{
+			0x42, 0x11, 0x51, 0xa4, 0x59, 0xfa, 0xea, 0xde,
+			0x3d, 0x24, 0x71, 0x15, 0xf9, 0x4a, 0xed, 0xae,
+			0x42, 0x31, 0x81, 0x24, 0x09, 0x5a, 0xfa, 0xbe,
+			0x4d, 0x14, 0x51, 0xa5, 0x59, 0xfa, 0xed, 0xee,
+		}
+
+		edPublic := [32]byte{
+			0xb5, 0x07, 0x6a, 0x84, 0x74, 0xa8, 0x32, 0xda,
+			0xee, 0x4d, 0xd5, 0xb4, 0x04, 0x09, 0x83, 0xb6,
+			0x62, 0x3b, 0x5f, 0x34, 0x4a, 0xca, 0x57, 0xd4,
+			0xd6, 0xee, 0x4b, 0xaf, 0x3f, 0x25, 0x9e, 0x6e,
+		}
+
+		wantPrivate := [32]byte{
+			0x80, 0x52, 0x03, 0x03, 0x76, 0xd4, 0x71, 0x12,
+			0xbe, 0x7f, 0x73, 0xed, 0x7a, 0x01, 0x92, 0x93,
+			0xdd, 0x12, 0xad, 0x91, 0x0b, 0x65, 0x44, 0x55,
+			0x79, 0x8b, 0x46, 0x67, 0xd7, 0x3d, 0xe1, 0x66,
+		}
+
+		wantPublic := [32]byte{
+			0xf1, 0x81, 0x4f, 0x0e, 0x8f, 0xf1, 0x04, 0x3d,
+			0x8a, 0x44, 0xd2, 0x5b, 0xab, 0xff, 0x3c, 0xed,
+			0xca, 0xe6, 0xc2, 0x2c, 0x3e, 0xda, 0xa4, 0x8f,
+			0x85, 0x7a, 0xe7, 0x0d, 0xe2, 0xba, 0xae, 0x50,
+		}
+
+		gotPrivate := x25519.FromEd25519PrivateKey(seed)
+		if gotPrivate != wantPrivate {
+			t.Errorf("want %v, got %v", wantPrivate, gotPrivate)
+		}
+
+		gotPublic, err := x25519.FromEd25519PublicKey(edPublic)
+		if gotPublic != wantPublic {
+			t.Errorf("want %v, got %v (error %v)", wantPublic, gotPublic, err)
+		}
+	})
+
+	t.Run("RFC 8032 - Test Vectors - 7.1 - #1", func(t *testing.T) {
+		t.Parallel()
+
+		seed := [32]byte{
+			0x9d, 0x61, 0xb1, 0x9d, 0xef, 0xfd, 0x5a, 0x60,
+			0xba, 0x84, 0x4a, 0xf4, 0x92, 0xec, 0x2c, 0xc4,
+			0x44, 0x49, 0xc5, 0x69, 0x7b, 0x32, 0x69, 0x19,
+			0x70, 0x3b, 0xac, 0x03, 0x1c, 0xae, 0x7f, 0x60,
+		}
+
+		edPublic := [32]byte{
+			0xd7, 0x5a, 0x98, 0x01, 0x82, 0xb1, 0x0a, 0xb7,
+			0xd5, 0x4b, 0xfe, 0xd3, 0xc9, 0x64, 0x07, 0x3a,
+			0x0e, 0xe1, 0x72, 0xf3, 0xda, 0xa6, 0x23, 0x25,
+			0xaf, 0x02, 0x1a, 0x68, 0xf7, 0x07, 0x51, 0x1a,
+		}
+
+		wantPrivate := [32]byte{
+			0x30, 0x7c, 0x83, 0x86, 0x4f, 0x28, 0x33, 0xcb,
+			0x42, 0x7a, 0x2e, 0xf1, 0xc0, 0x0a, 0x01, 0x3c,
+			0xfd, 0xff, 0x27, 0x68, 0xd9, 0x80, 0xc0, 0xa3,
+			0xa5, 0x20, 0xf0, 0x06, 0x90, 0x4d, 0xe9, 0x4f,
+		}
+
+		wantPublic := [32]byte{
+			0xd8, 0x5e, 0x07, 0xec, 0x22, 0xb0, 0xad, 0x88,
+			0x15, 0x37, 0xc2, 0xf4, 0x4d, 0x66, 0x2d, 0x1a,
+			0x14, 0x3c, 0xf8, 0x30, 0xc5, 0x7a, 0xca, 0x43,
+			0x05, 0xd8, 0x5c, 0x7a, 0x90, 0xf6, 0xb6, 0x2e,
+		}
+
+		gotPrivate := x25519.FromEd25519PrivateKey(seed)
+		if gotPrivate != wantPrivate {
+			t.Errorf("want %v, got %v", wantPrivate, gotPrivate)
+		}
+
+		gotPublic, err := x25519.FromEd25519PublicKey(edPublic)
+		if gotPublic != wantPublic {
+			t.Errorf("want %v, got %v (error %v)", wantPublic, gotPublic, err)
+		}
+	})
+
+	t.Run("Key Pair", func(t *testing.T) {
+		t.Parallel()
+
+		for range 10 {
+			edPublic, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+
+			public, err := x25519.FromEd25519PublicKey([32]byte(edPublic))
+			if err != nil {
+				t.Fatalf("want error %v, got %v", nil, err)
+			}
+
+			want := x25519.PublicKey(x25519.FromEd25519PrivateKey([32]byte(edPrivate.Seed())))
+			if public != want {
+				t.Errorf("want %v, got %v", want, public)
+			}
+		}
+	})
+
+	t.Run("Invalid Public Keys", func(t *testing.T) {
+		t.Parallel()
+
+		// The identity point (y = 1), a y-coordinate without a point on the curve
+		// (y = 2) and a non-canonical y-coordinate (y = p).
+		identity := [32]byte{0x01}
+		notOnCurve := [32]byte{0x02}
+		nonCanonical := [32]byte{
+			0xed, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+			0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+			0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+			0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f,
+		}
+
+		for _, public := range [][32]byte{identity, notOnCurve, nonCanonical} {
+			_, err := x25519.FromEd25519PublicKey(public)
+			if !errors.Is(err, x25519.ErrInvalidEd25519PublicKey) {
+				t.Errorf("%v: want error %v, got %v", public, x25519.ErrInvalidEd25519PublicKey, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,246 @@
+package x25519
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// fieldElement is an element of the field of integers modulo P, represented
+// as five 51 bit limbs (least significant limb first) so that the arithmetic
+// runs in constant time. The limbs may exceed 51 bits between operations (the
+// representation isn't unique) and are only fully reduced when the element is
+// encoded.
+type fieldElement [5]uint64
+
+// maskLow51Bits masks the lowest 51 bits of a limb.
+const maskLow51Bits = (1 << 51) - 1
+
+// fieldOne is the field element 1.
+var fieldOne = fieldElement{1}
+
+// fieldA24 is the field element of the constant a24 = (486662 - 2) / 4 used in
+// the Montgomery ladder.
+var fieldA24 = fieldElement{121665}
+
+// feFromBytes decodes the 32 byte little endian encoding (ignoring the most
+// significant bit, see RFC 7748, Section 5). Encodings of values that aren't
+// smaller than P are accepted and reduced by the arithmetic.
+func feFromBytes(b [32]byte) fieldElement {
+	// Limb i starts at bit 51 * i.
+	return fieldElement{
+		binary.LittleEndian.Uint64(b[0:8]) & maskLow51Bits,
+		binary.LittleEndian.Uint64(b[6:14]) >> 3 & maskLow51Bits,
+		binary.LittleEndian.Uint64(b[12:20]) >> 6 & maskLow51Bits,
+		binary.LittleEndian.Uint64(b[19:27]) >> 1 & maskLow51Bits,
+		binary.LittleEndian.Uint64(b[24:32]) >> 12 & maskLow51Bits,
+	}
+}
+
+// bytes returns the 32 byte little endian encoding of the fully reduced
+// element.
+func (v fieldElement) bytes() [32]byte {
+	v = v.reduce()
+
+	var result [32]byte
+	for i, limb := range v {
+		offset := i * 51
+
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], limb<<(offset%8))
+
+		for j, b := range buf {
+			if offset/8+j < len(result) {
+				result[offset/8+j] |= b
+			}
+		}
+	}
+
+	return result
+}
+
+// carryPropagate carries the bits above the lowest 51 bits of every limb into
+// the next limb. The carry of the most significant limb is multiplied by 19 and
+// added to the least significant one (as 2^255 = 19 mod P).
+func (v fieldElement) carryPropagate() fieldElement {
+	c0 := v[0] >> 51
+	c1 := v[1] >> 51
+	c2 := v[2] >> 51
+	c3 := v[3] >> 51
+	c4 := v[4] >> 51
+
+	return fieldElement{
+		v[0]&maskLow51Bits + c4*19,
+		v[1]&maskLow51Bits + c0,
+		v[2]&maskLow51Bits + c1,
+		v[3]&maskLow51Bits + c2,
+		v[4]&maskLow51Bits + c3,
+	}
+}
+
+// reduce fully reduces the element modulo P (so that its encoding is
+// canonical) in constant time.
+func (v fieldElement) reduce() fieldElement {
+	v = v.carryPropagate()
+
+	// The element is now smaller than 2^255 + 2^13 * 19. c is 1 if it's at
+	// least P (i.e. if adding 19 carries into bit 255) and 0 otherwise.
+	c := (v[0] + 19) >> 51
+	c = (v[1] + c) >> 51
+	c = (v[2] + c) >> 51
+	c = (v[3] + c) >> 51
+	c = (v[4] + c) >> 51
+
+	// Subtract P by adding 19 and dropping bit 255.
+	v[0] += 19 * c
+
+	v[1] += v[0] >> 51
+	v[0] &= maskLow51Bits
+	v[2] += v[1] >> 51
+	v[1] &= maskLow51Bits
+	v[3] += v[2] >> 51
+	v[2] &= maskLow51Bits
+	v[4] += v[3] >> 51
+	v[3] &= maskLow51Bits
+	v[4] &= maskLow51Bits
+
+	return v
+}
+
+// feAdd returns a + b.
+func feAdd(a, b fieldElement) fieldElement {
+	return fieldElement{
+		a[0] + b[0],
+		a[1] + b[1],
+		a[2] + b[2],
+		a[3] + b[3],
+		a[4] + b[4],
+	}.carryPropagate()
+}
+
+// feSub returns a - b. 2 * P is added first so that the limbs don't underflow.
+func feSub(a, b fieldElement) fieldElement {
+	return fieldElement{
+		(a[0] + 0xFFFFFFFFFFFDA) - b[0],
+		(a[1] + 0xFFFFFFFFFFFFE) - b[1],
+		(a[2] + 0xFFFFFFFFFFFFE) - b[2],
+		(a[3] + 0xFFFFFFFFFFFFE) - b[3],
+		(a[4] + 0xFFFFFFFFFFFFE) - b[4],
+	}.carryPropagate()
+}
+
+// uint128 is a 128 bit product of two limbs.
+type uint128 struct {
+	lo, hi uint64
+}
+
+// mul64 returns a * b.
+func mul64(a, b uint64) uint128 {
+	hi, lo := bits.Mul64(a, b)
+	return uint128{lo, hi}
+}
+
+// addMul64 returns v + a * b.
+func addMul64(v uint128, a, b uint64) uint128 {
+	hi, lo := bits.Mul64(a, b)
+	lo, c := bits.Add64(lo, v.lo, 0)
+	hi, _ = bits.Add64(hi, v.hi, c)
+
+	return uint128{lo, hi}
+}
+
+// shiftRightBy51 returns a >> 51 (which fits into 64 bits for the products of
+// the multiplication).
+func shiftRightBy51(a uint128) uint64 {
+	return a.hi<<(64-51) | a.lo>>51
+}
+
+// feMul returns a * b. The products of the limbs that exceed 2^255 are
+// multiplied by 19 and added to the lower limbs (as 2^255 = 19 mod P).
+func feMul(a, b fieldElement) fieldElement {
+	a1x19 := a[1] * 19
+	a2x19 := a[2] * 19
+	a3x19 := a[3] * 19
+	a4x19 := a[4] * 19
+
+	// r0 = a0×b0 + 19×(a1×b4 + a2×b3 + a3×b2 + a4×b1)
+	r0 := mul64(a[0], b[0])
+	r0 = addMul64(r0, a1x19, b[4])
+	r0 = addMul64(r0, a2x19, b[3])
+	r0 = addMul64(r0, a3x19, b[2])
+	r0 = addMul64(r0, a4x19, b[1])
+
+	// r1 = a0×b1 + a1×b0 + 19×(a2×b4 + a3×b3 + a4×b2)
+	r1 := mul64(a[0], b[1])
+	r1 = addMul64(r1, a[1], b[0])
+	r1 = addMul64(r1, a2x19, b[4])
+	r1 = addMul64(r1, a3x19, b[3])
+	r1 = addMul64(r1, a4x19, b[2])
+
+	// r2 = a0×b2 + a1×b1 + a2×b0 + 19×(a3×b4 + a4×b3)
+	r2 := mul64(a[0], b[2])
+	r2 = addMul64(r2, a[1], b[1])
+	r2 = addMul64(r2, a[2], b[0])
+	r2 = addMul64(r2, a3x19, b[4])
+	r2 = addMul64(r2, a4x19, b[3])
+
+	// r3 = a0×b3 + a1×b2 + a2×b1 + a3×b0 + 19×a4×b4
+	r3 := mul64(a[0], b[3])
+	r3 = addMul64(r3, a[1], b[2])
+	r3 = addMul64(r3, a[2], b[1])
+	r3 = addMul64(r3, a[3], b[0])
+	r3 = addMul64(r3, a4x19, b[4])
+
+	// r4 = a0×b4 + a1×b3 + a2×b2 + a3×b1 + a4×b0
+	r4 := mul64(a[0], b[4])
+	r4 = addMul64(r4, a[1], b[3])
+	r4 = addMul64(r4, a[2], b[2])
+	r4 = addMul64(r4, a[3], b[1])
+	r4 = addMul64(r4, a[4], b[0])
+
+	c0 := shiftRightBy51(r0)
+	c1 := shiftRightBy51(r1)
+	c2 := shiftRightBy51(r2)
+	c3 := shiftRightBy51(r3)
+	c4 := shiftRightBy51(r4)
+
+	return fieldElement{
+		r0.lo&maskLow51Bits + c4*19,
+		r1.lo&maskLow51Bits + c0,
+		r2.lo&maskLow51Bits + c1,
+		r3.lo&maskLow51Bits + c2,
+		r4.lo&maskLow51Bits + c3,
+	}.carryPropagate()
+}
+
+// feSquare returns a * a.
+func feSquare(a fieldElement) fieldElement {
+	return feMul(a, a)
+}
+
+// feInvert returns 1 / z which is z^(P-2) (mod P) by Fermat's little theorem
+// (and 0 if z is 0). The exponent is public which is why branching on its bits
+// doesn't leak anything about z.
+func feInvert(z fieldElement) fieldElement {
+	// P - 2 = 2^255 - 21 has all bits from 254 down to 0 set except for bits
+	// 4 and 2 (21 = 0b10101).
+	result := fieldOne
+	for i := 254; i >= 0; i-- {
+		result = feSquare(result)
+		if i != 4 && i != 2 {
+			result = feMul(result, z)
+		}
+	}
+
+	return result
+}
+
+// feCSwap swaps a and b if swap is 1 and leaves them unchanged if it's 0
+// without branching on swap.
+func feCSwap(swap uint64, a, b *fieldElement) {
+	mask := -swap
+	for i := range a {
+		t := mask & (a[i] ^ b[i])
+		a[i] ^= t
+		b[i] ^= t
+	}
+}
@@ -0,0 +1,133 @@
+// Package x25519 implements the X25519 Diffie-Hellman function as specified in
+// https://datatracker.ietf.org/doc/html/rfc7748.
+package x25519
+
+import (
+	"math/big"
+	"slices"
+
+	"github.com/pmuens/ctk-go/ctk/random"
+)
+
+const (
+	// ErrLowOrderPoint is returned if the computed shared secret is all zeros
+	// which happens if the peer's public key is a low order point.
+	ErrLowOrderPoint = Error("low order point")
+
+	// ErrInvalidEd25519PublicKey is returned if an Ed25519 public key isn't the
+	// encoding of a point on the Edwards curve or is the identity point.
+	ErrInvalidEd25519PublicKey = Error("invalid Ed25519 public key")
+)
+
+// KeySize is the size (in bytes) of private keys, public keys and shared secrets.
+const KeySize = 32
+
+// P is the prime 2^255-19.
+var P, _ = new(big.Int).SetString("7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffed", 16)
+
+// Basepoint is the u-coordinate of the base point of Curve25519.
+var Basepoint = [32]byte{9}
+
+// GenerateKey generates a new private key and its corresponding public key
+// using randomness from crypto/rand (see random.Read for the health checks).
+func GenerateKey() ([32]byte, [32]byte, error) {
+	var private [32]byte
+
+	err := random.Read(private[:])
+	if err != nil {
+		return [32]byte{}, [32]byte{}, err
+	}
+
+	return private, PublicKey(private), nil
+}
+
+// PublicKey computes the public key that corresponds to the private key.
+func PublicKey(private [32]byte) [32]byte {
+	return ScalarMult(private, Basepoint)
+}
+
+// SharedSecret computes the shared secret between the private key and the
+// peer's public key.
+// Returns an error if the resulting shared secret is all zeros.
+func SharedSecret(private [32]byte, peerPublic [32]byte) ([32]byte, error) {
+	result := ScalarMult(private, peerPublic)
+
+	if result == [32]byte{} {
+		return [32]byte{}, ErrLowOrderPoint
+	}
+
+	return result, nil
+}
+
+// ScalarMult multiplies the point with the u-coordinate u by the scalar using
+// the Montgomery ladder. The ladder runs in constant time, i.e. it doesn't
+// branch on or index memory with the bits of the scalar.
+func ScalarMult(scalar [32]byte, u [32]byte) [32]byte {
+	k := decodeScalar(scalar)
+	x1 := feFromBytes(u)
+
+	x2 := fieldOne
+	z2 := fieldElement{}
+	x3 := x1
+	z3 := fieldOne
+
+	swap := uint64(0)
+
+	for t := 254; t >= 0; t-- {
+		kt := uint64(k[t/8]>>(t%8)) & 1
+
+		swap ^= kt
+		feCSwap(swap, &x2, &x3)
+		feCSwap(swap, &z2, &z3)
+		swap = kt
+
+		a := feAdd(x2, z2)
+		aa := feSquare(a)
+		b := feSub(x2, z2)
+		bb := feSquare(b)
+		e := feSub(aa, bb)
+		c := feAdd(x3, z3)
+		d := feSub(x3, z3)
+		da := feMul(d, a)
+		cb := feMul(c, b)
+
+		x3 = feSquare(feAdd(da, cb))
+		z3 = feMul(x1, feSquare(feSub(da, cb)))
+		x2 = feMul(aa, bb)
+		z2 = feMul(e, feAdd(aa, feMul(fieldA24, e)))
+	}
+
+	feCSwap(swap, &x2, &x3)
+	feCSwap(swap, &z2, &z3)
+
+	// Compute x2 * z2^(p - 2) which is x2 / z2 (mod p).
+	return feMul(x2, feInvert(z2)).bytes()
+}
+
+// mod reduces x modulo P.
+func mod(x *big.Int) *big.Int {
+	return x.Mod(x, P)
+}
+
+// decodeScalar clamps the scalar according to the specification.
+func decodeScalar(scalar [32]byte) [32]byte {
+	scalar[0] &= 248
+	scalar[31] &= 127
+	scalar[31] |= 64
+
+	return scalar
+}
+
+// encodeUCoordinate turns the u-coordinate into its 32 byte little endian
+// representation.
+func encodeUCoordinate(u *big.Int) [32]byte {
+	var result [32]byte
+
+	// FillBytes pads the big endian representation with leading zeros.
+	u.FillBytes(result[:])
+
+	// Reverse to turn the big endian order into little endian order.
+	slices.Reverse(result[:])
+
+	return result
+}
@@ -0,0 +1,47 @@
+package xchacha20_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/blake2b"
+	"github.com/pmuens/ctk-go/ctk/internal/edgecase"
+	"github.com/pmuens/ctk-go/ctk/xchacha20"
+)
+
+func TestXChaCha20EdgeCases(t *testing.T) {
+	t.Parallel()
+
+	key := edgecase.Key()
+	nonce := [24]byte(edgecase.Nonce(24))
+	counter := [4]byte{0x01}
+
+	// The digest of all ciphertexts was cross-checked with
+	// golang.org/x/crypto/chacha20.
+	digest := blake2b.New256()
+
+	for _, length := range edgecase.Lengths {
+		data := edgecase.Data(length)
+
+		ciphertext := xchacha20.NewXChaCha20(key, nonce, counter).XORWithKeyStream(data)
+		plaintext := xchacha20.NewXChaCha20(key, nonce, counter).XORWithKeyStream(ciphertext)
+
+		if !slices.Equal(plaintext, data) {
+			t.Errorf("%v bytes: want %v, got %v", length, data, plaintext)
+		}
+
+		digest.Write(ciphertext)
+	}
+
+	got := [32]byte(digest.Sum(nil))
+	want := [32]byte{
+		0x7a, 0x1f, 0xbe, 0x53, 0x09, 0x58, 0xbd, 0x67,
+		0xc4, 0xf3, 0x5a, 0x79, 0x79, 0x8d, 0x70, 0xa9,
+		0xf5, 0x97, 0x8e, 0x02, 0x1c, 0x0d, 0x38, 0xd1,
+		0xc0, 0x90, 0x54, 0xc2, 0x2a, 0x1a, 0xa1, 0x01,
+	}
+
+	if got != want {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
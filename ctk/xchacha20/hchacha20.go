@@ -1,32 +1,51 @@
 package xchacha20
 
-import "github.com/pmuens/ctk-go/ctk/chacha20"
+import (
+	"encoding/binary"
+
+	"github.com/pmuens/ctk-go/ctk/chacha20"
+	"github.com/pmuens/ctk-go/ctk/internal/leutil"
+)
 
 // HChaCha20 is a stateful instance of HChaCha20.
+// An instance isn't safe for concurrent use.
 type HChaCha20 struct {
 	// chacha20 is an instance of the ChaCha20 stream cipher.
 	chacha20 *chacha20.ChaCha20
 }
 
 // NewHChaCha20 creates a new instance of HChaCha20.
-func NewHChaCha20(key [32]byte, nonce [16]byte) *HChaCha20 {
-	// Given that ChaCha20 uses a counter, but HChaCha20 doesn't and instead stores
-	// a part of the nonce where the counter would be stored, we need to slice
-	// the nonce to derive the counter value that's expected by ChaCha20.
-	counter := [4]byte(nonce[0:4])
-	slicedNonce := [12]byte(nonce[4:16])
-
-	chacha20 := chacha20.NewChaCha20(key, slicedNonce, counter)
+// The options are passed on to the underlying ChaCha20 instance.
+func NewHChaCha20(key [32]byte, nonce [16]byte, opts ...chacha20.Option) *HChaCha20 {
+	counter, slicedNonce := splitNonce(nonce)
+	chacha20 := chacha20.NewChaCha20WithCounter(key, slicedNonce, counter, opts...)
 
 	return &HChaCha20{
 		chacha20: chacha20,
 	}
 }
 
+// reset reinitializes the instance with the key and nonce so that it can be
+// reused without allocating a new ChaCha20 instance.
+func (h *HChaCha20) reset(key [32]byte, nonce [16]byte) {
+	counter, slicedNonce := splitNonce(nonce)
+	h.chacha20.ResetWithCounter(key, slicedNonce, counter)
+}
+
+// splitNonce splits the nonce into the counter and the nonce that are expected
+// by ChaCha20.
+func splitNonce(nonce [16]byte) (uint32, [12]byte) {
+	// Given that ChaCha20 uses a counter, but HChaCha20 doesn't and instead stores
+	// a part of the nonce where the counter would be stored, we need to slice
+	// the nonce to derive the counter value that's expected by ChaCha20.
+	return binary.LittleEndian.Uint32(nonce[0:4]), [12]byte(nonce[4:16])
+}
+
 // GenerateSubKey generates a key usable by ChaCha20.
 func (h *HChaCha20) GenerateSubKey() [32]byte {
-	// Mix the state by running 20 rounds using regular ChaCha20.
-	state := h.chacha20.TwentyRounds()
+	// Mix the state by running 20 (or the configured number of) rounds using
+	// regular ChaCha20.
+	state := h.chacha20.Permute()
 
 	// Take the first and last row of the mixed state.
 	firstRow := state[0:4]
@@ -35,27 +54,9 @@ func (h *HChaCha20) GenerateSubKey() [32]byte {
 	// The key is the bytes (little endian order) of the first- and last row.
 	var key [32]byte
 
-	// Turn words in first row into bytes with little endian order.
-	for i, word := range firstRow {
-		index := (i * 4)
-
-		// Extract the individual bytes from the word.
-		key[index] = byte(word)
-		key[index+1] = byte(word >> 8)
-		key[index+2] = byte(word >> 16)
-		key[index+3] = byte(word >> 24)
-	}
-
-	// Turn words in last row into bytes with little endian order.
-	for i, word := range lastRow {
-		index := ((i * 4) + 16)
-
-		// Extract the individual bytes from the word.
-		key[index] = byte(word)
-		key[index+1] = byte(word >> 8)
-		key[index+2] = byte(word >> 16)
-		key[index+3] = byte(word >> 24)
-	}
+	// Turn words in first and last row into bytes with little endian order.
+	leutil.PutWords(key[0:16], firstRow)
+	leutil.PutWords(key[16:32], lastRow)
 
 	return key
 }
@@ -2,33 +2,90 @@
 // https://datatracker.ietf.org/doc/html/draft-irtf-cfrg-xchacha-03.
 package xchacha20
 
-import "github.com/pmuens/ctk-go/ctk/chacha20"
+import (
+	"encoding/binary"
+
+	"github.com/pmuens/ctk-go/ctk/chacha20"
+)
 
 // XChaCha20 is a stateful instance of XChaCha20.
+// An instance isn't safe for concurrent use.
 type XChaCha20 struct {
 	// chacha20 is an instance of the ChaCha20 stream cipher.
 	chacha20 *chacha20.ChaCha20
+
+	// hchacha20 is the instance of HChaCha20 that derives the subkeys (which
+	// is kept so that resets don't allocate).
+	hchacha20 *HChaCha20
 }
 
-// NewXChaCha20 creates a new instance of XChaCha20.
-func NewXChaCha20(key [32]byte, nonce [24]byte, counter [4]byte) *XChaCha20 {
-	// The nonce for HChaCha20 consists of the first 16 bytes of the 24 byte nonce.
-	hChaChaNonce := [16]byte(nonce[0:16])
-	hCha := NewHChaCha20(key, hChaChaNonce)
+// NewXChaCha20 creates a new instance of XChaCha20 with the counter encoded as
+// 4 little endian bytes.
+// The options are passed on to the underlying HChaCha20 and ChaCha20 instances.
+//
+// Deprecated: Use NewXChaCha20WithCounter which takes the counter as a uint32.
+func NewXChaCha20(key [32]byte, nonce [24]byte, counter [4]byte, opts ...chacha20.Option) *XChaCha20 {
+	return NewXChaCha20WithCounter(key, nonce, binary.LittleEndian.Uint32(counter[:]), opts...)
+}
 
-	// Generate a subKey via HChaCha20 which will be the key used for ChaCha20.
+// NewXChaCha20WithCounter creates a new instance of XChaCha20 whose first
+// keystream block has the block counter counter.
+// The options are passed on to the underlying HChaCha20 and ChaCha20 instances.
+func NewXChaCha20WithCounter(key [32]byte, nonce [24]byte, counter uint32, opts ...chacha20.Option) *XChaCha20 {
+	hCha := NewHChaCha20(key, [16]byte(nonce[0:16]), opts...)
 	subKey := hCha.GenerateSubKey()
-
-	// The nonce for ChaCha20 consists of the last 8 bytes of the 24 byte nonce
-	// prefixed with 4 zero bytes (as RFC 8439 specifies a 12 byte ChaCha20 nonce).
-	chaChaNonce := [12]byte(append([]byte{0x00, 0x00, 0x00, 0x00}, nonce[16:24]...))
-	chacha20 := chacha20.NewChaCha20(subKey, chaChaNonce, counter)
+	chacha20 := chacha20.NewChaCha20WithCounter(subKey, chaChaNonce(nonce), counter, opts...)
 
 	return &XChaCha20{
-		chacha20: chacha20,
+		chacha20:  chacha20,
+		hchacha20: hCha,
 	}
 }
 
+// DeriveSubkeyNonce returns the key and the nonce XChaCha20 runs ChaCha20 with
+// so that protocols can run ChaCha20 (or ChaCha20-Poly1305) with the derived
+// material directly. The subkey is derived from the key and the first 16 bytes
+// of the 24 byte nonce via HChaCha20. The ChaCha20 nonce consists of the last 8
+// bytes of the 24 byte nonce prefixed with 4 zero bytes (as RFC 8439 specifies a
+// 12 byte ChaCha20 nonce), i.e. protocols using a 8 byte nonce take the last 8
+// bytes.
+// The options are passed on to the underlying HChaCha20 instance.
+func DeriveSubkeyNonce(key [32]byte, nonce [24]byte, opts ...chacha20.Option) ([32]byte, [12]byte) {
+	hCha := NewHChaCha20(key, [16]byte(nonce[0:16]), opts...)
+	subKey := hCha.GenerateSubKey()
+
+	return subKey, chaChaNonce(nonce)
+}
+
+// chaChaNonce returns the ChaCha20 nonce for the 24 byte nonce (see
+// DeriveSubkeyNonce).
+func chaChaNonce(nonce [24]byte) [12]byte {
+	var result [12]byte
+	copy(result[4:], nonce[16:24])
+
+	return result
+}
+
+// Reset reinitializes the instance with the key, nonce and counter (encoded as
+// 4 little endian bytes) so that it can be reused without allocating a new
+// ChaCha20 instance.
+// The options (e.g. the number of rounds) are kept.
+//
+// Deprecated: Use ResetWithCounter which takes the counter as a uint32.
+func (x *XChaCha20) Reset(key [32]byte, nonce [24]byte, counter [4]byte) {
+	x.ResetWithCounter(key, nonce, binary.LittleEndian.Uint32(counter[:]))
+}
+
+// ResetWithCounter reinitializes the instance with the key, nonce and counter
+// so that it can be reused without allocating a new ChaCha20 instance.
+// The options (e.g. the number of rounds) are kept.
+func (x *XChaCha20) ResetWithCounter(key [32]byte, nonce [24]byte, counter uint32) {
+	x.hchacha20.reset(key, [16]byte(nonce[0:16]))
+	subKey := x.hchacha20.GenerateSubKey()
+
+	x.chacha20.ResetWithCounter(subKey, chaChaNonce(nonce), counter)
+}
+
 // XORWithKeyStream creates a key stream using the ChaCha20 block function
 // and XOR's the data with such key stream to create the return value.
 // This function is used for both, encryption and decryption.
@@ -37,9 +94,44 @@ func (x *XChaCha20) XORWithKeyStream(data []byte) []byte {
 	return x.chacha20.XORWithKeyStream(data)
 }
 
+// XORKeyStream XORs src with the key stream and writes the result to dst
+// without allocating (see chacha20.ChaCha20.XORKeyStream).
+func (x *XChaCha20) XORKeyStream(dst []byte, src []byte) {
+	x.chacha20.XORKeyStream(dst, src)
+}
+
 // CreateBlock produces a 512 bit XChaCha20 block by permuting the state via 10
 // double rounds (10 * 2 = 20 rounds in total).
 func (x *XChaCha20) CreateBlock() [16]uint32 {
 	// Reuse the ChaCha20 CreateBlock function.
 	return x.chacha20.CreateBlock()
 }
+
+// KeystreamReader returns a reader of the keystream which starts at the
+// instance's current counter (see chacha20.ChaCha20.KeystreamReader).
+func (x *XChaCha20) KeystreamReader() *chacha20.KeystreamReader {
+	return x.chacha20.KeystreamReader()
+}
+
+// Counter returns the block counter of the next keystream block.
+func (x *XChaCha20) Counter() uint32 {
+	return x.chacha20.Counter()
+}
+
+// SetCounter sets the block counter of the next keystream block (see
+// chacha20.ChaCha20.SetCounter).
+func (x *XChaCha20) SetCounter(counter uint32) {
+	x.chacha20.SetCounter(counter)
+}
+
+// Seek moves the instance to the keystream byte offset which needs to be a
+// multiple of chacha20.BlockSize (see chacha20.ChaCha20.Seek).
+func (x *XChaCha20) Seek(offset uint64) error {
+	return x.chacha20.Seek(offset)
+}
+
+// RemainingBlocks returns the number of keystream blocks that are left before
+// the counter would wrap around and repeat the keystream.
+func (x *XChaCha20) RemainingBlocks() uint64 {
+	return x.chacha20.RemainingBlocks()
+}
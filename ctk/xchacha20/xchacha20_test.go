@@ -1,9 +1,12 @@
 package xchacha20_test
 
 import (
+	"errors"
+	"io"
 	"slices"
 	"testing"
 
+	"github.com/pmuens/ctk-go/ctk/chacha20"
 	"github.com/pmuens/ctk-go/ctk/xchacha20"
 )
 
@@ -155,3 +158,183 @@ func TestXChaCha20XORWithKeyStream(t *testing.T) {
 		}
 	})
 }
+
+func TestXChaCha20WithCounter(t *testing.T) {
+	key := [32]byte{0x01}
+	nonce := [24]byte{0x02}
+	data := make([]byte, 100)
+
+	tt := map[string]struct {
+		counter [4]byte
+		initial uint32
+	}{
+		"Zero":      {[4]byte{0x00, 0x00, 0x00, 0x00}, 0},
+		"Multibyte": {[4]byte{0x04, 0x03, 0x02, 0x01}, 0x01020304},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			want := xchacha20.NewXChaCha20(key, nonce, tc.counter).XORWithKeyStream(data)
+
+			got := xchacha20.NewXChaCha20WithCounter(key, nonce, tc.initial).XORWithKeyStream(data)
+			if !slices.Equal(got, want) {
+				t.Errorf("want %v, got %v", want, got)
+			}
+
+			x := xchacha20.NewXChaCha20WithCounter([32]byte{}, [24]byte{}, 0)
+			x.ResetWithCounter(key, nonce, tc.initial)
+
+			got = x.XORWithKeyStream(data)
+			if !slices.Equal(got, want) {
+				t.Errorf("want %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestXChaCha20KeystreamReader(t *testing.T) {
+	t.Parallel()
+
+	key := [32]byte{0x01}
+	nonce := [24]byte{0x02}
+	counter := [4]byte{0x01}
+
+	want := xchacha20.NewXChaCha20(key, nonce, counter).XORWithKeyStream(make([]byte, 100))
+
+	got := make([]byte, 100)
+	io.ReadFull(xchacha20.NewXChaCha20(key, nonce, counter).KeystreamReader(), got)
+
+	if !slices.Equal(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestDeriveSubkeyNonce(t *testing.T) {
+	key := [32]byte{0x01}
+	nonce := [24]byte{
+		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07,
+		0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+		0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17,
+	}
+
+	t.Run("Material", func(t *testing.T) {
+		t.Parallel()
+
+		subKey, chaChaNonce := xchacha20.DeriveSubkeyNonce(key, nonce)
+
+		want := xchacha20.NewHChaCha20(key, [16]byte(nonce[0:16])).GenerateSubKey()
+		if subKey != want {
+			t.Errorf("want %v, got %v", want, subKey)
+		}
+
+		wantNonce := [12]byte{0x00, 0x00, 0x00, 0x00, 0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17}
+		if chaChaNonce != wantNonce {
+			t.Errorf("want %v, got %v", wantNonce, chaChaNonce)
+		}
+	})
+
+	t.Run("ChaCha20", func(t *testing.T) {
+		t.Parallel()
+
+		data := make([]byte, 100)
+		want := xchacha20.NewXChaCha20WithCounter(key, nonce, 1).XORWithKeyStream(data)
+
+		subKey, chaChaNonce := xchacha20.DeriveSubkeyNonce(key, nonce)
+		got := chacha20.NewChaCha20WithCounter(subKey, chaChaNonce, 1).XORWithKeyStream(data)
+
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+}
+
+func TestXChaCha20Seek(t *testing.T) {
+	key := [32]byte{0x01}
+	nonce := [24]byte{0x02}
+	data := make([]byte, 4*chacha20.BlockSize)
+
+	want := xchacha20.NewXChaCha20WithCounter(key, nonce, 0).XORWithKeyStream(data)
+
+	t.Run("Random Access", func(t *testing.T) {
+		t.Parallel()
+
+		x := xchacha20.NewXChaCha20WithCounter(key, nonce, 0)
+
+		// Read the third block first and the second block afterwards.
+		for _, block := range []int{2, 1} {
+			offset := block * chacha20.BlockSize
+
+			err := x.Seek(uint64(offset))
+			if err != nil {
+				t.Fatalf("want error %v, got %v", nil, err)
+			}
+
+			got := x.XORWithKeyStream(data[:chacha20.BlockSize])
+			if !slices.Equal(got, want[offset:offset+chacha20.BlockSize]) {
+				t.Errorf("want %v, got %v", want[offset:offset+chacha20.BlockSize], got)
+			}
+
+			if x.Counter() != uint32(block+1) {
+				t.Errorf("want %v, got %v", block+1, x.Counter())
+			}
+		}
+	})
+
+	t.Run("SetCounter", func(t *testing.T) {
+		t.Parallel()
+
+		x := xchacha20.NewXChaCha20WithCounter(key, nonce, 0)
+		x.SetCounter(3)
+
+		got := x.XORWithKeyStream(data[:chacha20.BlockSize])
+		if !slices.Equal(got, want[3*chacha20.BlockSize:]) {
+			t.Errorf("want %v, got %v", want[3*chacha20.BlockSize:], got)
+		}
+	})
+
+	t.Run("Invalid Offsets", func(t *testing.T) {
+		t.Parallel()
+
+		tt := map[string]struct {
+			offset uint64
+			err    error
+		}{
+			"Unaligned":   {chacha20.BlockSize + 1, chacha20.ErrUnalignedOffset},
+			"End":         {chacha20.MaxKeystreamSize, chacha20.ErrOffsetOutOfRange},
+			"Beyond End":  {2 * chacha20.MaxKeystreamSize, chacha20.ErrOffsetOutOfRange},
+			"Last Block":  {chacha20.MaxKeystreamSize - chacha20.BlockSize, nil},
+			"First Block": {0, nil},
+		}
+
+		for name, tc := range tt {
+			x := xchacha20.NewXChaCha20WithCounter(key, nonce, 0)
+
+			err := x.Seek(tc.offset)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("%v: want error %v, got %v", name, tc.err, err)
+			}
+		}
+	})
+
+	t.Run("Remaining Blocks", func(t *testing.T) {
+		t.Parallel()
+
+		x := xchacha20.NewXChaCha20WithCounter(key, nonce, 0xffffffff)
+		if x.RemainingBlocks() != 1 {
+			t.Errorf("want %v, got %v", 1, x.RemainingBlocks())
+		}
+
+		x.XORWithKeyStream(data[:1])
+		if x.RemainingBlocks() != 0 {
+			t.Errorf("want %v, got %v", 0, x.RemainingBlocks())
+		}
+
+		// Seeking recovers the exhausted instance.
+		x.Seek(0)
+		if x.RemainingBlocks() != chacha20.MaxBlocks {
+			t.Errorf("want %v, got %v", uint64(chacha20.MaxBlocks), x.RemainingBlocks())
+		}
+	})
+}
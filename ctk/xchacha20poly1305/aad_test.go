@@ -0,0 +1,73 @@
+package xchacha20poly1305_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
+)
+
+func TestXChaCha20Poly1305AddAAD(t *testing.T) {
+	key := [32]byte{0x01}
+	nonce := [24]byte{0x02}
+	plaintext := []byte("Hello World")
+	aad := []byte("version 1 | header with 23 bytes | record 42")
+
+	want := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce).Seal(nil, plaintext, aad)
+
+	tt := map[string]struct {
+		segments [][]byte
+		aad      []byte
+	}{
+		"Segments Only":        {[][]byte{aad[:9], aad[9:34], aad[34:]}, []byte{}},
+		"Segments And AAD":     {[][]byte{aad[:9], aad[9:34]}, aad[34:]},
+		"Empty Segments":       {[][]byte{{}, aad[:16], {}, aad[16:]}, nil},
+		"Single Byte Segments": {chunks(aad, 1), []byte{}},
+	}
+
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			sealer := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce)
+			sealer.AddAAD(tc.segments...)
+
+			got := sealer.Seal(nil, plaintext, tc.aad)
+			if !slices.Equal(got, want) {
+				t.Errorf("want %v, got %v", want, got)
+			}
+
+			opener := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce)
+			opener.AddAAD(tc.segments[0])
+			opener.AddAAD(tc.segments[1:]...)
+
+			opened, err := opener.Open(nil, want, tc.aad)
+			if err != nil || !slices.Equal(opened, plaintext) {
+				t.Errorf("want %v, got %v (error %v)", plaintext, opened, err)
+			}
+		})
+	}
+
+	t.Run("Reordered Segments", func(t *testing.T) {
+		t.Parallel()
+
+		opener := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce)
+		opener.AddAAD(aad[9:], aad[:9])
+
+		_, err := opener.Open(nil, want, []byte{})
+		if !errors.Is(err, xchacha20poly1305.ErrInvalidTag) {
+			t.Errorf("want error %v, got %v", xchacha20poly1305.ErrInvalidTag, err)
+		}
+	})
+}
+
+// chunks splits data into chunks of the size.
+func chunks(data []byte, size int) [][]byte {
+	var result [][]byte
+	for chunk := range slices.Chunk(data, size) {
+		result = append(result, chunk)
+	}
+
+	return result
+}
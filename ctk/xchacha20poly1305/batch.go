@@ -0,0 +1,62 @@
+package xchacha20poly1305
+
+import (
+	"github.com/pmuens/ctk-go/ctk/chacha20poly1305"
+	"github.com/pmuens/ctk-go/ctk/internal/parallel"
+	"github.com/pmuens/ctk-go/ctk/poly1305"
+)
+
+// BatchOption configures a batch operation (see chacha20poly1305.BatchOption).
+type BatchOption = chacha20poly1305.BatchOption
+
+// WithConcurrency processes the records of a batch with n goroutines (see
+// chacha20poly1305.WithConcurrency).
+func WithConcurrency(n int) BatchOption {
+	return chacha20poly1305.WithConcurrency(n)
+}
+
+// Record is a sealed message (the ciphertext with the appended tag, see Seal)
+// of a batch.
+type Record struct {
+	// Nonce is the nonce the message was sealed with.
+	Nonce [24]byte
+
+	// Sealed is the ciphertext followed by the tag.
+	Sealed []byte
+
+	// AAD is the additional authenticated data.
+	AAD []byte
+}
+
+// OpenBatch opens all records (see chacha20poly1305.Pool.OpenBatch) and returns
+// the plaintexts and the errors (nil for every record that was opened). The
+// plaintext of a record that can't be opened is nil.
+func (p *Pool) OpenBatch(records []Record, opts ...BatchOption) ([][]byte, []error) {
+	plaintexts := make([][]byte, len(records))
+	errs := make([]error, len(records))
+
+	// The plaintext of record i is written to buf[offsets[i]:offsets[i+1]].
+	offsets := make([]int, len(records)+1)
+	for i, r := range records {
+		offsets[i+1] = offsets[i] + max(len(r.Sealed)-p.tagSize, 0)
+	}
+	buf := make([]byte, offsets[len(records)])
+
+	o := poly1305.NewBatchOptions(opts...)
+	parallel.Range(len(records), parallel.Workers(o.Concurrency), func(start int, end int) {
+		x := p.pool.Get().(*XChaCha20Poly1305)
+		defer p.pool.Put(x)
+
+		for i := start; i < end; i++ {
+			x.reset(p.key, records[i].Nonce)
+
+			dst := buf[offsets[i]:offsets[i]:offsets[i+1]]
+			plaintexts[i], errs[i] = x.Open(dst, records[i].Sealed, records[i].AAD)
+			if errs[i] != nil {
+				plaintexts[i] = nil
+			}
+		}
+	})
+
+	return plaintexts, errs
+}
@@ -0,0 +1,66 @@
+package xchacha20poly1305_test
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
+)
+
+func TestPoolOpenBatch(t *testing.T) {
+	key := [32]byte{0x01}
+	pool := xchacha20poly1305.NewPool(key)
+
+	records := make([]xchacha20poly1305.Record, 50)
+	plaintexts := make([][]byte, len(records))
+	for i := range records {
+		nonce := [24]byte{byte(i)}
+		aad := []byte(fmt.Sprintf("record %d", i))
+		plaintexts[i] = make([]byte, i)
+
+		records[i] = xchacha20poly1305.Record{
+			Nonce:  nonce,
+			Sealed: pool.Seal(nil, nonce[:], plaintexts[i], aad),
+			AAD:    aad,
+		}
+	}
+
+	// Tamper with every 5th record and truncate the last one.
+	for i := 0; i < len(records); i += 5 {
+		records[i].Sealed = slices.Clone(records[i].Sealed)
+		records[i].Sealed[0] ^= 0x01
+	}
+	records[len(records)-1].Sealed = records[len(records)-1].Sealed[:3]
+
+	tt := map[string][]xchacha20poly1305.BatchOption{
+		"Sequential": {},
+		"Concurrent": {xchacha20poly1305.WithConcurrency(3)},
+	}
+
+	for name, opts := range tt {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, errs := pool.OpenBatch(records, opts...)
+
+			for i := range records {
+				if i%5 == 0 || i == len(records)-1 {
+					if !errors.Is(errs[i], xchacha20poly1305.ErrInvalidTag) || got[i] != nil {
+						t.Errorf("%v: want error %v, got %v", i, xchacha20poly1305.ErrInvalidTag, errs[i])
+					}
+					continue
+				}
+
+				if errs[i] != nil {
+					t.Fatalf("%v: want error %v, got %v", i, nil, errs[i])
+				}
+
+				if !slices.Equal(got[i], plaintexts[i]) {
+					t.Errorf("%v: want %v, got %v", i, plaintexts[i], got[i])
+				}
+			}
+		})
+	}
+}
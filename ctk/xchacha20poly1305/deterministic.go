@@ -0,0 +1,85 @@
+package xchacha20poly1305
+
+import (
+	"encoding/binary"
+
+	"github.com/pmuens/ctk-go/ctk/blake2b"
+	"github.com/pmuens/ctk-go/ctk/subtle"
+)
+
+const (
+	// ErrInvalidSealedMessage is returned if a deterministically sealed message
+	// is malformed or can't be authenticated.
+	ErrInvalidSealedMessage = Error("invalid sealed message")
+)
+
+// deterministicNonceInfo is used to derive the key that's used to derive nonces
+// so that the encryption key isn't used for two different primitives.
+var deterministicNonceInfo = []byte("ctk-go xchacha20poly1305 deterministic nonce")
+
+// DeterministicSeal encrypts the plaintext with a nonce that's derived from the
+// key, the plaintext and the additional authenticated data (AAD) via keyed
+// BLAKE2b and returns the nonce followed by the ciphertext and the tag.
+//
+// WARNING: Sealing the same plaintext and AAD under the same key always results
+// in the same output which reveals whether two messages are equal. Only use this
+// if that's intended (e.g. to deduplicate encrypted storage or to cache
+// encryption results). Use random nonces otherwise.
+func DeterministicSeal(key [32]byte, plaintext []byte, aad []byte) []byte {
+	nonce := deterministicNonce(key, plaintext, aad)
+
+	xchaPoly := NewXChaCha20Poly1305(key, nonce)
+	ciphertext, tag := xchaPoly.Encrypt(plaintext, aad)
+
+	result := make([]byte, 0, len(nonce)+len(ciphertext)+len(tag))
+	result = append(result, nonce[:]...)
+	result = append(result, ciphertext...)
+	result = append(result, tag[:]...)
+
+	return result
+}
+
+// DeterministicOpen decrypts a message that was sealed via DeterministicSeal.
+// Besides the tag it also checks that the nonce was derived from the plaintext.
+// Returns an error if the message is malformed or can't be authenticated.
+func DeterministicOpen(key [32]byte, sealed []byte, aad []byte) ([]byte, error) {
+	if len(sealed) < 24+16 {
+		return []byte{}, ErrInvalidSealedMessage
+	}
+
+	nonce := [24]byte(sealed[:24])
+	ciphertext := sealed[24 : len(sealed)-16]
+	tag := [16]byte(sealed[len(sealed)-16:])
+
+	xchaPoly := NewXChaCha20Poly1305(key, nonce)
+	plaintext, err := xchaPoly.Decrypt(ciphertext, aad, tag)
+	if err != nil {
+		return []byte{}, ErrInvalidSealedMessage
+	}
+
+	expected := deterministicNonce(key, plaintext, aad)
+	if subtle.ConstantTimeCompare(nonce[:], expected[:]) != 1 {
+		clear(plaintext)
+		return []byte{}, ErrInvalidSealedMessage
+	}
+
+	return plaintext, nil
+}
+
+// deterministicNonce derives the nonce via BLAKE2b keyed with a key that's
+// derived from the encryption key. The AAD is length prefixed so that the
+// boundary between it and the plaintext is unambiguous.
+func deterministicNonce(key [32]byte, plaintext []byte, aad []byte) [24]byte {
+	// The sizes are valid so that no errors can occur.
+	kdf, _ := blake2b.NewBlake2b(blake2b.Size256, key[:])
+	kdf.Write(deterministicNonceInfo)
+	nonceKey := kdf.Sum(nil)
+	defer clear(nonceKey)
+
+	mac, _ := blake2b.NewBlake2b(24, nonceKey)
+	mac.Write(binary.LittleEndian.AppendUint64(nil, uint64(len(aad))))
+	mac.Write(aad)
+	mac.Write(plaintext)
+
+	return [24]byte(mac.Sum(nil))
+}
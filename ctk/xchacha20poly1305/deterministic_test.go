@@ -0,0 +1,74 @@
+package xchacha20poly1305_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
+)
+
+func TestDeterministicSeal(t *testing.T) {
+	key := [32]byte{0x01}
+	aad := []byte{0x02}
+	data := []byte("attack at dawn")
+
+	t.Run("Deterministic", func(t *testing.T) {
+		t.Parallel()
+
+		sealed1 := xchacha20poly1305.DeterministicSeal(key, data, aad)
+		sealed2 := xchacha20poly1305.DeterministicSeal(key, data, aad)
+
+		if !slices.Equal(sealed1, sealed2) {
+			t.Errorf("want %v, got %v", sealed1, sealed2)
+		}
+
+		tt := map[string][]byte{
+			"Other Plaintext": xchacha20poly1305.DeterministicSeal(key, []byte("attack at dusk"), aad),
+			"Other AAD":       xchacha20poly1305.DeterministicSeal(key, data, []byte{0x03}),
+			"Other Key":       xchacha20poly1305.DeterministicSeal([32]byte{0x04}, data, aad),
+		}
+
+		for name, sealed := range tt {
+			if slices.Equal(sealed[:24], sealed1[:24]) {
+				t.Errorf("%v: want different nonces, got %v", name, sealed[:24])
+			}
+		}
+	})
+
+	t.Run("Open", func(t *testing.T) {
+		t.Parallel()
+
+		sealed := xchacha20poly1305.DeterministicSeal(key, data, aad)
+
+		got, err := xchacha20poly1305.DeterministicOpen(key, sealed, aad)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		if !slices.Equal(got, data) {
+			t.Errorf("want %v, got %v", data, got)
+		}
+	})
+
+	t.Run("Random Nonce", func(t *testing.T) {
+		t.Parallel()
+
+		// A message that was encrypted with a nonce that wasn't derived from the
+		// plaintext is rejected.
+		nonce := [24]byte{0x05}
+
+		ciphertext, tag := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce).Encrypt(data, aad)
+
+		sealed := slices.Concat(nonce[:], ciphertext, tag[:])
+
+		_, err := xchacha20poly1305.DeterministicOpen(key, sealed, aad)
+
+		gotError := err
+		wantError := xchacha20poly1305.ErrInvalidSealedMessage
+
+		if !errors.Is(gotError, wantError) {
+			t.Errorf("want error %v, got %v", wantError, gotError)
+		}
+	})
+}
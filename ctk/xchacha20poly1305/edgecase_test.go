@@ -0,0 +1,62 @@
+package xchacha20poly1305_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/blake2b"
+	"github.com/pmuens/ctk-go/ctk/internal/edgecase"
+	"github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
+)
+
+func TestXChaCha20Poly1305EdgeCases(t *testing.T) {
+	t.Parallel()
+
+	key := edgecase.Key()
+	nonce := [24]byte(edgecase.Nonce(24))
+
+	// The digest of all ciphertexts and tags was cross-checked with
+	// golang.org/x/crypto/chacha20poly1305.
+	digest := blake2b.New256()
+
+	for _, tc := range edgecase.AEADCases() {
+		ciphertext, tag := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce).Encrypt(tc.Plaintext, tc.AAD)
+
+		if len(ciphertext) != len(tc.Plaintext) {
+			t.Errorf("%v: want length %v, got %v", tc.Name, len(tc.Plaintext), len(ciphertext))
+		}
+
+		plaintext, err := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce).Decrypt(ciphertext, tc.AAD, tag)
+		if err != nil {
+			t.Fatalf("%v: want error %v, got %v", tc.Name, nil, err)
+		}
+
+		if !slices.Equal(plaintext, tc.Plaintext) {
+			t.Errorf("%v: want %v, got %v", tc.Name, tc.Plaintext, plaintext)
+		}
+
+		tamperedTag := tag
+		tamperedTag[len(tamperedTag)-1] ^= 0x01
+
+		_, err = xchacha20poly1305.NewXChaCha20Poly1305(key, nonce).Decrypt(ciphertext, tc.AAD, tamperedTag)
+		if !errors.Is(err, xchacha20poly1305.ErrInvalidTag) {
+			t.Errorf("%v: want error %v, got %v", tc.Name, xchacha20poly1305.ErrInvalidTag, err)
+		}
+
+		digest.Write(ciphertext)
+		digest.Write(tag[:])
+	}
+
+	got := [32]byte(digest.Sum(nil))
+	want := [32]byte{
+		0xcc, 0x0f, 0x83, 0x89, 0x11, 0xe7, 0x63, 0xc6,
+		0x2d, 0x64, 0xdb, 0x69, 0xa7, 0xfc, 0xcf, 0xe2,
+		0xdd, 0x5e, 0x72, 0xb6, 0x9a, 0x79, 0x88, 0x77,
+		0x21, 0xf2, 0x3d, 0x4d, 0x5c, 0x22, 0x4a, 0xa3,
+	}
+
+	if got != want {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,54 @@
+//go:build libsodium
+
+package xchacha20poly1305_test
+
+import (
+	"crypto/rand"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/internal/libsodium"
+	"github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
+)
+
+func TestXChaCha20Poly1305LibsodiumLive(t *testing.T) {
+	generator, err := libsodium.NewGenerator()
+	if err != nil {
+		t.Skipf("libsodium isn't available: %v", err)
+	}
+	t.Cleanup(func() { generator.Close() })
+
+	for _, size := range []int{0, 1, 15, 16, 17, 63, 64, 65, 255, 256, 1024, 4096 + 3} {
+		for _, aadSize := range []int{0, 1, 16, 33} {
+			t.Run(fmt.Sprintf("%d byte plaintext, %d byte AAD", size, aadSize), func(t *testing.T) {
+				t.Parallel()
+
+				var key [32]byte
+				var nonce [24]byte
+				plaintext := make([]byte, size)
+				aad := make([]byte, aadSize)
+
+				rand.Read(key[:])
+				rand.Read(nonce[:])
+				rand.Read(plaintext)
+				rand.Read(aad)
+
+				wantCiphertext, wantTag, err := generator.XChaCha20Poly1305(key, nonce, plaintext, aad)
+				if err != nil {
+					t.Fatalf("want error %v, got %v", nil, err)
+				}
+
+				ciphertext, tag := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce).Encrypt(plaintext, aad)
+
+				if !slices.Equal(ciphertext, wantCiphertext) {
+					t.Errorf("want %v, got %v", wantCiphertext, ciphertext)
+				}
+
+				if tag != wantTag {
+					t.Errorf("want %v, got %v", wantTag, tag)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package xchacha20poly1305_test
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/internal/libsodium"
+	"github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
+)
+
+func TestXChaCha20Poly1305Libsodium(t *testing.T) {
+	vectors, err := libsodium.Load()
+	if err != nil {
+		t.Fatalf("want error %v, got %v", nil, err)
+	}
+
+	for i, v := range vectors.XChaCha20Poly1305 {
+		name := fmt.Sprintf("#%d (%d byte plaintext, %d byte AAD)", i, len(v.Plaintext), len(v.AAD))
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			key := [32]byte(v.Key)
+			nonce := [24]byte(v.Nonce)
+
+			ciphertext, tag := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce).Encrypt(v.Plaintext, v.AAD)
+
+			if !slices.Equal(ciphertext, v.Ciphertext) {
+				t.Errorf("want %v, got %v", []byte(v.Ciphertext), ciphertext)
+			}
+
+			if !slices.Equal(tag[:], v.Tag) {
+				t.Errorf("want %v, got %v", []byte(v.Tag), tag)
+			}
+
+			plaintext, err := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce).Decrypt(v.Ciphertext, v.AAD, [16]byte(v.Tag))
+			if err != nil {
+				t.Fatalf("want error %v, got %v", nil, err)
+			}
+
+			if !slices.Equal(plaintext, v.Plaintext) {
+				t.Errorf("want %v, got %v", []byte(v.Plaintext), plaintext)
+			}
+
+			tamperedTag := [16]byte(v.Tag)
+			tamperedTag[0] ^= 0x01
+
+			_, err = xchacha20poly1305.NewXChaCha20Poly1305(key, nonce).Decrypt(v.Ciphertext, v.AAD, tamperedTag)
+
+			gotError := err
+			wantError := xchacha20poly1305.ErrInvalidTag
+
+			if !errors.Is(gotError, wantError) {
+				t.Errorf("want error %v, got %v", wantError, gotError)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package xchacha20poly1305_test
+
+import (
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
+)
+
+func TestPool(t *testing.T) {
+	t.Run("Matches Instances", func(t *testing.T) {
+		t.Parallel()
+
+		key := [32]byte{0x01}
+		aad := []byte{0x02}
+		data := []byte("attack at dawn")
+
+		pool := xchacha20poly1305.NewPool(key)
+
+		for i := range 10 {
+			nonce := [24]byte{byte(i)}
+
+			gotCiphertext, gotTag := pool.Encrypt(nonce, data, aad)
+			wantCiphertext, wantTag := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce).Encrypt(data, aad)
+
+			if !slices.Equal(gotCiphertext, wantCiphertext) || gotTag != wantTag {
+				t.Errorf("want %v %v, got %v %v", wantCiphertext, wantTag, gotCiphertext, gotTag)
+			}
+
+			plaintext, err := pool.Decrypt(nonce, gotCiphertext, aad, gotTag)
+			if err != nil {
+				t.Fatalf("want error %v, got %v", nil, err)
+			}
+
+			if !slices.Equal(plaintext, data) {
+				t.Errorf("want %v, got %v", data, plaintext)
+			}
+		}
+	})
+
+	t.Run("Concurrent Use", func(t *testing.T) {
+		t.Parallel()
+
+		pool := xchacha20poly1305.NewPool([32]byte{0x01})
+
+		var wg sync.WaitGroup
+
+		for i := range 8 {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				nonce := [24]byte{byte(i)}
+				data := []byte{byte(i)}
+
+				ciphertext, tag := pool.Encrypt(nonce, data, nil)
+
+				plaintext, err := pool.Decrypt(nonce, ciphertext, nil, tag)
+				if err != nil || !slices.Equal(plaintext, data) {
+					t.Errorf("want %v, got %v (error %v)", data, plaintext, err)
+				}
+			}()
+		}
+
+		wg.Wait()
+	})
+}
@@ -0,0 +1,62 @@
+package xchacha20poly1305
+
+import (
+	"time"
+
+	"github.com/pmuens/ctk-go/ctk/chacha20poly1305"
+	"github.com/pmuens/ctk-go/ctk/clock"
+)
+
+const (
+	// ErrKeyExpired is returned if sealing a message would exceed a usage limit
+	// of the key.
+	ErrKeyExpired = chacha20poly1305.ErrKeyExpired
+)
+
+// DefaultMaxMessages is the default number of messages that can be sealed under
+// a key. With random 192 bit nonces the probability of a nonce collision stays
+// below 2^-96 for 2^48 messages.
+const DefaultMaxMessages = 1 << 48
+
+// DefaultMaxBytes is the default number of plaintext bytes that can be sealed
+// under a key (see chacha20poly1305.DefaultMaxBytes).
+const DefaultMaxBytes = chacha20poly1305.DefaultMaxBytes
+
+// KeyUsage counts the messages and the plaintext bytes that are sealed under a
+// key (see chacha20poly1305.KeyUsage).
+type KeyUsage = chacha20poly1305.KeyUsage
+
+// UsageOption configures a KeyUsage (see chacha20poly1305.UsageOption).
+type UsageOption = chacha20poly1305.UsageOption
+
+// WithMaxMessages sets the number of messages that can be sealed under the key.
+func WithMaxMessages(n uint64) UsageOption {
+	return chacha20poly1305.WithMaxMessages(n)
+}
+
+// WithMaxBytes sets the number of plaintext bytes that can be sealed under the
+// key.
+func WithMaxBytes(n uint64) UsageOption {
+	return chacha20poly1305.WithMaxBytes(n)
+}
+
+// WithMaxAge sets the time after which no more messages can be sealed under the
+// key (see chacha20poly1305.WithMaxAge).
+func WithMaxAge(d time.Duration) UsageOption {
+	return chacha20poly1305.WithMaxAge(d)
+}
+
+// WithClock sets the clock the age of the key is measured with (see
+// chacha20poly1305.WithClock).
+func WithClock(c clock.Clock) UsageOption {
+	return chacha20poly1305.WithClock(c)
+}
+
+// NewKeyUsage creates a new KeyUsage with the default limits of
+// XChaCha20-Poly1305 (see DefaultMaxMessages and DefaultMaxBytes) or the limits
+// set via the options.
+func NewKeyUsage(opts ...UsageOption) *KeyUsage {
+	defaults := []UsageOption{WithMaxMessages(DefaultMaxMessages), WithMaxBytes(DefaultMaxBytes)}
+
+	return chacha20poly1305.NewKeyUsage(append(defaults, opts...)...)
+}
@@ -0,0 +1,24 @@
+package xchacha20poly1305_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
+)
+
+func TestKeyUsage(t *testing.T) {
+	t.Parallel()
+
+	u := xchacha20poly1305.NewKeyUsage(xchacha20poly1305.WithMaxBytes(16))
+
+	err := u.Record(16)
+	if !errors.Is(err, nil) {
+		t.Errorf("want error %v, got %v", nil, err)
+	}
+
+	err = u.Record(1)
+	if !errors.Is(err, xchacha20poly1305.ErrKeyExpired) {
+		t.Errorf("want error %v, got %v", xchacha20poly1305.ErrKeyExpired, err)
+	}
+}
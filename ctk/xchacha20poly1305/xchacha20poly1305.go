@@ -1,10 +1,25 @@
 // Package xchacha20poly1305 implements the XChaCha20-Poly1305 authenticated
 // encryption with associated data (AEAD) algorithm as specified in
 // https://datatracker.ietf.org/doc/html/draft-irtf-cfrg-xchacha-03.
+//
+// Concurrency: The stateful AEAD instances aren't safe for concurrent use and
+// are meant to be used for a single message. A Pool (which hands out an instance
+// per call) and a SyncAEAD (which serializes calls via a mutex) are safe for
+// concurrent use and can be shared between goroutines.
+//
+// Key usage: A KeyUsage counts the messages and bytes that are sealed under a
+// key (and optionally tracks its age via an injectable clock) and reports (via
+// ErrKeyExpired) once the key should be rotated.
+//
+// Tags: SealDetached and OpenDetached (as well as Encrypt and Decrypt) handle
+// the tag separately from the ciphertext (e.g. for libsodium's detached mode)
+// whereas Seal and Open append the tag to the ciphertext (e.g. for TLS records).
 package xchacha20poly1305
 
 import (
 	"github.com/pmuens/ctk-go/ctk/chacha20poly1305"
+	"github.com/pmuens/ctk-go/ctk/internal/debug"
+	"github.com/pmuens/ctk-go/ctk/padding"
 	"github.com/pmuens/ctk-go/ctk/poly1305"
 	"github.com/pmuens/ctk-go/ctk/xchacha20"
 )
@@ -14,25 +29,67 @@ const (
 	ErrInvalidTag = chacha20poly1305.ErrInvalidTag
 )
 
+// TagSize is the size (in bytes) of a (full) Poly1305 tag.
+const TagSize = chacha20poly1305.TagSize
+
+// Option configures an AEAD instance (see chacha20poly1305.Option).
+type Option = chacha20poly1305.Option
+
+// WithRounds sets the number of rounds of the underlying stream cipher (see
+// chacha20.WithRounds).
+// Note that the reduced-round variants aren't interoperable with other
+// XChaCha20-Poly1305 implementations.
+// The constructor panics if the rounds aren't 8, 12 or 20.
+func WithRounds(rounds int) Option {
+	return chacha20poly1305.WithRounds(rounds)
+}
+
+// WithTruncatedTag truncates the tag to the size (in bytes).
+//
+// WARNING: A truncated tag significantly lowers the security level. Only use
+// this option if a protocol requires it (see chacha20poly1305.WithTruncatedTag).
+// Panics if the size isn't between 8 and 16 bytes.
+func WithTruncatedTag(size int) Option {
+	return chacha20poly1305.WithTruncatedTag(size)
+}
+
+// WithPadding pads the plaintext according to the mode before it's encrypted
+// and removes the padding after decryption so that the ciphertext only reveals
+// the padded length.
+func WithPadding(mode padding.Mode) Option {
+	return chacha20poly1305.WithPadding(mode)
+}
+
 // XChaCha20Poly1305 is a stateful instance of the XChaCha20-Poly1305 AEAD
 // algorithm.
+// An instance isn't safe for concurrent use. Use a Pool or a SyncAEAD to share
+// a key across goroutines.
 type XChaCha20Poly1305 struct {
 	// xchacha20 is an instance of the XChaCha20 stream cipher.
 	xchacha20 *xchacha20.XChaCha20
 
 	// poly1305 is an instance of the Poly1305 one-time authenticator.
 	poly1305 *poly1305.Poly1305
+
+	// aad are the AAD segments that were added via AddAAD.
+	aad [][]byte
+
+	// debug tracks the use of the instance (see the ctkdebug build tag).
+	debug debug.Instance
+
+	// options are the options the instance was created with.
+	options chacha20poly1305.Options
 }
 
 // NewXChaCha20Poly1305 creates a new instance of the XChaCha20-Poly1305 AEAD
 // algorithm.
-func NewXChaCha20Poly1305(key [32]byte, nonce [24]byte) *XChaCha20Poly1305 {
-	// The counter needs to be set to 0 as the first block of XChaCha20 will
-	// be used to generate the Poly1305 key.
-	counter := [4]byte{0x00, 0x00, 0x00, 0x00}
+func NewXChaCha20Poly1305(key [32]byte, nonce [24]byte, opts ...Option) *XChaCha20Poly1305 {
+	options := chacha20poly1305.NewOptions(opts...)
 
 	// Create a new instance of XChaCha20 that will be used for the AEAD construction.
-	xchacha20 := xchacha20.NewXChaCha20(key, nonce, counter)
+	// The counter needs to be set to 0 as the first block of XChaCha20 will
+	// be used to generate the Poly1305 key.
+	xchacha20 := xchacha20.NewXChaCha20WithCounter(key, nonce, 0, options.CipherOptions()...)
 
 	// Use XChaCha20's first block to generated the Poly1305 key and create a new
 	// instance of Poly1305 with it.
@@ -40,39 +97,115 @@ func NewXChaCha20Poly1305(key [32]byte, nonce [24]byte) *XChaCha20Poly1305 {
 	polyKey := chacha20poly1305.Poly1305KeyGen(firstBlock)
 	poly1305 := poly1305.NewPoly1305(polyKey)
 
-	return &XChaCha20Poly1305{
+	x := &XChaCha20Poly1305{
 		xchacha20: xchacha20,
 		poly1305:  poly1305,
+		options:   options,
 	}
+	x.debug.Reset(key[:], nonce[:])
+
+	return x
+}
+
+// reset reinitializes the instance with the key and nonce so that it can be
+// reused for another message.
+func (x *XChaCha20Poly1305) reset(key [32]byte, nonce [24]byte) {
+	x.xchacha20.ResetWithCounter(key, nonce, 0)
+
+	// Derive the new Poly1305 key from the first block (see the constructor).
+	firstBlock := x.xchacha20.CreateBlock()
+	x.poly1305.Reset(chacha20poly1305.Poly1305KeyGen(firstBlock))
+
+	clear(x.aad)
+	x.aad = x.aad[:0]
+
+	x.debug.Reset(key[:], nonce[:])
 }
 
 // Encrypt encrypts the plaintext via XChaCha20 and creates a message
 // authentication tag for the additional authenticated data (AAD) and the generated
 // ciphertext using Poly1305.
+// As the Poly1305 key is derived from the key and nonce, an instance can only
+// be used for a single Encrypt or Decrypt call.
+// Panics if the instance was already used (like the counter overflow of
+// ChaCha20, reusing it is a programming error).
 func (x *XChaCha20Poly1305) Encrypt(plaintext []byte, aad []byte) ([]byte, [16]byte) {
+	if debug.Enabled {
+		x.debug.Encrypt(plaintext, x.segments(aad)...)
+	}
+
+	if x.options.Padding != nil {
+		plaintext = padding.Pad(plaintext, x.options.Padding)
+	}
+
 	// Use XChaCha20 to encrypt the plaintext (note that at this point the counter
 	// is 1, given that we initialized XChaCha20 with a counter of 0 to generate
 	// the Poly1305 key).
 	ciphertext := x.xchacha20.XORWithKeyStream(plaintext)
 
-	// Get the padded input for Poly1305 and create a tag based on such data.
-	poly1305Input := chacha20poly1305.GeneratePoly1305Input(aad, ciphertext)
-	tag := x.poly1305.GenerateTag(poly1305Input)
+	// Create a tag based on the padded AAD and ciphertext.
+	tag, err := chacha20poly1305.GeneratePoly1305Tag(x.poly1305, x.aad, aad, ciphertext)
+	if err != nil {
+		panic("xchacha20poly1305: instance reused")
+	}
+
+	return ciphertext, x.options.TruncateTag(tag)
+}
+
+// Authenticate creates a tag for the additional authenticated data (AAD)
+// without encrypting anything (the same tag that Encrypt returns for an empty
+// plaintext). It's meant for callers who need authentication but no
+// encryption (e.g. for public headers). The padding option is ignored.
+// As for Encrypt, an instance can only be used for a single call.
+// Panics if the instance was already used.
+func (x *XChaCha20Poly1305) Authenticate(aad []byte) [16]byte {
+	if debug.Enabled {
+		x.debug.Encrypt([]byte{}, x.segments(aad)...)
+	}
+
+	tag, err := chacha20poly1305.GeneratePoly1305Tag(x.poly1305, x.aad, aad, []byte{})
+	if err != nil {
+		panic("xchacha20poly1305: instance reused")
+	}
+
+	return x.options.TruncateTag(tag)
+}
+
+// Verify checks the tag that was created via Authenticate for the additional
+// authenticated data (AAD).
+// Returns an error if the tag is invalid or if the instance was already used.
+func (x *XChaCha20Poly1305) Verify(aad []byte, tag [16]byte) error {
+	x.debug.Decrypt()
+
+	computedTag, err := chacha20poly1305.GeneratePoly1305Tag(x.poly1305, x.aad, aad, []byte{})
+	if err != nil {
+		return err
+	}
 
-	return ciphertext, tag
+	if !x.options.VerifyTag(tag, computedTag) {
+		return ErrInvalidTag
+	}
+
+	return nil
 }
 
 // Decrypt checks if the tag generated via Poly1305 is valid using the additional
 // authenticated data (AAD) and the ciphertext. If valid it decrypts the ciphertext
 // using XChaCha20.
-// Returns an error if the tag is invalid.
+// Returns an error if the tag or the padding is invalid or if the instance was
+// already used.
 func (x *XChaCha20Poly1305) Decrypt(ciphertext []byte, aad []byte, tag [16]byte) ([]byte, error) {
-	// Get the padded input for Poly1305 and create a tag based on such data.
-	poly1305Input := chacha20poly1305.GeneratePoly1305Input(aad, ciphertext)
-	computedTag := x.poly1305.GenerateTag(poly1305Input)
+	x.debug.Decrypt()
+
+	// Create a tag based on the padded AAD and ciphertext.
+	computedTag, err := chacha20poly1305.GeneratePoly1305Tag(x.poly1305, x.aad, aad, ciphertext)
+	if err != nil {
+		return []byte{}, err
+	}
 
-	// Return an error and exit early if the tags don't match.
-	if tag != computedTag {
+	// Return an error and exit early if the tags don't match (the comparison is
+	// done in constant time).
+	if !x.options.VerifyTag(tag, computedTag) {
 		return []byte{}, ErrInvalidTag
 	}
 
@@ -81,5 +214,27 @@ func (x *XChaCha20Poly1305) Decrypt(ciphertext []byte, aad []byte, tag [16]byte)
 	// the Poly1305 key).
 	plaintext := x.xchacha20.XORWithKeyStream(ciphertext)
 
+	if x.options.Padding != nil {
+		return unpad(plaintext)
+	}
+
 	return plaintext, nil
 }
+
+// unpad removes the padding from the decrypted plaintext. The plaintext is
+// wiped if the padding is invalid so that no decrypted data outlives the error.
+func unpad(plaintext []byte) ([]byte, error) {
+	unpadded, err := padding.Unpad(plaintext)
+	if err != nil {
+		clear(plaintext)
+		return []byte{}, err
+	}
+
+	return unpadded, nil
+}
+
+// TagSize returns the number of meaningful bytes of the tags that are emitted
+// and verified.
+func (x *XChaCha20Poly1305) TagSize() int {
+	return x.options.TagSize
+}
@@ -0,0 +1,24 @@
+package xchacha20poly1305
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pmuens/ctk-go/ctk/padding"
+)
+
+func TestUnpadWipesPlaintext(t *testing.T) {
+	t.Parallel()
+
+	plaintext := []byte("attack at dawn\x01")
+
+	got, err := unpad(plaintext)
+	if !errors.Is(err, padding.ErrInvalidPadding) || len(got) != 0 {
+		t.Errorf("want error %v and empty result, got %v (error %v)", padding.ErrInvalidPadding, got, err)
+	}
+
+	if !slices.Equal(plaintext, make([]byte, len(plaintext))) {
+		t.Errorf("want wiped plaintext, got %v", plaintext)
+	}
+}
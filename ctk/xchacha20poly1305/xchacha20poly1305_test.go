@@ -5,6 +5,7 @@ import (
 	"slices"
 	"testing"
 
+	"github.com/pmuens/ctk-go/ctk/padding"
 	"github.com/pmuens/ctk-go/ctk/xchacha20poly1305"
 )
 
@@ -232,4 +233,131 @@ func TestXChaCha20Poly1305(t *testing.T) {
 			t.Errorf("want %v, got %v", want, got)
 		}
 	})
+
+	t.Run("Padding", func(t *testing.T) {
+		t.Parallel()
+
+		key := [32]byte{0x01}
+		nonce := [24]byte{0x07}
+		aad := []byte{0x02}
+
+		data := []byte("attack at dawn")
+
+		xchaPoly1 := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce, xchacha20poly1305.WithPadding(padding.PadToBlock(32)))
+		ciphertext, tag := xchaPoly1.Encrypt(data, aad)
+
+		if len(ciphertext) != 32 {
+			t.Errorf("want length %v, got %v", 32, len(ciphertext))
+		}
+
+		xchaPoly2 := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce, xchacha20poly1305.WithPadding(padding.PadToBlock(32)))
+		plaintext, err := xchaPoly2.Decrypt(ciphertext, aad, tag)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		got := plaintext
+		want := data
+
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Rounds", func(t *testing.T) {
+		t.Parallel()
+
+		key := [32]byte{0x01}
+		nonce := [24]byte{0x07}
+		aad := []byte{0x02}
+
+		data := []byte("attack at dawn")
+
+		xchaPoly1 := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce, xchacha20poly1305.WithRounds(12))
+		ciphertext, tag := xchaPoly1.Encrypt(data, aad)
+
+		xchaPoly2 := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce)
+		defaultCiphertext, _ := xchaPoly2.Encrypt(data, aad)
+
+		if slices.Equal(ciphertext, defaultCiphertext) {
+			t.Errorf("want ciphertexts to differ, got %v", ciphertext)
+		}
+
+		xchaPoly3 := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce, xchacha20poly1305.WithRounds(12))
+		plaintext, err := xchaPoly3.Decrypt(ciphertext, aad, tag)
+		if err != nil {
+			t.Fatalf("want error %v, got %v", nil, err)
+		}
+
+		got := plaintext
+		want := data
+
+		if !slices.Equal(got, want) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+}
+
+func TestXChaCha20Poly1305Authenticate(t *testing.T) {
+	// The tag was generated with golang.org/x/crypto/chacha20poly1305 (sealing an
+	// empty plaintext).
+	key := [32]byte{
+		0x80, 0x81, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87,
+		0x88, 0x89, 0x8a, 0x8b, 0x8c, 0x8d, 0x8e, 0x8f,
+		0x90, 0x91, 0x92, 0x93, 0x94, 0x95, 0x96, 0x97,
+		0x98, 0x99, 0x9a, 0x9b, 0x9c, 0x9d, 0x9e, 0x9f,
+	}
+
+	nonce := [24]byte{
+		0x40, 0x41, 0x42, 0x43, 0x44, 0x45, 0x46, 0x47,
+		0x48, 0x49, 0x4a, 0x4b, 0x4c, 0x4d, 0x4e, 0x4f,
+		0x50, 0x51, 0x52, 0x53, 0x54, 0x55, 0x56, 0x57,
+	}
+
+	aad := []byte{
+		0x50, 0x51, 0x52, 0x53, 0xc0, 0xc1, 0xc2, 0xc3, 0xc4, 0xc5, 0xc6, 0xc7,
+	}
+
+	wantTag := [16]byte{
+		0xe4, 0xc5, 0x19, 0x1f, 0x68, 0xfd, 0x06, 0xd9, 0x59, 0x2f, 0x83, 0x75, 0x44, 0x80, 0xd1, 0x9d,
+	}
+
+	t.Run("Authenticate", func(t *testing.T) {
+		t.Parallel()
+
+		got := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce).Authenticate(aad)
+		if got != wantTag {
+			t.Errorf("want %v, got %v", wantTag, got)
+		}
+	})
+
+	t.Run("Verify", func(t *testing.T) {
+		t.Parallel()
+
+		err := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce).Verify(aad, wantTag)
+		if !errors.Is(err, nil) {
+			t.Errorf("want error %v, got %v", nil, err)
+		}
+
+		err = xchacha20poly1305.NewXChaCha20Poly1305(key, nonce).Verify(aad[1:], wantTag)
+		if !errors.Is(err, xchacha20poly1305.ErrInvalidTag) {
+			t.Errorf("want error %v, got %v", xchacha20poly1305.ErrInvalidTag, err)
+		}
+	})
+
+	t.Run("Reuse", func(t *testing.T) {
+		t.Parallel()
+
+		xchaPoly := xchacha20poly1305.NewXChaCha20Poly1305(key, nonce)
+		xchaPoly.Encrypt([]byte("attack at dawn"), aad)
+
+		// Encrypting again would reuse the Poly1305 key.
+		if !panics(func() { xchaPoly.Encrypt([]byte("attack at dusk"), aad) }) {
+			t.Errorf("want panic, got none")
+		}
+
+		if !panics(func() { xchaPoly.Authenticate(aad) }) {
+			t.Errorf("want panic, got none")
+		}
+	})
 }